@@ -4,29 +4,39 @@ package main
 
 import (
 	"bytes"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // BufferPool manages a pool of bytes.Buffer for efficient reuse
 type BufferPool struct {
 	pool sync.Pool
+	// maxRetainCap を超える容量に育ったバッファはプールに戻さない（0なら無制限）
+	maxRetainCap int
+	poolCounter
 }
 
-// NewBufferPool creates a new BufferPool
+// NewBufferPool creates a new BufferPool that retains buffers of any size
 func NewBufferPool() *BufferPool {
-	return &BufferPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				// TODO: ここに実装を追加してください
-				//
-				// 実装の流れ:
-				// 1. 新しいbytes.Bufferを作成
-				// 2. 適切な初期容量を設定
-				return &bytes.Buffer{}
-			},
-		},
+	return NewBufferPoolWithLimit(0)
+}
+
+// NewBufferPoolWithLimit creates a BufferPool that discards buffers whose
+// capacity exceeds maxRetainCap instead of pooling them
+func NewBufferPoolWithLimit(maxRetainCap int) *BufferPool {
+	bp := &BufferPool{maxRetainCap: maxRetainCap}
+	bp.pool.New = func() interface{} {
+		// TODO: ここに実装を追加してください
+		//
+		// 実装の流れ:
+		// 1. 新しいbytes.Bufferを作成
+		// 2. 適切な初期容量を設定
+		bp.recordNew()
+		return &bytes.Buffer{}
 	}
+	return bp
 }
 
 // Get retrieves a buffer from the pool
@@ -37,6 +47,7 @@ func (bp *BufferPool) Get() *bytes.Buffer {
 	// 1. poolからオブジェクトを取得
 	// 2. *bytes.Bufferに型アサーション
 	// 3. バッファをリセット
+	bp.recordGet()
 	return bp.pool.Get().(*bytes.Buffer)
 }
 
@@ -48,6 +59,9 @@ func (bp *BufferPool) Put(buf *bytes.Buffer) {
 	// 1. バッファが大きすぎる場合は破棄
 	// 2. バッファをリセット
 	// 3. poolに戻す
+	if bp.maxRetainCap > 0 && buf.Cap() > bp.maxRetainCap {
+		return
+	}
 	buf.Reset()
 	bp.pool.Put(buf)
 }
@@ -82,26 +96,26 @@ func (wd *WorkerData) Reset() {
 // WorkerDataPool manages a pool of WorkerData structs
 type WorkerDataPool struct {
 	pool sync.Pool
+	poolCounter
 }
 
 // NewWorkerDataPool creates a new WorkerDataPool
 func NewWorkerDataPool() *WorkerDataPool {
-	return &WorkerDataPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				// TODO: ここに実装を追加してください
-				//
-				// 実装の流れ:
-				// 1. 新しいWorkerDataを作成
-				// 2. マップとスライスを初期化
-				return &WorkerData{
-					Payload:  make([]byte, 0, 1024),
-					Metadata: make(map[string]string),
-					Results:  make([]float64, 0, 10),
-				}
-			},
-		},
+	wdp := &WorkerDataPool{}
+	wdp.pool.New = func() interface{} {
+		// TODO: ここに実装を追加してください
+		//
+		// 実装の流れ:
+		// 1. 新しいWorkerDataを作成
+		// 2. マップとスライスを初期化
+		wdp.recordNew()
+		return &WorkerData{
+			Payload:  make([]byte, 0, 1024),
+			Metadata: make(map[string]string),
+			Results:  make([]float64, 0, 10),
+		}
 	}
+	return wdp
 }
 
 // Get retrieves a WorkerData from the pool
@@ -112,6 +126,7 @@ func (wdp *WorkerDataPool) Get() *WorkerData {
 	// 1. poolからオブジェクトを取得
 	// 2. *WorkerDataに型アサーション
 	// 3. 必要に応じて初期化
+	wdp.recordGet()
 	return wdp.pool.Get().(*WorkerData)
 }
 
@@ -134,6 +149,7 @@ func (wdp *WorkerDataPool) Put(wd *WorkerData) {
 type SlicePool struct {
 	pools map[int]*sync.Pool // key: capacity range, value: pool
 	mu    sync.RWMutex
+	poolCounter
 }
 
 // NewSlicePool creates a new SlicePool
@@ -170,6 +186,7 @@ func (sp *SlicePool) getPoolForCapacity(capacity int) *sync.Pool {
 
 	pool = &sync.Pool{
 		New: func() interface{} {
+			sp.recordNew()
 			return make([]byte, bucketSize)
 		},
 	}
@@ -206,6 +223,7 @@ func (sp *SlicePool) GetSlice(capacity int) []byte {
 	// 2. プールからスライスを取得
 	// 3. 容量をチェックして調整
 	pool := sp.getPoolForCapacity(capacity)
+	sp.recordGet()
 	slice := pool.Get().([]byte)
 
 	return slice[:0] // Reset length but keep capacity
@@ -232,6 +250,58 @@ func (sp *SlicePool) PutSlice(slice []byte) {
 	pool.Put(slice)
 }
 
+// Buckets returns the sorted capacity buckets currently registered
+func (sp *SlicePool) Buckets() []int {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+
+	buckets := make([]int, 0, len(sp.pools))
+	for size := range sp.pools {
+		buckets = append(buckets, size)
+	}
+	sort.Ints(buckets)
+	return buckets
+}
+
+// Reset drops every bucket pool so that no slice is reused afterwards
+func (sp *SlicePool) Reset() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	sp.pools = make(map[int]*sync.Pool)
+}
+
+// TypedPool is a type-safe sync.Pool wrapper for arbitrary objects
+type TypedPool[T any] struct {
+	pool    sync.Pool
+	resetFn func(T)
+	poolCounter
+}
+
+// NewTypedPool creates a TypedPool. resetFn may be nil when objects need no cleanup
+func NewTypedPool[T any](newFn func() T, resetFn func(T)) *TypedPool[T] {
+	tp := &TypedPool[T]{resetFn: resetFn}
+	tp.pool.New = func() interface{} {
+		tp.recordNew()
+		return newFn()
+	}
+	return tp
+}
+
+// Get retrieves an object from the pool
+func (tp *TypedPool[T]) Get() T {
+	tp.recordGet()
+	return tp.pool.Get().(T)
+}
+
+// Put resets the object and returns it to the pool
+func (tp *TypedPool[T]) Put(obj T) {
+	if tp.resetFn != nil {
+		tp.resetFn(obj)
+	}
+	tp.pool.Put(obj)
+}
+
 // ProcessingService demonstrates object pooling in a service
 type ProcessingService struct {
 	bufferPool     *BufferPool
@@ -321,6 +391,33 @@ func ProcessWithoutPool(inputData []byte) (string, error) {
 	return buf.String(), nil
 }
 
+// poolCounter はsync.Poolの取得回数と新規生成回数を記録する
+// sync.Pool.NewはGetがプール内のオブジェクトを見つけられなかった時だけ呼ばれるため、
+// gets - news が再利用（ヒット）回数になる
+type poolCounter struct {
+	gets atomic.Int64
+	news atomic.Int64
+}
+
+func (c *poolCounter) recordGet() { c.gets.Add(1) }
+
+func (c *poolCounter) recordNew() { c.news.Add(1) }
+
+// Hits returns how many Get calls were served by a reused object
+func (c *poolCounter) Hits() int64 {
+	hits := c.gets.Load() - c.news.Load()
+	if hits < 0 {
+		// 並行実行中はNewの記録がGetの記録より先に見えることがある
+		return 0
+	}
+	return hits
+}
+
+// Allocations returns how many objects were newly allocated by the pool
+func (c *poolCounter) Allocations() int64 {
+	return c.news.Load()
+}
+
 // PoolStats provides statistics about pool usage
 type PoolStats struct {
 	BufferPoolHits   int64
@@ -329,10 +426,16 @@ type PoolStats struct {
 	TotalAllocations int64
 }
 
-// GetStats returns current pool statistics (stub for demonstration)
+// GetStats returns current pool statistics
 func (ps *ProcessingService) GetStats() PoolStats {
-	// 実際の実装では適切な統計情報を収集
-	return PoolStats{}
+	return PoolStats{
+		BufferPoolHits: ps.bufferPool.Hits(),
+		WorkerPoolHits: ps.workerDataPool.Hits(),
+		SlicePoolHits:  ps.slicePool.Hits(),
+		TotalAllocations: ps.bufferPool.Allocations() +
+			ps.workerDataPool.Allocations() +
+			ps.slicePool.Allocations(),
+	}
 }
 
 func main() {
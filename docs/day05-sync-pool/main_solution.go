@@ -4,13 +4,18 @@ package main
 
 import (
 	"bytes"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // BufferPool manages a pool of bytes.Buffer for efficient reuse
 type BufferPool struct {
 	pool sync.Pool
+	// maxRetainCap を超える容量に育ったバッファはプールに戻さない（0なら無制限）
+	maxRetainCap int
+	poolCounter
 }
 
 // WorkerData represents data processed by workers
@@ -24,12 +29,14 @@ type WorkerData struct {
 // WorkerDataPool manages a pool of WorkerData structs
 type WorkerDataPool struct {
 	pool sync.Pool
+	poolCounter
 }
 
 // SlicePool manages pools of slices with different capacities
 type SlicePool struct {
 	pools map[int]*sync.Pool // key: capacity range, value: pool
 	mu    sync.RWMutex
+	poolCounter
 }
 
 // ProcessingService demonstrates object pooling in a service
@@ -39,6 +46,33 @@ type ProcessingService struct {
 	slicePool      *SlicePool
 }
 
+// poolCounter はsync.Poolの取得回数と新規生成回数を記録する
+// sync.Pool.NewはGetがプール内のオブジェクトを見つけられなかった時だけ呼ばれるため、
+// gets - news が再利用（ヒット）回数になる
+type poolCounter struct {
+	gets atomic.Int64
+	news atomic.Int64
+}
+
+func (c *poolCounter) recordGet() { c.gets.Add(1) }
+
+func (c *poolCounter) recordNew() { c.news.Add(1) }
+
+// Hits returns how many Get calls were served by a reused object
+func (c *poolCounter) Hits() int64 {
+	hits := c.gets.Load() - c.news.Load()
+	if hits < 0 {
+		// 並行実行中はNewの記録がGetの記録より先に見えることがある
+		return 0
+	}
+	return hits
+}
+
+// Allocations returns how many objects were newly allocated by the pool
+func (c *poolCounter) Allocations() int64 {
+	return c.news.Load()
+}
+
 // PoolStats provides statistics about pool usage
 type PoolStats struct {
 	BufferPoolHits   int64
@@ -49,16 +83,20 @@ type PoolStats struct {
 
 // BufferPool の実装
 func NewBufferPool() *BufferPool {
-	return &BufferPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				return &bytes.Buffer{}
-			},
-		},
+	return NewBufferPoolWithLimit(0)
+}
+
+func NewBufferPoolWithLimit(maxRetainCap int) *BufferPool {
+	bp := &BufferPool{maxRetainCap: maxRetainCap}
+	bp.pool.New = func() interface{} {
+		bp.recordNew()
+		return &bytes.Buffer{}
 	}
+	return bp
 }
 
 func (bp *BufferPool) Get() *bytes.Buffer {
+	bp.recordGet()
 	buf := bp.pool.Get().(*bytes.Buffer)
 	buf.Reset() // バッファをクリア
 	return buf
@@ -70,8 +108,7 @@ func (bp *BufferPool) Put(buf *bytes.Buffer) {
 	}
 	
 	// 大きすぎるバッファは破棄
-	const maxSize = 1 << 20 // 1MB
-	if buf.Cap() > maxSize {
+	if bp.maxRetainCap > 0 && buf.Cap() > bp.maxRetainCap {
 		return
 	}
 	
@@ -95,19 +132,19 @@ func (wd *WorkerData) Reset() {
 
 // WorkerDataPool の実装
 func NewWorkerDataPool() *WorkerDataPool {
-	return &WorkerDataPool{
-		pool: sync.Pool{
-			New: func() interface{} {
-				return &WorkerData{
-					Metadata: make(map[string]string),
-					Results:  make([]float64, 0, 10),
-				}
-			},
-		},
+	wdp := &WorkerDataPool{}
+	wdp.pool.New = func() interface{} {
+		wdp.recordNew()
+		return &WorkerData{
+			Metadata: make(map[string]string),
+			Results:  make([]float64, 0, 10),
+		}
 	}
+	return wdp
 }
 
 func (wdp *WorkerDataPool) Get() *WorkerData {
+	wdp.recordGet()
 	wd := wdp.pool.Get().(*WorkerData)
 	wd.Reset()
 	return wd
@@ -149,6 +186,7 @@ func (sp *SlicePool) getPoolForCapacity(capacity int) *sync.Pool {
 	
 	pool = &sync.Pool{
 		New: func() interface{} {
+			sp.recordNew()
 			return make([]byte, 0, bucketSize)
 		},
 	}
@@ -171,9 +209,11 @@ func roundUpToPowerOf2(n int) int {
 
 func (sp *SlicePool) GetSlice(capacity int) []byte {
 	pool := sp.getPoolForCapacity(capacity)
+	sp.recordGet()
 	slice := pool.Get().([]byte)
 	
 	if cap(slice) < capacity {
+		sp.recordNew()
 		return make([]byte, 0, capacity)
 	}
 	
@@ -194,6 +234,59 @@ func (sp *SlicePool) PutSlice(slice []byte) {
 	pool.Put(slice[:0])
 }
 
+// Buckets returns the sorted capacity buckets currently registered
+func (sp *SlicePool) Buckets() []int {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	
+	buckets := make([]int, 0, len(sp.pools))
+	for size := range sp.pools {
+		buckets = append(buckets, size)
+	}
+	sort.Ints(buckets)
+	return buckets
+}
+
+// Reset drops every bucket pool so that no slice is reused afterwards
+func (sp *SlicePool) Reset() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	
+	sp.pools = make(map[int]*sync.Pool)
+}
+
+// TypedPool の実装
+// TypedPool is a type-safe sync.Pool wrapper for arbitrary objects
+type TypedPool[T any] struct {
+	pool    sync.Pool
+	resetFn func(T)
+	poolCounter
+}
+
+// NewTypedPool creates a TypedPool. resetFn may be nil when objects need no cleanup
+func NewTypedPool[T any](newFn func() T, resetFn func(T)) *TypedPool[T] {
+	tp := &TypedPool[T]{resetFn: resetFn}
+	tp.pool.New = func() interface{} {
+		tp.recordNew()
+		return newFn()
+	}
+	return tp
+}
+
+// Get retrieves an object from the pool
+func (tp *TypedPool[T]) Get() T {
+	tp.recordGet()
+	return tp.pool.Get().(T)
+}
+
+// Put resets the object and returns it to the pool
+func (tp *TypedPool[T]) Put(obj T) {
+	if tp.resetFn != nil {
+		tp.resetFn(obj)
+	}
+	tp.pool.Put(obj)
+}
+
 // ProcessingService の実装
 func NewProcessingService() *ProcessingService {
 	return &ProcessingService{
@@ -253,10 +346,16 @@ func ProcessWithoutPool(inputData []byte) (string, error) {
 	return buf.String(), nil
 }
 
-// GetStats returns current pool statistics (stub for demonstration)
+// GetStats returns current pool statistics
 func (ps *ProcessingService) GetStats() PoolStats {
-	// 実際の実装では適切な統計情報を収集
-	return PoolStats{}
+	return PoolStats{
+		BufferPoolHits: ps.bufferPool.Hits(),
+		WorkerPoolHits: ps.workerDataPool.Hits(),
+		SlicePoolHits:  ps.slicePool.Hits(),
+		TotalAllocations: ps.bufferPool.Allocations() +
+			ps.workerDataPool.Allocations() +
+			ps.slicePool.Allocations(),
+	}
 }
 
 // simulateHeavyProcessing simulates CPU-intensive work
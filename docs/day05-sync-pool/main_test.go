@@ -88,6 +88,52 @@ func TestBufferPool(t *testing.T) {
 			t.Error("Buffer not reset after large buffer")
 		}
 	})
+
+	t.Run("Retain capacity limit", func(t *testing.T) {
+		const limit = 64 * 1024
+		pool := NewBufferPoolWithLimit(limit)
+		
+		buf := pool.Get()
+		buf.Write(make([]byte, 1024*1024)) // 1MB
+		pool.Put(buf)
+		
+		// The oversized buffer must have been discarded, not pooled
+		buf2 := pool.Get()
+		if buf2.Cap() > limit {
+			t.Errorf("Expected buffer capacity <= %d, got %d", limit, buf2.Cap())
+		}
+		if pool.Allocations() != 2 {
+			t.Errorf("Expected second Get to allocate, got %d allocations", pool.Allocations())
+		}
+	})
+
+	t.Run("Hit statistics", func(t *testing.T) {
+		pool := NewBufferPool()
+		
+		// Warm the pool: the first Get always misses
+		buf := pool.Get()
+		pool.Put(buf)
+		
+		if allocs := pool.Allocations(); allocs != 1 {
+			t.Errorf("Expected 1 allocation after warm-up, got %d", allocs)
+		}
+		before := pool.Hits()
+		
+		// Returned buffers should be reused on subsequent Gets
+		for i := 0; i < 50; i++ {
+			buf := pool.Get()
+			buf.WriteString("reuse")
+			pool.Put(buf)
+		}
+		
+		after := pool.Hits()
+		if after <= before {
+			t.Errorf("Expected hit count to rise on reuse, before %d, after %d", before, after)
+		}
+		if after+pool.Allocations() != 51 {
+			t.Errorf("Expected hits + allocations to equal 51 gets, got %d + %d", after, pool.Allocations())
+		}
+	})
 }
 
 func TestWorkerDataPool(t *testing.T) {
@@ -167,6 +213,108 @@ func TestWorkerDataPool(t *testing.T) {
 	})
 }
 
+func TestTypedPool(t *testing.T) {
+	// WorkerDataPoolと同じ振る舞いをTypedPoolで再現する
+	newWorkerDataPool := func() *TypedPool[*WorkerData] {
+		return NewTypedPool(
+			func() *WorkerData {
+				return &WorkerData{
+					Metadata: make(map[string]string),
+					Results:  make([]float64, 0, 10),
+				}
+			},
+			(*WorkerData).Reset,
+		)
+	}
+
+	t.Run("Equivalent to WorkerDataPool", func(t *testing.T) {
+		pool := newWorkerDataPool()
+		
+		wd := pool.Get()
+		if wd == nil {
+			t.Fatal("Got nil WorkerData from pool")
+		}
+		
+		wd.ID = 123
+		wd.Payload = []byte("test payload")
+		wd.Metadata["key"] = "value"
+		wd.Results = append(wd.Results, 1.23, 4.56)
+		
+		pool.Put(wd)
+		
+		// Put must have reset the object regardless of reuse
+		if wd.ID != 0 || len(wd.Payload) != 0 || len(wd.Metadata) != 0 || len(wd.Results) != 0 {
+			t.Errorf("WorkerData not reset on Put: %+v", wd)
+		}
+		
+		wd2 := pool.Get()
+		if wd2 == nil {
+			t.Fatal("Got nil WorkerData from pool on second get")
+		}
+		if wd2.ID != 0 || len(wd2.Payload) != 0 || len(wd2.Metadata) != 0 || len(wd2.Results) != 0 {
+			t.Errorf("WorkerData not reset: %+v", wd2)
+		}
+		if wd2.Metadata == nil {
+			t.Error("Metadata map should be initialized")
+		}
+	})
+
+	t.Run("Nil reset function", func(t *testing.T) {
+		pool := NewTypedPool(func() []int { return make([]int, 0, 8) }, nil)
+		
+		s := pool.Get()
+		if cap(s) != 8 {
+			t.Errorf("Expected capacity 8, got %d", cap(s))
+		}
+		pool.Put(s)
+	})
+
+	t.Run("Hit statistics", func(t *testing.T) {
+		pool := newWorkerDataPool()
+		
+		for i := 0; i < 50; i++ {
+			wd := pool.Get()
+			wd.ID = i
+			pool.Put(wd)
+		}
+		
+		if pool.Hits() == 0 {
+			t.Error("Expected pooled objects to be reused")
+		}
+		if total := pool.Hits() + pool.Allocations(); total != 50 {
+			t.Errorf("Expected hits + allocations to equal 50 gets, got %d", total)
+		}
+	})
+
+	t.Run("Concurrent access", func(t *testing.T) {
+		pool := newWorkerDataPool()
+		const numGoroutines = 50
+		const numOperations = 20
+		
+		var wg sync.WaitGroup
+		
+		for i := 0; i < numGoroutines; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				
+				for j := 0; j < numOperations; j++ {
+					wd := pool.Get()
+					if wd.ID != 0 || len(wd.Metadata) != 0 {
+						t.Errorf("Got dirty WorkerData in goroutine %d", id)
+						return
+					}
+					wd.ID = id
+					wd.Metadata["goroutine"] = "test"
+					pool.Put(wd)
+				}
+			}(i)
+		}
+		
+		wg.Wait()
+	})
+}
+
 func TestSlicePool(t *testing.T) {
 	t.Run("Basic operations", func(t *testing.T) {
 		pool := NewSlicePool()
@@ -244,6 +392,40 @@ func TestSlicePool(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("Buckets and Reset", func(t *testing.T) {
+		pool := NewSlicePool()
+		
+		if buckets := pool.Buckets(); len(buckets) != 0 {
+			t.Errorf("Expected no buckets on a new pool, got %v", buckets)
+		}
+		
+		for _, capacity := range []int{1000, 10, 100, 20} {
+			pool.PutSlice(pool.GetSlice(capacity))
+		}
+		
+		expected := []int{32, 128, 1024}
+		buckets := pool.Buckets()
+		if len(buckets) != len(expected) {
+			t.Fatalf("Expected buckets %v, got %v", expected, buckets)
+		}
+		for i := range expected {
+			if buckets[i] != expected[i] {
+				t.Errorf("Expected buckets %v, got %v", expected, buckets)
+				break
+			}
+		}
+		
+		pool.Reset()
+		if buckets := pool.Buckets(); len(buckets) != 0 {
+			t.Errorf("Expected no buckets after Reset, got %v", buckets)
+		}
+		
+		// The pool remains usable after Reset
+		if slice := pool.GetSlice(64); cap(slice) < 64 {
+			t.Errorf("Expected capacity >= 64 after Reset, got %d", cap(slice))
+		}
+	})
 }
 
 func TestProcessingService(t *testing.T) {
@@ -300,6 +482,32 @@ func TestProcessingService(t *testing.T) {
 		
 		wg.Wait()
 	})
+
+	t.Run("Pool statistics", func(t *testing.T) {
+		service := NewProcessingService()
+		
+		if stats := service.GetStats(); stats != (PoolStats{}) {
+			t.Errorf("Expected zero stats before processing, got %+v", stats)
+		}
+		
+		for i := 0; i < 20; i++ {
+			if _, err := service.ProcessData([]byte("test data")); err != nil {
+				t.Fatalf("ProcessData failed: %v", err)
+			}
+		}
+		
+		stats := service.GetStats()
+		if stats.TotalAllocations == 0 {
+			t.Error("Expected at least one allocation")
+		}
+		if stats.BufferPoolHits == 0 || stats.WorkerPoolHits == 0 || stats.SlicePoolHits == 0 {
+			t.Errorf("Expected hits on every pool after reuse, got %+v", stats)
+		}
+		// 3つのプールから20回ずつ取得している
+		if total := stats.BufferPoolHits + stats.WorkerPoolHits + stats.SlicePoolHits + stats.TotalAllocations; total != 60 {
+			t.Errorf("Expected hits + allocations to equal 60 gets, got %d", total)
+		}
+	})
 }
 
 // ベンチマークテスト: プール使用時 vs 非使用時の比較
@@ -360,6 +568,46 @@ func BenchmarkWorkerDataPoolVsNew(b *testing.B) {
 	})
 }
 
+func BenchmarkTypedPoolVsNew(b *testing.B) {
+	b.Run("WithTypedPool", func(b *testing.B) {
+		pool := NewTypedPool(
+			func() *WorkerData {
+				return &WorkerData{
+					Metadata: make(map[string]string),
+					Results:  make([]float64, 0, 10),
+				}
+			},
+			(*WorkerData).Reset,
+		)
+		
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				wd := pool.Get()
+				wd.ID = 123
+				wd.Payload = make([]byte, 100)
+				wd.Metadata["key"] = "value"
+				pool.Put(wd)
+			}
+		})
+	})
+
+	b.Run("WithoutPool", func(b *testing.B) {
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				wd := &WorkerData{
+					Metadata: make(map[string]string),
+					Results:  make([]float64, 0, 10),
+				}
+				wd.ID = 123
+				wd.Payload = make([]byte, 100)
+				wd.Metadata["key"] = "value"
+			}
+		})
+	})
+}
+
 func BenchmarkSlicePoolVsNew(b *testing.B) {
 	b.Run("WithPool", func(b *testing.B) {
 		pool := NewSlicePool()
@@ -15,6 +15,10 @@ type Task struct {
 	Data     interface{}
 	Priority int
 	Created  time.Time
+	// Run is an optional cancellable task body. It receives a per-task
+	// context and should return promptly once the context is done.
+	// When nil, the task is processed by the built-in simulation.
+	Run func(ctx context.Context) (interface{}, error)
 }
 
 // Result represents the result of processing a task
@@ -35,6 +39,7 @@ type WorkerPool struct {
 	wg         sync.WaitGroup
 	ctx        context.Context
 	cancel     context.CancelFunc
+	processor  TaskProcessor
 }
 
 // NewWorkerPool creates a new WorkerPool
@@ -51,6 +56,30 @@ func NewWorkerPool(numWorkers int, queueSize int) *WorkerPool {
 	}
 }
 
+// NewWorkerPoolWithProcessor creates a new WorkerPool that processes task data
+// with the given TaskProcessor
+func NewWorkerPoolWithProcessor(numWorkers int, queueSize int, processor TaskProcessor) *WorkerPool {
+	wp := NewWorkerPool(numWorkers, queueSize)
+	wp.processor = processor
+	return wp
+}
+
+// NewWorkerPoolWithPreemption creates a new WorkerPool that schedules tasks by
+// priority and lets a newly submitted high-priority task preempt a running
+// lower-priority task when every worker is busy.
+//
+// Only cancellable tasks (tasks with a Run function that honors its context)
+// can be preempted.
+func NewWorkerPoolWithPreemption(numWorkers int, queueSize int) *WorkerPool {
+	// TODO: ここに実装を追加してください
+	//
+	// 実装の流れ:
+	// 1. 優先度順のキュー（container/heap）を用意し、queueSize件までに制限
+	// 2. 全ワーカーが実行中なら、より低優先度で実行中のタスクのコンテキストをキャンセル
+	// 3. キャンセルで中断されたタスクは再キューイング（キャンセルを無視して完了したタスクは再実行しない）
+	return NewWorkerPool(numWorkers, queueSize)
+}
+
 // Start starts the worker pool
 func (wp *WorkerPool) Start() {
 	// TODO: ここに実装を追加してください
@@ -171,6 +200,19 @@ func (wp *WorkerPool) SubmitTask(task Task) error {
 	}
 }
 
+// TryMap processes every input on the worker pool and returns the results in
+// input order. Unlike a fail-fast variant, all inputs are processed and every
+// task error is collected into the returned error via errors.Join.
+func (wp *WorkerPool) TryMap(ctx context.Context, inputs []interface{}) ([]Result, error) {
+	// TODO: ここに実装を追加してください
+	//
+	// 実装の流れ:
+	// 1. 各入力をタスクとして投入（ctx がキャンセルされたら投入を止める）
+	// 2. 結果を入力順に並べる
+	// 3. 失敗したタスクのエラーを errors.Join でまとめて返す
+	return nil, nil
+}
+
 // GetResult gets a result from the result channel
 func (wp *WorkerPool) GetResult() (Result, bool) {
 	// TODO: ここに実装を追加してください
@@ -243,6 +285,12 @@ func (wp *WorkerPool) GetStats() PoolStats {
 	}
 }
 
+// Preemptions returns how many running tasks have been preempted and re-queued
+func (wp *WorkerPool) Preemptions() int64 {
+	// TODO: ここに実装を追加してください
+	return 0
+}
+
 // TaskProcessor defines the interface for processing different types of tasks
 type TaskProcessor interface {
 	Process(data interface{}) (interface{}, error)
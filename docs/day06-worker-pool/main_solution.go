@@ -1,9 +1,12 @@
 package main
 
 import (
+	"container/heap"
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +16,12 @@ type Task struct {
 	Data     interface{}
 	Priority int
 	Created  time.Time
+	// Run is an optional cancellable task body. It receives a per-task
+	// context and should return promptly once the context is done.
+	// When nil, the task is processed by the built-in simulation.
+	Run func(ctx context.Context) (interface{}, error)
+	// reply receives the result instead of the pool's result channel
+	reply chan<- Result
 }
 
 // Result represents the result of processing a task
@@ -33,8 +42,38 @@ type WorkerPool struct {
 	wg         sync.WaitGroup
 	ctx        context.Context
 	cancel     context.CancelFunc
+	processor  TaskProcessor
+
+	// Preemption mode (see NewWorkerPoolWithPreemption). Tasks are kept in a
+	// priority queue of up to cap(taskQueue) tasks instead of taskQueue,
+	// guarded by mu.
+	preemptive  bool
+	mu          sync.Mutex
+	cond        *sync.Cond
+	queue       taskHeap
+	queueSpace  chan struct{}
+	running     map[int]*runningTask
+	closed      bool
+	seq         int64
+	preemptions int64
+}
+
+// runningTask tracks a task currently executed by a worker
+type runningTask struct {
+	task      Task
+	cancel    context.CancelFunc
+	preempted bool
+}
+
+// queuedTask is an entry of the priority queue
+type queuedTask struct {
+	task Task
+	seq  int64
 }
 
+// taskHeap orders tasks by descending priority, FIFO within the same priority
+type taskHeap []queuedTask
+
 // PoolStats represents statistics about the worker pool
 type PoolStats struct {
 	NumWorkers    int
@@ -79,6 +118,32 @@ func NewWorkerPool(numWorkers int, queueSize int) *WorkerPool {
 	}
 }
 
+// NewWorkerPoolWithProcessor creates a new WorkerPool that processes task data
+// with the given TaskProcessor
+func NewWorkerPoolWithProcessor(numWorkers int, queueSize int, processor TaskProcessor) *WorkerPool {
+	wp := NewWorkerPool(numWorkers, queueSize)
+	wp.processor = processor
+	return wp
+}
+
+// NewWorkerPoolWithPreemption creates a new WorkerPool that schedules tasks by
+// priority and lets a newly submitted high-priority task preempt a running
+// lower-priority task when every worker is busy. The preempted task's context
+// is cancelled and the task is re-queued to run again later, unless it
+// finished anyway. Like the FIFO mode, at most queueSize tasks may wait.
+//
+// Only cancellable tasks (tasks with a Run function that honors its context)
+// can be preempted. Tasks processed by the built-in simulation or a
+// TaskProcessor always run to completion.
+func NewWorkerPoolWithPreemption(numWorkers int, queueSize int) *WorkerPool {
+	wp := NewWorkerPool(numWorkers, queueSize)
+	wp.preemptive = true
+	wp.running = make(map[int]*runningTask)
+	wp.cond = sync.NewCond(&wp.mu)
+	wp.queueSpace = make(chan struct{}, 1)
+	return wp
+}
+
 // NewBatchProcessor creates a new BatchProcessor
 func NewBatchProcessor(batchSize int) *BatchProcessor {
 	return &BatchProcessor{
@@ -92,7 +157,11 @@ func NewBatchProcessor(batchSize int) *BatchProcessor {
 func (wp *WorkerPool) Start() {
 	for i := 0; i < wp.numWorkers; i++ {
 		wp.wg.Add(1)
-		go wp.worker(i)
+		if wp.preemptive {
+			go wp.preemptiveWorker(i)
+		} else {
+			go wp.worker(i)
+		}
 	}
 }
 
@@ -109,12 +178,10 @@ func (wp *WorkerPool) worker(workerID int) {
 			}
 			
 			// Process the task
-			result := wp.processTask(task, workerID)
+			result := wp.processTask(wp.ctx, task, workerID)
 			
 			// Send result
-			select {
-			case wp.resultChan <- result:
-			case <-wp.ctx.Done():
+			if !wp.sendResult(task, result) {
 				return
 			}
 			
@@ -125,13 +192,97 @@ func (wp *WorkerPool) worker(workerID int) {
 	}
 }
 
+// preemptiveWorker takes the highest-priority task and runs it with a
+// per-task context so that it can be preempted
+func (wp *WorkerPool) preemptiveWorker(workerID int) {
+	defer wp.wg.Done()
+
+	for {
+		wp.mu.Lock()
+		for wp.queue.Len() == 0 && !wp.closed {
+			wp.cond.Wait()
+		}
+		if wp.closed {
+			wp.mu.Unlock()
+			return
+		}
+
+		task := heap.Pop(&wp.queue).(queuedTask).task
+		wp.notifyQueueSpace()
+		taskCtx, cancel := context.WithCancel(wp.ctx)
+		rt := &runningTask{task: task, cancel: cancel}
+		wp.running[workerID] = rt
+		wp.mu.Unlock()
+
+		result := wp.processTask(taskCtx, task, workerID)
+		cancel()
+
+		wp.mu.Lock()
+		delete(wp.running, workerID)
+		// A task that ignored the cancellation and finished is not run again
+		preempted := rt.preempted && !wp.closed && errors.Is(result.Error, context.Canceled)
+		if preempted {
+			// Re-queue so the task runs again once higher-priority work is done.
+			// This may briefly exceed the queue bound, as the task was already accepted.
+			wp.push(task)
+			wp.cond.Signal()
+			atomic.AddInt64(&wp.preemptions, 1)
+		}
+		wp.mu.Unlock()
+
+		if preempted {
+			continue
+		}
+
+		if !wp.sendResult(task, result) {
+			return
+		}
+	}
+}
+
+// sendResult delivers a result to the task's reply channel or the pool's
+// result channel. It returns false if the pool was stopped first.
+func (wp *WorkerPool) sendResult(task Task, result Result) bool {
+	// Reply channels are buffered for every task of the caller
+	if task.reply != nil {
+		task.reply <- result
+		return true
+	}
+
+	select {
+	case wp.resultChan <- result:
+		return true
+	case <-wp.ctx.Done():
+		return false
+	}
+}
+
 // processTask processes a single task
-func (wp *WorkerPool) processTask(task Task, workerID int) Result {
+func (wp *WorkerPool) processTask(ctx context.Context, task Task, workerID int) Result {
 	start := time.Now()
 	
-	// Simulate work based on task data
 	var output interface{}
 	var err error
+	if task.Run == nil && wp.processor != nil {
+		output, err = wp.processor.Process(task.Data)
+	} else {
+		output, err = runTask(ctx, task)
+	}
+	
+	return Result{
+		TaskID:   task.ID,
+		Output:   output,
+		Error:    err,
+		Duration: time.Since(start),
+		WorkerID: workerID,
+	}
+}
+
+// runTask runs the task body, or simulates work based on task data
+func runTask(ctx context.Context, task Task) (interface{}, error) {
+	if task.Run != nil {
+		return task.Run(ctx)
+	}
 	
 	switch data := task.Data.(type) {
 	case string:
@@ -141,28 +292,24 @@ func (wp *WorkerPool) processTask(task Task, workerID int) Result {
 		} else {
 			time.Sleep(50 * time.Millisecond) // Simulate work
 		}
-		output = "processed: " + data
+		return "processed: " + data, nil
 	case int:
 		// Number processing
 		time.Sleep(100 * time.Millisecond) // Simulate work
-		output = data * 2
+		return data * 2, nil
 	default:
 		// Default processing
 		time.Sleep(30 * time.Millisecond)
-		output = fmt.Sprintf("processed_%v", data)
-	}
-	
-	return Result{
-		TaskID:   task.ID,
-		Output:   output,
-		Error:    err,
-		Duration: time.Since(start),
-		WorkerID: workerID,
+		return fmt.Sprintf("processed_%v", data), nil
 	}
 }
 
 // SubmitTask submits a task to the worker pool
 func (wp *WorkerPool) SubmitTask(task Task) error {
+	if wp.preemptive {
+		return wp.scheduleWait(wp.ctx, task, time.After(100*time.Millisecond))
+	}
+
 	select {
 	case wp.taskQueue <- task:
 		return nil
@@ -181,6 +328,76 @@ func (wp *WorkerPool) SubmitTask(task Task) error {
 	}
 }
 
+// TryMap processes every input on the worker pool and returns the results in
+// input order. Unlike a fail-fast variant, all inputs are processed and every
+// task error is collected into the returned error via errors.Join.
+func (wp *WorkerPool) TryMap(ctx context.Context, inputs []interface{}) ([]Result, error) {
+	results := make([]Result, len(inputs))
+	reply := make(chan Result, len(inputs))
+	
+	submitted := 0
+submit:
+	for i, input := range inputs {
+		if ctx.Err() != nil {
+			break
+		}
+		
+		task := Task{
+			ID:      i,
+			Data:    input,
+			Created: time.Now(),
+			reply:   reply,
+		}
+
+		if wp.preemptive {
+			if wp.scheduleWait(ctx, task, nil) != nil {
+				break
+			}
+			submitted++
+			continue
+		}
+		
+		select {
+		case wp.taskQueue <- task:
+			submitted++
+		case <-ctx.Done():
+			break submit
+		case <-wp.ctx.Done():
+			break submit
+		}
+	}
+	
+	received := make([]bool, len(inputs))
+collect:
+	for n := 0; n < submitted; n++ {
+		select {
+		case result := <-reply:
+			results[result.TaskID] = result
+			received[result.TaskID] = true
+		case <-ctx.Done():
+			break collect
+		case <-wp.ctx.Done():
+			break collect
+		}
+	}
+	
+	var errs []error
+	for i := range results {
+		if !received[i] {
+			err := ctx.Err()
+			if err == nil {
+				err = wp.ctx.Err()
+			}
+			results[i] = Result{TaskID: i, Error: err}
+		}
+		if results[i].Error != nil {
+			errs = append(errs, fmt.Errorf("input %d: %w", i, results[i].Error))
+		}
+	}
+	
+	return results, errors.Join(errs...)
+}
+
 // GetResult gets a result from the result channel
 func (wp *WorkerPool) GetResult() (Result, bool) {
 	select {
@@ -194,6 +411,13 @@ func (wp *WorkerPool) GetResult() (Result, bool) {
 // Stop gracefully stops the worker pool
 func (wp *WorkerPool) Stop() {
 	close(wp.taskQueue)
+	if wp.preemptive {
+		// Queued tasks are dropped; running ones are cancelled below
+		wp.mu.Lock()
+		wp.closed = true
+		wp.cond.Broadcast()
+		wp.mu.Unlock()
+	}
 	wp.cancel()
 	wp.wg.Wait()
 	close(wp.resultChan)
@@ -202,7 +426,7 @@ func (wp *WorkerPool) Stop() {
 // WaitForCompletion waits for all submitted tasks to complete
 func (wp *WorkerPool) WaitForCompletion() {
 	// Wait for task queue to be empty
-	for len(wp.taskQueue) > 0 {
+	for wp.queueLength() > 0 {
 		time.Sleep(10 * time.Millisecond)
 	}
 	
@@ -215,10 +439,128 @@ func (wp *WorkerPool) GetStats() PoolStats {
 	return PoolStats{
 		NumWorkers:  wp.numWorkers,
 		QueueSize:   cap(wp.taskQueue),
-		QueueLength: len(wp.taskQueue),
+		QueueLength: wp.queueLength(),
+	}
+}
+
+// queueLength returns the number of tasks waiting for a worker
+func (wp *WorkerPool) queueLength() int {
+	if !wp.preemptive {
+		return len(wp.taskQueue)
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.queue.Len()
+}
+
+// Preemptions returns how many running tasks have been preempted and re-queued
+func (wp *WorkerPool) Preemptions() int64 {
+	return atomic.LoadInt64(&wp.preemptions)
+}
+
+// errQueueFull is returned by schedule when the priority queue has no room
+var errQueueFull = errors.New("task queue is full")
+
+// scheduleWait schedules a task, waiting for room in the priority queue until
+// ctx is done or timeout fires. A nil timeout waits indefinitely.
+func (wp *WorkerPool) scheduleWait(ctx context.Context, task Task, timeout <-chan time.Time) error {
+	for {
+		err := wp.schedule(task)
+		if err != errQueueFull {
+			return err
+		}
+
+		select {
+		case <-wp.queueSpace:
+		case <-timeout:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wp.ctx.Done():
+			return wp.ctx.Err()
+		}
 	}
 }
 
+// schedule queues a task by priority and preempts a lower-priority
+// cancellable task if no worker is available to pick it up
+func (wp *WorkerPool) schedule(task Task) error {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if wp.closed {
+		return fmt.Errorf("worker pool is stopped")
+	}
+	if wp.queue.Len() >= cap(wp.taskQueue) {
+		return errQueueFull
+	}
+
+	wp.push(task)
+	if wp.queue.Len() < cap(wp.taskQueue) {
+		// Pass the wake-up on to the next waiting submitter
+		wp.notifyQueueSpace()
+	}
+
+	// Tasks waiting beyond the idle workers would have to wait for a running one
+	if len(wp.running)+wp.queue.Len() > wp.numWorkers {
+		wp.preemptFor(task)
+	}
+
+	wp.cond.Signal()
+	return nil
+}
+
+// push pushes a task into the priority queue; callers must hold wp.mu
+func (wp *WorkerPool) push(task Task) {
+	wp.seq++
+	heap.Push(&wp.queue, queuedTask{task: task, seq: wp.seq})
+}
+
+// preemptFor cancels the lowest-priority cancellable running task whose
+// priority is lower than the given task; callers must hold wp.mu
+func (wp *WorkerPool) preemptFor(task Task) {
+	var victim *runningTask
+	for _, rt := range wp.running {
+		if rt.preempted || rt.task.Run == nil || rt.task.Priority >= task.Priority {
+			continue
+		}
+		if victim == nil || rt.task.Priority < victim.task.Priority {
+			victim = rt
+		}
+	}
+
+	if victim != nil {
+		victim.preempted = true
+		victim.cancel()
+	}
+}
+
+// notifyQueueSpace wakes a submitter waiting for room in the priority queue
+func (wp *WorkerPool) notifyQueueSpace() {
+	select {
+	case wp.queueSpace <- struct{}{}:
+	default:
+	}
+}
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].task.Priority != h[j].task.Priority {
+		return h[i].task.Priority > h[j].task.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h taskHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(queuedTask)) }
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
 // SimpleTaskProcessor implementation
 func (stp *SimpleTaskProcessor) Process(data interface{}) (interface{}, error) {
 	switch v := data.(type) {
@@ -291,4 +633,4 @@ func (bp *BatchProcessor) ProcessBatch() []Result {
 	}
 	
 	return results
-}
\ No newline at end of file
+}
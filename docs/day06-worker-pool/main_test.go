@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -424,4 +429,299 @@ func TestWorkerPoolRace(t *testing.T) {
 	}
 	
 	wg.Wait()
-}
\ No newline at end of file
+}
+
+func TestWorkerPoolPreemption(t *testing.T) {
+	t.Run("High priority task preempts running low priority task", func(t *testing.T) {
+		const numWorkers = 2
+		pool := NewWorkerPoolWithPreemption(numWorkers, 10)
+		pool.Start()
+		defer pool.Stop()
+
+		var lowStarts int32
+		lowTask := func(id int) Task {
+			return Task{
+				ID:       id,
+				Priority: 1,
+				Run: func(ctx context.Context) (interface{}, error) {
+					atomic.AddInt32(&lowStarts, 1)
+					select {
+					case <-time.After(300 * time.Millisecond):
+						return "low done", nil
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					}
+				},
+			}
+		}
+
+		// Saturate all workers with long low-priority tasks
+		for i := 0; i < numWorkers; i++ {
+			if err := pool.SubmitTask(lowTask(i)); err != nil {
+				t.Fatalf("Failed to submit low priority task: %v", err)
+			}
+		}
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt32(&lowStarts) < numWorkers {
+			if time.Now().After(deadline) {
+				t.Fatal("Low priority tasks did not start")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		submitted := time.Now()
+		started := make(chan time.Time, 1)
+		err := pool.SubmitTask(Task{
+			ID:       100,
+			Priority: 10,
+			Run: func(ctx context.Context) (interface{}, error) {
+				started <- time.Now()
+				return "high done", nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to submit high priority task: %v", err)
+		}
+
+		select {
+		case at := <-started:
+			if wait := at.Sub(submitted); wait > 100*time.Millisecond {
+				t.Errorf("High priority task started too late: %v", wait)
+			}
+		case <-time.After(250 * time.Millisecond):
+			t.Fatal("High priority task should start promptly via preemption")
+		}
+
+		if pool.Preemptions() != 1 {
+			t.Errorf("Expected 1 preemption, got %d", pool.Preemptions())
+		}
+
+		// The high priority result comes first, then both low priority tasks complete
+		first, ok := pool.GetResult()
+		if !ok {
+			t.Fatal("Expected result but got none")
+		}
+		if first.TaskID != 100 {
+			t.Errorf("Expected high priority task result first, got task %d", first.TaskID)
+		}
+
+		for i := 0; i < numWorkers; i++ {
+			result, ok := pool.GetResult()
+			if !ok {
+				t.Fatalf("Expected low priority result %d but got none", i)
+			}
+			if result.Error != nil {
+				t.Errorf("Preempted task should be re-queued and complete, got error: %v", result.Error)
+			}
+		}
+
+		// The preempted task ran once more after re-queueing
+		if got := atomic.LoadInt32(&lowStarts); got != numWorkers+1 {
+			t.Errorf("Expected %d low priority starts, got %d", numWorkers+1, got)
+		}
+	})
+
+	t.Run("Non-cancellable tasks are not preempted", func(t *testing.T) {
+		pool := NewWorkerPoolWithPreemption(1, 10)
+		pool.Start()
+		defer pool.Stop()
+
+		pool.SubmitTask(Task{ID: 1, Data: "slow task", Priority: 1})
+		time.Sleep(20 * time.Millisecond)
+		pool.SubmitTask(Task{ID: 2, Data: "urgent", Priority: 10})
+
+		for _, expectedID := range []int{1, 2} {
+			result, ok := pool.GetResult()
+			if !ok {
+				t.Fatalf("Expected result for task %d but got none", expectedID)
+			}
+			if result.TaskID != expectedID {
+				t.Errorf("Expected task %d, got %d", expectedID, result.TaskID)
+			}
+		}
+
+		if pool.Preemptions() != 0 {
+			t.Errorf("Expected no preemptions, got %d", pool.Preemptions())
+		}
+	})
+
+	t.Run("Queued tasks run in priority order", func(t *testing.T) {
+		pool := NewWorkerPoolWithPreemption(1, 10)
+
+		for i, priority := range []int{1, 5, 3} {
+			pool.SubmitTask(Task{ID: i, Data: i, Priority: priority})
+		}
+		pool.Start()
+		defer pool.Stop()
+
+		for _, expectedID := range []int{1, 2, 0} {
+			result, ok := pool.GetResult()
+			if !ok {
+				t.Fatalf("Expected result for task %d but got none", expectedID)
+			}
+			if result.TaskID != expectedID {
+				t.Errorf("Expected task %d, got %d", expectedID, result.TaskID)
+			}
+		}
+	})
+
+	t.Run("Queue is bounded by queueSize", func(t *testing.T) {
+		pool := NewWorkerPoolWithPreemption(1, 2)
+		defer pool.Stop()
+
+		for i := 0; i < 2; i++ {
+			if err := pool.SubmitTask(Task{ID: i, Data: i}); err != nil {
+				t.Fatalf("Failed to submit task %d: %v", i, err)
+			}
+		}
+		if err := pool.SubmitTask(Task{ID: 2, Data: 2}); err == nil {
+			t.Error("Expected an error when the queue is full")
+		}
+		if stats := pool.GetStats(); stats.QueueLength != 2 {
+			t.Errorf("Expected queue length 2, got %d", stats.QueueLength)
+		}
+	})
+
+	t.Run("Preempted task that finishes anyway is not re-run", func(t *testing.T) {
+		pool := NewWorkerPoolWithPreemption(1, 10)
+		pool.Start()
+		defer pool.Stop()
+
+		var runs int32
+		started := make(chan struct{})
+		pool.SubmitTask(Task{
+			ID:       1,
+			Priority: 1,
+			Run: func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt32(&runs, 1)
+				close(started)
+				// Ignores the cancellation and completes its work
+				time.Sleep(50 * time.Millisecond)
+				return "low done", nil
+			},
+		})
+		<-started
+		pool.SubmitTask(Task{ID: 2, Data: "urgent", Priority: 10})
+
+		for _, expectedID := range []int{1, 2} {
+			result, ok := pool.GetResult()
+			if !ok {
+				t.Fatalf("Expected result for task %d but got none", expectedID)
+			}
+			if result.TaskID != expectedID {
+				t.Errorf("Expected task %d, got %d", expectedID, result.TaskID)
+			}
+		}
+
+		if got := atomic.LoadInt32(&runs); got != 1 {
+			t.Errorf("Expected the completed task to run once, ran %d times", got)
+		}
+		if pool.Preemptions() != 0 {
+			t.Errorf("Expected no preemptions, got %d", pool.Preemptions())
+		}
+	})
+}
+
+// failingProcessor fails for the configured inputs
+type failingProcessor struct {
+	failOn map[int]bool
+}
+
+func (fp *failingProcessor) Process(data interface{}) (interface{}, error) {
+	v := data.(int)
+	if fp.failOn[v] {
+		return nil, fmt.Errorf("cannot process %d", v)
+	}
+	return v * 10, nil
+}
+
+func TestWorkerPoolTryMap(t *testing.T) {
+	t.Run("Aggregates every task error", func(t *testing.T) {
+		processor := &failingProcessor{failOn: map[int]bool{1: true, 3: true, 4: true}}
+		pool := NewWorkerPoolWithProcessor(3, 2, processor)
+		pool.Start()
+		defer pool.Stop()
+
+		inputs := []interface{}{0, 1, 2, 3, 4, 5}
+		results, err := pool.TryMap(context.Background(), inputs)
+		if err == nil {
+			t.Fatal("Expected aggregated error")
+		}
+
+		for _, failed := range []int{1, 3, 4} {
+			want := fmt.Sprintf("cannot process %d", failed)
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected joined error to contain %q, got %q", want, err.Error())
+			}
+		}
+
+		if len(results) != len(inputs) {
+			t.Fatalf("Expected %d results, got %d", len(inputs), len(results))
+		}
+
+		for i, result := range results {
+			if result.TaskID != i {
+				t.Errorf("Result %d: expected TaskID %d, got %d", i, i, result.TaskID)
+			}
+			if processor.failOn[i] {
+				if result.Error == nil {
+					t.Errorf("Result %d: expected error", i)
+				}
+				continue
+			}
+			if result.Error != nil {
+				t.Errorf("Result %d: unexpected error %v", i, result.Error)
+			}
+			if result.Output != i*10 {
+				t.Errorf("Result %d: expected output %d, got %v", i, i*10, result.Output)
+			}
+		}
+	})
+
+	t.Run("No error when every task succeeds", func(t *testing.T) {
+		pool := NewWorkerPoolWithProcessor(2, 2, &failingProcessor{})
+		pool.Start()
+		defer pool.Stop()
+
+		results, err := pool.TryMap(context.Background(), []interface{}{1, 2, 3})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != 3 {
+			t.Errorf("Expected 3 results, got %d", len(results))
+		}
+	})
+
+	t.Run("Cancelled context reports unprocessed inputs", func(t *testing.T) {
+		pool := NewWorkerPool(1, 1)
+		pool.Start()
+		defer pool.Stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results, err := pool.TryMap(ctx, []interface{}{"a", "b"})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled in joined error, got %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected 2 results, got %d", len(results))
+		}
+	})
+
+	t.Run("Works in preemption mode", func(t *testing.T) {
+		pool := NewWorkerPoolWithPreemption(2, 2)
+		pool.Start()
+		defer pool.Stop()
+
+		results, err := pool.TryMap(context.Background(), []interface{}{1, 2, 3})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for i, result := range results {
+			if want := (i + 1) * 2; result.Output != want {
+				t.Errorf("Result %d: expected output %d, got %v", i, want, result.Output)
+			}
+		}
+	})
+}
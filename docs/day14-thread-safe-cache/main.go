@@ -53,6 +53,19 @@ func (cs *CacheStats) HitRate() float64 {
 	return float64(hits) / float64(total)
 }
 
+// SnapshotAndReset returns the current counters and resets them to zero.
+// Each counter is swapped atomically, so no increment made by concurrent
+// Get/Set calls is lost between two snapshots.
+func (cs *CacheStats) SnapshotAndReset() CacheStats {
+	return CacheStats{
+		hits:      atomic.SwapInt64(&cs.hits, 0),
+		misses:    atomic.SwapInt64(&cs.misses, 0),
+		evictions: atomic.SwapInt64(&cs.evictions, 0),
+		sets:      atomic.SwapInt64(&cs.sets, 0),
+		deletes:   atomic.SwapInt64(&cs.deletes, 0),
+	}
+}
+
 // cacheItem represents a cached item with metadata
 type cacheItem[K comparable, V any] struct {
 	key        K
@@ -202,6 +215,12 @@ func (c *Cache[K, V]) Stats() CacheStats {
 	}
 }
 
+// SnapshotAndReset returns the statistics accumulated since the previous
+// snapshot and starts a new interval
+func (c *Cache[K, V]) SnapshotAndReset() CacheStats {
+	return c.stats.SnapshotAndReset()
+}
+
 // CleanupExpired removes all expired items from the cache
 func (c *Cache[K, V]) CleanupExpired() int {
 	c.mu.Lock()
@@ -273,13 +292,14 @@ type CacheWithCleanup[K comparable, V any] struct {
 	cleanupInterval time.Duration
 	stopCleanup     chan struct{}
 	cleanupDone     chan struct{}
+	startOnce       sync.Once
+	stopOnce        sync.Once
 }
 
 // NewCacheWithCleanup creates a cache with automatic expired item cleanup
 func NewCacheWithCleanup[K comparable, V any](maxSize int, cleanupInterval time.Duration) *CacheWithCleanup[K, V] {
-	cache := NewCache[K, V](maxSize)
 	cwc := &CacheWithCleanup[K, V]{
-		Cache:           cache,
+		Cache:           NewCache[K, V](maxSize),
 		cleanupInterval: cleanupInterval,
 		stopCleanup:     make(chan struct{}),
 		cleanupDone:     make(chan struct{}),
@@ -288,30 +308,46 @@ func NewCacheWithCleanup[K comparable, V any](maxSize int, cleanupInterval time.
 	return cwc
 }
 
-// StartCleanup starts the background cleanup goroutine
+// StartCleanup starts the background cleanup goroutine.
+// Only the first call starts the goroutine; calls after Stop do nothing.
 func (cwc *CacheWithCleanup[K, V]) StartCleanup() {
-	go func() {
-		defer close(cwc.cleanupDone)
-		ticker := time.NewTicker(cwc.cleanupInterval)
-		defer ticker.Stop()
-		
-		for {
-			select {
-			case <-ticker.C:
-				cwc.CleanupExpired()
-			case <-cwc.stopCleanup:
-				return
+	cwc.startOnce.Do(func() {
+		go func() {
+			defer close(cwc.cleanupDone)
+			ticker := time.NewTicker(cwc.cleanupInterval)
+			defer ticker.Stop()
+			
+			for {
+				select {
+				case <-ticker.C:
+					cwc.CleanupExpired()
+				case <-cwc.stopCleanup:
+					return
+				}
 			}
-		}
-	}()
+		}()
+	})
 }
 
-// Stop stops the background cleanup goroutine
+// Stop stops the background cleanup goroutine and waits for it to exit.
+// It is safe to call Stop more than once.
 func (cwc *CacheWithCleanup[K, V]) Stop() {
-	close(cwc.stopCleanup)
+	cwc.stopOnce.Do(func() {
+		close(cwc.stopCleanup)
+	})
+	// 一度も起動していない場合は起動を封じてから完了扱いにする
+	cwc.startOnce.Do(func() {
+		close(cwc.cleanupDone)
+	})
 	<-cwc.cleanupDone
 }
 
+// Close stops the cleanup goroutine. It implements io.Closer.
+func (cwc *CacheWithCleanup[K, V]) Close() error {
+	cwc.Stop()
+	return nil
+}
+
 // LoadingCache extends Cache with loading functionality
 type LoadingCache[K comparable, V any] struct {
 	*Cache[K, V]
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -174,6 +175,100 @@ func TestCacheStats(t *testing.T) {
 			t.Errorf("Expected hit rate %.2f, got %.2f", expectedHitRate, hitRate)
 		}
 	})
+
+	t.Run("Snapshot and reset", func(t *testing.T) {
+		cache := NewCache[string, int](1)
+
+		cache.Set("a", 1, time.Hour)
+		cache.Set("b", 2, time.Hour) // evicts "a"
+		cache.Get("b")               // hit
+		cache.Get("a")               // miss
+
+		snapshot := cache.SnapshotAndReset()
+		if snapshot.GetHits() != 1 || snapshot.GetMisses() != 1 || snapshot.GetSets() != 2 || snapshot.GetEvictions() != 1 {
+			t.Errorf("Unexpected snapshot: hits=%d misses=%d sets=%d evictions=%d",
+				snapshot.GetHits(), snapshot.GetMisses(), snapshot.GetSets(), snapshot.GetEvictions())
+		}
+
+		stats := cache.Stats()
+		if stats.GetHits() != 0 || stats.GetMisses() != 0 || stats.GetSets() != 0 || stats.GetEvictions() != 0 {
+			t.Error("Expected counters to be zero after SnapshotAndReset")
+		}
+
+		// 次の区間は新しい操作だけを数える
+		cache.Get("b")
+		if snapshot := cache.SnapshotAndReset(); snapshot.GetHits() != 1 || snapshot.GetSets() != 0 {
+			t.Errorf("Expected interval delta of 1 hit, got hits=%d sets=%d", snapshot.GetHits(), snapshot.GetSets())
+		}
+	})
+
+	t.Run("Snapshots under load", func(t *testing.T) {
+		cache := NewCache[int, int](50)
+		var gets, sets int64
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				for j := 0; j < 2000; j++ {
+					key := (id*31 + j) % 100
+					if j%3 == 0 {
+						cache.Set(key, j, time.Hour)
+						atomic.AddInt64(&sets, 1)
+					} else {
+						cache.Get(key)
+						atomic.AddInt64(&gets, 1)
+					}
+				}
+			}(i)
+		}
+
+		// 監視ループを模して定期的にスナップショットを取る
+		var total CacheStats
+		snapshots := 0
+		stop := make(chan struct{})
+		monitorDone := make(chan struct{})
+		collect := func(s CacheStats) {
+			total.hits += s.GetHits()
+			total.misses += s.GetMisses()
+			total.sets += s.GetSets()
+			total.evictions += s.GetEvictions()
+			snapshots++
+		}
+		go func() {
+			defer close(monitorDone)
+			ticker := time.NewTicker(time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					collect(cache.SnapshotAndReset())
+				case <-stop:
+					return
+				}
+			}
+		}()
+
+		wg.Wait()
+		close(stop)
+		<-monitorDone
+		collect(cache.SnapshotAndReset())
+
+		if snapshots < 2 {
+			t.Logf("Only %d snapshots were taken", snapshots)
+		}
+		if total.hits+total.misses != gets {
+			t.Errorf("Expected hits+misses across snapshots to equal %d gets, got %d", gets, total.hits+total.misses)
+		}
+		if total.sets != sets {
+			t.Errorf("Expected %d sets across snapshots, got %d", sets, total.sets)
+		}
+		// 容量50に対して100種類のキーを書き込むため、退避が必ず発生する
+		if total.evictions == 0 {
+			t.Error("Expected evictions to be counted across snapshots")
+		}
+	})
 }
 
 func TestCacheConcurrency(t *testing.T) {
@@ -338,6 +433,65 @@ func TestCacheWithCleanup(t *testing.T) {
 			t.Error("Expected long-lived item to remain")
 		}
 	})
+
+	t.Run("Stop terminates cleanup goroutine", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		
+		caches := make([]*CacheWithCleanup[string, int], 5)
+		for i := range caches {
+			caches[i] = NewCacheWithCleanup[string, int](10, 10*time.Millisecond)
+		}
+		if running := runtime.NumGoroutine(); running < before+len(caches) {
+			t.Errorf("Expected at least %d goroutines while running, got %d", before+len(caches), running)
+		}
+		
+		for _, cache := range caches {
+			cache.Stop()
+		}
+		
+		// Stopは終了を待つが、ランタイムへの反映をわずかに待つ
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("Goroutine leak: %d before, %d after Stop", before, after)
+		}
+	})
+	
+	t.Run("Double Stop and Close", func(t *testing.T) {
+		cache := NewCacheWithCleanup[string, int](10, 10*time.Millisecond)
+		
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			cache.Stop()
+			cache.Stop()
+			if err := cache.Close(); err != nil {
+				t.Errorf("Expected nil error from Close, got %v", err)
+			}
+			cache.StartCleanup() // no-op after Stop
+			cache.Stop()
+		}()
+		
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Repeated Stop did not return")
+		}
+	})
+	
+	t.Run("No cleanup after Stop", func(t *testing.T) {
+		cache := NewCacheWithCleanup[string, int](10, 10*time.Millisecond)
+		cache.Stop()
+		
+		cache.Set("short", 1, 5*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		
+		if cache.Size() != 1 {
+			t.Errorf("Expected expired item to remain after Stop, got size %d", cache.Size())
+		}
+	})
 }
 
 func TestLoadingCache(t *testing.T) {
@@ -300,6 +300,86 @@ func Chain[T any](generators ...Generator[T]) Generator[T] {
 	})
 }
 
+// Merge interleaves values from multiple generators as they arrive.
+// The order between sources is not guaranteed.
+func Merge[T any](generators ...Generator[T]) Generator[T] {
+	return NewGenerator(func(ctx context.Context, yield func(T) bool) {
+		// Stop all upstreams when the merged generator finishes or is cancelled
+		defer func() {
+			for _, gen := range generators {
+				gen.Cancel()
+			}
+		}()
+
+		merged := make(chan T)
+		var wg sync.WaitGroup
+		for _, gen := range generators {
+			wg.Add(1)
+			go func(gen Generator[T]) {
+				defer wg.Done()
+				for value := range gen.ch {
+					select {
+					case merged <- value:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}(gen)
+		}
+
+		go func() {
+			wg.Wait()
+			close(merged)
+		}()
+
+		for value := range merged {
+			if !yield(value) {
+				return
+			}
+		}
+	})
+}
+
+// teeBufferSize is how far the faster branch of Tee may run ahead of the slower one
+const teeBufferSize = 8
+
+// Tee duplicates a generator into two streams, reading the source only once.
+// A slow consumer blocks the fast one once teeBufferSize values are buffered,
+// so both branches must be consumed concurrently.
+// Cancelling either branch cancels the other and the source.
+func Tee[T any](gen Generator[T]) (Generator[T], Generator[T]) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch1 := make(chan T, teeBufferSize)
+	ch2 := make(chan T, teeBufferSize)
+
+	go func() {
+		defer close(ch1)
+		defer close(ch2)
+		defer gen.Cancel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case value, ok := <-gen.ch:
+				if !ok {
+					return
+				}
+				for _, ch := range []chan T{ch1, ch2} {
+					select {
+					case ch <- value:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return Generator[T]{ch: ch1, cancel: cancel, ctx: ctx},
+		Generator[T]{ch: ch2, cancel: cancel, ctx: ctx}
+}
+
 // Zip combines two generators into pairs
 func Zip[T, U any](gen1 Generator[T], gen2 Generator[U]) Generator[Pair[T, U]] {
 	return NewGenerator(func(ctx context.Context, yield func(Pair[T, U]) bool) {
@@ -331,6 +411,42 @@ type Pair[T, U any] struct {
 	Second U
 }
 
+// Error handling
+
+// Result carries either a value or the error that occurred while producing it
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// ResultGenerator is a generator of values that may carry errors.
+// The embedded Generator can be passed to the existing combinators.
+type ResultGenerator[T any] struct {
+	Generator[Result[T]]
+}
+
+// MapErr transforms each value with a fallible function, emitting the error as a Result
+func MapErr[T, U any](gen Generator[T], fn func(T) (U, error)) ResultGenerator[U] {
+	return ResultGenerator[U]{Map(gen, func(value T) Result[U] {
+		transformed, err := fn(value)
+		return Result[U]{Value: transformed, Err: err}
+	})}
+}
+
+// CollectErr collects values until the first error and then cancels the generator
+func CollectErr[T any](gen ResultGenerator[T]) ([]T, error) {
+	defer gen.Cancel()
+
+	var values []T
+	for result := range gen.ch {
+		if result.Err != nil {
+			return values, result.Err
+		}
+		values = append(values, result.Value)
+	}
+	return values, nil
+}
+
 // Aggregate functions
 
 // Reduce reduces the generator to a single value
@@ -342,6 +458,29 @@ func Reduce[T, U any](gen Generator[T], initial U, fn func(U, T) U) U {
 	return accumulator
 }
 
+// Scan emits the running accumulation after each value
+func Scan[T, U any](gen Generator[T], initial U, fn func(U, T) U) Generator[U] {
+	return NewGenerator(func(ctx context.Context, yield func(U) bool) {
+		defer gen.Cancel()
+
+		accumulator := initial
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case value, ok := <-gen.ch:
+				if !ok {
+					return
+				}
+				accumulator = fn(accumulator, value)
+				if !yield(accumulator) {
+					return
+				}
+			}
+		}
+	})
+}
+
 // Count counts the number of values in the generator
 func Count[T any](gen Generator[T]) int {
 	count := 0
@@ -403,6 +542,73 @@ func Batch[T any](gen Generator[T], size int) Generator[[]T] {
 	})
 }
 
+// Window groups values into overlapping windows of the given size, advancing by step.
+// An incomplete trailing window is dropped.
+func Window[T any](gen Generator[T], size, step int) Generator[[]T] {
+	return window(gen, size, step, false)
+}
+
+// WindowPartial behaves like Window but also yields the incomplete trailing windows
+func WindowPartial[T any](gen Generator[T], size, step int) Generator[[]T] {
+	return window(gen, size, step, true)
+}
+
+func window[T any](gen Generator[T], size, step int, partial bool) Generator[[]T] {
+	return NewGenerator(func(ctx context.Context, yield func([]T) bool) {
+		if size <= 0 || step <= 0 {
+			return
+		}
+
+		buf := make([]T, 0, size)
+		skip := 0
+
+		// advance drops step elements from the head of the window.
+		// When step exceeds the buffered length, the remainder is skipped from upstream.
+		advance := func() {
+			if step >= len(buf) {
+				skip = step - len(buf)
+				buf = buf[:0]
+				return
+			}
+			buf = append(buf[:0], buf[step:]...)
+		}
+
+		for value := range gen.ch {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				if skip > 0 {
+					skip--
+					continue
+				}
+				buf = append(buf, value)
+				if len(buf) == size {
+					// Copy so that consumers can safely retain the slice
+					w := make([]T, size)
+					copy(w, buf)
+					if !yield(w) {
+						return
+					}
+					advance()
+				}
+			}
+		}
+
+		if !partial {
+			return
+		}
+		for len(buf) > 0 {
+			w := make([]T, len(buf))
+			copy(w, buf)
+			if !yield(w) {
+				return
+			}
+			advance()
+		}
+	})
+}
+
 // Distinct removes duplicate values
 func Distinct[T comparable](gen Generator[T]) Generator[T] {
 	return NewGenerator(func(ctx context.Context, yield func(T) bool) {
@@ -426,6 +632,66 @@ func Distinct[T comparable](gen Generator[T]) Generator[T] {
 	})
 }
 
+// GroupByKey groups all values by key and yields one pair per key, in order of
+// first appearance. It must drain the entire upstream before yielding anything,
+// so memory grows with the length of the stream and it never completes on an
+// infinite generator. Use GroupByConsecutive when input is already clustered.
+func GroupByKey[T any, K comparable](gen Generator[T], keyFn func(T) K) Generator[Pair[K, []T]] {
+	return NewGenerator(func(ctx context.Context, yield func(Pair[K, []T]) bool) {
+		groups := make(map[K][]T)
+		var order []K
+		for value := range gen.ch {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				key := keyFn(value)
+				if _, ok := groups[key]; !ok {
+					order = append(order, key)
+				}
+				groups[key] = append(groups[key], value)
+			}
+		}
+
+		for _, key := range order {
+			if !yield(Pair[K, []T]{First: key, Second: groups[key]}) {
+				return
+			}
+		}
+	})
+}
+
+// GroupByConsecutive groups runs of adjacent values that share a key. Only the
+// current run is buffered, so a key that reappears later starts a new group.
+func GroupByConsecutive[T any, K comparable](gen Generator[T], keyFn func(T) K) Generator[Pair[K, []T]] {
+	return NewGenerator(func(ctx context.Context, yield func(Pair[K, []T]) bool) {
+		var (
+			current K
+			run     []T
+		)
+		for value := range gen.ch {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				key := keyFn(value)
+				if len(run) > 0 && key != current {
+					if !yield(Pair[K, []T]{First: current, Second: run}) {
+						return
+					}
+					run = nil
+				}
+				current = key
+				run = append(run, value)
+			}
+		}
+
+		if len(run) > 0 {
+			yield(Pair[K, []T]{First: current, Second: run})
+		}
+	})
+}
+
 // Parallel processes values in parallel
 func Parallel[T, U any](gen Generator[T], fn func(T) U, workers int) Generator[U] {
 	return NewGenerator(func(ctx context.Context, yield func(U) bool) {
@@ -483,6 +749,200 @@ func Parallel[T, U any](gen Generator[T], fn func(T) U, workers int) Generator[U
 	})
 }
 
+// ParallelOptions configures ParallelWithOptions
+type ParallelOptions struct {
+	// Workers is the number of goroutines spawned per call when Executor is nil.
+	// With an Executor it only sizes the output buffer.
+	Workers int
+	// Executor, when set, runs fn on a shared bounded pool instead of
+	// spawning fresh worker goroutines for every call.
+	Executor *Executor
+}
+
+// ParallelWithOptions processes values in parallel using the given options.
+// Output order is not preserved.
+func ParallelWithOptions[T, U any](gen Generator[T], fn func(T) U, opts ParallelOptions) Generator[U] {
+	if opts.Executor == nil {
+		return Parallel(gen, fn, opts.Workers)
+	}
+
+	exec := opts.Executor
+	bufSize := opts.Workers
+	if bufSize <= 0 {
+		bufSize = exec.workers
+	}
+
+	return NewGenerator(func(ctx context.Context, yield func(U) bool) {
+		output := make(chan U, bufSize)
+
+		// Submit one task per input value; the executor bounds concurrency
+		go func() {
+			var inflight sync.WaitGroup
+			defer func() {
+				inflight.Wait()
+				close(output)
+			}()
+
+			for value := range gen.ch {
+				value := value
+				inflight.Add(1)
+				task := func() {
+					defer inflight.Done()
+					result := fn(value)
+					select {
+					case output <- result:
+					case <-ctx.Done():
+						// Cancelled: drop the result so the pool worker is freed
+					}
+				}
+				if !exec.Submit(ctx, task) {
+					inflight.Done()
+					return
+				}
+			}
+		}()
+
+		// Yield results
+		for result := range output {
+			if !yield(result) {
+				return
+			}
+		}
+	})
+}
+
+// Executor is a bounded pool of goroutines that can be shared across
+// ParallelWithOptions calls to avoid spawning workers on every call.
+type Executor struct {
+	workers int
+	tasks   chan func()
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+// NewExecutor starts an executor with the given number of workers
+func NewExecutor(workers int) *Executor {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	e := &Executor{
+		workers: workers,
+		tasks:   make(chan func()),
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			for {
+				select {
+				case task := <-e.tasks:
+					task()
+				case <-e.done:
+					return
+				}
+			}
+		}()
+	}
+
+	return e
+}
+
+// Submit blocks until a worker accepts the task. It returns false without
+// running the task if ctx is cancelled or the executor has been closed.
+func (e *Executor) Submit(ctx context.Context, task func()) bool {
+	select {
+	case e.tasks <- task:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-e.done:
+		return false
+	}
+}
+
+// Close stops the workers and waits for running tasks to finish
+func (e *Executor) Close() {
+	e.once.Do(func() {
+		close(e.done)
+	})
+	e.wg.Wait()
+}
+
+// ParallelOrdered processes values in parallel while preserving input order.
+// Results are reassembled with per-item sequence numbers, so a slow early
+// item stalls the output until it completes.
+func ParallelOrdered[T, U any](gen Generator[T], fn func(T) U, workers int) Generator[U] {
+	return NewGenerator(func(ctx context.Context, yield func(U) bool) {
+		input := make(chan sequenced[T], workers)
+		output := make(chan sequenced[U], workers)
+
+		// Start workers
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for in := range input {
+					result := sequenced[U]{seq: in.seq, value: fn(in.value)}
+					select {
+					case output <- result:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		// Feed input with sequence numbers
+		go func() {
+			defer close(input)
+			seq := 0
+			for value := range gen.ch {
+				select {
+				case input <- sequenced[T]{seq: seq, value: value}:
+					seq++
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		// Close output when all workers done
+		go func() {
+			wg.Wait()
+			close(output)
+		}()
+
+		// Reorder buffer: hold results until every earlier one has been yielded
+		pending := make(map[int]U)
+		next := 0
+		for result := range output {
+			pending[result.seq] = result.value
+			for {
+				value, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !yield(value) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// sequenced tags a value with its position in the input stream
+type sequenced[T any] struct {
+	seq   int
+	value T
+}
+
 // Buffer buffers values to improve throughput
 func Buffer[T any](gen Generator[T], size int) Generator[T] {
 	return NewGenerator(func(ctx context.Context, yield func(T) bool) {
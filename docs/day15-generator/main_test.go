@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"sync"
 	"testing"
 	"time"
@@ -300,6 +302,118 @@ func TestComposition(t *testing.T) {
 		}
 	})
 	
+	t.Run("Merge timer generators", func(t *testing.T) {
+		fast := Take(Timer(10*time.Millisecond), 4)
+		slow := Take(Timer(25*time.Millisecond), 2)
+
+		merged := Merge(fast, slow)
+
+		count := 0
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			merged.ForEach(func(time.Time) {
+				count++
+			})
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(1 * time.Second):
+			t.Fatal("Merge should close after every input is exhausted")
+		}
+
+		if count != 4+2 {
+			t.Errorf("Expected %d values, got %d", 4+2, count)
+		}
+	})
+
+	t.Run("Merge cancellation stops upstreams", func(t *testing.T) {
+		gen1 := Repeat(1)
+		gen2 := Repeat(2)
+		merged := Merge(gen1, gen2)
+
+		for i := 0; i < 10; i++ {
+			if _, ok := merged.Next(); !ok {
+				t.Fatal("Merged generator closed unexpectedly")
+			}
+		}
+		merged.Cancel()
+
+		for _, gen := range []Generator[int]{gen1, gen2} {
+			select {
+			case <-gen.ctx.Done():
+			case <-time.After(1 * time.Second):
+				t.Error("Upstream generator should have been cancelled")
+			}
+		}
+	})
+
+	t.Run("Tee generator", func(t *testing.T) {
+		var calls int
+		source := NewGenerator(func(ctx context.Context, yield func(int) bool) {
+			for i := 1; i <= 100; i++ {
+				calls++
+				if !yield(i) {
+					return
+				}
+			}
+		})
+
+		left, right := Tee(source)
+
+		var sum, count int
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sum = Reduce(left, 0, func(acc, x int) int {
+				return acc + x
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			count = Count(right)
+		}()
+		wg.Wait()
+
+		if count != 100 {
+			t.Errorf("Expected count 100, got %d", count)
+		}
+		if sum != 5050 {
+			t.Errorf("Expected sum 5050, got %d", sum)
+		}
+		if calls != 100 {
+			t.Errorf("Expected source to run once (100 values), got %d", calls)
+		}
+	})
+
+	t.Run("Tee cancellation", func(t *testing.T) {
+		source := Repeat(1)
+		left, right := Tee(source)
+
+		left.Next()
+		right.Cancel()
+
+		select {
+		case <-source.ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("Cancelling one branch should cancel the source")
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			left.ToSlice()
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(100 * time.Millisecond):
+			t.Error("Both branches should close after cancellation")
+		}
+	})
+
 	t.Run("Zip generators", func(t *testing.T) {
 		gen1 := Range(1, 3)
 		gen2 := FromSlice([]string{"a", "b", "c"})
@@ -349,6 +463,55 @@ func TestAggregations(t *testing.T) {
 		}
 	})
 	
+	t.Run("Scan", func(t *testing.T) {
+		gen := Scan(Range(1, 4), 0, func(acc, x int) int {
+			return acc + x
+		})
+		values := gen.ToSlice()
+
+		expected := []int{1, 3, 6, 10}
+		if len(values) != len(expected) {
+			t.Fatalf("Expected %d values, got %d: %v", len(expected), len(values), values)
+		}
+
+		for i, v := range values {
+			if v != expected[i] {
+				t.Errorf("Expected %d at index %d, got %d", expected[i], i, v)
+			}
+		}
+	})
+
+	t.Run("Scan cancellation", func(t *testing.T) {
+		source := Repeat(1)
+		gen := Scan(source, 0, func(acc, x int) int {
+			return acc + x
+		})
+
+		for i := 0; i < 3; i++ {
+			gen.Next()
+		}
+		gen.Cancel()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range gen.Chan() {
+			}
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("Scan should stop emitting promptly after cancellation")
+		}
+
+		select {
+		case <-source.ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+			t.Error("Scan should cancel its source generator")
+		}
+	})
+
 	t.Run("Count", func(t *testing.T) {
 		count := Count(Range(1, 100))
 		
@@ -394,6 +557,63 @@ func TestAggregations(t *testing.T) {
 	})
 }
 
+func TestErrorHandling(t *testing.T) {
+	t.Run("CollectErr stops at first error", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		gen := MapErr(Range(1, 5), func(x int) (string, error) {
+			if x == 3 {
+				return "", errBoom
+			}
+			return fmt.Sprintf("v%d", x), nil
+		})
+
+		values, err := CollectErr(gen)
+		if !errors.Is(err, errBoom) {
+			t.Errorf("Expected error %v, got %v", errBoom, err)
+		}
+
+		expected := []string{"v1", "v2"}
+		if len(values) != len(expected) {
+			t.Fatalf("Expected %d values, got %d: %v", len(expected), len(values), values)
+		}
+		for i, v := range values {
+			if v != expected[i] {
+				t.Errorf("Expected %s at index %d, got %s", expected[i], i, v)
+			}
+		}
+	})
+
+	t.Run("CollectErr without errors", func(t *testing.T) {
+		gen := MapErr(Range(1, 3), func(x int) (int, error) {
+			return x * 2, nil
+		})
+
+		values, err := CollectErr(gen)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fmt.Sprint(values) != fmt.Sprint([]int{2, 4, 6}) {
+			t.Errorf("Expected [2 4 6], got %v", values)
+		}
+	})
+
+	t.Run("ResultGenerator works with existing combinators", func(t *testing.T) {
+		gen := MapErr(Range(1, 6), func(x int) (int, error) {
+			if x%2 == 0 {
+				return 0, fmt.Errorf("even %d", x)
+			}
+			return x, nil
+		})
+
+		failures := Filter(gen.Generator, func(r Result[int]) bool {
+			return r.Err != nil
+		})
+		if count := Count(failures); count != 3 {
+			t.Errorf("Expected 3 failed results, got %d", count)
+		}
+	})
+}
+
 func TestAdvancedFeatures(t *testing.T) {
 	t.Run("Batch", func(t *testing.T) {
 		gen := Batch(Range(1, 10), 3)
@@ -423,6 +643,67 @@ func TestAdvancedFeatures(t *testing.T) {
 		}
 	})
 	
+	t.Run("Window", func(t *testing.T) {
+		windows := Window(Range(1, 5), 3, 1).ToSlice()
+
+		expected := [][]int{
+			{1, 2, 3},
+			{2, 3, 4},
+			{3, 4, 5},
+		}
+
+		if len(windows) != len(expected) {
+			t.Fatalf("Expected %d windows, got %d: %v", len(expected), len(windows), windows)
+		}
+
+		for i, w := range windows {
+			if len(w) != len(expected[i]) {
+				t.Errorf("Window %d: expected length %d, got %d", i, len(expected[i]), len(w))
+				continue
+			}
+			for j, v := range w {
+				if v != expected[i][j] {
+					t.Errorf("Window %d[%d]: expected %d, got %d", i, j, expected[i][j], v)
+				}
+			}
+		}
+	})
+
+	t.Run("Window slices are independent copies", func(t *testing.T) {
+		windows := Window(Range(1, 5), 3, 1).ToSlice()
+		if len(windows) != 3 {
+			t.Fatalf("Expected 3 windows, got %d", len(windows))
+		}
+
+		windows[0][1] = 100
+		if windows[1][0] != 2 {
+			t.Errorf("Modifying one window affected another: %v", windows)
+		}
+	})
+
+	t.Run("Window with step larger than size", func(t *testing.T) {
+		windows := Window(Range(1, 10), 2, 3).ToSlice()
+
+		expected := [][]int{{1, 2}, {4, 5}, {7, 8}}
+		if fmt.Sprint(windows) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, windows)
+		}
+	})
+
+	t.Run("WindowPartial keeps trailing windows", func(t *testing.T) {
+		windows := WindowPartial(Range(1, 5), 3, 2).ToSlice()
+
+		expected := [][]int{{1, 2, 3}, {3, 4, 5}, {5}}
+		if fmt.Sprint(windows) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, windows)
+		}
+
+		dropped := Window(Range(1, 5), 3, 2).ToSlice()
+		if fmt.Sprint(dropped) != fmt.Sprint([][]int{{1, 2, 3}, {3, 4, 5}}) {
+			t.Errorf("Expected trailing window to be dropped, got %v", dropped)
+		}
+	})
+
 	t.Run("Distinct", func(t *testing.T) {
 		input := []int{1, 2, 2, 3, 1, 4, 3, 5}
 		gen := Distinct(FromSlice(input))
@@ -441,6 +722,50 @@ func TestAdvancedFeatures(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("GroupByKey", func(t *testing.T) {
+		parity := func(n int) string {
+			if n%2 == 0 {
+				return "even"
+			}
+			return "odd"
+		}
+
+		interleaved := GroupByKey(FromSlice([]int{1, 2, 3, 4, 5, 6}), parity).ToSlice()
+		expected := "[{odd [1 3 5]} {even [2 4 6]}]"
+		if fmt.Sprint(interleaved) != expected {
+			t.Errorf("Expected %s, got %v", expected, interleaved)
+		}
+
+		clustered := GroupByKey(FromSlice([]int{2, 4, 1, 3, 6}), parity).ToSlice()
+		expected = "[{even [2 4 6]} {odd [1 3]}]"
+		if fmt.Sprint(clustered) != expected {
+			t.Errorf("Expected %s, got %v", expected, clustered)
+		}
+	})
+
+	t.Run("GroupByConsecutive", func(t *testing.T) {
+		firstLetter := func(s string) byte { return s[0] }
+
+		clustered := GroupByConsecutive(FromSlice([]string{"apple", "avocado", "banana", "blueberry", "cherry"}), firstLetter).ToSlice()
+		if len(clustered) != 3 {
+			t.Fatalf("Expected 3 groups, got %d: %v", len(clustered), clustered)
+		}
+		if fmt.Sprint(clustered[0].Second) != "[apple avocado]" || fmt.Sprint(clustered[2].Second) != "[cherry]" {
+			t.Errorf("Unexpected clustered grouping: %v", clustered)
+		}
+
+		// Interleaved keys are not merged: each run becomes its own group
+		interleaved := GroupByConsecutive(FromSlice([]int{1, 1, 2, 1, 3, 3}), func(n int) int { return n }).ToSlice()
+		expected := "[{1 [1 1]} {2 [2]} {1 [1]} {3 [3 3]}]"
+		if fmt.Sprint(interleaved) != expected {
+			t.Errorf("Expected %s, got %v", expected, interleaved)
+		}
+
+		if groups := GroupByConsecutive(FromSlice([]int{}), func(n int) int { return n }).ToSlice(); len(groups) != 0 {
+			t.Errorf("Expected no groups for empty input, got %v", groups)
+		}
+	})
 	
 	t.Run("Buffer", func(t *testing.T) {
 		gen := Buffer(Range(1, 5), 2)
@@ -639,6 +964,160 @@ func TestConcurrentAccess(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("Parallel ordered processing", func(t *testing.T) {
+		square := func(x int) int {
+			return x * x
+		}
+		delays := make(map[int]time.Duration)
+		for i := 1; i <= 20; i++ {
+			delays[i] = time.Duration(rand.Intn(10)) * time.Millisecond
+		}
+
+		values := ParallelOrdered(Range(1, 20), func(x int) int {
+			time.Sleep(delays[x])
+			return square(x)
+		}, 4).ToSlice()
+
+		expected := Map(Range(1, 20), square).ToSlice()
+		if len(values) != len(expected) {
+			t.Fatalf("Expected %d values, got %d", len(expected), len(values))
+		}
+
+		for i, v := range values {
+			if v != expected[i] {
+				t.Errorf("Expected %d at index %d, got %d", expected[i], i, v)
+			}
+		}
+	})
+}
+
+func TestParallelExecutor(t *testing.T) {
+	square := func(x int) int {
+		return x * x
+	}
+	sorted := func(values []int) []int {
+		result := append([]int(nil), values...)
+		for i := 1; i < len(result); i++ {
+			for j := i; j > 0 && result[j-1] > result[j]; j-- {
+				result[j-1], result[j] = result[j], result[j-1]
+			}
+		}
+		return result
+	}
+
+	t.Run("Same output as per-call workers", func(t *testing.T) {
+		exec := NewExecutor(4)
+		defer exec.Close()
+
+		expected := sorted(Parallel(Range(1, 50), square, 4).ToSlice())
+
+		// Repeated calls share the same pool
+		for call := 0; call < 5; call++ {
+			values := sorted(ParallelWithOptions(Range(1, 50), square, ParallelOptions{Executor: exec}).ToSlice())
+			if len(values) != len(expected) {
+				t.Fatalf("Call %d: expected %d values, got %d", call, len(expected), len(values))
+			}
+			for i := range expected {
+				if values[i] != expected[i] {
+					t.Errorf("Call %d: expected %d at index %d, got %d", call, expected[i], i, values[i])
+				}
+			}
+		}
+	})
+
+	t.Run("Concurrent calls share executor", func(t *testing.T) {
+		exec := NewExecutor(3)
+		defer exec.Close()
+
+		var wg sync.WaitGroup
+		sums := make([]int, 8)
+		for i := range sums {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				gen := ParallelWithOptions(Range(1, 100), square, ParallelOptions{Workers: 2, Executor: exec})
+				sums[i] = Reduce(gen, 0, func(acc, x int) int { return acc + x })
+			}(i)
+		}
+		wg.Wait()
+
+		for i, sum := range sums {
+			if sum != 338350 {
+				t.Errorf("Call %d: expected sum of squares 338350, got %d", i, sum)
+			}
+		}
+	})
+
+	t.Run("Nil executor falls back to Parallel", func(t *testing.T) {
+		values := ParallelWithOptions(Range(1, 10), square, ParallelOptions{Workers: 3}).ToSlice()
+		if len(values) != 10 {
+			t.Errorf("Expected 10 values, got %d", len(values))
+		}
+	})
+
+	t.Run("Cancellation stops workers", func(t *testing.T) {
+		exec := NewExecutor(2)
+		defer exec.Close()
+
+		var mu sync.Mutex
+		calls := 0
+		slow := func(x int) int {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			return x
+		}
+		callCount := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return calls
+		}
+
+		gen := ParallelWithOptions(Range(1, 1000), slow, ParallelOptions{Executor: exec})
+		for i := 0; i < 5; i++ {
+			if _, ok := gen.Next(); !ok {
+				t.Fatal("Generator ended early")
+			}
+		}
+		gen.Cancel()
+
+		// Let in-flight tasks finish, then make sure nothing new is started
+		time.Sleep(20 * time.Millisecond)
+		afterCancel := callCount()
+		time.Sleep(20 * time.Millisecond)
+		if callCount() != afterCancel {
+			t.Errorf("Workers kept processing after cancel: %d -> %d calls", afterCancel, callCount())
+		}
+		if afterCancel >= 1000 {
+			t.Errorf("Expected cancellation to stop processing early, got %d calls", afterCancel)
+		}
+
+		// The pool must still be usable by other pipelines
+		done := make(chan int)
+		go func() {
+			done <- Count(ParallelWithOptions(Range(1, 20), square, ParallelOptions{Executor: exec}))
+		}()
+		select {
+		case n := <-done:
+			if n != 20 {
+				t.Errorf("Expected 20 values after cancellation, got %d", n)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Executor workers were not released after cancellation")
+		}
+	})
+
+	t.Run("Submit after Close", func(t *testing.T) {
+		exec := NewExecutor(1)
+		exec.Close()
+		exec.Close() // idempotent
+
+		if exec.Submit(context.Background(), func() {}) {
+			t.Error("Expected Submit to fail on a closed executor")
+		}
+	})
 }
 
 // Benchmark tests
@@ -688,4 +1167,28 @@ func BenchmarkParallel(b *testing.B) {
 		}, 4)
 		Count(gen)
 	}
-}
\ No newline at end of file
+}
+
+func BenchmarkParallelExecutor(b *testing.B) {
+	square := func(x int) int {
+		return x * x
+	}
+
+	b.Run("Spawn", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Count(Parallel(Range(1, 100), square, 4))
+		}
+	})
+
+	b.Run("SharedExecutor", func(b *testing.B) {
+		exec := NewExecutor(4)
+		defer exec.Close()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			Count(ParallelWithOptions(Range(1, 100), square, ParallelOptions{Workers: 4, Executor: exec}))
+		}
+	})
+}
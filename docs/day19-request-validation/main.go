@@ -3,14 +3,23 @@
 package main
 
 import (
+	"bytes"
+	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // TODO: Request Validation システムを実装してください
@@ -22,6 +31,7 @@ import (
 // 4. パフォーマンス最適化
 // 5. 詳細なエラー情報
 
+// ValidationError はバリデーションエラーの詳細情報
 type ValidationError struct {
 	Field    string                 `json:"field"`
 	Message  string                 `json:"message"`
@@ -30,12 +40,14 @@ type ValidationError struct {
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// ValidationResult はバリデーション結果
 type ValidationResult struct {
 	IsValid  bool              `json:"is_valid"`
 	Errors   []ValidationError `json:"errors,omitempty"`
 	Warnings []ValidationError `json:"warnings,omitempty"`
 }
 
+// RequestValidator はリクエストバリデーター
 type RequestValidator struct {
 	customValidators map[string]ValidatorFunc
 	businessRules    []BusinessRule
@@ -45,261 +57,975 @@ type RequestValidator struct {
 	metrics         *ValidationMetrics
 }
 
+// ValidatorFunc はカスタムバリデーター関数
 type ValidatorFunc func(interface{}) bool
 
+// BusinessRule はビジネスルールインターフェース
 type BusinessRule interface {
 	Validate(interface{}) []ValidationError
 }
 
+// SecurityRule はセキュリティルールインターフェース
 type SecurityRule interface {
 	Validate(interface{}, *http.Request) []ValidationError
 }
 
+// Translator は翻訳インターフェース
 type Translator interface {
 	Translate(code, lang string, params map[string]interface{}) string
 }
 
-// TODO: RequestValidator を初期化
+// languageSupporter は対応言語を判定できる翻訳器（Accept-Languageの交渉に使用）
+type languageSupporter interface {
+	SupportsLanguage(lang string) bool
+}
+
+// ErrValidationFailed はバリデーション失敗時に ValidateRequest が返すエラー
+var ErrValidationFailed = errors.New("validation failed")
+
+// builtinRules は構造バリデーション層で処理する組み込みルール
+var builtinRules = map[string]bool{
+	"required":  true,
+	"omitempty": true,
+	"min":       true,
+	"max":       true,
+	"email":     true,
+}
+
+// customValidatorCodes はカスタムバリデーター名とエラーコードの対応
+var customValidatorCodes = map[string]string{
+	"password_strength": "WEAK_PASSWORD",
+	"url":               "INVALID_URL",
+	"phone":             "INVALID_PHONE",
+}
+
+// NewRequestValidator はRequestValidatorを初期化
 func NewRequestValidator() *RequestValidator {
-	// ヒント: 各フィールドを初期化し、デフォルトのバリデーターを設定
-	return nil
+	translator := NewSimpleTranslator("en")
+	setupDefaultTranslations(translator)
+	
+	rv := &RequestValidator{
+		customValidators: make(map[string]ValidatorFunc),
+		businessRules:    make([]BusinessRule, 0),
+		securityRules:    make([]SecurityRule, 0),
+		translator:       translator,
+		cache:           NewValidationCache(5 * time.Minute),
+		metrics:         NewValidationMetrics(),
+	}
+	
+	// デフォルトバリデーターを設定
+	rv.setupDefaultValidators()
+	
+	return rv
 }
 
-// TODO: カスタムバリデーターを登録
+// setupDefaultValidators はデフォルトバリデーターを設定
+func (rv *RequestValidator) setupDefaultValidators() {
+	rv.customValidators["email"] = EmailValidator
+	rv.customValidators["password_strength"] = PasswordStrengthValidator
+	rv.customValidators["url"] = URLValidator
+	rv.customValidators["phone"] = PhoneValidator
+}
+
+// RegisterValidator はカスタムバリデーターを登録
+// キャッシュ済みの結果は古いバリデーターによるものなので破棄する
 func (rv *RequestValidator) RegisterValidator(name string, fn ValidatorFunc) {
-	// ヒント: customValidators マップに追加
+	rv.customValidators[name] = fn
+	rv.cache.Clear()
 }
 
-// TODO: ビジネスルールを追加
+// AddBusinessRule はビジネスルールを追加
 func (rv *RequestValidator) AddBusinessRule(rule BusinessRule) {
-	// ヒント: businessRules スライスに追加
+	rv.businessRules = append(rv.businessRules, rule)
 }
 
-// TODO: セキュリティルールを追加
+// AddSecurityRule はセキュリティルールを追加
 func (rv *RequestValidator) AddSecurityRule(rule SecurityRule) {
-	// ヒント: securityRules スライスに追加
+	rv.securityRules = append(rv.securityRules, rule)
 }
 
-// TODO: リクエストをバリデーション
+// ValidateRequest はリクエストをバリデーション
+// 各層は順番に実行され、最初に失敗した層でエラーレスポンスを書き込んで ErrValidationFailed を返す
+// lang が空の場合は Accept-Language ヘッダーからメッセージの言語を決定する
 func (rv *RequestValidator) ValidateRequest(w http.ResponseWriter, r *http.Request, target interface{}, lang string) error {
-	// ヒント:
+	start := time.Now()
+	
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	
+	fail := func(errorType string, errs []ValidationError) error {
+		rv.metrics.RecordError(errorType, time.Since(start))
+		return rv.writeErrorResponse(w, errs, lang)
+	}
+	
 	// 1. Content-Type検証
-	// 2. JSON デコード
-	// 3. 構造バリデーション
-	// 4. カスタムバリデーション
+	contentType := r.Header.Get("Content-Type")
+	if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return fail("content_type", []ValidationError{{
+				Field:   "Content-Type",
+				Message: "Invalid content type",
+				Code:    "INVALID_CONTENT_TYPE",
+				Value:   contentType,
+			}})
+		}
+	}
+	
+	// 2. JSONデコード
+	var payload []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err == nil {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.DisallowUnknownFields()
+			err = decoder.Decode(target)
+		}
+		if err != nil {
+			return fail("json_decode", []ValidationError{{
+				Field:   "body",
+				Message: "Invalid JSON format",
+				Code:    "INVALID_JSON",
+				Value:   err.Error(),
+			}})
+		}
+		payload = body
+	}
+	
+	// 3-4. 構造・カスタムバリデーション（ペイロードのみで決まるためキャッシュ可能）
+	result := rv.validatePayload(target, payload)
+	if !result.IsValid {
+		// writeErrorResponse はメッセージを書き換えるため、キャッシュ内のスライスは渡さない
+		return fail("validation", append([]ValidationError(nil), result.Errors...))
+	}
+	
 	// 5. ビジネスルールバリデーション
+	var businessErrors []ValidationError
+	for _, rule := range rv.businessRules {
+		businessErrors = append(businessErrors, rule.Validate(target)...)
+	}
+	if len(businessErrors) > 0 {
+		return fail("business", businessErrors)
+	}
+	
 	// 6. セキュリティバリデーション
-	// 7. エラーレスポンス作成
+	var securityErrors []ValidationError
+	for _, rule := range rv.securityRules {
+		securityErrors = append(securityErrors, rule.Validate(target, r)...)
+	}
+	if len(securityErrors) > 0 {
+		return fail("security", securityErrors)
+	}
 	
+	rv.metrics.RecordSuccess(time.Since(start))
 	return nil
 }
 
-// TODO: 構造バリデーション
+// validatePayload は構造・カスタムバリデーションを実行し、結果をペイロード単位でキャッシュ
+// ビジネスルールやセキュリティルールは外部状態に依存するためキャッシュしない
+// ボディがない場合は target の既存の値が検証対象になり、キーで区別できないためキャッシュしない
+func (rv *RequestValidator) validatePayload(target interface{}, payload []byte) ValidationResult {
+	if payload == nil {
+		return rv.validateTarget(target)
+	}
+	
+	key := generateCacheKey(target, payload)
+	if result, ok := rv.cache.Get(key); ok {
+		rv.metrics.RecordCacheHit()
+		return result
+	}
+	rv.metrics.RecordCacheMiss()
+	
+	result := rv.validateTarget(target)
+	rv.cache.Set(key, result)
+	return result
+}
+
+// validateTarget は構造バリデーション、成功した場合はカスタムバリデーションを実行
+func (rv *RequestValidator) validateTarget(target interface{}) ValidationResult {
+	errs := rv.validateStruct(target)
+	if len(errs) == 0 {
+		errs = rv.validateCustom(target)
+	}
+	return ValidationResult{IsValid: len(errs) == 0, Errors: errs}
+}
+
+// fieldRules はvalidateタグを持つフィールドとそのルール
+type fieldRules struct {
+	name  string
+	value interface{}
+	rules []string
+}
+
+// isOptionalEmpty はomitemptyが指定され、かつ値が空かチェック
+func (fr fieldRules) isOptionalEmpty() bool {
+	for _, rule := range fr.rules {
+		if rule == "omitempty" {
+			return isEmpty(fr.value)
+		}
+	}
+	return false
+}
+
+// collectFieldRules はvalidateタグを持つフィールドを列挙
+// ネストした構造体やスライスの要素も再帰的に辿り、"items[0].quantity" のようなパスで名前を付ける
+func collectFieldRules(data interface{}) []fieldRules {
+	return appendFieldRules(nil, reflect.ValueOf(data), "")
+}
+
+// appendFieldRules は構造体のフィールドを再帰的に走査
+func appendFieldRules(fields []fieldRules, val reflect.Value, prefix string) []fieldRules {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return fields
+		}
+		val = val.Elem()
+	}
+	
+	if val.Kind() != reflect.Struct {
+		return fields
+	}
+	
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		
+		name := prefix + jsonFieldName(field)
+		fieldValue := val.Field(i)
+		
+		if validateTag := field.Tag.Get("validate"); validateTag != "" {
+			fields = append(fields, fieldRules{
+				name:  name,
+				value: fieldValue.Interface(),
+				rules: parseValidationTags(validateTag),
+			})
+		}
+		
+		// ネストした構造体・スライス要素を再帰的に検証
+		switch indirectKind(fieldValue) {
+		case reflect.Struct:
+			fields = appendFieldRules(fields, fieldValue, name+".")
+		case reflect.Slice, reflect.Array:
+			elems := reflect.Indirect(fieldValue)
+			for j := 0; j < elems.Len(); j++ {
+				fields = appendFieldRules(fields, elems.Index(j), fmt.Sprintf("%s[%d].", name, j))
+			}
+		}
+	}
+	
+	return fields
+}
+
+// indirectKind はポインタを辿った先の値の種類を返す
+func indirectKind(val reflect.Value) reflect.Kind {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Invalid
+		}
+		val = val.Elem()
+	}
+	return val.Kind()
+}
+
+// jsonFieldName はJSONタグからフィールド名を取得
+func jsonFieldName(field reflect.StructField) string {
+	jsonTag := field.Tag.Get("json")
+	if jsonTag != "" && jsonTag != "-" {
+		if name := strings.Split(jsonTag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// validateStruct は構造体バリデーション（組み込みルールのみ）
 func (rv *RequestValidator) validateStruct(data interface{}) []ValidationError {
-	// ヒント: リフレクションを使用して構造体フィールドをバリデーション
+	var errors []ValidationError
+	
+	for _, fr := range collectFieldRules(data) {
+		if fr.isOptionalEmpty() {
+			continue
+		}
+		
+		for _, rule := range fr.rules {
+			if err := rv.validateField(fr.name, fr.value, rule); err != nil {
+				errors = append(errors, *err)
+				break // 1フィールドにつき最初のエラーのみ報告
+			}
+		}
+	}
+	
+	return errors
+}
+
+// validateField は単一フィールドのバリデーション
+func (rv *RequestValidator) validateField(fieldName string, value interface{}, rule string) *ValidationError {
+	parts := strings.SplitN(rule, "=", 2)
+	ruleName := parts[0]
+	var ruleValue string
+	if len(parts) > 1 {
+		ruleValue = parts[1]
+	}
+	
+	switch ruleName {
+	case "required":
+		if isEmpty(value) {
+			return &ValidationError{
+				Field:   fieldName,
+				Message: "Field is required",
+				Code:    "REQUIRED",
+				Value:   value,
+			}
+		}
+	case "min":
+		if minVal, err := strconv.ParseFloat(ruleValue, 64); err == nil {
+			if !validateMin(value, minVal) {
+				return &ValidationError{
+					Field:   fieldName,
+					Message: fmt.Sprintf("Minimum value is %v", minVal),
+					Code:    "MIN_VALUE",
+					Value:   value,
+					Metadata: map[string]interface{}{"min": minVal},
+				}
+			}
+		}
+	case "max":
+		if maxVal, err := strconv.ParseFloat(ruleValue, 64); err == nil {
+			if !validateMax(value, maxVal) {
+				return &ValidationError{
+					Field:   fieldName,
+					Message: fmt.Sprintf("Maximum value is %v", maxVal),
+					Code:    "MAX_VALUE",
+					Value:   value,
+					Metadata: map[string]interface{}{"max": maxVal},
+				}
+			}
+		}
+	case "email":
+		validator, ok := rv.customValidators["email"]
+		if !ok {
+			validator = EmailValidator
+		}
+		if !validator(value) {
+			return &ValidationError{
+				Field:   fieldName,
+				Message: "Invalid email format",
+				Code:    "INVALID_EMAIL",
+				Value:   value,
+			}
+		}
+	}
+	
 	return nil
 }
 
-// TODO: カスタムバリデーション
+// validateCustom は登録済みカスタムバリデーターをタグに従って実行
 func (rv *RequestValidator) validateCustom(data interface{}) []ValidationError {
-	// ヒント: 登録されたカスタムバリデーターを実行
-	return nil
+	var errors []ValidationError
+	
+	for _, fr := range collectFieldRules(data) {
+		if fr.isOptionalEmpty() {
+			continue
+		}
+		
+		for _, rule := range fr.rules {
+			name := strings.SplitN(rule, "=", 2)[0]
+			if builtinRules[name] {
+				continue
+			}
+			
+			validator, ok := rv.customValidators[name]
+			if !ok || validator(fr.value) {
+				continue
+			}
+			
+			code, ok := customValidatorCodes[name]
+			if !ok {
+				code = "INVALID_" + strings.ToUpper(name)
+			}
+			
+			value := fr.value
+			if name == "password_strength" {
+				value = "***" // パスワードはレスポンスに含めない
+			}
+			
+			errors = append(errors, ValidationError{
+				Field:   fr.name,
+				Message: fmt.Sprintf("Validation %q failed", name),
+				Code:    code,
+				Value:   value,
+			})
+			break
+		}
+	}
+	
+	return errors
 }
 
-// TODO: エラーレスポンスを作成
+// writeErrorResponse はエラーレスポンスを作成し、ErrValidationFailed を返す
+// lang は "ja" のような言語タグ、または "ja-JP,ja;q=0.9,en;q=0.8" のような Accept-Language の値
 func (rv *RequestValidator) writeErrorResponse(w http.ResponseWriter, errors []ValidationError, lang string) error {
-	// ヒント:
-	// 1. エラーメッセージを翻訳
-	// 2. JSONレスポンスを作成
-	// 3. 適切なHTTPステータスコードを設定
+	lang = rv.negotiateLanguage(lang)
 	
-	return nil
+	// エラーメッセージを翻訳（翻訳がない場合は元のメッセージを維持）
+	for i := range errors {
+		params := map[string]interface{}{"field": errors[i].Field}
+		for k, v := range errors[i].Metadata {
+			params[k] = v
+		}
+		
+		if message := rv.translator.Translate(errors[i].Code, lang, params); message != errors[i].Code {
+			errors[i].Message = message
+		}
+	}
+	
+	response := map[string]interface{}{
+		"error":   "validation_failed",
+		"details": errors,
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	if lang != "" {
+		w.Header().Set("Content-Language", lang)
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		return fmt.Errorf("%w: write response: %v", ErrValidationFailed, err)
+	}
+	
+	return fmt.Errorf("%w: %s: %s", ErrValidationFailed, errors[0].Field, errors[0].Code)
+}
+
+// negotiateLanguage は Accept-Language の候補から翻訳器が対応する言語を選択
+// 対応言語が判定できない翻訳器の場合は最も優先度の高い候補を返す
+func (rv *RequestValidator) negotiateLanguage(accept string) string {
+	candidates := parseAcceptLanguage(accept)
+	if len(candidates) == 0 {
+		return ""
+	}
+	
+	supporter, ok := rv.translator.(languageSupporter)
+	if !ok {
+		return candidates[0]
+	}
+	
+	for _, candidate := range candidates {
+		if supporter.SupportsLanguage(candidate) {
+			return candidate
+		}
+		if base, _, found := strings.Cut(candidate, "-"); found && supporter.SupportsLanguage(base) {
+			return base
+		}
+	}
+	
+	return ""
+}
+
+// parseAcceptLanguage は Accept-Language を品質値(q)の高い順の言語タグに分解
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+	
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
 }
 
 // バリデーションキャッシュ
 
+// ValidationCache はバリデーション結果のキャッシュ
 type ValidationCache struct {
 	cache sync.Map
 	ttl   time.Duration
 }
 
+// CachedValidationResult はキャッシュされたバリデーション結果
 type CachedValidationResult struct {
 	Result    ValidationResult
 	ExpiresAt time.Time
 }
 
-// TODO: キャッシュを初期化
+// NewValidationCache はキャッシュを初期化
 func NewValidationCache(ttl time.Duration) *ValidationCache {
-	return nil
+	return &ValidationCache{
+		ttl: ttl,
+	}
 }
 
-// TODO: キャッシュから取得
+// Get はキャッシュから取得
+// 期限切れのエントリはアクセス時に削除する
 func (vc *ValidationCache) Get(key string) (ValidationResult, bool) {
-	// ヒント: sync.Map を使用し、TTLをチェック
-	return ValidationResult{}, false
+	value, ok := vc.cache.Load(key)
+	if !ok {
+		return ValidationResult{}, false
+	}
+	
+	cached := value.(*CachedValidationResult)
+	if !time.Now().Before(cached.ExpiresAt) {
+		vc.cache.CompareAndDelete(key, value)
+		return ValidationResult{}, false
+	}
+	
+	return cached.Result, true
 }
 
-// TODO: キャッシュに保存
+// Set はキャッシュに保存
 func (vc *ValidationCache) Set(key string, result ValidationResult) {
-	// ヒント: 有効期限付きで保存
+	vc.cache.Store(key, &CachedValidationResult{
+		Result:    result,
+		ExpiresAt: time.Now().Add(vc.ttl),
+	})
+}
+
+// Clear は全てのエントリを削除
+func (vc *ValidationCache) Clear() {
+	vc.cache.Range(func(key, _ interface{}) bool {
+		vc.cache.Delete(key)
+		return true
+	})
 }
 
 // バリデーションメトリクス
 
+// ValidationMetrics はバリデーションメトリクス
 type ValidationMetrics struct {
 	totalValidations int64
 	successCount     int64
 	errorCount       int64
 	avgDuration      time.Duration
+	cacheHits        int64
+	cacheMisses      int64
 	mu              sync.RWMutex
+	totalDuration   time.Duration
 }
 
-// TODO: メトリクスを初期化
+// NewValidationMetrics はメトリクスを初期化
 func NewValidationMetrics() *ValidationMetrics {
-	return nil
+	return &ValidationMetrics{}
 }
 
-// TODO: バリデーション成功を記録
+// RecordSuccess はバリデーション成功を記録
 func (vm *ValidationMetrics) RecordSuccess(duration time.Duration) {
-	// ヒント: カウンターを更新し、平均時間を計算
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	
+	vm.totalValidations++
+	vm.successCount++
+	vm.totalDuration += duration
+	vm.avgDuration = time.Duration(int64(vm.totalDuration) / vm.totalValidations)
 }
 
-// TODO: バリデーションエラーを記録
+// RecordError はバリデーションエラーを記録
 func (vm *ValidationMetrics) RecordError(errorType string, duration time.Duration) {
-	// ヒント: エラーカウンターを更新
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	
+	vm.totalValidations++
+	vm.errorCount++
+	vm.totalDuration += duration
+	vm.avgDuration = time.Duration(int64(vm.totalDuration) / vm.totalValidations)
+}
+
+// RecordCacheHit はキャッシュヒットを記録
+func (vm *ValidationMetrics) RecordCacheHit() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	
+	vm.cacheHits++
+}
+
+// RecordCacheMiss はキャッシュミスを記録
+func (vm *ValidationMetrics) RecordCacheMiss() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	
+	vm.cacheMisses++
 }
 
-// TODO: メトリクスを取得
+// GetMetrics はメトリクスを取得
 func (vm *ValidationMetrics) GetMetrics() map[string]interface{} {
-	// ヒント: 現在のメトリクス情報を返す
-	return nil
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	
+	successRate := float64(0)
+	if vm.totalValidations > 0 {
+		successRate = float64(vm.successCount) / float64(vm.totalValidations) * 100
+	}
+	
+	cacheHitRate := float64(0)
+	if lookups := vm.cacheHits + vm.cacheMisses; lookups > 0 {
+		cacheHitRate = float64(vm.cacheHits) / float64(lookups) * 100
+	}
+	
+	return map[string]interface{}{
+		"total_validations": vm.totalValidations,
+		"success_count":     vm.successCount,
+		"error_count":       vm.errorCount,
+		"success_rate":      successRate,
+		"avg_duration_ms":   float64(vm.avgDuration.Nanoseconds()) / 1e6,
+		"cache_hits":        vm.cacheHits,
+		"cache_misses":      vm.cacheMisses,
+		"cache_hit_rate":    cacheHitRate,
+	}
 }
 
 // 翻訳機能
 
+// SimpleTranslator は簡単な翻訳器
 type SimpleTranslator struct {
 	translations map[string]map[string]string
+	languages    map[string]bool
 	defaultLang  string
+	mu          sync.RWMutex
 }
 
-// TODO: 翻訳器を初期化
+// NewSimpleTranslator は翻訳器を初期化
 func NewSimpleTranslator(defaultLang string) *SimpleTranslator {
-	// ヒント: デフォルトの翻訳を設定
-	return nil
+	return &SimpleTranslator{
+		translations: make(map[string]map[string]string),
+		languages:    make(map[string]bool),
+		defaultLang:  strings.ToLower(defaultLang),
+	}
 }
 
-// TODO: メッセージを翻訳
+// Translate はメッセージを翻訳
+// 指定言語 → 地域を除いた言語（"ja-JP" → "ja"）→ デフォルト言語 → コードの順にフォールバック
 func (st *SimpleTranslator) Translate(code, lang string, params map[string]interface{}) string {
-	// ヒント:
-	// 1. 指定言語の翻訳を検索
-	// 2. なければデフォルト言語を使用
-	// 3. パラメータを置換
+	st.mu.RLock()
+	defer st.mu.RUnlock()
 	
-	return code // 仮の実装
+	if langMap, exists := st.translations[code]; exists {
+		lang = strings.ToLower(lang)
+		base, _, _ := strings.Cut(lang, "-")
+		
+		for _, candidate := range []string{lang, base, st.defaultLang} {
+			if message, exists := langMap[candidate]; exists {
+				return st.interpolate(message, params)
+			}
+		}
+	}
+	
+	// 翻訳が見つからない場合はコードをそのまま返す
+	return code
 }
 
-// TODO: 翻訳を追加
+// AddTranslation は翻訳を追加
 func (st *SimpleTranslator) AddTranslation(code, lang, message string) {
-	// ヒント: translations マップに追加
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	
+	if st.translations[code] == nil {
+		st.translations[code] = make(map[string]string)
+	}
+	st.translations[code][strings.ToLower(lang)] = message
+	st.languages[strings.ToLower(lang)] = true
+}
+
+// SupportsLanguage は翻訳が1件以上登録されている言語か判定
+func (st *SimpleTranslator) SupportsLanguage(lang string) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	
+	return st.languages[strings.ToLower(lang)]
+}
+
+// interpolate はパラメータを置換（"{min}" 形式と "{{.min}}" 形式に対応）
+func (st *SimpleTranslator) interpolate(message string, params map[string]interface{}) string {
+	if params == nil {
+		return message
+	}
+	
+	result := message
+	for key, value := range params {
+		replacement := fmt.Sprintf("%v", value)
+		result = strings.ReplaceAll(result, "{{."+key+"}}", replacement)
+		result = strings.ReplaceAll(result, "{"+key+"}", replacement)
+	}
+	
+	return result
 }
 
 // カスタムバリデーター関数
 
-// TODO: メールアドレスバリデーター
+// EmailValidator はメールアドレスバリデーター
 func EmailValidator(value interface{}) bool {
-	// ヒント: 正規表現でメールアドレス形式をチェック
+	if str, ok := value.(string); ok {
+		emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+		return emailRegex.MatchString(str)
+	}
 	return false
 }
 
-// TODO: パスワード強度バリデーター
+// PasswordStrengthValidator はパスワード強度バリデーター
 func PasswordStrengthValidator(value interface{}) bool {
-	// ヒント:
-	// 1. 最低8文字
-	// 2. 大文字、小文字、数字、記号を含む
+	if str, ok := value.(string); ok {
+		// 最低8文字
+		if len(str) < 8 {
+			return false
+		}
+		
+		// 大文字、小文字、数字、記号を含む
+		hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(str)
+		hasLower := regexp.MustCompile(`[a-z]`).MatchString(str)
+		hasDigit := regexp.MustCompile(`\d`).MatchString(str)
+		hasSpecial := regexp.MustCompile(`[!@#$%^&*()_+\-=\[\]{};':"\\|,.<>\/?]`).MatchString(str)
+		
+		return hasUpper && hasLower && hasDigit && hasSpecial
+	}
 	return false
 }
 
-// TODO: URL バリデーター
+// URLValidator はURLバリデーター
 func URLValidator(value interface{}) bool {
-	// ヒント: url.Parse を使用
+	if str, ok := value.(string); ok {
+		if str == "" {
+			return true // omitemptyの場合は空文字を許可
+		}
+		u, err := url.ParseRequestURI(str)
+		if err != nil {
+			return false
+		}
+		return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+	}
 	return false
 }
 
-// TODO: 電話番号バリデーター
+// PhoneValidator は電話番号バリデーター
 func PhoneValidator(value interface{}) bool {
-	// ヒント: 国際電話番号形式をチェック
+	if str, ok := value.(string); ok {
+		if str == "" {
+			return true // omitemptyの場合は空文字を許可
+		}
+		// 国際電話番号形式をチェック
+		phoneRegex := regexp.MustCompile(`^\+?[\d\s\-\(\)]{10,15}$`)
+		return phoneRegex.MatchString(str)
+	}
 	return false
 }
 
 // ビジネスルール
 
+// UserUniquenessRule はユーザー一意性ルール
 type UserUniquenessRule struct {
 	userRepository UserRepository
 }
 
+// UserRepository はユーザーリポジトリインターフェース
 type UserRepository interface {
 	ExistsByEmail(email string) (bool, error)
 	ExistsByUsername(username string) (bool, error)
 }
 
-// TODO: ユーザー一意性ルール
+// Validate はユーザー一意性ルールを実行
 func (uur *UserUniquenessRule) Validate(data interface{}) []ValidationError {
-	// ヒント:
-	// 1. データから User 構造体を取得
-	// 2. メールアドレスとユーザー名の重複をチェック
-	// 3. 重複があればエラーを返す
+	var errors []ValidationError
 	
-	return nil
+	if user, ok := data.(*User); ok {
+		// メールアドレスの重複チェック
+		if exists, err := uur.userRepository.ExistsByEmail(user.Email); err == nil && exists {
+			errors = append(errors, ValidationError{
+				Field:   "email",
+				Message: "Email address already exists",
+				Code:    "EMAIL_EXISTS",
+				Value:   user.Email,
+			})
+		}
+		
+		// ユーザー名の重複チェック
+		if exists, err := uur.userRepository.ExistsByUsername(user.Username); err == nil && exists {
+			errors = append(errors, ValidationError{
+				Field:   "username",
+				Message: "Username already exists",
+				Code:    "USERNAME_EXISTS",
+				Value:   user.Username,
+			})
+		}
+	}
+	
+	return errors
 }
 
+// ProductAvailabilityRule は商品在庫ルール
 type ProductAvailabilityRule struct {
 	productRepository ProductRepository
 }
 
+// ProductRepository は商品リポジトリインターフェース
 type ProductRepository interface {
 	GetStock(productID string) (int, error)
 	IsActive(productID string) (bool, error)
 }
 
-// TODO: 商品在庫ルール
+// Validate は商品在庫ルールを実行
 func (par *ProductAvailabilityRule) Validate(data interface{}) []ValidationError {
-	// ヒント: 注文データから商品在庫をチェック
-	return nil
+	var errors []ValidationError
+	
+	if order, ok := data.(*Order); ok {
+		for i, item := range order.Items {
+			// 商品がアクティブかチェック
+			if active, err := par.productRepository.IsActive(item.ProductID); err == nil && !active {
+				errors = append(errors, ValidationError{
+					Field:   fmt.Sprintf("items[%d].product_id", i),
+					Message: "Product is not available",
+					Code:    "PRODUCT_INACTIVE",
+					Value:   item.ProductID,
+				})
+				continue
+			}
+			
+			// 在庫チェック
+			if stock, err := par.productRepository.GetStock(item.ProductID); err == nil {
+				if stock < item.Quantity {
+					errors = append(errors, ValidationError{
+						Field:   fmt.Sprintf("items[%d].quantity", i),
+						Message: "Insufficient stock",
+						Code:    "INSUFFICIENT_STOCK",
+						Value:   item.Quantity,
+						Metadata: map[string]interface{}{
+							"available": stock,
+							"requested": item.Quantity,
+						},
+					})
+				}
+			}
+		}
+	}
+	
+	return errors
 }
 
 // セキュリティルール
 
+// RateLimitRule はレート制限ルール
 type RateLimitRule struct {
 	limitChecker RateLimitChecker
 }
 
+// RateLimitChecker はレート制限チェッカーインターフェース
 type RateLimitChecker interface {
 	IsAllowed(clientIP string) bool
 }
 
-// TODO: レート制限ルール
+// Validate はレート制限ルールを実行
 func (rlr *RateLimitRule) Validate(data interface{}, r *http.Request) []ValidationError {
-	// ヒント:
-	// 1. クライアントIPを取得
-	// 2. レート制限をチェック
-	// 3. 制限超過時はエラーを返す
+	clientIP := getClientIP(r)
+	
+	if !rlr.limitChecker.IsAllowed(clientIP) {
+		return []ValidationError{{
+			Field:   "rate_limit",
+			Message: "Rate limit exceeded",
+			Code:    "RATE_LIMIT_EXCEEDED",
+			Metadata: map[string]interface{}{
+				"client_ip": clientIP,
+			},
+		}}
+	}
 	
 	return nil
 }
 
+// SQLInjectionRule はSQLインジェクション検出ルール
 type SQLInjectionRule struct{}
 
-// TODO: SQLインジェクション検出ルール
+// Validate はネストしたフィールドを含むすべての文字列フィールドからSQLインジェクションを検出
 func (sir *SQLInjectionRule) Validate(data interface{}, r *http.Request) []ValidationError {
-	// ヒント:
-	// 1. 文字列フィールドからSQL構文を検出
-	// 2. 危険なパターンがあればエラーを返す
+	return scanStringFields(data, containsSQLInjectionPattern, "SQL_INJECTION", "Potential SQL injection detected")
+}
+
+// XSSRule はクロスサイトスクリプティング検出ルール
+type XSSRule struct{}
+
+// Validate はネストしたフィールドを含むすべての文字列フィールドからXSSパターンを検出
+func (xr *XSSRule) Validate(data interface{}, r *http.Request) []ValidationError {
+	return scanStringFields(data, containsXSSPattern, "XSS_DETECTED", "Potential XSS detected")
+}
+
+// scanStringFields は文字列フィールドを検査し、検出したフィールドごとにセキュリティエラーを返す
+func scanStringFields(data interface{}, detect func(string) bool, code, message string) []ValidationError {
+	var errors []ValidationError
 	
-	return nil
+	walkStringFields(reflect.ValueOf(data), "", func(name, value string) {
+		if detect(value) {
+			errors = append(errors, ValidationError{
+				Field:    name,
+				Message:  message,
+				Code:     code,
+				Value:    "***", // 攻撃ペイロードはレスポンスに含めない
+				Metadata: map[string]interface{}{"category": "security"},
+			})
+		}
+	})
+	
+	return errors
+}
+
+// walkStringFields は構造体・スライスを再帰的に辿り、文字列フィールドごとに fn を呼び出す
+func walkStringFields(val reflect.Value, name string, fn func(name, value string)) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return
+		}
+		val = val.Elem()
+	}
+	
+	switch val.Kind() {
+	case reflect.String:
+		fn(name, val.String())
+	case reflect.Struct:
+		typ := val.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			
+			fieldName := jsonFieldName(field)
+			if name != "" {
+				fieldName = name + "." + fieldName
+			}
+			walkStringFields(val.Field(i), fieldName, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			walkStringFields(val.Index(i), fmt.Sprintf("%s[%d]", name, i), fn)
+		}
+	}
 }
 
 // データ構造
 
+// User はユーザー情報
 type User struct {
 	ID       string `json:"id" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
@@ -311,6 +1037,7 @@ type User struct {
 	Phone    string `json:"phone" validate:"omitempty,phone"`
 }
 
+// Order は注文情報
 type Order struct {
 	ID     string      `json:"id"`
 	UserID string      `json:"user_id" validate:"required"`
@@ -318,6 +1045,7 @@ type Order struct {
 	Total  float64     `json:"total" validate:"required,min=0"`
 }
 
+// OrderItem は注文アイテム
 type OrderItem struct {
 	ProductID string  `json:"product_id" validate:"required"`
 	Quantity  int     `json:"quantity" validate:"required,min=1"`
@@ -326,45 +1054,310 @@ type OrderItem struct {
 
 // ユーティリティ関数
 
-// TODO: リフレクションでフィールドを取得
+// getStructFields はリフレクションでフィールドを取得
 func getStructFields(data interface{}) []reflect.StructField {
-	// ヒント: reflect パッケージを使用
-	return nil
+	val := reflect.ValueOf(data)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	
+	typ := val.Type()
+	fields := make([]reflect.StructField, typ.NumField())
+	
+	for i := 0; i < typ.NumField(); i++ {
+		fields[i] = typ.Field(i)
+	}
+	
+	return fields
 }
 
-// TODO: バリデーションタグを解析
+// parseValidationTags はバリデーションタグを解析
+// "required, min=3" のような空白や空要素を含むタグも受け付ける
 func parseValidationTags(tag string) []string {
-	// ヒント: カンマ区切りでタグを分割
-	return nil
+	if tag == "" {
+		return nil
+	}
+	
+	var rules []string
+	for _, rule := range strings.Split(tag, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
 }
 
-// TODO: クライアントIPを取得
+// getClientIP はクライアントIPを取得
 func getClientIP(r *http.Request) string {
-	// ヒント: X-Forwarded-For ヘッダーをチェック
-	return r.RemoteAddr
+	// X-Forwarded-Forヘッダーをチェック
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		ips := strings.Split(xff, ",")
+		if len(ips) > 0 {
+			return strings.TrimSpace(ips[0])
+		}
+	}
+	
+	// X-Real-IPヘッダーをチェック
+	if xri := r.Header.Get("X-Real-IP"); xri != "" {
+		return xri
+	}
+	
+	// RemoteAddrから取得
+	return strings.Split(r.RemoteAddr, ":")[0]
 }
 
-// TODO: SQLインジェクションパターンを検出
+// sqlInjectionPatterns はSQLインジェクションの典型的なパターン
+var sqlInjectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(union\s+select)`),
+	regexp.MustCompile(`(?i)(drop\s+table)`),
+	regexp.MustCompile(`(?i)(delete\s+from)`),
+	regexp.MustCompile(`(?i)(insert\s+into)`),
+	regexp.MustCompile(`(?i)(update\s+.+set)`),
+	regexp.MustCompile(`(?i)(exec\s*\()`),
+	regexp.MustCompile(`(?i)(script\s*>)`),
+	regexp.MustCompile(`(?i)('|\").*(\bor\b|\band\b).*('|\")`),
+	regexp.MustCompile(`(?i)(--|\#|\/\*)`),
+}
+
+// containsSQLInjectionPattern はSQLインジェクションパターンを検出
 func containsSQLInjectionPattern(input string) bool {
-	// ヒント: 危険なSQLキーワードをチェック
-	patterns := []string{
-		`(?i)(union\s+select)`,
-		`(?i)(drop\s+table)`,
-		`(?i)(delete\s+from)`,
-		`(?i)(insert\s+into)`,
-		`(?i)(update\s+.+set)`,
-		`(?i)(exec\s*\()`,
-		`(?i)(script\s*>)`,
-	}
-	
-	for _, pattern := range patterns {
-		if matched, _ := regexp.MatchString(pattern, input); matched {
+	for _, pattern := range sqlInjectionPatterns {
+		if pattern.MatchString(input) {
 			return true
 		}
 	}
 	return false
 }
 
+// xssPatterns はXSSの典型的なパターン
+var xssPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)<\s*script\b`),
+	regexp.MustCompile(`(?i)javascript\s*:`),
+	regexp.MustCompile(`(?i)\bon(error|load|click|mouseover)\s*=`),
+}
+
+// containsXSSPattern はXSSパターンを検出
+func containsXSSPattern(input string) bool {
+	for _, pattern := range xssPatterns {
+		if pattern.MatchString(input) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty は値が空かチェック
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	
+	val := reflect.ValueOf(value)
+	switch val.Kind() {
+	case reflect.String:
+		return val.String() == ""
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return val.Len() == 0
+	case reflect.Ptr:
+		return val.IsNil()
+	default:
+		return false
+	}
+}
+
+// validateMin は最小値バリデーション
+// 文字列は文字数、スライス・配列・マップは要素数、数値は値そのものを比較
+func validateMin(value interface{}, min float64) bool {
+	size, ok := measure(value)
+	return !ok || size >= min
+}
+
+// validateMax は最大値バリデーション
+func validateMax(value interface{}, max float64) bool {
+	size, ok := measure(value)
+	return !ok || size <= max
+}
+
+// measure はmin/max比較に使う値を返す（比較できない型はok=false）
+func measure(value interface{}) (float64, bool) {
+	val := reflect.ValueOf(value)
+	switch val.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(val.String())), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(val.Len()), true
+	default:
+		return 0, false
+	}
+}
+
+// setupDefaultTranslations はデフォルトの翻訳を設定
+func setupDefaultTranslations(translator *SimpleTranslator) {
+	// 英語
+	translator.AddTranslation("REQUIRED", "en", "Field is required")
+	translator.AddTranslation("INVALID_EMAIL", "en", "Invalid email format")
+	translator.AddTranslation("WEAK_PASSWORD", "en", "Password does not meet strength requirements")
+	translator.AddTranslation("INVALID_URL", "en", "Invalid URL format")
+	translator.AddTranslation("INVALID_PHONE", "en", "Invalid phone number format")
+	translator.AddTranslation("MIN_VALUE", "en", "Minimum value is {min}")
+	translator.AddTranslation("MAX_VALUE", "en", "Maximum value is {max}")
+	translator.AddTranslation("EMAIL_EXISTS", "en", "Email address already exists")
+	translator.AddTranslation("USERNAME_EXISTS", "en", "Username already exists")
+	translator.AddTranslation("INSUFFICIENT_STOCK", "en", "Insufficient stock (available: {available}, requested: {requested})")
+	translator.AddTranslation("RATE_LIMIT_EXCEEDED", "en", "Rate limit exceeded")
+	translator.AddTranslation("SQL_INJECTION", "en", "Potential security threat detected")
+	translator.AddTranslation("XSS_DETECTED", "en", "Potential security threat detected")
+	
+	// 日本語
+	translator.AddTranslation("REQUIRED", "ja", "必須項目です")
+	translator.AddTranslation("INVALID_EMAIL", "ja", "メールアドレスの形式が正しくありません")
+	translator.AddTranslation("WEAK_PASSWORD", "ja", "パスワードが強度要件を満たしていません")
+	translator.AddTranslation("INVALID_URL", "ja", "URLの形式が正しくありません")
+	translator.AddTranslation("INVALID_PHONE", "ja", "電話番号の形式が正しくありません")
+	translator.AddTranslation("MIN_VALUE", "ja", "最小値は{min}です")
+	translator.AddTranslation("MAX_VALUE", "ja", "最大値は{max}です")
+	translator.AddTranslation("EMAIL_EXISTS", "ja", "このメールアドレスは既に使用されています")
+	translator.AddTranslation("USERNAME_EXISTS", "ja", "このユーザー名は既に使用されています")
+	translator.AddTranslation("INSUFFICIENT_STOCK", "ja", "在庫不足です（利用可能: {available}, 要求: {requested}）")
+	translator.AddTranslation("RATE_LIMIT_EXCEEDED", "ja", "アクセス制限に達しました")
+	translator.AddTranslation("SQL_INJECTION", "ja", "セキュリティ上の脅威が検出されました")
+	translator.AddTranslation("XSS_DETECTED", "ja", "セキュリティ上の脅威が検出されました")
+}
+
+// generateCacheKey はキャッシュキーを生成
+// 同じ型・同じペイロードのリクエストは同じキーになる
+func generateCacheKey(target interface{}, payload []byte) string {
+	h := md5.New()
+	fmt.Fprintf(h, "%T\x00", target)
+	h.Write(payload)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// SimpleRateLimitChecker は簡単なレート制限チェッカー
+type SimpleRateLimitChecker struct {
+	requests map[string][]time.Time
+	limit    int
+	window   time.Duration
+	mu       sync.RWMutex
+}
+
+// NewSimpleRateLimitChecker は新しいレート制限チェッカーを作成
+func NewSimpleRateLimitChecker(limit int, window time.Duration) *SimpleRateLimitChecker {
+	checker := &SimpleRateLimitChecker{
+		requests: make(map[string][]time.Time),
+		limit:    limit,
+		window:   window,
+	}
+	
+	// 定期的にクリーンアップ
+	go checker.cleanup()
+	
+	return checker
+}
+
+// IsAllowed はリクエストが許可されているかチェック
+func (srlc *SimpleRateLimitChecker) IsAllowed(clientIP string) bool {
+	srlc.mu.Lock()
+	defer srlc.mu.Unlock()
+	
+	now := time.Now()
+	windowStart := now.Add(-srlc.window)
+	
+	// 古いリクエストを削除
+	if requests, exists := srlc.requests[clientIP]; exists {
+		validRequests := make([]time.Time, 0)
+		for _, reqTime := range requests {
+			if reqTime.After(windowStart) {
+				validRequests = append(validRequests, reqTime)
+			}
+		}
+		srlc.requests[clientIP] = validRequests
+	} else {
+		srlc.requests[clientIP] = make([]time.Time, 0)
+	}
+	
+	// 制限チェック
+	if len(srlc.requests[clientIP]) >= srlc.limit {
+		return false
+	}
+	
+	// リクエストを記録
+	srlc.requests[clientIP] = append(srlc.requests[clientIP], now)
+	return true
+}
+
+// cleanup は古いエントリを削除
+func (srlc *SimpleRateLimitChecker) cleanup() {
+	ticker := time.NewTicker(srlc.window)
+	defer ticker.Stop()
+	
+	for range ticker.C {
+		srlc.mu.Lock()
+		now := time.Now()
+		windowStart := now.Add(-srlc.window)
+		
+		for ip, requests := range srlc.requests {
+			validRequests := make([]time.Time, 0)
+			for _, reqTime := range requests {
+				if reqTime.After(windowStart) {
+					validRequests = append(validRequests, reqTime)
+				}
+			}
+			
+			if len(validRequests) == 0 {
+				delete(srlc.requests, ip)
+			} else {
+				srlc.requests[ip] = validRequests
+			}
+		}
+		srlc.mu.Unlock()
+	}
+}
+
+// MockUserRepository はテスト用のユーザーリポジトリ
+type MockUserRepository struct {
+	existingEmails    map[string]bool
+	existingUsernames map[string]bool
+}
+
+// NewMockUserRepository は新しいモックリポジトリを作成
+func NewMockUserRepository() *MockUserRepository {
+	return &MockUserRepository{
+		existingEmails:    make(map[string]bool),
+		existingUsernames: make(map[string]bool),
+	}
+}
+
+// ExistsByEmail はメールアドレスの存在チェック
+func (mur *MockUserRepository) ExistsByEmail(email string) (bool, error) {
+	return mur.existingEmails[email], nil
+}
+
+// ExistsByUsername はユーザー名の存在チェック
+func (mur *MockUserRepository) ExistsByUsername(username string) (bool, error) {
+	return mur.existingUsernames[username], nil
+}
+
+// AddExistingEmail は既存メールアドレスを追加
+func (mur *MockUserRepository) AddExistingEmail(email string) {
+	mur.existingEmails[email] = true
+}
+
+// AddExistingUsername は既存ユーザー名を追加
+func (mur *MockUserRepository) AddExistingUsername(username string) {
+	mur.existingUsernames[username] = true
+}
+
 // メイン関数（テスト用）
 func main() {
 	validator := NewRequestValidator()
@@ -375,6 +1368,12 @@ func main() {
 	validator.RegisterValidator("url", URLValidator)
 	validator.RegisterValidator("phone", PhoneValidator)
 	
+	// セキュリティルールを追加
+	rateLimitChecker := NewSimpleRateLimitChecker(100, time.Minute)
+	validator.AddSecurityRule(&RateLimitRule{limitChecker: rateLimitChecker})
+	validator.AddSecurityRule(&SQLInjectionRule{})
+	validator.AddSecurityRule(&XSSRule{})
+	
 	// ユーザー作成エンドポイント
 	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -383,12 +1382,8 @@ func main() {
 		}
 		
 		var user User
-		lang := r.Header.Get("Accept-Language")
-		if lang == "" {
-			lang = "en"
-		}
-		
-		if err := validator.ValidateRequest(w, r, &user, lang); err != nil {
+		// メッセージの言語は Accept-Language ヘッダーから決定される
+		if err := validator.ValidateRequest(w, r, &user, ""); err != nil {
 			return // エラーレスポンスは ValidateRequest 内で処理
 		}
 		
@@ -401,9 +1396,17 @@ func main() {
 		})
 	})
 	
+	// メトリクスエンドポイント
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		metrics := validator.metrics.GetMetrics()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(metrics)
+	})
+	
 	fmt.Println("Starting server on :8080")
 	fmt.Println("Try:")
-	fmt.Println(`curl -X POST http://localhost:8080/users -d '{"email":"test@example.com","username":"testuser","password":"Test123!","name":"Test User","age":25}' -H "Content-Type: application/json"`)
+	fmt.Println(`curl -X POST http://localhost:8080/users -d '{"id":"123","email":"test@example.com","username":"testuser","password":"Test123!","name":"Test User","age":25}' -H "Content-Type: application/json"`)
+	fmt.Println(`curl http://localhost:8080/metrics`)
 	
 	http.ListenAndServe(":8080", nil)
 }
\ No newline at end of file
@@ -1,17 +1,23 @@
 package main
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // ValidationError はバリデーションエラーの詳細情報
@@ -58,6 +64,30 @@ type Translator interface {
 	Translate(code, lang string, params map[string]interface{}) string
 }
 
+// languageSupporter は対応言語を判定できる翻訳器（Accept-Languageの交渉に使用）
+type languageSupporter interface {
+	SupportsLanguage(lang string) bool
+}
+
+// ErrValidationFailed はバリデーション失敗時に ValidateRequest が返すエラー
+var ErrValidationFailed = errors.New("validation failed")
+
+// builtinRules は構造バリデーション層で処理する組み込みルール
+var builtinRules = map[string]bool{
+	"required":  true,
+	"omitempty": true,
+	"min":       true,
+	"max":       true,
+	"email":     true,
+}
+
+// customValidatorCodes はカスタムバリデーター名とエラーコードの対応
+var customValidatorCodes = map[string]string{
+	"password_strength": "WEAK_PASSWORD",
+	"url":               "INVALID_URL",
+	"phone":             "INVALID_PHONE",
+}
+
 // NewRequestValidator はRequestValidatorを初期化
 func NewRequestValidator() *RequestValidator {
 	translator := NewSimpleTranslator("en")
@@ -80,58 +110,17 @@ func NewRequestValidator() *RequestValidator {
 
 // setupDefaultValidators はデフォルトバリデーターを設定
 func (rv *RequestValidator) setupDefaultValidators() {
-	rv.customValidators["url"] = func(value interface{}) bool {
-		str, ok := value.(string)
-		if !ok {
-			return false
-		}
-		if str == "" {
-			return true // empty is valid for omitempty
-		}
-		// Simple URL validation
-		return strings.HasPrefix(str, "http://") || strings.HasPrefix(str, "https://")
-	}
-	
-	rv.customValidators["phone"] = func(value interface{}) bool {
-		str, ok := value.(string)
-		if !ok {
-			return false
-		}
-		if str == "" {
-			return true // empty is valid for omitempty
-		}
-		// Simple phone validation
-		return len(str) >= 10 && len(str) <= 15
-	}
-	
-	rv.customValidators["password_strength"] = func(value interface{}) bool {
-		str, ok := value.(string)
-		if !ok {
-			return false
-		}
-		if len(str) < 8 {
-			return false
-		}
-		// Check for at least one uppercase, one lowercase, one digit
-		hasUpper := false
-		hasLower := false
-		hasDigit := false
-		for _, char := range str {
-			if char >= 'A' && char <= 'Z' {
-				hasUpper = true
-			} else if char >= 'a' && char <= 'z' {
-				hasLower = true
-			} else if char >= '0' && char <= '9' {
-				hasDigit = true
-			}
-		}
-		return hasUpper && hasLower && hasDigit
-	}
+	rv.customValidators["email"] = EmailValidator
+	rv.customValidators["password_strength"] = PasswordStrengthValidator
+	rv.customValidators["url"] = URLValidator
+	rv.customValidators["phone"] = PhoneValidator
 }
 
 // RegisterValidator はカスタムバリデーターを登録
+// キャッシュ済みの結果は古いバリデーターによるものなので破棄する
 func (rv *RequestValidator) RegisterValidator(name string, fn ValidatorFunc) {
 	rv.customValidators[name] = fn
+	rv.cache.Clear()
 }
 
 // AddBusinessRule はビジネスルールを追加
@@ -145,109 +134,216 @@ func (rv *RequestValidator) AddSecurityRule(rule SecurityRule) {
 }
 
 // ValidateRequest はリクエストをバリデーション
+// 各層は順番に実行され、最初に失敗した層でエラーレスポンスを書き込んで ErrValidationFailed を返す
+// lang が空の場合は Accept-Language ヘッダーからメッセージの言語を決定する
 func (rv *RequestValidator) ValidateRequest(w http.ResponseWriter, r *http.Request, target interface{}, lang string) error {
 	start := time.Now()
-	defer func() {
-		rv.metrics.RecordSuccess(time.Since(start))
-	}()
+	
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	
+	fail := func(errorType string, errs []ValidationError) error {
+		rv.metrics.RecordError(errorType, time.Since(start))
+		return rv.writeErrorResponse(w, errs, lang)
+	}
 	
 	// 1. Content-Type検証
 	contentType := r.Header.Get("Content-Type")
-	if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
-		if !strings.HasPrefix(contentType, "application/json") {
-			rv.metrics.RecordError("content_type", time.Since(start))
-			return rv.writeErrorResponse(w, []ValidationError{{
+	if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return fail("content_type", []ValidationError{{
 				Field:   "Content-Type",
 				Message: "Invalid content type",
 				Code:    "INVALID_CONTENT_TYPE",
 				Value:   contentType,
-			}}, lang)
+			}})
 		}
 	}
 	
 	// 2. JSONデコード
-	if r.Body != nil {
-		decoder := json.NewDecoder(r.Body)
-		decoder.DisallowUnknownFields()
-		
-		if err := decoder.Decode(target); err != nil {
-			rv.metrics.RecordError("json_decode", time.Since(start))
-			return rv.writeErrorResponse(w, []ValidationError{{
+	var payload []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err == nil {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.DisallowUnknownFields()
+			err = decoder.Decode(target)
+		}
+		if err != nil {
+			return fail("json_decode", []ValidationError{{
 				Field:   "body",
 				Message: "Invalid JSON format",
 				Code:    "INVALID_JSON",
 				Value:   err.Error(),
-			}}, lang)
+			}})
 		}
+		payload = body
 	}
 	
-	// 3. 構造バリデーション
-	var allErrors []ValidationError
-	structErrors := rv.validateStruct(target)
-	allErrors = append(allErrors, structErrors...)
-	
-	// 4. カスタムバリデーション
-	customErrors := rv.validateCustom(target)
-	allErrors = append(allErrors, customErrors...)
+	// 3-4. 構造・カスタムバリデーション（ペイロードのみで決まるためキャッシュ可能）
+	result := rv.validatePayload(target, payload)
+	if !result.IsValid {
+		// writeErrorResponse はメッセージを書き換えるため、キャッシュ内のスライスは渡さない
+		return fail("validation", append([]ValidationError(nil), result.Errors...))
+	}
 	
 	// 5. ビジネスルールバリデーション
+	var businessErrors []ValidationError
 	for _, rule := range rv.businessRules {
-		businessErrors := rule.Validate(target)
-		allErrors = append(allErrors, businessErrors...)
+		businessErrors = append(businessErrors, rule.Validate(target)...)
+	}
+	if len(businessErrors) > 0 {
+		return fail("business", businessErrors)
 	}
 	
 	// 6. セキュリティバリデーション
+	var securityErrors []ValidationError
 	for _, rule := range rv.securityRules {
-		securityErrors := rule.Validate(target, r)
-		allErrors = append(allErrors, securityErrors...)
+		securityErrors = append(securityErrors, rule.Validate(target, r)...)
 	}
-	
-	// エラーがある場合はレスポンスを書き込み
-	if len(allErrors) > 0 {
-		rv.metrics.RecordError("validation", time.Since(start))
-		return rv.writeErrorResponse(w, allErrors, lang)
+	if len(securityErrors) > 0 {
+		return fail("security", securityErrors)
 	}
 	
+	rv.metrics.RecordSuccess(time.Since(start))
 	return nil
 }
 
-// validateStruct は構造体バリデーション
-func (rv *RequestValidator) validateStruct(data interface{}) []ValidationError {
-	var errors []ValidationError
+// validatePayload は構造・カスタムバリデーションを実行し、結果をペイロード単位でキャッシュ
+// ビジネスルールやセキュリティルールは外部状態に依存するためキャッシュしない
+// ボディがない場合は target の既存の値が検証対象になり、キーで区別できないためキャッシュしない
+func (rv *RequestValidator) validatePayload(target interface{}, payload []byte) ValidationResult {
+	if payload == nil {
+		return rv.validateTarget(target)
+	}
 	
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
+	key := generateCacheKey(target, payload)
+	if result, ok := rv.cache.Get(key); ok {
+		rv.metrics.RecordCacheHit()
+		return result
+	}
+	rv.metrics.RecordCacheMiss()
+	
+	result := rv.validateTarget(target)
+	rv.cache.Set(key, result)
+	return result
+}
+
+// validateTarget は構造バリデーション、成功した場合はカスタムバリデーションを実行
+func (rv *RequestValidator) validateTarget(target interface{}) ValidationResult {
+	errs := rv.validateStruct(target)
+	if len(errs) == 0 {
+		errs = rv.validateCustom(target)
+	}
+	return ValidationResult{IsValid: len(errs) == 0, Errors: errs}
+}
+
+// fieldRules はvalidateタグを持つフィールドとそのルール
+type fieldRules struct {
+	name  string
+	value interface{}
+	rules []string
+}
+
+// isOptionalEmpty はomitemptyが指定され、かつ値が空かチェック
+func (fr fieldRules) isOptionalEmpty() bool {
+	for _, rule := range fr.rules {
+		if rule == "omitempty" {
+			return isEmpty(fr.value)
+		}
+	}
+	return false
+}
+
+// collectFieldRules はvalidateタグを持つフィールドを列挙
+// ネストした構造体やスライスの要素も再帰的に辿り、"items[0].quantity" のようなパスで名前を付ける
+func collectFieldRules(data interface{}) []fieldRules {
+	return appendFieldRules(nil, reflect.ValueOf(data), "")
+}
+
+// appendFieldRules は構造体のフィールドを再帰的に走査
+func appendFieldRules(fields []fieldRules, val reflect.Value, prefix string) []fieldRules {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return fields
+		}
 		val = val.Elem()
 	}
 	
 	if val.Kind() != reflect.Struct {
-		return errors
+		return fields
 	}
 	
 	typ := val.Type()
-	
-	for i := 0; i < val.NumField(); i++ {
+	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
-		fieldValue := val.Field(i)
-		
-		// validateタグを取得
-		validateTag := field.Tag.Get("validate")
-		if validateTag == "" {
+		if !field.IsExported() {
 			continue
 		}
 		
-		jsonTag := field.Tag.Get("json")
-		fieldName := field.Name
-		if jsonTag != "" && jsonTag != "-" {
-			fieldName = strings.Split(jsonTag, ",")[0]
+		name := prefix + jsonFieldName(field)
+		fieldValue := val.Field(i)
+		
+		if validateTag := field.Tag.Get("validate"); validateTag != "" {
+			fields = append(fields, fieldRules{
+				name:  name,
+				value: fieldValue.Interface(),
+				rules: parseValidationTags(validateTag),
+			})
 		}
 		
-		// バリデーションルールを解析
-		rules := parseValidationTags(validateTag)
+		// ネストした構造体・スライス要素を再帰的に検証
+		switch indirectKind(fieldValue) {
+		case reflect.Struct:
+			fields = appendFieldRules(fields, fieldValue, name+".")
+		case reflect.Slice, reflect.Array:
+			elems := reflect.Indirect(fieldValue)
+			for j := 0; j < elems.Len(); j++ {
+				fields = appendFieldRules(fields, elems.Index(j), fmt.Sprintf("%s[%d].", name, j))
+			}
+		}
+	}
+	
+	return fields
+}
+
+// indirectKind はポインタを辿った先の値の種類を返す
+func indirectKind(val reflect.Value) reflect.Kind {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Invalid
+		}
+		val = val.Elem()
+	}
+	return val.Kind()
+}
+
+// jsonFieldName はJSONタグからフィールド名を取得
+func jsonFieldName(field reflect.StructField) string {
+	jsonTag := field.Tag.Get("json")
+	if jsonTag != "" && jsonTag != "-" {
+		if name := strings.Split(jsonTag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// validateStruct は構造体バリデーション（組み込みルールのみ）
+func (rv *RequestValidator) validateStruct(data interface{}) []ValidationError {
+	var errors []ValidationError
+	
+	for _, fr := range collectFieldRules(data) {
+		if fr.isOptionalEmpty() {
+			continue
+		}
 		
-		for _, rule := range rules {
-			if err := rv.validateField(fieldName, fieldValue.Interface(), rule); err != nil {
+		for _, rule := range fr.rules {
+			if err := rv.validateField(fr.name, fr.value, rule); err != nil {
 				errors = append(errors, *err)
+				break // 1フィールドにつき最初のエラーのみ報告
 			}
 		}
 	}
@@ -257,7 +353,7 @@ func (rv *RequestValidator) validateStruct(data interface{}) []ValidationError {
 
 // validateField は単一フィールドのバリデーション
 func (rv *RequestValidator) validateField(fieldName string, value interface{}, rule string) *ValidationError {
-	parts := strings.Split(rule, "=")
+	parts := strings.SplitN(rule, "=", 2)
 	ruleName := parts[0]
 	var ruleValue string
 	if len(parts) > 1 {
@@ -275,11 +371,11 @@ func (rv *RequestValidator) validateField(fieldName string, value interface{}, r
 			}
 		}
 	case "min":
-		if minVal, err := strconv.Atoi(ruleValue); err == nil {
+		if minVal, err := strconv.ParseFloat(ruleValue, 64); err == nil {
 			if !validateMin(value, minVal) {
 				return &ValidationError{
 					Field:   fieldName,
-					Message: fmt.Sprintf("Minimum value is %d", minVal),
+					Message: fmt.Sprintf("Minimum value is %v", minVal),
 					Code:    "MIN_VALUE",
 					Value:   value,
 					Metadata: map[string]interface{}{"min": minVal},
@@ -287,11 +383,11 @@ func (rv *RequestValidator) validateField(fieldName string, value interface{}, r
 			}
 		}
 	case "max":
-		if maxVal, err := strconv.Atoi(ruleValue); err == nil {
+		if maxVal, err := strconv.ParseFloat(ruleValue, 64); err == nil {
 			if !validateMax(value, maxVal) {
 				return &ValidationError{
 					Field:   fieldName,
-					Message: fmt.Sprintf("Maximum value is %d", maxVal),
+					Message: fmt.Sprintf("Maximum value is %v", maxVal),
 					Code:    "MAX_VALUE",
 					Value:   value,
 					Metadata: map[string]interface{}{"max": maxVal},
@@ -299,7 +395,11 @@ func (rv *RequestValidator) validateField(fieldName string, value interface{}, r
 			}
 		}
 	case "email":
-		if !rv.customValidators["email"](value) {
+		validator, ok := rv.customValidators["email"]
+		if !ok {
+			validator = EmailValidator
+		}
+		if !validator(value) {
 			return &ValidationError{
 				Field:   fieldName,
 				Message: "Invalid email format",
@@ -307,53 +407,69 @@ func (rv *RequestValidator) validateField(fieldName string, value interface{}, r
 				Value:   value,
 			}
 		}
-	case "password_strength":
-		if !rv.customValidators["password_strength"](value) {
-			return &ValidationError{
-				Field:   fieldName,
-				Message: "Password does not meet strength requirements",
-				Code:    "WEAK_PASSWORD",
-				Value:   "***",
-			}
-		}
-	case "url":
-		if !rv.customValidators["url"](value) {
-			return &ValidationError{
-				Field:   fieldName,
-				Message: "Invalid URL format",
-				Code:    "INVALID_URL",
-				Value:   value,
-			}
-		}
-	case "phone":
-		if !rv.customValidators["phone"](value) {
-			return &ValidationError{
-				Field:   fieldName,
-				Message: "Invalid phone number format",
-				Code:    "INVALID_PHONE",
-				Value:   value,
-			}
-		}
 	}
 	
 	return nil
 }
 
-// validateCustom はカスタムバリデーション
+// validateCustom は登録済みカスタムバリデーターをタグに従って実行
 func (rv *RequestValidator) validateCustom(data interface{}) []ValidationError {
 	var errors []ValidationError
 	
-	// 追加のカスタムバリデーションロジックをここに実装
-	// 例：複合フィールドの検証、ビジネスロジック固有の検証など
+	for _, fr := range collectFieldRules(data) {
+		if fr.isOptionalEmpty() {
+			continue
+		}
+		
+		for _, rule := range fr.rules {
+			name := strings.SplitN(rule, "=", 2)[0]
+			if builtinRules[name] {
+				continue
+			}
+			
+			validator, ok := rv.customValidators[name]
+			if !ok || validator(fr.value) {
+				continue
+			}
+			
+			code, ok := customValidatorCodes[name]
+			if !ok {
+				code = "INVALID_" + strings.ToUpper(name)
+			}
+			
+			value := fr.value
+			if name == "password_strength" {
+				value = "***" // パスワードはレスポンスに含めない
+			}
+			
+			errors = append(errors, ValidationError{
+				Field:   fr.name,
+				Message: fmt.Sprintf("Validation %q failed", name),
+				Code:    code,
+				Value:   value,
+			})
+			break
+		}
+	}
 	
 	return errors
 }
 
-// writeErrorResponse はエラーレスポンスを作成
+// writeErrorResponse はエラーレスポンスを作成し、ErrValidationFailed を返す
+// lang は "ja" のような言語タグ、または "ja-JP,ja;q=0.9,en;q=0.8" のような Accept-Language の値
 func (rv *RequestValidator) writeErrorResponse(w http.ResponseWriter, errors []ValidationError, lang string) error {
-	// エラーメッセージを翻訳
+	lang = rv.negotiateLanguage(lang)
+	
+	// エラーメッセージを翻訳（翻訳がない場合は元のメッセージを維持）
 	for i := range errors {
-		errors[i].Message = rv.translator.Translate(errors[i].Code, lang, errors[i].Metadata)
+		params := map[string]interface{}{"field": errors[i].Field}
+		for k, v := range errors[i].Metadata {
+			params[k] = v
+		}
+		
+		if message := rv.translator.Translate(errors[i].Code, lang, params); message != errors[i].Code {
+			errors[i].Message = message
+		}
 	}
 	
 	response := map[string]interface{}{
@@ -362,8 +478,77 @@ func (rv *RequestValidator) writeErrorResponse(w http.ResponseWriter, errors []V
 	}
 	
 	w.Header().Set("Content-Type", "application/json")
+	if lang != "" {
+		w.Header().Set("Content-Language", lang)
+	}
 	w.WriteHeader(http.StatusBadRequest)
-	return json.NewEncoder(w).Encode(response)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		return fmt.Errorf("%w: write response: %v", ErrValidationFailed, err)
+	}
+	
+	return fmt.Errorf("%w: %s: %s", ErrValidationFailed, errors[0].Field, errors[0].Code)
+}
+
+// negotiateLanguage は Accept-Language の候補から翻訳器が対応する言語を選択
+// 対応言語が判定できない翻訳器の場合は最も優先度の高い候補を返す
+func (rv *RequestValidator) negotiateLanguage(accept string) string {
+	candidates := parseAcceptLanguage(accept)
+	if len(candidates) == 0 {
+		return ""
+	}
+	
+	supporter, ok := rv.translator.(languageSupporter)
+	if !ok {
+		return candidates[0]
+	}
+	
+	for _, candidate := range candidates {
+		if supporter.SupportsLanguage(candidate) {
+			return candidate
+		}
+		if base, _, found := strings.Cut(candidate, "-"); found && supporter.SupportsLanguage(base) {
+			return base
+		}
+	}
+	
+	return ""
+}
+
+// parseAcceptLanguage は Accept-Language を品質値(q)の高い順の言語タグに分解
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+	
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
 }
 
 // バリデーションキャッシュ
@@ -382,54 +567,42 @@ type CachedValidationResult struct {
 
 // NewValidationCache はキャッシュを初期化
 func NewValidationCache(ttl time.Duration) *ValidationCache {
-	vc := &ValidationCache{
+	return &ValidationCache{
 		ttl: ttl,
 	}
-	
-	// 定期的にキャッシュをクリーンアップ
-	go vc.cleanup()
-	
-	return vc
 }
 
 // Get はキャッシュから取得
+// 期限切れのエントリはアクセス時に削除する
 func (vc *ValidationCache) Get(key string) (ValidationResult, bool) {
-	if value, ok := vc.cache.Load(key); ok {
-		if cached, ok := value.(*CachedValidationResult); ok {
-			if time.Now().Before(cached.ExpiresAt) {
-				return cached.Result, true
-			}
-			vc.cache.Delete(key)
-		}
+	value, ok := vc.cache.Load(key)
+	if !ok {
+		return ValidationResult{}, false
 	}
-	return ValidationResult{}, false
+	
+	cached := value.(*CachedValidationResult)
+	if !time.Now().Before(cached.ExpiresAt) {
+		vc.cache.CompareAndDelete(key, value)
+		return ValidationResult{}, false
+	}
+	
+	return cached.Result, true
 }
 
 // Set はキャッシュに保存
 func (vc *ValidationCache) Set(key string, result ValidationResult) {
-	cached := &CachedValidationResult{
+	vc.cache.Store(key, &CachedValidationResult{
 		Result:    result,
 		ExpiresAt: time.Now().Add(vc.ttl),
-	}
-	vc.cache.Store(key, cached)
+	})
 }
 
-// cleanup は期限切れのキャッシュエントリを削除
-func (vc *ValidationCache) cleanup() {
-	ticker := time.NewTicker(vc.ttl / 2)
-	defer ticker.Stop()
-	
-	for range ticker.C {
-		now := time.Now()
-		vc.cache.Range(func(key, value interface{}) bool {
-			if cached, ok := value.(*CachedValidationResult); ok {
-				if now.After(cached.ExpiresAt) {
-					vc.cache.Delete(key)
-				}
-			}
-			return true
-		})
-	}
+// Clear は全てのエントリを削除
+func (vc *ValidationCache) Clear() {
+	vc.cache.Range(func(key, _ interface{}) bool {
+		vc.cache.Delete(key)
+		return true
+	})
 }
 
 // バリデーションメトリクス
@@ -440,6 +613,8 @@ type ValidationMetrics struct {
 	successCount     int64
 	errorCount       int64
 	avgDuration      time.Duration
+	cacheHits        int64
+	cacheMisses      int64
 	mu              sync.RWMutex
 	totalDuration   time.Duration
 }
@@ -471,6 +646,22 @@ func (vm *ValidationMetrics) RecordError(errorType string, duration time.Duratio
 	vm.avgDuration = time.Duration(int64(vm.totalDuration) / vm.totalValidations)
 }
 
+// RecordCacheHit はキャッシュヒットを記録
+func (vm *ValidationMetrics) RecordCacheHit() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	
+	vm.cacheHits++
+}
+
+// RecordCacheMiss はキャッシュミスを記録
+func (vm *ValidationMetrics) RecordCacheMiss() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	
+	vm.cacheMisses++
+}
+
 // GetMetrics はメトリクスを取得
 func (vm *ValidationMetrics) GetMetrics() map[string]interface{} {
 	vm.mu.RLock()
@@ -481,12 +672,20 @@ func (vm *ValidationMetrics) GetMetrics() map[string]interface{} {
 		successRate = float64(vm.successCount) / float64(vm.totalValidations) * 100
 	}
 	
+	cacheHitRate := float64(0)
+	if lookups := vm.cacheHits + vm.cacheMisses; lookups > 0 {
+		cacheHitRate = float64(vm.cacheHits) / float64(lookups) * 100
+	}
+	
 	return map[string]interface{}{
 		"total_validations": vm.totalValidations,
 		"success_count":     vm.successCount,
 		"error_count":       vm.errorCount,
 		"success_rate":      successRate,
 		"avg_duration_ms":   float64(vm.avgDuration.Nanoseconds()) / 1e6,
+		"cache_hits":        vm.cacheHits,
+		"cache_misses":      vm.cacheMisses,
+		"cache_hit_rate":    cacheHitRate,
 	}
 }
 
@@ -495,6 +694,7 @@ func (vm *ValidationMetrics) GetMetrics() map[string]interface{} {
 // SimpleTranslator は簡単な翻訳器
 type SimpleTranslator struct {
 	translations map[string]map[string]string
+	languages    map[string]bool
 	defaultLang  string
 	mu          sync.RWMutex
 }
@@ -503,23 +703,25 @@ type SimpleTranslator struct {
 func NewSimpleTranslator(defaultLang string) *SimpleTranslator {
 	return &SimpleTranslator{
 		translations: make(map[string]map[string]string),
-		defaultLang:  defaultLang,
+		languages:    make(map[string]bool),
+		defaultLang:  strings.ToLower(defaultLang),
 	}
 }
 
 // Translate はメッセージを翻訳
+// 指定言語 → 地域を除いた言語（"ja-JP" → "ja"）→ デフォルト言語 → コードの順にフォールバック
 func (st *SimpleTranslator) Translate(code, lang string, params map[string]interface{}) string {
 	st.mu.RLock()
 	defer st.mu.RUnlock()
 	
-	// 指定言語の翻訳を検索
 	if langMap, exists := st.translations[code]; exists {
-		if message, exists := langMap[lang]; exists {
-			return st.interpolate(message, params)
-		}
-		// デフォルト言語を試行
-		if message, exists := langMap[st.defaultLang]; exists {
-			return st.interpolate(message, params)
+		lang = strings.ToLower(lang)
+		base, _, _ := strings.Cut(lang, "-")
+		
+		for _, candidate := range []string{lang, base, st.defaultLang} {
+			if message, exists := langMap[candidate]; exists {
+				return st.interpolate(message, params)
+			}
 		}
 	}
 	
@@ -535,10 +737,19 @@ func (st *SimpleTranslator) AddTranslation(code, lang, message string) {
 	if st.translations[code] == nil {
 		st.translations[code] = make(map[string]string)
 	}
-	st.translations[code][lang] = message
+	st.translations[code][strings.ToLower(lang)] = message
+	st.languages[strings.ToLower(lang)] = true
 }
 
-// interpolate はパラメータを置換
+// SupportsLanguage は翻訳が1件以上登録されている言語か判定
+func (st *SimpleTranslator) SupportsLanguage(lang string) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	
+	return st.languages[strings.ToLower(lang)]
+}
+
+// interpolate はパラメータを置換（"{min}" 形式と "{{.min}}" 形式に対応）
 func (st *SimpleTranslator) interpolate(message string, params map[string]interface{}) string {
 	if params == nil {
 		return message
@@ -546,8 +757,9 @@ func (st *SimpleTranslator) interpolate(message string, params map[string]interf
 	
 	result := message
 	for key, value := range params {
-		placeholder := fmt.Sprintf("{{.%s}}", key)
-		result = strings.ReplaceAll(result, placeholder, fmt.Sprintf("%v", value))
+		replacement := fmt.Sprintf("%v", value)
+		result = strings.ReplaceAll(result, "{{."+key+"}}", replacement)
+		result = strings.ReplaceAll(result, "{"+key+"}", replacement)
 	}
 	
 	return result
@@ -589,8 +801,11 @@ func URLValidator(value interface{}) bool {
 		if str == "" {
 			return true // omitemptyの場合は空文字を許可
 		}
-		_, err := url.ParseRequestURI(str)
-		return err == nil
+		u, err := url.ParseRequestURI(str)
+		if err != nil {
+			return false
+		}
+		return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 	}
 	return false
 }
@@ -732,42 +947,69 @@ func (rlr *RateLimitRule) Validate(data interface{}, r *http.Request) []Validati
 // SQLInjectionRule はSQLインジェクション検出ルール
 type SQLInjectionRule struct{}
 
-// Validate はSQLインジェクション検出ルールを実行
+// Validate はネストしたフィールドを含むすべての文字列フィールドからSQLインジェクションを検出
 func (sir *SQLInjectionRule) Validate(data interface{}, r *http.Request) []ValidationError {
+	return scanStringFields(data, containsSQLInjectionPattern, "SQL_INJECTION", "Potential SQL injection detected")
+}
+
+// XSSRule はクロスサイトスクリプティング検出ルール
+type XSSRule struct{}
+
+// Validate はネストしたフィールドを含むすべての文字列フィールドからXSSパターンを検出
+func (xr *XSSRule) Validate(data interface{}, r *http.Request) []ValidationError {
+	return scanStringFields(data, containsXSSPattern, "XSS_DETECTED", "Potential XSS detected")
+}
+
+// scanStringFields は文字列フィールドを検査し、検出したフィールドごとにセキュリティエラーを返す
+func scanStringFields(data interface{}, detect func(string) bool, code, message string) []ValidationError {
 	var errors []ValidationError
 	
-	// 構造体の文字列フィールドをチェック
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
+	walkStringFields(reflect.ValueOf(data), "", func(name, value string) {
+		if detect(value) {
+			errors = append(errors, ValidationError{
+				Field:    name,
+				Message:  message,
+				Code:     code,
+				Value:    "***", // 攻撃ペイロードはレスポンスに含めない
+				Metadata: map[string]interface{}{"category": "security"},
+			})
+		}
+	})
+	
+	return errors
+}
+
+// walkStringFields は構造体・スライスを再帰的に辿り、文字列フィールドごとに fn を呼び出す
+func walkStringFields(val reflect.Value, name string, fn func(name, value string)) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return
+		}
 		val = val.Elem()
 	}
 	
-	if val.Kind() == reflect.Struct {
+	switch val.Kind() {
+	case reflect.String:
+		fn(name, val.String())
+	case reflect.Struct:
 		typ := val.Type()
-		for i := 0; i < val.NumField(); i++ {
+		for i := 0; i < typ.NumField(); i++ {
 			field := typ.Field(i)
-			fieldValue := val.Field(i)
+			if !field.IsExported() {
+				continue
+			}
 			
-			if fieldValue.Kind() == reflect.String {
-				if containsSQLInjectionPattern(fieldValue.String()) {
-					jsonTag := field.Tag.Get("json")
-					fieldName := field.Name
-					if jsonTag != "" && jsonTag != "-" {
-						fieldName = strings.Split(jsonTag, ",")[0]
-					}
-					
-					errors = append(errors, ValidationError{
-						Field:   fieldName,
-						Message: "Potential SQL injection detected",
-						Code:    "SQL_INJECTION",
-						Value:   "***",
-					})
-				}
+			fieldName := jsonFieldName(field)
+			if name != "" {
+				fieldName = name + "." + fieldName
 			}
+			walkStringFields(val.Field(i), fieldName, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			walkStringFields(val.Index(i), fmt.Sprintf("%s[%d]", name, i), fn)
 		}
 	}
-	
-	return errors
 }
 
 // データ構造
@@ -823,11 +1065,19 @@ func getStructFields(data interface{}) []reflect.StructField {
 }
 
 // parseValidationTags はバリデーションタグを解析
+// "required, min=3" のような空白や空要素を含むタグも受け付ける
 func parseValidationTags(tag string) []string {
 	if tag == "" {
 		return nil
 	}
-	return strings.Split(tag, ",")
+	
+	var rules []string
+	for _, rule := range strings.Split(tag, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
 }
 
 // getClientIP はクライアントIPを取得
@@ -849,22 +1099,40 @@ func getClientIP(r *http.Request) string {
 	return strings.Split(r.RemoteAddr, ":")[0]
 }
 
+// sqlInjectionPatterns はSQLインジェクションの典型的なパターン
+var sqlInjectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(union\s+select)`),
+	regexp.MustCompile(`(?i)(drop\s+table)`),
+	regexp.MustCompile(`(?i)(delete\s+from)`),
+	regexp.MustCompile(`(?i)(insert\s+into)`),
+	regexp.MustCompile(`(?i)(update\s+.+set)`),
+	regexp.MustCompile(`(?i)(exec\s*\()`),
+	regexp.MustCompile(`(?i)(script\s*>)`),
+	regexp.MustCompile(`(?i)('|\").*(\bor\b|\band\b).*('|\")`),
+	regexp.MustCompile(`(?i)(--|\#|\/\*)`),
+}
+
 // containsSQLInjectionPattern はSQLインジェクションパターンを検出
 func containsSQLInjectionPattern(input string) bool {
-	patterns := []string{
-		`(?i)(union\s+select)`,
-		`(?i)(drop\s+table)`,
-		`(?i)(delete\s+from)`,
-		`(?i)(insert\s+into)`,
-		`(?i)(update\s+.+set)`,
-		`(?i)(exec\s*\()`,
-		`(?i)(script\s*>)`,
-		`(?i)('|\").*(\bor\b|\band\b).*('|\")`,
-		`(?i)(--|\#|\/\*)`,
-	}
-	
-	for _, pattern := range patterns {
-		if matched, _ := regexp.MatchString(pattern, input); matched {
+	for _, pattern := range sqlInjectionPatterns {
+		if pattern.MatchString(input) {
+			return true
+		}
+	}
+	return false
+}
+
+// xssPatterns はXSSの典型的なパターン
+var xssPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)<\s*script\b`),
+	regexp.MustCompile(`(?i)javascript\s*:`),
+	regexp.MustCompile(`(?i)\bon(error|load|click|mouseover)\s*=`),
+}
+
+// containsXSSPattern はXSSパターンを検出
+func containsXSSPattern(input string) bool {
+	for _, pattern := range xssPatterns {
+		if pattern.MatchString(input) {
 			return true
 		}
 	}
@@ -891,40 +1159,34 @@ func isEmpty(value interface{}) bool {
 }
 
 // validateMin は最小値バリデーション
-func validateMin(value interface{}, min int) bool {
-	val := reflect.ValueOf(value)
-	switch val.Kind() {
-	case reflect.String:
-		return len(val.String()) >= min
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return val.Int() >= int64(min)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return val.Uint() >= uint64(min)
-	case reflect.Float32, reflect.Float64:
-		return val.Float() >= float64(min)
-	case reflect.Slice, reflect.Array:
-		return val.Len() >= min
-	default:
-		return true
-	}
+// 文字列は文字数、スライス・配列・マップは要素数、数値は値そのものを比較
+func validateMin(value interface{}, min float64) bool {
+	size, ok := measure(value)
+	return !ok || size >= min
 }
 
 // validateMax は最大値バリデーション
-func validateMax(value interface{}, max int) bool {
+func validateMax(value interface{}, max float64) bool {
+	size, ok := measure(value)
+	return !ok || size <= max
+}
+
+// measure はmin/max比較に使う値を返す（比較できない型はok=false）
+func measure(value interface{}) (float64, bool) {
 	val := reflect.ValueOf(value)
 	switch val.Kind() {
 	case reflect.String:
-		return len(val.String()) <= max
+		return float64(utf8.RuneCountInString(val.String())), true
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return val.Int() <= int64(max)
+		return float64(val.Int()), true
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return val.Uint() <= uint64(max)
+		return float64(val.Uint()), true
 	case reflect.Float32, reflect.Float64:
-		return val.Float() <= float64(max)
-	case reflect.Slice, reflect.Array:
-		return val.Len() <= max
+		return val.Float(), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(val.Len()), true
 	default:
-		return true
+		return 0, false
 	}
 }
 
@@ -936,13 +1198,14 @@ func setupDefaultTranslations(translator *SimpleTranslator) {
 	translator.AddTranslation("WEAK_PASSWORD", "en", "Password does not meet strength requirements")
 	translator.AddTranslation("INVALID_URL", "en", "Invalid URL format")
 	translator.AddTranslation("INVALID_PHONE", "en", "Invalid phone number format")
-	translator.AddTranslation("MIN_VALUE", "en", "Minimum value is {{.min}}")
-	translator.AddTranslation("MAX_VALUE", "en", "Maximum value is {{.max}}")
+	translator.AddTranslation("MIN_VALUE", "en", "Minimum value is {min}")
+	translator.AddTranslation("MAX_VALUE", "en", "Maximum value is {max}")
 	translator.AddTranslation("EMAIL_EXISTS", "en", "Email address already exists")
 	translator.AddTranslation("USERNAME_EXISTS", "en", "Username already exists")
-	translator.AddTranslation("INSUFFICIENT_STOCK", "en", "Insufficient stock (available: {{.available}}, requested: {{.requested}})")
+	translator.AddTranslation("INSUFFICIENT_STOCK", "en", "Insufficient stock (available: {available}, requested: {requested})")
 	translator.AddTranslation("RATE_LIMIT_EXCEEDED", "en", "Rate limit exceeded")
 	translator.AddTranslation("SQL_INJECTION", "en", "Potential security threat detected")
+	translator.AddTranslation("XSS_DETECTED", "en", "Potential security threat detected")
 	
 	// 日本語
 	translator.AddTranslation("REQUIRED", "ja", "必須項目です")
@@ -950,19 +1213,23 @@ func setupDefaultTranslations(translator *SimpleTranslator) {
 	translator.AddTranslation("WEAK_PASSWORD", "ja", "パスワードが強度要件を満たしていません")
 	translator.AddTranslation("INVALID_URL", "ja", "URLの形式が正しくありません")
 	translator.AddTranslation("INVALID_PHONE", "ja", "電話番号の形式が正しくありません")
-	translator.AddTranslation("MIN_VALUE", "ja", "最小値は{{.min}}です")
-	translator.AddTranslation("MAX_VALUE", "ja", "最大値は{{.max}}です")
+	translator.AddTranslation("MIN_VALUE", "ja", "最小値は{min}です")
+	translator.AddTranslation("MAX_VALUE", "ja", "最大値は{max}です")
 	translator.AddTranslation("EMAIL_EXISTS", "ja", "このメールアドレスは既に使用されています")
 	translator.AddTranslation("USERNAME_EXISTS", "ja", "このユーザー名は既に使用されています")
-	translator.AddTranslation("INSUFFICIENT_STOCK", "ja", "在庫不足です（利用可能: {{.available}}, 要求: {{.requested}}）")
+	translator.AddTranslation("INSUFFICIENT_STOCK", "ja", "在庫不足です（利用可能: {available}, 要求: {requested}）")
 	translator.AddTranslation("RATE_LIMIT_EXCEEDED", "ja", "アクセス制限に達しました")
 	translator.AddTranslation("SQL_INJECTION", "ja", "セキュリティ上の脅威が検出されました")
+	translator.AddTranslation("XSS_DETECTED", "ja", "セキュリティ上の脅威が検出されました")
 }
 
 // generateCacheKey はキャッシュキーを生成
-func generateCacheKey(data interface{}) string {
-	jsonData, _ := json.Marshal(data)
-	return fmt.Sprintf("%x", md5.Sum(jsonData))
+// 同じ型・同じペイロードのリクエストは同じキーになる
+func generateCacheKey(target interface{}, payload []byte) string {
+	h := md5.New()
+	fmt.Fprintf(h, "%T\x00", target)
+	h.Write(payload)
+	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
 // SimpleRateLimitChecker は簡単なレート制限チェッカー
@@ -1094,6 +1361,7 @@ func main() {
 	rateLimitChecker := NewSimpleRateLimitChecker(100, time.Minute)
 	validator.AddSecurityRule(&RateLimitRule{limitChecker: rateLimitChecker})
 	validator.AddSecurityRule(&SQLInjectionRule{})
+	validator.AddSecurityRule(&XSSRule{})
 	
 	// ユーザー作成エンドポイント
 	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
@@ -1103,12 +1371,8 @@ func main() {
 		}
 		
 		var user User
-		lang := r.Header.Get("Accept-Language")
-		if lang == "" {
-			lang = "en"
-		}
-		
-		if err := validator.ValidateRequest(w, r, &user, lang); err != nil {
+		// メッセージの言語は Accept-Language ヘッダーから決定される
+		if err := validator.ValidateRequest(w, r, &user, ""); err != nil {
 			return // エラーレスポンスは ValidateRequest 内で処理
 		}
 		
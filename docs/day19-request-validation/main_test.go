@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -275,6 +276,268 @@ func TestRequestValidator_BusinessRules(t *testing.T) {
 	t.Log("Business rule validation working")
 }
 
+// blockingSecurityRule は常にリクエストをブロックするセキュリティルール
+type blockingSecurityRule struct {
+	calls int
+}
+
+func (b *blockingSecurityRule) Validate(data interface{}, r *http.Request) []ValidationError {
+	b.calls++
+	return []ValidationError{{Field: "request", Message: "blocked", Code: "BLOCKED"}}
+}
+
+// countingBusinessRule は呼び出し回数を記録するビジネスルール
+type countingBusinessRule struct {
+	calls int
+}
+
+func (c *countingBusinessRule) Validate(data interface{}) []ValidationError {
+	c.calls++
+	return nil
+}
+
+func TestRequestValidator_Pipeline(t *testing.T) {
+	validUser := User{
+		ID:       "123",
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "Test123!",
+		Name:     "Test User",
+		Age:      25,
+	}
+
+	tests := []struct {
+		name          string
+		user          User
+		contentType   string
+		setup         func(rv *RequestValidator)
+		expectCode    string
+		expectField   string
+		businessCalls int
+		securityCalls int
+	}{
+		{
+			name:          "Valid user",
+			user:          validUser,
+			contentType:   "application/json; charset=utf-8",
+			businessCalls: 1,
+		},
+		{
+			name:        "Invalid content type",
+			user:        validUser,
+			contentType: "text/plain",
+			expectCode:  "INVALID_CONTENT_TYPE",
+			expectField: "Content-Type",
+		},
+		{
+			name: "Missing required field",
+			user: func() User {
+				u := validUser
+				u.Name = ""
+				return u
+			}(),
+			contentType: "application/json",
+			expectCode:  "REQUIRED",
+			expectField: "name",
+		},
+		{
+			name:        "Custom validator failure",
+			user:        validUser,
+			contentType: "application/json",
+			setup: func(rv *RequestValidator) {
+				rv.RegisterValidator("password_strength", func(interface{}) bool { return false })
+			},
+			expectCode:  "WEAK_PASSWORD",
+			expectField: "password",
+		},
+		{
+			name:        "Security rule block",
+			user:        validUser,
+			contentType: "application/json",
+			setup: func(rv *RequestValidator) {
+				rv.AddSecurityRule(&blockingSecurityRule{})
+			},
+			expectCode:    "BLOCKED",
+			expectField:   "request",
+			businessCalls: 1,
+			securityCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewRequestValidator()
+			business := &countingBusinessRule{}
+			validator.AddBusinessRule(business)
+			if tt.setup != nil {
+				tt.setup(validator)
+			}
+
+			jsonData, _ := json.Marshal(tt.user)
+			req := httptest.NewRequest("POST", "/users", bytes.NewReader(jsonData))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			err := validator.ValidateRequest(w, req, &User{}, "en")
+
+			if business.calls != tt.businessCalls {
+				t.Errorf("Expected business rule to run %d times, got %d", tt.businessCalls, business.calls)
+			}
+			for _, rule := range validator.securityRules {
+				if b, ok := rule.(*blockingSecurityRule); ok && b.calls != tt.securityCalls {
+					t.Errorf("Expected security rule to run %d times, got %d", tt.securityCalls, b.calls)
+				}
+			}
+
+			if tt.expectCode == "" {
+				if err != nil {
+					t.Fatalf("Expected validation to pass, got %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrValidationFailed) {
+				t.Fatalf("Expected ErrValidationFailed, got %v", err)
+			}
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected JSON response, got %s", ct)
+			}
+
+			var response struct {
+				Error   string            `json:"error"`
+				Details []ValidationError `json:"details"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Details) != 1 {
+				t.Fatalf("Expected 1 error detail, got %+v", response.Details)
+			}
+			if response.Details[0].Code != tt.expectCode || response.Details[0].Field != tt.expectField {
+				t.Errorf("Expected %s on %s, got %s on %s",
+					tt.expectCode, tt.expectField, response.Details[0].Code, response.Details[0].Field)
+			}
+		})
+	}
+}
+
+func TestRequestValidator_StructTags(t *testing.T) {
+	validator := NewRequestValidator()
+
+	fieldCodes := func(errs []ValidationError) map[string]string {
+		codes := make(map[string]string)
+		for _, e := range errs {
+			codes[e.Field] = e.Code
+		}
+		return codes
+	}
+
+	tests := []struct {
+		name     string
+		data     interface{}
+		expected map[string]string
+	}{
+		{
+			name: "Order with empty items",
+			data: &Order{
+				UserID: "user-1",
+				Items:  []OrderItem{},
+				Total:  100,
+			},
+			expected: map[string]string{"items": "REQUIRED"},
+		},
+		{
+			name: "Order with invalid nested items",
+			data: &Order{
+				UserID: "user-1",
+				Items: []OrderItem{
+					{ProductID: "p-1", Quantity: 2, UnitPrice: 10},
+					{ProductID: "", Quantity: 0, UnitPrice: -1},
+				},
+				Total: 20,
+			},
+			expected: map[string]string{
+				"items[1].product_id": "REQUIRED",
+				"items[1].quantity":   "MIN_VALUE",
+				"items[1].unit_price": "MIN_VALUE",
+			},
+		},
+		{
+			name: "User with out-of-range age and short username",
+			data: &User{
+				ID:       "123",
+				Email:    "test@example.com",
+				Username: "ab",
+				Password: "Test123!",
+				Name:     "Test User",
+				Age:      130,
+			},
+			expected: map[string]string{
+				"username": "MIN_VALUE",
+				"age":      "MAX_VALUE",
+			},
+		},
+		{
+			name: "Multibyte name counted by characters",
+			data: &User{
+				ID:       "123",
+				Email:    "test@example.com",
+				Username: "testuser",
+				Password: "Test123!",
+				Name:     "山田",
+				Age:      30,
+			},
+			expected: map[string]string{},
+		},
+		{
+			name: "Optional empty fields skipped",
+			data: &User{
+				ID:       "123",
+				Email:    "test@example.com",
+				Username: "testuser",
+				Password: "Test123!",
+				Name:     "Test User",
+				Age:      30,
+				Website:  "",
+				Phone:    "",
+			},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldCodes(validator.validateStruct(tt.data))
+
+			if len(got) != len(tt.expected) {
+				t.Errorf("Expected errors %v, got %v", tt.expected, got)
+			}
+			for field, code := range tt.expected {
+				if got[field] != code {
+					t.Errorf("Expected %s on %s, got %q", code, field, got[field])
+				}
+			}
+		})
+	}
+}
+
+func TestParseValidationTags(t *testing.T) {
+	rules := parseValidationTags("required, min=3,,max=20 ")
+	expected := []string{"required", "min=3", "max=20"}
+
+	if len(rules) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, rules)
+	}
+	for i := range expected {
+		if rules[i] != expected[i] {
+			t.Errorf("Expected rule %q at %d, got %q", expected[i], i, rules[i])
+		}
+	}
+}
+
 func TestRequestValidator_Localization(t *testing.T) {
 	validator := NewRequestValidator()
 	validator.RegisterValidator("email", EmailValidator)
@@ -490,6 +753,151 @@ func TestValidationCache(t *testing.T) {
 	}
 }
 
+func TestRequestValidator_CacheReuse(t *testing.T) {
+	validator := NewRequestValidator()
+	validator.cache = NewValidationCache(50 * time.Millisecond)
+
+	calls := 0
+	validator.RegisterValidator("password_strength", func(value interface{}) bool {
+		calls++
+		return PasswordStrengthValidator(value)
+	})
+
+	user := User{
+		ID:       "123",
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "weak-password",
+		Name:     "Test User",
+		Age:      25,
+	}
+	jsonData, _ := json.Marshal(user)
+
+	validate := func() (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest("POST", "/users", bytes.NewReader(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		return w, validator.ValidateRequest(w, req, &User{}, "en")
+	}
+
+	// 1回目: 計算してキャッシュ
+	if _, err := validate(); err == nil {
+		t.Fatal("Expected validation to fail")
+	}
+	if calls != 1 {
+		t.Fatalf("Expected custom validator to run once, got %d", calls)
+	}
+
+	// 2回目: 同じペイロードはキャッシュを再利用
+	w, err := validate()
+	if err == nil {
+		t.Fatal("Expected cached validation to fail")
+	}
+	if calls != 1 {
+		t.Errorf("Expected cached result within TTL, but validator ran %d times", calls)
+	}
+	if !strings.Contains(w.Body.String(), "WEAK_PASSWORD") {
+		t.Errorf("Expected cached error in response, got %s", w.Body.String())
+	}
+
+	metrics := validator.metrics.GetMetrics()
+	if hits := metrics["cache_hits"].(int64); hits != 1 {
+		t.Errorf("Expected 1 cache hit, got %d", hits)
+	}
+	if misses := metrics["cache_misses"].(int64); misses != 1 {
+		t.Errorf("Expected 1 cache miss, got %d", misses)
+	}
+
+	// TTL経過後は再計算される
+	time.Sleep(80 * time.Millisecond)
+	if _, err := validate(); err == nil {
+		t.Fatal("Expected validation to fail after expiry")
+	}
+	if calls != 2 {
+		t.Errorf("Expected validator to rerun after TTL, got %d calls", calls)
+	}
+	if misses := validator.metrics.GetMetrics()["cache_misses"].(int64); misses != 2 {
+		t.Errorf("Expected 2 cache misses, got %d", misses)
+	}
+}
+
+func TestRequestValidator_CacheSkippedWithoutBody(t *testing.T) {
+	validator := NewRequestValidator()
+
+	validate := func(target *User) error {
+		req := httptest.NewRequest("GET", "/users", nil)
+		return validator.ValidateRequest(httptest.NewRecorder(), req, target, "en")
+	}
+
+	valid := &User{
+		ID:       "123",
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "Password123!",
+		Name:     "Test User",
+		Age:      25,
+	}
+	if err := validate(valid); err != nil {
+		t.Fatalf("Expected pre-filled valid user to pass, got %v", err)
+	}
+
+	// ボディがない場合は既存の値が検証されるため、前回の結果を使い回してはいけない
+	if errs := validator.validateStruct(&User{}); len(errs) == 0 {
+		t.Fatal("Expected empty user to have struct validation errors")
+	}
+	if err := validate(&User{}); err == nil {
+		t.Error("Expected empty user without body to fail validation")
+	}
+
+	metrics := validator.metrics.GetMetrics()
+	if hits, misses := metrics["cache_hits"].(int64), metrics["cache_misses"].(int64); hits != 0 || misses != 0 {
+		t.Errorf("Expected requests without body to bypass the cache, got %d hits and %d misses", hits, misses)
+	}
+}
+
+func TestRequestValidator_RegisterValidatorClearsCache(t *testing.T) {
+	validator := NewRequestValidator()
+
+	user := User{
+		ID:       "123",
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "weak-password",
+		Name:     "Test User",
+		Age:      25,
+	}
+	jsonData, _ := json.Marshal(user)
+
+	validate := func() error {
+		req := httptest.NewRequest("POST", "/users", bytes.NewReader(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		return validator.ValidateRequest(httptest.NewRecorder(), req, &User{}, "en")
+	}
+
+	if err := validate(); err == nil {
+		t.Fatal("Expected weak password to fail")
+	}
+
+	validator.RegisterValidator("password_strength", func(value interface{}) bool { return true })
+	if err := validate(); err != nil {
+		t.Errorf("Expected result computed with the new validator, got %v", err)
+	}
+}
+
+func TestGenerateCacheKey(t *testing.T) {
+	payload := []byte(`{"id":"1"}`)
+
+	if generateCacheKey(&User{}, payload) != generateCacheKey(&User{}, payload) {
+		t.Error("Identical payloads should produce the same key")
+	}
+	if generateCacheKey(&User{}, payload) == generateCacheKey(&User{}, []byte(`{"id":"2"}`)) {
+		t.Error("Different payloads should produce different keys")
+	}
+	if generateCacheKey(&User{}, payload) == generateCacheKey(&Order{}, payload) {
+		t.Error("Different target types should produce different keys")
+	}
+}
+
 func TestValidationMetrics(t *testing.T) {
 	metrics := NewValidationMetrics()
 	
@@ -559,6 +967,86 @@ func TestSimpleTranslator(t *testing.T) {
 	}
 }
 
+func TestSimpleTranslator_PlaceholdersAndFallback(t *testing.T) {
+	translator := NewSimpleTranslator("en")
+	translator.AddTranslation("required", "en", "{field} is required")
+	translator.AddTranslation("required", "ja", "{field}は必須です")
+	translator.AddTranslation("min", "en", "{field} must be at least {min}")
+
+	tests := []struct {
+		name     string
+		code     string
+		lang     string
+		params   map[string]interface{}
+		expected string
+	}{
+		{"English", "required", "en", map[string]interface{}{"field": "email"}, "email is required"},
+		{"Japanese", "required", "ja", map[string]interface{}{"field": "email"}, "emailは必須です"},
+		{"Region falls back to base language", "required", "ja-JP", map[string]interface{}{"field": "name"}, "nameは必須です"},
+		{"Unknown language falls back to default", "required", "fr", map[string]interface{}{"field": "age"}, "age is required"},
+		{"Missing translation falls back to default", "min", "ja", map[string]interface{}{"field": "age", "min": 18}, "age must be at least 18"},
+		{"Unknown code returns code", "unknown", "ja", nil, "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := translator.Translate(tt.code, tt.lang, tt.params)
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRequestValidator_AcceptLanguage(t *testing.T) {
+	validator := NewRequestValidator()
+
+	invalidUser := User{
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "Test123!",
+		Name:     "Test User",
+		Age:      25,
+	}
+
+	tests := []struct {
+		acceptLanguage  string
+		expected        string
+		contentLanguage string
+	}{
+		{"ja-JP,ja;q=0.9,en;q=0.8", "必須項目です", "ja"},
+		{"fr;q=0.9, en;q=0.5", "Field is required", "en"},
+		{"de, en;q=0.1, ja;q=0.6", "必須項目です", "ja"},
+		{"", "Field is required", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run("Accept-Language_"+tt.acceptLanguage, func(t *testing.T) {
+			jsonData, _ := json.Marshal(invalidUser)
+			req := httptest.NewRequest("POST", "/users", bytes.NewReader(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			w := httptest.NewRecorder()
+
+			if err := validator.ValidateRequest(w, req, &User{}, ""); err == nil {
+				t.Fatal("Expected validation to fail")
+			}
+
+			var response struct {
+				Details []ValidationError `json:"details"`
+			}
+			json.NewDecoder(w.Body).Decode(&response)
+
+			if len(response.Details) == 0 || response.Details[0].Message != tt.expected {
+				t.Errorf("Expected message '%s', got %+v", tt.expected, response.Details)
+			}
+			if cl := w.Header().Get("Content-Language"); cl != tt.contentLanguage {
+				t.Errorf("Expected Content-Language '%s', got '%s'", tt.contentLanguage, cl)
+			}
+		})
+	}
+}
+
 func TestSQLInjectionRule(t *testing.T) {
 	rule := &SQLInjectionRule{}
 	
@@ -669,6 +1157,134 @@ func TestContainsSQLInjectionPattern(t *testing.T) {
 	}
 }
 
+func TestRequestValidator_SecurityRules(t *testing.T) {
+	validator := NewRequestValidator()
+	validator.AddSecurityRule(&SQLInjectionRule{})
+	validator.AddSecurityRule(&XSSRule{})
+
+	base := User{
+		ID:       "123",
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "Test123!",
+		Name:     "Test User",
+		Age:      25,
+	}
+
+	tests := []struct {
+		name       string
+		mutate     func(u *User)
+		expectCode string
+		expectPass bool
+	}{
+		{
+			name:       "Clean payload",
+			mutate:     func(u *User) {},
+			expectPass: true,
+		},
+		{
+			name:       "UNION SELECT payload",
+			mutate:     func(u *User) { u.Name = "x UNION SELECT pw FROM users" },
+			expectCode: "SQL_INJECTION",
+		},
+		{
+			name:       "Inline script payload",
+			mutate:     func(u *User) { u.Name = "<script>alert(1)</script>" },
+			expectCode: "XSS_DETECTED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := base
+			tt.mutate(&user)
+
+			jsonData, _ := json.Marshal(user)
+			req := httptest.NewRequest("POST", "/users", bytes.NewReader(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			err := validator.ValidateRequest(w, req, &User{}, "en")
+			if tt.expectPass {
+				if err != nil {
+					t.Fatalf("Expected validation to pass, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrValidationFailed) {
+				t.Fatalf("Expected payload to be blocked, got %v", err)
+			}
+
+			var response struct {
+				Details []ValidationError `json:"details"`
+			}
+			json.NewDecoder(w.Body).Decode(&response)
+
+			found := false
+			for _, detail := range response.Details {
+				if detail.Code == tt.expectCode {
+					found = true
+					if detail.Field != "name" {
+						t.Errorf("Expected field 'name', got '%s'", detail.Field)
+					}
+					if detail.Value != "***" {
+						t.Errorf("Expected payload to be masked, got %v", detail.Value)
+					}
+					if detail.Metadata["category"] != "security" {
+						t.Errorf("Expected security category, got %v", detail.Metadata)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("Expected %s in %+v", tt.expectCode, response.Details)
+			}
+		})
+	}
+}
+
+func TestXSSRule_NestedFields(t *testing.T) {
+	order := &Order{
+		UserID: "user-1",
+		Items: []OrderItem{
+			{ProductID: "p-1", Quantity: 1, UnitPrice: 10},
+			{ProductID: `<img src=x onerror=alert(1)>`, Quantity: 1, UnitPrice: 10},
+		},
+		Total: 20,
+	}
+
+	errs := (&XSSRule{}).Validate(order, httptest.NewRequest("POST", "/orders", nil))
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 XSS error, got %+v", errs)
+	}
+	if errs[0].Field != "items[1].product_id" {
+		t.Errorf("Expected field items[1].product_id, got %s", errs[0].Field)
+	}
+}
+
+func TestContainsXSSPattern(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"normal text", false},
+		{"I like javascript", false},
+		{"<script>alert('xss')</script>", true},
+		{"< SCRIPT src=evil.js>", true},
+		{"javascript:alert(1)", true},
+		{`<img src=x onerror="alert(1)">`, true},
+		{"one rror = fine", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := containsXSSPattern(tt.input)
+			if result != tt.expected {
+				t.Errorf("containsXSSPattern(%s) = %v, expected %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGetClientIP(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -3,13 +3,18 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -23,17 +28,41 @@ const (
 
 // LoggingMiddleware provides structured logging for HTTP requests
 type LoggingMiddleware struct {
-	logger *slog.Logger
+	logger        *slog.Logger
+	metrics       *RequestMetrics
+	tracer        *Tracer
+	sampleRate    float64
+	slowThreshold time.Duration
+	random        func() float64 // returns a value in [0, 1) for sampling decisions
+}
+
+// Options configures NewLoggingMiddlewareWithOptions
+type Options struct {
+	// Level is the minimum level that is written
+	Level slog.Level
+	// SampleRate is the fraction of successful requests that are logged.
+	// 4xx/5xx responses are always logged. 0 or >= 1 logs every request.
+	SampleRate float64
+	// SlowThreshold escalates requests taking at least this long to warn. 0 disables it.
+	SlowThreshold time.Duration
+	// Output is where logs are written. Defaults to os.Stdout.
+	Output io.Writer
 }
 
 // NewLoggingMiddleware creates a new logging middleware
 func NewLoggingMiddleware() *LoggingMiddleware {
+	return NewLoggingMiddlewareWithOptions(Options{Level: slog.LevelInfo})
+}
+
+// NewLoggingMiddlewareWithOptions creates a logging middleware with a custom
+// level, sampling of successful requests and slow request escalation
+func NewLoggingMiddlewareWithOptions(opts Options) *LoggingMiddleware {
 	// TODO: 実装してください
 	//
 	// 実装の流れ:
-	// 1. slog.JSONハンドラーでJSON形式のログを設定
-	// 2. ログレベルを設定
-	// 3. LoggingMiddlewareを作成
+	// 1. slog.JSONハンドラーでJSON形式のログを設定（出力先はopts.Output、未指定ならos.Stdout）
+	// 2. ログレベルをopts.Levelに設定
+	// 3. サンプリング率・スロー判定の閾値・乱数源を持つLoggingMiddlewareを作成
 	return nil
 }
 
@@ -49,6 +78,20 @@ func (lm *LoggingMiddleware) Middleware(next http.Handler) http.Handler {
 	return nil
 }
 
+// sampled reports whether a request should be logged under the sample rate
+func (lm *LoggingMiddleware) sampled() bool {
+	// TODO: 実装してください
+	//
+	// sampleRateが0以下または1以上なら常にtrue、それ以外はrandom()と比較する
+	return true
+}
+
+// isSlow reports whether duration reaches the slow request threshold
+func (lm *LoggingMiddleware) isSlow(duration time.Duration) bool {
+	// TODO: 実装してください
+	return false
+}
+
 // logRequest logs request information
 func (lm *LoggingMiddleware) logRequest(r *http.Request, event string) {
 	// TODO: 実装してください
@@ -88,6 +131,227 @@ func (rw *responseWriter) Write(data []byte) (int, error) {
 	return 0, nil
 }
 
+// Flush forwards to the underlying writer so streaming responses (e.g. SSE)
+// still work behind the middleware. It is a no-op if flushing is unsupported.
+func (rw *responseWriter) Flush() {
+	// TODO: 実装してください
+	//
+	// 実装の流れ:
+	// 1. 元のResponseWriterがhttp.Flusherを実装しているか確認
+	// 2. 実装していればヘッダー送信済みとしてFlushを呼び出す
+}
+
+// Hijack forwards to the underlying writer so connection upgrades
+// (e.g. WebSocket) still work behind the middleware
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	// TODO: 実装してください
+	//
+	// 実装の流れ:
+	// 1. 元のResponseWriterがhttp.Hijackerを実装しているか確認
+	// 2. 実装していなければhttp.ErrNotSupportedを返す
+	return nil, nil, http.ErrNotSupported
+}
+
+// defaultMaxBodyBytes caps captured bodies when BodyLoggingOptions.MaxBodyBytes is unset
+const defaultMaxBodyBytes = 4096
+
+// BodyLoggingOptions configures BodyLoggingMiddleware
+type BodyLoggingOptions struct {
+	// Enabled turns body logging on. When false the middleware is a no-op.
+	Enabled bool
+	// MaxBodyBytes is the maximum number of request and response bytes captured
+	MaxBodyBytes int
+	// ContentTypes lists the media types whose bodies are captured. Defaults to application/json.
+	ContentTypes []string
+	// Redactor masks secrets in a captured body before it is logged
+	Redactor func([]byte) []byte
+}
+
+// BodyLoggingMiddleware logs the request body and the first bytes of the
+// response body for debugging. Only the first MaxBodyBytes are kept in memory,
+// and the handler still reads the complete request body.
+func (lm *LoggingMiddleware) BodyLoggingMiddleware(opts BodyLoggingOptions) func(http.Handler) http.Handler {
+	// TODO: 実装してください
+	//
+	// 実装の流れ:
+	// 1. MaxBodyBytes・ContentTypes・Redactorのデフォルト値を設定
+	// 2. Enabledがfalseなら次のハンドラーをそのまま返す
+	// 3. 対象のContent-Typeなら、リクエストボディを最大MaxBodyBytes+1バイト読み込み、
+	//    読み込んだ分と残りをio.MultiReaderでつなげてr.Bodyに戻す
+	// 4. bodyCaptureWriterでレスポンスの先頭MaxBodyBytesバイトを記録
+	// 5. Redactorを適用したボディをログ出力
+	return nil
+}
+
+// RedactJSONFields returns a Redactor that masks the string values of the
+// given JSON keys. It works on truncated bodies because it does not parse JSON.
+func RedactJSONFields(fields ...string) func([]byte) []byte {
+	// TODO: 実装してください
+	return nil
+}
+
+// matchesContentType reports whether the media type of contentType is in allowed
+func matchesContentType(contentType string, allowed []string) bool {
+	// TODO: 実装してください
+	return false
+}
+
+// bodyCaptureWriter keeps up to limit bytes of the response body
+type bodyCaptureWriter struct {
+	*responseWriter
+	limit     int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	// TODO: 実装してください
+	return 0, nil
+}
+
+// SpanContextKey stores the active request span in the context
+const SpanContextKey contextKey = "span"
+
+// RequestLabels identifies a request consistently across logs, metrics and spans.
+// Route is the normalized path (see RouteFor) so that label cardinality stays bounded.
+type RequestLabels struct {
+	Method string
+	Route  string
+	Status int
+}
+
+// DefaultDurationBuckets are the upper bounds of the request duration histogram
+var DefaultDurationBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// RequestStats aggregates the requests observed for one label set.
+// Buckets[i] counts requests with a duration <= DefaultDurationBuckets[i];
+// the last element counts the ones above every bound.
+type RequestStats struct {
+	Count   int64
+	Sum     time.Duration
+	Buckets []int64
+}
+
+// RequestMetrics keeps an active request gauge and a request duration histogram in memory
+type RequestMetrics struct {
+	mu     sync.Mutex
+	active map[string]int64 // "METHOD route" -> in-flight requests
+	stats  map[RequestLabels]*RequestStats
+}
+
+// NewRequestMetrics creates an empty metrics store
+func NewRequestMetrics() *RequestMetrics {
+	// TODO: 実装してください
+	return nil
+}
+
+// ActiveRequests returns the number of in-flight requests for method and route
+func (m *RequestMetrics) ActiveRequests(method, route string) int64 {
+	// TODO: 実装してください
+	return 0
+}
+
+// Stats returns a copy of the aggregated request durations per label set
+func (m *RequestMetrics) Stats() map[RequestLabels]RequestStats {
+	// TODO: 実装してください
+	//
+	// ヒント: Buckets スライスもコピーして呼び出し側に内部状態を共有しない
+	return nil
+}
+
+// RouteFor returns the route label for a request path. Segments that look
+// like identifiers (numbers, UUIDs, long hex strings) are replaced with
+// ":id" so that /users/1 and /users/2 share one series.
+func RouteFor(path string) string {
+	// TODO: 実装してください
+	//
+	// ヒント: パスを "/" で分割し、数値・UUID・長い16進数のセグメントを ":id" に置き換える
+	return path
+}
+
+// Span is a minimal trace span covering one request
+type Span struct {
+	Name     string
+	TraceID  string
+	SpanID   string
+	Start    time.Time
+	Duration time.Duration
+	Labels   RequestLabels
+	Error    bool
+}
+
+// DefaultMaxSpans is the default number of finished spans a Tracer keeps
+const DefaultMaxSpans = 1000
+
+// Tracer creates spans and keeps the most recent finished ones for inspection
+type Tracer struct {
+	mu       sync.Mutex
+	maxSpans int
+	finished []Span
+}
+
+// NewTracer creates a tracer with no recorded spans
+func NewTracer() *Tracer {
+	// TODO: 実装してください
+	return nil
+}
+
+// SetMaxSpans changes how many finished spans are kept; older ones are dropped
+func (t *Tracer) SetMaxSpans(n int) {
+	// TODO: 実装してください
+}
+
+// Start begins a span and stores it in the returned context
+func (t *Tracer) Start(ctx context.Context, name string, start time.Time) (context.Context, *Span) {
+	// TODO: 実装してください
+	return ctx, nil
+}
+
+// End finishes span with the given labels and duration
+func (t *Tracer) End(span *Span, labels RequestLabels, duration time.Duration) {
+	// TODO: 実装してください
+	//
+	// ヒント: maxSpans を超えた古いスパンは破棄する
+}
+
+// FinishedSpans returns a copy of the retained finished spans, oldest first
+func (t *Tracer) FinishedSpans() []Span {
+	// TODO: 実装してください
+	return nil
+}
+
+// SpanFromContext returns the request span, if any
+func SpanFromContext(ctx context.Context) (*Span, bool) {
+	// TODO: 実装してください
+	return nil, false
+}
+
+// ObservabilityMiddleware starts a span, tracks active requests, and logs and
+// records metrics for each request. The duration and status are measured once
+// and shared by all three signals so they always agree.
+func (lm *LoggingMiddleware) ObservabilityMiddleware(next http.Handler) http.Handler {
+	// TODO: 実装してください
+	//
+	// 実装の流れ:
+	// 1. 開始時刻を記録し、スパンを開始してcontextに追加
+	// 2. RouteFor で生のパスではなくルートをラベルにし、アクティブリクエスト数を増やす（終了時に減らす）
+	// 3. request_start ログを出力
+	// 4. レスポンスライターをラップして次のハンドラーを実行
+	// 5. 処理時間とステータスを一度だけ計測し、スパン・メトリクス・ログで同じ値を使う
+	return nil
+}
+
 // generateRequestID generates a unique request ID
 func generateRequestID() string {
 	// TODO: 実装してください
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
+	mathrand "math/rand"
+	"mime"
+	"net"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,25 +31,58 @@ const (
 
 // LoggingMiddleware provides structured logging for HTTP requests
 type LoggingMiddleware struct {
-	logger *slog.Logger
+	logger        *slog.Logger
+	metrics       *RequestMetrics
+	tracer        *Tracer
+	sampleRate    float64
+	slowThreshold time.Duration
+	random        func() float64 // returns a value in [0, 1) for sampling decisions
+}
+
+// Options configures NewLoggingMiddlewareWithOptions
+type Options struct {
+	// Level is the minimum level that is written
+	Level slog.Level
+	// SampleRate is the fraction of successful requests that are logged.
+	// 4xx/5xx responses are always logged. 0 or >= 1 logs every request.
+	SampleRate float64
+	// SlowThreshold escalates requests taking at least this long to warn. 0 disables it.
+	SlowThreshold time.Duration
+	// Output is where logs are written. Defaults to os.Stdout.
+	Output io.Writer
 }
 
 // NewLoggingMiddleware creates a new logging middleware
 func NewLoggingMiddleware() *LoggingMiddleware {
+	return NewLoggingMiddlewareWithOptions(Options{Level: slog.LevelInfo})
+}
+
+// NewLoggingMiddlewareWithOptions creates a logging middleware with a custom
+// level, sampling of successful requests and slow request escalation
+func NewLoggingMiddlewareWithOptions(opts Options) *LoggingMiddleware {
 	// TODO: 実装してください
 	//
 	// 実装の流れ:
 	// 1. slog.JSONハンドラーでJSON形式のログを設定
 	// 2. ログレベルを設定
 	// 3. LoggingMiddlewareを作成
-	
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	handler := slog.NewJSONHandler(output, &slog.HandlerOptions{
+		Level: opts.Level,
 	})
 	logger := slog.New(handler)
-	
+
 	return &LoggingMiddleware{
-		logger: logger,
+		logger:        logger,
+		metrics:       NewRequestMetrics(),
+		tracer:        NewTracer(),
+		sampleRate:    opts.SampleRate,
+		slowThreshold: opts.SlowThreshold,
+		random:        mathrand.Float64,
 	}
 }
 
@@ -83,18 +126,38 @@ func (lm *LoggingMiddleware) Middleware(next http.Handler) http.Handler {
 			statusCode:     http.StatusOK,
 		}
 		
+		// Decide up front so a sampled request gets both its start and completion logs
+		sampled := lm.sampled()
+
 		// Log request start
-		lm.logRequest(r, "request_start")
+		if sampled {
+			lm.logRequest(r, "request_start")
+		}
 		
 		// Process request
 		next.ServeHTTP(wrapped, r)
 		
-		// Log request completion
+		// Log request completion. Errors and slow requests are logged even when not sampled.
 		duration := time.Since(start)
-		lm.logRequestComplete(r, wrapped.statusCode, wrapped.bytesWritten, duration)
+		if sampled || wrapped.statusCode >= 400 || lm.isSlow(duration) {
+			lm.logRequestComplete(r, wrapped.statusCode, wrapped.bytesWritten, duration)
+		}
 	})
 }
 
+// sampled reports whether a request should be logged under the sample rate
+func (lm *LoggingMiddleware) sampled() bool {
+	if lm.sampleRate <= 0 || lm.sampleRate >= 1 {
+		return true
+	}
+	return lm.random() < lm.sampleRate
+}
+
+// isSlow reports whether duration reaches the slow request threshold
+func (lm *LoggingMiddleware) isSlow(duration time.Duration) bool {
+	return lm.slowThreshold > 0 && duration >= lm.slowThreshold
+}
+
 // logRequest logs request information
 func (lm *LoggingMiddleware) logRequest(r *http.Request, event string) {
 	// TODO: 実装してください
@@ -129,8 +192,9 @@ func (lm *LoggingMiddleware) logRequestComplete(r *http.Request, statusCode int,
 	requestID, _ := r.Context().Value(RequestIDKey).(string)
 	userID, _ := r.Context().Value(UserIDKey).(string)
 	
+	slow := lm.isSlow(duration)
 	logLevel := slog.LevelInfo
-	if statusCode >= 400 {
+	if statusCode >= 400 || slow {
 		logLevel = slog.LevelWarn
 	}
 	if statusCode >= 500 {
@@ -145,6 +209,7 @@ func (lm *LoggingMiddleware) logRequestComplete(r *http.Request, statusCode int,
 		"response_size_bytes", bytesWritten,
 		"duration_ms", duration.Milliseconds(),
 		"user_id", userID,
+		"slow", slow,
 	)
 }
 
@@ -225,6 +290,439 @@ func (rw *responseWriter) Write(data []byte) (int, error) {
 	return n, err
 }
 
+// Flush forwards to the underlying writer so streaming responses (e.g. SSE)
+// still work behind the middleware. It is a no-op if flushing is unsupported.
+func (rw *responseWriter) Flush() {
+	flusher, ok := rw.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	// Flushing commits the headers with the current status code
+	rw.headerWritten = true
+	flusher.Flush()
+}
+
+// Hijack forwards to the underlying writer so connection upgrades
+// (e.g. WebSocket) still work behind the middleware
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijack: %w", http.ErrNotSupported)
+	}
+	return hijacker.Hijack()
+}
+
+// defaultMaxBodyBytes caps captured bodies when BodyLoggingOptions.MaxBodyBytes is unset
+const defaultMaxBodyBytes = 4096
+
+// BodyLoggingOptions configures BodyLoggingMiddleware
+type BodyLoggingOptions struct {
+	// Enabled turns body logging on. When false the middleware is a no-op.
+	Enabled bool
+	// MaxBodyBytes is the maximum number of request and response bytes captured
+	MaxBodyBytes int
+	// ContentTypes lists the media types whose bodies are captured. Defaults to application/json.
+	ContentTypes []string
+	// Redactor masks secrets in a captured body before it is logged
+	Redactor func([]byte) []byte
+}
+
+// BodyLoggingMiddleware logs the request body and the first bytes of the
+// response body for debugging. Only the first MaxBodyBytes are kept in memory,
+// and the handler still reads the complete request body.
+func (lm *LoggingMiddleware) BodyLoggingMiddleware(opts BodyLoggingOptions) func(http.Handler) http.Handler {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = []string{"application/json"}
+	}
+	if opts.Redactor == nil {
+		opts.Redactor = func(body []byte) []byte { return body }
+	}
+
+	return func(next http.Handler) http.Handler {
+		if !opts.Enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var requestBody []byte
+			var requestTruncated bool
+			captureRequest := r.Body != nil && matchesContentType(r.Header.Get("Content-Type"), opts.ContentTypes)
+			if captureRequest {
+				// Read one extra byte to detect truncation, then put everything read back in front of the body
+				head, err := io.ReadAll(io.LimitReader(r.Body, int64(opts.MaxBodyBytes)+1))
+				if err != nil {
+					lm.logger.WarnContext(r.Context(), "request_body_read_failed",
+						"request_id", requestIDFrom(r.Context()),
+						"error", err,
+					)
+				}
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+
+				requestTruncated = len(head) > opts.MaxBodyBytes
+				if requestTruncated {
+					head = head[:opts.MaxBodyBytes]
+				}
+				requestBody = head
+			}
+
+			captured := &bodyCaptureWriter{
+				responseWriter: &responseWriter{ResponseWriter: w, statusCode: http.StatusOK},
+				limit:          opts.MaxBodyBytes,
+			}
+			next.ServeHTTP(captured, r)
+
+			captureResponse := matchesContentType(captured.Header().Get("Content-Type"), opts.ContentTypes)
+			if !captureRequest && !captureResponse {
+				return
+			}
+
+			attrs := []any{
+				"request_id", requestIDFrom(r.Context()),
+				"method", r.Method,
+				"url", r.URL.String(),
+				"status_code", captured.statusCode,
+			}
+			if captureRequest {
+				attrs = append(attrs,
+					"request_body", string(opts.Redactor(requestBody)),
+					"request_body_truncated", requestTruncated,
+				)
+			}
+			if captureResponse {
+				attrs = append(attrs,
+					"response_body", string(opts.Redactor(captured.body.Bytes())),
+					"response_body_truncated", captured.truncated,
+				)
+			}
+			lm.logger.InfoContext(r.Context(), "http_body", attrs...)
+		})
+	}
+}
+
+// RedactJSONFields returns a Redactor that masks the string values of the
+// given JSON keys. It works on truncated bodies because it does not parse JSON.
+func RedactJSONFields(fields ...string) func([]byte) []byte {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	pattern := regexp.MustCompile(`("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	return func(body []byte) []byte {
+		return pattern.ReplaceAll(body, []byte(`${1}"[REDACTED]"`))
+	}
+}
+
+// matchesContentType reports whether the media type of contentType is in allowed
+func matchesContentType(contentType string, allowed []string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range allowed {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyCaptureWriter keeps up to limit bytes of the response body
+type bodyCaptureWriter struct {
+	*responseWriter
+	limit     int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	if remaining := w.limit - w.body.Len(); remaining < len(data) {
+		w.body.Write(data[:max(remaining, 0)])
+		w.truncated = true
+	} else {
+		w.body.Write(data)
+	}
+	return w.responseWriter.Write(data)
+}
+
+// SpanContextKey stores the active request span in the context
+const SpanContextKey contextKey = "span"
+
+// RequestLabels identifies a request consistently across logs, metrics and spans.
+// Route is the normalized path (see RouteFor) so that label cardinality stays bounded.
+type RequestLabels struct {
+	Method string
+	Route  string
+	Status int
+}
+
+// DefaultDurationBuckets are the upper bounds of the request duration histogram
+var DefaultDurationBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// RequestStats aggregates the requests observed for one label set.
+// Buckets[i] counts requests with a duration <= DefaultDurationBuckets[i];
+// the last element counts the ones above every bound.
+type RequestStats struct {
+	Count   int64
+	Sum     time.Duration
+	Buckets []int64
+}
+
+// RequestMetrics keeps an active request gauge and a request duration histogram in memory
+type RequestMetrics struct {
+	mu     sync.Mutex
+	active map[string]int64 // "METHOD route" -> in-flight requests
+	stats  map[RequestLabels]*RequestStats
+}
+
+// NewRequestMetrics creates an empty metrics store
+func NewRequestMetrics() *RequestMetrics {
+	return &RequestMetrics{
+		active: make(map[string]int64),
+		stats:  make(map[RequestLabels]*RequestStats),
+	}
+}
+
+// ActiveRequests returns the number of in-flight requests for method and route
+func (m *RequestMetrics) ActiveRequests(method, route string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active[method+" "+route]
+}
+
+// Stats returns a copy of the aggregated request durations per label set
+func (m *RequestMetrics) Stats() map[RequestLabels]RequestStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make(map[RequestLabels]RequestStats, len(m.stats))
+	for labels, s := range m.stats {
+		buckets := make([]int64, len(s.Buckets))
+		copy(buckets, s.Buckets)
+		result[labels] = RequestStats{Count: s.Count, Sum: s.Sum, Buckets: buckets}
+	}
+	return result
+}
+
+func (m *RequestMetrics) addActive(method, route string, delta int64) {
+	key := method + " " + route
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active[key] += delta
+	if m.active[key] <= 0 {
+		delete(m.active, key)
+	}
+}
+
+func (m *RequestMetrics) observe(labels RequestLabels, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.stats[labels]
+	if !ok {
+		s = &RequestStats{Buckets: make([]int64, len(DefaultDurationBuckets)+1)}
+		m.stats[labels] = s
+	}
+	s.Count++
+	s.Sum += duration
+	i := 0
+	for i < len(DefaultDurationBuckets) && duration > DefaultDurationBuckets[i] {
+		i++
+	}
+	s.Buckets[i]++
+}
+
+var (
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexIDSegment   = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+)
+
+// RouteFor returns the route label for a request path. Segments that look
+// like identifiers (numbers, UUIDs, long hex strings) are replaced with
+// ":id" so that /users/1 and /users/2 share one series.
+func RouteFor(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if numericSegment.MatchString(segment) || uuidSegment.MatchString(segment) || hexIDSegment.MatchString(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// methodLabel keeps arbitrary client supplied methods out of the label set
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// Span is a minimal trace span covering one request
+type Span struct {
+	Name     string
+	TraceID  string
+	SpanID   string
+	Start    time.Time
+	Duration time.Duration
+	Labels   RequestLabels
+	Error    bool
+}
+
+// DefaultMaxSpans is the default number of finished spans a Tracer keeps
+const DefaultMaxSpans = 1000
+
+// Tracer creates spans and keeps the most recent finished ones for inspection
+type Tracer struct {
+	mu       sync.Mutex
+	maxSpans int
+	finished []Span
+}
+
+// NewTracer creates a tracer with no recorded spans
+func NewTracer() *Tracer {
+	return &Tracer{maxSpans: DefaultMaxSpans}
+}
+
+// SetMaxSpans changes how many finished spans are kept; older ones are dropped
+func (t *Tracer) SetMaxSpans(n int) {
+	if n < 1 {
+		n = 1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxSpans = n
+	t.evictLocked()
+}
+
+// Start begins a span and stores it in the returned context
+func (t *Tracer) Start(ctx context.Context, name string, start time.Time) (context.Context, *Span) {
+	span := &Span{
+		Name:    name,
+		TraceID: generateRequestID() + generateRequestID(),
+		SpanID:  generateRequestID(),
+		Start:   start,
+	}
+	return context.WithValue(ctx, SpanContextKey, span), span
+}
+
+// End finishes span with the given labels and duration
+func (t *Tracer) End(span *Span, labels RequestLabels, duration time.Duration) {
+	span.Labels = labels
+	span.Duration = duration
+	span.Error = labels.Status >= 500
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished = append(t.finished, *span)
+	t.evictLocked()
+}
+
+// evictLocked must be called with t.mu held. Reslicing keeps End O(1); the
+// backing array is reallocated by append and never grows past about 2*maxSpans.
+func (t *Tracer) evictLocked() {
+	if t.maxSpans > 0 && len(t.finished) > t.maxSpans {
+		t.finished = t.finished[len(t.finished)-t.maxSpans:]
+	}
+}
+
+// FinishedSpans returns a copy of the retained finished spans, oldest first
+func (t *Tracer) FinishedSpans() []Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]Span, len(t.finished))
+	copy(result, t.finished)
+	return result
+}
+
+// SpanFromContext returns the request span, if any
+func SpanFromContext(ctx context.Context) (*Span, bool) {
+	span, ok := ctx.Value(SpanContextKey).(*Span)
+	return span, ok
+}
+
+// ObservabilityMiddleware starts a span, tracks active requests, and logs and
+// records metrics for each request. The duration and status are measured once
+// and shared by all three signals so they always agree.
+func (lm *LoggingMiddleware) ObservabilityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		path := r.URL.Path
+		method, route := methodLabel(r.Method), RouteFor(path)
+
+		ctx, span := lm.tracer.Start(r.Context(), method+" "+route, start)
+		r = r.WithContext(ctx)
+
+		lm.metrics.addActive(method, route, 1)
+		defer lm.metrics.addActive(method, route, -1)
+
+		lm.logger.InfoContext(ctx, "request_start",
+			"request_id", requestIDFrom(ctx),
+			"trace_id", span.TraceID,
+			"span_id", span.SpanID,
+			"method", method,
+			"route", route,
+			"path", path,
+		)
+
+		wrapped := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		next.ServeHTTP(wrapped, r)
+
+		duration := time.Since(start)
+		labels := RequestLabels{Method: method, Route: route, Status: wrapped.statusCode}
+
+		lm.tracer.End(span, labels, duration)
+		lm.metrics.observe(labels, duration)
+
+		logLevel := slog.LevelInfo
+		if labels.Status >= 500 {
+			logLevel = slog.LevelError
+		} else if labels.Status >= 400 {
+			logLevel = slog.LevelWarn
+		}
+		lm.logger.Log(ctx, logLevel, "request_complete",
+			"request_id", requestIDFrom(ctx),
+			"trace_id", span.TraceID,
+			"span_id", span.SpanID,
+			"method", labels.Method,
+			"route", labels.Route,
+			"path", path,
+			"status_code", labels.Status,
+			"response_size_bytes", wrapped.bytesWritten,
+			"duration_ns", duration.Nanoseconds(),
+			"duration_ms", duration.Milliseconds(),
+		)
+	})
+}
+
+func requestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
+
 // generateRequestID generates a unique request ID
 func generateRequestID() string {
 	// TODO: 実装してください
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoggingMiddleware(t *testing.T) {
@@ -234,6 +241,351 @@ func TestLoggingMiddleware(t *testing.T) {
 	})
 }
 
+// countCompleteLogs returns the request_complete log entries grouped by status code
+func countCompleteLogs(t *testing.T, buffer *bytes.Buffer) map[int]int {
+	t.Helper()
+	counts := make(map[int]int)
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		if entry["msg"] == "request_complete" {
+			counts[int(entry["status_code"].(float64))]++
+		}
+	}
+	return counts
+}
+
+func TestLoggingMiddlewareWithOptions(t *testing.T) {
+	statusHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("Errors are always logged", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logging := NewLoggingMiddlewareWithOptions(Options{
+			Level:      slog.LevelInfo,
+			SampleRate: 0.1,
+			Output:     &logBuffer,
+		})
+		logging.random = func() float64 { return 0.99 } // never sampled
+
+		handler := logging.Middleware(statusHandler)
+		for i := 0; i < 20; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+		}
+
+		counts := countCompleteLogs(t, &logBuffer)
+		if counts[http.StatusInternalServerError] != 20 {
+			t.Errorf("Expected all 20 5xx requests to be logged, got %d", counts[http.StatusInternalServerError])
+		}
+		if counts[http.StatusOK] != 0 {
+			t.Errorf("Expected unsampled 2xx requests to be dropped, got %d", counts[http.StatusOK])
+		}
+		if strings.Contains(logBuffer.String(), `"request_start"`) {
+			t.Error("Expected request_start to be skipped for unsampled requests")
+		}
+	})
+
+	t.Run("Sampling drops the expected fraction", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logging := NewLoggingMiddlewareWithOptions(Options{
+			Level:      slog.LevelInfo,
+			SampleRate: 0.2,
+			Output:     &logBuffer,
+		})
+
+		const requests = 2000
+		handler := logging.Middleware(statusHandler)
+		for i := 0; i < requests; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+		}
+
+		logged := countCompleteLogs(t, &logBuffer)[http.StatusOK]
+		if logged < 300 || logged > 500 {
+			t.Errorf("Expected roughly %d of %d requests to be logged, got %d", requests/5, requests, logged)
+		}
+	})
+
+	t.Run("Slow requests escalate to warn", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logging := NewLoggingMiddlewareWithOptions(Options{
+			Level:         slog.LevelWarn,
+			SlowThreshold: 10 * time.Millisecond,
+			Output:        &logBuffer,
+		})
+
+		handler := logging.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(20 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+
+		// Level is warn, so only the slow request's completion log is written
+		lines := strings.Split(strings.TrimSpace(logBuffer.String()), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("Expected exactly 1 log line, got %d: %s", len(lines), logBuffer.String())
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+			t.Fatalf("Invalid log line: %v", err)
+		}
+		if entry["level"] != "WARN" || entry["slow"] != true || !strings.Contains(entry["url"].(string), "/slow") {
+			t.Errorf("Expected slow request logged at WARN, got %v", entry)
+		}
+	})
+}
+
+func TestBodyLoggingMiddleware(t *testing.T) {
+	newLogging := func(buffer *bytes.Buffer) *LoggingMiddleware {
+		logging := NewLoggingMiddleware()
+		logging.logger = createTestLogger(buffer)
+		return logging
+	}
+
+	t.Run("Handler sees full body and secrets are redacted", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logging := newLogging(&logBuffer)
+
+		requestBody := `{"user":"alice","password":"hunter2","note":"` + strings.Repeat("x", 100) + `"}`
+		var seen string
+		handler := logging.BodyLoggingMiddleware(BodyLoggingOptions{
+			Enabled:      true,
+			MaxBodyBytes: 64,
+			Redactor:     RedactJSONFields("password", "token"),
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Errorf("Failed to read body: %v", err)
+			}
+			seen = string(body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"token":"secret-token","ok":true}`))
+		}))
+
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(requestBody))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if seen != requestBody {
+			t.Errorf("Handler did not see the full request body: got %d bytes, want %d", len(seen), len(requestBody))
+		}
+		if recorder.Body.String() != `{"token":"secret-token","ok":true}` {
+			t.Errorf("Response body was altered: %s", recorder.Body.String())
+		}
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal(logBuffer.Bytes(), &entry); err != nil {
+			t.Fatalf("Failed to parse log entry: %v", err)
+		}
+		logged := entry["request_body"].(string)
+		if strings.Contains(logBuffer.String(), "hunter2") || strings.Contains(logBuffer.String(), "secret-token") {
+			t.Errorf("Expected secrets to be redacted, got %s", logBuffer.String())
+		}
+		if !strings.Contains(logged, `"password":"[REDACTED]"`) {
+			t.Errorf("Expected redacted password in request body, got %s", logged)
+		}
+		if strings.Contains(logged, strings.Repeat("x", 100)) || entry["request_body_truncated"] != true {
+			t.Errorf("Expected request body capped at 64 bytes and marked truncated, got %s", logged)
+		}
+		if entry["response_body"] != `{"token":"[REDACTED]","ok":true}` || entry["response_body_truncated"] != false {
+			t.Errorf("Unexpected response body log: %v", entry["response_body"])
+		}
+	})
+
+	t.Run("Other content types are not captured", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logging := newLogging(&logBuffer)
+
+		handler := logging.BodyLoggingMiddleware(BodyLoggingOptions{Enabled: true})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte("plain"))
+			}))
+
+		req := httptest.NewRequest("POST", "/upload", strings.NewReader("binary"))
+		req.Header.Set("Content-Type", "application/octet-stream")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if logBuffer.Len() != 0 {
+			t.Errorf("Expected no body log for non-JSON content, got %s", logBuffer.String())
+		}
+	})
+
+	t.Run("Disabled middleware is a no-op", func(t *testing.T) {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		logging := NewLoggingMiddleware()
+		handler := logging.BodyLoggingMiddleware(BodyLoggingOptions{})(next)
+		if fmt.Sprintf("%p", handler) != fmt.Sprintf("%p", next) {
+			t.Error("Expected disabled middleware to return the next handler")
+		}
+	})
+}
+
+func TestObservabilityMiddleware(t *testing.T) {
+	t.Run("Log, metric and span agree on duration and status", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logging := &LoggingMiddleware{
+			logger:  createTestLogger(&logBuffer),
+			metrics: NewRequestMetrics(),
+			tracer:  NewTracer(),
+		}
+
+		var activeDuringRequest int64
+		var spanInContext bool
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			activeDuringRequest = logging.metrics.ActiveRequests("POST", "/api/orders")
+			_, spanInContext = SpanFromContext(r.Context())
+			time.Sleep(5 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		req := httptest.NewRequest("POST", "/api/orders?debug=1", nil)
+		rr := httptest.NewRecorder()
+		logging.RequestIDMiddleware(logging.ObservabilityMiddleware(handler)).ServeHTTP(rr, req)
+
+		if activeDuringRequest != 1 {
+			t.Errorf("Expected 1 active request during handling, got %d", activeDuringRequest)
+		}
+		if got := logging.metrics.ActiveRequests("POST", "/api/orders"); got != 0 {
+			t.Errorf("Expected 0 active requests after completion, got %d", got)
+		}
+		if !spanInContext {
+			t.Error("Span should be available from request context")
+		}
+
+		stats := logging.metrics.Stats()
+		spans := logging.tracer.FinishedSpans()
+		if len(stats) != 1 || len(spans) != 1 {
+			t.Fatalf("Expected 1 metric series and 1 span, got %d and %d", len(stats), len(spans))
+		}
+		span := spans[0]
+
+		var completeLog map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(logBuffer.String()), "\n") {
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Invalid log line %q: %v", line, err)
+			}
+			if entry["msg"] == "request_complete" {
+				completeLog = entry
+			}
+		}
+		if completeLog == nil {
+			t.Fatal("request_complete log not found")
+		}
+
+		want := RequestLabels{Method: "POST", Route: "/api/orders", Status: http.StatusServiceUnavailable}
+		obs, ok := stats[want]
+		if !ok || obs.Count != 1 {
+			t.Fatalf("Expected 1 request for %+v, got %+v", want, stats)
+		}
+		if span.Labels != want {
+			t.Errorf("Expected span labels %+v, got %+v", want, span.Labels)
+		}
+		if completeLog["method"] != want.Method || completeLog["route"] != want.Route ||
+			int(completeLog["status_code"].(float64)) != want.Status {
+			t.Errorf("Log labels do not match %+v: %v", want, completeLog)
+		}
+
+		if obs.Sum < 5*time.Millisecond {
+			t.Errorf("Expected duration of at least 5ms, got %v", obs.Sum)
+		}
+		if span.Duration != obs.Sum {
+			t.Errorf("Span duration %v differs from metric duration %v", span.Duration, obs.Sum)
+		}
+		if logged := time.Duration(completeLog["duration_ns"].(float64)); logged != obs.Sum {
+			t.Errorf("Logged duration %v differs from metric duration %v", logged, obs.Sum)
+		}
+
+		if completeLog["trace_id"] != span.TraceID || completeLog["span_id"] != span.SpanID {
+			t.Errorf("Log trace context %v/%v does not match span %s/%s",
+				completeLog["trace_id"], completeLog["span_id"], span.TraceID, span.SpanID)
+		}
+		if completeLog["request_id"] != rr.Header().Get("X-Request-ID") {
+			t.Errorf("Log request ID %v does not match response header", completeLog["request_id"])
+		}
+		if !span.Error {
+			t.Error("Span should be marked as error for 5xx status")
+		}
+	})
+
+	t.Run("Memory stays bounded across many distinct paths", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		logging := &LoggingMiddleware{
+			logger:  createTestLogger(&logBuffer),
+			metrics: NewRequestMetrics(),
+			tracer:  NewTracer(),
+		}
+		logging.tracer.SetMaxSpans(10)
+
+		handler := logging.ObservabilityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		for i := 0; i < 100; i++ {
+			req := httptest.NewRequest("GET", fmt.Sprintf("/users/%d", i), nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		stats := logging.metrics.Stats()
+		want := RequestLabels{Method: "GET", Route: "/users/:id", Status: http.StatusOK}
+		if len(stats) != 1 || stats[want].Count != 100 {
+			t.Errorf("Expected a single series %+v with 100 requests, got %+v", want, stats)
+		}
+		var bucketTotal int64
+		for _, n := range stats[want].Buckets {
+			bucketTotal += n
+		}
+		if bucketTotal != 100 {
+			t.Errorf("Expected histogram buckets to sum to 100, got %d", bucketTotal)
+		}
+
+		spans := logging.tracer.FinishedSpans()
+		if len(spans) != 10 {
+			t.Fatalf("Expected 10 retained spans, got %d", len(spans))
+		}
+		if spans[len(spans)-1].Name != "GET /users/:id" {
+			t.Errorf("Expected span named by route, got %q", spans[len(spans)-1].Name)
+		}
+
+		logging.metrics.mu.Lock()
+		activeKeys := len(logging.metrics.active)
+		logging.metrics.mu.Unlock()
+		if activeKeys != 0 {
+			t.Errorf("Expected no active gauge entries after requests, got %d", activeKeys)
+		}
+	})
+}
+
+func TestRouteFor(t *testing.T) {
+	tests := map[string]string{
+		"/api/orders":       "/api/orders",
+		"/users/42":         "/users/:id",
+		"/users/42/posts/7": "/users/:id/posts/:id",
+		"/items/550e8400-e29b-41d4-a716-446655440000": "/items/:id",
+		"/blobs/0123456789abcdef0123": "/blobs/:id",
+		"/v1/status":        "/v1/status",
+	}
+	for path, want := range tests {
+		if got := RouteFor(path); got != want {
+			t.Errorf("RouteFor(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
 func TestResponseWriter(t *testing.T) {
 	t.Run("Status code capture", func(t *testing.T) {
 		rw := &responseWriter{
@@ -286,6 +638,95 @@ func TestResponseWriter(t *testing.T) {
 	})
 }
 
+// plainResponseWriter implements only http.ResponseWriter (no Flusher/Hijacker)
+type plainResponseWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (w *plainResponseWriter) Header() http.Header         { return w.header }
+func (w *plainResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *plainResponseWriter) WriteHeader(statusCode int)  { w.status = statusCode }
+
+// hijackableRecorder is a recorder that also supports connection hijacking
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+func TestResponseWriterPassthrough(t *testing.T) {
+	t.Run("Flush is forwarded", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		rw := &responseWriter{ResponseWriter: recorder, statusCode: http.StatusOK}
+
+		var w http.ResponseWriter = rw
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("Expected responseWriter to implement http.Flusher")
+		}
+		flusher.Flush()
+
+		if !recorder.Flushed {
+			t.Error("Expected Flush to be forwarded to the underlying writer")
+		}
+	})
+
+	t.Run("Flush without flusher does not panic", func(t *testing.T) {
+		rw := &responseWriter{
+			ResponseWriter: &plainResponseWriter{header: make(http.Header)},
+			statusCode:     http.StatusOK,
+		}
+		rw.Flush()
+	})
+
+	t.Run("Flush through middleware", func(t *testing.T) {
+		logging := NewLoggingMiddleware()
+		var logBuffer bytes.Buffer
+		logging.logger = createTestLogger(&logBuffer)
+
+		handler := logging.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("data: hello\n\n"))
+			w.(http.Flusher).Flush()
+		}))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/events", nil))
+		if !recorder.Flushed {
+			t.Error("Expected streaming handler to flush through the middleware")
+		}
+	})
+
+	t.Run("Hijack is forwarded", func(t *testing.T) {
+		server, client := net.Pipe()
+		defer server.Close()
+		defer client.Close()
+
+		rw := &responseWriter{
+			ResponseWriter: &hijackableRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server},
+			statusCode:     http.StatusOK,
+		}
+		conn, _, err := rw.Hijack()
+		if err != nil {
+			t.Fatalf("Unexpected hijack error: %v", err)
+		}
+		if conn != server {
+			t.Error("Expected the underlying connection to be returned")
+		}
+	})
+
+	t.Run("Hijack without hijacker returns error", func(t *testing.T) {
+		rw := &responseWriter{ResponseWriter: httptest.NewRecorder(), statusCode: http.StatusOK}
+		if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Expected http.ErrNotSupported, got %v", err)
+		}
+	})
+}
+
 func TestGenerateRequestID(t *testing.T) {
 	t.Run("ID uniqueness", func(t *testing.T) {
 		ids := make(map[string]bool)
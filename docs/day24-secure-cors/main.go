@@ -4,7 +4,10 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -12,129 +15,393 @@ import (
 
 // CORSConfig CORS設定を定義
 type CORSConfig struct {
-	AllowedOrigins   []string
-	AllowAllOrigins  bool
-	AllowedMethods   []string
-	AllowedHeaders   []string
-	ExposedHeaders   []string
-	AllowCredentials bool
-	MaxAge           int
+	AllowedOrigins  []string
+	AllowAllOrigins bool
+	// AllowedOriginPatterns オリジンにマッチさせる正規表現（小文字化したオリジンと比較）
+	AllowedOriginPatterns []string
+	// AllowLocalhostAnyPort http://localhost:* と http://127.0.0.1:* を許可（開発用）
+	AllowLocalhostAnyPort bool
+	AllowedMethods        []string
+	AllowedHeaders        []string
+	ExposedHeaders        []string
+	AllowCredentials      bool
+	MaxAge                int
 }
 
 // CORS Cross-Origin Resource Sharing ミドルウェア
 type CORS struct {
-	config CORSConfig
+	config         CORSConfig
+	originPatterns []*regexp.Regexp
 }
 
 // NewCORS 新しいCORSミドルウェアを作成
+// AllowedOriginPatternsに不正な正規表現が含まれる場合はpanicする
 func NewCORS(config CORSConfig) *CORS {
-	// TODO: CORS構造体を初期化
-	// - 設定のバリデーション
-	// - デフォルト値の設定
-	return nil
+	// オリジンパターンのコンパイルと検証
+	patterns := make([]*regexp.Regexp, 0, len(config.AllowedOriginPatterns))
+	for _, p := range config.AllowedOriginPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			panic(fmt.Sprintf("cors: invalid origin pattern %q: %v", p, err))
+		}
+		patterns = append(patterns, re)
+	}
+	
+	// デフォルト値の設定
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	}
+	
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"}
+	}
+	
+	if config.MaxAge == 0 {
+		config.MaxAge = 86400 // 24時間
+	}
+	
+	// AllowAllOriginsとAllowCredentialsの組み合わせチェック
+	if config.AllowAllOrigins && config.AllowCredentials {
+		// セキュリティ上の理由で、すべてのオリジンを許可する場合は認証情報を無効化
+		config.AllowCredentials = false
+	}
+	
+	return &CORS{config: config, originPatterns: patterns}
 }
 
 // isOriginAllowed オリジンが許可されているかチェック
 func (cors *CORS) isOriginAllowed(origin string) bool {
-	// TODO: オリジン検証の実装
-	// 1. AllowAllOriginsの場合はtrueを返す
-	// 2. 許可されたオリジンリストをチェック
-	// 3. ワイルドカードサブドメインのサポート
-	// 4. 大文字小文字を区別しない比較
+	if origin == "" {
+		return true // Originヘッダーがない場合は許可
+	}
+	
+	if cors.config.AllowAllOrigins {
+		return true
+	}
+	
+	// 大文字小文字を区別しない比較のために小文字に変換
+	origin = strings.ToLower(origin)
+	
+	for _, allowed := range cors.config.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		
+		// 完全一致
+		if origin == allowed {
+			return true
+		}
+		
+		// ワイルドカードサブドメインチェック
+		if strings.HasPrefix(allowed, "*.") && matchesWildcard(allowed, origin) {
+			return true
+		}
+	}
+	
+	// 開発用: localhostの任意ポートを許可
+	if cors.config.AllowLocalhostAnyPort && isLocalhostOrigin(origin) {
+		return true
+	}
+	
+	// 正規表現パターンのチェック
+	for _, re := range cors.originPatterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	
 	return false
 }
 
 // isMethodAllowed メソッドが許可されているかチェック
 func (cors *CORS) isMethodAllowed(method string) bool {
-	// TODO: メソッド検証の実装
+	method = strings.ToUpper(method)
+	
+	for _, allowed := range cors.config.AllowedMethods {
+		if strings.ToUpper(allowed) == method {
+			return true
+		}
+	}
+	
 	return false
 }
 
 // isHeaderAllowed ヘッダーが許可されているかチェック
 func (cors *CORS) isHeaderAllowed(header string) bool {
-	// TODO: ヘッダー検証の実装
-	// 1. 危険なヘッダーのブロック
-	// 2. 許可されたヘッダーリストのチェック
-	// 3. 大文字小文字を区別しない比較
+	header = strings.ToLower(header)
+	
+	// 危険なヘッダーをブロック
+	if isDangerousHeader(header) {
+		return false
+	}
+	
+	for _, allowed := range cors.config.AllowedHeaders {
+		if strings.ToLower(allowed) == header {
+			return true
+		}
+	}
+	
 	return false
 }
 
 // handlePreflight プリフライトリクエストを処理
 func (cors *CORS) handlePreflight(w http.ResponseWriter, r *http.Request) {
-	// TODO: プリフライト処理の実装
-	// 1. Originの検証
-	// 2. リクエストメソッドの検証
-	// 3. リクエストヘッダーの検証
-	// 4. 適切なCORSヘッダーの設定
-	// 5. ステータスコードの設定
+	origin := r.Header.Get("Origin")
+	
+	// Originの検証
+	if !cors.isOriginAllowed(origin) {
+		cors.sendCORSError(w, http.StatusForbidden, "Origin not allowed", map[string]interface{}{
+			"origin": origin,
+		})
+		return
+	}
+	
+	// リクエストメソッドの検証
+	requestMethod := r.Header.Get("Access-Control-Request-Method")
+	if requestMethod == "" || !cors.isMethodAllowed(requestMethod) {
+		cors.sendCORSError(w, http.StatusMethodNotAllowed, "Method not allowed", map[string]interface{}{
+			"method":          requestMethod,
+			"allowed_methods": cors.config.AllowedMethods,
+		})
+		return
+	}
+	
+	// リクエストヘッダーの検証
+	requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+	if requestHeaders != "" {
+		headers := strings.Split(requestHeaders, ",")
+		for _, header := range headers {
+			header = strings.TrimSpace(header)
+			if !cors.isHeaderAllowed(header) {
+				cors.sendCORSError(w, http.StatusForbidden, "Header not allowed", map[string]interface{}{
+					"header":          header,
+					"allowed_headers": cors.config.AllowedHeaders,
+				})
+				return
+			}
+		}
+	}
+	
+	// CORSヘッダーの設定
+	cors.setCORSHeaders(w, origin)
+	cors.setPreflightHeaders(w, origin)
+	
+	w.WriteHeader(http.StatusOK)
 }
 
 // handleSimpleRequest Simple Requestを処理
 func (cors *CORS) handleSimpleRequest(w http.ResponseWriter, r *http.Request) bool {
-	// TODO: Simple Request処理の実装
-	// 1. Originの検証
-	// 2. 適切なCORSヘッダーの設定
-	// 3. 成功/失敗の判定を返す
-	return false
+	origin := r.Header.Get("Origin")
+	
+	// Originヘッダーがない場合は常に許可
+	if origin == "" {
+		return true
+	}
+	
+	// Originの検証
+	if !cors.isOriginAllowed(origin) {
+		cors.sendCORSError(w, http.StatusForbidden, "Origin not allowed", map[string]interface{}{
+			"origin": origin,
+		})
+		return false
+	}
+	
+	// CORSヘッダーの設定
+	cors.setCORSHeaders(w, origin)
+	
+	return true
 }
 
 // setCORSHeaders CORSヘッダーを設定
 func (cors *CORS) setCORSHeaders(w http.ResponseWriter, origin string) {
-	// TODO: CORSヘッダーの設定
-	// - Access-Control-Allow-Origin
-	// - Access-Control-Allow-Credentials
-	// - Access-Control-Expose-Headers
-	// - Vary
+	if cors.config.AllowAllOrigins {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else if origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		// Varyヘッダーでキャッシュ制御（既存のVary値は上書きしない）
+		addVary(w.Header(), "Origin")
+	}
+	
+	if cors.config.AllowCredentials && !cors.config.AllowAllOrigins {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	
+	if len(cors.config.ExposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(cors.config.ExposedHeaders, ", "))
+	}
+}
+
+// addVary Varyヘッダーに値を重複なく追加
+func addVary(h http.Header, value string) {
+	for _, v := range h.Values("Vary") {
+		for _, existing := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
 }
 
 // setPreflightHeaders プリフライト用ヘッダーを設定
 func (cors *CORS) setPreflightHeaders(w http.ResponseWriter, origin string) {
-	// TODO: プリフライト用ヘッダーの設定
-	// - Access-Control-Allow-Methods
-	// - Access-Control-Allow-Headers
-	// - Access-Control-Max-Age
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.config.AllowedMethods, ", "))
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.config.AllowedHeaders, ", "))
+	w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cors.config.MaxAge))
 }
 
 // sendCORSError CORS エラーレスポンスを送信
 func (cors *CORS) sendCORSError(w http.ResponseWriter, code int, message string, details map[string]interface{}) {
-	// TODO: エラーレスポンスの送信
-	// - 適切なステータスコード
-	// - JSON形式のエラーメッセージ
-	// - 追加の詳細情報
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	
+	response := map[string]interface{}{
+		"error":   message,
+		"details": details,
+	}
+	
+	json.NewEncoder(w).Encode(response)
 }
 
 // Middleware CORSミドルウェア関数
 func (cors *CORS) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: メインのミドルウェア処理
-		// 1. プリフライトリクエストの検出と処理
-		// 2. Simple Requestの処理
-		// 3. 次のハンドラーへの処理継続
+		// プリフライトリクエストの処理
+		if r.Method == http.MethodOptions {
+			origin := r.Header.Get("Origin")
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			
+			// プリフライトリクエストかどうかの判定
+			if origin != "" && requestMethod != "" {
+				cors.handlePreflight(w, r)
+				return
+			}
+		}
+		
+		// Simple Requestの処理
+		if !cors.handleSimpleRequest(w, r) {
+			return
+		}
+		
 		next.ServeHTTP(w, r)
 	})
 }
 
 // isSimpleRequest リクエストがSimple Requestかどうか判定
 func isSimpleRequest(r *http.Request) bool {
-	// TODO: Simple Request判定の実装
-	// - メソッドチェック (GET, HEAD, POST)
-	// - Content-Typeチェック
-	// - カスタムヘッダーの有無
-	return false
+	// Simple Requestのメソッドチェック
+	method := r.Method
+	if method != "GET" && method != "HEAD" && method != "POST" {
+		return false
+	}
+	
+	// Content-Typeのチェック（POSTの場合）
+	if method == "POST" {
+		contentType := r.Header.Get("Content-Type")
+		if contentType != "" {
+			// Simple RequestのContent-Type
+			simpleContentTypes := []string{
+				"application/x-www-form-urlencoded",
+				"multipart/form-data",
+				"text/plain",
+			}
+			
+			// メディアタイプの抽出（パラメータを除外）
+			mediaType := strings.Split(contentType, ";")[0]
+			mediaType = strings.TrimSpace(mediaType)
+			
+			isSimpleContentType := false
+			for _, simpleType := range simpleContentTypes {
+				if strings.ToLower(mediaType) == simpleType {
+					isSimpleContentType = true
+					break
+				}
+			}
+			
+			if !isSimpleContentType {
+				return false
+			}
+		}
+	}
+	
+	// カスタムヘッダーのチェック
+	for headerName := range r.Header {
+		headerName = strings.ToLower(headerName)
+		
+		// Simple Requestで許可されているヘッダー
+		simpleHeaders := map[string]bool{
+			"accept":          true,
+			"accept-language": true,
+			"content-language": true,
+			"content-type":    true,
+		}
+		
+		if !simpleHeaders[headerName] {
+			return false
+		}
+	}
+	
+	return true
 }
 
 // isDangerousHeader 危険なヘッダーかどうか判定
 func isDangerousHeader(header string) bool {
-	// TODO: 危険なヘッダーの判定
-	// - host, connection, upgrade など
-	return false
+	header = strings.ToLower(header)
+	
+	dangerousHeaders := map[string]bool{
+		"host":               true,
+		"connection":         true,
+		"upgrade":            true,
+		"proxy-authorization": true,
+		"sec-websocket-key":   true,
+		"sec-websocket-version": true,
+		"sec-websocket-protocol": true,
+		"sec-websocket-extensions": true,
+	}
+	
+	return dangerousHeaders[header]
 }
 
 // matchesWildcard ワイルドカードパターンにマッチするかチェック
 func matchesWildcard(pattern, origin string) bool {
-	// TODO: ワイルドカードマッチングの実装
-	// - *.example.com パターンのサポート
-	return false
+	if !strings.HasPrefix(pattern, "*.") {
+		return false
+	}
+	
+	domain := pattern[2:] // "*.example.com" → "example.com"
+	
+	// "https://app.example.com" → "app.example.com"
+	// プロトコルを除去してドメイン部分を抽出
+	originWithoutProtocol := origin
+	if strings.Contains(origin, "://") {
+		parts := strings.SplitN(origin, "://", 2)
+		if len(parts) == 2 {
+			originWithoutProtocol = parts[1]
+		}
+	}
+	
+	// ポート番号があれば除去
+	if strings.Contains(originWithoutProtocol, ":") {
+		parts := strings.Split(originWithoutProtocol, ":")
+		originWithoutProtocol = parts[0]
+	}
+	
+	// サブドメインマッチング
+	return strings.HasSuffix(originWithoutProtocol, "."+domain)
+}
+
+// isLocalhostOrigin http://localhost または http://127.0.0.1 のオリジンか判定（ポートは任意）
+func isLocalhostOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	
+	// パスやユーザー情報を含むものはオリジンとして不正
+	if u.User != nil || (u.Path != "" && u.Path != "/") {
+		return false
+	}
+	
+	host := u.Hostname()
+	return host == "localhost" || host == "127.0.0.1"
 }
 
 // DefaultCORSConfig デフォルトのCORS設定を返す
@@ -2,7 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -10,22 +13,38 @@ import (
 
 // CORSConfig CORS設定を定義
 type CORSConfig struct {
-	AllowedOrigins   []string
-	AllowAllOrigins  bool
-	AllowedMethods   []string
-	AllowedHeaders   []string
-	ExposedHeaders   []string
-	AllowCredentials bool
-	MaxAge           int
+	AllowedOrigins  []string
+	AllowAllOrigins bool
+	// AllowedOriginPatterns オリジンにマッチさせる正規表現（小文字化したオリジンと比較）
+	AllowedOriginPatterns []string
+	// AllowLocalhostAnyPort http://localhost:* と http://127.0.0.1:* を許可（開発用）
+	AllowLocalhostAnyPort bool
+	AllowedMethods        []string
+	AllowedHeaders        []string
+	ExposedHeaders        []string
+	AllowCredentials      bool
+	MaxAge                int
 }
 
 // CORS Cross-Origin Resource Sharing ミドルウェア
 type CORS struct {
-	config CORSConfig
+	config         CORSConfig
+	originPatterns []*regexp.Regexp
 }
 
 // NewCORS 新しいCORSミドルウェアを作成
+// AllowedOriginPatternsに不正な正規表現が含まれる場合はpanicする
 func NewCORS(config CORSConfig) *CORS {
+	// オリジンパターンのコンパイルと検証
+	patterns := make([]*regexp.Regexp, 0, len(config.AllowedOriginPatterns))
+	for _, p := range config.AllowedOriginPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			panic(fmt.Sprintf("cors: invalid origin pattern %q: %v", p, err))
+		}
+		patterns = append(patterns, re)
+	}
+	
 	// デフォルト値の設定
 	if len(config.AllowedMethods) == 0 {
 		config.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
@@ -45,7 +64,7 @@ func NewCORS(config CORSConfig) *CORS {
 		config.AllowCredentials = false
 	}
 	
-	return &CORS{config: config}
+	return &CORS{config: config, originPatterns: patterns}
 }
 
 // isOriginAllowed オリジンが許可されているかチェック
@@ -75,6 +94,18 @@ func (cors *CORS) isOriginAllowed(origin string) bool {
 		}
 	}
 	
+	// 開発用: localhostの任意ポートを許可
+	if cors.config.AllowLocalhostAnyPort && isLocalhostOrigin(origin) {
+		return true
+	}
+	
+	// 正規表現パターンのチェック
+	for _, re := range cors.originPatterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	
 	return false
 }
 
@@ -183,8 +214,8 @@ func (cors *CORS) setCORSHeaders(w http.ResponseWriter, origin string) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 	} else if origin != "" {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
-		// Varyヘッダーでキャッシュ制御
-		w.Header().Set("Vary", "Origin")
+		// Varyヘッダーでキャッシュ制御（既存のVary値は上書きしない）
+		addVary(w.Header(), "Origin")
 	}
 	
 	if cors.config.AllowCredentials && !cors.config.AllowAllOrigins {
@@ -196,6 +227,18 @@ func (cors *CORS) setCORSHeaders(w http.ResponseWriter, origin string) {
 	}
 }
 
+// addVary Varyヘッダーに値を重複なく追加
+func addVary(h http.Header, value string) {
+	for _, v := range h.Values("Vary") {
+		for _, existing := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
+
 // setPreflightHeaders プリフライト用ヘッダーを設定
 func (cors *CORS) setPreflightHeaders(w http.ResponseWriter, origin string) {
 	w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.config.AllowedMethods, ", "))
@@ -343,6 +386,22 @@ func matchesWildcard(pattern, origin string) bool {
 	return strings.HasSuffix(originWithoutProtocol, "."+domain)
 }
 
+// isLocalhostOrigin http://localhost または http://127.0.0.1 のオリジンか判定（ポートは任意）
+func isLocalhostOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	
+	// パスやユーザー情報を含むものはオリジンとして不正
+	if u.User != nil || (u.Path != "" && u.Path != "/") {
+		return false
+	}
+	
+	host := u.Hostname()
+	return host == "localhost" || host == "127.0.0.1"
+}
+
 // DefaultCORSConfig デフォルトのCORS設定を返す
 func DefaultCORSConfig() CORSConfig {
 	return CORSConfig{
@@ -617,6 +617,252 @@ func TestCORSComplexPreflight(t *testing.T) {
 	}
 }
 
+func TestCORSMiddlewareScenarios(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          CORSConfig
+		method          string
+		headers         map[string]string
+		expectedStatus  int
+		expectedOrigin  string
+		expectedCreds   string
+		expectVary      bool
+		expectPreflight bool
+	}{
+		{
+			name:           "Allowed origin",
+			config:         CORSConfig{AllowedOrigins: []string{"https://example.com"}},
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://example.com"},
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://example.com",
+			expectVary:     true,
+		},
+		{
+			name:           "Allowed origin with different case",
+			config:         CORSConfig{AllowedOrigins: []string{"https://Example.COM"}},
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://example.com"},
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://example.com",
+			expectVary:     true,
+		},
+		{
+			name:           "Blocked origin",
+			config:         CORSConfig{AllowedOrigins: []string{"https://example.com"}},
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://evil.com"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Wildcard subdomain allowed",
+			config:         CORSConfig{AllowedOrigins: []string{"*.example.com"}},
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://api.example.com:8443"},
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://api.example.com:8443",
+			expectVary:     true,
+		},
+		{
+			name:           "Wildcard does not match lookalike domain",
+			config:         CORSConfig{AllowedOrigins: []string{"*.example.com"}},
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://evilexample.com"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Preflight success",
+			config: CORSConfig{
+				AllowedOrigins: []string{"https://example.com"},
+				AllowedMethods: []string{"GET", "PUT"},
+				AllowedHeaders: []string{"Content-Type", "X-Custom-Header"},
+				MaxAge:         600,
+			},
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "content-type, x-custom-header",
+			},
+			expectedStatus:  http.StatusOK,
+			expectedOrigin:  "https://example.com",
+			expectVary:      true,
+			expectPreflight: true,
+		},
+		{
+			name:   "Preflight with disallowed method",
+			config: CORSConfig{AllowedOrigins: []string{"https://example.com"}, AllowedMethods: []string{"GET"}},
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                        "https://example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "Credentialed request echoes origin",
+			config:         CORSConfig{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true},
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://example.com"},
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://example.com",
+			expectedCreds:  "true",
+			expectVary:     true,
+		},
+		{
+			name:           "Credentials dropped when all origins allowed",
+			config:         CORSConfig{AllowAllOrigins: true, AllowCredentials: true},
+			method:         "GET",
+			headers:        map[string]string{"Origin": "https://any.com"},
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cors := NewCORS(tt.config)
+			handler := cors.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+			if allowOrigin != tt.expectedOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.expectedOrigin, allowOrigin)
+			}
+
+			creds := w.Header().Get("Access-Control-Allow-Credentials")
+			if creds != tt.expectedCreds {
+				t.Errorf("Expected Access-Control-Allow-Credentials %q, got %q", tt.expectedCreds, creds)
+			}
+			if creds == "true" && allowOrigin == "*" {
+				t.Error("Credentialed response must not use wildcard origin")
+			}
+
+			hasVary := strings.Contains(w.Header().Get("Vary"), "Origin")
+			if hasVary != tt.expectVary {
+				t.Errorf("Expected Vary: Origin present=%v, got %q", tt.expectVary, w.Header().Get("Vary"))
+			}
+
+			if tt.expectPreflight {
+				if w.Header().Get("Access-Control-Allow-Methods") == "" {
+					t.Error("Preflight response should include Access-Control-Allow-Methods")
+				}
+				if w.Header().Get("Access-Control-Allow-Headers") == "" {
+					t.Error("Preflight response should include Access-Control-Allow-Headers")
+				}
+				if maxAge := w.Header().Get("Access-Control-Max-Age"); maxAge != "600" {
+					t.Errorf("Expected Access-Control-Max-Age 600, got %q", maxAge)
+				}
+			}
+		})
+	}
+}
+
+func TestSetCORSHeadersPreservesVary(t *testing.T) {
+	cors := NewCORS(CORSConfig{AllowedOrigins: []string{"https://example.com"}})
+
+	w := httptest.NewRecorder()
+	w.Header().Set("Vary", "Accept-Encoding")
+	cors.setCORSHeaders(w, "https://example.com")
+	cors.setCORSHeaders(w, "https://example.com")
+
+	vary := w.Header().Values("Vary")
+	if len(vary) != 2 || vary[0] != "Accept-Encoding" || vary[1] != "Origin" {
+		t.Errorf("Expected Vary [Accept-Encoding Origin], got %v", vary)
+	}
+}
+
+func TestOriginPatternsAndLocalhost(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   CORSConfig
+		origin   string
+		expected bool
+	}{
+		{
+			name:     "Localhost any port with flag",
+			config:   CORSConfig{AllowLocalhostAnyPort: true},
+			origin:   "http://localhost:5173",
+			expected: true,
+		},
+		{
+			name:     "Loopback IP any port with flag",
+			config:   CORSConfig{AllowLocalhostAnyPort: true},
+			origin:   "http://127.0.0.1:8081",
+			expected: true,
+		},
+		{
+			name:     "Localhost without flag",
+			config:   CORSConfig{AllowedOrigins: []string{"http://localhost:3000"}},
+			origin:   "http://localhost:5173",
+			expected: false,
+		},
+		{
+			name:     "Localhost over https is not matched by flag",
+			config:   CORSConfig{AllowLocalhostAnyPort: true},
+			origin:   "https://localhost:5173",
+			expected: false,
+		},
+		{
+			name:     "Localhost lookalike host",
+			config:   CORSConfig{AllowLocalhostAnyPort: true},
+			origin:   "http://localhost.evil.com:5173",
+			expected: false,
+		},
+		{
+			name:     "Regex pattern match",
+			config:   CORSConfig{AllowedOriginPatterns: []string{`^https://.*\.corp\.net$`}},
+			origin:   "https://app.corp.net",
+			expected: true,
+		},
+		{
+			name:     "Regex pattern scheme mismatch",
+			config:   CORSConfig{AllowedOriginPatterns: []string{`^https://.*\.corp\.net$`}},
+			origin:   "http://app.corp.net",
+			expected: false,
+		},
+		{
+			name:     "Regex pattern suffix mismatch",
+			config:   CORSConfig{AllowedOriginPatterns: []string{`^https://.*\.corp\.net$`}},
+			origin:   "https://app.corp.net.evil.com",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cors := NewCORS(tt.config)
+
+			result := cors.isOriginAllowed(tt.origin)
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewCORSInvalidOriginPattern(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewCORS should panic on invalid origin pattern")
+		}
+	}()
+
+	NewCORS(CORSConfig{AllowedOriginPatterns: []string{`^https://(`}})
+}
+
 func BenchmarkCORSMiddleware(b *testing.B) {
 	config := CORSConfig{
 		AllowedOrigins: []string{"https://example.com", "https://app.example.com"},
@@ -5,6 +5,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,7 +14,7 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // User represents a user entity
@@ -52,6 +53,24 @@ type ErrorResponse struct {
 	Details map[string]string `json:"details,omitempty"`
 }
 
+// Repository errors. Callers should match them with errors.Is.
+var (
+	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrUserNotFound   = errors.New("user not found")
+)
+
+// PostgreSQL error codes we translate into repository errors
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+// isPQError reports whether err is a PostgreSQL error with the given code
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
 // UserRepository handles user data operations
 type UserRepository struct {
 	db *sql.DB
@@ -64,37 +83,124 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 // Create creates a new user
 func (r *UserRepository) Create(user *User) error {
-	// TODO: Implement user creation
-	// - Insert user into database
-	// - Set ID and CreatedAt from returned values
-	// - Handle duplicate email errors
+	query := `INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id, created_at`
+	err := r.db.QueryRow(query, user.Name, user.Email).Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
+		}
+		return err
+	}
 	return nil
 }
 
 // GetByID retrieves user by ID with posts
 func (r *UserRepository) GetByID(id int) (*User, error) {
-	// TODO: Implement user retrieval by ID
-	// - Query user by ID
-	// - Query associated posts
-	// - Return combined data
-	return nil, nil
+	// A single LEFT JOIN loads the user together with its posts; a user
+	// without posts yields one row whose post columns are all NULL.
+	query := `
+		SELECT u.id, u.name, u.email, u.created_at,
+		       p.id, p.user_id, p.title, p.content, p.created_at
+		FROM users u
+		LEFT JOIN posts p ON p.user_id = u.id
+		WHERE u.id = $1
+		ORDER BY p.id`
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var user *User
+	for rows.Next() {
+		var u User
+		var (
+			postID        sql.NullInt64
+			postUserID    sql.NullInt64
+			postTitle     sql.NullString
+			postContent   sql.NullString
+			postCreatedAt sql.NullTime
+		)
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt,
+			&postID, &postUserID, &postTitle, &postContent, &postCreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			user = &u
+		}
+		if postID.Valid {
+			user.Posts = append(user.Posts, Post{
+				ID:        int(postID.Int64),
+				UserID:    int(postUserID.Int64),
+				Title:     postTitle.String,
+				Content:   postContent.String,
+				CreatedAt: postCreatedAt.Time,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUserNotFound, id)
+	}
+	return user, nil
 }
 
 // Update updates user information
 func (r *UserRepository) Update(user *User) error {
-	// TODO: Implement user update
-	// - Update name and email
-	// - Handle validation errors
+	query := `UPDATE users SET name = $1, email = $2 WHERE id = $3`
+	result, err := r.db.Exec(query, user.Name, user.Email, user.ID)
+	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrUserNotFound, user.ID)
+	}
+
 	return nil
 }
 
 // Delete deletes user and associated posts
 func (r *UserRepository) Delete(id int) error {
-	// TODO: Implement user deletion
-	// - Use transaction to delete posts first
-	// - Then delete user
-	// - Handle referential integrity
-	return nil
+	// Posts are removed explicitly inside the same transaction so the
+	// deletion does not depend on the ON DELETE CASCADE constraint.
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM posts WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrUserNotFound, id)
+	}
+
+	return tx.Commit()
 }
 
 // PostRepository handles post data operations
@@ -109,19 +215,37 @@ func NewPostRepository(db *sql.DB) *PostRepository {
 
 // Create creates a new post
 func (r *PostRepository) Create(post *Post) error {
-	// TODO: Implement post creation
-	// - Insert post into database
-	// - Set ID and CreatedAt from returned values
-	// - Validate user_id exists
+	query := `INSERT INTO posts (user_id, title, content) VALUES ($1, $2, $3) RETURNING id, created_at`
+	err := r.db.QueryRow(query, post.UserID, post.Title, post.Content).Scan(&post.ID, &post.CreatedAt)
+	if err != nil {
+		if isPQError(err, pqForeignKeyViolation) {
+			return fmt.Errorf("%w: %d", ErrUserNotFound, post.UserID)
+		}
+		return err
+	}
 	return nil
 }
 
 // GetByUserID retrieves posts by user ID
 func (r *PostRepository) GetByUserID(userID int) ([]Post, error) {
-	// TODO: Implement posts retrieval by user ID
-	// - Query posts for given user
-	// - Order by created_at DESC
-	return nil, nil
+	query := `SELECT id, user_id, title, COALESCE(content, ''), created_at FROM posts WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []Post
+	for rows.Next() {
+		var post Post
+		err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
 }
 
 // UserService handles user business logic
@@ -173,11 +297,53 @@ func (s *UserService) DeleteUser(id int) error {
 
 // CreateUserWithPosts creates user with initial posts in transaction
 func (s *UserService) CreateUserWithPosts(user *User, posts []Post) error {
-	// TODO: Implement transactional user+posts creation
-	// - Begin transaction
-	// - Create user
-	// - Create posts
-	// - Commit or rollback on error
+	// Validate user
+	req := &CreateUserRequest{Name: user.Name, Email: user.Email}
+	if errors := validateUser(req); len(errors) > 0 {
+		return fmt.Errorf("user validation failed")
+	}
+
+	// Begin transaction
+	tx, err := s.userRepo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Results are staged in copies so that a rolled back transaction
+	// leaves the caller's user and posts untouched.
+	created := *user
+
+	// Create user
+	userQuery := `INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id, created_at`
+	err = tx.QueryRow(userQuery, created.Name, created.Email).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEmail, created.Email)
+		}
+		return err
+	}
+
+	// Create posts
+	createdPosts := make([]Post, len(posts))
+	postQuery := `INSERT INTO posts (user_id, title, content) VALUES ($1, $2, $3) RETURNING id, created_at`
+	for i, post := range posts {
+		post.UserID = created.ID
+		err = tx.QueryRow(postQuery, post.UserID, post.Title, post.Content).Scan(&post.ID, &post.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("create post %d: %w", i, err)
+		}
+		createdPosts[i] = post
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	copy(posts, createdPosts)
+	created.Posts = createdPosts
+	*user = created
 	return nil
 }
 
@@ -248,17 +414,24 @@ func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 
 // Validation helpers
 func validateUser(req *CreateUserRequest) map[string]string {
-	// TODO: Implement user validation
-	// - Check name is not empty
-	// - Check email format with regex
-	// - Return map of field -> error message
-	return nil
+	errors := make(map[string]string)
+
+	if strings.TrimSpace(req.Name) == "" {
+		errors["name"] = "name is required"
+	}
+
+	if strings.TrimSpace(req.Email) == "" {
+		errors["email"] = "email is required"
+	} else if !validateEmail(req.Email) {
+		errors["email"] = "invalid email format"
+	}
+
+	return errors
 }
 
 func validateEmail(email string) bool {
-	// TODO: Implement email validation
-	// - Use regex to validate email format
-	return false
+	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+	return emailRegex.MatchString(email)
 }
 
 // HTTP helpers
@@ -316,8 +489,26 @@ func SeedTestData(db *sql.DB) error {
 	return nil
 }
 
+// DefaultMaxInFlight bounds concurrent requests so that a burst cannot exhaust the DB connection pool
+const DefaultMaxInFlight = 50
+
+// LimitConcurrency rejects requests with 503 once maxInFlight requests are already being served
+func LimitConcurrency(maxInFlight int, next http.Handler) http.Handler {
+	// TODO: Implement concurrency limiting middleware
+	// - Use a buffered channel of size maxInFlight as a semaphore
+	// - Acquire without blocking; release when the request finishes
+	// - When saturated, set Retry-After and respond with 503
+	// - maxInFlight <= 0 disables the limit
+	return next
+}
+
 // Server setup
 func SetupServer(db *sql.DB) http.Handler {
+	return SetupServerWithLimit(db, DefaultMaxInFlight)
+}
+
+// SetupServerWithLimit builds the API handler, allowing at most maxInFlight concurrent requests
+func SetupServerWithLimit(db *sql.DB, maxInFlight int) http.Handler {
 	userRepo := NewUserRepository(db)
 	postRepo := NewPostRepository(db)
 	userService := NewUserService(userRepo, postRepo)
@@ -357,7 +548,7 @@ func SetupServer(db *sql.DB) http.Handler {
 		}
 	})
 	
-	return mux
+	return LimitConcurrency(maxInFlight, mux)
 }
 
 func main() {
@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,7 +12,7 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // User represents a user entity
@@ -50,6 +51,24 @@ type ErrorResponse struct {
 	Details map[string]string `json:"details,omitempty"`
 }
 
+// Repository errors. Callers should match them with errors.Is.
+var (
+	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrUserNotFound   = errors.New("user not found")
+)
+
+// PostgreSQL error codes we translate into repository errors
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+// isPQError reports whether err is a PostgreSQL error with the given code
+func isPQError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
 // UserRepository handles user data operations
 type UserRepository struct {
 	db *sql.DB
@@ -65,8 +84,8 @@ func (r *UserRepository) Create(user *User) error {
 	query := `INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id, created_at`
 	err := r.db.QueryRow(query, user.Name, user.Email).Scan(&user.ID, &user.CreatedAt)
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-			return fmt.Errorf("duplicate email: %s", user.Email)
+		if isPQError(err, pqUniqueViolation) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
 		}
 		return err
 	}
@@ -75,35 +94,56 @@ func (r *UserRepository) Create(user *User) error {
 
 // GetByID retrieves user by ID with posts
 func (r *UserRepository) GetByID(id int) (*User, error) {
-	query := `SELECT id, name, email, created_at FROM users WHERE id = $1`
-	user := &User{}
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found: %d", id)
-		}
-		return nil, err
-	}
-	
-	// Get posts
-	postQuery := `SELECT id, user_id, title, content, created_at FROM posts WHERE user_id = $1 ORDER BY created_at DESC`
-	rows, err := r.db.Query(postQuery, id)
+	// A single LEFT JOIN loads the user together with its posts; a user
+	// without posts yields one row whose post columns are all NULL.
+	query := `
+		SELECT u.id, u.name, u.email, u.created_at,
+		       p.id, p.user_id, p.title, p.content, p.created_at
+		FROM users u
+		LEFT JOIN posts p ON p.user_id = u.id
+		WHERE u.id = $1
+		ORDER BY p.id`
+	rows, err := r.db.Query(query, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	
-	var posts []Post
+
+	var user *User
 	for rows.Next() {
-		var post Post
-		err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CreatedAt)
+		var u User
+		var (
+			postID        sql.NullInt64
+			postUserID    sql.NullInt64
+			postTitle     sql.NullString
+			postContent   sql.NullString
+			postCreatedAt sql.NullTime
+		)
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt,
+			&postID, &postUserID, &postTitle, &postContent, &postCreatedAt)
 		if err != nil {
 			return nil, err
 		}
-		posts = append(posts, post)
+		if user == nil {
+			user = &u
+		}
+		if postID.Valid {
+			user.Posts = append(user.Posts, Post{
+				ID:        int(postID.Int64),
+				UserID:    int(postUserID.Int64),
+				Title:     postTitle.String,
+				Content:   postContent.String,
+				CreatedAt: postCreatedAt.Time,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUserNotFound, id)
 	}
-	
-	user.Posts = posts
 	return user, nil
 }
 
@@ -112,40 +152,53 @@ func (r *UserRepository) Update(user *User) error {
 	query := `UPDATE users SET name = $1, email = $2 WHERE id = $3`
 	result, err := r.db.Exec(query, user.Name, user.Email, user.ID)
 	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
+		}
 		return err
 	}
-	
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	
+
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found: %d", user.ID)
+		return fmt.Errorf("%w: %d", ErrUserNotFound, user.ID)
 	}
-	
+
 	return nil
 }
 
 // Delete deletes user and associated posts
 func (r *UserRepository) Delete(id int) error {
-	// PostgreSQL will cascade delete posts due to foreign key constraint
-	query := `DELETE FROM users WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+	// Posts are removed explicitly inside the same transaction so the
+	// deletion does not depend on the ON DELETE CASCADE constraint.
+	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-	
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM posts WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	
+
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found: %d", id)
+		return fmt.Errorf("%w: %d", ErrUserNotFound, id)
 	}
-	
-	return nil
+
+	return tx.Commit()
 }
 
 // PostRepository handles post data operations
@@ -163,8 +216,8 @@ func (r *PostRepository) Create(post *Post) error {
 	query := `INSERT INTO posts (user_id, title, content) VALUES ($1, $2, $3) RETURNING id, created_at`
 	err := r.db.QueryRow(query, post.UserID, post.Title, post.Content).Scan(&post.ID, &post.CreatedAt)
 	if err != nil {
-		if strings.Contains(err.Error(), "foreign key") {
-			return fmt.Errorf("user not found: %d", post.UserID)
+		if isPQError(err, pqForeignKeyViolation) {
+			return fmt.Errorf("%w: %d", ErrUserNotFound, post.UserID)
 		}
 		return err
 	}
@@ -173,13 +226,13 @@ func (r *PostRepository) Create(post *Post) error {
 
 // GetByUserID retrieves posts by user ID
 func (r *PostRepository) GetByUserID(userID int) ([]Post, error) {
-	query := `SELECT id, user_id, title, content, created_at FROM posts WHERE user_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, user_id, title, COALESCE(content, ''), created_at FROM posts WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
 	rows, err := r.db.Query(query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	
+
 	var posts []Post
 	for rows.Next() {
 		var post Post
@@ -189,8 +242,8 @@ func (r *PostRepository) GetByUserID(userID int) ([]Post, error) {
 		}
 		posts = append(posts, post)
 	}
-	
-	return posts, nil
+
+	return posts, rows.Err()
 }
 
 // UserService handles user business logic
@@ -273,33 +326,49 @@ func (s *UserService) CreateUserWithPosts(user *User, posts []Post) error {
 	if errors := validateUser(req); len(errors) > 0 {
 		return fmt.Errorf("user validation failed")
 	}
-	
+
 	// Begin transaction
 	tx, err := s.userRepo.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
-	
+
+	// Results are staged in copies so that a rolled back transaction
+	// leaves the caller's user and posts untouched.
+	created := *user
+
 	// Create user
 	userQuery := `INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id, created_at`
-	err = tx.QueryRow(userQuery, user.Name, user.Email).Scan(&user.ID, &user.CreatedAt)
+	err = tx.QueryRow(userQuery, created.Name, created.Email).Scan(&created.ID, &created.CreatedAt)
 	if err != nil {
+		if isPQError(err, pqUniqueViolation) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEmail, created.Email)
+		}
 		return err
 	}
-	
+
 	// Create posts
-	for i := range posts {
-		postQuery := `INSERT INTO posts (user_id, title, content) VALUES ($1, $2, $3) RETURNING id, created_at`
-		err = tx.QueryRow(postQuery, user.ID, posts[i].Title, posts[i].Content).Scan(&posts[i].ID, &posts[i].CreatedAt)
+	createdPosts := make([]Post, len(posts))
+	postQuery := `INSERT INTO posts (user_id, title, content) VALUES ($1, $2, $3) RETURNING id, created_at`
+	for i, post := range posts {
+		post.UserID = created.ID
+		err = tx.QueryRow(postQuery, post.UserID, post.Title, post.Content).Scan(&post.ID, &post.CreatedAt)
 		if err != nil {
-			return err
+			return fmt.Errorf("create post %d: %w", i, err)
 		}
-		posts[i].UserID = user.ID
+		createdPosts[i] = post
 	}
-	
+
 	// Commit transaction
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	copy(posts, createdPosts)
+	created.Posts = createdPosts
+	*user = created
+	return nil
 }
 
 // UserHandler handles HTTP requests for users
@@ -577,8 +646,36 @@ func SeedTestData(db *sql.DB) error {
 	return tx.Commit()
 }
 
+// DefaultMaxInFlight bounds concurrent requests so that a burst cannot exhaust the DB connection pool
+const DefaultMaxInFlight = 50
+
+// LimitConcurrency rejects requests with 503 once maxInFlight requests are already being served
+func LimitConcurrency(maxInFlight int, next http.Handler) http.Handler {
+	if maxInFlight <= 0 {
+		return next
+	}
+
+	sem := make(chan struct{}, maxInFlight)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			next.ServeHTTP(w, r)
+		default:
+			// Fail fast instead of queueing so clients can back off and retry
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, "Server is busy", nil)
+		}
+	})
+}
+
 // Server setup
 func SetupServer(db *sql.DB) http.Handler {
+	return SetupServerWithLimit(db, DefaultMaxInFlight)
+}
+
+// SetupServerWithLimit builds the API handler, allowing at most maxInFlight concurrent requests
+func SetupServerWithLimit(db *sql.DB, maxInFlight int) http.Handler {
 	userRepo := NewUserRepository(db)
 	postRepo := NewPostRepository(db)
 	userService := NewUserService(userRepo, postRepo)
@@ -617,7 +714,7 @@ func SetupServer(db *sql.DB) http.Handler {
 		}
 	})
 	
-	return mux
+	return LimitConcurrency(maxInFlight, mux)
 }
 
 func main() {
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -259,6 +260,56 @@ func TestUserRepository_Delete(t *testing.T) {
 	assert.Empty(t, posts)
 }
 
+func TestUserRepository_PostsLifecycle(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userRepo := NewUserRepository(db)
+	postRepo := NewPostRepository(db)
+
+	user := createTestUser(t, db, "Lifecycle User", "lifecycle@example.com")
+	post1 := createTestPost(t, db, user.ID, "First", "First content")
+	post2 := createTestPost(t, db, user.ID, "Second", "Second content")
+
+	// Duplicate email is reported as a typed error
+	err := userRepo.Create(&User{Name: "Other", Email: "lifecycle@example.com"})
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	// Posts for an unknown user are rejected
+	err = postRepo.Create(&Post{UserID: 999, Title: "Orphan"})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// GetByID join-loads the posts in insertion order
+	retrieved, err := userRepo.GetByID(user.ID)
+	require.NoError(t, err)
+	require.Len(t, retrieved.Posts, 2)
+	assert.Equal(t, post1.ID, retrieved.Posts[0].ID)
+	assert.Equal(t, post2.ID, retrieved.Posts[1].ID)
+	assert.Equal(t, user.ID, retrieved.Posts[0].UserID)
+	assert.Equal(t, "Second content", retrieved.Posts[1].Content)
+	assert.False(t, retrieved.Posts[0].CreatedAt.IsZero())
+
+	// A user without posts is still returned
+	lonely := createTestUser(t, db, "Lonely User", "lonely@example.com")
+	retrieved, err = userRepo.GetByID(lonely.ID)
+	require.NoError(t, err)
+	assert.Empty(t, retrieved.Posts)
+
+	// Delete removes the user together with its posts
+	require.NoError(t, userRepo.Delete(user.ID))
+
+	_, err = userRepo.GetByID(user.ID)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = $1", user.ID).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// Deleting again reports the missing user
+	assert.ErrorIs(t, userRepo.Delete(user.ID), ErrUserNotFound)
+}
+
 func TestPostRepository_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -424,6 +475,74 @@ func TestUserService_CreateUserWithPosts(t *testing.T) {
 	})
 }
 
+func TestUserService_CreateUserWithPosts_Atomicity(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userRepo := NewUserRepository(db)
+	postRepo := NewPostRepository(db)
+	service := NewUserService(userRepo, postRepo)
+
+	t.Run("returns populated user", func(t *testing.T) {
+		user := &User{Name: "Populated User", Email: "populated@example.com"}
+		posts := []Post{
+			{Title: "Hello", Content: "First"},
+			{Title: "World", Content: "Second"},
+		}
+
+		require.NoError(t, service.CreateUserWithPosts(user, posts))
+
+		assert.NotZero(t, user.ID)
+		assert.False(t, user.CreatedAt.IsZero())
+		require.Len(t, user.Posts, 2)
+		for i, post := range user.Posts {
+			assert.NotZero(t, post.ID)
+			assert.Equal(t, user.ID, post.UserID)
+			assert.False(t, post.CreatedAt.IsZero())
+			assert.Equal(t, post, posts[i])
+		}
+		assert.Equal(t, "World", user.Posts[1].Title)
+	})
+
+	t.Run("failing post rolls back everything", func(t *testing.T) {
+		user := &User{Name: "Atomic User", Email: "atomic@example.com"}
+		posts := []Post{
+			{Title: "Valid Post", Content: "persisted only on commit"},
+			{Title: strings.Repeat("x", 201), Content: "exceeds VARCHAR(200)"},
+		}
+
+		err := service.CreateUserWithPosts(user, posts)
+		require.Error(t, err)
+
+		// The caller's values are left untouched
+		assert.Zero(t, user.ID)
+		assert.Empty(t, user.Posts)
+		assert.Zero(t, posts[0].ID)
+
+		var count int
+		err = db.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'atomic@example.com'").Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+
+		err = db.QueryRow("SELECT COUNT(*) FROM posts WHERE title = 'Valid Post'").Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		createTestUser(t, db, "Existing", "existing@example.com")
+
+		user := &User{Name: "Duplicate", Email: "existing@example.com"}
+		err := service.CreateUserWithPosts(user, []Post{{Title: "Never stored"}})
+		assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+		var count int
+		err = db.QueryRow("SELECT COUNT(*) FROM posts WHERE title = 'Never stored'").Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+}
+
 func TestUserAPI_Integration(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -583,6 +702,84 @@ func TestUserAPI_DeleteUser(t *testing.T) {
 	})
 }
 
+func TestLimitConcurrency(t *testing.T) {
+	const limit = 3
+	entered := make(chan struct{}, limit)
+	release := make(chan struct{})
+
+	handler := LimitConcurrency(limit, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// Fill every slot with a request that blocks until released
+	allowed := make(chan int, limit)
+	for i := 0; i < limit; i++ {
+		go func() {
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				allowed <- 0
+				return
+			}
+			resp.Body.Close()
+			allowed <- resp.StatusCode
+		}()
+	}
+	for i := 0; i < limit; i++ {
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for requests to occupy the limiter")
+		}
+	}
+
+	// Excess requests are rejected immediately
+	var wg sync.WaitGroup
+	rejected := make(chan *http.Response, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rejected <- resp
+		}()
+	}
+	wg.Wait()
+	close(rejected)
+
+	for resp := range rejected {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.NotEmpty(t, errResp.Error)
+		resp.Body.Close()
+	}
+
+	// The admitted requests complete successfully once released
+	close(release)
+	for i := 0; i < limit; i++ {
+		assert.Equal(t, http.StatusOK, <-allowed)
+	}
+
+	// Capacity is released as requests finish
+	for i := 0; i < limit*2; i++ {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		<-entered
+	}
+}
+
 func TestParallelTests(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -4,12 +4,15 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -34,6 +37,17 @@ func (e *ValidationError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Field, e.Message)
 }
 
+// ValidationErrors represents multiple validation errors
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, ", ")
+}
+
 // SearchQuery represents search parameters
 type SearchQuery struct {
 	Name     string   `json:"name,omitempty"`
@@ -59,7 +73,9 @@ type DataProcessor struct {
 type SortAlgorithm func([]int)
 
 // UserRepository manages user data
+// 並行するHTTPリクエストから安全に利用できるよう、全メソッドがmuでロックする
 type UserRepository struct {
+	mu     sync.RWMutex
 	users  map[int]*User
 	nextID int
 }
@@ -74,133 +90,453 @@ func NewUserRepository() *UserRepository {
 
 // Create creates a new user
 func (r *UserRepository) Create(user User) (*User, error) {
-	// TODO: Implement user creation
-	// - Validate user data
-	// - Assign ID and timestamp
-	// - Store in repository
-	// - Return created user
-	return nil, nil
+	if err := ValidateUser(user); err != nil {
+		return nil, err
+	}
+	
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	user.ID = r.nextID
+	r.nextID++
+	user.CreatedAt = time.Now()
+	
+	r.users[user.ID] = &user
+	return &user, nil
 }
 
 // GetByID retrieves user by ID
 func (r *UserRepository) GetByID(id int) (*User, error) {
-	// TODO: Implement user retrieval by ID
-	// - Look up user in repository
-	// - Return user or error if not found
-	return nil, nil
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	user, exists := r.users[id]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
 }
 
 // Update updates existing user
 func (r *UserRepository) Update(id int, user User) (*User, error) {
-	// TODO: Implement user update
-	// - Validate user exists
-	// - Validate new data
-	// - Update user in repository
-	// - Return updated user
-	return nil, nil
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	existing, exists := r.users[id]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+	
+	if err := ValidateUser(user); err != nil {
+		return nil, err
+	}
+	
+	// 保存済みのポインタは書き換えず、新しい値に差し替える
+	user.ID = id
+	user.CreatedAt = existing.CreatedAt
+	r.users[id] = &user
+	return &user, nil
 }
 
 // Delete deletes user by ID
 func (r *UserRepository) Delete(id int) error {
-	// TODO: Implement user deletion
-	// - Check if user exists
-	// - Remove from repository
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if _, exists := r.users[id]; !exists {
+		return fmt.Errorf("user not found")
+	}
+	delete(r.users, id)
 	return nil
 }
 
 // Search searches users based on query
 func (r *UserRepository) Search(query SearchQuery) []*User {
-	// TODO: Implement user search
-	// - Filter users based on query parameters
-	// - Support name, email, role, age range, keywords
-	// - Return matching users
-	return nil
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	var result []*User
+	
+	for _, user := range r.users {
+		if query.Name != "" && !strings.Contains(strings.ToLower(user.Name), strings.ToLower(query.Name)) {
+			continue
+		}
+		if query.Email != "" && !matchEmail(user.Email, query.Email) {
+			continue
+		}
+		if query.Role != "" && user.Role != query.Role {
+			continue
+		}
+		if query.MinAge > 0 && user.Age < query.MinAge {
+			continue
+		}
+		if query.MaxAge > 0 && user.Age > query.MaxAge {
+			continue
+		}
+		if len(query.Keywords) > 0 {
+			hasAllKeywords := true
+			for _, keyword := range query.Keywords {
+				if !strings.Contains(strings.ToLower(user.Description), strings.ToLower(keyword)) {
+					hasAllKeywords = false
+					break
+				}
+			}
+			if !hasAllKeywords {
+				continue
+			}
+		}
+		result = append(result, user)
+	}
+	
+	sortByID(result)
+	return result
+}
+
+// matchEmail matches the whole address exactly (case-insensitive), or only
+// the domain when the query starts with "@" (e.g. "@example.com").
+func matchEmail(email, query string) bool {
+	if strings.HasPrefix(query, "@") {
+		return strings.HasSuffix(strings.ToLower(email), strings.ToLower(query))
+	}
+	return strings.EqualFold(email, query)
+}
+
+// sortByID orders users by ID so results don't depend on map iteration order
+func sortByID(users []*User) {
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+}
+
+// ScoredUser pairs a search result with its relevance score
+type ScoredUser struct {
+	User  *User   `json:"user"`
+	Score float64 `json:"score"`
+}
+
+// Relevance weights used by SearchRanked
+const (
+	exactNameScore     = 100.0
+	prefixNameScore    = 50.0
+	substringNameScore = 25.0
+	keywordHitScore    = 10.0
+)
+
+// SearchRanked searches users and orders them by relevance to the query.
+// Name matches rank exact > prefix > substring, and every keyword occurrence
+// in the name or description adds to the score. Ties are broken by ID.
+func (r *UserRepository) SearchRanked(query SearchQuery) []ScoredUser {
+	users := r.Search(query)
+	result := make([]ScoredUser, 0, len(users))
+	for _, user := range users {
+		result = append(result, ScoredUser{User: user, Score: scoreUser(user, query)})
+	}
+	
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].User.ID < result[j].User.ID
+	})
+	
+	return result
+}
+
+func scoreUser(user *User, query SearchQuery) float64 {
+	score := 0.0
+	
+	if query.Name != "" {
+		name := strings.ToLower(user.Name)
+		q := strings.ToLower(query.Name)
+		switch {
+		case name == q:
+			score += exactNameScore
+		case strings.HasPrefix(name, q):
+			score += prefixNameScore
+		case strings.Contains(name, q):
+			score += substringNameScore
+		}
+	}
+	
+	text := strings.ToLower(user.Name + " " + user.Description)
+	for _, keyword := range query.Keywords {
+		if keyword == "" {
+			continue
+		}
+		score += keywordHitScore * float64(strings.Count(text, strings.ToLower(keyword)))
+	}
+	
+	return score
 }
 
 // GetAll returns all users
 func (r *UserRepository) GetAll() []*User {
-	// TODO: Implement get all users
-	// - Return all users in repository
-	return nil
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	result := make([]*User, 0, len(r.users))
+	for _, user := range r.users {
+		result = append(result, user)
+	}
+	sortByID(result)
+	return result
 }
 
 // ValidateUser validates user data
 func ValidateUser(user User) error {
-	// TODO: Implement user validation
-	// - Check name is not empty
-	// - Validate email format
-	// - Check age is positive
-	// - Validate role (admin/user)
-	// - Return ValidationError for specific fields
+	var errors ValidationErrors
+	
+	if strings.TrimSpace(user.Name) == "" {
+		errors = append(errors, ValidationError{Field: "name", Message: "name is required"})
+	}
+	if !validateEmail(user.Email) {
+		errors = append(errors, ValidationError{Field: "email", Message: "invalid email format"})
+	}
+	if user.Age <= 0 || user.Age > 150 {
+		errors = append(errors, ValidationError{Field: "age", Message: "age must be between 1 and 150"})
+	}
+	if user.Role != "admin" && user.Role != "user" {
+		errors = append(errors, ValidationError{Field: "role", Message: "role must be 'admin' or 'user'"})
+	}
+	
+	if len(errors) > 0 {
+		return errors
+	}
 	return nil
 }
 
 // NewDataProcessor creates a new data processor
 func NewDataProcessor() *DataProcessor {
-	// TODO: Initialize data processor with sorting algorithms
-	// - BubbleSort
-	// - QuickSort  
-	// - MergeSort
-	return nil
+	return &DataProcessor{
+		algorithms: map[string]SortAlgorithm{
+			"BubbleSort": BubbleSort,
+			"QuickSort":  QuickSort,
+			"MergeSort":  MergeSort,
+		},
+	}
 }
 
 // Sort sorts data using specified algorithm
 func (dp *DataProcessor) Sort(data []int, algorithm string) error {
-	// TODO: Implement sorting
-	// - Look up algorithm by name
-	// - Apply algorithm to data
-	// - Return error if algorithm not found
+	alg, exists := dp.algorithms[algorithm]
+	if !exists {
+		return fmt.Errorf("algorithm not found: %s", algorithm)
+	}
+	alg(data)
 	return nil
 }
 
 // GetAvailableAlgorithms returns list of available algorithms
 func (dp *DataProcessor) GetAvailableAlgorithms() []string {
-	// TODO: Return list of algorithm names
-	return nil
+	var algorithms []string
+	for name := range dp.algorithms {
+		algorithms = append(algorithms, name)
+	}
+	sort.Strings(algorithms)
+	return algorithms
 }
 
 // Transform applies transformation to data
 func (dp *DataProcessor) Transform(data []int, operation string) ([]int, error) {
-	// TODO: Implement data transformation
-	// - Support operations: "double", "square", "abs"
-	// - Apply operation to each element
-	// - Return transformed data
-	return nil, nil
+	result := make([]int, len(data))
+	
+	switch operation {
+	case "double":
+		for i, v := range data {
+			result[i] = v * 2
+		}
+	case "square":
+		for i, v := range data {
+			result[i] = v * v
+		}
+	case "abs":
+		for i, v := range data {
+			if v < 0 {
+				result[i] = -v
+			} else {
+				result[i] = v
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown operation: %s", operation)
+	}
+	
+	return result, nil
+}
+
+// filterPredicates maps predicate names accepted by Filter to their checks
+var filterPredicates = map[string]func(int) bool{
+	"positive": func(v int) bool { return v > 0 },
+	"negative": func(v int) bool { return v < 0 },
+	"even":     func(v int) bool { return v%2 == 0 },
+	"odd":      func(v int) bool { return v%2 != 0 },
 }
 
 // Filter filters data based on predicate
 func (dp *DataProcessor) Filter(data []int, predicate string) ([]int, error) {
-	// TODO: Implement data filtering
-	// - Support predicates: "positive", "negative", "even", "odd"
-	// - Filter elements based on predicate
-	// - Return filtered data
-	return nil, nil
+	keep, exists := filterPredicates[predicate]
+	if !exists {
+		return nil, fmt.Errorf("unknown predicate: %s", predicate)
+	}
+	
+	result := make([]int, 0, len(data))
+	for _, v := range data {
+		if keep(v) {
+			result = append(result, v)
+		}
+	}
+	
+	return result, nil
 }
 
 // Calculate performs statistical calculations
 func (dp *DataProcessor) Calculate(data []int, operation string) (float64, error) {
-	// TODO: Implement statistical calculations
-	// - Support operations: "mean", "median", "mode", "stddev"
-	// - Calculate statistic for data
-	// - Return result
+	switch operation {
+	case "mean", "median", "mode", "stddev":
+	default:
+		return 0, fmt.Errorf("unknown operation: %s", operation)
+	}
+	
+	if len(data) == 0 {
+		return 0, fmt.Errorf("empty data")
+	}
+	
+	switch operation {
+	case "mean":
+		sum := 0
+		for _, v := range data {
+			sum += v
+		}
+		return float64(sum) / float64(len(data)), nil
+	case "median":
+		sorted := make([]int, len(data))
+		copy(sorted, data)
+		sort.Ints(sorted)
+		n := len(sorted)
+		if n%2 == 0 {
+			return float64(sorted[n/2-1]+sorted[n/2]) / 2.0, nil
+		}
+		return float64(sorted[n/2]), nil
+	case "mode":
+		freq := make(map[int]int)
+		for _, v := range data {
+			freq[v]++
+		}
+		maxFreq := 0
+		mode := 0
+		for v, f := range freq {
+			// 同じ頻度の値が複数ある場合は最小値を採用（結果を決定的にする）
+			if f > maxFreq || (f == maxFreq && v < mode) {
+				maxFreq = f
+				mode = v
+			}
+		}
+		return float64(mode), nil
+	case "stddev":
+		mean := 0.0
+		for _, v := range data {
+			mean += float64(v)
+		}
+		mean /= float64(len(data))
+		
+		variance := 0.0
+		for _, v := range data {
+			variance += (float64(v) - mean) * (float64(v) - mean)
+		}
+		variance /= float64(len(data))
+		
+		return math.Sqrt(variance), nil
+	}
+	
 	return 0, nil
 }
 
 // Sorting algorithms
 func BubbleSort(data []int) {
-	// TODO: Implement bubble sort algorithm
+	n := len(data)
+	for i := 0; i < n-1; i++ {
+		for j := 0; j < n-i-1; j++ {
+			if data[j] > data[j+1] {
+				data[j], data[j+1] = data[j+1], data[j]
+			}
+		}
+	}
 }
 
 func QuickSort(data []int) {
-	// TODO: Implement quick sort algorithm
-	// - Use recursive approach
-	// - Handle edge cases (empty/single element)
+	if len(data) <= 1 {
+		return
+	}
+	quickSortHelper(data, 0, len(data)-1)
+}
+
+func quickSortHelper(data []int, low, high int) {
+	if low < high {
+		pi := partition(data, low, high)
+		quickSortHelper(data, low, pi-1)
+		quickSortHelper(data, pi+1, high)
+	}
+}
+
+func partition(data []int, low, high int) int {
+	pivot := data[high]
+	i := low - 1
+	
+	for j := low; j < high; j++ {
+		if data[j] <= pivot {
+			i++
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	data[i+1], data[high] = data[high], data[i+1]
+	return i + 1
 }
 
 func MergeSort(data []int) {
-	// TODO: Implement merge sort algorithm
-	// - Use divide and conquer approach
-	// - Implement merge helper function
+	if len(data) <= 1 {
+		return
+	}
+	mergeSortHelper(data, 0, len(data)-1)
+}
+
+func mergeSortHelper(data []int, left, right int) {
+	if left < right {
+		mid := (left + right) / 2
+		mergeSortHelper(data, left, mid)
+		mergeSortHelper(data, mid+1, right)
+		merge(data, left, mid, right)
+	}
+}
+
+func merge(data []int, left, mid, right int) {
+	leftArr := make([]int, mid-left+1)
+	rightArr := make([]int, right-mid)
+	
+	copy(leftArr, data[left:mid+1])
+	copy(rightArr, data[mid+1:right+1])
+	
+	i, j, k := 0, 0, left
+	
+	for i < len(leftArr) && j < len(rightArr) {
+		if leftArr[i] <= rightArr[j] {
+			data[k] = leftArr[i]
+			i++
+		} else {
+			data[k] = rightArr[j]
+			j++
+		}
+		k++
+	}
+	
+	for i < len(leftArr) {
+		data[k] = leftArr[i]
+		i++
+		k++
+	}
+	
+	for j < len(rightArr) {
+		data[k] = rightArr[j]
+		j++
+		k++
+	}
 }
 
 // UserMatcher provides flexible user matching
@@ -216,12 +552,32 @@ type UserMatcher struct {
 
 // Matches checks if user matches criteria
 func (m UserMatcher) Matches(user User) bool {
-	// TODO: Implement user matching logic
-	// - Check each field if specified
-	// - Check age range
-	// - Check if description contains keywords
-	// - Return true if all criteria match
-	return false
+	if m.ID != nil && user.ID != *m.ID {
+		return false
+	}
+	if m.Name != nil && user.Name != *m.Name {
+		return false
+	}
+	if m.Email != nil && user.Email != *m.Email {
+		return false
+	}
+	if m.Role != nil && user.Role != *m.Role {
+		return false
+	}
+	if m.MinAge != nil && user.Age < *m.MinAge {
+		return false
+	}
+	if m.MaxAge != nil && user.Age > *m.MaxAge {
+		return false
+	}
+	if len(m.Contains) > 0 {
+		for _, keyword := range m.Contains {
+			if !strings.Contains(strings.ToLower(user.Description), strings.ToLower(keyword)) {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // UserBuilder provides fluent interface for user creation
@@ -231,43 +587,49 @@ type UserBuilder struct {
 
 // NewUserBuilder creates a new user builder
 func NewUserBuilder() *UserBuilder {
-	// TODO: Initialize builder with default values
-	return nil
+	return &UserBuilder{
+		user: User{
+			Name:      "Default User",
+			Email:     "default@example.com",
+			Age:       25,
+			Role:      "user",
+			CreatedAt: time.Now(),
+		},
+	}
 }
 
 // WithName sets user name
 func (b *UserBuilder) WithName(name string) *UserBuilder {
-	// TODO: Set name and return builder
+	b.user.Name = name
 	return b
 }
 
 // WithEmail sets user email
 func (b *UserBuilder) WithEmail(email string) *UserBuilder {
-	// TODO: Set email and return builder
+	b.user.Email = email
 	return b
 }
 
 // WithAge sets user age
 func (b *UserBuilder) WithAge(age int) *UserBuilder {
-	// TODO: Set age and return builder
+	b.user.Age = age
 	return b
 }
 
 // WithRole sets user role
 func (b *UserBuilder) WithRole(role string) *UserBuilder {
-	// TODO: Set role and return builder
+	b.user.Role = role
 	return b
 }
 
 // WithDescription sets user description
 func (b *UserBuilder) WithDescription(desc string) *UserBuilder {
-	// TODO: Set description and return builder
+	b.user.Description = desc
 	return b
 }
 
 // Build creates the user
 func (b *UserBuilder) Build() User {
-	// TODO: Return built user
 	return b.user
 }
 
@@ -283,12 +645,23 @@ func NewUserAPI(repo *UserRepository) *UserAPI {
 
 // CreateUser handles POST /users
 func (api *UserAPI) CreateUser(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement user creation endpoint
-	// - Parse JSON request body
-	// - Validate user data
-	// - Create user via repository
-	// - Return 201 with created user
-	// - Return 400 for validation errors
+	var user User
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+	
+	created, err := api.repo.Create(user)
+	if err != nil {
+		if details, ok := validationDetails(err); ok {
+			writeError(w, http.StatusBadRequest, "validation error", details)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+	
+	writeJSON(w, http.StatusCreated, created)
 }
 
 // GetUser handles GET /users/{id}
@@ -302,13 +675,33 @@ func (api *UserAPI) GetUser(w http.ResponseWriter, r *http.Request) {
 
 // UpdateUser handles PUT /users/{id}
 func (api *UserAPI) UpdateUser(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement user update endpoint
-	// - Extract ID from URL path
-	// - Parse JSON request body
-	// - Update user via repository
-	// - Return 200 with updated user
-	// - Return 404 if not found
-	// - Return 400 for validation errors
+	id, err := extractIDFromPath(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+	
+	var user User
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+	
+	updated, err := api.repo.Update(id, user)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeError(w, http.StatusNotFound, "User not found", nil)
+			return
+		}
+		if details, ok := validationDetails(err); ok {
+			writeError(w, http.StatusBadRequest, "validation error", details)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Internal server error", nil)
+		return
+	}
+	
+	writeJSON(w, http.StatusOK, updated)
 }
 
 // DeleteUser handles DELETE /users/{id}
@@ -329,27 +722,56 @@ func (api *UserAPI) SearchUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 // Helper functions
+var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
 func validateEmail(email string) bool {
-	// TODO: Implement email validation using regex
-	return false
+	return emailRegex.MatchString(email)
+}
+
+// validationDetails converts validation errors into a field -> message map
+func validationDetails(err error) (map[string]string, bool) {
+	var validationErrors ValidationErrors
+	if errors.As(err, &validationErrors) {
+		details := make(map[string]string, len(validationErrors))
+		for _, validationErr := range validationErrors {
+			details[validationErr.Field] = validationErr.Message
+		}
+		return details, true
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return map[string]string{validationErr.Field: validationErr.Message}, true
+	}
+	return nil, false
 }
 
 func extractIDFromPath(path string) (int, error) {
-	// TODO: Extract ID from URL path like "/users/123"
-	return 0, nil
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 {
+		return 0, fmt.Errorf("invalid path")
+	}
+	
+	idStr := parts[len(parts)-1]
+	if idStr == "search" {
+		return 0, fmt.Errorf("invalid path")
+	}
+	
+	return strconv.Atoi(idStr)
 }
 
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	// TODO: Write JSON response
-	// - Set Content-Type header
-	// - Set status code
-	// - Encode data as JSON
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
 }
 
 func writeError(w http.ResponseWriter, status int, message string, details map[string]string) {
-	// TODO: Write error response
-	// - Create ErrorResponse
-	// - Write as JSON
+	errorResponse := ErrorResponse{
+		Message: message,
+		Details: details,
+	}
+	writeJSON(w, status, errorResponse)
 }
 
 // Utility functions for pointers (used in tests)
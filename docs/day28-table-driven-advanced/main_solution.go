@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -69,7 +71,9 @@ type DataProcessor struct {
 type SortAlgorithm func([]int)
 
 // UserRepository manages user data
+// 並行するHTTPリクエストから安全に利用できるよう、全メソッドがmuでロックする
 type UserRepository struct {
+	mu     sync.RWMutex
 	users  map[int]*User
 	nextID int
 }
@@ -88,6 +92,9 @@ func (r *UserRepository) Create(user User) (*User, error) {
 		return nil, err
 	}
 	
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
 	user.ID = r.nextID
 	r.nextID++
 	user.CreatedAt = time.Now()
@@ -98,6 +105,9 @@ func (r *UserRepository) Create(user User) (*User, error) {
 
 // GetByID retrieves user by ID
 func (r *UserRepository) GetByID(id int) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
 	user, exists := r.users[id]
 	if !exists {
 		return nil, fmt.Errorf("user not found")
@@ -107,6 +117,9 @@ func (r *UserRepository) GetByID(id int) (*User, error) {
 
 // Update updates existing user
 func (r *UserRepository) Update(id int, user User) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
 	if _, exists := r.users[id]; !exists {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -123,6 +136,9 @@ func (r *UserRepository) Update(id int, user User) (*User, error) {
 
 // Delete deletes user by ID
 func (r *UserRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
 	if _, exists := r.users[id]; !exists {
 		return fmt.Errorf("user not found")
 	}
@@ -132,13 +148,16 @@ func (r *UserRepository) Delete(id int) error {
 
 // Search searches users based on query
 func (r *UserRepository) Search(query SearchQuery) []*User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
 	var result []*User
 	
 	for _, user := range r.users {
 		if query.Name != "" && !strings.Contains(strings.ToLower(user.Name), strings.ToLower(query.Name)) {
 			continue
 		}
-		if query.Email != "" && !strings.Contains(strings.ToLower(user.Email), strings.ToLower(query.Email)) {
+		if query.Email != "" && !matchEmail(user.Email, query.Email) {
 			continue
 		}
 		if query.Role != "" && user.Role != query.Role {
@@ -165,15 +184,95 @@ func (r *UserRepository) Search(query SearchQuery) []*User {
 		result = append(result, user)
 	}
 	
+	sortByID(result)
+	return result
+}
+
+// matchEmail matches the whole address exactly (case-insensitive), or only
+// the domain when the query starts with "@" (e.g. "@example.com").
+func matchEmail(email, query string) bool {
+	if strings.HasPrefix(query, "@") {
+		return strings.HasSuffix(strings.ToLower(email), strings.ToLower(query))
+	}
+	return strings.EqualFold(email, query)
+}
+
+// sortByID orders users by ID so results don't depend on map iteration order
+func sortByID(users []*User) {
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+}
+
+// ScoredUser pairs a search result with its relevance score
+type ScoredUser struct {
+	User  *User   `json:"user"`
+	Score float64 `json:"score"`
+}
+
+// Relevance weights used by SearchRanked
+const (
+	exactNameScore     = 100.0
+	prefixNameScore    = 50.0
+	substringNameScore = 25.0
+	keywordHitScore    = 10.0
+)
+
+// SearchRanked searches users and orders them by relevance to the query.
+// Name matches rank exact > prefix > substring, and every keyword occurrence
+// in the name or description adds to the score. Ties are broken by ID.
+func (r *UserRepository) SearchRanked(query SearchQuery) []ScoredUser {
+	users := r.Search(query)
+	result := make([]ScoredUser, 0, len(users))
+	for _, user := range users {
+		result = append(result, ScoredUser{User: user, Score: scoreUser(user, query)})
+	}
+	
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].User.ID < result[j].User.ID
+	})
+	
 	return result
 }
 
+func scoreUser(user *User, query SearchQuery) float64 {
+	score := 0.0
+	
+	if query.Name != "" {
+		name := strings.ToLower(user.Name)
+		q := strings.ToLower(query.Name)
+		switch {
+		case name == q:
+			score += exactNameScore
+		case strings.HasPrefix(name, q):
+			score += prefixNameScore
+		case strings.Contains(name, q):
+			score += substringNameScore
+		}
+	}
+	
+	text := strings.ToLower(user.Name + " " + user.Description)
+	for _, keyword := range query.Keywords {
+		if keyword == "" {
+			continue
+		}
+		score += keywordHitScore * float64(strings.Count(text, strings.ToLower(keyword)))
+	}
+	
+	return score
+}
+
 // GetAll returns all users
 func (r *UserRepository) GetAll() []*User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
 	result := make([]*User, 0, len(r.users))
 	for _, user := range r.users {
 		result = append(result, user)
 	}
+	sortByID(result)
 	return result
 }
 
@@ -259,30 +358,25 @@ func (dp *DataProcessor) Transform(data []int, operation string) ([]int, error)
 	return result, nil
 }
 
+// filterPredicates maps predicate names accepted by Filter to their checks
+var filterPredicates = map[string]func(int) bool{
+	"positive": func(v int) bool { return v > 0 },
+	"negative": func(v int) bool { return v < 0 },
+	"even":     func(v int) bool { return v%2 == 0 },
+	"odd":      func(v int) bool { return v%2 != 0 },
+}
+
 // Filter filters data based on predicate
 func (dp *DataProcessor) Filter(data []int, predicate string) ([]int, error) {
-	var result []int
+	keep, exists := filterPredicates[predicate]
+	if !exists {
+		return nil, fmt.Errorf("unknown predicate: %s", predicate)
+	}
 	
+	result := make([]int, 0, len(data))
 	for _, v := range data {
-		switch predicate {
-		case "positive":
-			if v > 0 {
-				result = append(result, v)
-			}
-		case "negative":
-			if v < 0 {
-				result = append(result, v)
-			}
-		case "even":
-			if v%2 == 0 {
-				result = append(result, v)
-			}
-		case "odd":
-			if v%2 != 0 {
-				result = append(result, v)
-			}
-		default:
-			return nil, fmt.Errorf("unknown predicate: %s", predicate)
+		if keep(v) {
+			result = append(result, v)
 		}
 	}
 	
@@ -291,6 +385,12 @@ func (dp *DataProcessor) Filter(data []int, predicate string) ([]int, error) {
 
 // Calculate performs statistical calculations
 func (dp *DataProcessor) Calculate(data []int, operation string) (float64, error) {
+	switch operation {
+	case "mean", "median", "mode", "stddev":
+	default:
+		return 0, fmt.Errorf("unknown operation: %s", operation)
+	}
+	
 	if len(data) == 0 {
 		return 0, fmt.Errorf("empty data")
 	}
@@ -319,7 +419,8 @@ func (dp *DataProcessor) Calculate(data []int, operation string) (float64, error
 		maxFreq := 0
 		mode := 0
 		for v, f := range freq {
-			if f > maxFreq {
+			// 同じ頻度の値が複数ある場合は最小値を採用（結果を決定的にする）
+			if f > maxFreq || (f == maxFreq && v < mode) {
 				maxFreq = f
 				mode = v
 			}
@@ -339,9 +440,9 @@ func (dp *DataProcessor) Calculate(data []int, operation string) (float64, error
 		variance /= float64(len(data))
 		
 		return math.Sqrt(variance), nil
-	default:
-		return 0, fmt.Errorf("unknown operation: %s", operation)
 	}
+	
+	return 0, nil
 }
 
 // Sorting algorithms
@@ -548,20 +649,10 @@ func (api *UserAPI) CreateUser(w http.ResponseWriter, r *http.Request) {
 	
 	created, err := api.repo.Create(user)
 	if err != nil {
-		if validationErrors, ok := err.(ValidationErrors); ok {
-			details := make(map[string]string)
-			for _, validationErr := range validationErrors {
-				details[validationErr.Field] = validationErr.Message
-			}
+		if details, ok := validationDetails(err); ok {
 			writeError(w, http.StatusBadRequest, "validation error", details)
 			return
 		}
-		if validationErr, ok := err.(*ValidationError); ok {
-			writeError(w, http.StatusBadRequest, "validation error", map[string]string{
-				validationErr.Field: validationErr.Message,
-			})
-			return
-		}
 		writeError(w, http.StatusInternalServerError, "Internal server error", nil)
 		return
 	}
@@ -606,20 +697,10 @@ func (api *UserAPI) UpdateUser(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusNotFound, "User not found", nil)
 			return
 		}
-		if validationErrors, ok := err.(ValidationErrors); ok {
-			details := make(map[string]string)
-			for _, validationErr := range validationErrors {
-				details[validationErr.Field] = validationErr.Message
-			}
+		if details, ok := validationDetails(err); ok {
 			writeError(w, http.StatusBadRequest, "validation error", details)
 			return
 		}
-		if validationErr, ok := err.(*ValidationError); ok {
-			writeError(w, http.StatusBadRequest, "validation error", map[string]string{
-				validationErr.Field: validationErr.Message,
-			})
-			return
-		}
 		writeError(w, http.StatusInternalServerError, "Internal server error", nil)
 		return
 	}
@@ -672,11 +753,30 @@ func (api *UserAPI) SearchUsers(w http.ResponseWriter, r *http.Request) {
 }
 
 // Helper functions
+var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
 func validateEmail(email string) bool {
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	return emailRegex.MatchString(email)
 }
 
+// validationDetails converts validation errors into a field -> message map
+func validationDetails(err error) (map[string]string, bool) {
+	var validationErrors ValidationErrors
+	if errors.As(err, &validationErrors) {
+		details := make(map[string]string, len(validationErrors))
+		for _, validationErr := range validationErrors {
+			details[validationErr.Field] = validationErr.Message
+		}
+		return details, true
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return map[string]string{validationErr.Field: validationErr.Message}, true
+	}
+	return nil, false
+}
+
 func extractIDFromPath(path string) (int, error) {
 	parts := strings.Split(path, "/")
 	if len(parts) < 3 {
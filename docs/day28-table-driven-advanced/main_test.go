@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -371,6 +372,202 @@ func TestUserRepositorySearch(t *testing.T) {
 	}
 }
 
+func TestUserRepository_CreateValidationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       User
+		wantFields []string
+	}{
+		{
+			name:       "missing name",
+			user:       User{Name: "  ", Email: "a@example.com", Age: 20, Role: "user"},
+			wantFields: []string{"name"},
+		},
+		{
+			name:       "invalid email and age",
+			user:       User{Name: "A", Email: "not-an-email", Age: 151, Role: "admin"},
+			wantFields: []string{"email", "age"},
+		},
+		{
+			name:       "unknown role",
+			user:       User{Name: "A", Email: "a@example.com", Age: 20, Role: "guest"},
+			wantFields: []string{"role"},
+		},
+		{
+			name:       "everything invalid",
+			user:       User{},
+			wantFields: []string{"name", "email", "age", "role"},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewUserRepository()
+			
+			user, err := repo.Create(tt.user)
+			require.Error(t, err)
+			assert.Nil(t, user)
+			
+			var verrs ValidationErrors
+			require.ErrorAs(t, err, &verrs)
+			
+			fields := make([]string, 0, len(verrs))
+			for _, verr := range verrs {
+				fields = append(fields, verr.Field)
+			}
+			assert.Equal(t, tt.wantFields, fields)
+			assert.Empty(t, repo.GetAll(), "invalid users must not be stored")
+		})
+	}
+}
+
+func TestUserRepository_GetAllAndMissingIDs(t *testing.T) {
+	repo := NewUserRepository()
+	
+	first, err := repo.Create(User{Name: "First", Email: "first@example.com", Age: 20, Role: "user"})
+	require.NoError(t, err)
+	second, err := repo.Create(User{Name: "Second", Email: "second@example.com", Age: 30, Role: "admin"})
+	require.NoError(t, err)
+	
+	assert.Equal(t, first.ID+1, second.ID, "IDs should be assigned sequentially")
+	assert.False(t, first.CreatedAt.IsZero())
+	
+	all := repo.GetAll()
+	require.Len(t, all, 2)
+	assert.Equal(t, []int{first.ID, second.ID}, []int{all[0].ID, all[1].ID})
+	
+	for _, id := range []int{0, -1, second.ID + 1} {
+		t.Run(fmt.Sprintf("id=%d", id), func(t *testing.T) {
+			user, err := repo.GetByID(id)
+			assert.Error(t, err)
+			assert.Nil(t, user)
+			
+			assert.Error(t, repo.Delete(id))
+		})
+	}
+}
+
+func TestUserRepositorySearch_MultiCriteria(t *testing.T) {
+	repo := NewUserRepository()
+	
+	for _, user := range []User{
+		{Name: "Alice Johnson", Email: "alice@example.com", Age: 25, Role: "user", Description: "Go backend developer"},
+		{Name: "Alice Cooper", Email: "cooper@music.com", Age: 45, Role: "user", Description: "Go enthusiast and singer"},
+		{Name: "Alicia Keys", Email: "alicia@example.com", Age: 38, Role: "admin", Description: "Go developer and pianist"},
+		{Name: "Bob Alison", Email: "bob@example.com", Age: 33, Role: "user", Description: "Python developer"},
+	} {
+		_, err := repo.Create(user)
+		require.NoError(t, err)
+	}
+	
+	tests := []struct {
+		name      string
+		query     SearchQuery
+		wantNames []string
+	}{
+		{
+			name:      "name substring is case-insensitive",
+			query:     SearchQuery{Name: "ALI"},
+			wantNames: []string{"Alice Johnson", "Alice Cooper", "Alicia Keys", "Bob Alison"},
+		},
+		{
+			name:      "exact email",
+			query:     SearchQuery{Email: "Alice@Example.com"},
+			wantNames: []string{"Alice Johnson"},
+		},
+		{
+			name:      "partial email without domain prefix does not match",
+			query:     SearchQuery{Email: "lice@example.com"},
+			wantNames: []string{},
+		},
+		{
+			name:      "domain without @ prefix does not match",
+			query:     SearchQuery{Email: "example.com"},
+			wantNames: []string{},
+		},
+		{
+			name:      "name, role, age range and keywords combined",
+			query:     SearchQuery{Name: "ali", Role: "user", MinAge: 20, MaxAge: 40, Keywords: []string{"developer"}},
+			wantNames: []string{"Alice Johnson", "Bob Alison"},
+		},
+		{
+			name:      "domain, age and keyword",
+			query:     SearchQuery{Email: "@example.com", MinAge: 30, Keywords: []string{"go"}},
+			wantNames: []string{"Alicia Keys"},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := make([]string, 0)
+			for _, user := range repo.Search(tt.query) {
+				names = append(names, user.Name)
+			}
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
+
+func TestUserRepositorySearchRanked(t *testing.T) {
+	repo := NewUserRepository()
+	
+	testUsers := []User{
+		{Name: "Annabel Lee", Email: "annabel@example.com", Age: 30, Role: "user", Description: "Go developer"},
+		{Name: "Ann", Email: "ann@example.com", Age: 28, Role: "user", Description: "Designer"},
+		{Name: "Joanna Smith", Email: "joanna@example.com", Age: 35, Role: "admin", Description: "Go and Go tooling developer"},
+		{Name: "Anne Marie", Email: "anne@example.com", Age: 40, Role: "user", Description: "Backend developer writing Go"},
+	}
+	
+	for _, user := range testUsers {
+		_, err := repo.Create(user)
+		require.NoError(t, err)
+	}
+	
+	tests := []struct {
+		name          string
+		query         SearchQuery
+		expectedOrder []string
+	}{
+		{
+			name:          "exact match ranks above prefix and substring",
+			query:         SearchQuery{Name: "ann"},
+			expectedOrder: []string{"Ann", "Annabel Lee", "Anne Marie", "Joanna Smith"},
+		},
+		{
+			name:          "more keyword hits rank higher",
+			query:         SearchQuery{Keywords: []string{"go"}},
+			expectedOrder: []string{"Joanna Smith", "Annabel Lee", "Anne Marie"},
+		},
+		{
+			name:          "name match outweighs keyword hits",
+			query:         SearchQuery{Name: "anne", Keywords: []string{"developer"}},
+			expectedOrder: []string{"Anne Marie"},
+		},
+		{
+			name:          "no results",
+			query:         SearchQuery{Name: "NonExistent"},
+			expectedOrder: []string{},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := repo.SearchRanked(tt.query)
+			
+			names := make([]string, 0, len(results))
+			for _, result := range results {
+				names = append(names, result.User.Name)
+			}
+			assert.Equal(t, tt.expectedOrder, names)
+			
+			for i := 1; i < len(results); i++ {
+				assert.GreaterOrEqual(t, results[i-1].Score, results[i].Score,
+					"results must be ordered by descending score")
+			}
+		})
+	}
+}
+
 func TestDataProcessor(t *testing.T) {
 	dp := NewDataProcessor()
 	
@@ -569,6 +766,97 @@ func TestDataProcessor(t *testing.T) {
 	})
 }
 
+func TestDataProcessor_Operations(t *testing.T) {
+	dp := NewDataProcessor()
+	
+	t.Run("every registered algorithm sorts", func(t *testing.T) {
+		inputs := map[string][]int{
+			"empty":      {},
+			"single":     {42},
+			"duplicates": {3, 1, 3, 2, 1},
+			"negatives":  {0, -5, 7, -1, 3},
+			"sorted":     {1, 2, 3, 4},
+			"reversed":   {9, 7, 5, 3, 1},
+		}
+		
+		assert.Equal(t, []string{"BubbleSort", "MergeSort", "QuickSort"}, dp.GetAvailableAlgorithms())
+		
+		for _, algorithm := range dp.GetAvailableAlgorithms() {
+			for name, input := range inputs {
+				t.Run(algorithm+"/"+name, func(t *testing.T) {
+					data := append([]int{}, input...)
+					expected := append([]int{}, input...)
+					sort.Ints(expected)
+					
+					require.NoError(t, dp.Sort(data, algorithm))
+					assert.Equal(t, expected, data)
+				})
+			}
+		}
+	})
+	
+	tests := []struct {
+		name    string
+		run     func() (interface{}, error)
+		want    interface{}
+		wantErr string
+	}{
+		{
+			name: "transform abs",
+			run:  func() (interface{}, error) { return dp.Transform([]int{-3, 0, 4}, "abs") },
+			want: []int{3, 0, 4},
+		},
+		{
+			name: "filter without matches returns empty slice",
+			run:  func() (interface{}, error) { return dp.Filter([]int{1, 3, 5}, "even") },
+			want: []int{},
+		},
+		{
+			name: "mode tie picks smallest value",
+			run:  func() (interface{}, error) { return dp.Calculate([]int{5, 5, 2, 2, 9}, "mode") },
+			want: 2.0,
+		},
+		{
+			name:    "unregistered sort algorithm",
+			run:     func() (interface{}, error) { return nil, dp.Sort([]int{2, 1}, "HeapSort") },
+			wantErr: "algorithm not found: HeapSort",
+		},
+		{
+			name:    "unknown transform",
+			run:     func() (interface{}, error) { return dp.Transform([]int{1}, "triple") },
+			wantErr: "unknown operation: triple",
+		},
+		{
+			name:    "unknown filter on empty data",
+			run:     func() (interface{}, error) { return dp.Filter(nil, "prime") },
+			wantErr: "unknown predicate: prime",
+		},
+		{
+			name:    "unknown calculation on empty data",
+			run:     func() (interface{}, error) { return dp.Calculate(nil, "variance") },
+			wantErr: "unknown operation: variance",
+		},
+		{
+			name:    "known calculation on empty data",
+			run:     func() (interface{}, error) { return dp.Calculate(nil, "mean") },
+			wantErr: "empty data",
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.run()
+			
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestUserMatcher(t *testing.T) {
 	users := []User{
 		{ID: 1, Name: "Alice", Email: "alice@example.com", Role: "user", Age: 25, Description: "Go developer"},
@@ -620,7 +908,7 @@ func TestUserMatcher(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var actualIDs []int
+			actualIDs := []int{}
 			for _, user := range users {
 				if tt.matcher.Matches(user) {
 					actualIDs = append(actualIDs, user.ID)
@@ -699,6 +987,71 @@ func TestUserBuilder(t *testing.T) {
 	}
 }
 
+func TestUserBuilder_FixturesWithMatcher(t *testing.T) {
+	builder := NewUserBuilder()
+	assert.Same(t, builder, builder.WithName("Grace Hopper"), "setters must return the builder for chaining")
+	
+	repo := NewUserRepository()
+	fixtures := []User{
+		builder.WithEmail("grace@navy.mil").WithAge(85).WithRole("admin").
+			WithDescription("Compiler pioneer and COBOL designer").Build(),
+		NewUserBuilder().WithName("Alan Turing").WithDescription("Computing theory").Build(),
+		NewUserBuilder().Build(),
+	}
+	for _, fixture := range fixtures {
+		_, err := repo.Create(fixture)
+		require.NoError(t, err, "builder fixtures should pass validation")
+	}
+	
+	// 後続の変更はビルド済みのユーザーに影響しない
+	builder.WithName("Changed")
+	assert.Equal(t, "Grace Hopper", fixtures[0].Name)
+	
+	tests := []struct {
+		name      string
+		matcher   UserMatcher
+		wantNames []string
+	}{
+		{
+			name:      "role only",
+			matcher:   UserMatcher{Role: stringPtr("admin")},
+			wantNames: []string{"Grace Hopper"},
+		},
+		{
+			name:      "age range with default user",
+			matcher:   UserMatcher{MinAge: intPtr(20), MaxAge: intPtr(30)},
+			wantNames: []string{"Alan Turing", "Default User"},
+		},
+		{
+			name:      "keyword is case-insensitive",
+			matcher:   UserMatcher{Contains: []string{"cobol", "COMPILER"}},
+			wantNames: []string{"Grace Hopper"},
+		},
+		{
+			name:      "partial criteria rejects on one mismatch",
+			matcher:   UserMatcher{Role: stringPtr("admin"), MaxAge: intPtr(80)},
+			wantNames: []string{},
+		},
+		{
+			name:      "empty matcher matches everyone",
+			matcher:   UserMatcher{},
+			wantNames: []string{"Grace Hopper", "Alan Turing", "Default User"},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{}
+			for _, user := range repo.GetAll() {
+				if tt.matcher.Matches(*user) {
+					names = append(names, user.Name)
+				}
+			}
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
+
 func TestUserAPI(t *testing.T) {
 	repo := NewUserRepository()
 	api := NewUserAPI(repo)
@@ -837,6 +1190,123 @@ func TestUserAPI(t *testing.T) {
 	})
 }
 
+func TestUserAPI_ValidationErrors(t *testing.T) {
+	repo := NewUserRepository()
+	api := NewUserAPI(repo)
+	existing, err := repo.Create(User{Name: "Existing", Email: "existing@example.com", Age: 40, Role: "admin"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		user            User
+		expectedStatus  int
+		expectedDetails map[string]string
+	}{
+		{
+			name:           "create with empty name",
+			method:         http.MethodPost,
+			path:           "/users",
+			user:           User{Name: " ", Email: "john@example.com", Age: 25, Role: "user"},
+			expectedStatus: http.StatusBadRequest,
+			expectedDetails: map[string]string{
+				"name": "name is required",
+			},
+		},
+		{
+			name:           "create with invalid email",
+			method:         http.MethodPost,
+			path:           "/users",
+			user:           User{Name: "John", Email: "john@", Age: 25, Role: "user"},
+			expectedStatus: http.StatusBadRequest,
+			expectedDetails: map[string]string{
+				"email": "invalid email format",
+			},
+		},
+		{
+			name:           "create with non-positive age",
+			method:         http.MethodPost,
+			path:           "/users",
+			user:           User{Name: "John", Email: "john@example.com", Age: 0, Role: "user"},
+			expectedStatus: http.StatusBadRequest,
+			expectedDetails: map[string]string{
+				"age": "age must be between 1 and 150",
+			},
+		},
+		{
+			name:           "create with invalid role",
+			method:         http.MethodPost,
+			path:           "/users",
+			user:           User{Name: "John", Email: "john@example.com", Age: 25, Role: "owner"},
+			expectedStatus: http.StatusBadRequest,
+			expectedDetails: map[string]string{
+				"role": "role must be 'admin' or 'user'",
+			},
+		},
+		{
+			name:           "create with every field invalid",
+			method:         http.MethodPost,
+			path:           "/users",
+			user:           User{Email: "invalid", Age: -1},
+			expectedStatus: http.StatusBadRequest,
+			expectedDetails: map[string]string{
+				"name":  "name is required",
+				"email": "invalid email format",
+				"age":   "age must be between 1 and 150",
+				"role":  "role must be 'admin' or 'user'",
+			},
+		},
+		{
+			name:           "update with invalid fields",
+			method:         http.MethodPut,
+			path:           fmt.Sprintf("/users/%d", existing.ID),
+			user:           User{Name: "Existing", Email: "existing@example.com", Age: 151, Role: "root"},
+			expectedStatus: http.StatusBadRequest,
+			expectedDetails: map[string]string{
+				"age":  "age must be between 1 and 150",
+				"role": "role must be 'admin' or 'user'",
+			},
+		},
+		{
+			name:           "update missing user is not a validation error",
+			method:         http.MethodPut,
+			path:           "/users/999",
+			user:           User{},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.user)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+			if tt.method == http.MethodPost {
+				api.CreateUser(rec, req)
+			} else {
+				api.UpdateUser(rec, req)
+			}
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var errResp ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.expectedDetails, errResp.Details)
+		})
+	}
+
+	// 検証に失敗したリクエストはリポジトリを変更しない
+	_, err = repo.GetByID(existing.ID + 1)
+	assert.Error(t, err)
+	stored, err := repo.GetByID(existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 40, stored.Age)
+}
+
 func TestParallelUserOperations(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -845,6 +1315,7 @@ func TestParallelUserOperations(t *testing.T) {
 		{"CreateUsers", testCreateMultipleUsersParallel},
 		{"ReadUsers", testReadMultipleUsersParallel},
 		{"UpdateUsers", testUpdateMultipleUsersParallel},
+		{"MixedCRUD", testMixedCRUDParallel},
 	}
 	
 	for _, tt := range tests {
@@ -905,6 +1376,77 @@ func testCreateMultipleUsersParallel(t *testing.T) {
 	}
 }
 
+// testMixedCRUDParallel hammers every repository method at once; run with -race
+func testMixedCRUDParallel(t *testing.T) {
+	repo := NewUserRepository()
+	
+	const numWorkers = 20
+	const usersPerWorker = 10
+	
+	var wg sync.WaitGroup
+	
+	// Readers scan the map while writers mutate it
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				repo.GetAll()
+				repo.Search(SearchQuery{Role: "admin"})
+			}
+		}()
+	}
+	
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			
+			for i := 0; i < usersPerWorker; i++ {
+				created, err := repo.Create(User{
+					Name:  fmt.Sprintf("Worker %d User %d", w, i),
+					Email: fmt.Sprintf("w%du%d@example.com", w, i),
+					Age:   30,
+					Role:  "user",
+				})
+				if err != nil {
+					t.Errorf("Create failed: %v", err)
+					return
+				}
+				
+				if _, err := repo.GetByID(created.ID); err != nil {
+					t.Errorf("GetByID(%d) failed: %v", created.ID, err)
+				}
+				
+				// Even users are promoted, odd users are deleted
+				if i%2 == 0 {
+					updated := *created
+					updated.Role = "admin"
+					if _, err := repo.Update(created.ID, updated); err != nil {
+						t.Errorf("Update(%d) failed: %v", created.ID, err)
+					}
+				} else if err := repo.Delete(created.ID); err != nil {
+					t.Errorf("Delete(%d) failed: %v", created.ID, err)
+				}
+			}
+		}(w)
+	}
+	
+	wg.Wait()
+	
+	remaining := repo.GetAll()
+	assert.Len(t, remaining, numWorkers*usersPerWorker/2)
+	
+	ids := make(map[int]bool)
+	for _, user := range remaining {
+		assert.Equal(t, "admin", user.Role, "user %d should have been promoted", user.ID)
+		assert.False(t, ids[user.ID], "Duplicate ID found: %d", user.ID)
+		ids[user.ID] = true
+	}
+	
+	assert.Len(t, repo.Search(SearchQuery{Role: "admin"}), numWorkers*usersPerWorker/2)
+}
+
 func testReadMultipleUsersParallel(t *testing.T) {
 	repo := NewUserRepository()
 	
@@ -4,6 +4,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -32,63 +33,167 @@ type SortingAlgorithms struct{}
 
 // BubbleSort implements bubble sort algorithm
 func (s *SortingAlgorithms) BubbleSort(data []int) {
-	// TODO: Implement bubble sort
-	// - Compare adjacent elements
-	// - Swap if in wrong order
-	// - Repeat until no swaps needed
+	n := len(data)
+	for i := 0; i < n-1; i++ {
+		swapped := false
+		for j := 0; j < n-i-1; j++ {
+			if data[j] > data[j+1] {
+				data[j], data[j+1] = data[j+1], data[j]
+				swapped = true
+			}
+		}
+		// No swaps means the rest is already in order
+		if !swapped {
+			return
+		}
+	}
 }
 
 // QuickSort implements quick sort algorithm
 func (s *SortingAlgorithms) QuickSort(data []int) {
-	// TODO: Implement quick sort
-	// - Choose pivot element
-	// - Partition around pivot
-	// - Recursively sort sub-arrays
+	if len(data) <= 1 {
+		return
+	}
+	s.quickSortHelper(data, 0, len(data)-1)
+}
+
+func (s *SortingAlgorithms) quickSortHelper(data []int, low, high int) {
+	if low < high {
+		pi := s.partition(data, low, high)
+		s.quickSortHelper(data, low, pi-1)
+		s.quickSortHelper(data, pi+1, high)
+	}
+}
+
+func (s *SortingAlgorithms) partition(data []int, low, high int) int {
+	// Use the middle element as pivot so sorted input doesn't degrade to O(n^2)
+	mid := low + (high-low)/2
+	data[mid], data[high] = data[high], data[mid]
+	pivot := data[high]
+	i := low - 1
+
+	for j := low; j < high; j++ {
+		if data[j] <= pivot {
+			i++
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	data[i+1], data[high] = data[high], data[i+1]
+	return i + 1
 }
 
 // MergeSort implements merge sort algorithm
 func (s *SortingAlgorithms) MergeSort(data []int) {
-	// TODO: Implement merge sort
-	// - Divide array into halves
-	// - Recursively sort halves
-	// - Merge sorted halves
+	if len(data) <= 1 {
+		return
+	}
+	buf := make([]int, len(data))
+	s.mergeSortHelper(data, buf)
+}
+
+func (s *SortingAlgorithms) mergeSortHelper(data, buf []int) {
+	if len(data) <= 1 {
+		return
+	}
+	mid := len(data) / 2
+	s.mergeSortHelper(data[:mid], buf[:mid])
+	s.mergeSortHelper(data[mid:], buf[mid:])
+
+	// Merge both halves into buf, then copy back
+	i, j, k := 0, mid, 0
+	for i < mid && j < len(data) {
+		if data[i] <= data[j] {
+			buf[k] = data[i]
+			i++
+		} else {
+			buf[k] = data[j]
+			j++
+		}
+		k++
+	}
+	k += copy(buf[k:], data[i:mid])
+	copy(buf[k:], data[j:])
+	copy(data, buf[:len(data)])
 }
 
 // HeapSort implements heap sort algorithm
 func (s *SortingAlgorithms) HeapSort(data []int) {
-	// TODO: Implement heap sort
-	// - Build max heap
-	// - Extract max elements
-	// - Maintain heap property
+	n := len(data)
+
+	// Build max heap
+	for i := n/2 - 1; i >= 0; i-- {
+		s.siftDown(data, n, i)
+	}
+
+	// Move the current max to the end and shrink the heap
+	for i := n - 1; i > 0; i-- {
+		data[0], data[i] = data[i], data[0]
+		s.siftDown(data, i, 0)
+	}
+}
+
+func (s *SortingAlgorithms) siftDown(data []int, n, i int) {
+	for {
+		largest := i
+		left := 2*i + 1
+		right := 2*i + 2
+
+		if left < n && data[left] > data[largest] {
+			largest = left
+		}
+		if right < n && data[right] > data[largest] {
+			largest = right
+		}
+		if largest == i {
+			return
+		}
+
+		data[i], data[largest] = data[largest], data[i]
+		i = largest
+	}
 }
 
 // StringProcessor handles string operations
 type StringProcessor struct{}
 
 // Concatenate concatenates strings using + operator
-func (sp *StringProcessor) Concatenate(strings []string) string {
-	// TODO: Implement string concatenation
-	// - Use + operator to join strings
-	// - Return concatenated result
-	return ""
+func (sp *StringProcessor) Concatenate(strs []string) string {
+	result := ""
+	for _, s := range strs {
+		result += s
+	}
+	return result
 }
 
 // BuilderConcatenate concatenates strings using strings.Builder
-func (sp *StringProcessor) BuilderConcatenate(strings []string) string {
-	// TODO: Implement string concatenation with Builder
-	// - Use strings.Builder for efficient concatenation
-	// - Pre-allocate capacity if possible
-	// - Return built string
-	return ""
+func (sp *StringProcessor) BuilderConcatenate(strs []string) string {
+	var builder strings.Builder
+
+	// 最初に合計長を求めて一度だけ確保する
+	totalLen := 0
+	for _, s := range strs {
+		totalLen += len(s)
+	}
+	builder.Grow(totalLen)
+
+	for _, s := range strs {
+		builder.WriteString(s)
+	}
+	return builder.String()
 }
 
 // ByteConcatenate concatenates strings using byte operations
-func (sp *StringProcessor) ByteConcatenate(strings []string) string {
-	// TODO: Implement string concatenation with bytes
-	// - Convert strings to bytes
-	// - Use byte operations for joining
-	// - Convert back to string
-	return ""
+func (sp *StringProcessor) ByteConcatenate(strs []string) string {
+	totalLen := 0
+	for _, s := range strs {
+		totalLen += len(s)
+	}
+
+	result := make([]byte, 0, totalLen)
+	for _, s := range strs {
+		result = append(result, s...)
+	}
+	return string(result)
 }
 
 // SearchAlgorithms contains search implementations
@@ -96,18 +201,30 @@ type SearchAlgorithms struct{}
 
 // LinearSearch performs linear search
 func (sa *SearchAlgorithms) LinearSearch(data []int, target int) int {
-	// TODO: Implement linear search
-	// - Iterate through array
-	// - Return index if found, -1 if not found
+	for i, v := range data {
+		if v == target {
+			return i
+		}
+	}
 	return -1
 }
 
 // BinarySearch performs binary search
 func (sa *SearchAlgorithms) BinarySearch(data []int, target int) int {
-	// TODO: Implement binary search
-	// - Assume data is sorted
-	// - Use divide and conquer approach
-	// - Return index if found, -1 if not found
+	left, right := 0, len(data)-1
+
+	for left <= right {
+		mid := left + (right-left)/2
+
+		if data[mid] == target {
+			return mid
+		} else if data[mid] < target {
+			left = mid + 1
+		} else {
+			right = mid - 1
+		}
+	}
+
 	return -1
 }
 
@@ -117,59 +234,106 @@ type ConcurrencyManager struct {
 	data  map[int]int
 	smap  sync.Map
 	pool  *WorkerPool
+
+	// Channel-based storage
+	chReqs  chan channelRequest
+	chStore map[int]int
+}
+
+type channelRequest struct {
+	op     string
+	key    int
+	value  int
+	respCh chan channelResponse
+}
+
+type channelResponse struct {
+	value  int
+	exists bool
 }
 
 // NewConcurrencyManager creates a new concurrency manager
 func NewConcurrencyManager(poolSize int) *ConcurrencyManager {
-	return &ConcurrencyManager{
-		data: make(map[int]int),
-		pool: NewWorkerPool(poolSize),
+	cm := &ConcurrencyManager{
+		data:    make(map[int]int),
+		pool:    NewWorkerPool(poolSize),
+		chReqs:  make(chan channelRequest),
+		chStore: make(map[int]int),
+	}
+
+	// chStore はこのゴルーチンだけが触る
+	go cm.channelStorageHandler()
+
+	return cm
+}
+
+func (cm *ConcurrencyManager) channelStorageHandler() {
+	for req := range cm.chReqs {
+		switch req.op {
+		case "read":
+			value, exists := cm.chStore[req.key]
+			req.respCh <- channelResponse{value: value, exists: exists}
+		case "write":
+			cm.chStore[req.key] = req.value
+			req.respCh <- channelResponse{}
+		}
 	}
 }
 
 // MutexRead reads data using mutex
 func (cm *ConcurrencyManager) MutexRead(key int) (int, bool) {
-	// TODO: Implement mutex-based read
-	// - Use RLock for reading
-	// - Return value and existence flag
-	return 0, false
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	value, exists := cm.data[key]
+	return value, exists
 }
 
 // MutexWrite writes data using mutex
 func (cm *ConcurrencyManager) MutexWrite(key, value int) {
-	// TODO: Implement mutex-based write
-	// - Use Lock for writing
-	// - Store key-value pair
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.data[key] = value
 }
 
 // SyncMapRead reads data using sync.Map
 func (cm *ConcurrencyManager) SyncMapRead(key int) (int, bool) {
-	// TODO: Implement sync.Map read
-	// - Use Load method
-	// - Return value and existence flag
+	if value, exists := cm.smap.Load(key); exists {
+		return value.(int), true
+	}
 	return 0, false
 }
 
 // SyncMapWrite writes data using sync.Map
 func (cm *ConcurrencyManager) SyncMapWrite(key, value int) {
-	// TODO: Implement sync.Map write
-	// - Use Store method
-	// - Store key-value pair
+	cm.smap.Store(key, value)
 }
 
 // ChannelRead reads data using channel
 func (cm *ConcurrencyManager) ChannelRead(key int) (int, bool) {
-	// TODO: Implement channel-based read
-	// - Use channel for communication
-	// - Return value and existence flag
-	return 0, false
+	respCh := make(chan channelResponse, 1)
+	cm.chReqs <- channelRequest{
+		op:     "read",
+		key:    key,
+		respCh: respCh,
+	}
+
+	resp := <-respCh
+	return resp.value, resp.exists
 }
 
 // ChannelWrite writes data using channel
 func (cm *ConcurrencyManager) ChannelWrite(key, value int) {
-	// TODO: Implement channel-based write
-	// - Use channel for communication
-	// - Store key-value pair
+	respCh := make(chan channelResponse, 1)
+	cm.chReqs <- channelRequest{
+		op:     "write",
+		key:    key,
+		value:  value,
+		respCh: respCh,
+	}
+
+	<-respCh
 }
 
 // WorkerPool implements worker pool pattern
@@ -193,35 +357,74 @@ type Result struct {
 	Error error
 }
 
+// ErrPoolClosed is returned in a Result when GetResult is called on a closed pool
+var ErrPoolClosed = errors.New("worker pool is closed")
+
 // NewWorkerPool creates a new worker pool
 func NewWorkerPool(workers int) *WorkerPool {
-	// TODO: Initialize worker pool
-	// - Create job and result channels
-	// - Start worker goroutines
-	// - Return pool instance
-	return nil
+	wp := &WorkerPool{
+		workers: workers,
+		jobs:    make(chan Job, 100),
+		results: make(chan Result, 100),
+	}
+	
+	// Start worker goroutines
+	for i := 0; i < workers; i++ {
+		wp.wg.Add(1)
+		go wp.worker()
+	}
+	
+	return wp
+}
+
+func (wp *WorkerPool) worker() {
+	defer wp.wg.Done()
+	
+	// jobs が閉じられ、残りのジョブを処理し終えたら終了する
+	for job := range wp.jobs {
+		wp.results <- wp.processJob(job)
+	}
+}
+
+func (wp *WorkerPool) processJob(job Job) Result {
+	// Simulate some work
+	switch data := job.Data.(type) {
+	case []int:
+		return Result{
+			JobID: job.ID,
+			Value: ProcessData(data),
+			Error: nil,
+		}
+	default:
+		return Result{
+			JobID: job.ID,
+			Value: nil,
+			Error: fmt.Errorf("unsupported data type"),
+		}
+	}
 }
 
 // Submit submits a job to the pool
 func (wp *WorkerPool) Submit(job Job) {
-	// TODO: Submit job to worker pool
-	// - Send job to jobs channel
+	wp.jobs <- job
 }
 
-// GetResult gets result from the pool
+// GetResult blocks until a result is available.
+// Once the pool is closed and all results are consumed, it returns ErrPoolClosed.
 func (wp *WorkerPool) GetResult() Result {
-	// TODO: Get result from worker pool
-	// - Receive from results channel
-	// - Return result
-	return Result{}
+	result, ok := <-wp.results
+	if !ok {
+		return Result{Error: ErrPoolClosed}
+	}
+	return result
 }
 
-// Close closes the worker pool
+// Close stops accepting jobs, waits for the workers to finish and closes results.
+// Results that are not consumed must fit in the results buffer, otherwise Close blocks.
 func (wp *WorkerPool) Close() {
-	// TODO: Close worker pool
-	// - Close job channel
-	// - Wait for workers to finish
-	// - Close result channel
+	close(wp.jobs)
+	wp.wg.Wait()
+	close(wp.results)
 }
 
 // MemoryOptimizer handles memory optimization techniques
@@ -301,20 +504,35 @@ func (so *SliceOptimizer) DynamicAppend(size int) []int {
 type InterfaceProcessor struct{}
 
 // ProcessInterface processes data using interface
+// Numeric values are doubled and returned boxed in their original type.
 func (ip *InterfaceProcessor) ProcessInterface(data interface{}) interface{} {
-	// TODO: Process data using interface
-	// - Type assert or type switch
-	// - Process based on type
-	// - Return result
-	return nil
+	switch v := data.(type) {
+	case int:
+		return v * 2
+	case int32:
+		return v * 2
+	case int64:
+		return v * 2
+	case uint:
+		return v * 2
+	case float64:
+		return v * 2
+	case string:
+		return v + "_processed"
+	case []int:
+		result := make([]int, len(v))
+		for i, val := range v {
+			result[i] = val * 2
+		}
+		return result
+	default:
+		return data
+	}
 }
 
 // ProcessConcrete processes data using concrete type
 func (ip *InterfaceProcessor) ProcessConcrete(data int) int {
-	// TODO: Process data using concrete type
-	// - Direct processing without type assertion
-	// - Return result
-	return 0
+	return data * 2
 }
 
 // FileProcessor handles file I/O operations
@@ -382,11 +600,12 @@ func (hp *HTTPProcessor) ServeStatic(w http.ResponseWriter, r *http.Request) {
 
 // GenerateRandomData generates random integer slice
 func GenerateRandomData(size int) []int {
-	// TODO: Generate random data
-	// - Create slice of given size
-	// - Fill with random integers
-	// - Return slice
-	return nil
+	rand.Seed(time.Now().UnixNano())
+	data := make([]int, size)
+	for i := 0; i < size; i++ {
+		data[i] = rand.Intn(1000)
+	}
+	return data
 }
 
 // GenerateRandomStrings generates random string slice
@@ -409,10 +628,12 @@ func GenerateRandomUsers(count int) []User {
 
 // IsSorted checks if slice is sorted
 func IsSorted(data []int) bool {
-	// TODO: Check if data is sorted
-	// - Compare adjacent elements
-	// - Return true if sorted, false otherwise
-	return false
+	for i := 1; i < len(data); i++ {
+		if data[i] < data[i-1] {
+			return false
+		}
+	}
+	return true
 }
 
 // ProcessData performs some CPU-intensive operation
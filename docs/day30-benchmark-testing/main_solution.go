@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -80,7 +81,7 @@ func (s *SortingAlgorithms) MergeSort(data []int) {
 
 func (s *SortingAlgorithms) mergeSortHelper(data []int, left, right int) {
 	if left < right {
-		mid := (left + right) / 2
+		mid := left + (right-left)/2
 		s.mergeSortHelper(data, left, mid)
 		s.mergeSortHelper(data, mid+1, right)
 		s.merge(data, left, mid, right)
@@ -196,7 +197,7 @@ func (sp *StringProcessor) ByteConcatenate(strs []string) string {
 	result := make([]byte, 0, totalLen)
 	
 	for _, s := range strs {
-		result = append(result, []byte(s)...)
+		result = append(result, s...)
 	}
 	
 	return string(result)
@@ -348,7 +349,6 @@ type WorkerPool struct {
 	jobs      chan Job
 	results   chan Result
 	wg        sync.WaitGroup
-	done      chan bool
 }
 
 // Job represents a work unit
@@ -364,13 +364,15 @@ type Result struct {
 	Error error
 }
 
+// ErrPoolClosed is returned in a Result when GetResult is called on a closed pool
+var ErrPoolClosed = errors.New("worker pool is closed")
+
 // NewWorkerPool creates a new worker pool
 func NewWorkerPool(workers int) *WorkerPool {
 	wp := &WorkerPool{
 		workers: workers,
 		jobs:    make(chan Job, 100),
 		results: make(chan Result, 100),
-		done:    make(chan bool),
 	}
 	
 	// Start worker goroutines
@@ -385,20 +387,9 @@ func NewWorkerPool(workers int) *WorkerPool {
 func (wp *WorkerPool) worker() {
 	defer wp.wg.Done()
 	
-	for {
-		select {
-		case job, ok := <-wp.jobs:
-			if !ok {
-				return
-			}
-			
-			// Process job
-			result := wp.processJob(job)
-			wp.results <- result
-			
-		case <-wp.done:
-			return
-		}
+	// jobs が閉じられ、残りのジョブを処理し終えたら終了する
+	for job := range wp.jobs {
+		wp.results <- wp.processJob(job)
 	}
 }
 
@@ -425,23 +416,22 @@ func (wp *WorkerPool) Submit(job Job) {
 	wp.jobs <- job
 }
 
-// GetResult gets result from the pool
+// GetResult blocks until a result is available.
+// Once the pool is closed and all results are consumed, it returns ErrPoolClosed.
 func (wp *WorkerPool) GetResult() Result {
-	return <-wp.results
+	result, ok := <-wp.results
+	if !ok {
+		return Result{Error: ErrPoolClosed}
+	}
+	return result
 }
 
-// Close closes the worker pool
+// Close stops accepting jobs, waits for the workers to finish and closes results.
+// Results that are not consumed must fit in the results buffer, otherwise Close blocks.
 func (wp *WorkerPool) Close() {
 	close(wp.jobs)
-	
-	// Signal workers to stop
-	for i := 0; i < wp.workers; i++ {
-		wp.done <- true
-	}
-	
 	wp.wg.Wait()
 	close(wp.results)
-	close(wp.done)
 }
 
 // MemoryOptimizer handles memory optimization techniques
@@ -534,10 +524,19 @@ func (so *SliceOptimizer) DynamicAppend(size int) []int {
 type InterfaceProcessor struct{}
 
 // ProcessInterface processes data using interface
+// Numeric values are doubled and returned boxed in their original type.
 func (ip *InterfaceProcessor) ProcessInterface(data interface{}) interface{} {
 	switch v := data.(type) {
 	case int:
 		return v * 2
+	case int32:
+		return v * 2
+	case int64:
+		return v * 2
+	case uint:
+		return v * 2
+	case float64:
+		return v * 2
 	case string:
 		return v + "_processed"
 	case []int:
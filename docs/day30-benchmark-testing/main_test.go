@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"math/rand"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 )
@@ -87,6 +89,43 @@ func BenchmarkByteConcatenation(b *testing.B) {
 	}
 }
 
+// concatInputs builds count strings of the given length without relying on GenerateRandomStrings
+func concatInputs(count, length int) []string {
+	strs := make([]string, count)
+	for i := range strs {
+		b := make([]byte, length)
+		for j := range b {
+			b[j] = byte('a' + (i+j)%26)
+		}
+		strs[i] = string(b)
+	}
+	return strs
+}
+
+// Compare all concatenation strategies on 1k strings
+func BenchmarkConcatenation1K(b *testing.B) {
+	processor := &StringProcessor{}
+	strs := concatInputs(1000, 16)
+
+	strategies := []struct {
+		name string
+		fn   func([]string) string
+	}{
+		{"Plus", processor.Concatenate},
+		{"Builder", processor.BuilderConcatenate},
+		{"Bytes", processor.ByteConcatenate},
+	}
+
+	for _, s := range strategies {
+		b.Run(s.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.fn(strs)
+			}
+		})
+	}
+}
+
 // Benchmark search algorithms
 func BenchmarkLinearSearch(b *testing.B) {
 	search := &SearchAlgorithms{}
@@ -113,6 +152,33 @@ func BenchmarkBinarySearch(b *testing.B) {
 	}
 }
 
+// Benchmark linear vs binary search across data sizes
+func BenchmarkSearchDataSize(b *testing.B) {
+	sizes := []int{100, 1000, 10000, 100000}
+	search := &SearchAlgorithms{}
+
+	for _, size := range sizes {
+		data := GenerateRandomData(size)
+		sorted := make([]int, len(data))
+		copy(sorted, data)
+		sort.Ints(sorted)
+		// Search for the last element so linear search scans the whole slice
+		target := sorted[len(sorted)-1]
+
+		b.Run(fmt.Sprintf("Linear_%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				search.LinearSearch(sorted, target)
+			}
+		})
+
+		b.Run(fmt.Sprintf("Binary_%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				search.BinarySearch(sorted, target)
+			}
+		})
+	}
+}
+
 // Benchmark concurrency patterns
 func BenchmarkMutexRead(b *testing.B) {
 	cm := NewConcurrencyManager(10)
@@ -250,6 +316,29 @@ func BenchmarkConcreteProcessing(b *testing.B) {
 	}
 }
 
+// Benchmark per-call dispatch overhead: type switch on a boxed value vs direct int call
+func BenchmarkDispatch(b *testing.B) {
+	ip := &InterfaceProcessor{}
+	
+	b.Run("Interface", func(b *testing.B) {
+		b.ReportAllocs()
+		var sink interface{}
+		for i := 0; i < b.N; i++ {
+			sink = ip.ProcessInterface(i)
+		}
+		_ = sink
+	})
+	
+	b.Run("Concrete", func(b *testing.B) {
+		b.ReportAllocs()
+		var sink int
+		for i := 0; i < b.N; i++ {
+			sink = ip.ProcessConcrete(i)
+		}
+		_ = sink
+	})
+}
+
 // Benchmark JSON operations
 func BenchmarkJSONEncode(b *testing.B) {
 	b.ReportAllocs()
@@ -397,6 +486,137 @@ func TestSortingCorrectness(t *testing.T) {
 	}
 }
 
+func TestSortingEdgeCases(t *testing.T) {
+	sorter := &SortingAlgorithms{}
+	
+	algorithms := []struct {
+		name string
+		sort func([]int)
+	}{
+		{"BubbleSort", sorter.BubbleSort},
+		{"QuickSort", sorter.QuickSort},
+		{"MergeSort", sorter.MergeSort},
+		{"HeapSort", sorter.HeapSort},
+	}
+	
+	r := rand.New(rand.NewSource(42))
+	random := make([]int, 200)
+	for i := range random {
+		random[i] = r.Intn(50) // small range to force duplicates
+	}
+	sorted := make([]int, 200)
+	reversed := make([]int, 200)
+	equal := make([]int, 200)
+	for i := range sorted {
+		sorted[i] = i
+		reversed[i] = len(reversed) - i
+		equal[i] = 7
+	}
+	
+	inputs := []struct {
+		name string
+		data []int
+	}{
+		{"empty", []int{}},
+		{"single", []int{1}},
+		{"two", []int{2, 1}},
+		{"random with duplicates", random},
+		{"already sorted", sorted},
+		{"reverse sorted", reversed},
+		{"all equal", equal},
+	}
+	
+	for _, algo := range algorithms {
+		for _, input := range inputs {
+			t.Run(algo.name+"/"+input.name, func(t *testing.T) {
+				data := make([]int, len(input.data))
+				copy(data, input.data)
+				
+				algo.sort(data)
+				
+				if !IsSorted(data) {
+					t.Errorf("Data is not sorted: %v", data)
+				}
+				
+				// Sorting must preserve the multiset of elements
+				counts := make(map[int]int)
+				for _, v := range input.data {
+					counts[v]++
+				}
+				for _, v := range data {
+					counts[v]--
+				}
+				for v, c := range counts {
+					if c != 0 {
+						t.Errorf("Element %d count changed by %d", v, -c)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestIsSorted(t *testing.T) {
+	tests := []struct {
+		name string
+		data []int
+		want bool
+	}{
+		{"empty", []int{}, true},
+		{"single", []int{5}, true},
+		{"ascending with duplicates", []int{1, 2, 2, 3}, true},
+		{"descending", []int{3, 2, 1}, false},
+		{"unsorted tail", []int{1, 2, 4, 3}, false},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSorted(tt.data); got != tt.want {
+				t.Errorf("IsSorted(%v) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringConcatenationEquivalence(t *testing.T) {
+	processor := &StringProcessor{}
+
+	tests := []struct {
+		name string
+		strs []string
+		want string
+	}{
+		{"nil", nil, ""},
+		{"empty strings", []string{"", "", ""}, ""},
+		{"single", []string{"go"}, "go"},
+		{"multiple", []string{"go", "-", "dojo"}, "go-dojo"},
+		{"unicode", []string{"こんにちは", "、", "世界"}, "こんにちは、世界"},
+		{"1k strings", concatInputs(1000, 16), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plus := processor.Concatenate(tt.strs)
+			builder := processor.BuilderConcatenate(tt.strs)
+			bytes := processor.ByteConcatenate(tt.strs)
+
+			if tt.want != "" && plus != tt.want {
+				t.Errorf("Concatenate = %q, want %q", plus, tt.want)
+			}
+			if builder != plus {
+				t.Errorf("BuilderConcatenate differs from Concatenate: %q vs %q", builder, plus)
+			}
+			if bytes != plus {
+				t.Errorf("ByteConcatenate differs from Concatenate: %q vs %q", bytes, plus)
+			}
+		})
+	}
+
+	if got := processor.Concatenate(concatInputs(1000, 16)); len(got) != 16000 {
+		t.Errorf("expected 16000 bytes for 1k strings, got %d", len(got))
+	}
+}
+
 func TestSearchCorrectness(t *testing.T) {
 	search := &SearchAlgorithms{}
 	data := []int{1, 3, 5, 7, 9, 11, 13, 15, 17, 19}
@@ -414,6 +634,60 @@ func TestSearchCorrectness(t *testing.T) {
 	}
 }
 
+func TestSearchAlgorithms(t *testing.T) {
+	search := &SearchAlgorithms{}
+	algorithms := []struct {
+		name string
+		fn   func([]int, int) int
+	}{
+		{"LinearSearch", search.LinearSearch},
+		{"BinarySearch", search.BinarySearch},
+	}
+	tests := []struct {
+		name   string
+		data   []int
+		target int
+		want   int
+	}{
+		{"found in middle", []int{1, 3, 5, 7, 9}, 5, 2},
+		{"first element", []int{1, 3, 5, 7, 9}, 1, 0},
+		{"last element", []int{1, 3, 5, 7, 9}, 9, 4},
+		{"not found between elements", []int{1, 3, 5, 7, 9}, 4, -1},
+		{"not found below range", []int{1, 3, 5, 7, 9}, 0, -1},
+		{"not found above range", []int{1, 3, 5, 7, 9}, 10, -1},
+		{"single element found", []int{42}, 42, 0},
+		{"single element not found", []int{42}, 7, -1},
+		{"empty slice", []int{}, 1, -1},
+		{"nil slice", nil, 1, -1},
+	}
+
+	for _, alg := range algorithms {
+		for _, tt := range tests {
+			t.Run(alg.name+"/"+tt.name, func(t *testing.T) {
+				if got := alg.fn(tt.data, tt.target); got != tt.want {
+					t.Errorf("%s(%v, %d) = %d, want %d", alg.name, tt.data, tt.target, got, tt.want)
+				}
+			})
+		}
+	}
+
+	t.Run("agree on random sorted data", func(t *testing.T) {
+		data := GenerateRandomData(1000)
+		sort.Ints(data)
+		for target := -1; target <= 1000; target++ {
+			linear := search.LinearSearch(data, target)
+			binary := search.BinarySearch(data, target)
+			if (linear == -1) != (binary == -1) {
+				t.Fatalf("target %d: linear=%d binary=%d", target, linear, binary)
+			}
+			// Data may contain duplicates, so compare values rather than indices
+			if binary != -1 && data[binary] != target {
+				t.Fatalf("target %d: binary search returned index %d holding %d", target, binary, data[binary])
+			}
+		}
+	})
+}
+
 func TestConcurrencyCorrectness(t *testing.T) {
 	cm := NewConcurrencyManager(10)
 	defer cm.pool.Close()
@@ -432,8 +706,66 @@ func TestConcurrencyCorrectness(t *testing.T) {
 		t.Error("Sync.Map operations failed")
 	}
 	
-	// Skip channel operations test as it causes deadlock
-	// TODO: Fix channel implementation
+	// Test channel operations
+	cm.ChannelWrite(3, 300)
+	value, exists = cm.ChannelRead(3)
+	if !exists || value != 300 {
+		t.Error("Channel operations failed")
+	}
+}
+
+// Run with go test -race to check the strategies for data races
+func TestConcurrencyStrategiesRace(t *testing.T) {
+	cm := NewConcurrencyManager(4)
+
+	strategies := []struct {
+		name  string
+		read  func(int) (int, bool)
+		write func(int, int)
+	}{
+		{"Mutex", cm.MutexRead, cm.MutexWrite},
+		{"SyncMap", cm.SyncMapRead, cm.SyncMapWrite},
+		{"Channel", cm.ChannelRead, cm.ChannelWrite},
+	}
+
+	const (
+		goroutines = 32
+		keysPerG   = 50
+		sharedKey  = -1
+	)
+
+	for _, s := range strategies {
+		t.Run(s.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < keysPerG; i++ {
+						key := g*keysPerG + i
+						s.write(key, key*10)
+						// 全ゴルーチンが同じキーを奪い合う
+						s.write(sharedKey, g)
+						s.read(sharedKey)
+						s.read((key + keysPerG) % (goroutines * keysPerG))
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			for key := 0; key < goroutines*keysPerG; key++ {
+				value, exists := s.read(key)
+				if !exists || value != key*10 {
+					t.Fatalf("key %d: expected %d, got %d (exists=%v)", key, key*10, value, exists)
+				}
+			}
+
+			value, exists := s.read(sharedKey)
+			if !exists || value < 0 || value >= goroutines {
+				t.Errorf("shared key holds unexpected value %d (exists=%v)", value, exists)
+			}
+		})
+	}
 }
 
 func TestMemoryOptimization(t *testing.T) {
@@ -486,6 +818,37 @@ func TestInterfaceProcessing(t *testing.T) {
 	}
 }
 
+func TestDispatchEquivalence(t *testing.T) {
+	ip := &InterfaceProcessor{}
+	
+	inputs := []interface{}{int(21), int32(21), int64(21), uint(21), float64(21)}
+	want := ip.ProcessConcrete(21)
+	
+	for _, input := range inputs {
+		t.Run(fmt.Sprintf("%T", input), func(t *testing.T) {
+			var got int
+			switch v := ip.ProcessInterface(input).(type) {
+			case int:
+				got = v
+			case int32:
+				got = int(v)
+			case int64:
+				got = int(v)
+			case uint:
+				got = int(v)
+			case float64:
+				got = int(v)
+			default:
+				t.Fatalf("Expected %T result, got %T", input, v)
+			}
+			
+			if got != want {
+				t.Errorf("Expected %d, got %d", want, got)
+			}
+		})
+	}
+}
+
 func TestJSONOperations(t *testing.T) {
 	jp := &JSONProcessor{}
 	user := User{ID: 1, Name: "Test", Email: "test@example.com"}
@@ -530,6 +893,72 @@ func TestWorkerPool(t *testing.T) {
 	}
 }
 
+func TestWorkerPoolAllResults(t *testing.T) {
+	const jobCount = 100
+	wp := NewWorkerPool(8)
+
+	inputs := make(map[int][]int, jobCount)
+	for i := 0; i < jobCount; i++ {
+		inputs[i] = []int{i, i + 1, i + 2}
+	}
+
+	// 結果を受け取りながら投入するので、バッファサイズに関係なく詰まらない
+	go func() {
+		for i := 0; i < jobCount; i++ {
+			wp.Submit(Job{ID: i, Data: inputs[i]})
+		}
+	}()
+
+	done := make(chan map[int]Result)
+	go func() {
+		results := make(map[int]Result, jobCount)
+		for i := 0; i < jobCount; i++ {
+			result := wp.GetResult()
+			results[result.JobID] = result
+		}
+		done <- results
+	}()
+
+	var results map[int]Result
+	select {
+	case results = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for worker pool results (deadlock?)")
+	}
+
+	if len(results) != jobCount {
+		t.Fatalf("expected %d distinct results, got %d", jobCount, len(results))
+	}
+	for id, data := range inputs {
+		result, ok := results[id]
+		if !ok {
+			t.Errorf("missing result for job %d", id)
+			continue
+		}
+		if result.Error != nil {
+			t.Errorf("job %d failed: %v", id, result.Error)
+		}
+		if result.Value != ProcessData(data) {
+			t.Errorf("job %d: expected %v, got %v", id, ProcessData(data), result.Value)
+		}
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		wp.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+
+	if result := wp.GetResult(); result.Error != ErrPoolClosed {
+		t.Errorf("expected ErrPoolClosed after Close, got %v", result.Error)
+	}
+}
+
 func TestUtilityFunctions(t *testing.T) {
 	// Test random data generation
 	data := GenerateRandomData(100)
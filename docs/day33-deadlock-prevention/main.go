@@ -5,16 +5,21 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// ErrInsufficientBalance is returned when a transfer would overdraw an account
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
 // Account represents a bank account
 type Account struct {
 	ID      int
@@ -53,21 +58,101 @@ type DeadlockPreventer struct {
 
 // NewDeadlockPreventer creates a new deadlock preventer
 func NewDeadlockPreventer(db *sql.DB) *DeadlockPreventer {
-	// TODO: DeadlockPreventerを初期化
-	panic("Not yet implemented")
+	return &DeadlockPreventer{
+		db: db,
+	}
 }
 
 // TransferMoneyOrdered transfers money using ordered resource locking
 func (dp *DeadlockPreventer) TransferMoneyOrdered(fromID, toID int, amount float64) error {
-	// TODO: 常に小さいIDから大きいIDの順番でリソースをロックして送金を実行
-	// デッドロックを予防する
-	panic("Not yet implemented")
+	if fromID == toID {
+		return fmt.Errorf("cannot transfer to the same account %d", fromID)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("transfer amount must be positive, got %.2f", amount)
+	}
+
+	tx, err := dp.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 送金の向きに関係なく、常に小さいIDから大きいIDの順で行ロックを取得する
+	// これにより A→B と B→A が同時に走っても循環待ちが起きない
+	accounts := make(map[int]*Account, 2)
+	for _, accountID := range orderAccountIDs([]int{fromID, toID}) {
+		account, err := getAccountForUpdate(tx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to lock account %d: %w", accountID, err)
+		}
+		accounts[accountID] = account
+	}
+
+	// 残高チェック（不足時は defer の Rollback で何も反映されない）
+	if accounts[fromID].Balance < amount {
+		return fmt.Errorf("account %d: %w", fromID, ErrInsufficientBalance)
+	}
+
+	if err := adjustAccountBalance(tx, fromID, -amount); err != nil {
+		return err
+	}
+	if err := adjustAccountBalance(tx, toID, amount); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // TransferMultipleOrdered transfers money between multiple accounts with ordered locking
 func (dp *DeadlockPreventer) TransferMultipleOrdered(transfers []Transfer) error {
-	// TODO: 複数の送金を順序付きでアトミックに実行
-	panic("Not yet implemented")
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	// 関係するすべてのアカウントIDを収集し、残高変更を計算
+	balanceChanges := make(map[int]float64)
+	for _, transfer := range transfers {
+		if transfer.Amount <= 0 {
+			return fmt.Errorf("transfer amount must be positive, got %.2f", transfer.Amount)
+		}
+		balanceChanges[transfer.FromID] -= transfer.Amount
+		balanceChanges[transfer.ToID] += transfer.Amount
+	}
+
+	accountIDs := make([]int, 0, len(balanceChanges))
+	for id := range balanceChanges {
+		accountIDs = append(accountIDs, id)
+	}
+	accountIDs = orderAccountIDs(accountIDs)
+
+	tx, err := dp.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 順序付きでアカウントをロック（FOR UPDATE）し、残高をチェック
+	for _, accountID := range accountIDs {
+		account, err := getAccountForUpdate(tx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to lock account %d: %w", accountID, err)
+		}
+		if account.Balance+balanceChanges[accountID] < 0 {
+			return fmt.Errorf("account %d: %w", accountID, ErrInsufficientBalance)
+		}
+	}
+
+	// 実際の残高更新もロックと同じ順序で行う
+	for _, accountID := range accountIDs {
+		if change := balanceChanges[accountID]; change != 0 {
+			if err := adjustAccountBalance(tx, accountID, change); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
 }
 
 // Transfer represents a money transfer operation
@@ -86,52 +171,137 @@ type DeadlockDetector struct {
 
 // NewDeadlockDetector creates a new deadlock detector
 func NewDeadlockDetector(db *sql.DB, maxRetries int, monitor *DeadlockMonitor) *DeadlockDetector {
-	// TODO: DeadlockDetectorを初期化
-	panic("Not yet implemented")
+	if monitor == nil {
+		monitor = NewDeadlockMonitor()
+	}
+	return &DeadlockDetector{
+		db:         db,
+		maxRetries: maxRetries,
+		monitor:    monitor,
+	}
 }
 
+// deadlockRetryBaseDelay is the base delay for exponential backoff between retries
+const deadlockRetryBaseDelay = 50 * time.Millisecond
+
 // ExecuteWithRetry executes operation with deadlock detection and retry
 func (dd *DeadlockDetector) ExecuteWithRetry(operation func(*sql.Tx) error) error {
-	// TODO: デッドロックが発生した場合に指数バックオフでリトライを行う
-	// デッドロック発生時は統計を更新する
-	panic("Not yet implemented")
+	return dd.ExecuteWithTimeout(context.Background(), operation)
 }
 
 // ExecuteWithTimeout executes operation with both retry and timeout
 func (dd *DeadlockDetector) ExecuteWithTimeout(ctx context.Context, operation func(*sql.Tx) error) error {
-	// TODO: タイムアウト付きでデッドロック検出・リトライを実行
-	panic("Not yet implemented")
+	var lastErr error
+
+	for attempt := 0; attempt <= dd.maxRetries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := dd.runInTx(ctx, operation)
+
+		// リトライの結果を記録（初回の試行はリトライに含めない）
+		if attempt > 0 {
+			dd.monitor.RecordRetry(err == nil)
+		}
+		if err == nil {
+			return nil
+		}
+		if !isDeadlockError(err) {
+			return err
+		}
+
+		dd.monitor.RecordDeadlock()
+		lastErr = err
+
+		if attempt < dd.maxRetries {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(calculateBackoffDelay(attempt, deadlockRetryBaseDelay)):
+			}
+		}
+	}
+
+	return fmt.Errorf("operation failed after %d retries: %w", dd.maxRetries, lastErr)
+}
+
+// runInTx runs operation in a new transaction and commits it.
+// デッドロックはCOMMIT時に検出されることもあるため、COMMITのエラーもそのまま返す
+func (dd *DeadlockDetector) runInTx(ctx context.Context, operation func(*sql.Tx) error) error {
+	tx, err := dd.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := operation(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // ResourceLockManager manages ordered resource locking
 type ResourceLockManager struct {
-	mu           sync.Mutex
+	mu              sync.Mutex
+	released        *sync.Cond // ロック解放を待つゴルーチンを起こす
 	lockedResources map[string]bool
 }
 
 // NewResourceLockManager creates a new resource lock manager
 func NewResourceLockManager() *ResourceLockManager {
-	// TODO: ResourceLockManagerを初期化
-	panic("Not yet implemented")
+	rlm := &ResourceLockManager{
+		lockedResources: make(map[string]bool),
+	}
+	rlm.released = sync.NewCond(&rlm.mu)
+	return rlm
 }
 
-// LockResources locks multiple resources in a consistent order
+// LockResources locks multiple resources in a consistent order.
+// 他のゴルーチンが保持しているリソースは解放されるまで待つ。
+// 全員が同じ順序で取得するため、保持しながら待っても循環待ちにならない
 func (rlm *ResourceLockManager) LockResources(resourceIDs []string) error {
-	// TODO: リソースIDを順序付けして順番にロックを取得
-	// デッドロックを予防する
-	panic("Not yet implemented")
+	orderedIDs := orderResourceIDs(resourceIDs)
+	for _, id := range orderedIDs {
+		if id == "" {
+			return fmt.Errorf("resource ID must not be empty")
+		}
+	}
+
+	rlm.mu.Lock()
+	defer rlm.mu.Unlock()
+
+	for _, id := range orderedIDs {
+		for rlm.lockedResources[id] {
+			rlm.released.Wait()
+		}
+		rlm.lockedResources[id] = true
+	}
+
+	return nil
 }
 
 // UnlockResources unlocks multiple resources
 func (rlm *ResourceLockManager) UnlockResources(resourceIDs []string) {
-	// TODO: ロックしたリソースを解放
-	panic("Not yet implemented")
+	rlm.mu.Lock()
+	defer rlm.mu.Unlock()
+
+	for _, id := range resourceIDs {
+		delete(rlm.lockedResources, id)
+	}
+	rlm.released.Broadcast()
 }
 
-// WithOrderedLocks executes function with ordered resource locks
+// WithOrderedLocks executes function with ordered resource locks.
+// fn が panic してもロックは解放される
 func (rlm *ResourceLockManager) WithOrderedLocks(resourceIDs []string, fn func() error) error {
-	// TODO: 順序付きロックを取得してからfnを実行し、最後にロックを解放
-	panic("Not yet implemented")
+	err := rlm.LockResources(resourceIDs)
+	if err != nil {
+		return err
+	}
+	defer rlm.UnlockResources(resourceIDs)
+
+	return fn()
 }
 
 // DeadlockMonitor monitors deadlock occurrences and statistics
@@ -145,32 +315,57 @@ type DeadlockMonitor struct {
 
 // NewDeadlockMonitor creates a new deadlock monitor
 func NewDeadlockMonitor() *DeadlockMonitor {
-	// TODO: DeadlockMonitorを初期化
-	panic("Not yet implemented")
+	return &DeadlockMonitor{}
 }
 
 // RecordDeadlock records a deadlock occurrence
 func (dm *DeadlockMonitor) RecordDeadlock() {
-	// TODO: デッドロック発生を記録
-	panic("Not yet implemented")
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	dm.deadlockCount++
+	dm.lastDeadlock = time.Now()
 }
 
 // RecordRetry records a retry attempt
 func (dm *DeadlockMonitor) RecordRetry(success bool) {
-	// TODO: リトライ試行を記録（成功/失敗）
-	panic("Not yet implemented")
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	dm.totalRetries++
+	if success {
+		dm.successfulRetries++
+	}
 }
 
 // GetStatistics returns current deadlock statistics
 func (dm *DeadlockMonitor) GetStatistics() DeadlockStats {
-	// TODO: 現在の統計情報を安全に取得
-	panic("Not yet implemented")
+	dm.mutex.RLock()
+	defer dm.mutex.RUnlock()
+
+	var successRate float64
+	if dm.totalRetries > 0 {
+		successRate = float64(dm.successfulRetries) / float64(dm.totalRetries)
+	}
+
+	return DeadlockStats{
+		DeadlockCount:     dm.deadlockCount,
+		LastDeadlock:      dm.lastDeadlock,
+		TotalRetries:      dm.totalRetries,
+		SuccessfulRetries: dm.successfulRetries,
+		RetrySuccessRate:  successRate,
+	}
 }
 
 // ResetStatistics resets all statistics
 func (dm *DeadlockMonitor) ResetStatistics() {
-	// TODO: 統計情報をリセット
-	panic("Not yet implemented")
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	dm.deadlockCount = 0
+	dm.lastDeadlock = time.Time{}
+	dm.totalRetries = 0
+	dm.successfulRetries = 0
 }
 
 // DeadlockStats holds deadlock statistics
@@ -184,31 +379,63 @@ type DeadlockStats struct {
 
 // Utility functions
 
+// pgDeadlockDetected is the PostgreSQL SQLSTATE for deadlock_detected
+const pgDeadlockDetected = "40P01"
+
 // isDeadlockError checks if error is a deadlock error
 func isDeadlockError(err error) bool {
-	// TODO: エラーがデッドロックエラーかどうかを判定
-	// PostgreSQLの40P01エラーコードや"deadlock"メッセージをチェック
-	panic("Not yet implemented")
+	if err == nil {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == pgDeadlockDetected
+	}
+
+	errStr := strings.ToLower(err.Error())
+	return strings.Contains(errStr, "deadlock") ||
+		strings.Contains(errStr, strings.ToLower(pgDeadlockDetected))
 }
 
 // calculateBackoffDelay calculates exponential backoff delay
 func calculateBackoffDelay(attempt int, baseDelay time.Duration) time.Duration {
-	// TODO: 指数バックオフで遅延時間を計算
-	// attempt回目のリトライに対する適切な遅延時間を返す
-	panic("Not yet implemented")
+	// 指数バックオフ: baseDelay * 2^attempt
+	delay := time.Duration(math.Pow(2, float64(attempt))) * baseDelay
+
+	// 最大遅延時間を制限（5秒）
+	maxDelay := 5 * time.Second
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	// ジッターを追加（±25%）。同時にデッドロックしたトランザクションのリトライをずらす
+	jitter := time.Duration((rand.Float64()*0.5 - 0.25) * float64(delay))
+	return delay + jitter
 }
 
-// orderResourceIDs sorts resource IDs consistently
+// orderResourceIDs sorts resource IDs consistently and drops duplicates
+// (同じIDを2回ロックしようとすると自分自身を待ってしまうため)
 func orderResourceIDs(ids []string) []string {
-	// TODO: リソースIDを一貫した順序でソート
-	// デッドロック予防のため
-	panic("Not yet implemented")
+	result := make([]string, len(ids))
+	copy(result, ids)
+	sort.Strings(result)
+
+	unique := result[:0]
+	for _, id := range result {
+		if len(unique) == 0 || unique[len(unique)-1] != id {
+			unique = append(unique, id)
+		}
+	}
+	return unique
 }
 
 // orderAccountIDs sorts account IDs consistently
 func orderAccountIDs(ids []int) []int {
-	// TODO: アカウントIDを一貫した順序でソート
-	panic("Not yet implemented")
+	result := make([]int, len(ids))
+	copy(result, ids)
+	sort.Ints(result)
+	return result
 }
 
 // Database helper functions
@@ -228,8 +455,13 @@ func transferMoneyInTx(tx *sql.Tx, fromID, toID int, amount float64) error {
 
 // getAccountForUpdate gets account with FOR UPDATE lock
 func getAccountForUpdate(tx *sql.Tx, accountID int) (*Account, error) {
-	// TODO: FOR UPDATEロックでアカウント情報を取得
-	panic("Not yet implemented")
+	var account Account
+	err := tx.QueryRow("SELECT id, balance, version FROM accounts WHERE id = $1 FOR UPDATE", accountID).
+		Scan(&account.ID, &account.Balance, &account.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
 }
 
 // updateAccountBalance updates account balance
@@ -238,6 +470,12 @@ func updateAccountBalance(tx *sql.Tx, accountID int, newBalance float64) error {
 	panic("Not yet implemented")
 }
 
+// adjustAccountBalance adds delta to account balance
+func adjustAccountBalance(tx *sql.Tx, accountID int, delta float64) error {
+	_, err := tx.Exec("UPDATE accounts SET balance = balance + $1 WHERE id = $2", delta, accountID)
+	return err
+}
+
 // Database initialization functions
 
 // InitializeDeadlockTestDB creates tables for deadlock testing
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -11,9 +12,12 @@ import (
 	"sync"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// ErrInsufficientBalance is returned when a transfer would overdraw an account
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
 // Account represents a bank account
 type Account struct {
 	ID      int
@@ -165,13 +169,11 @@ func NewDeadlockPreventer(db *sql.DB) *DeadlockPreventer {
 
 // TransferMoneyOrdered transfers money using ordered resource locking
 func (dp *DeadlockPreventer) TransferMoneyOrdered(fromID, toID int, amount float64) error {
-	// 常に小さいIDから大きいIDの順でロック
-	firstID, secondID := fromID, toID
-	firstAmount, secondAmount := -amount, amount
-
-	if fromID > toID {
-		firstID, secondID = toID, fromID
-		firstAmount, secondAmount = amount, -amount
+	if fromID == toID {
+		return fmt.Errorf("cannot transfer to the same account %d", fromID)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("transfer amount must be positive, got %.2f", amount)
 	}
 
 	tx, err := dp.db.Begin()
@@ -180,14 +182,26 @@ func (dp *DeadlockPreventer) TransferMoneyOrdered(fromID, toID int, amount float
 	}
 	defer tx.Rollback()
 
-	// 順序付きでアカウントを更新
-	_, err = tx.Exec("UPDATE accounts SET balance = balance + $1 WHERE id = $2", firstAmount, firstID)
-	if err != nil {
-		return err
+	// 送金の向きに関係なく、常に小さいIDから大きいIDの順で行ロックを取得する
+	// これにより A→B と B→A が同時に走っても循環待ちが起きない
+	accounts := make(map[int]*Account, 2)
+	for _, accountID := range orderAccountIDs([]int{fromID, toID}) {
+		account, err := getAccountForUpdate(tx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to lock account %d: %w", accountID, err)
+		}
+		accounts[accountID] = account
 	}
 
-	_, err = tx.Exec("UPDATE accounts SET balance = balance + $1 WHERE id = $2", secondAmount, secondID)
-	if err != nil {
+	// 残高チェック（不足時は defer の Rollback で何も反映されない）
+	if accounts[fromID].Balance < amount {
+		return fmt.Errorf("account %d: %w", fromID, ErrInsufficientBalance)
+	}
+
+	if err := adjustAccountBalance(tx, fromID, -amount); err != nil {
+		return err
+	}
+	if err := adjustAccountBalance(tx, toID, amount); err != nil {
 		return err
 	}
 
@@ -200,19 +214,21 @@ func (dp *DeadlockPreventer) TransferMultipleOrdered(transfers []Transfer) error
 		return nil
 	}
 
-	// 関係するすべてのアカウントIDを収集
-	accountIDSet := make(map[int]bool)
+	// 関係するすべてのアカウントIDを収集し、残高変更を計算
+	balanceChanges := make(map[int]float64)
 	for _, transfer := range transfers {
-		accountIDSet[transfer.FromID] = true
-		accountIDSet[transfer.ToID] = true
+		if transfer.Amount <= 0 {
+			return fmt.Errorf("transfer amount must be positive, got %.2f", transfer.Amount)
+		}
+		balanceChanges[transfer.FromID] -= transfer.Amount
+		balanceChanges[transfer.ToID] += transfer.Amount
 	}
 
-	// アカウントIDを順序付け
-	var accountIDs []int
-	for id := range accountIDSet {
+	accountIDs := make([]int, 0, len(balanceChanges))
+	for id := range balanceChanges {
 		accountIDs = append(accountIDs, id)
 	}
-	sort.Ints(accountIDs)
+	accountIDs = orderAccountIDs(accountIDs)
 
 	tx, err := dp.db.Begin()
 	if err != nil {
@@ -220,37 +236,21 @@ func (dp *DeadlockPreventer) TransferMultipleOrdered(transfers []Transfer) error
 	}
 	defer tx.Rollback()
 
-	// 順序付きでアカウントをロック（FOR UPDATE）
-	accountBalances := make(map[int]float64)
+	// 順序付きでアカウントをロック（FOR UPDATE）し、残高をチェック
 	for _, accountID := range accountIDs {
-		var balance float64
-		err := tx.QueryRow("SELECT balance FROM accounts WHERE id = $1 FOR UPDATE", accountID).Scan(&balance)
+		account, err := getAccountForUpdate(tx, accountID)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to lock account %d: %w", accountID, err)
 		}
-		accountBalances[accountID] = balance
-	}
-
-	// 残高変更を計算
-	balanceChanges := make(map[int]float64)
-	for _, transfer := range transfers {
-		balanceChanges[transfer.FromID] -= transfer.Amount
-		balanceChanges[transfer.ToID] += transfer.Amount
-	}
-
-	// 残高チェック
-	for accountID, change := range balanceChanges {
-		newBalance := accountBalances[accountID] + change
-		if newBalance < 0 {
-			return fmt.Errorf("insufficient balance for account %d", accountID)
+		if account.Balance+balanceChanges[accountID] < 0 {
+			return fmt.Errorf("account %d: %w", accountID, ErrInsufficientBalance)
 		}
 	}
 
-	// 実際の残高更新
-	for accountID, change := range balanceChanges {
-		if change != 0 {
-			_, err := tx.Exec("UPDATE accounts SET balance = balance + $1 WHERE id = $2", change, accountID)
-			if err != nil {
+	// 実際の残高更新もロックと同じ順序で行う
+	for _, accountID := range accountIDs {
+		if change := balanceChanges[accountID]; change != 0 {
+			if err := adjustAccountBalance(tx, accountID, change); err != nil {
 				return err
 			}
 		}
@@ -275,6 +275,9 @@ type DeadlockDetector struct {
 
 // NewDeadlockDetector creates a new deadlock detector
 func NewDeadlockDetector(db *sql.DB, maxRetries int, monitor *DeadlockMonitor) *DeadlockDetector {
+	if monitor == nil {
+		monitor = NewDeadlockMonitor()
+	}
 	return &DeadlockDetector{
 		db:         db,
 		maxRetries: maxRetries,
@@ -282,6 +285,9 @@ func NewDeadlockDetector(db *sql.DB, maxRetries int, monitor *DeadlockMonitor) *
 	}
 }
 
+// deadlockRetryBaseDelay is the base delay for exponential backoff between retries
+const deadlockRetryBaseDelay = 50 * time.Millisecond
+
 // ExecuteWithRetry executes operation with deadlock detection and retry
 func (dd *DeadlockDetector) ExecuteWithRetry(operation func(*sql.Tx) error) error {
 	return dd.ExecuteWithTimeout(context.Background(), operation)
@@ -289,98 +295,90 @@ func (dd *DeadlockDetector) ExecuteWithRetry(operation func(*sql.Tx) error) erro
 
 // ExecuteWithTimeout executes operation with both retry and timeout
 func (dd *DeadlockDetector) ExecuteWithTimeout(ctx context.Context, operation func(*sql.Tx) error) error {
-	for attempt := 0; attempt <= dd.maxRetries; attempt++ {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	var lastErr error
 
-		tx, err := dd.db.BeginTx(ctx, nil)
-		if err != nil {
+	for attempt := 0; attempt <= dd.maxRetries; attempt++ {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		err = operation(tx)
-		if err != nil {
-			tx.Rollback()
-
-			if isDeadlockError(err) {
-				dd.monitor.RecordDeadlock()
-
-				if attempt < dd.maxRetries {
-					dd.monitor.RecordRetry(false)
-					delay := calculateBackoffDelay(attempt, 50*time.Millisecond)
+		err := dd.runInTx(ctx, operation)
 
-					select {
-					case <-ctx.Done():
-						return ctx.Err()
-					case <-time.After(delay):
-						continue
-					}
-				}
-			}
+		// リトライの結果を記録（初回の試行はリトライに含めない）
+		if attempt > 0 {
+			dd.monitor.RecordRetry(err == nil)
+		}
+		if err == nil {
+			return nil
+		}
+		if !isDeadlockError(err) {
 			return err
 		}
 
-		err = tx.Commit()
-		if err != nil {
-			if isDeadlockError(err) {
-				dd.monitor.RecordDeadlock()
-
-				if attempt < dd.maxRetries {
-					dd.monitor.RecordRetry(false)
-					delay := calculateBackoffDelay(attempt, 50*time.Millisecond)
-
-					select {
-					case <-ctx.Done():
-						return ctx.Err()
-					case <-time.After(delay):
-						continue
-					}
-				}
+		dd.monitor.RecordDeadlock()
+		lastErr = err
+
+		if attempt < dd.maxRetries {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(calculateBackoffDelay(attempt, deadlockRetryBaseDelay)):
 			}
-			return err
 		}
+	}
 
-		if attempt > 0 {
-			dd.monitor.RecordRetry(true)
-		}
-		return nil
+	return fmt.Errorf("operation failed after %d retries: %w", dd.maxRetries, lastErr)
+}
+
+// runInTx runs operation in a new transaction and commits it.
+// デッドロックはCOMMIT時に検出されることもあるため、COMMITのエラーもそのまま返す
+func (dd *DeadlockDetector) runInTx(ctx context.Context, operation func(*sql.Tx) error) error {
+	tx, err := dd.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	return fmt.Errorf("operation failed after %d retries", dd.maxRetries)
+	if err := operation(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // ResourceLockManager manages ordered resource locking
 type ResourceLockManager struct {
 	mu              sync.Mutex
+	released        *sync.Cond // ロック解放を待つゴルーチンを起こす
 	lockedResources map[string]bool
 }
 
 // NewResourceLockManager creates a new resource lock manager
 func NewResourceLockManager() *ResourceLockManager {
-	return &ResourceLockManager{
+	rlm := &ResourceLockManager{
 		lockedResources: make(map[string]bool),
 	}
+	rlm.released = sync.NewCond(&rlm.mu)
+	return rlm
 }
 
-// LockResources locks multiple resources in a consistent order
+// LockResources locks multiple resources in a consistent order.
+// 他のゴルーチンが保持しているリソースは解放されるまで待つ。
+// 全員が同じ順序で取得するため、保持しながら待っても循環待ちにならない
 func (rlm *ResourceLockManager) LockResources(resourceIDs []string) error {
 	orderedIDs := orderResourceIDs(resourceIDs)
+	for _, id := range orderedIDs {
+		if id == "" {
+			return fmt.Errorf("resource ID must not be empty")
+		}
+	}
 
 	rlm.mu.Lock()
 	defer rlm.mu.Unlock()
 
-	// 既にロックされているリソースがないかチェック
 	for _, id := range orderedIDs {
-		if rlm.lockedResources[id] {
-			return fmt.Errorf("resource %s is already locked", id)
+		for rlm.lockedResources[id] {
+			rlm.released.Wait()
 		}
-	}
-
-	// すべてのリソースをロック
-	for _, id := range orderedIDs {
 		rlm.lockedResources[id] = true
 	}
 
@@ -395,9 +393,11 @@ func (rlm *ResourceLockManager) UnlockResources(resourceIDs []string) {
 	for _, id := range resourceIDs {
 		delete(rlm.lockedResources, id)
 	}
+	rlm.released.Broadcast()
 }
 
-// WithOrderedLocks executes function with ordered resource locks
+// WithOrderedLocks executes function with ordered resource locks.
+// fn が panic してもロックは解放される
 func (rlm *ResourceLockManager) WithOrderedLocks(resourceIDs []string, fn func() error) error {
 	err := rlm.LockResources(resourceIDs)
 	if err != nil {
@@ -483,15 +483,23 @@ type DeadlockStats struct {
 
 // Utility functions
 
+// pgDeadlockDetected is the PostgreSQL SQLSTATE for deadlock_detected
+const pgDeadlockDetected = "40P01"
+
 // isDeadlockError checks if error is a deadlock error
 func isDeadlockError(err error) bool {
 	if err == nil {
 		return false
 	}
 
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == pgDeadlockDetected
+	}
+
 	errStr := strings.ToLower(err.Error())
 	return strings.Contains(errStr, "deadlock") ||
-		strings.Contains(errStr, "40p01") // PostgreSQL deadlock error code
+		strings.Contains(errStr, strings.ToLower(pgDeadlockDetected))
 }
 
 // calculateBackoffDelay calculates exponential backoff delay
@@ -505,17 +513,25 @@ func calculateBackoffDelay(attempt int, baseDelay time.Duration) time.Duration {
 		delay = maxDelay
 	}
 
-	// ジッターを追加（±25%）
-	jitter := time.Duration(rand.Float64()*0.5-0.25) * delay
+	// ジッターを追加（±25%）。同時にデッドロックしたトランザクションのリトライをずらす
+	jitter := time.Duration((rand.Float64()*0.5 - 0.25) * float64(delay))
 	return delay + jitter
 }
 
-// orderResourceIDs sorts resource IDs consistently
+// orderResourceIDs sorts resource IDs consistently and drops duplicates
+// (同じIDを2回ロックしようとすると自分自身を待ってしまうため)
 func orderResourceIDs(ids []string) []string {
 	result := make([]string, len(ids))
 	copy(result, ids)
 	sort.Strings(result)
-	return result
+
+	unique := result[:0]
+	for _, id := range result {
+		if len(unique) == 0 || unique[len(unique)-1] != id {
+			unique = append(unique, id)
+		}
+	}
+	return unique
 }
 
 // orderAccountIDs sorts account IDs consistently
@@ -536,10 +552,14 @@ func transferMoney(db *sql.DB, fromID, toID int, amount float64) error {
 	}
 	defer tx.Rollback()
 
-	return transferMoneyInTx(tx, fromID, toID, amount)
+	if err := transferMoneyInTx(tx, fromID, toID, amount); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// transferMoneyInTx performs money transfer within existing transaction
+// transferMoneyInTx performs money transfer within existing transaction.
+// The caller owns the transaction and is responsible for committing it.
 func transferMoneyInTx(tx *sql.Tx, fromID, toID int, amount float64) error {
 	// 送金元から減額
 	_, err := tx.Exec("UPDATE accounts SET balance = balance - $1 WHERE id = $2", amount, fromID)
@@ -549,11 +569,7 @@ func transferMoneyInTx(tx *sql.Tx, fromID, toID int, amount float64) error {
 
 	// 送金先に加算
 	_, err = tx.Exec("UPDATE accounts SET balance = balance + $1 WHERE id = $2", amount, toID)
-	if err != nil {
-		return err
-	}
-
-	return tx.Commit()
+	return err
 }
 
 // getAccountForUpdate gets account with FOR UPDATE lock
@@ -573,6 +589,12 @@ func updateAccountBalance(tx *sql.Tx, accountID int, newBalance float64) error {
 	return err
 }
 
+// adjustAccountBalance adds delta to account balance
+func adjustAccountBalance(tx *sql.Tx, accountID int, delta float64) error {
+	_, err := tx.Exec("UPDATE accounts SET balance = balance + $1 WHERE id = $2", delta, accountID)
+	return err
+}
+
 // Database initialization functions
 
 // InitializeDeadlockTestDB creates tables for deadlock testing
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -10,7 +11,7 @@ import (
 
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var (
@@ -185,6 +186,91 @@ func TestDeadlockPreventer_TransferMoneyOrdered(t *testing.T) {
 	}
 }
 
+func TestDeadlockPreventer_OpposingTransfers(t *testing.T) {
+	setupTestAccounts(t)
+	preventer := NewDeadlockPreventer(db)
+
+	const rounds = 50
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, rounds*2)
+
+	// A→B と B→A を同時に大量に実行する
+	for i := 0; i < rounds; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- preventer.TransferMoneyOrdered(1, 2, 5.0)
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- preventer.TransferMoneyOrdered(2, 1, 3.0)
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err == nil {
+			continue
+		}
+		if isDeadlockError(err) {
+			t.Errorf("Ordered locking should prevent deadlocks, got: %v", err)
+		} else {
+			t.Errorf("Transfer failed: %v", err)
+		}
+	}
+
+	var balance1, balance2 float64
+	if err := db.QueryRow("SELECT balance FROM accounts WHERE id = 1").Scan(&balance1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT balance FROM accounts WHERE id = 2").Scan(&balance2); err != nil {
+		t.Fatal(err)
+	}
+
+	if balance1+balance2 != 2000.0 {
+		t.Errorf("Total balance not conserved: %f + %f = %f", balance1, balance2, balance1+balance2)
+	}
+	if expected := 1000.0 - rounds*5.0 + rounds*3.0; balance1 != expected {
+		t.Errorf("Account 1 balance: expected %f, got %f", expected, balance1)
+	}
+}
+
+func TestDeadlockPreventer_InsufficientBalance(t *testing.T) {
+	setupTestAccounts(t)
+	preventer := NewDeadlockPreventer(db)
+
+	err := preventer.TransferMoneyOrdered(2, 1, 5000.0)
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("Expected ErrInsufficientBalance, got %v", err)
+	}
+
+	// 複数送金でも1件でも残高不足なら全体がロールバックされる
+	err = preventer.TransferMultipleOrdered([]Transfer{
+		{FromID: 1, ToID: 2, Amount: 100.0},
+		{FromID: 3, ToID: 4, Amount: 2000.0},
+	})
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("Expected ErrInsufficientBalance, got %v", err)
+	}
+
+	for id := 1; id <= 4; id++ {
+		var balance float64
+		if err := db.QueryRow("SELECT balance FROM accounts WHERE id = $1", id).Scan(&balance); err != nil {
+			t.Fatal(err)
+		}
+		if balance != 1000.0 {
+			t.Errorf("Account %d balance changed after rollback: %f", id, balance)
+		}
+	}
+}
+
 func TestDeadlockPreventer_TransferMultipleOrdered(t *testing.T) {
 	setupTestAccounts(t)
 	preventer := NewDeadlockPreventer(db)
@@ -280,6 +366,113 @@ func TestDeadlockDetector_ExecuteWithTimeout(t *testing.T) {
 	}
 }
 
+func TestDeadlockDetector_RetryStatistics(t *testing.T) {
+	deadlockErr := &pq.Error{Code: "40P01", Message: "deadlock detected"}
+
+	t.Run("retries deadlocks until success", func(t *testing.T) {
+		setupTestAccounts(t)
+		monitor := NewDeadlockMonitor()
+		detector := NewDeadlockDetector(db, 3, monitor)
+
+		attempts := 0
+		err := detector.ExecuteWithRetry(func(tx *sql.Tx) error {
+			attempts++
+			if attempts <= 2 {
+				return deadlockErr
+			}
+			_, err := tx.Exec("UPDATE accounts SET balance = balance + 10 WHERE id = 1")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("ExecuteWithRetry failed: %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("Expected 3 attempts, got %d", attempts)
+		}
+
+		stats := monitor.GetStatistics()
+		if stats.DeadlockCount != 2 || stats.TotalRetries != 2 || stats.SuccessfulRetries != 1 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if stats.LastDeadlock.IsZero() {
+			t.Error("Expected LastDeadlock to be recorded")
+		}
+
+		// 成功した試行の更新だけがコミットされている
+		var balance float64
+		if err := db.QueryRow("SELECT balance FROM accounts WHERE id = 1").Scan(&balance); err != nil {
+			t.Fatal(err)
+		}
+		if balance != 1010.0 {
+			t.Errorf("Expected balance 1010, got %f", balance)
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		monitor := NewDeadlockMonitor()
+		detector := NewDeadlockDetector(db, 3, monitor)
+
+		uniqueErr := &pq.Error{Code: "23505", Message: "duplicate key value"}
+		attempts := 0
+		err := detector.ExecuteWithRetry(func(tx *sql.Tx) error {
+			attempts++
+			return uniqueErr
+		})
+		if err != uniqueErr {
+			t.Errorf("Expected original error, got %v", err)
+		}
+		if attempts != 1 {
+			t.Errorf("Expected a single attempt, got %d", attempts)
+		}
+		if stats := monitor.GetStatistics(); stats.DeadlockCount != 0 || stats.TotalRetries != 0 {
+			t.Errorf("Expected no deadlock stats, got %+v", stats)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		monitor := NewDeadlockMonitor()
+		detector := NewDeadlockDetector(db, 2, monitor)
+
+		attempts := 0
+		err := detector.ExecuteWithRetry(func(tx *sql.Tx) error {
+			attempts++
+			return deadlockErr
+		})
+
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code != "40P01" {
+			t.Errorf("Expected wrapped deadlock error, got %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("Expected 3 attempts (1 + 2 retries), got %d", attempts)
+		}
+
+		stats := monitor.GetStatistics()
+		if stats.DeadlockCount != 3 || stats.TotalRetries != 2 || stats.SuccessfulRetries != 0 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+}
+
+func TestIsDeadlockError_SQLState(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"pq deadlock", &pq.Error{Code: "40P01", Message: "deadlock detected"}, true},
+		{"wrapped pq deadlock", fmt.Errorf("transfer: %w", &pq.Error{Code: "40P01"}), true},
+		{"pq serialization failure", &pq.Error{Code: "40001", Message: "could not serialize access"}, false},
+		{"pq unique violation", &pq.Error{Code: "23505", Message: "duplicate key value"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := isDeadlockError(tt.err); got != tt.expected {
+			t.Errorf("%s: isDeadlockError = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
 func TestResourceLockManager_LockResources(t *testing.T) {
 	rlm := NewResourceLockManager()
 
@@ -321,6 +514,92 @@ func TestResourceLockManager_LockResources(t *testing.T) {
 	}
 }
 
+func TestResourceLockManager_OpposingOrders(t *testing.T) {
+	rlm := NewResourceLockManager()
+
+	forward := []string{"account_a", "account_b", "account_c"}
+	backward := []string{"account_c", "account_b", "account_a"}
+	overlap := []string{"account_b", "account_d", "account_b"} // 重複IDも許容する
+
+	// 同じリソースを同時に保持していないことを確認するためのカウンタ
+	var mu sync.Mutex
+	holders := make(map[string]int)
+	enter := func(ids []string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, id := range orderResourceIDs(ids) {
+			holders[id]++
+			if holders[id] > 1 {
+				return fmt.Errorf("resource %s held by %d goroutines", id, holders[id])
+			}
+		}
+		return nil
+	}
+	leave := func(ids []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, id := range orderResourceIDs(ids) {
+			holders[id]--
+		}
+	}
+
+	const workers = 30
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	done := make(chan struct{})
+
+	for i := 0; i < workers; i++ {
+		ids := [][]string{forward, backward, overlap}[i%3]
+		wg.Add(1)
+		go func(ids []string) {
+			defer wg.Done()
+			errs <- rlm.WithOrderedLocks(ids, func() error {
+				if err := enter(ids); err != nil {
+					return err
+				}
+				time.Sleep(time.Millisecond)
+				leave(ids)
+				return nil
+			})
+		}(ids)
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Deadlock: workers did not finish")
+	}
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// fn が panic してもロックは解放される
+	func() {
+		defer func() { recover() }()
+		rlm.WithOrderedLocks(forward, func() error { panic("boom") })
+	}()
+	if err := rlm.LockResources(backward); err != nil {
+		t.Fatalf("LockResources after panic failed: %v", err)
+	}
+	rlm.UnlockResources(backward)
+
+	rlm.mu.Lock()
+	remaining := len(rlm.lockedResources)
+	rlm.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("Expected all resources to be released, %d still locked", remaining)
+	}
+}
+
 func TestDeadlockMonitor_Statistics(t *testing.T) {
 	monitor := NewDeadlockMonitor()
 
@@ -5,6 +5,10 @@ package main
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -100,8 +104,31 @@ func (r *PostgreSQLUserRepository) List(ctx context.Context, limit, offset int)
 
 // FindBySpec finds users by specification
 func (r *PostgreSQLUserRepository) FindBySpec(ctx context.Context, spec UserSpecification) ([]*User, error) {
-	// TODO: 仕様パターンでユーザーを検索
-	panic("Not yet implemented")
+	whereClause, args := spec.ToSQL()
+	query := fmt.Sprintf("SELECT id, username, email, created FROM users WHERE %s", whereClause)
+
+	var rows *sql.Rows
+	var err error
+	if r.tx != nil {
+		rows, err = r.tx.QueryContext(ctx, query, args...)
+	} else {
+		rows, err = r.db.QueryContext(ctx, query, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user := &User{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Created); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
 }
 
 // WithTx returns a repository that uses the provided transaction
@@ -225,6 +252,146 @@ func (m *MockUserRepository) WithTx(tx *sql.Tx) UserRepository {
 	panic("Not yet implemented")
 }
 
+// CircuitBreakerState represents the state of a circuit breaker
+type CircuitBreakerState int
+
+const (
+	StateClosed CircuitBreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+// String returns string representation of the state
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "Closed"
+	case StateOpen:
+		return "Open"
+	case StateHalfOpen:
+		return "Half-Open"
+	default:
+		return "Unknown"
+	}
+}
+
+// CircuitBreakerSettings configures a CircuitBreaker
+type CircuitBreakerSettings struct {
+	MaxFailures  int           // Open状態に移行する連続失敗回数
+	ResetTimeout time.Duration // Open状態からHalf-Openに移行するまでの時間
+	// IsFailure reports whether err should count as a failure.
+	// Defaults to every error except sql.ErrNoRows and context cancellation.
+	IsFailure func(err error) bool
+}
+
+// CircuitBreakerOpenError is returned when a call is rejected without reaching the database
+type CircuitBreakerOpenError struct {
+	State CircuitBreakerState
+}
+
+func (e *CircuitBreakerOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is %s", e.State)
+}
+
+// CircuitBreaker shields a failing dependency by fast-failing calls while open
+type CircuitBreaker struct {
+	settings CircuitBreakerSettings
+	mu       sync.Mutex
+	state    CircuitBreakerState
+	failures int
+	openedAt time.Time
+	probing  bool // Half-Open中に試行中の呼び出しがあるか
+}
+
+// NewCircuitBreaker creates a new circuit breaker
+func NewCircuitBreaker(settings CircuitBreakerSettings) *CircuitBreaker {
+	// TODO: デフォルト値（MaxFailures, IsFailure）を補ってCircuitBreakerを初期化
+	panic("Not yet implemented")
+}
+
+// Execute runs fn if the breaker allows it and records the outcome
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	// TODO: Open中は*CircuitBreakerOpenErrorで即座に失敗させる
+	// - ResetTimeout経過後はHalf-Openに移行し、1件だけ試行を通す
+	// - IsFailureが真の失敗が連続でMaxFailures回続いたらOpenにする
+	// - Half-Openでの失敗は即座にOpenへ戻し、成功したらClosedに戻す
+	panic("Not yet implemented")
+}
+
+// State returns the current state
+func (cb *CircuitBreaker) State() CircuitBreakerState {
+	// TODO: 現在の状態を返す（ResetTimeout経過後のOpenはHalf-Open）
+	panic("Not yet implemented")
+}
+
+// ResilientUserRepository decorates a UserRepository with a circuit breaker.
+// All methods share one breaker because they fail together when the database is down.
+type ResilientUserRepository struct {
+	repo    UserRepository
+	breaker *CircuitBreaker
+}
+
+// NewResilientUserRepository wraps repo with a circuit breaker
+func NewResilientUserRepository(repo UserRepository, settings CircuitBreakerSettings) *ResilientUserRepository {
+	// TODO: ResilientUserRepositoryを初期化
+	panic("Not yet implemented")
+}
+
+// Breaker returns the shared circuit breaker
+func (r *ResilientUserRepository) Breaker() *CircuitBreaker {
+	return r.breaker
+}
+
+// Create creates a new user
+func (r *ResilientUserRepository) Create(ctx context.Context, user *User) error {
+	// TODO: サーキットブレーカー経由で委譲
+	panic("Not yet implemented")
+}
+
+// GetByID retrieves a user by ID
+func (r *ResilientUserRepository) GetByID(ctx context.Context, id int) (*User, error) {
+	// TODO: サーキットブレーカー経由で委譲
+	panic("Not yet implemented")
+}
+
+// GetByEmail retrieves a user by email
+func (r *ResilientUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	// TODO: サーキットブレーカー経由で委譲
+	panic("Not yet implemented")
+}
+
+// Update updates a user
+func (r *ResilientUserRepository) Update(ctx context.Context, user *User) error {
+	// TODO: サーキットブレーカー経由で委譲
+	panic("Not yet implemented")
+}
+
+// Delete deletes a user by ID
+func (r *ResilientUserRepository) Delete(ctx context.Context, id int) error {
+	// TODO: サーキットブレーカー経由で委譲
+	panic("Not yet implemented")
+}
+
+// List returns a paginated list of users
+func (r *ResilientUserRepository) List(ctx context.Context, limit, offset int) ([]*User, error) {
+	// TODO: サーキットブレーカー経由で委譲
+	panic("Not yet implemented")
+}
+
+// FindBySpec finds users by specification
+func (r *ResilientUserRepository) FindBySpec(ctx context.Context, spec UserSpecification) ([]*User, error) {
+	// TODO: サーキットブレーカー経由で委譲
+	panic("Not yet implemented")
+}
+
+// WithTx returns the underlying repository bound to tx without the breaker.
+// A transaction must see every statement succeed or fail as a unit, so
+// individual calls inside it are never rejected by the breaker.
+func (r *ResilientUserRepository) WithTx(tx *sql.Tx) UserRepository {
+	// TODO: ブレーカーを通さずに内側のリポジトリのWithTxを返す
+	panic("Not yet implemented")
+}
+
 // UserService provides business logic for user operations
 type UserService struct {
 	userRepo UserRepository
@@ -234,8 +401,11 @@ type UserService struct {
 
 // NewUserService creates a new user service
 func NewUserService(userRepo UserRepository, postRepo PostRepository, db *sql.DB) *UserService {
-	// TODO: UserServiceを初期化
-	panic("Not yet implemented")
+	return &UserService{
+		userRepo: userRepo,
+		postRepo: postRepo,
+		db:       db,
+	}
 }
 
 // CreateUserWithProfile creates a user and their profile in a single transaction
@@ -245,9 +415,34 @@ func (s *UserService) CreateUserWithProfile(ctx context.Context, user *User, bio
 }
 
 // CreateUserWithPost creates a user and their first post in a single transaction
-func (s *UserService) CreateUserWithPost(ctx context.Context, user *User, post *Post) error {
-	// TODO: トランザクション内でユーザーと投稿を作成
-	panic("Not yet implemented")
+func (s *UserService) CreateUserWithPost(ctx context.Context, user *User, post *Post) (err error) {
+	uow := &UnitOfWork{db: s.db, userRepo: s.userRepo, postRepo: s.postRepo}
+	if err := uow.Begin(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			uow.Rollback()
+			// ロールバックされた行のIDを呼び出し側に残さない
+			user.ID = 0
+			post.ID = 0
+			post.UserID = 0
+		}
+	}()
+
+	// Create user first
+	if err := uow.Users().Create(ctx, user); err != nil {
+		return err
+	}
+
+	// Set the user ID for the post
+	post.UserID = user.ID
+
+	if err := uow.Posts().Create(ctx, post); err != nil {
+		return err
+	}
+
+	return uow.Commit()
 }
 
 // UnitOfWork manages multiple repositories in a single transaction
@@ -260,38 +455,60 @@ type UnitOfWork struct {
 
 // NewUnitOfWork creates a new unit of work
 func NewUnitOfWork(db *sql.DB) *UnitOfWork {
-	// TODO: UnitOfWorkを初期化
-	panic("Not yet implemented")
+	return &UnitOfWork{
+		db:       db,
+		userRepo: NewPostgreSQLUserRepository(db),
+		postRepo: NewPostgreSQLPostRepository(db),
+	}
 }
 
 // Begin starts a new transaction
 func (uow *UnitOfWork) Begin(ctx context.Context) error {
-	// TODO: トランザクションを開始
-	panic("Not yet implemented")
+	if uow.tx != nil {
+		return fmt.Errorf("transaction already active")
+	}
+	tx, err := uow.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	uow.tx = tx
+	return nil
 }
 
 // Users returns the user repository within the transaction
 func (uow *UnitOfWork) Users() UserRepository {
-	// TODO: トランザクション内のユーザーリポジトリを返す
-	panic("Not yet implemented")
+	if uow.tx != nil {
+		return uow.userRepo.WithTx(uow.tx)
+	}
+	return uow.userRepo
 }
 
 // Posts returns the post repository within the transaction
 func (uow *UnitOfWork) Posts() PostRepository {
-	// TODO: トランザクション内の投稿リポジトリを返す
-	panic("Not yet implemented")
+	if uow.tx != nil {
+		return uow.postRepo.WithTx(uow.tx)
+	}
+	return uow.postRepo
 }
 
 // Commit commits the transaction
 func (uow *UnitOfWork) Commit() error {
-	// TODO: トランザクションをコミット
-	panic("Not yet implemented")
+	if uow.tx == nil {
+		return fmt.Errorf("no active transaction")
+	}
+	err := uow.tx.Commit()
+	uow.tx = nil
+	return err
 }
 
 // Rollback rolls back the transaction
 func (uow *UnitOfWork) Rollback() error {
-	// TODO: トランザクションをロールバック
-	panic("Not yet implemented")
+	if uow.tx == nil {
+		return nil
+	}
+	err := uow.tx.Rollback()
+	uow.tx = nil
+	return err
 }
 
 // Specification pattern for complex queries
@@ -307,8 +524,7 @@ type UserByEmailSpec struct {
 }
 
 func (s UserByEmailSpec) ToSQL() (string, []interface{}) {
-	// TODO: メール検索条件のSQLを生成
-	panic("Not yet implemented")
+	return "email = $1", []interface{}{s.Email}
 }
 
 // UserCreatedAfterSpec specification for finding users created after a date
@@ -317,8 +533,37 @@ type UserCreatedAfterSpec struct {
 }
 
 func (s UserCreatedAfterSpec) ToSQL() (string, []interface{}) {
-	// TODO: 作成日時検索条件のSQLを生成
-	panic("Not yet implemented")
+	return "created > $1", []interface{}{s.After}
+}
+
+// UserInIDsSpec specification for finding users whose ID is in a set
+type UserInIDsSpec struct {
+	IDs []int
+}
+
+func (s UserInIDsSpec) ToSQL() (string, []interface{}) {
+	if len(s.IDs) == 0 {
+		// IN () は構文エラーになるため、常に偽となる条件を返す
+		return "1=0", nil
+	}
+
+	placeholders := make([]string, len(s.IDs))
+	args := make([]interface{}, len(s.IDs))
+	for i, id := range s.IDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	return fmt.Sprintf("id IN (%s)", strings.Join(placeholders, ",")), args
+}
+
+// NotSpec negates a specification
+type NotSpec struct {
+	Inner UserSpecification
+}
+
+func (s NotSpec) ToSQL() (string, []interface{}) {
+	innerSQL, args := s.Inner.ToSQL()
+	return fmt.Sprintf("NOT (%s)", innerSQL), args
 }
 
 // AndSpec combines specifications with AND
@@ -327,8 +572,7 @@ type AndSpec struct {
 }
 
 func (s AndSpec) ToSQL() (string, []interface{}) {
-	// TODO: AND条件でSpecificationを結合
-	panic("Not yet implemented")
+	return combineSpecs("AND", s.Left, s.Right)
 }
 
 // OrSpec combines specifications with OR
@@ -337,8 +581,47 @@ type OrSpec struct {
 }
 
 func (s OrSpec) ToSQL() (string, []interface{}) {
-	// TODO: OR条件でSpecificationを結合
-	panic("Not yet implemented")
+	return combineSpecs("OR", s.Left, s.Right)
+}
+
+// combineSpecs joins two specifications with op, wrapping each side in parentheses.
+// The right side's placeholders are shifted past the left side's args so that
+// args can be concatenated in left-then-right order.
+func combineSpecs(op string, left, right UserSpecification) (string, []interface{}) {
+	leftSQL, leftArgs := left.ToSQL()
+	rightSQL, rightArgs := right.ToSQL()
+
+	sql := fmt.Sprintf("(%s) %s (%s)", leftSQL, op, shiftPlaceholders(rightSQL, len(leftArgs)))
+	args := make([]interface{}, 0, len(leftArgs)+len(rightArgs))
+	args = append(args, leftArgs...)
+	args = append(args, rightArgs...)
+	return sql, args
+}
+
+// shiftPlaceholders renumbers every $n placeholder in sql to $(n+offset)
+func shiftPlaceholders(sql string, offset int) string {
+	if offset == 0 {
+		return sql
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(sql); i++ {
+		b.WriteByte(sql[i])
+		if sql[i] != '$' {
+			continue
+		}
+		j := i + 1
+		for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			continue
+		}
+		n, _ := strconv.Atoi(sql[i+1 : j])
+		b.WriteString(strconv.Itoa(n + offset))
+		i = j - 1
+	}
+	return b.String()
 }
 
 // Database setup functions
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -462,6 +464,228 @@ func (m *MockUserRepository) WithTx(tx *sql.Tx) UserRepository {
 	return m
 }
 
+// CircuitBreakerState represents the state of a circuit breaker
+type CircuitBreakerState int
+
+const (
+	StateClosed CircuitBreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+// String returns string representation of the state
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "Closed"
+	case StateOpen:
+		return "Open"
+	case StateHalfOpen:
+		return "Half-Open"
+	default:
+		return "Unknown"
+	}
+}
+
+// CircuitBreakerSettings configures a CircuitBreaker
+type CircuitBreakerSettings struct {
+	MaxFailures  int           // Open状態に移行する連続失敗回数
+	ResetTimeout time.Duration // Open状態からHalf-Openに移行するまでの時間
+	// IsFailure reports whether err should count as a failure.
+	// Defaults to every error except sql.ErrNoRows and context cancellation.
+	IsFailure func(err error) bool
+}
+
+// CircuitBreakerOpenError is returned when a call is rejected without reaching the database
+type CircuitBreakerOpenError struct {
+	State CircuitBreakerState
+}
+
+func (e *CircuitBreakerOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is %s", e.State)
+}
+
+// CircuitBreaker shields a failing dependency by fast-failing calls while open
+type CircuitBreaker struct {
+	settings CircuitBreakerSettings
+	mu       sync.Mutex
+	state    CircuitBreakerState
+	failures int
+	openedAt time.Time
+	probing  bool // Half-Open中に試行中の呼び出しがあるか
+}
+
+// NewCircuitBreaker creates a new circuit breaker
+func NewCircuitBreaker(settings CircuitBreakerSettings) *CircuitBreaker {
+	if settings.MaxFailures <= 0 {
+		settings.MaxFailures = 5
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = defaultIsFailure
+	}
+	return &CircuitBreaker{settings: settings}
+}
+
+// defaultIsFailure ignores errors that do not indicate an unhealthy database
+func defaultIsFailure(err error) bool {
+	return !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, context.Canceled)
+}
+
+// Execute runs fn if the breaker allows it and records the outcome
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	if err := cb.beforeCall(); err != nil {
+		return err
+	}
+
+	err := fn()
+	cb.afterCall(err)
+	return err
+}
+
+// State returns the current state
+func (cb *CircuitBreaker) State() CircuitBreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == StateOpen && time.Since(cb.openedAt) >= cb.settings.ResetTimeout {
+		return StateHalfOpen
+	}
+	return cb.state
+}
+
+func (cb *CircuitBreaker) beforeCall() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == StateOpen && time.Since(cb.openedAt) >= cb.settings.ResetTimeout {
+		cb.state = StateHalfOpen
+		cb.probing = false
+	}
+
+	switch cb.state {
+	case StateOpen:
+		return &CircuitBreakerOpenError{State: StateOpen}
+	case StateHalfOpen:
+		// 復旧確認のため1件だけ通す
+		if cb.probing {
+			return &CircuitBreakerOpenError{State: StateHalfOpen}
+		}
+		cb.probing = true
+	}
+	return nil
+}
+
+func (cb *CircuitBreaker) afterCall(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if err != nil && cb.settings.IsFailure(err) {
+		cb.failures++
+		if cb.state == StateHalfOpen || cb.failures >= cb.settings.MaxFailures {
+			cb.state = StateOpen
+			cb.openedAt = time.Now()
+		}
+		cb.probing = false
+		return
+	}
+
+	cb.state = StateClosed
+	cb.failures = 0
+	cb.probing = false
+}
+
+// ResilientUserRepository decorates a UserRepository with a circuit breaker.
+// All methods share one breaker because they fail together when the database is down.
+type ResilientUserRepository struct {
+	repo    UserRepository
+	breaker *CircuitBreaker
+}
+
+// NewResilientUserRepository wraps repo with a circuit breaker
+func NewResilientUserRepository(repo UserRepository, settings CircuitBreakerSettings) *ResilientUserRepository {
+	return &ResilientUserRepository{
+		repo:    repo,
+		breaker: NewCircuitBreaker(settings),
+	}
+}
+
+// Breaker returns the shared circuit breaker
+func (r *ResilientUserRepository) Breaker() *CircuitBreaker {
+	return r.breaker
+}
+
+// Create creates a new user
+func (r *ResilientUserRepository) Create(ctx context.Context, user *User) error {
+	return r.breaker.Execute(func() error {
+		return r.repo.Create(ctx, user)
+	})
+}
+
+// GetByID retrieves a user by ID
+func (r *ResilientUserRepository) GetByID(ctx context.Context, id int) (*User, error) {
+	var user *User
+	err := r.breaker.Execute(func() error {
+		var err error
+		user, err = r.repo.GetByID(ctx, id)
+		return err
+	})
+	return user, err
+}
+
+// GetByEmail retrieves a user by email
+func (r *ResilientUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	var user *User
+	err := r.breaker.Execute(func() error {
+		var err error
+		user, err = r.repo.GetByEmail(ctx, email)
+		return err
+	})
+	return user, err
+}
+
+// Update updates a user
+func (r *ResilientUserRepository) Update(ctx context.Context, user *User) error {
+	return r.breaker.Execute(func() error {
+		return r.repo.Update(ctx, user)
+	})
+}
+
+// Delete deletes a user by ID
+func (r *ResilientUserRepository) Delete(ctx context.Context, id int) error {
+	return r.breaker.Execute(func() error {
+		return r.repo.Delete(ctx, id)
+	})
+}
+
+// List returns a paginated list of users
+func (r *ResilientUserRepository) List(ctx context.Context, limit, offset int) ([]*User, error) {
+	var users []*User
+	err := r.breaker.Execute(func() error {
+		var err error
+		users, err = r.repo.List(ctx, limit, offset)
+		return err
+	})
+	return users, err
+}
+
+// FindBySpec finds users by specification
+func (r *ResilientUserRepository) FindBySpec(ctx context.Context, spec UserSpecification) ([]*User, error) {
+	var users []*User
+	err := r.breaker.Execute(func() error {
+		var err error
+		users, err = r.repo.FindBySpec(ctx, spec)
+		return err
+	})
+	return users, err
+}
+
+// WithTx returns the underlying repository bound to tx without the breaker.
+// A transaction must see every statement succeed or fail as a unit, so
+// individual calls inside it are never rejected by the breaker.
+func (r *ResilientUserRepository) WithTx(tx *sql.Tx) UserRepository {
+	return r.repo.WithTx(tx)
+}
+
 // UserService provides business logic for user operations
 type UserService struct {
 	userRepo UserRepository
@@ -507,33 +731,34 @@ func (s *UserService) CreateUserWithProfile(ctx context.Context, user *User, bio
 }
 
 // CreateUserWithPost creates a user and their first post in a single transaction
-func (s *UserService) CreateUserWithPost(ctx context.Context, user *User, post *Post) error {
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
+func (s *UserService) CreateUserWithPost(ctx context.Context, user *User, post *Post) (err error) {
+	uow := &UnitOfWork{db: s.db, userRepo: s.userRepo, postRepo: s.postRepo}
+	if err := uow.Begin(ctx); err != nil {
 		return err
 	}
-	defer tx.Rollback()
+	defer func() {
+		if err != nil {
+			uow.Rollback()
+			// ロールバックされた行のIDを呼び出し側に残さない
+			user.ID = 0
+			post.ID = 0
+			post.UserID = 0
+		}
+	}()
 
-	// Use transaction-aware repositories
-	txUserRepo := s.userRepo.WithTx(tx)
-	txPostRepo := s.postRepo.WithTx(tx)
-	
 	// Create user first
-	err = txUserRepo.Create(ctx, user)
-	if err != nil {
+	if err := uow.Users().Create(ctx, user); err != nil {
 		return err
 	}
 
 	// Set the user ID for the post
 	post.UserID = user.ID
-	
-	// Create post
-	err = txPostRepo.Create(ctx, post)
-	if err != nil {
+
+	if err := uow.Posts().Create(ctx, post); err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	return uow.Commit()
 }
 
 // UnitOfWork manages multiple repositories in a single transaction
@@ -555,6 +780,9 @@ func NewUnitOfWork(db *sql.DB) *UnitOfWork {
 
 // Begin starts a new transaction
 func (uow *UnitOfWork) Begin(ctx context.Context) error {
+	if uow.tx != nil {
+		return fmt.Errorf("transaction already active")
+	}
 	tx, err := uow.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -633,25 +861,43 @@ func (s UsernameLikeSpec) ToSQL() (string, []interface{}) {
 	return "username LIKE $1", []interface{}{s.Pattern}
 }
 
+// UserInIDsSpec specification for finding users whose ID is in a set
+type UserInIDsSpec struct {
+	IDs []int
+}
+
+func (s UserInIDsSpec) ToSQL() (string, []interface{}) {
+	if len(s.IDs) == 0 {
+		// IN () は構文エラーになるため、常に偽となる条件を返す
+		return "1=0", nil
+	}
+
+	placeholders := make([]string, len(s.IDs))
+	args := make([]interface{}, len(s.IDs))
+	for i, id := range s.IDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	return fmt.Sprintf("id IN (%s)", strings.Join(placeholders, ",")), args
+}
+
+// NotSpec negates a specification
+type NotSpec struct {
+	Inner UserSpecification
+}
+
+func (s NotSpec) ToSQL() (string, []interface{}) {
+	innerSQL, args := s.Inner.ToSQL()
+	return fmt.Sprintf("NOT (%s)", innerSQL), args
+}
+
 // AndSpec combines specifications with AND
 type AndSpec struct {
 	Left, Right UserSpecification
 }
 
 func (s AndSpec) ToSQL() (string, []interface{}) {
-	leftSQL, leftArgs := s.Left.ToSQL()
-	rightSQL, rightArgs := s.Right.ToSQL()
-	
-	// Adjust parameter placeholders for the right side
-	adjustedRightSQL := rightSQL
-	for i := len(leftArgs); i > 0; i-- {
-		placeholder := fmt.Sprintf("$%d", len(leftArgs)+1)
-		adjustedRightSQL = strings.Replace(adjustedRightSQL, "$1", placeholder, 1)
-	}
-	
-	sql := fmt.Sprintf("(%s) AND (%s)", leftSQL, adjustedRightSQL)
-	args := append(leftArgs, rightArgs...)
-	return sql, args
+	return combineSpecs("AND", s.Left, s.Right)
 }
 
 // OrSpec combines specifications with OR
@@ -660,21 +906,49 @@ type OrSpec struct {
 }
 
 func (s OrSpec) ToSQL() (string, []interface{}) {
-	leftSQL, leftArgs := s.Left.ToSQL()
-	rightSQL, rightArgs := s.Right.ToSQL()
-	
-	// Adjust parameter placeholders for the right side
-	adjustedRightSQL := rightSQL
-	for i := len(leftArgs); i > 0; i-- {
-		placeholder := fmt.Sprintf("$%d", len(leftArgs)+1)
-		adjustedRightSQL = strings.Replace(adjustedRightSQL, "$1", placeholder, 1)
-	}
-	
-	sql := fmt.Sprintf("(%s) OR (%s)", leftSQL, adjustedRightSQL)
-	args := append(leftArgs, rightArgs...)
+	return combineSpecs("OR", s.Left, s.Right)
+}
+
+// combineSpecs joins two specifications with op, wrapping each side in parentheses.
+// The right side's placeholders are shifted past the left side's args so that
+// args can be concatenated in left-then-right order.
+func combineSpecs(op string, left, right UserSpecification) (string, []interface{}) {
+	leftSQL, leftArgs := left.ToSQL()
+	rightSQL, rightArgs := right.ToSQL()
+
+	sql := fmt.Sprintf("(%s) %s (%s)", leftSQL, op, shiftPlaceholders(rightSQL, len(leftArgs)))
+	args := make([]interface{}, 0, len(leftArgs)+len(rightArgs))
+	args = append(args, leftArgs...)
+	args = append(args, rightArgs...)
 	return sql, args
 }
 
+// shiftPlaceholders renumbers every $n placeholder in sql to $(n+offset)
+func shiftPlaceholders(sql string, offset int) string {
+	if offset == 0 {
+		return sql
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(sql); i++ {
+		b.WriteByte(sql[i])
+		if sql[i] != '$' {
+			continue
+		}
+		j := i + 1
+		for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			continue
+		}
+		n, _ := strconv.Atoi(sql[i+1 : j])
+		b.WriteString(strconv.Itoa(n + offset))
+		i = j - 1
+	}
+	return b.String()
+}
+
 // Database setup functions
 
 // setupDatabase initializes the database schema
@@ -3,6 +3,9 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -340,6 +343,31 @@ func TestUserService(t *testing.T) {
 		assert.Zero(t, user.ID)
 		assert.Zero(t, post.ID)
 	})
+
+	t.Run("Post failure rolls back user", func(t *testing.T) {
+		user := &User{
+			Username: "orphanuser",
+			Email:    "orphan@example.com",
+			Created:  time.Now(),
+		}
+
+		// titleはVARCHAR(255)なので投稿のINSERTだけが失敗する
+		post := &Post{
+			Title:   strings.Repeat("x", 300),
+			Content: "Too long title",
+			Created: time.Now(),
+		}
+
+		err := service.CreateUserWithPost(ctx, user, post)
+		require.Error(t, err)
+		assert.Zero(t, user.ID)
+		assert.Zero(t, post.UserID)
+
+		var count int
+		err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE email = $1", "orphan@example.com").Scan(&count)
+		require.NoError(t, err)
+		assert.Zero(t, count, "user insert must be rolled back with the failed post")
+	})
 }
 
 // TestUnitOfWork tests the Unit of Work pattern
@@ -470,6 +498,65 @@ func TestSpecificationPattern(t *testing.T) {
 		assert.Contains(t, sql, "OR")
 		assert.Len(t, args, 3)
 	})
+
+	t.Run("And renders parenthesized fragments in argument order", func(t *testing.T) {
+		after := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		spec := AndSpec{
+			Left:  UserByEmailSpec{Email: "test@example.com"},
+			Right: UserCreatedAfterSpec{After: after},
+		}
+
+		sql, args := spec.ToSQL()
+
+		assert.Equal(t, "(email = $1) AND (created > $2)", sql)
+		assert.Equal(t, []interface{}{"test@example.com", after}, args)
+	})
+
+	t.Run("Nested specifications number placeholders sequentially", func(t *testing.T) {
+		after := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		spec := OrSpec{
+			Left: UserByEmailSpec{Email: "a@example.com"},
+			Right: AndSpec{
+				Left:  UserByEmailSpec{Email: "b@example.com"},
+				Right: UserCreatedAfterSpec{After: after},
+			},
+		}
+
+		sql, args := spec.ToSQL()
+
+		assert.Equal(t, "(email = $1) OR ((email = $2) AND (created > $3))", sql)
+		assert.Equal(t, []interface{}{"a@example.com", "b@example.com", after}, args)
+	})
+
+	t.Run("UserInIDsSpec", func(t *testing.T) {
+		sql, args := UserInIDsSpec{IDs: []int{3, 5, 8}}.ToSQL()
+
+		assert.Equal(t, "id IN ($1,$2,$3)", sql)
+		assert.Equal(t, []interface{}{3, 5, 8}, args)
+	})
+
+	t.Run("UserInIDsSpec with no IDs", func(t *testing.T) {
+		sql, args := UserInIDsSpec{}.ToSQL()
+
+		assert.Equal(t, "1=0", sql)
+		assert.Empty(t, args)
+
+		sql, args = AndSpec{Left: UserByEmailSpec{Email: "a@example.com"}, Right: UserInIDsSpec{}}.ToSQL()
+		assert.Equal(t, "(email = $1) AND (1=0)", sql)
+		assert.Equal(t, []interface{}{"a@example.com"}, args)
+	})
+
+	t.Run("Not of Or with InIDs", func(t *testing.T) {
+		spec := NotSpec{Inner: OrSpec{
+			Left:  UserByEmailSpec{Email: "test@example.com"},
+			Right: UserInIDsSpec{IDs: []int{1, 2}},
+		}}
+
+		sql, args := spec.ToSQL()
+
+		assert.Equal(t, "NOT ((email = $1) OR (id IN ($2,$3)))", sql)
+		assert.Equal(t, []interface{}{"test@example.com", 1, 2}, args)
+	})
 }
 
 // TestTransactionHandling tests transaction scenarios
@@ -547,6 +634,145 @@ func TestRepositoryErrorHandling(t *testing.T) {
 	})
 }
 
+// flakyUserRepository wraps a repository and fails every call while failing is set
+type flakyUserRepository struct {
+	UserRepository
+	failing atomic.Bool
+	failErr error // nilならerrDatabaseDown
+	calls   atomic.Int64
+}
+
+var errDatabaseDown = errors.New("database is down")
+
+func (f *flakyUserRepository) GetByID(ctx context.Context, id int) (*User, error) {
+	f.calls.Add(1)
+	if f.failing.Load() {
+		return nil, f.err()
+	}
+	return f.UserRepository.GetByID(ctx, id)
+}
+
+func (f *flakyUserRepository) Create(ctx context.Context, user *User) error {
+	f.calls.Add(1)
+	if f.failing.Load() {
+		return f.err()
+	}
+	return f.UserRepository.Create(ctx, user)
+}
+
+func (f *flakyUserRepository) err() error {
+	if f.failErr != nil {
+		return f.failErr
+	}
+	return errDatabaseDown
+}
+
+func (f *flakyUserRepository) WithTx(tx *sql.Tx) UserRepository {
+	return f
+}
+
+// TestResilientUserRepository tests the circuit-breaker-protected repository decorator
+func TestResilientUserRepository(t *testing.T) {
+	ctx := context.Background()
+	settings := CircuitBreakerSettings{
+		MaxFailures:  3,
+		ResetTimeout: 50 * time.Millisecond,
+	}
+
+	newRepo := func(t *testing.T) (*ResilientUserRepository, *flakyUserRepository, *User) {
+		inner := &flakyUserRepository{UserRepository: NewMockUserRepository()}
+		user := &User{Username: "alice", Email: "alice@example.com", Created: time.Now()}
+		require.NoError(t, inner.Create(ctx, user))
+		inner.calls.Store(0)
+		return NewResilientUserRepository(inner, settings), inner, user
+	}
+
+	t.Run("Opens after repeated failures and fast-fails", func(t *testing.T) {
+		repo, inner, user := newRepo(t)
+		inner.failing.Store(true)
+
+		for i := 0; i < settings.MaxFailures; i++ {
+			_, err := repo.GetByID(ctx, user.ID)
+			assert.ErrorIs(t, err, errDatabaseDown)
+		}
+		assert.Equal(t, StateOpen, repo.Breaker().State())
+		assert.Equal(t, int64(settings.MaxFailures), inner.calls.Load())
+
+		// ブレーカーは全メソッドで共有されるため、別メソッドも即座に失敗する
+		err := repo.Create(ctx, &User{Username: "bob", Email: "bob@example.com"})
+		var openErr *CircuitBreakerOpenError
+		require.ErrorAs(t, err, &openErr)
+		assert.Equal(t, StateOpen, openErr.State)
+
+		_, err = repo.GetByID(ctx, user.ID)
+		assert.ErrorAs(t, err, &openErr)
+		assert.Equal(t, int64(settings.MaxFailures), inner.calls.Load(), "open breaker must not reach the repository")
+	})
+
+	t.Run("Recovers after reset timeout", func(t *testing.T) {
+		repo, inner, user := newRepo(t)
+		inner.failing.Store(true)
+		for i := 0; i < settings.MaxFailures; i++ {
+			repo.GetByID(ctx, user.ID)
+		}
+		require.Equal(t, StateOpen, repo.Breaker().State())
+
+		inner.failing.Store(false)
+		time.Sleep(settings.ResetTimeout + 10*time.Millisecond)
+		assert.Equal(t, StateHalfOpen, repo.Breaker().State())
+
+		got, err := repo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.Username, got.Username)
+		assert.Equal(t, StateClosed, repo.Breaker().State())
+	})
+
+	t.Run("Half-open failure reopens", func(t *testing.T) {
+		repo, inner, user := newRepo(t)
+		inner.failing.Store(true)
+		for i := 0; i < settings.MaxFailures; i++ {
+			repo.GetByID(ctx, user.ID)
+		}
+
+		time.Sleep(settings.ResetTimeout + 10*time.Millisecond)
+		_, err := repo.GetByID(ctx, user.ID)
+		assert.ErrorIs(t, err, errDatabaseDown)
+		assert.Equal(t, StateOpen, repo.Breaker().State())
+	})
+
+	t.Run("Not found does not trip the breaker", func(t *testing.T) {
+		inner := &flakyUserRepository{UserRepository: NewMockUserRepository(), failErr: sql.ErrNoRows}
+		inner.failing.Store(true)
+		repo := NewResilientUserRepository(inner, CircuitBreakerSettings{
+			MaxFailures:  1,
+			ResetTimeout: time.Minute,
+		})
+
+		for i := 0; i < 3; i++ {
+			_, err := repo.GetByID(ctx, 999)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		}
+		assert.Equal(t, int64(3), inner.calls.Load())
+		assert.Equal(t, StateClosed, repo.Breaker().State())
+	})
+
+	t.Run("WithTx bypasses the breaker", func(t *testing.T) {
+		repo, inner, user := newRepo(t)
+		inner.failing.Store(true)
+		for i := 0; i < settings.MaxFailures; i++ {
+			repo.GetByID(ctx, user.ID)
+		}
+		require.Equal(t, StateOpen, repo.Breaker().State())
+
+		inner.failing.Store(false)
+		txRepo := repo.WithTx(nil)
+		got, err := txRepo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+		assert.Equal(t, StateOpen, repo.Breaker().State())
+	})
+}
+
 // Benchmark tests
 func BenchmarkUserRepository_Create(b *testing.B) {
 	repo := NewMockUserRepository()
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -66,117 +67,171 @@ func NewQueryAnalyzer(db *sql.DB) *QueryAnalyzer {
 func (qa *QueryAnalyzer) ExplainQuery(query string, args ...interface{}) ([]ExplainResult, error) {
 	explainQuery := "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) " + query
 
-	var jsonResult string
+	var jsonResult []byte
 	err := qa.db.QueryRow(explainQuery, args...).Scan(&jsonResult)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute EXPLAIN: %w", err)
 	}
 
-	var plans []map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonResult), &plans); err != nil {
+	return parseExplainJSON(jsonResult)
+}
+
+// explainNode は EXPLAIN (FORMAT JSON) の1ノードに対応する。
+// PostgreSQL 18 以降は行数を小数で返すため、数値はすべて float64 で受ける。
+type explainNode struct {
+	NodeType          string        `json:"Node Type"`
+	Relation          string        `json:"Relation Name"`
+	Alias             string        `json:"Alias"`
+	StartupCost       float64       `json:"Startup Cost"`
+	TotalCost         float64       `json:"Total Cost"`
+	PlanRows          float64       `json:"Plan Rows"`
+	PlanWidth         float64       `json:"Plan Width"`
+	ActualStartupTime float64       `json:"Actual Startup Time"`
+	ActualTotalTime   float64       `json:"Actual Total Time"`
+	ActualRows        float64       `json:"Actual Rows"`
+	IndexName         string        `json:"Index Name"`
+	IndexCondition    string        `json:"Index Cond"`
+	Filter            string        `json:"Filter"`
+	SharedHitBlocks   float64       `json:"Shared Hit Blocks"`  // EXPLAIN (BUFFERS) の共有バッファヒット数
+	SharedReadBlocks  float64       `json:"Shared Read Blocks"` // EXPLAIN (BUFFERS) の共有バッファ読み込み数
+	Plans             []explainNode `json:"Plans"`
+}
+
+// parseExplainJSON は EXPLAIN (FORMAT JSON) の出力をパースし、
+// プランツリーを親→子の順（深さ優先）に平坦化して返す
+func parseExplainJSON(data []byte) ([]ExplainResult, error) {
+	var plans []struct {
+		Plan *explainNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(data, &plans); err != nil {
 		return nil, fmt.Errorf("failed to parse EXPLAIN result: %w", err)
 	}
 
 	if len(plans) == 0 {
 		return nil, fmt.Errorf("empty EXPLAIN result")
 	}
-
-	plan, ok := plans[0]["Plan"].(map[string]interface{})
-	if !ok {
+	if plans[0].Plan == nil {
 		return nil, fmt.Errorf("invalid EXPLAIN result format")
 	}
 
-	return parseExplainNode(plan), nil
+	return flattenExplainNode(*plans[0].Plan, nil), nil
 }
 
-func parseExplainNode(node map[string]interface{}) []ExplainResult {
-	result := ExplainResult{}
-
-	if nodeType, ok := node["Node Type"].(string); ok {
-		result.NodeType = nodeType
-	}
-	if relation, ok := node["Relation Name"].(string); ok {
-		result.Relation = relation
-	}
-	if alias, ok := node["Alias"].(string); ok {
-		result.Alias = alias
-	}
-	if cost, ok := node["Startup Cost"].(float64); ok {
-		result.StartupCost = cost
-	}
-	if cost, ok := node["Total Cost"].(float64); ok {
-		result.TotalCost = cost
-	}
-	if rows, ok := node["Plan Rows"].(float64); ok {
-		result.PlanRows = int(rows)
-	}
-	if width, ok := node["Plan Width"].(float64); ok {
-		result.PlanWidth = int(width)
-	}
-	if time, ok := node["Actual Startup Time"].(float64); ok {
-		result.ActualStartupTime = time
-	}
-	if time, ok := node["Actual Total Time"].(float64); ok {
-		result.ActualTotalTime = time
-	}
-	if rows, ok := node["Actual Rows"].(float64); ok {
-		result.ActualRows = int(rows)
-	}
-	if indexName, ok := node["Index Name"].(string); ok {
-		result.IndexName = indexName
-	}
-	if indexCond, ok := node["Index Cond"].(string); ok {
-		result.IndexCondition = indexCond
-	}
-	if filter, ok := node["Filter"].(string); ok {
-		result.Filter = filter
-	}
-
-	results := []ExplainResult{result}
+func flattenExplainNode(node explainNode, results []ExplainResult) []ExplainResult {
+	results = append(results, ExplainResult{
+		NodeType:          node.NodeType,
+		Relation:          node.Relation,
+		Alias:             node.Alias,
+		StartupCost:       node.StartupCost,
+		TotalCost:         node.TotalCost,
+		PlanRows:          int(node.PlanRows),
+		PlanWidth:         int(node.PlanWidth),
+		ActualStartupTime: node.ActualStartupTime,
+		ActualTotalTime:   node.ActualTotalTime,
+		ActualRows:        int(node.ActualRows),
+		IndexName:         node.IndexName,
+		IndexCondition:    node.IndexCondition,
+		Filter:            node.Filter,
+		BuffersHit:        int(node.SharedHitBlocks),
+		BuffersRead:       int(node.SharedReadBlocks),
+	})
 
-	// Parse child plans
-	if plans, ok := node["Plans"].([]interface{}); ok {
-		for _, planInterface := range plans {
-			if childPlan, ok := planInterface.(map[string]interface{}); ok {
-				childResults := parseExplainNode(childPlan)
-				results = append(results, childResults...)
-			}
-		}
+	for _, child := range node.Plans {
+		results = flattenExplainNode(child, results)
 	}
 
 	return results
 }
 
-// AnalyzeQueryPlan analyzes the query execution plan
+// アンチパターン検出のしきい値
+const (
+	largeNestedLoopRows  = 10000 // これ以上の行を処理するNested Loopは結合方法を見直す
+	largeSeqScanRows     = 10000 // これ以上の行を読むSeq Scanは大きなテーブルとみなす
+	minBuffersForHitRate = 1000  // 少量のバッファアクセスではヒット率を評価しない
+	lowCacheHitRatio     = 0.9
+)
+
+// AnalyzeQueryPlan analyzes the query execution plan.
+// Both the flattened output of ExplainQuery and nested trees built through
+// ExplainResult.Plans are accepted.
 func (qa *QueryAnalyzer) AnalyzeQueryPlan(results []ExplainResult) QueryPlanAnalysis {
 	analysis := QueryPlanAnalysis{
 		Recommendations: make([]string, 0),
 	}
 
 	for _, result := range results {
-		analysis.TotalCost += result.TotalCost
-		analysis.ExecutionTime += result.ActualTotalTime
-		analysis.RowsProcessed += result.ActualRows
-		analysis.BuffersUsed += result.BuffersHit + result.BuffersRead
-
-		switch result.NodeType {
-		case "Seq Scan":
-			analysis.HasSeqScan = true
-			if result.ActualTotalTime > 10.0 {
-				analysis.Recommendations = append(analysis.Recommendations,
-					fmt.Sprintf("Consider adding index on table %s", result.Relation))
-			}
-		case "Index Scan", "Index Only Scan", "Bitmap Index Scan":
-			analysis.HasIndexScan = true
+		analyzePlanNode(&analysis, result)
+	}
+
+	return analysis
+}
+
+// analyzePlanNode は1ノード分の集計とアンチパターン検出を行い、子ノードへ再帰する
+func analyzePlanNode(analysis *QueryPlanAnalysis, result ExplainResult) {
+	analysis.TotalCost += result.TotalCost
+	analysis.ExecutionTime += result.ActualTotalTime
+	analysis.RowsProcessed += result.ActualRows
+	analysis.BuffersUsed += result.BuffersHit + result.BuffersRead
+
+	node := describePlanNode(result)
+	rows := planNodeRows(result)
+
+	switch result.NodeType {
+	case "Seq Scan":
+		analysis.HasSeqScan = true
+		if rows >= largeSeqScanRows {
+			analysis.Recommendations = append(analysis.Recommendations,
+				fmt.Sprintf("Sequential scan on large table in %s (%d rows): add an index matching the filter or limit the scanned rows", node, rows))
+		} else if result.ActualTotalTime > 10.0 {
+			analysis.Recommendations = append(analysis.Recommendations,
+				fmt.Sprintf("Consider adding index on table %s", result.Relation))
+		}
+	case "Index Scan", "Index Only Scan", "Bitmap Index Scan":
+		analysis.HasIndexScan = true
+	case "Nested Loop":
+		if rows >= largeNestedLoopRows {
+			analysis.Recommendations = append(analysis.Recommendations,
+				fmt.Sprintf("Nested loop join over large row count in %s (%d rows): index the inner join key or allow a hash/merge join", node, rows))
 		}
+	}
 
-		if result.Filter != "" && result.ActualTotalTime > 5.0 {
+	if result.Filter != "" && result.ActualTotalTime > 5.0 {
+		analysis.Recommendations = append(analysis.Recommendations,
+			fmt.Sprintf("Consider adding index for filter condition: %s", result.Filter))
+	}
+
+	buffers := result.BuffersHit + result.BuffersRead
+	if buffers >= minBuffersForHitRate {
+		hitRatio := float64(result.BuffersHit) / float64(buffers)
+		if hitRatio < lowCacheHitRatio {
 			analysis.Recommendations = append(analysis.Recommendations,
-				fmt.Sprintf("Consider adding index for filter condition: %s", result.Filter))
+				fmt.Sprintf("High buffer reads in %s (%d read, cache hit ratio %.1f%%): reduce the pages touched or increase shared_buffers", node, result.BuffersRead, hitRatio*100))
 		}
 	}
 
-	return analysis
+	for _, child := range result.Plans {
+		analyzePlanNode(analysis, child)
+	}
+}
+
+// describePlanNode はレコメンデーションで参照するノード名を返す（例: "Seq Scan on users u"）
+func describePlanNode(result ExplainResult) string {
+	if result.Relation == "" {
+		return result.NodeType
+	}
+	desc := result.NodeType + " on " + result.Relation
+	if result.Alias != "" && result.Alias != result.Relation {
+		desc += " " + result.Alias
+	}
+	return desc
+}
+
+// planNodeRows はANALYZE済みなら実際の行数を、そうでなければ推定行数を返す
+func planNodeRows(result ExplainResult) int {
+	if result.ActualRows > 0 {
+		return result.ActualRows
+	}
+	return result.PlanRows
 }
 
 // QueryPlanAnalysis holds query plan analysis results
@@ -226,49 +281,193 @@ func (ia *IndexAdvisor) AnalyzeQuery(query string, args ...interface{}) error {
 	}
 
 	ia.queries = append(ia.queries, query)
+	ia.recommendFromPlan(query, results)
+
+	return nil
+}
+
+// recommendFromPlan はプラン中の Seq Scan ごとに、クエリから抽出した
+// そのテーブルのカラムを使った複合インデックスを推奨する
+func (ia *IndexAdvisor) recommendFromPlan(query string, results []ExplainResult) {
+	columnsByTable := extractIndexColumns(query)
+
+	var visit func(result ExplainResult)
+	visit = func(result ExplainResult) {
+		if rec, ok := ia.recommendForScan(columnsByTable, result); ok {
+			ia.recommendations = append(ia.recommendations, rec)
+		}
+		for _, child := range result.Plans {
+			visit(child)
+		}
+	}
 
 	for _, result := range results {
-		if result.NodeType == "Seq Scan" && result.ActualTotalTime > 5.0 {
-			columns := ia.extractColumnsFromQuery(query, result.Relation)
-			if len(columns) > 0 {
-				recommendation := IndexRecommendation{
-					TableName:    result.Relation,
-					Columns:      columns,
-					IndexType:    "btree",
-					Reason:       "Sequential scan detected on large table",
-					ExpectedGain: result.ActualTotalTime * 0.7,
-					Priority:     ia.calculatePriority(result.ActualTotalTime),
-				}
-				ia.recommendations = append(ia.recommendations, recommendation)
+		visit(result)
+	}
+}
+
+func (ia *IndexAdvisor) recommendForScan(columnsByTable map[string]*queryColumns, result ExplainResult) (IndexRecommendation, bool) {
+	if result.NodeType != "Seq Scan" {
+		return IndexRecommendation{}, false
+	}
+	qc, ok := columnsByTable[strings.ToLower(result.Relation)]
+	if !ok {
+		return IndexRecommendation{}, false
+	}
+	columns := qc.ordered()
+	if len(columns) == 0 {
+		return IndexRecommendation{}, false
+	}
+
+	// 小さなテーブルをフィルタなしで全件読むだけならインデックスは不要
+	rows := planNodeRows(result)
+	if result.Filter == "" && rows < largeSeqScanRows && result.ActualTotalTime <= 5.0 {
+		return IndexRecommendation{}, false
+	}
+
+	priority := ia.calculatePriority(result.ActualTotalTime)
+	if rows >= largeSeqScanRows && priority > 2 {
+		priority = 2
+	}
+
+	return IndexRecommendation{
+		TableName:    result.Relation,
+		Columns:      columns,
+		IndexType:    "btree",
+		Reason:       fmt.Sprintf("Sequential scan on %s (%d rows) %s", result.Relation, rows, qc.describe()),
+		ExpectedGain: result.ActualTotalTime * 0.7,
+		Priority:     priority,
+	}, true
+}
+
+// queryColumns はインデックス候補となるカラムを用途ごとに保持する
+type queryColumns struct {
+	equality []string // WHERE の =, IN, IS
+	join     []string // JOIN ... ON の結合キー
+	ranges   []string // WHERE の <, >, BETWEEN, LIKE など
+	order    []string // ORDER BY
+}
+
+// ordered は複合インデックスのカラム順（等価条件 → 結合キー → 範囲条件 → ソート）で重複なく返す
+func (qc *queryColumns) ordered() []string {
+	seen := make(map[string]bool)
+	columns := make([]string, 0)
+	for _, group := range [][]string{qc.equality, qc.join, qc.ranges, qc.order} {
+		for _, col := range group {
+			if !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
 			}
 		}
 	}
+	return columns
+}
+
+func (qc *queryColumns) describe() string {
+	parts := make([]string, 0, 3)
+	if filters := append(append([]string{}, qc.equality...), qc.ranges...); len(filters) > 0 {
+		parts = append(parts, "filtering by "+strings.Join(filters, ", "))
+	}
+	if len(qc.join) > 0 {
+		parts = append(parts, "joining on "+strings.Join(qc.join, ", "))
+	}
+	if len(qc.order) > 0 {
+		parts = append(parts, "sorting by "+strings.Join(qc.order, ", "))
+	}
+	return strings.Join(parts, " and ")
+}
 
-	return nil
+var (
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlTableRef      = regexp.MustCompile(`\b(?:from|join)\s+([a-z_]\w*)(?:\s+(?:as\s+)?([a-z_]\w*))?`)
+	sqlWhereClause   = regexp.MustCompile(`\bwhere\b(.*?)(?:\bgroup\s+by\b|\border\s+by\b|\bhaving\b|\blimit\b|\boffset\b|$)`)
+	sqlOnClause      = regexp.MustCompile(`\bon\b(.*?)(?:\b(?:left|right|inner|full|cross|join|where|group|order|limit)\b|$)`)
+	sqlOrderByClause = regexp.MustCompile(`\border\s+by\b(.*?)(?:\blimit\b|\boffset\b|\bfor\b|$)`)
+	sqlPredicate     = regexp.MustCompile(`(?:\b([a-z_]\w*)\.)?\b([a-z_]\w*)\s*(<>|!=|<=|>=|=|<|>|\bnot\s+in\b|\bnot\s+i?like\b|\bin\b|\bi?like\b|\bbetween\b|\bis\b)`)
+	sqlJoinCondition = regexp.MustCompile(`(?:\b([a-z_]\w*)\.)?\b([a-z_]\w*)\s*=\s*(?:\b([a-z_]\w*)\.)?\b([a-z_]\w*)`)
+	sqlIdentifier    = regexp.MustCompile(`^(?:([a-z_]\w*)\.)?([a-z_]\w*)$`)
+)
+
+// sqlKeywords はテーブル別名やカラムと誤認しないキーワード
+var sqlKeywords = map[string]bool{
+	"where": true, "on": true, "join": true, "left": true, "right": true, "inner": true,
+	"full": true, "cross": true, "outer": true, "group": true, "order": true, "limit": true,
+	"offset": true, "having": true, "and": true, "or": true, "not": true, "using": true,
 }
 
-func (ia *IndexAdvisor) extractColumnsFromQuery(query, tableName string) []string {
-	query = strings.ToLower(query)
-	tableName = strings.ToLower(tableName)
+// extractIndexColumns は WHERE / JOIN ON / ORDER BY からテーブルごとのカラムを抽出する。
+// サブクエリや関数呼び出しは扱わない簡易的な解析で、修飾されていないカラムは FROM の最初のテーブルに属するものとみなす。
+func extractIndexColumns(query string) map[string]*queryColumns {
+	q := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	q = sqlStringLiteral.ReplaceAllString(q, "?")
+
+	tables := make(map[string]string) // 別名 → テーブル名
+	defaultTable := ""
+	for _, m := range sqlTableRef.FindAllStringSubmatch(q, -1) {
+		tables[m[1]] = m[1]
+		if m[2] != "" && !sqlKeywords[m[2]] {
+			tables[m[2]] = m[1]
+		}
+		if defaultTable == "" {
+			defaultTable = m[1]
+		}
+	}
 
-	// Simple pattern matching for WHERE clauses
-	whereIndex := strings.Index(query, "where")
-	if whereIndex == -1 {
-		return []string{}
+	result := make(map[string]*queryColumns)
+	add := func(qualifier, column string, group func(*queryColumns) *[]string) {
+		if sqlKeywords[column] {
+			return
+		}
+		table := defaultTable
+		if qualifier != "" {
+			table = tables[qualifier]
+		}
+		if table == "" {
+			return
+		}
+		if result[table] == nil {
+			result[table] = &queryColumns{}
+		}
+		list := group(result[table])
+		*list = append(*list, column)
+	}
+	equality := func(qc *queryColumns) *[]string { return &qc.equality }
+	join := func(qc *queryColumns) *[]string { return &qc.join }
+	ranges := func(qc *queryColumns) *[]string { return &qc.ranges }
+	order := func(qc *queryColumns) *[]string { return &qc.order }
+
+	for _, where := range sqlWhereClause.FindAllStringSubmatch(q, -1) {
+		for _, m := range sqlPredicate.FindAllStringSubmatch(where[1], -1) {
+			switch m[3] {
+			case "=", "in", "is":
+				add(m[1], m[2], equality)
+			case "<", ">", "<=", ">=", "between", "like", "ilike":
+				add(m[1], m[2], ranges)
+			}
+			// <>, !=, NOT IN, NOT LIKE はインデックスで絞り込めないため無視する
+		}
 	}
 
-	whereClause := query[whereIndex:]
-	columns := make([]string, 0)
+	for _, on := range sqlOnClause.FindAllStringSubmatch(q, -1) {
+		for _, m := range sqlJoinCondition.FindAllStringSubmatch(on[1], -1) {
+			add(m[1], m[2], join)
+			add(m[3], m[4], join)
+		}
+	}
 
-	// Look for common column patterns
-	commonColumns := []string{"id", "email", "user_id", "created_at", "status", "name", "age", "city"}
-	for _, col := range commonColumns {
-		if strings.Contains(whereClause, col) {
-			columns = append(columns, col)
+	for _, orderBy := range sqlOrderByClause.FindAllStringSubmatch(q, -1) {
+		for _, item := range strings.Split(orderBy[1], ",") {
+			fields := strings.Fields(item)
+			if len(fields) == 0 {
+				continue
+			}
+			if m := sqlIdentifier.FindStringSubmatch(fields[0]); m != nil {
+				add(m[1], m[2], order)
+			}
 		}
 	}
 
-	return columns
+	return result
 }
 
 func (ia *IndexAdvisor) calculatePriority(executionTime float64) int {
@@ -280,19 +479,52 @@ func (ia *IndexAdvisor) calculatePriority(executionTime float64) int {
 	return 3 // Low priority
 }
 
-// GetRecommendations returns all index recommendations
+// GetRecommendations returns all index recommendations.
+// Recommendations for the same table/columns are merged, and the result is
+// sorted by priority (1 = highest) then by expected gain.
 func (ia *IndexAdvisor) GetRecommendations() []IndexRecommendation {
-	// Sort by priority
-	recommendations := make([]IndexRecommendation, len(ia.recommendations))
-	copy(recommendations, ia.recommendations)
+	recommendations := mergeRecommendations(ia.recommendations)
 
-	sort.Slice(recommendations, func(i, j int) bool {
-		return recommendations[i].Priority < recommendations[j].Priority
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Priority != recommendations[j].Priority {
+			return recommendations[i].Priority < recommendations[j].Priority
+		}
+		return recommendations[i].ExpectedGain > recommendations[j].ExpectedGain
 	})
 
 	return recommendations
 }
 
+// mergeRecommendations combines recommendations targeting the same table and columns.
+// Expected gains are summed, and each duplicate raises the priority by one level.
+func mergeRecommendations(recs []IndexRecommendation) []IndexRecommendation {
+	merged := make([]IndexRecommendation, 0, len(recs))
+	indexByKey := make(map[string]int)
+
+	for _, rec := range recs {
+		key := strings.ToLower(rec.TableName) + "(" + strings.ToLower(strings.Join(rec.Columns, ",")) + ")"
+
+		i, exists := indexByKey[key]
+		if !exists {
+			rec.Columns = append([]string(nil), rec.Columns...)
+			indexByKey[key] = len(merged)
+			merged = append(merged, rec)
+			continue
+		}
+
+		existing := &merged[i]
+		existing.ExpectedGain += rec.ExpectedGain
+		if rec.Priority < existing.Priority {
+			existing.Priority = rec.Priority
+		}
+		if existing.Priority > 1 {
+			existing.Priority--
+		}
+	}
+
+	return merged
+}
+
 // GenerateIndexSQL generates SQL statements to create recommended indexes
 func (ia *IndexAdvisor) GenerateIndexSQL() []string {
 	recommendations := ia.GetRecommendations()
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"math"
 	"strings"
 	"testing"
 
@@ -146,6 +147,256 @@ func TestQueryAnalyzer_AnalyzeQueryPlan(t *testing.T) {
 	}
 }
 
+func TestQueryAnalyzer_AnalyzeQueryPlanAntiPatterns(t *testing.T) {
+	analyzer := NewQueryAnalyzer(nil)
+
+	tests := []struct {
+		name    string
+		plan    []ExplainResult
+		want    string
+		wantRef string
+	}{
+		{
+			name: "nested loop over large row count",
+			plan: []ExplainResult{
+				{NodeType: "Nested Loop", TotalCost: 52000, ActualRows: 250000, ActualTotalTime: 320},
+				{NodeType: "Seq Scan", Relation: "users", Alias: "u", PlanRows: 1000, ActualRows: 1000},
+				{NodeType: "Index Scan", Relation: "orders", Alias: "o", IndexName: "idx_orders_user_id", ActualRows: 250},
+			},
+			want:    "Nested loop join over large row count",
+			wantRef: "Nested Loop (250000 rows)",
+		},
+		{
+			name: "sequential scan on big table",
+			plan: []ExplainResult{
+				{NodeType: "Seq Scan", Relation: "orders", Alias: "o", TotalCost: 18000, PlanRows: 500000, Filter: "(status = 'pending'::text)"},
+			},
+			want:    "Sequential scan on large table",
+			wantRef: "Seq Scan on orders o",
+		},
+		{
+			name: "low cache hit ratio",
+			plan: []ExplainResult{
+				{NodeType: "Index Scan", Relation: "users", IndexName: "idx_users_city", ActualRows: 800, BuffersHit: 200, BuffersRead: 4800},
+			},
+			want:    "High buffer reads",
+			wantRef: "Index Scan on users (4800 read, cache hit ratio 4.0%)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := analyzer.AnalyzeQueryPlan(tt.plan)
+
+			found := false
+			for _, rec := range analysis.Recommendations {
+				if strings.Contains(rec, tt.want) {
+					found = true
+					if !strings.Contains(rec, tt.wantRef) {
+						t.Errorf("Expected recommendation to reference %q, got %q", tt.wantRef, rec)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("Expected recommendation containing %q, got %v", tt.want, analysis.Recommendations)
+			}
+		})
+	}
+
+	t.Run("healthy plan", func(t *testing.T) {
+		plan := []ExplainResult{
+			{NodeType: "Nested Loop", ActualRows: 20},
+			{NodeType: "Index Scan", Relation: "users", IndexName: "users_pkey", ActualRows: 1, BuffersHit: 3000, BuffersRead: 10},
+			{NodeType: "Seq Scan", Relation: "cities", PlanRows: 47, ActualRows: 47},
+		}
+
+		analysis := analyzer.AnalyzeQueryPlan(plan)
+		if len(analysis.Recommendations) != 0 {
+			t.Errorf("Expected no recommendations, got %v", analysis.Recommendations)
+		}
+		if analysis.BuffersUsed != 3010 {
+			t.Errorf("Expected 3010 buffers used, got %d", analysis.BuffersUsed)
+		}
+	})
+}
+
+// explainFixture は以下のクエリに対する EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) の出力
+//
+//	SELECT u.name, o.amount FROM orders o JOIN users u ON u.id = o.user_id WHERE o.status = 'pending'
+const explainFixture = `[
+  {
+    "Plan": {
+      "Node Type": "Hash Join",
+      "Parallel Aware": false,
+      "Join Type": "Inner",
+      "Startup Cost": 38.50,
+      "Total Cost": 1290.42,
+      "Plan Rows": 12480,
+      "Plan Width": 22,
+      "Actual Startup Time": 0.610,
+      "Actual Total Time": 21.370,
+      "Actual Rows": 12500,
+      "Actual Loops": 1,
+      "Inner Unique": true,
+      "Hash Cond": "(o.user_id = u.id)",
+      "Shared Hit Blocks": 128,
+      "Shared Read Blocks": 1380,
+      "Plans": [
+        {
+          "Node Type": "Seq Scan",
+          "Parent Relationship": "Outer",
+          "Parallel Aware": false,
+          "Relation Name": "orders",
+          "Alias": "o",
+          "Startup Cost": 0.00,
+          "Total Cost": 1104.00,
+          "Plan Rows": 12480,
+          "Plan Width": 14,
+          "Actual Startup Time": 0.012,
+          "Actual Total Time": 14.800,
+          "Actual Rows": 12500,
+          "Actual Loops": 1,
+          "Filter": "((status)::text = 'pending'::text)",
+          "Rows Removed by Filter": 37500,
+          "Shared Hit Blocks": 100,
+          "Shared Read Blocks": 1380
+        },
+        {
+          "Node Type": "Hash",
+          "Parent Relationship": "Inner",
+          "Parallel Aware": false,
+          "Startup Cost": 26.00,
+          "Total Cost": 26.00,
+          "Plan Rows": 1000,
+          "Plan Width": 16,
+          "Actual Startup Time": 0.450,
+          "Actual Total Time": 0.450,
+          "Actual Rows": 1000,
+          "Actual Loops": 1,
+          "Hash Buckets": 1024,
+          "Shared Hit Blocks": 28,
+          "Shared Read Blocks": 0,
+          "Plans": [
+            {
+              "Node Type": "Index Scan",
+              "Parent Relationship": "Outer",
+              "Parallel Aware": false,
+              "Scan Direction": "Forward",
+              "Index Name": "users_pkey",
+              "Relation Name": "users",
+              "Alias": "u",
+              "Startup Cost": 0.28,
+              "Total Cost": 24.28,
+              "Plan Rows": 1000,
+              "Plan Width": 16,
+              "Actual Startup Time": 0.008,
+              "Actual Total Time": 0.290,
+              "Actual Rows": 1000,
+              "Actual Loops": 1,
+              "Shared Hit Blocks": 28,
+              "Shared Read Blocks": 0
+            }
+          ]
+        }
+      ]
+    },
+    "Planning Time": 0.215,
+    "Triggers": [],
+    "Execution Time": 22.104
+  }
+]`
+
+func TestQueryAnalyzer_ExplainFixture(t *testing.T) {
+	results, err := parseExplainJSON([]byte(explainFixture))
+	if err != nil {
+		t.Fatalf("Failed to parse EXPLAIN fixture: %v", err)
+	}
+
+	// プランツリーは親→子の順に平坦化される
+	wantNodes := []string{"Hash Join", "Seq Scan", "Hash", "Index Scan"}
+	if len(results) != len(wantNodes) {
+		t.Fatalf("Expected %d plan nodes, got %d: %+v", len(wantNodes), len(results), results)
+	}
+	for i, want := range wantNodes {
+		if results[i].NodeType != want {
+			t.Errorf("Node %d: expected %q, got %q", i, want, results[i].NodeType)
+		}
+	}
+
+	scan := results[1]
+	if scan.Relation != "orders" || scan.Alias != "o" || scan.ActualRows != 12500 {
+		t.Errorf("Unexpected Seq Scan node: %+v", scan)
+	}
+	if scan.BuffersHit != 100 || scan.BuffersRead != 1380 {
+		t.Errorf("Expected buffers 100 hit / 1380 read, got %d / %d", scan.BuffersHit, scan.BuffersRead)
+	}
+	if results[3].IndexName != "users_pkey" {
+		t.Errorf("Expected index users_pkey, got %q", results[3].IndexName)
+	}
+
+	analyzer := NewQueryAnalyzer(nil)
+	analysis := analyzer.AnalyzeQueryPlan(results)
+
+	if !analysis.HasSeqScan || !analysis.HasIndexScan {
+		t.Errorf("Expected both seq scan and index scan, got seq=%v index=%v", analysis.HasSeqScan, analysis.HasIndexScan)
+	}
+	if math.Abs(analysis.TotalCost-2444.70) > 1e-6 {
+		t.Errorf("Expected total cost 2444.70, got %.2f", analysis.TotalCost)
+	}
+	if analysis.RowsProcessed != 27000 {
+		t.Errorf("Expected 27000 rows processed, got %d", analysis.RowsProcessed)
+	}
+	if analysis.BuffersUsed != 3044 {
+		t.Errorf("Expected 3044 buffers used, got %d", analysis.BuffersUsed)
+	}
+
+	wantRecommendations := []string{
+		"Sequential scan on large table in Seq Scan on orders o (12500 rows)",
+		"Consider adding index for filter condition: ((status)::text = 'pending'::text)",
+		"High buffer reads in Seq Scan on orders o (1380 read, cache hit ratio 6.8%)",
+	}
+	for _, want := range wantRecommendations {
+		found := false
+		for _, rec := range analysis.Recommendations {
+			if strings.Contains(rec, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected recommendation containing %q, got %v", want, analysis.Recommendations)
+		}
+	}
+	for _, rec := range analysis.Recommendations {
+		if strings.Contains(rec, "users") {
+			t.Errorf("Index scan on users should not be flagged: %q", rec)
+		}
+	}
+
+	t.Run("nested tree", func(t *testing.T) {
+		root := results[0]
+		hash := results[2]
+		hash.Plans = []ExplainResult{results[3]}
+		root.Plans = []ExplainResult{results[1], hash}
+
+		nested := analyzer.AnalyzeQueryPlan([]ExplainResult{root})
+		if nested.TotalCost != analysis.TotalCost || nested.RowsProcessed != analysis.RowsProcessed || nested.BuffersUsed != analysis.BuffersUsed {
+			t.Errorf("Nested analysis differs from flattened: %+v vs %+v", nested, analysis)
+		}
+		if strings.Join(nested.Recommendations, "\n") != strings.Join(analysis.Recommendations, "\n") {
+			t.Errorf("Expected same recommendations, got %v vs %v", nested.Recommendations, analysis.Recommendations)
+		}
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		for _, input := range []string{`not json`, `[]`, `[{"Execution Time": 1.0}]`} {
+			if _, err := parseExplainJSON([]byte(input)); err == nil {
+				t.Errorf("Expected error for %q", input)
+			}
+		}
+	})
+}
+
 func TestIndexAdvisor_Recommendations(t *testing.T) {
 	if testDB == nil {
 		t.Skip("Database not available")
@@ -198,6 +449,198 @@ func TestIndexAdvisor_Recommendations(t *testing.T) {
 	}
 }
 
+func TestIndexAdvisor_MergeRecommendations(t *testing.T) {
+	advisor := NewIndexAdvisor(nil)
+
+	// Recommendations as AnalyzeQuery would accumulate them over several queries
+	advisor.recommendations = []IndexRecommendation{
+		{TableName: "users", Columns: []string{"email"}, IndexType: "btree", ExpectedGain: 10, Priority: 3},
+		{TableName: "orders", Columns: []string{"user_id", "status"}, IndexType: "btree", ExpectedGain: 40, Priority: 2},
+		{TableName: "users", Columns: []string{"city"}, IndexType: "btree", ExpectedGain: 50, Priority: 3},
+		{TableName: "users", Columns: []string{"email"}, IndexType: "btree", ExpectedGain: 12, Priority: 3},
+		{TableName: "orders", Columns: []string{"user_id"}, IndexType: "btree", ExpectedGain: 60, Priority: 1},
+		{TableName: "Users", Columns: []string{"Email"}, IndexType: "btree", ExpectedGain: 8, Priority: 3},
+	}
+
+	recommendations := advisor.GetRecommendations()
+
+	expected := []struct {
+		table    string
+		columns  string
+		gain     float64
+		priority int
+	}{
+		{"orders", "user_id", 60, 1},
+		{"users", "email", 30, 1}, // 3回出現: 3 → 2 → 1
+		{"orders", "user_id,status", 40, 2},
+		{"users", "city", 50, 3},
+	}
+
+	if len(recommendations) != len(expected) {
+		t.Fatalf("Expected %d merged recommendations, got %d: %+v", len(expected), len(recommendations), recommendations)
+	}
+
+	for i, want := range expected {
+		got := recommendations[i]
+		if got.TableName != want.table || strings.Join(got.Columns, ",") != want.columns {
+			t.Errorf("Position %d: expected %s(%s), got %s(%s)", i, want.table, want.columns, got.TableName, strings.Join(got.Columns, ","))
+			continue
+		}
+		if got.ExpectedGain != want.gain {
+			t.Errorf("Position %d: expected gain %.1f, got %.1f", i, want.gain, got.ExpectedGain)
+		}
+		if got.Priority != want.priority {
+			t.Errorf("Position %d: expected priority %d, got %d", i, want.priority, got.Priority)
+		}
+	}
+
+	// Merging must not mutate the advisor's accumulated recommendations
+	if len(advisor.recommendations) != 6 {
+		t.Errorf("Expected 6 raw recommendations to remain, got %d", len(advisor.recommendations))
+	}
+}
+
+func TestExtractIndexColumns(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string
+	}{
+		{
+			name:  "filter and sort",
+			query: "SELECT * FROM users WHERE city = $1 ORDER BY created_at",
+			want:  map[string]string{"users": "city,created_at"},
+		},
+		{
+			name:  "equality before range",
+			query: "SELECT id FROM users WHERE age > 30 AND city IN ('Tokyo', 'Osaka') ORDER BY name DESC LIMIT 10",
+			want:  map[string]string{"users": "city,age,name"},
+		},
+		{
+			name:  "join with aliases",
+			query: "SELECT u.name, o.amount FROM orders o JOIN users AS u ON u.id = o.user_id WHERE o.status = 'pending' ORDER BY o.created_at DESC",
+			want: map[string]string{
+				"orders": "status,user_id,created_at",
+				"users":  "id",
+			},
+		},
+		{
+			name:  "string literals and negations are ignored",
+			query: "SELECT * FROM orders WHERE status <> 'amount = 1' AND created_at BETWEEN $1 AND $2",
+			want:  map[string]string{"orders": "created_at"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractIndexColumns(tt.query)
+			if len(got) != len(tt.want) {
+				t.Errorf("Expected tables %v, got %d tables", tt.want, len(got))
+			}
+			for table, want := range tt.want {
+				qc, ok := got[table]
+				if !ok {
+					t.Errorf("Expected columns for table %s", table)
+					continue
+				}
+				if cols := strings.Join(qc.ordered(), ","); cols != want {
+					t.Errorf("Table %s: expected columns %s, got %s", table, want, cols)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexAdvisor_RecommendFromPlan(t *testing.T) {
+	query := "SELECT * FROM users WHERE city = $1 ORDER BY created_at"
+	seqScanPlan := []ExplainResult{
+		{NodeType: "Sort", TotalCost: 2100, ActualRows: 2000, ActualTotalTime: 64},
+		{NodeType: "Seq Scan", Relation: "users", TotalCost: 1800, PlanRows: 2000, ActualRows: 2000, ActualTotalTime: 60, Filter: "((city)::text = 'Tokyo'::text)"},
+	}
+
+	t.Run("composite index for seq scan", func(t *testing.T) {
+		advisor := NewIndexAdvisor(nil)
+		advisor.recommendFromPlan(query, seqScanPlan)
+
+		recommendations := advisor.GetRecommendations()
+		if len(recommendations) != 1 {
+			t.Fatalf("Expected 1 recommendation, got %+v", recommendations)
+		}
+
+		rec := recommendations[0]
+		if rec.TableName != "users" || strings.Join(rec.Columns, ",") != "city,created_at" {
+			t.Errorf("Expected users(city, created_at), got %s(%s)", rec.TableName, strings.Join(rec.Columns, ", "))
+		}
+		if rec.IndexType != "btree" {
+			t.Errorf("Expected btree index, got %s", rec.IndexType)
+		}
+		if rec.Priority != 1 {
+			t.Errorf("Expected priority 1, got %d", rec.Priority)
+		}
+		if math.Abs(rec.ExpectedGain-42) > 1e-9 {
+			t.Errorf("Expected gain 42.0, got %.2f", rec.ExpectedGain)
+		}
+		wantReason := "Sequential scan on users (2000 rows) filtering by city and sorting by created_at"
+		if rec.Reason != wantReason {
+			t.Errorf("Expected reason %q, got %q", wantReason, rec.Reason)
+		}
+
+		sqlStatements := advisor.GenerateIndexSQL()
+		want := "CREATE INDEX idx_users_city_created_at ON users(city, created_at);"
+		if len(sqlStatements) != 1 || sqlStatements[0] != want {
+			t.Errorf("Expected %q, got %v", want, sqlStatements)
+		}
+	})
+
+	t.Run("nested plan", func(t *testing.T) {
+		root := seqScanPlan[0]
+		root.Plans = []ExplainResult{seqScanPlan[1]}
+
+		advisor := NewIndexAdvisor(nil)
+		advisor.recommendFromPlan(query, []ExplainResult{root})
+		if len(advisor.GetRecommendations()) != 1 {
+			t.Errorf("Expected seq scan in child plan to be found, got %+v", advisor.GetRecommendations())
+		}
+	})
+
+	t.Run("large table without analyze timings", func(t *testing.T) {
+		advisor := NewIndexAdvisor(nil)
+		advisor.recommendFromPlan("SELECT * FROM orders ORDER BY created_at", []ExplainResult{
+			{NodeType: "Seq Scan", Relation: "orders", PlanRows: 500000},
+		})
+
+		recommendations := advisor.GetRecommendations()
+		if len(recommendations) != 1 || recommendations[0].Priority != 2 {
+			t.Fatalf("Expected one priority 2 recommendation, got %+v", recommendations)
+		}
+		if strings.Join(recommendations[0].Columns, ",") != "created_at" {
+			t.Errorf("Expected created_at index, got %v", recommendations[0].Columns)
+		}
+	})
+
+	t.Run("no recommendation", func(t *testing.T) {
+		plans := map[string][]ExplainResult{
+			"index scan": {
+				{NodeType: "Index Scan", Relation: "users", IndexName: "idx_users_city", ActualRows: 20, ActualTotalTime: 0.1},
+			},
+			"small unfiltered scan": {
+				{NodeType: "Seq Scan", Relation: "users", PlanRows: 40, ActualRows: 40, ActualTotalTime: 0.05},
+			},
+			"scan on other table": {
+				{NodeType: "Seq Scan", Relation: "orders", PlanRows: 50000, Filter: "(amount > 100)", ActualTotalTime: 30},
+			},
+		}
+
+		for name, plan := range plans {
+			advisor := NewIndexAdvisor(nil)
+			advisor.recommendFromPlan(query, plan)
+			if recs := advisor.GetRecommendations(); len(recs) != 0 {
+				t.Errorf("%s: expected no recommendations, got %+v", name, recs)
+			}
+		}
+	})
+}
+
 func TestIndexAdvisor_GenerateIndexSQL(t *testing.T) {
 	if testDB == nil {
 		t.Skip("Database not available")
@@ -3,7 +3,14 @@
 package main
 
 import (
+	"context"
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -55,19 +62,105 @@ type JSONB map[string]interface{}
 
 // Scan implements the sql.Scanner interface
 func (j *JSONB) Scan(value interface{}) error {
-	// TODO: JSONB型のスキャンを実装
-	panic("Not yet implemented")
+	if j == nil {
+		return errors.New("cannot scan into nil *JSONB")
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		// SQL NULL
+		*j = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONB", value)
+	}
+
+	if len(data) == 0 {
+		*j = nil
+		return nil
+	}
+
+	result := make(map[string]interface{})
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal JSONB: %w", err)
+	}
+
+	*j = result
+	return nil
 }
 
 // Value implements the driver.Valuer interface
 func (j JSONB) Value() (driver.Value, error) {
-	// TODO: JSONB型の値変換を実装
+	if j == nil {
+		return nil, nil
+	}
+	return json.Marshal(j)
+}
+
+// StatementCache lazily prepares statements and reuses them by query string.
+// Cached statements belong to the connection pool; use PrepareTx/PrepareNamedTx
+// to obtain a transaction-bound copy instead of reusing them inside a Tx.
+type StatementCache struct {
+	db       *sqlx.DB
+	mu       sync.Mutex
+	stmts    map[string]*sqlx.Stmt
+	named    map[string]*sqlx.NamedStmt
+	prepares int
+}
+
+// NewStatementCache creates a new statement cache
+func NewStatementCache(db *sqlx.DB) *StatementCache {
+	// TODO: StatementCacheを初期化
+	panic("Not yet implemented")
+}
+
+// Prepare returns the cached statement for query, preparing it on first use
+func (sc *StatementCache) Prepare(query string) (*sqlx.Stmt, error) {
+	// TODO: キャッシュになければPreparexしてキャッシュ
+	panic("Not yet implemented")
+}
+
+// PrepareNamed returns the cached named statement for query, preparing it on first use
+func (sc *StatementCache) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	// TODO: キャッシュになければPrepareNamedしてキャッシュ
+	panic("Not yet implemented")
+}
+
+// PrepareTx returns a transaction-specific copy of the cached statement.
+// The returned statement is closed automatically when tx commits or rolls back.
+func (sc *StatementCache) PrepareTx(tx *sqlx.Tx, query string) (*sqlx.Stmt, error) {
+	// TODO: キャッシュ済みの文をtx.Stmtxでトランザクションに束縛
+	panic("Not yet implemented")
+}
+
+// PrepareNamedTx returns a transaction-specific copy of the cached named statement.
+// The returned statement is closed automatically when tx commits or rolls back.
+func (sc *StatementCache) PrepareNamedTx(tx *sqlx.Tx, query string) (*sqlx.NamedStmt, error) {
+	// TODO: キャッシュ済みの名前付き文をtx.NamedStmtでトランザクションに束縛
+	panic("Not yet implemented")
+}
+
+// PrepareCount returns how many statements have been prepared against the database
+func (sc *StatementCache) PrepareCount() int {
+	// TODO: これまでに準備した文の数を返す
+	panic("Not yet implemented")
+}
+
+// Close closes every cached statement and empties the cache
+func (sc *StatementCache) Close() error {
+	// TODO: 全ての文を閉じてエラーを集約
 	panic("Not yet implemented")
 }
 
 // UserRepository handles user database operations
 type UserRepository struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	stmts *StatementCache
 }
 
 // NewUserRepository creates a new user repository
@@ -76,6 +169,12 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 	panic("Not yet implemented")
 }
 
+// Close releases the repository's prepared statements
+func (ur *UserRepository) Close() error {
+	// TODO: StatementCacheを閉じる
+	panic("Not yet implemented")
+}
+
 // GetByID retrieves a user by ID
 func (ur *UserRepository) GetByID(id int) (*User, error) {
 	// TODO: IDでユーザーを取得
@@ -118,15 +217,62 @@ func (ur *UserRepository) Delete(id int) error {
 	panic("Not yet implemented")
 }
 
-// BatchInsert inserts multiple users efficiently
+// batchInsertSize is the number of rows per INSERT statement.
+// Postgres allows at most 65535 bound parameters per statement (4 per user here).
+const batchInsertSize = 1000
+
+// BatchInsert inserts multiple users with multi-row INSERT statements.
+// All chunks run in a single transaction, so any failure rolls back every row.
 func (ur *UserRepository) BatchInsert(users []User) error {
-	// TODO: 複数ユーザーの効率的な一括挿入
-	panic("Not yet implemented")
+	if len(users) == 0 {
+		return nil
+	}
+
+	tx, err := ur.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(users); start += batchInsertSize {
+		end := start + batchInsertSize
+		if end > len(users) {
+			end = len(users)
+		}
+
+		query, args := buildBatchInsertQuery(users[start:end])
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("batch insert rows %d-%d: %w", start, end-1, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// buildBatchInsertQuery builds INSERT INTO users ... VALUES ($1, $2, $3, $4), ($5, ...)
+func buildBatchInsertQuery(users []User) (string, []interface{}) {
+	const columns = 4
+
+	var query strings.Builder
+	query.WriteString("INSERT INTO users (name, email, age, city) VALUES ")
+
+	args := make([]interface{}, 0, len(users)*columns)
+	for i, user := range users {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := i * columns
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4)
+		args = append(args, user.Name, user.Email, user.Age, user.City)
+	}
+
+	return query.String(), args
 }
 
 // OrderRepository handles order database operations
 type OrderRepository struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	stmts *StatementCache
 }
 
 // NewOrderRepository creates a new order repository
@@ -135,6 +281,12 @@ func NewOrderRepository(db *sqlx.DB) *OrderRepository {
 	panic("Not yet implemented")
 }
 
+// Close releases the repository's prepared statements
+func (or *OrderRepository) Close() error {
+	// TODO: StatementCacheを閉じる
+	panic("Not yet implemented")
+}
+
 // GetByID retrieves an order by ID
 func (or *OrderRepository) GetByID(id int) (*Order, error) {
 	// TODO: IDで注文を取得
@@ -182,12 +334,70 @@ type OrderSummary struct {
 	CreatedAt  time.Time `db:"created_at"`
 }
 
+// PostgreSQL SQLSTATE codes for failures that succeed when the transaction is rerun
+const (
+	pgDeadlockDetected     = "40P01"
+	pgSerializationFailure = "40001"
+)
+
+// IsRetryableTxError reports whether err is a deadlock or serialization failure
+func IsRetryableTxError(err error) bool {
+	// TODO: errors.As で *pq.Error を取り出し、Code が 40P01 / 40001 か判定
+	panic("Not yet implemented")
+}
+
+// DeadlockRetrier reruns transactions that fail with a deadlock or serialization error
+type DeadlockRetrier struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+
+	attempts int64 // transactions started, including reruns
+	retries  int64 // reruns after a retryable error
+}
+
+// DeadlockRetryStats summarizes the work done by a DeadlockRetrier
+type DeadlockRetryStats struct {
+	Attempts int64
+	Retries  int64
+}
+
+// NewDeadlockRetrier creates a retrier that tries each transaction at most maxAttempts times
+func NewDeadlockRetrier(maxAttempts int, baseDelay, maxDelay time.Duration) *DeadlockRetrier {
+	// TODO: DeadlockRetrierを初期化（maxAttemptsは最低1）
+	panic("Not yet implemented")
+}
+
+// RetryOnDeadlock runs fn in a transaction and reruns it on deadlock or serialization errors
+func RetryOnDeadlock(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	// TODO: デフォルト設定のDeadlockRetrierでRunを呼び出す
+	panic("Not yet implemented")
+}
+
+// Run executes fn in a transaction, committing on success.
+// fn must be safe to run more than once since every attempt starts a fresh transaction.
+func (r *DeadlockRetrier) Run(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	// TODO: 以下を実装
+	// 1. BeginTxxでトランザクションを開始しfnを実行（失敗時はRollback、成功時はCommit）
+	// 2. IsRetryableTxErrorがtrueかつ試行回数が残っていれば、ジッター付き指数バックオフ後に再実行
+	// 3. attempts/retriesをatomicに記録
+	// 4. 待機中にctxがキャンセルされたらctx.Err()を返す
+	panic("Not yet implemented")
+}
+
+// Stats returns the attempt counts recorded so far
+func (r *DeadlockRetrier) Stats() DeadlockRetryStats {
+	// TODO: atomicに読み出して返す
+	panic("Not yet implemented")
+}
+
 // TransactionService handles complex database transactions
 type TransactionService struct {
 	db             *sqlx.DB
 	userRepo       *UserRepository
 	orderRepo      *OrderRepository
 	accountRepo    *AccountRepository
+	retrier        *DeadlockRetrier
 }
 
 // NewTransactionService creates a new transaction service
@@ -196,15 +406,15 @@ func NewTransactionService(db *sqlx.DB) *TransactionService {
 	panic("Not yet implemented")
 }
 
-// Transfer performs money transfer between accounts
+// Transfer performs money transfer between accounts, retrying on deadlock
 func (ts *TransactionService) Transfer(fromUserID, toUserID int, amount float64) error {
-	// TODO: アカウント間の送金処理を実装
+	// TODO: ts.retrier.Run内でアカウント間の送金処理を実装
 	panic("Not yet implemented")
 }
 
-// CreateOrderWithAccount creates an order and updates account balance
+// CreateOrderWithAccount creates an order and updates account balance, retrying on deadlock
 func (ts *TransactionService) CreateOrderWithAccount(userID int, orderAmount float64, items JSONB) (*Order, error) {
-	// TODO: 注文作成とアカウント残高更新を実装
+	// TODO: ts.retrier.Run内で注文作成とアカウント残高更新を実装
 	panic("Not yet implemented")
 }
 
@@ -231,66 +441,134 @@ func (ar *AccountRepository) UpdateBalance(userID int, amount float64) error {
 	panic("Not yet implemented")
 }
 
-// QueryBuilder helps build dynamic SQL queries
+// QueryBuilder helps build dynamic SQL queries.
+// Clauses are accumulated and assembled by Build, so they may be added in any order.
+// Conditions may use ? placeholders, which are rebound to the driver's positional form ($1, $2, ...).
 type QueryBuilder struct {
-	db      *sqlx.DB
-	query   string
-	args    []interface{}
-	namedArgs map[string]interface{}
+	db       *sqlx.DB
+	fields   string
+	table    string
+	joins    []string
+	wheres   []string
+	orderBy  []string
+	limit    int
+	hasLimit bool
+	args     []interface{}
 }
 
 // NewQueryBuilder creates a new query builder
 func NewQueryBuilder(db *sqlx.DB) *QueryBuilder {
-	// TODO: QueryBuilderを初期化
-	panic("Not yet implemented")
+	return &QueryBuilder{
+		db:   db,
+		args: make([]interface{}, 0),
+	}
 }
 
 // Select starts a SELECT query
 func (qb *QueryBuilder) Select(fields string) *QueryBuilder {
-	// TODO: SELECT句を追加
-	panic("Not yet implemented")
+	qb.fields = fields
+	return qb
 }
 
 // From adds FROM clause
 func (qb *QueryBuilder) From(table string) *QueryBuilder {
-	// TODO: FROM句を追加
-	panic("Not yet implemented")
+	qb.table = table
+	return qb
 }
 
-// Where adds WHERE clause
+// Where adds WHERE clause. Multiple calls are combined with AND and
+// their args are appended in call order.
 func (qb *QueryBuilder) Where(condition string, args ...interface{}) *QueryBuilder {
-	// TODO: WHERE句を追加
-	panic("Not yet implemented")
+	qb.wheres = append(qb.wheres, condition)
+	qb.args = append(qb.args, args...)
+	return qb
 }
 
 // Join adds JOIN clause
 func (qb *QueryBuilder) Join(joinClause string) *QueryBuilder {
-	// TODO: JOIN句を追加
-	panic("Not yet implemented")
+	qb.joins = append(qb.joins, joinClause)
+	return qb
 }
 
 // OrderBy adds ORDER BY clause
 func (qb *QueryBuilder) OrderBy(orderClause string) *QueryBuilder {
-	// TODO: ORDER BY句を追加
-	panic("Not yet implemented")
+	qb.orderBy = append(qb.orderBy, orderClause)
+	return qb
 }
 
 // Limit adds LIMIT clause
 func (qb *QueryBuilder) Limit(limit int) *QueryBuilder {
-	// TODO: LIMIT句を追加
-	panic("Not yet implemented")
+	qb.limit = limit
+	qb.hasLimit = true
+	return qb
 }
 
 // Build builds the final query
 func (qb *QueryBuilder) Build() (string, []interface{}) {
-	// TODO: 最終的なクエリを構築
-	panic("Not yet implemented")
-}
-
-// Execute executes the query and returns results
+	var query strings.Builder
+
+	fields := qb.fields
+	if fields == "" {
+		fields = "*"
+	}
+	query.WriteString("SELECT ")
+	query.WriteString(fields)
+
+	if qb.table != "" {
+		query.WriteString(" FROM ")
+		query.WriteString(qb.table)
+	}
+
+	for _, join := range qb.joins {
+		query.WriteString(" ")
+		query.WriteString(join)
+	}
+
+	if len(qb.wheres) > 0 {
+		conditions := make([]string, len(qb.wheres))
+		for i, cond := range qb.wheres {
+			// ORを含む条件はANDと結合したときに優先順位が崩れないよう括弧で囲む
+			if len(qb.wheres) > 1 && strings.Contains(strings.ToUpper(cond), " OR ") {
+				cond = "(" + cond + ")"
+			}
+			conditions[i] = cond
+		}
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(conditions, " AND "))
+	}
+
+	if len(qb.orderBy) > 0 {
+		query.WriteString(" ORDER BY ")
+		query.WriteString(strings.Join(qb.orderBy, ", "))
+	}
+
+	if qb.hasLimit {
+		query.WriteString(fmt.Sprintf(" LIMIT %d", qb.limit))
+	}
+
+	bindType := sqlx.DOLLAR
+	if qb.db != nil {
+		bindType = sqlx.BindType(qb.db.DriverName())
+	}
+
+	args := make([]interface{}, len(qb.args))
+	copy(args, qb.args)
+	return sqlx.Rebind(bindType, query.String()), args
+}
+
+// Execute executes the query and scans the results into dest.
+// A pointer to a slice receives all rows (Select); any other pointer receives a single row (Get).
 func (qb *QueryBuilder) Execute(dest interface{}) error {
-	// TODO: クエリを実行して結果を返す
-	panic("Not yet implemented")
+	query, args := qb.Build()
+
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return fmt.Errorf("dest must be a pointer, got %T", dest)
+	}
+	if t.Elem().Kind() == reflect.Slice {
+		return qb.db.Select(dest, query, args...)
+	}
+	return qb.db.Get(dest, query, args...)
 }
 
 // MigrationRunner handles database schema migrations
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
+	"reflect"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // User represents a user entity
@@ -58,25 +63,32 @@ type JSONB map[string]interface{}
 
 // Scan implements the sql.Scanner interface
 func (j *JSONB) Scan(value interface{}) error {
-	if value == nil {
-		*j = nil
-		return nil
+	if j == nil {
+		return errors.New("cannot scan into nil *JSONB")
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("cannot scan into JSONB: value is not []byte")
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		// SQL NULL
+		*j = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONB", value)
 	}
 
-	if len(bytes) == 0 {
+	if len(data) == 0 {
 		*j = nil
 		return nil
 	}
 
 	result := make(map[string]interface{})
-	err := json.Unmarshal(bytes, &result)
-	if err != nil {
-		return err
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal JSONB: %w", err)
 	}
 
 	*j = result
@@ -91,33 +103,152 @@ func (j JSONB) Value() (driver.Value, error) {
 	return json.Marshal(j)
 }
 
+// StatementCache lazily prepares statements and reuses them by query string.
+// Cached statements belong to the connection pool; use PrepareTx/PrepareNamedTx
+// to obtain a transaction-bound copy instead of reusing them inside a Tx.
+type StatementCache struct {
+	db       *sqlx.DB
+	mu       sync.Mutex
+	stmts    map[string]*sqlx.Stmt
+	named    map[string]*sqlx.NamedStmt
+	prepares int
+}
+
+// NewStatementCache creates a new statement cache
+func NewStatementCache(db *sqlx.DB) *StatementCache {
+	return &StatementCache{
+		db:    db,
+		stmts: make(map[string]*sqlx.Stmt),
+		named: make(map[string]*sqlx.NamedStmt),
+	}
+}
+
+// Prepare returns the cached statement for query, preparing it on first use
+func (sc *StatementCache) Prepare(query string) (*sqlx.Stmt, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if stmt, ok := sc.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := sc.db.Preparex(query)
+	if err != nil {
+		return nil, err
+	}
+	sc.stmts[query] = stmt
+	sc.prepares++
+	return stmt, nil
+}
+
+// PrepareNamed returns the cached named statement for query, preparing it on first use
+func (sc *StatementCache) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if stmt, ok := sc.named[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := sc.db.PrepareNamed(query)
+	if err != nil {
+		return nil, err
+	}
+	sc.named[query] = stmt
+	sc.prepares++
+	return stmt, nil
+}
+
+// PrepareTx returns a transaction-specific copy of the cached statement.
+// The returned statement is closed automatically when tx commits or rolls back.
+func (sc *StatementCache) PrepareTx(tx *sqlx.Tx, query string) (*sqlx.Stmt, error) {
+	stmt, err := sc.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Stmtx(stmt), nil
+}
+
+// PrepareNamedTx returns a transaction-specific copy of the cached named statement.
+// The returned statement is closed automatically when tx commits or rolls back.
+func (sc *StatementCache) PrepareNamedTx(tx *sqlx.Tx, query string) (*sqlx.NamedStmt, error) {
+	stmt, err := sc.PrepareNamed(query)
+	if err != nil {
+		return nil, err
+	}
+	return tx.NamedStmt(stmt), nil
+}
+
+// PrepareCount returns how many statements have been prepared against the database
+func (sc *StatementCache) PrepareCount() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.prepares
+}
+
+// Close closes every cached statement and empties the cache
+func (sc *StatementCache) Close() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var errs []error
+	for query, stmt := range sc.stmts {
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close statement %q: %w", query, err))
+		}
+	}
+	for query, stmt := range sc.named {
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close named statement %q: %w", query, err))
+		}
+	}
+
+	sc.stmts = make(map[string]*sqlx.Stmt)
+	sc.named = make(map[string]*sqlx.NamedStmt)
+	return errors.Join(errs...)
+}
+
 // UserRepository handles user database operations
 type UserRepository struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	stmts *StatementCache
 }
 
 // NewUserRepository creates a new user repository
 func NewUserRepository(db *sqlx.DB) *UserRepository {
-	return &UserRepository{db: db}
+	return &UserRepository{db: db, stmts: NewStatementCache(db)}
+}
+
+// Close releases the repository's prepared statements
+func (ur *UserRepository) Close() error {
+	return ur.stmts.Close()
 }
 
 // GetByID retrieves a user by ID
 func (ur *UserRepository) GetByID(id int) (*User, error) {
-	var user User
-	err := ur.db.Get(&user, "SELECT * FROM users WHERE id = $1", id)
+	stmt, err := ur.stmts.Prepare("SELECT * FROM users WHERE id = $1")
 	if err != nil {
 		return nil, err
 	}
+
+	var user User
+	if err := stmt.Get(&user, id); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
 // GetByEmail retrieves a user by email
 func (ur *UserRepository) GetByEmail(email string) (*User, error) {
-	var user User
-	err := ur.db.Get(&user, "SELECT * FROM users WHERE email = $1", email)
+	stmt, err := ur.stmts.Prepare("SELECT * FROM users WHERE email = $1")
 	if err != nil {
 		return nil, err
 	}
+
+	var user User
+	if err := stmt.Get(&user, email); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
@@ -154,11 +285,10 @@ func (ur *UserRepository) Create(user *User) error {
 		VALUES (:name, :email, :age, :city) 
 		RETURNING id, created_at, updated_at`
 
-	stmt, err := ur.db.PrepareNamed(query)
+	stmt, err := ur.stmts.PrepareNamed(query)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
 	return stmt.Get(user, user)
 }
@@ -171,22 +301,31 @@ func (ur *UserRepository) Update(user *User) error {
 		WHERE id = :id
 		RETURNING updated_at`
 
-	stmt, err := ur.db.PrepareNamed(query)
+	stmt, err := ur.stmts.PrepareNamed(query)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
 	return stmt.Get(user, user)
 }
 
 // Delete deletes a user
 func (ur *UserRepository) Delete(id int) error {
-	_, err := ur.db.Exec("DELETE FROM users WHERE id = $1", id)
+	stmt, err := ur.stmts.Prepare("DELETE FROM users WHERE id = $1")
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec(id)
 	return err
 }
 
-// BatchInsert inserts multiple users efficiently
+// batchInsertSize is the number of rows per INSERT statement.
+// Postgres allows at most 65535 bound parameters per statement (4 per user here).
+const batchInsertSize = 1000
+
+// BatchInsert inserts multiple users with multi-row INSERT statements.
+// All chunks run in a single transaction, so any failure rolls back every row.
 func (ur *UserRepository) BatchInsert(users []User) error {
 	if len(users) == 0 {
 		return nil
@@ -198,40 +337,68 @@ func (ur *UserRepository) BatchInsert(users []User) error {
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareNamed(`
-		INSERT INTO users (name, email, age, city) 
-		VALUES (:name, :email, :age, :city)`)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+	for start := 0; start < len(users); start += batchInsertSize {
+		end := start + batchInsertSize
+		if end > len(users) {
+			end = len(users)
+		}
 
-	for _, user := range users {
-		if _, err := stmt.Exec(user); err != nil {
-			return err
+		query, args := buildBatchInsertQuery(users[start:end])
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("batch insert rows %d-%d: %w", start, end-1, err)
 		}
 	}
 
 	return tx.Commit()
 }
 
+// buildBatchInsertQuery builds INSERT INTO users ... VALUES ($1, $2, $3, $4), ($5, ...)
+func buildBatchInsertQuery(users []User) (string, []interface{}) {
+	const columns = 4
+
+	var query strings.Builder
+	query.WriteString("INSERT INTO users (name, email, age, city) VALUES ")
+
+	args := make([]interface{}, 0, len(users)*columns)
+	for i, user := range users {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := i * columns
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4)
+		args = append(args, user.Name, user.Email, user.Age, user.City)
+	}
+
+	return query.String(), args
+}
+
 // OrderRepository handles order database operations
 type OrderRepository struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	stmts *StatementCache
 }
 
 // NewOrderRepository creates a new order repository
 func NewOrderRepository(db *sqlx.DB) *OrderRepository {
-	return &OrderRepository{db: db}
+	return &OrderRepository{db: db, stmts: NewStatementCache(db)}
+}
+
+// Close releases the repository's prepared statements
+func (or *OrderRepository) Close() error {
+	return or.stmts.Close()
 }
 
 // GetByID retrieves an order by ID
 func (or *OrderRepository) GetByID(id int) (*Order, error) {
-	var order Order
-	err := or.db.Get(&order, "SELECT * FROM orders WHERE id = $1", id)
+	stmt, err := or.stmts.Prepare("SELECT * FROM orders WHERE id = $1")
 	if err != nil {
 		return nil, err
 	}
+
+	var order Order
+	if err := stmt.Get(&order, id); err != nil {
+		return nil, err
+	}
 	return &order, nil
 }
 
@@ -260,20 +427,22 @@ func (or *OrderRepository) Create(order *Order) error {
 		VALUES (:user_id, :amount, :status, :items) 
 		RETURNING id, created_at, updated_at`
 
-	stmt, err := or.db.PrepareNamed(query)
+	stmt, err := or.stmts.PrepareNamed(query)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
 	return stmt.Get(order, order)
 }
 
 // UpdateStatus updates order status
 func (or *OrderRepository) UpdateStatus(id int, status string) error {
-	_, err := or.db.Exec(
-		"UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2", 
-		status, id)
+	stmt, err := or.stmts.Prepare("UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2")
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.Exec(status, id)
 	return err
 }
 
@@ -311,12 +480,121 @@ type OrderSummary struct {
 	CreatedAt time.Time `db:"created_at"`
 }
 
+// PostgreSQL SQLSTATE codes for failures that succeed when the transaction is rerun
+const (
+	pgDeadlockDetected     = "40P01"
+	pgSerializationFailure = "40001"
+)
+
+// IsRetryableTxError reports whether err is a deadlock or serialization failure
+func IsRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == pgDeadlockDetected || pqErr.Code == pgSerializationFailure
+}
+
+// DeadlockRetrier reruns transactions that fail with a deadlock or serialization error
+type DeadlockRetrier struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+
+	attempts int64 // transactions started, including reruns
+	retries  int64 // reruns after a retryable error
+}
+
+// DeadlockRetryStats summarizes the work done by a DeadlockRetrier
+type DeadlockRetryStats struct {
+	Attempts int64
+	Retries  int64
+}
+
+// NewDeadlockRetrier creates a retrier that tries each transaction at most maxAttempts times
+func NewDeadlockRetrier(maxAttempts int, baseDelay, maxDelay time.Duration) *DeadlockRetrier {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &DeadlockRetrier{
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+	}
+}
+
+// defaultDeadlockRetrier backs RetryOnDeadlock
+var defaultDeadlockRetrier = NewDeadlockRetrier(5, 10*time.Millisecond, 500*time.Millisecond)
+
+// RetryOnDeadlock runs fn in a transaction and reruns it on deadlock or serialization errors
+func RetryOnDeadlock(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	return defaultDeadlockRetrier.Run(ctx, db, fn)
+}
+
+// Run executes fn in a transaction, committing on success.
+// fn must be safe to run more than once since every attempt starts a fresh transaction.
+func (r *DeadlockRetrier) Run(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		atomic.AddInt64(&r.attempts, 1)
+
+		err := runInTx(ctx, db, fn)
+		if err == nil || !IsRetryableTxError(err) || attempt >= r.maxAttempts {
+			return err
+		}
+
+		atomic.AddInt64(&r.retries, 1)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.backoff(attempt)):
+		}
+	}
+}
+
+// Stats returns the attempt counts recorded so far
+func (r *DeadlockRetrier) Stats() DeadlockRetryStats {
+	return DeadlockRetryStats{
+		Attempts: atomic.LoadInt64(&r.attempts),
+		Retries:  atomic.LoadInt64(&r.retries),
+	}
+}
+
+// backoff returns an exponential delay with jitter so competing transactions
+// do not collide again on the next attempt
+func (r *DeadlockRetrier) backoff(attempt int) time.Duration {
+	delay := r.baseDelay << (attempt - 1)
+	if delay > r.maxDelay || delay <= 0 {
+		delay = r.maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// ランダムに半分〜全体の範囲で待つ
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// runInTx runs fn in a single transaction
+func runInTx(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 // TransactionService handles complex database transactions
 type TransactionService struct {
 	db          *sqlx.DB
 	userRepo    *UserRepository
 	orderRepo   *OrderRepository
 	accountRepo *AccountRepository
+	retrier     *DeadlockRetrier
 }
 
 // NewTransactionService creates a new transaction service
@@ -326,126 +604,125 @@ func NewTransactionService(db *sqlx.DB) *TransactionService {
 		userRepo:    NewUserRepository(db),
 		orderRepo:   NewOrderRepository(db),
 		accountRepo: NewAccountRepository(db),
+		retrier:     NewDeadlockRetrier(5, 10*time.Millisecond, 500*time.Millisecond),
 	}
 }
 
-// Transfer performs money transfer between accounts
+// Transfer performs money transfer between accounts, retrying on deadlock
 func (ts *TransactionService) Transfer(fromUserID, toUserID int, amount float64) error {
 	if amount <= 0 {
 		return errors.New("transfer amount must be positive")
 	}
 
-	tx, err := ts.db.Beginx()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
+	return ts.retrier.Run(context.Background(), ts.db, func(tx *sqlx.Tx) error {
+		// Get sender's account for update
+		var fromAccount Account
+		err := tx.Get(&fromAccount,
+			"SELECT * FROM accounts WHERE user_id = $1 FOR UPDATE",
+			fromUserID)
+		if err != nil {
+			return fmt.Errorf("failed to get sender account: %w", err)
+		}
 
-	// Get sender's account for update
-	var fromAccount Account
-	err = tx.Get(&fromAccount, 
-		"SELECT * FROM accounts WHERE user_id = $1 FOR UPDATE", 
-		fromUserID)
-	if err != nil {
-		return fmt.Errorf("failed to get sender account: %w", err)
-	}
+		if fromAccount.Balance < amount {
+			return errors.New("insufficient balance")
+		}
 
-	if fromAccount.Balance < amount {
-		return errors.New("insufficient balance")
-	}
+		// Get receiver's account for update
+		var toAccount Account
+		err = tx.Get(&toAccount,
+			"SELECT * FROM accounts WHERE user_id = $1 FOR UPDATE",
+			toUserID)
+		if err != nil {
+			return fmt.Errorf("failed to get receiver account: %w", err)
+		}
 
-	// Get receiver's account for update
-	var toAccount Account
-	err = tx.Get(&toAccount, 
-		"SELECT * FROM accounts WHERE user_id = $1 FOR UPDATE", 
-		toUserID)
-	if err != nil {
-		return fmt.Errorf("failed to get receiver account: %w", err)
-	}
+		// Update sender's balance
+		_, err = tx.Exec(
+			"UPDATE accounts SET balance = balance - $1 WHERE user_id = $2",
+			amount, fromUserID)
+		if err != nil {
+			return fmt.Errorf("failed to update sender balance: %w", err)
+		}
 
-	// Update sender's balance
-	_, err = tx.Exec(
-		"UPDATE accounts SET balance = balance - $1 WHERE user_id = $2",
-		amount, fromUserID)
-	if err != nil {
-		return fmt.Errorf("failed to update sender balance: %w", err)
-	}
+		// Update receiver's balance
+		_, err = tx.Exec(
+			"UPDATE accounts SET balance = balance + $1 WHERE user_id = $2",
+			amount, toUserID)
+		if err != nil {
+			return fmt.Errorf("failed to update receiver balance: %w", err)
+		}
 
-	// Update receiver's balance
-	_, err = tx.Exec(
-		"UPDATE accounts SET balance = balance + $1 WHERE user_id = $2",
-		amount, toUserID)
-	if err != nil {
-		return fmt.Errorf("failed to update receiver balance: %w", err)
-	}
-
-	// Record transfer
-	_, err = tx.NamedExec(`
-		INSERT INTO transfers (from_user_id, to_user_id, amount)
-		VALUES (:from_user_id, :to_user_id, :amount)`,
-		map[string]interface{}{
-			"from_user_id": fromUserID,
-			"to_user_id":   toUserID,
-			"amount":       amount,
-		})
-	if err != nil {
-		return fmt.Errorf("failed to record transfer: %w", err)
-	}
+		// Record transfer
+		_, err = tx.NamedExec(`
+			INSERT INTO transfers (from_user_id, to_user_id, amount)
+			VALUES (:from_user_id, :to_user_id, :amount)`,
+			map[string]interface{}{
+				"from_user_id": fromUserID,
+				"to_user_id":   toUserID,
+				"amount":       amount,
+			})
+		if err != nil {
+			return fmt.Errorf("failed to record transfer: %w", err)
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
-// CreateOrderWithAccount creates an order and updates account balance
+// CreateOrderWithAccount creates an order and updates account balance, retrying on deadlock
 func (ts *TransactionService) CreateOrderWithAccount(userID int, orderAmount float64, items JSONB) (*Order, error) {
 	if orderAmount <= 0 {
 		return nil, errors.New("order amount must be positive")
 	}
 
-	tx, err := ts.db.Beginx()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
+	var order *Order
+	err := ts.retrier.Run(context.Background(), ts.db, func(tx *sqlx.Tx) error {
+		// Check account balance
+		var account Account
+		err := tx.Get(&account,
+			"SELECT * FROM accounts WHERE user_id = $1 FOR UPDATE",
+			userID)
+		if err != nil {
+			return fmt.Errorf("failed to get account: %w", err)
+		}
 
-	// Check account balance
-	var account Account
-	err = tx.Get(&account, 
-		"SELECT * FROM accounts WHERE user_id = $1 FOR UPDATE", 
-		userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
-	}
+		if account.Balance < orderAmount {
+			return errors.New("insufficient balance")
+		}
 
-	if account.Balance < orderAmount {
-		return nil, errors.New("insufficient balance")
-	}
+		// Create order (a fresh value per attempt so a rerun does not see stale IDs)
+		created := &Order{
+			UserID: userID,
+			Amount: orderAmount,
+			Status: "pending",
+			Items:  items,
+		}
 
-	// Create order
-	order := &Order{
-		UserID: userID,
-		Amount: orderAmount,
-		Status: "pending",
-		Items:  items,
-	}
+		err = tx.Get(created, `
+			INSERT INTO orders (user_id, amount, status, items)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at, updated_at`,
+			created.UserID, created.Amount, created.Status, created.Items)
+		if err != nil {
+			return fmt.Errorf("failed to create order: %w", err)
+		}
 
-	err = tx.Get(order, `
-		INSERT INTO orders (user_id, amount, status, items) 
-		VALUES ($1, $2, $3, $4) 
-		RETURNING id, created_at, updated_at`,
-		order.UserID, order.Amount, order.Status, order.Items)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
-	}
+		// Update account balance
+		_, err = tx.Exec(
+			"UPDATE accounts SET balance = balance - $1 WHERE user_id = $2",
+			orderAmount, userID)
+		if err != nil {
+			return fmt.Errorf("failed to update account balance: %w", err)
+		}
 
-	// Update account balance
-	_, err = tx.Exec(
-		"UPDATE accounts SET balance = balance - $1 WHERE user_id = $2",
-		orderAmount, userID)
+		order = created
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update account balance: %w", err)
+		return nil, err
 	}
-
-	return order, tx.Commit()
+	return order, nil
 }
 
 // AccountRepository handles account database operations
@@ -476,79 +753,134 @@ func (ar *AccountRepository) UpdateBalance(userID int, amount float64) error {
 	return err
 }
 
-// QueryBuilder helps build dynamic SQL queries
+// QueryBuilder helps build dynamic SQL queries.
+// Clauses are accumulated and assembled by Build, so they may be added in any order.
+// Conditions may use ? placeholders, which are rebound to the driver's positional form ($1, $2, ...).
 type QueryBuilder struct {
-	db        *sqlx.DB
-	query     strings.Builder
-	args      []interface{}
-	namedArgs map[string]interface{}
-	argCount  int
+	db       *sqlx.DB
+	fields   string
+	table    string
+	joins    []string
+	wheres   []string
+	orderBy  []string
+	limit    int
+	hasLimit bool
+	args     []interface{}
 }
 
 // NewQueryBuilder creates a new query builder
 func NewQueryBuilder(db *sqlx.DB) *QueryBuilder {
 	return &QueryBuilder{
-		db:        db,
-		args:      make([]interface{}, 0),
-		namedArgs: make(map[string]interface{}),
+		db:   db,
+		args: make([]interface{}, 0),
 	}
 }
 
 // Select starts a SELECT query
 func (qb *QueryBuilder) Select(fields string) *QueryBuilder {
-	qb.query.WriteString("SELECT ")
-	qb.query.WriteString(fields)
+	qb.fields = fields
 	return qb
 }
 
 // From adds FROM clause
 func (qb *QueryBuilder) From(table string) *QueryBuilder {
-	qb.query.WriteString(" FROM ")
-	qb.query.WriteString(table)
+	qb.table = table
 	return qb
 }
 
-// Where adds WHERE clause
+// Where adds WHERE clause. Multiple calls are combined with AND and
+// their args are appended in call order.
 func (qb *QueryBuilder) Where(condition string, args ...interface{}) *QueryBuilder {
-	if strings.Contains(qb.query.String(), "WHERE") {
-		qb.query.WriteString(" AND ")
-	} else {
-		qb.query.WriteString(" WHERE ")
-	}
-	qb.query.WriteString(condition)
+	qb.wheres = append(qb.wheres, condition)
 	qb.args = append(qb.args, args...)
 	return qb
 }
 
 // Join adds JOIN clause
 func (qb *QueryBuilder) Join(joinClause string) *QueryBuilder {
-	qb.query.WriteString(" ")
-	qb.query.WriteString(joinClause)
+	qb.joins = append(qb.joins, joinClause)
 	return qb
 }
 
 // OrderBy adds ORDER BY clause
 func (qb *QueryBuilder) OrderBy(orderClause string) *QueryBuilder {
-	qb.query.WriteString(" ORDER BY ")
-	qb.query.WriteString(orderClause)
+	qb.orderBy = append(qb.orderBy, orderClause)
 	return qb
 }
 
 // Limit adds LIMIT clause
 func (qb *QueryBuilder) Limit(limit int) *QueryBuilder {
-	qb.query.WriteString(fmt.Sprintf(" LIMIT %d", limit))
+	qb.limit = limit
+	qb.hasLimit = true
 	return qb
 }
 
 // Build builds the final query
 func (qb *QueryBuilder) Build() (string, []interface{}) {
-	return qb.query.String(), qb.args
+	var query strings.Builder
+
+	fields := qb.fields
+	if fields == "" {
+		fields = "*"
+	}
+	query.WriteString("SELECT ")
+	query.WriteString(fields)
+
+	if qb.table != "" {
+		query.WriteString(" FROM ")
+		query.WriteString(qb.table)
+	}
+
+	for _, join := range qb.joins {
+		query.WriteString(" ")
+		query.WriteString(join)
+	}
+
+	if len(qb.wheres) > 0 {
+		conditions := make([]string, len(qb.wheres))
+		for i, cond := range qb.wheres {
+			// ORを含む条件はANDと結合したときに優先順位が崩れないよう括弧で囲む
+			if len(qb.wheres) > 1 && strings.Contains(strings.ToUpper(cond), " OR ") {
+				cond = "(" + cond + ")"
+			}
+			conditions[i] = cond
+		}
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(conditions, " AND "))
+	}
+
+	if len(qb.orderBy) > 0 {
+		query.WriteString(" ORDER BY ")
+		query.WriteString(strings.Join(qb.orderBy, ", "))
+	}
+
+	if qb.hasLimit {
+		query.WriteString(fmt.Sprintf(" LIMIT %d", qb.limit))
+	}
+
+	bindType := sqlx.DOLLAR
+	if qb.db != nil {
+		bindType = sqlx.BindType(qb.db.DriverName())
+	}
+
+	args := make([]interface{}, len(qb.args))
+	copy(args, qb.args)
+	return sqlx.Rebind(bindType, query.String()), args
 }
 
-// Execute executes the query and returns results
+// Execute executes the query and scans the results into dest.
+// A pointer to a slice receives all rows (Select); any other pointer receives a single row (Get).
 func (qb *QueryBuilder) Execute(dest interface{}) error {
 	query, args := qb.Build()
-	return qb.db.Select(dest, query, args...)
+
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return fmt.Errorf("dest must be a pointer, got %T", dest)
+	}
+	if t.Elem().Kind() == reflect.Slice {
+		return qb.db.Select(dest, query, args...)
+	}
+	return qb.db.Get(dest, query, args...)
 }
 
 // MigrationRunner handles database schema migrations
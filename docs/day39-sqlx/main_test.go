@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
-	_ "github.com/lib/pq"
 )
 
 var testDB *sqlx.DB
@@ -119,6 +125,76 @@ func TestJSONB_ScanAndValue(t *testing.T) {
 	}
 }
 
+func TestJSONB_RoundTrip(t *testing.T) {
+	original := JSONB{
+		"customer": map[string]interface{}{
+			"name": "Alice",
+			"address": map[string]interface{}{
+				"city": "Tokyo",
+				"zip":  "100-0001",
+			},
+		},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "A-1", "qty": float64(2), "tags": []interface{}{"new", "sale"}},
+			map[string]interface{}{"sku": "B-2", "qty": float64(1), "tags": []interface{}{}},
+		},
+		"total":  float64(1980.5),
+		"gift":   false,
+		"coupon": nil,
+	}
+
+	value, err := original.Value()
+	if err != nil {
+		t.Fatalf("Failed to get JSONB value: %v", err)
+	}
+	raw, ok := value.([]byte)
+	if !ok {
+		t.Fatalf("Expected Value to return []byte, got %T", value)
+	}
+
+	// ドライバは []byte と string のどちらで返してくることもある
+	for _, input := range []interface{}{raw, string(raw)} {
+		var scanned JSONB
+		if err := scanned.Scan(input); err != nil {
+			t.Fatalf("Failed to scan %T: %v", input, err)
+		}
+		if !reflect.DeepEqual(scanned, original) {
+			t.Errorf("Round trip via %T mismatch:\n got  %#v\n want %#v", input, scanned, original)
+		}
+	}
+
+	t.Run("NULL and empty input", func(t *testing.T) {
+		j := JSONB{"stale": true}
+		if err := j.Scan(nil); err != nil || j != nil {
+			t.Errorf("Expected nil map for SQL NULL, got %v (err=%v)", j, err)
+		}
+		if err := j.Scan([]byte{}); err != nil || j != nil {
+			t.Errorf("Expected nil map for empty input, got %v (err=%v)", j, err)
+		}
+
+		var nilMap JSONB
+		value, err := nilMap.Value()
+		if err != nil || value != nil {
+			t.Errorf("Expected nil JSONB to be stored as NULL, got %v (err=%v)", value, err)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		var j JSONB
+		if err := j.Scan(42); err == nil {
+			t.Error("Expected error scanning an int")
+		}
+		if err := j.Scan([]byte(`[1, 2, 3]`)); err == nil {
+			t.Error("Expected error scanning a JSON array into JSONB")
+		}
+
+		var nilPtr *JSONB
+		if err := nilPtr.Scan([]byte(`{}`)); err == nil {
+			t.Error("Expected error scanning into a nil *JSONB")
+		}
+	})
+}
+
 func TestUserRepository_CRUD(t *testing.T) {
 	if testDB == nil {
 		t.Skip("Database not available")
@@ -206,6 +282,106 @@ func TestUserRepository_CRUD(t *testing.T) {
 	}
 }
 
+func TestStatementCache(t *testing.T) {
+	if testDB == nil {
+		t.Skip("Database not available")
+	}
+
+	helper := NewTestHelper(testDB)
+	if err := helper.TruncateAll(); err != nil {
+		t.Fatalf("Failed to truncate tables: %v", err)
+	}
+
+	users, err := helper.SeedUsers(3)
+	if err != nil {
+		t.Fatalf("Failed to seed users: %v", err)
+	}
+
+	t.Run("prepares each query once", func(t *testing.T) {
+		userRepo := NewUserRepository(testDB)
+		defer userRepo.Close()
+
+		for i := 0; i < 50; i++ {
+			user, err := userRepo.GetByID(users[i%len(users)].ID)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if user.ID != users[i%len(users)].ID {
+				t.Errorf("Expected user %d, got %d", users[i%len(users)].ID, user.ID)
+			}
+		}
+
+		if count := userRepo.stmts.PrepareCount(); count != 1 {
+			t.Errorf("Expected 1 prepared statement, got %d", count)
+		}
+
+		if _, err := userRepo.GetByEmail(users[0].Email); err != nil {
+			t.Fatalf("GetByEmail failed: %v", err)
+		}
+		if count := userRepo.stmts.PrepareCount(); count != 2 {
+			t.Errorf("Expected 2 prepared statements, got %d", count)
+		}
+	})
+
+	t.Run("transaction-bound statements", func(t *testing.T) {
+		cache := NewStatementCache(testDB)
+		defer cache.Close()
+
+		const query = "SELECT name FROM users WHERE id = $1"
+
+		tx, err := testDB.Beginx()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+
+		txStmt, err := cache.PrepareTx(tx, query)
+		if err != nil {
+			t.Fatalf("PrepareTx failed: %v", err)
+		}
+
+		var name string
+		if err := txStmt.Get(&name, users[0].ID); err != nil {
+			t.Fatalf("Query in transaction failed: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+
+		// The cached statement must outlive the transaction it was bound to
+		stmt, err := cache.Prepare(query)
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		if err := stmt.Get(&name, users[1].ID); err != nil {
+			t.Errorf("Cached statement unusable after commit: %v", err)
+		}
+		if name != users[1].Name {
+			t.Errorf("Expected name %s, got %s", users[1].Name, name)
+		}
+
+		if count := cache.PrepareCount(); count != 1 {
+			t.Errorf("Expected 1 prepared statement, got %d", count)
+		}
+	})
+
+	t.Run("Close releases statements", func(t *testing.T) {
+		cache := NewStatementCache(testDB)
+
+		stmt, err := cache.Prepare("SELECT COUNT(*) FROM users")
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		if err := cache.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		var count int
+		if err := stmt.Get(&count); err == nil {
+			t.Error("Expected closed statement to fail")
+		}
+	})
+}
+
 func TestUserRepository_BatchInsert(t *testing.T) {
 	if testDB == nil {
 		t.Skip("Database not available")
@@ -240,6 +416,87 @@ func TestUserRepository_BatchInsert(t *testing.T) {
 	}
 }
 
+func TestUserRepository_BatchInsertLarge(t *testing.T) {
+	if testDB == nil {
+		t.Skip("Database not available")
+	}
+
+	helper := NewTestHelper(testDB)
+	userRepo := NewUserRepository(testDB)
+	defer userRepo.Close()
+
+	newUsers := func(n int) []User {
+		users := make([]User, n)
+		for i := range users {
+			age := 20 + i%50
+			users[i] = User{
+				Name:  fmt.Sprintf("Batch User %d", i),
+				Email: fmt.Sprintf("batch%d@example.com", i),
+				Age:   &age,
+				City:  "Tokyo",
+			}
+		}
+		return users
+	}
+	countUsers := func() int {
+		var count int
+		if err := testDB.Get(&count, "SELECT COUNT(*) FROM users"); err != nil {
+			t.Fatalf("Failed to count users: %v", err)
+		}
+		return count
+	}
+
+	t.Run("inserts across multiple chunks", func(t *testing.T) {
+		if err := helper.TruncateAll(); err != nil {
+			t.Fatalf("Failed to truncate tables: %v", err)
+		}
+
+		if err := userRepo.BatchInsert(newUsers(5000)); err != nil {
+			t.Fatalf("Failed to batch insert users: %v", err)
+		}
+		if count := countUsers(); count != 5000 {
+			t.Errorf("Expected 5000 users, got %d", count)
+		}
+	})
+
+	t.Run("duplicate email rolls back every chunk", func(t *testing.T) {
+		if err := helper.TruncateAll(); err != nil {
+			t.Fatalf("Failed to truncate tables: %v", err)
+		}
+
+		users := newUsers(5000)
+		// 3つ目のチャンクで1つ目のチャンクと重複させる
+		users[2500].Email = users[10].Email
+
+		if err := userRepo.BatchInsert(users); err == nil {
+			t.Fatal("Expected duplicate email to fail the batch")
+		}
+		if count := countUsers(); count != 0 {
+			t.Errorf("Expected rollback to leave 0 users, got %d", count)
+		}
+	})
+}
+
+func TestBuildBatchInsertQuery(t *testing.T) {
+	age := 30
+	users := []User{
+		{Name: "Alice", Email: "alice@example.com", Age: &age, City: "Tokyo"},
+		{Name: "Bob", Email: "bob@example.com", City: "Osaka"},
+	}
+
+	query, args := buildBatchInsertQuery(users)
+
+	expected := "INSERT INTO users (name, email, age, city) VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)"
+	if query != expected {
+		t.Errorf("Expected query %s, got %s", expected, query)
+	}
+
+	expectedArgs := []interface{}{"Alice", "alice@example.com", &age, "Tokyo", "Bob", "bob@example.com", (*int)(nil), "Osaka"}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %v, got %v", expectedArgs, args)
+	}
+}
+
 func TestUserRepository_GetByIDs(t *testing.T) {
 	if testDB == nil {
 		t.Skip("Database not available")
@@ -497,6 +754,217 @@ func TestTransactionService_CreateOrderWithAccount(t *testing.T) {
 	}
 }
 
+// txCountingDriver is a minimal database/sql driver that only tracks
+// transaction outcomes, so retry logic can be tested without PostgreSQL
+type txCountingDriver struct {
+	mu        sync.Mutex
+	begins    int
+	commits   int
+	rollbacks int
+}
+
+func (d *txCountingDriver) Open(name string) (driver.Conn, error) { return &txCountingConn{d: d}, nil }
+
+func (d *txCountingDriver) counts() (begins, commits, rollbacks int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.begins, d.commits, d.rollbacks
+}
+
+type txCountingConn struct{ d *txCountingDriver }
+
+func (c *txCountingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("txCountingConn: queries not supported")
+}
+func (c *txCountingConn) Close() error { return nil }
+func (c *txCountingConn) Begin() (driver.Tx, error) {
+	c.d.mu.Lock()
+	c.d.begins++
+	c.d.mu.Unlock()
+	return &txCountingTx{d: c.d}, nil
+}
+
+type txCountingTx struct{ d *txCountingDriver }
+
+func (t *txCountingTx) Commit() error {
+	t.d.mu.Lock()
+	t.d.commits++
+	t.d.mu.Unlock()
+	return nil
+}
+func (t *txCountingTx) Rollback() error {
+	t.d.mu.Lock()
+	t.d.rollbacks++
+	t.d.mu.Unlock()
+	return nil
+}
+
+func newTxCountingDB(t *testing.T) (*sqlx.DB, *txCountingDriver) {
+	t.Helper()
+	drv := &txCountingDriver{}
+	name := fmt.Sprintf("txcounting-%s", t.Name())
+	sql.Register(name, drv)
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatalf("Failed to open fake database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, drv
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"deadlock", &pq.Error{Code: "40P01"}, true},
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"wrapped deadlock", fmt.Errorf("failed to update: %w", &pq.Error{Code: "40P01"}), true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"plain error", errors.New("insufficient balance"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableTxError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableTxError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeadlockRetrier_Run(t *testing.T) {
+	t.Run("retries after deadlock and commits", func(t *testing.T) {
+		db, drv := newTxCountingDB(t)
+		retrier := NewDeadlockRetrier(3, time.Millisecond, 5*time.Millisecond)
+
+		calls := 0
+		err := retrier.Run(context.Background(), db, func(tx *sqlx.Tx) error {
+			calls++
+			if calls == 1 {
+				return fmt.Errorf("failed to update sender balance: %w", &pq.Error{Code: "40P01"})
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected second attempt to succeed, got: %v", err)
+		}
+
+		if calls != 2 {
+			t.Errorf("Expected 2 attempts, got %d", calls)
+		}
+		begins, commits, rollbacks := drv.counts()
+		if begins != 2 || commits != 1 || rollbacks != 1 {
+			t.Errorf("Expected 2 begins/1 commit/1 rollback, got %d/%d/%d", begins, commits, rollbacks)
+		}
+		if stats := retrier.Stats(); stats.Attempts != 2 || stats.Retries != 1 {
+			t.Errorf("Expected stats {2 1}, got %+v", stats)
+		}
+	})
+
+	t.Run("non-retryable error fails immediately", func(t *testing.T) {
+		db, drv := newTxCountingDB(t)
+		retrier := NewDeadlockRetrier(3, time.Millisecond, 5*time.Millisecond)
+
+		wantErr := errors.New("insufficient balance")
+		calls := 0
+		err := retrier.Run(context.Background(), db, func(tx *sqlx.Tx) error {
+			calls++
+			return wantErr
+		})
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("Expected %v, got %v", wantErr, err)
+		}
+
+		if calls != 1 {
+			t.Errorf("Expected 1 attempt, got %d", calls)
+		}
+		begins, commits, rollbacks := drv.counts()
+		if begins != 1 || commits != 0 || rollbacks != 1 {
+			t.Errorf("Expected 1 begin/0 commits/1 rollback, got %d/%d/%d", begins, commits, rollbacks)
+		}
+		if stats := retrier.Stats(); stats.Retries != 0 {
+			t.Errorf("Expected no retries, got %d", stats.Retries)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		db, _ := newTxCountingDB(t)
+		retrier := NewDeadlockRetrier(3, time.Millisecond, 5*time.Millisecond)
+
+		calls := 0
+		err := retrier.Run(context.Background(), db, func(tx *sqlx.Tx) error {
+			calls++
+			return &pq.Error{Code: "40001"}
+		})
+		if !IsRetryableTxError(err) {
+			t.Fatalf("Expected serialization failure to be returned, got %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+	})
+}
+
+func TestTransactionService_ConcurrentOpposingTransfers(t *testing.T) {
+	if testDB == nil {
+		t.Skip("Database not available")
+	}
+
+	helper := NewTestHelper(testDB)
+	if err := helper.TruncateAll(); err != nil {
+		t.Fatalf("Failed to truncate tables: %v", err)
+	}
+
+	users, err := helper.SeedUsers(2)
+	if err != nil {
+		t.Fatalf("Failed to seed users: %v", err)
+	}
+
+	ids := []int{users[0].ID, users[1].ID}
+	if _, err := helper.SeedAccounts(ids, 1000.0); err != nil {
+		t.Fatalf("Failed to seed accounts: %v", err)
+	}
+
+	ts := NewTransactionService(testDB)
+	accountRepo := NewAccountRepository(testDB)
+
+	// 逆方向の送金を同時に走らせ、デッドロックが発生してもリトライで完了することを確認
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- ts.Transfer(ids[0], ids[1], 10)
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- ts.Transfer(ids[1], ids[0], 10)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Transfer failed: %v", err)
+		}
+	}
+
+	for _, id := range ids {
+		account, err := accountRepo.GetByUserID(id)
+		if err != nil {
+			t.Fatalf("Failed to get account: %v", err)
+		}
+		if account.Balance != 1000 {
+			t.Errorf("Expected balance 1000 for user %d, got %.2f", id, account.Balance)
+		}
+	}
+}
+
 func TestQueryBuilder_Dynamic(t *testing.T) {
 	if testDB == nil {
 		t.Skip("Database not available")
@@ -564,6 +1032,61 @@ func TestQueryBuilder_Dynamic(t *testing.T) {
 	}
 }
 
+func TestQueryBuilder_Build(t *testing.T) {
+	t.Run("where, join, order by and limit", func(t *testing.T) {
+		query, args := NewQueryBuilder(nil).
+			Select("u.id, u.name, o.amount").
+			From("users u").
+			Where("u.city = ?", "Tokyo").
+			Join("INNER JOIN orders o ON o.user_id = u.id").
+			Where("o.amount >= ? AND o.status = ?", 1000.0, "completed").
+			OrderBy("o.amount DESC").
+			Limit(5).
+			Build()
+
+		expected := "SELECT u.id, u.name, o.amount FROM users u INNER JOIN orders o ON o.user_id = u.id " +
+			"WHERE u.city = $1 AND o.amount >= $2 AND o.status = $3 ORDER BY o.amount DESC LIMIT 5"
+		if query != expected {
+			t.Errorf("Expected query:\n%s\ngot:\n%s", expected, query)
+		}
+
+		expectedArgs := []interface{}{"Tokyo", 1000.0, "completed"}
+		if !reflect.DeepEqual(args, expectedArgs) {
+			t.Errorf("Expected args %v, got %v", expectedArgs, args)
+		}
+	})
+
+	t.Run("OR conditions are grouped", func(t *testing.T) {
+		query, args := NewQueryBuilder(nil).
+			Select("*").
+			From("users").
+			Where("city = ? OR city = ?", "Tokyo", "Osaka").
+			Where("age > ?", 20).
+			Build()
+
+		expected := "SELECT * FROM users WHERE (city = $1 OR city = $2) AND age > $3"
+		if query != expected {
+			t.Errorf("Expected query %s, got %s", expected, query)
+		}
+		if len(args) != 3 || args[2] != 20 {
+			t.Errorf("Expected args [Tokyo Osaka 20], got %v", args)
+		}
+	})
+
+	t.Run("build is repeatable", func(t *testing.T) {
+		qb := NewQueryBuilder(nil).From("users").OrderBy("name").OrderBy("id DESC")
+
+		first, _ := qb.Build()
+		second, _ := qb.Build()
+		if first != second {
+			t.Errorf("Expected Build to be repeatable, got %q and %q", first, second)
+		}
+		if first != "SELECT * FROM users ORDER BY name, id DESC" {
+			t.Errorf("Unexpected query: %s", first)
+		}
+	})
+}
+
 func TestMigrationRunner_Schema(t *testing.T) {
 	if testDB == nil {
 		t.Skip("Database not available")
@@ -757,6 +1280,54 @@ func BenchmarkUserRepository_GetByID(b *testing.B) {
 	})
 }
 
+func BenchmarkStatementCache(b *testing.B) {
+	if testDB == nil {
+		b.Skip("Database not available")
+	}
+
+	helper := NewTestHelper(testDB)
+	helper.TruncateAll()
+
+	users, err := helper.SeedUsers(100)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	const query = "SELECT * FROM users WHERE id = $1"
+
+	b.Run("Cached", func(b *testing.B) {
+		cache := NewStatementCache(testDB)
+		defer cache.Close()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			stmt, err := cache.Prepare(query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var user User
+			if err := stmt.Get(&user, users[i%len(users)].ID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			stmt, err := testDB.Preparex(query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var user User
+			err = stmt.Get(&user, users[i%len(users)].ID)
+			stmt.Close()
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func BenchmarkUserRepository_BatchInsert(b *testing.B) {
 	if testDB == nil {
 		b.Skip("Database not available")
@@ -5,6 +5,11 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -31,18 +36,79 @@ type UserFilter struct {
 	Offset int
 }
 
+// connectTimeout bounds how long NewDBCluster waits for each database to answer a ping
+const connectTimeout = 5 * time.Second
+
 // DBCluster manages primary and replica databases
 type DBCluster struct {
 	primary   *sqlx.DB
 	replicas  []*sqlx.DB
 	mu        sync.RWMutex
 	current   int
+
+	// warnings holds replica connection failures tolerated at startup
+	warnings []error
+
+	// demoted holds former primaries waiting to rejoin as replicas
+	demoted []*sqlx.DB
 }
 
-// NewDBCluster creates a new database cluster
+// NewDBCluster creates a new database cluster.
+// The primary must be reachable. Replicas that cannot be reached are skipped and
+// reported via Warnings, but an error is returned if every replica fails.
 func NewDBCluster(primaryDSN string, replicaDSNs []string) (*DBCluster, error) {
-	// TODO: プライマリとレプリカデータベースクラスターを初期化
-	panic("Not yet implemented")
+	primary, err := openDB(primaryDSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to primary: %w", err)
+	}
+
+	var replicaErrs []error
+	replicas := make([]*sqlx.DB, 0, len(replicaDSNs))
+	for i, dsn := range replicaDSNs {
+		replica, err := openDB(dsn)
+		if err != nil {
+			replicaErrs = append(replicaErrs, fmt.Errorf("failed to connect to replica %d: %w", i, err))
+			continue
+		}
+		replicas = append(replicas, replica)
+	}
+
+	if len(replicaDSNs) > 0 && len(replicas) == 0 {
+		primary.Close()
+		return nil, fmt.Errorf("no replicas available: %w", errors.Join(replicaErrs...))
+	}
+
+	for _, err := range replicaErrs {
+		log.Printf("warning: %v", err)
+	}
+
+	return &DBCluster{
+		primary:  primary,
+		replicas: replicas,
+		warnings: replicaErrs,
+	}, nil
+}
+
+// openDB opens a connection pool and verifies it with a ping
+func openDB(dsn string) (*sqlx.DB, error) {
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Warnings returns the replica connection errors tolerated by NewDBCluster
+func (cluster *DBCluster) Warnings() []error {
+	return cluster.warnings
 }
 
 // GetPrimary returns the primary database for write operations
@@ -63,10 +129,38 @@ func (cluster *DBCluster) GetHealthyReplicas() []*sqlx.DB {
 	panic("Not yet implemented")
 }
 
+// replicaSnapshot returns a copy of the replica pool that is safe to iterate
+// while a failover reshapes the cluster
+func (cluster *DBCluster) replicaSnapshot() []*sqlx.DB {
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+	return append([]*sqlx.DB(nil), cluster.replicas...)
+}
+
 // Close closes all database connections
 func (cluster *DBCluster) Close() error {
-	// TODO: 全てのデータベース接続を閉じる
-	panic("Not yet implemented")
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+
+	var errs []error
+
+	if err := cluster.primary.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close primary: %w", err))
+	}
+
+	for i, replica := range cluster.replicas {
+		if err := replica.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close replica %d: %w", i, err))
+		}
+	}
+
+	for i, db := range cluster.demoted {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close demoted primary %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 // RoutingStrategy defines how to select replicas
@@ -82,40 +176,126 @@ type RoundRobinStrategy struct {
 
 // NewRoundRobinStrategy creates a new round-robin strategy
 func NewRoundRobinStrategy() *RoundRobinStrategy {
-	// TODO: ラウンドロビン戦略を初期化
-	panic("Not yet implemented")
+	return &RoundRobinStrategy{}
 }
 
 // SelectReplica selects a replica using round-robin
 func (rr *RoundRobinStrategy) SelectReplica(replicas []*sqlx.DB, metrics *RoutingMetrics) *sqlx.DB {
-	// TODO: ラウンドロビンでレプリカを選択
-	panic("Not yet implemented")
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	// レプリカ数が変わっても範囲外にならないよう毎回剰余を取る
+	replica := replicas[rr.current%len(replicas)]
+	rr.current = (rr.current + 1) % len(replicas)
+	return replica
 }
 
 // WeightedStrategy implements weighted replica selection
 type WeightedStrategy struct {
 	weights []int
+	total   int
 	mu      sync.RWMutex
 }
 
 // NewWeightedStrategy creates a new weighted strategy
 func NewWeightedStrategy(weights []int) *WeightedStrategy {
-	// TODO: 重み付き戦略を初期化
-	panic("Not yet implemented")
+	// 負の重みは0として扱い、選択対象から外す
+	copied := make([]int, len(weights))
+	total := 0
+	for i, w := range weights {
+		if w > 0 {
+			copied[i] = w
+			total += w
+		}
+	}
+	return &WeightedStrategy{
+		weights: copied,
+		total:   total,
+	}
 }
 
 // SelectReplica selects a replica using weights
 func (ws *WeightedStrategy) SelectReplica(replicas []*sqlx.DB, metrics *RoutingMetrics) *sqlx.DB {
-	// TODO: 重み付けでレプリカを選択
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	if len(replicas) == 0 {
+		return nil
+	}
+	if len(ws.weights) != len(replicas) || ws.total == 0 {
+		// 重みが使えない場合は一様に選択
+		return replicas[rand.Intn(len(replicas))]
+	}
+
+	// 重み0のレプリカは累積値が増えないため選ばれない
+	r := rand.Intn(ws.total)
+	cumulative := 0
+	for i, weight := range ws.weights {
+		cumulative += weight
+		if r < cumulative {
+			return replicas[i]
+		}
+	}
+
+	return replicas[len(replicas)-1]
+}
+
+// LeastConnectionsStrategy selects the replica with the fewest in-flight reads
+type LeastConnectionsStrategy struct {
+	next uint64 // 同数のときに先頭へ偏らないよう開始位置をずらす
+}
+
+// NewLeastConnectionsStrategy creates a new least-connections strategy
+func NewLeastConnectionsStrategy() *LeastConnectionsStrategy {
+	// TODO: 最小接続数戦略を初期化
+	panic("Not yet implemented")
+}
+
+// SelectReplica selects the replica with the fewest in-flight reads
+func (lc *LeastConnectionsStrategy) SelectReplica(replicas []*sqlx.DB, metrics *RoutingMetrics) *sqlx.DB {
+	// TODO: metrics.InFlightが最小のレプリカを選択
+	// - 空ならnil、metricsがnilなら先頭を返す
+	// - 同数の場合に偏らないよう、走査の開始位置をnextでずらす
 	panic("Not yet implemented")
 }
 
+// ReadTier identifies a stage of the read fallback chain
+type ReadTier int
+
+const (
+	// ReadTierLowLag selects among healthy replicas within the lag threshold
+	ReadTierLowLag ReadTier = iota
+	// ReadTierHealthy selects among any healthy replicas regardless of lag
+	ReadTierHealthy
+	// ReadTierPrimary sends the read to the primary
+	ReadTierPrimary
+)
+
+func (t ReadTier) String() string {
+	switch t {
+	case ReadTierLowLag:
+		return "low_lag"
+	case ReadTierHealthy:
+		return "healthy"
+	case ReadTierPrimary:
+		return "primary"
+	default:
+		return fmt.Sprintf("ReadTier(%d)", int(t))
+	}
+}
+
 // RoutingMetrics holds routing performance metrics
 type RoutingMetrics struct {
 	ReadCount    int64
 	WriteCount   int64
 	ErrorCount   int64
 	ResponseTime map[*sqlx.DB]time.Duration
+	tierCounts   map[ReadTier]int64
+	inFlight     map[*sqlx.DB]*int64 // 実行中の読み取り数（アトミックに更新）
 	mu           sync.RWMutex
 }
 
@@ -149,6 +329,36 @@ func (rm *RoutingMetrics) GetStats() (int64, int64, int64) {
 	panic("Not yet implemented")
 }
 
+// RecordReadTier records which fallback tier served a read
+func (rm *RoutingMetrics) RecordReadTier(tier ReadTier) {
+	// TODO: tierCountsをロックを取って加算
+	panic("Not yet implemented")
+}
+
+// GetReadTierCount returns how many reads were served by the given tier
+func (rm *RoutingMetrics) GetReadTierCount(tier ReadTier) int64 {
+	// TODO: tierCountsから件数を返す
+	panic("Not yet implemented")
+}
+
+// IncInFlight marks a read as dispatched to db
+func (rm *RoutingMetrics) IncInFlight(db *sqlx.DB) {
+	// TODO: dbの実行中読み取り数をアトミックに加算
+	panic("Not yet implemented")
+}
+
+// DecInFlight marks a read on db as completed
+func (rm *RoutingMetrics) DecInFlight(db *sqlx.DB) {
+	// TODO: dbの実行中読み取り数をアトミックに減算
+	panic("Not yet implemented")
+}
+
+// InFlight returns the number of reads currently dispatched to db
+func (rm *RoutingMetrics) InFlight(db *sqlx.DB) int64 {
+	// TODO: dbの実行中読み取り数を返す（未登録なら0）
+	panic("Not yet implemented")
+}
+
 // RoutingManager handles read-write routing
 type RoutingManager struct {
 	cluster    *DBCluster
@@ -156,6 +366,8 @@ type RoutingManager struct {
 	health     *HealthMonitor
 	lagDetector *LagDetector
 	metrics    *RoutingMetrics
+	fallbackChain []ReadTier
+	mu         sync.RWMutex
 }
 
 // NewRoutingManager creates a new routing manager
@@ -164,9 +376,26 @@ func NewRoutingManager(cluster *DBCluster, strategy RoutingStrategy) *RoutingMan
 	panic("Not yet implemented")
 }
 
+// SetFallbackChain replaces the ordered tiers RouteRead tries.
+// If no tier yields a database, RouteRead returns nil and records an error.
+func (rm *RoutingManager) SetFallbackChain(tiers ...ReadTier) {
+	// TODO: フォールバックチェーンをコピーして差し替える
+	panic("Not yet implemented")
+}
+
 // RouteRead routes read operations to appropriate replica
 func (rm *RoutingManager) RouteRead(ctx context.Context) *sqlx.DB {
 	// TODO: 読み取り操作を適切なレプリカにルーティング
+	// - fallbackChain（既定: 低ラグ → 健全 → プライマリ）を順に試す
+	// - 選ばれたティアをmetrics.RecordReadTierで記録
+	// - 選ばれたDBの実行中読み取り数をmetrics.IncInFlightで加算
+	// - どのティアでも見つからなければRecordErrorしてnilを返す
+	panic("Not yet implemented")
+}
+
+// DoneRead must be called once a read routed by RouteRead has completed
+func (rm *RoutingManager) DoneRead(db *sqlx.DB) {
+	// TODO: RouteReadで加算した実行中読み取り数を減算
 	panic("Not yet implemented")
 }
 
@@ -209,36 +438,110 @@ func (hm *HealthMonitor) IsHealthy(db *sqlx.DB) bool {
 	panic("Not yet implemented")
 }
 
+// SetHealthy overrides the health status of a database until the next check
+func (hm *HealthMonitor) SetHealthy(db *sqlx.DB, healthy bool) {
+	// TODO: healthMapの状態を上書き
+	panic("Not yet implemented")
+}
+
 // GetHealthyReplicas returns healthy replicas
 func (hm *HealthMonitor) GetHealthyReplicas() []*sqlx.DB {
 	// TODO: 健全なレプリカを返す
 	panic("Not yet implemented")
 }
 
+// LagProbe measures the replication lag of a single replica
+type LagProbe func(ctx context.Context, replica *sqlx.DB) (time.Duration, error)
+
+// InfiniteLag marks a replica whose lag could not be measured
+const InfiniteLag = time.Duration(math.MaxInt64)
+
+// replicationLagQuery returns seconds since the last replayed transaction.
+// pg_last_xact_replay_timestamp() is NULL on a server that is not a standby,
+// which is treated as no lag.
+const replicationLagQuery = `SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)`
+
+// queryReplicationLag is the default LagProbe backed by pg_last_xact_replay_timestamp
+func queryReplicationLag(ctx context.Context, replica *sqlx.DB) (time.Duration, error) {
+	var seconds float64
+	if err := replica.GetContext(ctx, &seconds, replicationLagQuery); err != nil {
+		return 0, err
+	}
+	if seconds < 0 {
+		seconds = 0 // クロックのずれで負になることがある
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
 // LagDetector monitors replication lag
 type LagDetector struct {
-	cluster   *DBCluster
-	maxLag    time.Duration
-	lagMap    map[*sqlx.DB]time.Duration
-	mu        sync.RWMutex
+	cluster *DBCluster
+	maxLag  time.Duration
+	lagMap  map[*sqlx.DB]time.Duration
+	probe   LagProbe
+	mu      sync.RWMutex
 }
 
 // NewLagDetector creates a new lag detector
 func NewLagDetector(cluster *DBCluster, maxLag time.Duration) *LagDetector {
-	// TODO: ラグディテクターを初期化
-	panic("Not yet implemented")
+	return &LagDetector{
+		cluster: cluster,
+		maxLag:  maxLag,
+		lagMap:  make(map[*sqlx.DB]time.Duration),
+	}
 }
 
-// CheckReplicationLag checks replication lag for all replicas
-func (ld *LagDetector) CheckReplicationLag(ctx context.Context) (map[*sqlx.DB]time.Duration, error) {
-	// TODO: 全レプリカのレプリケーションラグをチェック
-	panic("Not yet implemented")
+// SetLagProbe replaces how replica lag is measured (nil restores the default)
+func (ld *LagDetector) SetLagProbe(probe LagProbe) {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+	ld.probe = probe
 }
 
-// GetLowLagReplicas returns replicas with acceptable lag
+// CheckReplicationLag checks replication lag for all replicas
+func (ld *LagDetector) CheckReplicationLag(ctx context.Context) (map[*sqlx.DB]time.Duration, error) {
+	ld.mu.RLock()
+	probe := ld.probe
+	ld.mu.RUnlock()
+
+	if probe == nil {
+		probe = queryReplicationLag
+	}
+
+	replicas := ld.cluster.replicaSnapshot()
+	lagMap := make(map[*sqlx.DB]time.Duration, len(replicas))
+	for _, replica := range replicas {
+		lag, err := probe(ctx, replica)
+		if err != nil {
+			// 測定できないレプリカは無限に遅延しているとみなして除外する
+			lagMap[replica] = InfiniteLag
+			continue
+		}
+		lagMap[replica] = lag
+	}
+
+	ld.mu.Lock()
+	ld.lagMap = lagMap
+	ld.mu.Unlock()
+
+	return lagMap, nil
+}
+
+// GetLowLagReplicas returns replicas whose lag is under maxLag, in cluster order
 func (ld *LagDetector) GetLowLagReplicas(ctx context.Context) ([]*sqlx.DB, error) {
-	// TODO: 許容可能なラグのレプリカを返す
-	panic("Not yet implemented")
+	lagMap, err := ld.CheckReplicationLag(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lowLagReplicas := make([]*sqlx.DB, 0, len(lagMap))
+	for _, replica := range ld.cluster.replicaSnapshot() {
+		if lag, ok := lagMap[replica]; ok && lag < ld.maxLag {
+			lowLagReplicas = append(lowLagReplicas, replica)
+		}
+	}
+
+	return lowLagReplicas, nil
 }
 
 // LoadBalancer distributes load across replicas
@@ -259,30 +562,126 @@ func (lb *LoadBalancer) SelectReplica(replicas []*sqlx.DB) *sqlx.DB {
 	panic("Not yet implemented")
 }
 
+// ErrFailoverInProgress is returned when another failover is already running
+var ErrFailoverInProgress = errors.New("failover already in progress")
+
+// ErrNoFailoverCandidate is returned when no healthy replica can be promoted
+var ErrNoFailoverCandidate = errors.New("no healthy replicas available for failover")
+
 // FailoverManager handles automatic failover
 type FailoverManager struct {
-	cluster       *DBCluster
-	health        *HealthMonitor
+	cluster            *DBCluster
+	health             *HealthMonitor
+	lagDetector        *LagDetector
 	failoverInProgress bool
-	mu            sync.Mutex
+	mu                 sync.Mutex
 }
 
 // NewFailoverManager creates a new failover manager
 func NewFailoverManager(cluster *DBCluster, health *HealthMonitor) *FailoverManager {
-	// TODO: フェイルオーバーマネージャーを初期化
-	panic("Not yet implemented")
+	return &FailoverManager{
+		cluster:     cluster,
+		health:      health,
+		lagDetector: NewLagDetector(cluster, 0),
+	}
 }
 
-// HandlePrimaryFailure handles primary database failure
+// HandlePrimaryFailure promotes the lowest-lag healthy replica once the health
+// monitor reports the primary as down. It does nothing while the primary is healthy.
 func (fm *FailoverManager) HandlePrimaryFailure(ctx context.Context) error {
-	// TODO: プライマリデータベース障害を処理
-	panic("Not yet implemented")
-}
-
-// PromoteReplica promotes a replica to primary
+	fm.mu.Lock()
+	if fm.failoverInProgress {
+		fm.mu.Unlock()
+		return ErrFailoverInProgress
+	}
+	fm.failoverInProgress = true
+	fm.mu.Unlock()
+
+	defer func() {
+		fm.mu.Lock()
+		fm.failoverInProgress = false
+		fm.mu.Unlock()
+	}()
+
+	if fm.health.IsHealthy(fm.cluster.GetPrimary()) {
+		return nil
+	}
+
+	candidate, err := fm.selectCandidate(ctx)
+	if err != nil {
+		return err
+	}
+	return fm.PromoteReplica(candidate)
+}
+
+// selectCandidate picks the healthy replica with the smallest measured lag
+func (fm *FailoverManager) selectCandidate(ctx context.Context) (*sqlx.DB, error) {
+	lagMap, err := fm.lagDetector.CheckReplicationLag(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure replication lag: %w", err)
+	}
+
+	var best *sqlx.DB
+	bestLag := InfiniteLag
+	for _, replica := range fm.health.GetHealthyReplicas() {
+		// 遅延を測定できなかったレプリカ (InfiniteLag) は昇格させない
+		if lag, ok := lagMap[replica]; ok && lag < bestLag {
+			best, bestLag = replica, lag
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoFailoverCandidate
+	}
+	return best, nil
+}
+
+// PromoteReplica promotes a replica to primary.
+// The old primary is held back from the replica pool until ReinstateRecovered
+// sees it healthy again.
 func (fm *FailoverManager) PromoteReplica(replica *sqlx.DB) error {
-	// TODO: レプリカをプライマリに昇格
-	panic("Not yet implemented")
+	fm.cluster.mu.Lock()
+	defer fm.cluster.mu.Unlock()
+
+	remaining := make([]*sqlx.DB, 0, len(fm.cluster.replicas))
+	found := false
+	for _, r := range fm.cluster.replicas {
+		if r == replica {
+			found = true
+			continue
+		}
+		remaining = append(remaining, r)
+	}
+	if !found {
+		return errors.New("cannot promote a database that is not a replica in the cluster")
+	}
+
+	fm.cluster.replicas = remaining
+	fm.cluster.demoted = append(fm.cluster.demoted, fm.cluster.primary)
+	fm.cluster.primary = replica
+
+	return nil
+}
+
+// ReinstateRecovered returns demoted primaries that the health monitor reports
+// healthy again to the replica pool, and reports how many rejoined
+func (fm *FailoverManager) ReinstateRecovered() int {
+	fm.cluster.mu.Lock()
+	defer fm.cluster.mu.Unlock()
+
+	stillDown := make([]*sqlx.DB, 0, len(fm.cluster.demoted))
+	reinstated := 0
+	for _, db := range fm.cluster.demoted {
+		if !fm.health.IsHealthy(db) {
+			stillDown = append(stillDown, db)
+			continue
+		}
+		fm.cluster.replicas = append(fm.cluster.replicas, db)
+		reinstated++
+	}
+	fm.cluster.demoted = stillDown
+
+	return reinstated
 }
 
 // UserService demonstrates read-write splitting
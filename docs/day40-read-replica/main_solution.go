@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
+	"math"
 	"math/rand"
 	"sync"
 	"sync/atomic"
@@ -33,64 +35,93 @@ type UserFilter struct {
 	Offset int
 }
 
+// connectTimeout bounds how long NewDBCluster waits for each database to answer a ping
+const connectTimeout = 5 * time.Second
+
 // DBCluster manages primary and replica databases
 type DBCluster struct {
 	primary  *sqlx.DB
 	replicas []*sqlx.DB
 	mu       sync.RWMutex
 	current  int64 // Atomic counter for round-robin
+
+	// warnings holds replica connection failures tolerated at startup
+	warnings []error
+
+	// demoted holds former primaries waiting to rejoin as replicas
+	demoted []*sqlx.DB
 }
 
-// NewDBCluster creates a new database cluster
+// NewDBCluster creates a new database cluster.
+// The primary must be reachable. Replicas that cannot be reached are skipped and
+// reported via Warnings, but an error is returned if every replica fails.
 func NewDBCluster(primaryDSN string, replicaDSNs []string) (*DBCluster, error) {
-	primary, err := sqlx.Open("postgres", primaryDSN)
+	primary, err := openDB(primaryDSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to primary: %w", err)
 	}
 
-	if err := primary.Ping(); err != nil {
-		primary.Close()
-		return nil, fmt.Errorf("failed to ping primary: %w", err)
-	}
-
+	var replicaErrs []error
 	replicas := make([]*sqlx.DB, 0, len(replicaDSNs))
 	for i, dsn := range replicaDSNs {
-		replica, err := sqlx.Open("postgres", dsn)
+		replica, err := openDB(dsn)
 		if err != nil {
-			// Close previously opened connections
-			for _, r := range replicas {
-				r.Close()
-			}
-			primary.Close()
-			return nil, fmt.Errorf("failed to connect to replica %d: %w", i, err)
+			replicaErrs = append(replicaErrs, fmt.Errorf("failed to connect to replica %d: %w", i, err))
+			continue
 		}
+		replicas = append(replicas, replica)
+	}
 
-		if err := replica.Ping(); err != nil {
-			replica.Close()
-			// Close previously opened connections
-			for _, r := range replicas {
-				r.Close()
-			}
-			primary.Close()
-			return nil, fmt.Errorf("failed to ping replica %d: %w", i, err)
-		}
+	if len(replicaDSNs) > 0 && len(replicas) == 0 {
+		primary.Close()
+		return nil, fmt.Errorf("no replicas available: %w", errors.Join(replicaErrs...))
+	}
 
-		replicas = append(replicas, replica)
+	for _, err := range replicaErrs {
+		log.Printf("warning: %v", err)
 	}
 
 	return &DBCluster{
 		primary:  primary,
 		replicas: replicas,
+		warnings: replicaErrs,
 	}, nil
 }
 
+// openDB opens a connection pool and verifies it with a ping
+func openDB(dsn string) (*sqlx.DB, error) {
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Warnings returns the replica connection errors tolerated by NewDBCluster
+func (cluster *DBCluster) Warnings() []error {
+	return cluster.warnings
+}
+
 // GetPrimary returns the primary database for write operations
 func (cluster *DBCluster) GetPrimary() *sqlx.DB {
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
 	return cluster.primary
 }
 
 // GetReplica returns a replica database for read operations
 func (cluster *DBCluster) GetReplica() *sqlx.DB {
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+
 	if len(cluster.replicas) == 0 {
 		return cluster.primary // Fallback to primary if no replicas
 	}
@@ -114,8 +145,19 @@ func (cluster *DBCluster) GetHealthyReplicas() []*sqlx.DB {
 	return healthy
 }
 
+// replicaSnapshot returns a copy of the replica pool that is safe to iterate
+// while a failover reshapes the cluster
+func (cluster *DBCluster) replicaSnapshot() []*sqlx.DB {
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+	return append([]*sqlx.DB(nil), cluster.replicas...)
+}
+
 // Close closes all database connections
 func (cluster *DBCluster) Close() error {
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+
 	var errs []error
 
 	if err := cluster.primary.Close(); err != nil {
@@ -128,10 +170,13 @@ func (cluster *DBCluster) Close() error {
 		}
 	}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("errors closing connections: %v", errs)
+	for i, db := range cluster.demoted {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close demoted primary %d: %w", i, err))
+		}
 	}
-	return nil
+
+	return errors.Join(errs...)
 }
 
 // RoutingStrategy defines how to select replicas
@@ -168,12 +213,17 @@ type WeightedStrategy struct {
 
 // NewWeightedStrategy creates a new weighted strategy
 func NewWeightedStrategy(weights []int) *WeightedStrategy {
+	// Negative weights are treated as 0 so the replica is never selected
+	copied := make([]int, len(weights))
 	total := 0
-	for _, w := range weights {
-		total += w
+	for i, w := range weights {
+		if w > 0 {
+			copied[i] = w
+			total += w
+		}
 	}
 	return &WeightedStrategy{
-		weights: append([]int(nil), weights...), // Copy slice
+		weights: copied,
 		total:   total,
 	}
 }
@@ -183,15 +233,15 @@ func (ws *WeightedStrategy) SelectReplica(replicas []*sqlx.DB, metrics *RoutingM
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
 
-	if len(replicas) == 0 || len(ws.weights) != len(replicas) || ws.total == 0 {
-		// Fallback to round-robin
-		if len(replicas) > 0 {
-			return replicas[rand.Intn(len(replicas))]
-		}
+	if len(replicas) == 0 {
 		return nil
 	}
+	if len(ws.weights) != len(replicas) || ws.total == 0 {
+		// Fall back to uniform selection when weights cannot be applied
+		return replicas[rand.Intn(len(replicas))]
+	}
 
-	// Weighted random selection
+	// Weighted random selection; zero-weight replicas never advance the cumulative sum
 	r := rand.Intn(ws.total)
 	cumulative := 0
 	for i, weight := range ws.weights {
@@ -205,12 +255,71 @@ func (ws *WeightedStrategy) SelectReplica(replicas []*sqlx.DB, metrics *RoutingM
 	return replicas[0]
 }
 
+// LeastConnectionsStrategy selects the replica with the fewest in-flight reads
+type LeastConnectionsStrategy struct {
+	next uint64 // 同数のときに先頭へ偏らないよう開始位置をずらす
+}
+
+// NewLeastConnectionsStrategy creates a new least-connections strategy
+func NewLeastConnectionsStrategy() *LeastConnectionsStrategy {
+	return &LeastConnectionsStrategy{}
+}
+
+// SelectReplica selects the replica with the fewest in-flight reads
+func (lc *LeastConnectionsStrategy) SelectReplica(replicas []*sqlx.DB, metrics *RoutingMetrics) *sqlx.DB {
+	if len(replicas) == 0 {
+		return nil
+	}
+	if metrics == nil {
+		return replicas[0]
+	}
+
+	offset := int(atomic.AddUint64(&lc.next, 1) % uint64(len(replicas)))
+	var best *sqlx.DB
+	var bestInFlight int64
+	for i := range replicas {
+		replica := replicas[(offset+i)%len(replicas)]
+		inFlight := metrics.InFlight(replica)
+		if best == nil || inFlight < bestInFlight {
+			best, bestInFlight = replica, inFlight
+		}
+	}
+	return best
+}
+
+// ReadTier identifies a stage of the read fallback chain
+type ReadTier int
+
+const (
+	// ReadTierLowLag selects among healthy replicas within the lag threshold
+	ReadTierLowLag ReadTier = iota
+	// ReadTierHealthy selects among any healthy replicas regardless of lag
+	ReadTierHealthy
+	// ReadTierPrimary sends the read to the primary
+	ReadTierPrimary
+)
+
+func (t ReadTier) String() string {
+	switch t {
+	case ReadTierLowLag:
+		return "low_lag"
+	case ReadTierHealthy:
+		return "healthy"
+	case ReadTierPrimary:
+		return "primary"
+	default:
+		return fmt.Sprintf("ReadTier(%d)", int(t))
+	}
+}
+
 // RoutingMetrics holds routing performance metrics
 type RoutingMetrics struct {
 	readCount    int64
 	writeCount   int64
 	errorCount   int64
 	responseTime map[*sqlx.DB]int64 // nanoseconds as int64 for atomic operations
+	tierCounts   map[ReadTier]int64
+	inFlight     map[*sqlx.DB]*int64 // 実行中の読み取り数（アトミックに更新）
 	mu           sync.RWMutex
 }
 
@@ -218,9 +327,25 @@ type RoutingMetrics struct {
 func NewRoutingMetrics() *RoutingMetrics {
 	return &RoutingMetrics{
 		responseTime: make(map[*sqlx.DB]int64),
+		tierCounts:   make(map[ReadTier]int64),
+		inFlight:     make(map[*sqlx.DB]*int64),
 	}
 }
 
+// RecordReadTier records which fallback tier served a read
+func (rm *RoutingMetrics) RecordReadTier(tier ReadTier) {
+	rm.mu.Lock()
+	rm.tierCounts[tier]++
+	rm.mu.Unlock()
+}
+
+// GetReadTierCount returns how many reads were served by the given tier
+func (rm *RoutingMetrics) GetReadTierCount(tier ReadTier) int64 {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.tierCounts[tier]
+}
+
 // RecordRead records a read operation
 func (rm *RoutingMetrics) RecordRead(db *sqlx.DB, duration time.Duration) {
 	atomic.AddInt64(&rm.readCount, 1)
@@ -247,27 +372,78 @@ func (rm *RoutingMetrics) GetStats() (int64, int64, int64) {
 		   atomic.LoadInt64(&rm.errorCount)
 }
 
+// IncInFlight marks a read as dispatched to db
+func (rm *RoutingMetrics) IncInFlight(db *sqlx.DB) {
+	atomic.AddInt64(rm.inFlightCounter(db), 1)
+}
+
+// DecInFlight marks a read on db as completed
+func (rm *RoutingMetrics) DecInFlight(db *sqlx.DB) {
+	atomic.AddInt64(rm.inFlightCounter(db), -1)
+}
+
+// InFlight returns the number of reads currently dispatched to db
+func (rm *RoutingMetrics) InFlight(db *sqlx.DB) int64 {
+	rm.mu.RLock()
+	counter, ok := rm.inFlight[db]
+	rm.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt64(counter)
+}
+
+// inFlightCounter returns the counter for db, creating it on first use
+func (rm *RoutingMetrics) inFlightCounter(db *sqlx.DB) *int64 {
+	rm.mu.RLock()
+	counter, ok := rm.inFlight[db]
+	rm.mu.RUnlock()
+	if ok {
+		return counter
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if counter, ok := rm.inFlight[db]; ok {
+		return counter
+	}
+	counter = new(int64)
+	rm.inFlight[db] = counter
+	return counter
+}
+
 // RoutingManager handles read-write routing
 type RoutingManager struct {
-	cluster     *DBCluster
-	strategy    RoutingStrategy
-	health      *HealthMonitor
-	lagDetector *LagDetector
-	metrics     *RoutingMetrics
+	cluster       *DBCluster
+	strategy      RoutingStrategy
+	health        *HealthMonitor
+	lagDetector   *LagDetector
+	metrics       *RoutingMetrics
+	fallbackChain []ReadTier
+	mu            sync.RWMutex
 }
 
 // NewRoutingManager creates a new routing manager
 func NewRoutingManager(cluster *DBCluster, strategy RoutingStrategy) *RoutingManager {
 	rm := &RoutingManager{
-		cluster:     cluster,
-		strategy:    strategy,
-		metrics:     NewRoutingMetrics(),
-		lagDetector: NewLagDetector(cluster, 100*time.Millisecond),
+		cluster:       cluster,
+		strategy:      strategy,
+		metrics:       NewRoutingMetrics(),
+		lagDetector:   NewLagDetector(cluster, 100*time.Millisecond),
+		fallbackChain: []ReadTier{ReadTierLowLag, ReadTierHealthy, ReadTierPrimary},
 	}
 	rm.health = NewHealthMonitor(cluster, 30*time.Second)
 	return rm
 }
 
+// SetFallbackChain replaces the ordered tiers RouteRead tries.
+// If no tier yields a database, RouteRead returns nil and records an error.
+func (rm *RoutingManager) SetFallbackChain(tiers ...ReadTier) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.fallbackChain = append([]ReadTier(nil), tiers...)
+}
+
 // RouteRead routes read operations to appropriate replica
 func (rm *RoutingManager) RouteRead(ctx context.Context) *sqlx.DB {
 	start := time.Now()
@@ -276,32 +452,78 @@ func (rm *RoutingManager) RouteRead(ctx context.Context) *sqlx.DB {
 		rm.metrics.RecordRead(nil, duration) // Simplified for this example
 	}()
 
-	// Get healthy replicas with low lag
-	var replicas []*sqlx.DB
-	if rm.health != nil {
-		replicas = rm.health.GetHealthyReplicas()
-	} else {
-		replicas = rm.cluster.GetHealthyReplicas()
+	rm.mu.RLock()
+	chain := rm.fallbackChain
+	rm.mu.RUnlock()
+
+	for _, tier := range chain {
+		if db := rm.selectFromTier(ctx, tier); db != nil {
+			rm.metrics.RecordReadTier(tier)
+			// 呼び出し側が完了時にDoneReadで減算する
+			rm.metrics.IncInFlight(db)
+			return db
+		}
 	}
 
-	if rm.lagDetector != nil {
-		lowLagReplicas, err := rm.lagDetector.GetLowLagReplicas(ctx)
-		if err == nil && len(lowLagReplicas) > 0 {
-			replicas = lowLagReplicas
+	rm.metrics.RecordError()
+	return nil
+}
+
+// selectFromTier returns a database for the tier, or nil if none qualifies
+func (rm *RoutingManager) selectFromTier(ctx context.Context, tier ReadTier) *sqlx.DB {
+	switch tier {
+	case ReadTierLowLag:
+		if rm.lagDetector == nil {
+			return nil
+		}
+		lowLag, err := rm.lagDetector.GetLowLagReplicas(ctx)
+		if err != nil {
+			return nil
+		}
+		lowLagSet := make(map[*sqlx.DB]bool, len(lowLag))
+		for _, replica := range lowLag {
+			lowLagSet[replica] = true
 		}
+		var candidates []*sqlx.DB
+		for _, replica := range rm.healthyReplicas() {
+			if lowLagSet[replica] {
+				candidates = append(candidates, replica)
+			}
+		}
+		return rm.selectReplica(candidates)
+	case ReadTierHealthy:
+		return rm.selectReplica(rm.healthyReplicas())
+	case ReadTierPrimary:
+		return rm.cluster.GetPrimary()
+	default:
+		return nil
 	}
+}
 
-	if len(replicas) == 0 {
-		return rm.cluster.GetPrimary() // Fallback to primary
+func (rm *RoutingManager) healthyReplicas() []*sqlx.DB {
+	if rm.health != nil {
+		return rm.health.GetHealthyReplicas()
 	}
+	return rm.cluster.GetHealthyReplicas()
+}
 
+func (rm *RoutingManager) selectReplica(replicas []*sqlx.DB) *sqlx.DB {
+	if len(replicas) == 0 {
+		return nil
+	}
 	if rm.strategy != nil {
 		return rm.strategy.SelectReplica(replicas, rm.metrics)
 	}
-
 	return replicas[0]
 }
 
+// DoneRead must be called once a read routed by RouteRead has completed
+func (rm *RoutingManager) DoneRead(db *sqlx.DB) {
+	if db != nil {
+		rm.metrics.DecInFlight(db)
+	}
+}
+
 // RouteWrite routes write operations to primary
 func (rm *RoutingManager) RouteWrite(ctx context.Context) *sqlx.DB {
 	start := time.Now()
@@ -333,8 +555,8 @@ func NewHealthMonitor(cluster *DBCluster, checkInterval time.Duration) *HealthMo
 	}
 
 	// Initialize health status
-	hm.healthMap[cluster.primary] = true
-	for _, replica := range cluster.replicas {
+	hm.healthMap[cluster.GetPrimary()] = true
+	for _, replica := range cluster.replicaSnapshot() {
 		hm.healthMap[replica] = true
 	}
 
@@ -386,13 +608,22 @@ func (hm *HealthMonitor) IsHealthy(db *sqlx.DB) bool {
 	return hm.healthMap[db]
 }
 
+// SetHealthy overrides the health status of a database until the next check
+func (hm *HealthMonitor) SetHealthy(db *sqlx.DB, healthy bool) {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	hm.healthMap[db] = healthy
+}
+
 // GetHealthyReplicas returns healthy replicas
 func (hm *HealthMonitor) GetHealthyReplicas() []*sqlx.DB {
+	replicas := hm.cluster.replicaSnapshot()
+
 	hm.mu.RLock()
 	defer hm.mu.RUnlock()
 
-	healthy := make([]*sqlx.DB, 0)
-	for _, replica := range hm.cluster.replicas {
+	healthy := make([]*sqlx.DB, 0, len(replicas))
+	for _, replica := range replicas {
 		if hm.healthMap[replica] {
 			healthy = append(healthy, replica)
 		}
@@ -404,12 +635,9 @@ func (hm *HealthMonitor) checkHealth() {
 	hm.mu.Lock()
 	defer hm.mu.Unlock()
 
-	// Check primary
-	hm.healthMap[hm.cluster.primary] = hm.pingDB(hm.cluster.primary)
-
-	// Check replicas
-	for _, replica := range hm.cluster.replicas {
-		hm.healthMap[replica] = hm.pingDB(replica)
+	// Check every known database, including demoted primaries that may recover
+	for db := range hm.healthMap {
+		hm.healthMap[db] = hm.pingDB(db)
 	}
 }
 
@@ -419,11 +647,35 @@ func (hm *HealthMonitor) pingDB(db *sqlx.DB) bool {
 	return db.PingContext(ctx) == nil
 }
 
+// LagProbe measures the replication lag of a single replica
+type LagProbe func(ctx context.Context, replica *sqlx.DB) (time.Duration, error)
+
+// InfiniteLag marks a replica whose lag could not be measured
+const InfiniteLag = time.Duration(math.MaxInt64)
+
+// replicationLagQuery returns seconds since the last replayed transaction.
+// pg_last_xact_replay_timestamp() is NULL on a server that is not a standby,
+// which is treated as no lag.
+const replicationLagQuery = `SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)`
+
+// queryReplicationLag is the default LagProbe backed by pg_last_xact_replay_timestamp
+func queryReplicationLag(ctx context.Context, replica *sqlx.DB) (time.Duration, error) {
+	var seconds float64
+	if err := replica.GetContext(ctx, &seconds, replicationLagQuery); err != nil {
+		return 0, err
+	}
+	if seconds < 0 {
+		seconds = 0 // クロックのずれで負になることがある
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
 // LagDetector monitors replication lag
 type LagDetector struct {
 	cluster *DBCluster
 	maxLag  time.Duration
 	lagMap  map[*sqlx.DB]time.Duration
+	probe   LagProbe
 	mu      sync.RWMutex
 }
 
@@ -436,31 +688,32 @@ func NewLagDetector(cluster *DBCluster, maxLag time.Duration) *LagDetector {
 	}
 }
 
+// SetLagProbe replaces how replica lag is measured (nil restores the default)
+func (ld *LagDetector) SetLagProbe(probe LagProbe) {
+	ld.mu.Lock()
+	defer ld.mu.Unlock()
+	ld.probe = probe
+}
+
 // CheckReplicationLag checks replication lag for all replicas
 func (ld *LagDetector) CheckReplicationLag(ctx context.Context) (map[*sqlx.DB]time.Duration, error) {
-	lagMap := make(map[*sqlx.DB]time.Duration)
+	ld.mu.RLock()
+	probe := ld.probe
+	ld.mu.RUnlock()
 
-	// Get current time from primary
-	var primaryTime time.Time
-	err := ld.cluster.GetPrimary().GetContext(ctx, &primaryTime, "SELECT NOW()")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get primary time: %w", err)
+	if probe == nil {
+		probe = queryReplicationLag
 	}
 
-	// Check lag for each replica
-	for _, replica := range ld.cluster.replicas {
-		var replicaTime time.Time
-		err := replica.GetContext(ctx, &replicaTime, "SELECT NOW()")
+	replicas := ld.cluster.replicaSnapshot()
+	lagMap := make(map[*sqlx.DB]time.Duration, len(replicas))
+	for _, replica := range replicas {
+		lag, err := probe(ctx, replica)
 		if err != nil {
-			lagMap[replica] = time.Hour // Mark as severely lagged
+			// 測定できないレプリカは無限に遅延しているとみなして除外する
+			lagMap[replica] = InfiniteLag
 			continue
 		}
-
-		lag := primaryTime.Sub(replicaTime)
-		if lag < 0 {
-			lag = 0 // Replica might be ahead due to clock differences
-		}
-
 		lagMap[replica] = lag
 	}
 
@@ -471,16 +724,16 @@ func (ld *LagDetector) CheckReplicationLag(ctx context.Context) (map[*sqlx.DB]ti
 	return lagMap, nil
 }
 
-// GetLowLagReplicas returns replicas with acceptable lag
+// GetLowLagReplicas returns replicas whose lag is under maxLag, in cluster order
 func (ld *LagDetector) GetLowLagReplicas(ctx context.Context) ([]*sqlx.DB, error) {
 	lagMap, err := ld.CheckReplicationLag(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	lowLagReplicas := make([]*sqlx.DB, 0)
-	for replica, lag := range lagMap {
-		if lag <= ld.maxLag {
+	lowLagReplicas := make([]*sqlx.DB, 0, len(lagMap))
+	for _, replica := range ld.cluster.replicaSnapshot() {
+		if lag, ok := lagMap[replica]; ok && lag < ld.maxLag {
 			lowLagReplicas = append(lowLagReplicas, replica)
 		}
 	}
@@ -515,10 +768,17 @@ func (lb *LoadBalancer) SelectReplica(replicas []*sqlx.DB) *sqlx.DB {
 	return replicas[0]
 }
 
+// ErrFailoverInProgress is returned when another failover is already running
+var ErrFailoverInProgress = errors.New("failover already in progress")
+
+// ErrNoFailoverCandidate is returned when no healthy replica can be promoted
+var ErrNoFailoverCandidate = errors.New("no healthy replicas available for failover")
+
 // FailoverManager handles automatic failover
 type FailoverManager struct {
 	cluster            *DBCluster
 	health             *HealthMonitor
+	lagDetector        *LagDetector
 	failoverInProgress bool
 	mu                 sync.Mutex
 }
@@ -526,67 +786,110 @@ type FailoverManager struct {
 // NewFailoverManager creates a new failover manager
 func NewFailoverManager(cluster *DBCluster, health *HealthMonitor) *FailoverManager {
 	return &FailoverManager{
-		cluster: cluster,
-		health:  health,
+		cluster:     cluster,
+		health:      health,
+		lagDetector: NewLagDetector(cluster, 0),
 	}
 }
 
-// HandlePrimaryFailure handles primary database failure
+// HandlePrimaryFailure promotes the lowest-lag healthy replica once the health
+// monitor reports the primary as down. It does nothing while the primary is healthy.
 func (fm *FailoverManager) HandlePrimaryFailure(ctx context.Context) error {
 	fm.mu.Lock()
-	defer fm.mu.Unlock()
-
 	if fm.failoverInProgress {
-		return errors.New("failover already in progress")
+		fm.mu.Unlock()
+		return ErrFailoverInProgress
 	}
-
 	fm.failoverInProgress = true
+	fm.mu.Unlock()
+
 	defer func() {
+		fm.mu.Lock()
 		fm.failoverInProgress = false
+		fm.mu.Unlock()
 	}()
 
-	// Find a healthy replica to promote
-	healthyReplicas := fm.health.GetHealthyReplicas()
-	if len(healthyReplicas) == 0 {
-		return errors.New("no healthy replicas available for failover")
+	if fm.health.IsHealthy(fm.cluster.GetPrimary()) {
+		return nil
 	}
 
-	// Promote the first healthy replica
-	newPrimary := healthyReplicas[0]
-	return fm.PromoteReplica(newPrimary)
+	candidate, err := fm.selectCandidate(ctx)
+	if err != nil {
+		return err
+	}
+	return fm.PromoteReplica(candidate)
 }
 
-// PromoteReplica promotes a replica to primary
+// selectCandidate picks the healthy replica with the smallest measured lag
+func (fm *FailoverManager) selectCandidate(ctx context.Context) (*sqlx.DB, error) {
+	lagMap, err := fm.lagDetector.CheckReplicationLag(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure replication lag: %w", err)
+	}
+
+	var best *sqlx.DB
+	bestLag := InfiniteLag
+	for _, replica := range fm.health.GetHealthyReplicas() {
+		// 遅延を測定できなかったレプリカ (InfiniteLag) は昇格させない
+		if lag, ok := lagMap[replica]; ok && lag < bestLag {
+			best, bestLag = replica, lag
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoFailoverCandidate
+	}
+	return best, nil
+}
+
+// PromoteReplica promotes a replica to primary.
+// The old primary is held back from the replica pool until ReinstateRecovered
+// sees it healthy again.
 func (fm *FailoverManager) PromoteReplica(replica *sqlx.DB) error {
-	// In a real implementation, this would involve:
-	// 1. Stopping writes to old primary
-	// 2. Ensuring replica is caught up
-	// 3. Promoting replica to primary
-	// 4. Redirecting writes to new primary
-	// 5. Updating cluster configuration
-
-	// For this example, we'll just swap the primary reference
 	fm.cluster.mu.Lock()
 	defer fm.cluster.mu.Unlock()
 
-	// Find the replica in the list and remove it
-	for i, r := range fm.cluster.replicas {
+	remaining := make([]*sqlx.DB, 0, len(fm.cluster.replicas))
+	found := false
+	for _, r := range fm.cluster.replicas {
 		if r == replica {
-			// Remove from replicas list
-			fm.cluster.replicas = append(fm.cluster.replicas[:i], fm.cluster.replicas[i+1:]...)
-			break
+			found = true
+			continue
 		}
+		remaining = append(remaining, r)
+	}
+	if !found {
+		return errors.New("cannot promote a database that is not a replica in the cluster")
 	}
 
-	// Add old primary to replicas (if it recovers)
-	fm.cluster.replicas = append(fm.cluster.replicas, fm.cluster.primary)
-
-	// Set new primary
+	fm.cluster.replicas = remaining
+	fm.cluster.demoted = append(fm.cluster.demoted, fm.cluster.primary)
 	fm.cluster.primary = replica
 
 	return nil
 }
 
+// ReinstateRecovered returns demoted primaries that the health monitor reports
+// healthy again to the replica pool, and reports how many rejoined
+func (fm *FailoverManager) ReinstateRecovered() int {
+	fm.cluster.mu.Lock()
+	defer fm.cluster.mu.Unlock()
+
+	stillDown := make([]*sqlx.DB, 0, len(fm.cluster.demoted))
+	reinstated := 0
+	for _, db := range fm.cluster.demoted {
+		if !fm.health.IsHealthy(db) {
+			stillDown = append(stillDown, db)
+			continue
+		}
+		fm.cluster.replicas = append(fm.cluster.replicas, db)
+		reinstated++
+	}
+	fm.cluster.demoted = stillDown
+
+	return reinstated
+}
+
 // UserService demonstrates read-write splitting
 type UserService struct {
 	router *RoutingManager
@@ -612,6 +915,7 @@ func (us *UserService) CreateUser(ctx context.Context, user *User) error {
 // GetUser retrieves a user by ID (read operation)
 func (us *UserService) GetUser(ctx context.Context, id int) (*User, error) {
 	db := us.router.RouteRead(ctx)
+	defer us.router.DoneRead(db)
 
 	var user User
 	err := db.GetContext(ctx, &user, "SELECT * FROM users WHERE id = $1", id)
@@ -639,6 +943,7 @@ func (us *UserService) UpdateUser(ctx context.Context, user *User) error {
 // SearchUsers searches for users (read operation)
 func (us *UserService) SearchUsers(ctx context.Context, filter UserFilter) ([]User, error) {
 	db := us.router.RouteRead(ctx)
+	defer us.router.DoneRead(db)
 
 	query := `
 		SELECT * FROM users 
@@ -662,6 +967,7 @@ func (us *UserService) SearchUsers(ctx context.Context, filter UserFilter) ([]Us
 // GetUserStats retrieves user statistics (read operation)
 func (us *UserService) GetUserStats(ctx context.Context) (*UserStats, error) {
 	db := us.router.RouteRead(ctx)
+	defer us.router.DoneRead(db)
 
 	var stats UserStats
 	query := `
@@ -8,6 +8,7 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -81,6 +82,7 @@ type ThunderingHerdProtector struct {
 	circuitBreaker *CircuitBreaker
 	metrics        *ProtectionMetrics
 	jitterPercent  float64
+	jitterSource   func() float64
 }
 
 // インターフェース定義
@@ -125,8 +127,7 @@ type Lock interface {
 	Release(ctx context.Context) error
 }
 
-// TODO: NewThunderingHerdProtector を実装してください
-// プロテクターの初期化を行い、必要なコンポーネントを設定してください
+// NewThunderingHerdProtector プロテクターを初期化します
 func NewThunderingHerdProtector(
 	cache CacheClient,
 	db DataRepository,
@@ -135,133 +136,417 @@ func NewThunderingHerdProtector(
 	circuitBreakerTimeout time.Duration,
 	jitterPercent float64,
 ) *ThunderingHerdProtector {
-	panic("TODO: implement NewThunderingHerdProtector")
+	return &ThunderingHerdProtector{
+		cache:          cache,
+		db:             db,
+		sf:             &singleflight.Group{},
+		lockManager:    lockManager,
+		circuitBreaker: NewCircuitBreaker(circuitBreakerThreshold, circuitBreakerTimeout),
+		metrics:        &ProtectionMetrics{},
+		jitterPercent:  jitterPercent,
+	}
 }
 
-// TODO: Get メソッドを実装してください
-// Single Flight、分散ロック、Circuit Breakerを組み合わせて
-// Thundering Herd問題を防ぐデータ取得を実装してください
+// SetJitterSource ジッター計算に使う乱数源を差し替えます
+// source は [0, 1) の値を返す必要があります。nil を指定すると暗号論的乱数に戻ります。
+func (p *ThunderingHerdProtector) SetJitterSource(source func() float64) {
+	p.jitterSource = source
+}
+
+// Get Single Flight、分散ロック、Circuit Breakerを組み合わせたデータ取得
 func (p *ThunderingHerdProtector) Get(ctx context.Context, key string) (*Data, error) {
-	panic("TODO: implement Get")
+	p.recordMetric(&p.metrics.TotalRequests)
+
+	// 1. 通常のキャッシュアクセス
+	if data, err := p.getFromCache(ctx, key); err == nil {
+		p.recordMetric(&p.metrics.CacheHits)
+		return data, nil
+	}
+	p.recordMetric(&p.metrics.CacheMisses)
+
+	// 2. Single Flight で重複リクエストを統合
+	v, err, shared := p.sf.Do(key, func() (interface{}, error) {
+		return p.getWithProtection(ctx, key)
+	})
+
+	if shared {
+		p.recordMetric(&p.metrics.SingleFlightHits)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(*Data), nil
 }
 
-// TODO: Set メソッドを実装してください
-// TTLジッターを追加してキャッシュの期限切れ時刻を分散させてください
+// Set TTLジッターを追加してキャッシュに設定
 func (p *ThunderingHerdProtector) Set(ctx context.Context, key string, value *Data, ttl time.Duration) error {
-	panic("TODO: implement Set")
-}
+	// TTLにジッターを追加
+	actualTTL := addJitterWithSource(ttl, p.jitterPercent, p.jitterSource)
 
-// TODO: GetStaleWhileRevalidate メソッドを実装してください
-// 期限切れのデータを一時的に返しながら、バックグラウンドで更新を行ってください
-func (p *ThunderingHerdProtector) GetStaleWhileRevalidate(ctx context.Context, key string) (*Data, error) {
-	panic("TODO: implement GetStaleWhileRevalidate")
-}
+	// データをJSONにシリアライズ
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
 
-// TODO: GetMetrics メソッドを実装してください
-// 現在のメトリクスを返してください
-func (p *ThunderingHerdProtector) GetMetrics() ProtectionMetrics {
-	panic("TODO: implement GetMetrics")
+	return p.cache.Set(ctx, key, string(jsonData), actualTTL)
 }
 
-// TODO: getFromCache メソッドを実装してください
-// キャッシュからデータを取得してください
+// GetStaleWhileRevalidate 期限切れデータを返しながらバックグラウンド更新
+func (p *ThunderingHerdProtector) GetStaleWhileRevalidate(ctx context.Context, key string) (*Data, error) {
+	p.recordMetric(&p.metrics.TotalRequests)
+
+	data, isStale, err := p.getWithStaleness(ctx, key)
+	if err == nil {
+		if isStale {
+			p.recordMetric(&p.metrics.StaleReturns)
+			// バックグラウンドで更新を開始（呼び出し元はブロックしない）
+			p.refreshInBackground(key)
+		} else {
+			p.recordMetric(&p.metrics.CacheHits)
+		}
+		return data, nil
+	}
+
+	p.recordMetric(&p.metrics.CacheMisses)
+	// キャッシュミスの場合は通常通り取得
+	return p.Get(ctx, key)
+}
+
+// GetMetrics 現在のメトリクスを返す
+func (p *ThunderingHerdProtector) GetMetrics() ProtectionMetrics {
+	return ProtectionMetrics{
+		TotalRequests:       atomic.LoadInt64(&p.metrics.TotalRequests),
+		CacheHits:           atomic.LoadInt64(&p.metrics.CacheHits),
+		CacheMisses:         atomic.LoadInt64(&p.metrics.CacheMisses),
+		SingleFlightHits:    atomic.LoadInt64(&p.metrics.SingleFlightHits),
+		LockAcquisitions:    atomic.LoadInt64(&p.metrics.LockAcquisitions),
+		CircuitBreakerTrips: atomic.LoadInt64(&p.metrics.CircuitBreakerTrips),
+		StaleReturns:        atomic.LoadInt64(&p.metrics.StaleReturns),
+		BackgroundRefresh:   atomic.LoadInt64(&p.metrics.BackgroundRefresh),
+	}
+}
+
+// getFromCache キャッシュからデータを取得
 func (p *ThunderingHerdProtector) getFromCache(ctx context.Context, key string) (*Data, error) {
-	panic("TODO: implement getFromCache")
+	value, err := p.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var data Data
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	return &data, nil
 }
 
-// TODO: getWithStaleness メソッドを実装してください
-// データと期限切れかどうかの情報を同時に取得してください
+// getWithStaleness データと期限切れ情報を同時に取得
 func (p *ThunderingHerdProtector) getWithStaleness(ctx context.Context, key string) (*Data, bool, error) {
-	panic("TODO: implement getWithStaleness")
+	value, ttl, err := p.cache.GetWithTTL(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// TTL が 0 以下の場合は期限切れ
+	isStale := ttl <= 0
+
+	var data Data
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	return &data, isStale, nil
 }
 
-// TODO: loadFromDB メソッドを実装してください
-// データベースからデータを取得し、キャッシュに保存してください
+// loadFromDB データベースからデータを取得してキャッシュに保存
 func (p *ThunderingHerdProtector) loadFromDB(ctx context.Context, key string) (*Data, error) {
-	panic("TODO: implement loadFromDB")
+	data, err := p.db.GetByID(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load from DB: %w", err)
+	}
+
+	// キャッシュに保存（デフォルトTTL: 5分）
+	if err := p.Set(ctx, key, data, 5*time.Minute); err != nil {
+		// キャッシュ保存エラーはログ出力のみで、データは返す
+		fmt.Printf("Warning: failed to cache data: %v\n", err)
+	}
+
+	return data, nil
 }
 
-// TODO: getWithProtection メソッドを実装してください
-// 分散ロックとCircuit Breakerを使用した保護付きデータ取得を実装してください
+// getWithProtection 分散ロックとCircuit Breakerを使用した保護付きデータ取得
 func (p *ThunderingHerdProtector) getWithProtection(ctx context.Context, key string) (*Data, error) {
-	panic("TODO: implement getWithProtection")
-}
+	lockKey := "lock:" + key
 
-// TODO: fallbackStrategy メソッドを実装してください
-// ロック取得失敗時の代替戦略を実装してください
-func (p *ThunderingHerdProtector) fallbackStrategy(ctx context.Context, key string) (*Data, error) {
-	panic("TODO: implement fallbackStrategy")
-}
+	// 分散ロック取得試行
+	lock, err := p.lockManager.TryLock(ctx, lockKey, 5*time.Second)
+	if err != nil {
+		// ロック取得失敗 - 代替戦略実行
+		return p.fallbackStrategy(ctx, key)
+	}
+	defer lock.Release(ctx)
 
-// TODO: refreshInBackground メソッドを実装してください
-// バックグラウンドでデータを更新してください
-func (p *ThunderingHerdProtector) refreshInBackground(key string) {
-	panic("TODO: implement refreshInBackground")
+	p.recordMetric(&p.metrics.LockAcquisitions)
+
+	// ロック取得後、再度キャッシュ確認
+	if data, err := p.getFromCache(ctx, key); err == nil {
+		return data, nil
+	}
+
+	// Circuit Breaker でDB保護
+	result, err := p.circuitBreaker.Call(func() (interface{}, error) {
+		return p.loadFromDB(ctx, key)
+	})
+
+	if errors.Is(err, ErrCircuitOpen) {
+		// DBに到達できないので代替戦略（期限切れデータの返却）に切り替える
+		// トリップの記録は代替戦略側で行う
+		return p.fallbackStrategy(ctx, key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*Data), nil
 }
 
-// TODO: recordMetric メソッドを実装してください
-// アトミックにメトリクスを記録してください
+// fallbackStrategy ロック取得失敗時・Circuit Breaker が開いている時の代替戦略
+func (p *ThunderingHerdProtector) fallbackStrategy(ctx context.Context, key string) (*Data, error) {
+	// 短時間待機後、キャッシュ再確認
+	select {
+	case <-time.After(10 * time.Millisecond):
+		if data, err := p.getFromCache(ctx, key); err == nil {
+			return data, nil
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// 古いデータがあれば返す
+	if data, _, err := p.getWithStaleness(ctx, key); err == nil {
+		p.recordMetric(&p.metrics.StaleReturns)
+		return data, nil
+	}
+
+	// 最後の手段：Circuit Breaker 経由でDB直接アクセス
+	result, err := p.circuitBreaker.Call(func() (interface{}, error) {
+		return p.loadFromDB(ctx, key)
+	})
+
+	if errors.Is(err, ErrCircuitOpen) {
+		p.recordMetric(&p.metrics.CircuitBreakerTrips)
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*Data), nil
+}
+
+// refreshInBackground バックグラウンドでデータを更新
+// 同一キーのリフレッシュは Single Flight で1つに統合されます
+func (p *ThunderingHerdProtector) refreshInBackground(key string) {
+	p.sf.DoChan("refresh:"+key, func() (interface{}, error) {
+		p.recordMetric(&p.metrics.BackgroundRefresh)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// 分散ロックを使用して他プロセスとのリフレッシュの重複を防ぐ
+		lockKey := "refresh:" + key
+		lock, err := p.lockManager.TryLock(ctx, lockKey, 30*time.Second)
+		if err != nil {
+			// 他のプロセスがリフレッシュ中
+			return nil, nil
+		}
+		defer lock.Release(ctx)
+
+		// データベースから最新データを取得
+		data, err := p.db.GetByID(ctx, key)
+		if err != nil {
+			fmt.Printf("Background refresh failed for key %s: %v\n", key, err)
+			return nil, err
+		}
+
+		// キャッシュを更新
+		if err := p.Set(ctx, key, data, 5*time.Minute); err != nil {
+			fmt.Printf("Failed to update cache during background refresh: %v\n", err)
+			return nil, err
+		}
+		return data, nil
+	})
+}
+
+// recordMetric アトミックにメトリクスを記録
 func (p *ThunderingHerdProtector) recordMetric(metric *int64) {
-	panic("TODO: implement recordMetric")
+	atomic.AddInt64(metric, 1)
 }
 
-// TODO: addJitter 関数を実装してください
-// TTLにランダムなジッターを追加してください
+// addJitter TTLにランダムなジッターを追加
 func addJitter(baseTTL time.Duration, jitterPercent float64) time.Duration {
-	panic("TODO: implement addJitter")
+	return addJitterWithSource(baseTTL, jitterPercent, nil)
+}
+
+// addJitterWithSource 指定した乱数源でTTLにジッターを追加
+// source が nil の場合は暗号論的乱数を使用します
+func addJitterWithSource(baseTTL time.Duration, jitterPercent float64, source func() float64) time.Duration {
+	if jitterPercent <= 0 {
+		return baseTTL
+	}
+
+	// ±jitterPercent のランダムな値を生成
+	maxJitter := int64(float64(baseTTL) * jitterPercent)
+	if maxJitter == 0 {
+		return baseTTL
+	}
+
+	if source == nil {
+		jitter, err := rand.Int(rand.Reader, big.NewInt(maxJitter*2))
+		if err != nil {
+			return baseTTL
+		}
+		return baseTTL + time.Duration(jitter.Int64()-maxJitter)
+	}
+
+	// [0, 1) を [-maxJitter, +maxJitter) に写像
+	actualJitter := int64((source()*2 - 1) * float64(maxJitter))
+	return baseTTL + time.Duration(actualJitter)
 }
 
 // Circuit Breaker メソッド
 
-// TODO: NewCircuitBreaker を実装してください
+// NewCircuitBreaker 新しいCircuit Breakerを作成
 func NewCircuitBreaker(threshold int64, timeout time.Duration) *CircuitBreaker {
-	panic("TODO: implement NewCircuitBreaker")
+	return &CircuitBreaker{
+		state:     Closed,
+		threshold: threshold,
+		timeout:   timeout,
+	}
 }
 
-// TODO: Call メソッドを実装してください
-// Circuit Breakerを通してリクエストを実行してください
+// Call Circuit Breakerを通してリクエストを実行
 func (cb *CircuitBreaker) Call(fn func() (interface{}, error)) (interface{}, error) {
-	panic("TODO: implement Call")
+	if !cb.canExecute() {
+		return nil, ErrCircuitOpen
+	}
+
+	result, err := fn()
+	if err != nil {
+		cb.recordFailure()
+	} else {
+		cb.recordSuccess()
+	}
+
+	return result, err
 }
 
-// TODO: recordFailure メソッドを実装してください
+// recordFailure 失敗を記録
 func (cb *CircuitBreaker) recordFailure() {
-	panic("TODO: implement recordFailure")
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failures++
+	cb.lastFailure = time.Now()
+
+	if cb.failures >= cb.threshold {
+		cb.state = Open
+	}
 }
 
-// TODO: recordSuccess メソッドを実装してください
+// recordSuccess 成功を記録
 func (cb *CircuitBreaker) recordSuccess() {
-	panic("TODO: implement recordSuccess")
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failures = 0
+	cb.state = Closed
 }
 
-// TODO: canExecute メソッドを実装してください
+// canExecute 実行可能かどうかを判定
 func (cb *CircuitBreaker) canExecute() bool {
-	panic("TODO: implement canExecute")
+	cb.mutex.RLock()
+	state := cb.state
+	lastFailure := cb.lastFailure
+	cb.mutex.RUnlock()
+
+	switch state {
+	case Closed:
+		return true
+	case Open:
+		if time.Since(lastFailure) > cb.timeout {
+			cb.mutex.Lock()
+			defer cb.mutex.Unlock()
+
+			// ダブルチェック
+			if cb.state == Open && time.Since(cb.lastFailure) > cb.timeout {
+				cb.state = HalfOpen
+				return true
+			}
+		}
+		return false
+	case HalfOpen:
+		return true
+	}
+	return false
 }
 
 // Distributed Lock メソッド
 
-// TODO: NewDistributedLock を実装してください
+// NewDistributedLock 新しい分散ロックを作成
 func NewDistributedLock(client LockClient, key string, ttl time.Duration) *DistributedLock {
-	panic("TODO: implement NewDistributedLock")
+	return &DistributedLock{
+		client: client,
+		key:    key,
+		value:  generateLockValue(),
+		ttl:    ttl,
+	}
 }
 
-// TODO: Acquire メソッドを実装してください
-// Redis SETNXを使用してロックを取得してください
+// Acquire Redis SETNXを使用してロックを取得
 func (l *DistributedLock) Acquire(ctx context.Context) error {
-	panic("TODO: implement Acquire")
-}
+	acquired, err := l.client.SetNX(ctx, l.key, l.value, l.ttl)
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
 
-// TODO: Release メソッドを実装してください
-// Luaスクリプトを使用して安全にロックを解放してください
-func (l *DistributedLock) Release(ctx context.Context) error {
-	panic("TODO: implement Release")
+	if !acquired {
+		return ErrLockNotAcquired
+	}
+
+	return nil
 }
 
-// TODO: generateLockValue を実装してください
-// ユニークなロック値を生成してください
+// Release Luaスクリプトを使用して安全にロックを解放
+func (l *DistributedLock) Release(ctx context.Context) error {
+	script := `
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			return redis.call("del", KEYS[1])
+		else
+			return 0
+		end
+	`
+	// 自分の値と一致する場合のみ削除するため、期限切れ後に他者が取得したロックは消さない
+	if err := l.client.Eval(ctx, script, []string{l.key}, l.value); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// generateLockValue ユニークなロック値を生成
 func generateLockValue() string {
-	panic("TODO: implement generateLockValue")
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		// 乱数源が使えない場合は時刻ベースの値にフォールバック
+		return fmt.Sprintf("lock-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
 }
 
 func main() {
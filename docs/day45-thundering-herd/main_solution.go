@@ -80,6 +80,7 @@ type ThunderingHerdProtector struct {
 	circuitBreaker *CircuitBreaker
 	metrics        *ProtectionMetrics
 	jitterPercent  float64
+	jitterSource   func() float64
 }
 
 // インターフェース定義
@@ -144,6 +145,13 @@ func NewThunderingHerdProtector(
 	}
 }
 
+// SetJitterSource ジッター計算に使う乱数源を差し替えます
+// source は [0, 1) の値を返す必要があります。nil を指定すると暗号論的乱数に戻ります。
+// テストでTTLを決定的にしたい場合に使用します。
+func (p *ThunderingHerdProtector) SetJitterSource(source func() float64) {
+	p.jitterSource = source
+}
+
 // Get Single Flight、分散ロック、Circuit Breakerを組み合わせたデータ取得
 func (p *ThunderingHerdProtector) Get(ctx context.Context, key string) (*Data, error) {
 	p.recordMetric(&p.metrics.TotalRequests)
@@ -174,7 +182,7 @@ func (p *ThunderingHerdProtector) Get(ctx context.Context, key string) (*Data, e
 // Set TTLジッターを追加してキャッシュに設定
 func (p *ThunderingHerdProtector) Set(ctx context.Context, key string, value *Data, ttl time.Duration) error {
 	// TTLにジッターを追加
-	actualTTL := addJitter(ttl, p.jitterPercent)
+	actualTTL := addJitterWithSource(ttl, p.jitterPercent, p.jitterSource)
 
 	// データをJSONにシリアライズ
 	jsonData, err := json.Marshal(value)
@@ -193,8 +201,8 @@ func (p *ThunderingHerdProtector) GetStaleWhileRevalidate(ctx context.Context, k
 	if err == nil {
 		if isStale {
 			p.recordMetric(&p.metrics.StaleReturns)
-			// バックグラウンドで更新を開始
-			go p.refreshInBackground(key)
+			// バックグラウンドで更新を開始（呼び出し元はブロックしない）
+			p.refreshInBackground(key)
 		} else {
 			p.recordMetric(&p.metrics.CacheHits)
 		}
@@ -293,9 +301,10 @@ func (p *ThunderingHerdProtector) getWithProtection(ctx context.Context, key str
 		return p.loadFromDB(ctx, key)
 	})
 
-	if err == ErrCircuitOpen {
-		p.recordMetric(&p.metrics.CircuitBreakerTrips)
-		return nil, err
+	if errors.Is(err, ErrCircuitOpen) {
+		// DBに到達できないので代替戦略（期限切れデータの返却）に切り替える
+		// トリップの記録は代替戦略側で行う
+		return p.fallbackStrategy(ctx, key)
 	}
 
 	if err != nil {
@@ -305,7 +314,7 @@ func (p *ThunderingHerdProtector) getWithProtection(ctx context.Context, key str
 	return result.(*Data), nil
 }
 
-// fallbackStrategy ロック取得失敗時の代替戦略
+// fallbackStrategy ロック取得失敗時・Circuit Breaker が開いている時の代替戦略
 func (p *ThunderingHerdProtector) fallbackStrategy(ctx context.Context, key string) (*Data, error) {
 	// 短時間待機後、キャッシュ再確認
 	select {
@@ -328,7 +337,7 @@ func (p *ThunderingHerdProtector) fallbackStrategy(ctx context.Context, key stri
 		return p.loadFromDB(ctx, key)
 	})
 
-	if err == ErrCircuitOpen {
+	if errors.Is(err, ErrCircuitOpen) {
 		p.recordMetric(&p.metrics.CircuitBreakerTrips)
 		return nil, err
 	}
@@ -341,32 +350,37 @@ func (p *ThunderingHerdProtector) fallbackStrategy(ctx context.Context, key stri
 }
 
 // refreshInBackground バックグラウンドでデータを更新
+// 同一キーのリフレッシュは Single Flight で1つに統合されます
 func (p *ThunderingHerdProtector) refreshInBackground(key string) {
-	p.recordMetric(&p.metrics.BackgroundRefresh)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// 分散ロックを使用してリフレッシュの重複を防ぐ
-	lockKey := "refresh:" + key
-	lock, err := p.lockManager.TryLock(ctx, lockKey, 30*time.Second)
-	if err != nil {
-		// 他のプロセスがリフレッシュ中
-		return
-	}
-	defer lock.Release(ctx)
+	p.sf.DoChan("refresh:"+key, func() (interface{}, error) {
+		p.recordMetric(&p.metrics.BackgroundRefresh)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// 分散ロックを使用して他プロセスとのリフレッシュの重複を防ぐ
+		lockKey := "refresh:" + key
+		lock, err := p.lockManager.TryLock(ctx, lockKey, 30*time.Second)
+		if err != nil {
+			// 他のプロセスがリフレッシュ中
+			return nil, nil
+		}
+		defer lock.Release(ctx)
 
-	// データベースから最新データを取得
-	data, err := p.db.GetByID(ctx, key)
-	if err != nil {
-		fmt.Printf("Background refresh failed for key %s: %v\n", key, err)
-		return
-	}
+		// データベースから最新データを取得
+		data, err := p.db.GetByID(ctx, key)
+		if err != nil {
+			fmt.Printf("Background refresh failed for key %s: %v\n", key, err)
+			return nil, err
+		}
 
-	// キャッシュを更新
-	if err := p.Set(ctx, key, data, 5*time.Minute); err != nil {
-		fmt.Printf("Failed to update cache during background refresh: %v\n", err)
-	}
+		// キャッシュを更新
+		if err := p.Set(ctx, key, data, 5*time.Minute); err != nil {
+			fmt.Printf("Failed to update cache during background refresh: %v\n", err)
+			return nil, err
+		}
+		return data, nil
+	})
 }
 
 // recordMetric アトミックにメトリクスを記録
@@ -376,6 +390,12 @@ func (p *ThunderingHerdProtector) recordMetric(metric *int64) {
 
 // addJitter TTLにランダムなジッターを追加
 func addJitter(baseTTL time.Duration, jitterPercent float64) time.Duration {
+	return addJitterWithSource(baseTTL, jitterPercent, nil)
+}
+
+// addJitterWithSource 指定した乱数源でTTLにジッターを追加
+// source が nil の場合は暗号論的乱数を使用します
+func addJitterWithSource(baseTTL time.Duration, jitterPercent float64, source func() float64) time.Duration {
 	if jitterPercent <= 0 {
 		return baseTTL
 	}
@@ -386,12 +406,16 @@ func addJitter(baseTTL time.Duration, jitterPercent float64) time.Duration {
 		return baseTTL
 	}
 
-	jitter, err := rand.Int(rand.Reader, big.NewInt(maxJitter*2))
-	if err != nil {
-		return baseTTL
+	if source == nil {
+		jitter, err := rand.Int(rand.Reader, big.NewInt(maxJitter*2))
+		if err != nil {
+			return baseTTL
+		}
+		return baseTTL + time.Duration(jitter.Int64()-maxJitter)
 	}
 
-	actualJitter := jitter.Int64() - maxJitter
+	// [0, 1) を [-maxJitter, +maxJitter) に写像
+	actualJitter := int64((source()*2 - 1) * float64(maxJitter))
 	return baseTTL + time.Duration(actualJitter)
 }
 
@@ -507,13 +531,20 @@ func (l *DistributedLock) Release(ctx context.Context) error {
 			return 0
 		end
 	`
-	return l.client.Eval(ctx, script, []string{l.key}, l.value)
+	// 自分の値と一致する場合のみ削除するため、期限切れ後に他者が取得したロックは消さない
+	if err := l.client.Eval(ctx, script, []string{l.key}, l.value); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
 }
 
 // generateLockValue ユニークなロック値を生成
 func generateLockValue() string {
 	bytes := make([]byte, 16)
-	rand.Read(bytes)
+	if _, err := rand.Read(bytes); err != nil {
+		// 乱数源が使えない場合は時刻ベースの値にフォールバック
+		return fmt.Sprintf("lock-%d", time.Now().UnixNano())
+	}
 	return hex.EncodeToString(bytes)
 }
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -231,6 +232,48 @@ func (l *MockLock) Release(ctx context.Context) error {
 	return nil
 }
 
+// fakeLockClient はSETNXと比較削除を再現するテスト用のロッククライアントです
+type fakeLockClient struct {
+	values map[string]string
+	mutex  sync.Mutex
+}
+
+func newFakeLockClient() *fakeLockClient {
+	return &fakeLockClient{values: make(map[string]string)}
+}
+
+func (c *fakeLockClient) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.values[key]; exists {
+		return false, nil
+	}
+	c.values[key] = value
+	return true, nil
+}
+
+// Eval はLuaスクリプトの代わりに「値が一致する場合のみ削除」を実行します
+func (c *fakeLockClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(keys) != 1 || len(args) != 1 {
+		return fmt.Errorf("unexpected eval arguments: keys=%v args=%v", keys, args)
+	}
+	if c.values[keys[0]] == args[0] {
+		delete(c.values, keys[0])
+	}
+	return nil
+}
+
+func (c *fakeLockClient) holder(key string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	value, exists := c.values[key]
+	return value, exists
+}
+
 // テストケース
 
 func TestThunderingHerdProtector_SingleFlight(t *testing.T) {
@@ -463,6 +506,93 @@ func TestThunderingHerdProtector_CircuitBreaker(t *testing.T) {
 	t.Log("DB protected from excessive load")
 }
 
+func TestThunderingHerdProtector_ColdCacheSingleDBLoad(t *testing.T) {
+	cache := NewMockCacheClient()
+	db := NewMockDataRepository()
+	lockManager := NewMockLockManager()
+
+	db.Create(context.Background(), &Data{ID: "cold-key", Value: "cold-value", CreatedAt: time.Now()})
+	// DB呼び出し中に後続リクエストが確実に合流するよう遅延させる
+	db.SetSlowRequest(true)
+
+	protector := NewThunderingHerdProtector(cache, db, lockManager, 3, 5*time.Second, 0.1)
+
+	ctx := context.Background()
+	const numRequests = 100
+	var wg sync.WaitGroup
+	var failures int64
+
+	wg.Add(numRequests)
+	for i := 0; i < numRequests; i++ {
+		go func() {
+			defer wg.Done()
+			data, err := protector.Get(ctx, "cold-key")
+			if err != nil || data == nil || data.Value != "cold-value" {
+				atomic.AddInt64(&failures, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failures != 0 {
+		t.Errorf("Expected all requests to succeed, got %d failures", failures)
+	}
+	if calls := db.GetCallCount(); calls != 1 {
+		t.Errorf("Expected exactly 1 DB call, got %d", calls)
+	}
+
+	metrics := protector.GetMetrics()
+	if metrics.TotalRequests != numRequests {
+		t.Errorf("Expected %d total requests, got %d", numRequests, metrics.TotalRequests)
+	}
+	if metrics.CacheHits+metrics.CacheMisses != numRequests {
+		t.Errorf("Expected hits+misses to equal %d, got %d+%d",
+			numRequests, metrics.CacheHits, metrics.CacheMisses)
+	}
+	if metrics.SingleFlightHits == 0 {
+		t.Error("Expected concurrent misses to share a single flight")
+	}
+}
+
+func TestThunderingHerdProtector_CircuitOpenServesStale(t *testing.T) {
+	cache := NewMockCacheClient()
+	db := NewMockDataRepository()
+	lockManager := NewMockLockManager()
+
+	protector := NewThunderingHerdProtector(cache, db, lockManager, 2, time.Minute, 0)
+
+	ctx := context.Background()
+
+	// 期限切れのデータをキャッシュに残しておく
+	stale, _ := json.Marshal(&Data{ID: "stale-key", Value: "stale-value", CreatedAt: time.Now()})
+	cache.Set(ctx, "stale-key", string(stale), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// 別のキーでDB障害を起こして Circuit Breaker を開く
+	db.SetFailNext(true)
+	for i := 0; i < 2; i++ {
+		if _, err := protector.Get(ctx, "broken-key"); err == nil {
+			t.Fatal("Expected error from failing database")
+		}
+	}
+
+	data, err := protector.Get(ctx, "stale-key")
+	if err != nil {
+		t.Fatalf("Expected stale data while circuit is open, got error: %v", err)
+	}
+	if data.Value != "stale-value" {
+		t.Errorf("Expected stale-value, got %s", data.Value)
+	}
+
+	metrics := protector.GetMetrics()
+	if metrics.StaleReturns != 1 {
+		t.Errorf("Expected 1 stale return, got %d", metrics.StaleReturns)
+	}
+	if calls := db.GetCallCount(); calls != 2 {
+		t.Errorf("Expected open circuit to block DB access, got %d DB calls", calls)
+	}
+}
+
 func TestThunderingHerdProtector_StaleWhileRevalidate(t *testing.T) {
 	cache := NewMockCacheClient()
 	db := NewMockDataRepository()
@@ -539,6 +669,70 @@ func TestThunderingHerdProtector_StaleWhileRevalidate(t *testing.T) {
 	t.Log("Background refresh completed")
 }
 
+func TestThunderingHerdProtector_StaleWhileRevalidateSingleRefresh(t *testing.T) {
+	cache := NewMockCacheClient()
+	db := NewMockDataRepository()
+	lockManager := NewMockLockManager()
+
+	protector := NewThunderingHerdProtector(cache, db, lockManager, 3, 5*time.Second, 0.1)
+
+	ctx := context.Background()
+	key := "swr-key"
+
+	protector.Set(ctx, key, &Data{ID: key, Value: "old-value", CreatedAt: time.Now()}, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	db.Create(ctx, &Data{ID: key, Value: "new-value", CreatedAt: time.Now()})
+	// リフレッシュ中に後続リクエストが届くようDBを遅延させる
+	db.SetSlowRequest(true)
+
+	const numRequests = 20
+	var wg sync.WaitGroup
+	var staleCount int64
+
+	wg.Add(numRequests)
+	for i := 0; i < numRequests; i++ {
+		go func() {
+			defer wg.Done()
+			data, err := protector.GetStaleWhileRevalidate(ctx, key)
+			if err == nil && data.Value == "old-value" {
+				atomic.AddInt64(&staleCount, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if staleCount != numRequests {
+		t.Errorf("Expected all %d requests to get stale data immediately, got %d", numRequests, staleCount)
+	}
+
+	// バックグラウンド更新の完了を待機
+	deadline := time.Now().Add(time.Second)
+	for {
+		if raw, err := cache.Get(ctx, key); err == nil {
+			var cached Data
+			if json.Unmarshal([]byte(raw), &cached) == nil && cached.Value == "new-value" {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Background refresh did not update the cache")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	metrics := protector.GetMetrics()
+	if metrics.StaleReturns != numRequests {
+		t.Errorf("Expected %d stale returns, got %d", numRequests, metrics.StaleReturns)
+	}
+	if metrics.BackgroundRefresh != 1 {
+		t.Errorf("Expected exactly 1 background refresh, got %d", metrics.BackgroundRefresh)
+	}
+	if calls := db.GetCallCount(); calls != 1 {
+		t.Errorf("Expected exactly 1 DB call, got %d", calls)
+	}
+}
+
 func TestThunderingHerdProtector_TTLJitter(t *testing.T) {
 	cache := NewMockCacheClient()
 	db := NewMockDataRepository()
@@ -596,6 +790,75 @@ func TestThunderingHerdProtector_TTLJitter(t *testing.T) {
 	t.Logf("TTL jitter created %d different expiration times", len(ttlVariations))
 }
 
+// ttlRecordingCache は Set に渡されたTTLを記録するキャッシュです
+type ttlRecordingCache struct {
+	*MockCacheClient
+	mutex sync.Mutex
+	ttls  []time.Duration
+}
+
+func (c *ttlRecordingCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	c.mutex.Lock()
+	c.ttls = append(c.ttls, ttl)
+	c.mutex.Unlock()
+	return c.MockCacheClient.Set(ctx, key, value, ttl)
+}
+
+func TestThunderingHerdProtector_DeterministicJitter(t *testing.T) {
+	ctx := context.Background()
+	baseTTL := 1 * time.Second
+	data := &Data{ID: "key", Value: "value", CreatedAt: time.Now()}
+
+	t.Run("fixed source yields predictable TTLs", func(t *testing.T) {
+		cache := &ttlRecordingCache{MockCacheClient: NewMockCacheClient()}
+		protector := NewThunderingHerdProtector(cache, NewMockDataRepository(), NewMockLockManager(), 3, 5*time.Second, 0.2)
+
+		values := []float64{0.0, 0.5, 0.75}
+		next := 0
+		protector.SetJitterSource(func() float64 {
+			v := values[next%len(values)]
+			next++
+			return v
+		})
+
+		for range values {
+			if err := protector.Set(ctx, "key", data, baseTTL); err != nil {
+				t.Fatalf("Failed to set data: %v", err)
+			}
+		}
+
+		// 20%ジッター: 0.0 -> -200ms, 0.5 -> ±0, 0.75 -> +100ms
+		expected := []time.Duration{800 * time.Millisecond, 1000 * time.Millisecond, 1100 * time.Millisecond}
+		if len(cache.ttls) != len(expected) {
+			t.Fatalf("Expected %d TTLs, got %d", len(expected), len(cache.ttls))
+		}
+		for i, ttl := range cache.ttls {
+			if ttl != expected[i] {
+				t.Errorf("TTL %d: expected %v, got %v", i, expected[i], ttl)
+			}
+		}
+	})
+
+	t.Run("default source varies across calls", func(t *testing.T) {
+		cache := &ttlRecordingCache{MockCacheClient: NewMockCacheClient()}
+		protector := NewThunderingHerdProtector(cache, NewMockDataRepository(), NewMockLockManager(), 3, 5*time.Second, 0.2)
+
+		for i := 0; i < 20; i++ {
+			if err := protector.Set(ctx, "key", data, baseTTL); err != nil {
+				t.Fatalf("Failed to set data: %v", err)
+			}
+		}
+
+		unique := make(map[time.Duration]bool)
+		for _, ttl := range cache.ttls {
+			unique[ttl] = true
+		}
+		if len(unique) < 2 {
+			t.Errorf("Expected TTL variation with default source, got %d unique values", len(unique))
+		}
+	})
+}
+
 func TestAddJitter(t *testing.T) {
 	baseTTL := 10 * time.Second
 	jitterPercent := 0.2
@@ -668,6 +931,46 @@ func TestCircuitBreaker_StateTransitions(t *testing.T) {
 	}
 }
 
+func TestDistributedLock_AcquireRelease(t *testing.T) {
+	client := newFakeLockClient()
+	ctx := context.Background()
+
+	owner := NewDistributedLock(client, "lock:resource", time.Second)
+	other := NewDistributedLock(client, "lock:resource", time.Second)
+
+	if owner.value == "" || owner.value == other.value {
+		t.Fatalf("Expected unique lock values, got %q and %q", owner.value, other.value)
+	}
+
+	if err := owner.Acquire(ctx); err != nil {
+		t.Fatalf("First Acquire failed: %v", err)
+	}
+
+	// 保持中は2つ目の取得が失敗する
+	if err := other.Acquire(ctx); !errors.Is(err, ErrLockNotAcquired) {
+		t.Errorf("Expected ErrLockNotAcquired while held, got %v", err)
+	}
+
+	// 所有者でないロックの解放は何もしない
+	if err := other.Release(ctx); err != nil {
+		t.Fatalf("Release by non-owner returned error: %v", err)
+	}
+	if value, held := client.holder("lock:resource"); !held || value != owner.value {
+		t.Errorf("Expected lock to remain held by owner, got %q (held=%v)", value, held)
+	}
+
+	if err := owner.Release(ctx); err != nil {
+		t.Fatalf("Release by owner failed: %v", err)
+	}
+	if _, held := client.holder("lock:resource"); held {
+		t.Error("Expected lock to be released by owner")
+	}
+
+	if err := other.Acquire(ctx); err != nil {
+		t.Errorf("Expected Acquire to succeed after release, got %v", err)
+	}
+}
+
 // ベンチマークテスト
 
 func BenchmarkThunderingHerdProtector_CacheHit(b *testing.B) {
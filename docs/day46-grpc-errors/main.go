@@ -12,11 +12,19 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
+// Empty represents an empty message
+type Empty struct{}
+
+func (e *Empty) String() string {
+	return "{}"
+}
+
 // gRPCステータスコードの定義（簡単化版）
 type Code int32
 
@@ -234,102 +242,355 @@ func NewUserServiceServer() *UserServiceServer {
 	}
 }
 
-// TODO: GetUser メソッドを実装してください
-// ユーザーIDの検証、ユーザーの取得、適切なエラーコードの返却を行ってください
+// GetUser ユーザーを取得します
 func (s *UserServiceServer) GetUser(ctx context.Context, req *GetUserRequest) (*User, error) {
-	panic("TODO: implement GetUser")
+	// 入力検証
+	if req.UserID == "" {
+		st := NewStatus(InvalidArgument, "user_id is required")
+		st, _ = st.WithDetails(&ValidationError{
+			Field:   "user_id",
+			Message: "cannot be empty",
+		})
+		return nil, st.Err()
+	}
+
+	s.mu.RLock()
+	user, exists := s.users[req.UserID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, Error(NotFound, "user not found")
+	}
+
+	return user, nil
 }
 
-// TODO: CreateUser メソッドを実装してください
-// ユーザーのバリデーション、重複チェック、ユーザー作成を行ってください
+// CreateUser ユーザーを作成します
 func (s *UserServiceServer) CreateUser(ctx context.Context, req *CreateUserRequest) (*User, error) {
-	panic("TODO: implement CreateUser")
+	// バリデーション
+	if err := s.validateUser(req.User); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 重複チェック（IDとメールアドレス）
+	if _, exists := s.users[req.User.ID]; exists {
+		return nil, Errorf(AlreadyExists, "user %s already exists", req.User.ID)
+	}
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, req.User.Email) {
+			return nil, Errorf(AlreadyExists, "email %s is already registered", req.User.Email)
+		}
+	}
+
+	// 作成日時を設定
+	req.User.CreatedAt = time.Now().Unix()
+
+	// ユーザー作成
+	s.users[req.User.ID] = req.User
+	return req.User, nil
 }
 
-// TODO: UpdateUser メソッドを実装してください
-// ユーザーの存在確認、バリデーション、更新を行ってください
+// UpdateUser ユーザーを更新します
 func (s *UserServiceServer) UpdateUser(ctx context.Context, req *UpdateUserRequest) (*User, error) {
-	panic("TODO: implement UpdateUser")
+	// バリデーション
+	if err := s.validateUser(req.User); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 存在確認
+	existingUser, exists := s.users[req.User.ID]
+	if !exists {
+		return nil, Error(NotFound, "user not found")
+	}
+
+	// 作成日時は保持
+	req.User.CreatedAt = existingUser.CreatedAt
+
+	// ユーザー更新
+	s.users[req.User.ID] = req.User
+	return req.User, nil
 }
 
-// TODO: DeleteUser メソッドを実装してください
-// ユーザーの存在確認、削除を行ってください
-func (s *UserServiceServer) DeleteUser(ctx context.Context, req *DeleteUserRequest) error {
-	panic("TODO: implement DeleteUser")
+// DeleteUser ユーザーを削除します
+func (s *UserServiceServer) DeleteUser(ctx context.Context, req *DeleteUserRequest) (*Empty, error) {
+	// 入力検証
+	if req.UserID == "" {
+		return nil, Error(InvalidArgument, "user_id is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 存在確認
+	if _, exists := s.users[req.UserID]; !exists {
+		return nil, Error(NotFound, "user not found")
+	}
+
+	// ユーザー削除
+	delete(s.users, req.UserID)
+	return &Empty{}, nil
 }
 
-// TODO: ListUsers メソッドを実装してください
-// ページネーション付きのユーザー一覧を返してください
+// ListUsers ユーザー一覧を取得します
 func (s *UserServiceServer) ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error) {
-	panic("TODO: implement ListUsers")
+	// デフォルトページサイズの設定
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// 全ユーザーを取得（ページ間で順序が変わらないようIDでソート）
+	allUsers := make([]*User, 0, len(s.users))
+	for _, user := range s.users {
+		allUsers = append(allUsers, user)
+	}
+	sort.Slice(allUsers, func(i, j int) bool {
+		return allUsers[i].ID < allUsers[j].ID
+	})
+
+	// ページング処理（簡単化）
+	start := 0
+	if req.PageToken != "" {
+		// ページトークンは簡単化のため、開始インデックスとして扱う
+		if _, err := fmt.Sscanf(req.PageToken, "%d", &start); err != nil || start < 0 || start > len(allUsers) {
+			return nil, Errorf(InvalidArgument, "invalid page_token: %q", req.PageToken)
+		}
+	}
+
+	end := start + int(pageSize)
+	if end > len(allUsers) {
+		end = len(allUsers)
+	}
+
+	users := allUsers[start:end]
+
+	// 次のページトークンを生成
+	var nextPageToken string
+	if end < len(allUsers) {
+		nextPageToken = fmt.Sprintf("%d", end)
+	}
+
+	return &ListUsersResponse{
+		Users:         users,
+		NextPageToken: nextPageToken,
+	}, nil
 }
 
-// TODO: validateUser 関数を実装してください
-// ユーザーのバリデーションを行い、エラー詳細を含むステータスを返してください
+// validateUser ユーザーのバリデーションを行います
 func (s *UserServiceServer) validateUser(user *User) error {
-	panic("TODO: implement validateUser")
+	if user == nil {
+		return Error(InvalidArgument, "user is required")
+	}
+
+	var validationErrors []*ValidationError
+
+	if user.ID == "" {
+		validationErrors = append(validationErrors, &ValidationError{
+			Field:   "id",
+			Message: "cannot be empty",
+		})
+	}
+
+	if user.Name == "" {
+		validationErrors = append(validationErrors, &ValidationError{
+			Field:   "name",
+			Message: "cannot be empty",
+		})
+	}
+
+	if user.Email == "" {
+		validationErrors = append(validationErrors, &ValidationError{
+			Field:   "email",
+			Message: "cannot be empty",
+		})
+	} else if !isValidEmail(user.Email) {
+		validationErrors = append(validationErrors, &ValidationError{
+			Field:   "email",
+			Message: "invalid email format",
+		})
+	}
+
+	if user.Age <= 0 {
+		validationErrors = append(validationErrors, &ValidationError{
+			Field:   "age",
+			Message: "must be positive",
+		})
+	}
+
+	if len(validationErrors) > 0 {
+		st := NewStatus(InvalidArgument, "validation failed")
+		st, _ = st.WithDetails(&ErrorDetails{
+			ValidationErrors: validationErrors,
+			RequestID:        generateRequestID(),
+			Timestamp:        time.Now().Unix(),
+		})
+		return st.Err()
+	}
+
+	return nil
 }
 
-// TODO: isValidEmail 関数を実装してください
-// メールアドレスの形式をチェックしてください
+// isValidEmail メールアドレスの形式をチェックします
 func isValidEmail(email string) bool {
-	panic("TODO: implement isValidEmail")
+	pattern := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
+	match, _ := regexp.MatchString(pattern, email)
+	return match
 }
 
-// TODO: generateRequestID 関数を実装してください
-// ユニークなリクエストIDを生成してください
+// generateRequestID ユニークなリクエストIDを生成します
 func generateRequestID() string {
-	panic("TODO: implement generateRequestID")
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		// 乱数が取れない環境でもリクエストIDは空にしない
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// UserServiceInterface defines the interface for user service operations
+type UserServiceInterface interface {
+	GetUser(ctx context.Context, req *GetUserRequest) (*User, error)
+	CreateUser(ctx context.Context, req *CreateUserRequest) (*User, error)
+	UpdateUser(ctx context.Context, req *UpdateUserRequest) (*User, error)
+	DeleteUser(ctx context.Context, req *DeleteUserRequest) (*Empty, error)
+	ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error)
 }
 
 // UserClient クライアント実装
 type UserClient struct {
-	server *UserServiceServer
+	server UserServiceInterface
 }
 
-func NewUserClient(server *UserServiceServer) *UserClient {
+func NewUserClient(server UserServiceInterface) *UserClient {
 	return &UserClient{server: server}
 }
 
-// TODO: GetUser メソッドを実装してください
-// サーバーを呼び出し、エラーハンドリングを行ってください
+// GetUser サーバーを呼び出してユーザーを取得します
 func (c *UserClient) GetUser(ctx context.Context, userID string) (*User, error) {
-	panic("TODO: implement GetUser")
+	req := &GetUserRequest{UserID: userID}
+	user, err := c.server.GetUser(ctx, req)
+	if err != nil {
+		return nil, c.handleError(err)
+	}
+	return user, nil
 }
 
-// TODO: CreateUser メソッドを実装してください
+// CreateUser ユーザーを作成します
 func (c *UserClient) CreateUser(ctx context.Context, user *User) (*User, error) {
-	panic("TODO: implement CreateUser")
+	req := &CreateUserRequest{User: user}
+	createdUser, err := c.server.CreateUser(ctx, req)
+	if err != nil {
+		return nil, c.handleError(err)
+	}
+	return createdUser, nil
 }
 
-// TODO: GetUserWithRetry メソッドを実装してください
-// リトライ機能付きでユーザーを取得してください
+// GetUserWithRetry リトライ機能付きでユーザーを取得します
 func (c *UserClient) GetUserWithRetry(ctx context.Context, userID string, config RetryConfig) (*User, error) {
-	panic("TODO: implement GetUserWithRetry")
+	var lastErr error
+
+	for attempt := 0; attempt < config.MaxAttempts; attempt++ {
+		req := &GetUserRequest{UserID: userID}
+		user, err := c.server.GetUser(ctx, req)
+		if err == nil {
+			return user, nil
+		}
+
+		lastErr = err
+
+		// リトライ可能なエラーかチェック（handleError前の生エラーを使用）
+		if !c.isRetryableErrorWithConfig(err, config) {
+			return nil, c.handleError(err)
+		}
+
+		// 最後の試行でなければ待機（待機中のキャンセルにも応じる）
+		if attempt < config.MaxAttempts-1 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(c.calculateBackoff(attempt, config)):
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("max retry attempts reached: %w", c.handleError(lastErr))
 }
 
-// TODO: handleError メソッドを実装してください
-// gRPCエラーを適切に処理し、分類してください
+// handleError gRPCエラーを適切に処理し、分類します
 func (c *UserClient) handleError(err error) error {
-	panic("TODO: implement handleError")
+	st, ok := FromError(err)
+	if !ok {
+		// gRPCエラーではない
+		return err
+	}
+
+	switch st.Code() {
+	case NotFound:
+		return ErrUserNotFound
+	case InvalidArgument:
+		// 詳細なバリデーションエラーを処理
+		return c.processValidationErrors(st)
+	case Unavailable:
+		// リトライ可能なエラー
+		return ErrServiceUnavailable
+	case DeadlineExceeded:
+		return ErrTimeout
+	case AlreadyExists:
+		return ErrUserAlreadyExists
+	default:
+		return fmt.Errorf("gRPC error: %s", st.Message())
+	}
 }
 
-// TODO: processValidationErrors メソッドを実装してください
-// バリデーションエラーの詳細を処理してください
+// processValidationErrors バリデーションエラーの詳細を処理します
 func (c *UserClient) processValidationErrors(st *Status) error {
-	panic("TODO: implement processValidationErrors")
+	for _, detail := range st.Details() {
+		if errorDetails, ok := detail.(*ErrorDetails); ok {
+			var messages []string
+			for _, ve := range errorDetails.ValidationErrors {
+				messages = append(messages, fmt.Sprintf("%s: %s", ve.Field, ve.Message))
+			}
+			return fmt.Errorf("validation errors: %s: %w", strings.Join(messages, ", "), ErrValidationFailed)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrValidationFailed, st.Message())
 }
 
-// TODO: isRetryableError メソッドを実装してください
-// エラーがリトライ可能かどうかを判定してください
+// isRetryableError エラーがデフォルトのリトライ設定でリトライ可能かどうかを判定します
 func (c *UserClient) isRetryableError(err error) bool {
-	panic("TODO: implement isRetryableError")
+	return c.isRetryableErrorWithConfig(err, NewDefaultRetryConfig())
 }
 
-// TODO: calculateBackoff メソッドを実装してください
-// 指数バックオフを計算してください
+// isRetryableErrorWithConfig configに基づいてリトライ可能かチェック
+// gRPCステータスを持たないエラーはリトライしない
+func (c *UserClient) isRetryableErrorWithConfig(err error, config RetryConfig) bool {
+	st, ok := FromError(err)
+	if !ok || st.Code() == OK {
+		return false
+	}
+
+	return config.RetryableCodes[st.Code()]
+}
+
+// calculateBackoff 指数バックオフを計算します
 func (c *UserClient) calculateBackoff(attempt int, config RetryConfig) time.Duration {
-	panic("TODO: implement calculateBackoff")
+	backoff := config.BackoffBase * time.Duration(1<<attempt) // 指数バックオフ
+	if backoff > config.MaxBackoff {
+		backoff = config.MaxBackoff
+	}
+	return backoff
 }
 
 // カスタムエラー定義
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -272,10 +273,15 @@ func (s *UserServiceServer) CreateUser(ctx context.Context, req *CreateUserReque
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 重複チェック
+	// 重複チェック（IDとメールアドレス）
 	if _, exists := s.users[req.User.ID]; exists {
 		return nil, Errorf(AlreadyExists, "user %s already exists", req.User.ID)
 	}
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, req.User.Email) {
+			return nil, Errorf(AlreadyExists, "email %s is already registered", req.User.Email)
+		}
+	}
 
 	// 作成日時を設定
 	req.User.CreatedAt = time.Now().Unix()
@@ -343,17 +349,22 @@ func (s *UserServiceServer) ListUsers(ctx context.Context, req *ListUsersRequest
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// 全ユーザーを取得
+	// 全ユーザーを取得（ページ間で順序が変わらないようIDでソート）
 	allUsers := make([]*User, 0, len(s.users))
 	for _, user := range s.users {
 		allUsers = append(allUsers, user)
 	}
+	sort.Slice(allUsers, func(i, j int) bool {
+		return allUsers[i].ID < allUsers[j].ID
+	})
 
 	// ページング処理（簡単化）
 	start := 0
 	if req.PageToken != "" {
 		// ページトークンは簡単化のため、開始インデックスとして扱う
-		fmt.Sscanf(req.PageToken, "%d", &start)
+		if _, err := fmt.Sscanf(req.PageToken, "%d", &start); err != nil || start < 0 || start > len(allUsers) {
+			return nil, Errorf(InvalidArgument, "invalid page_token: %q", req.PageToken)
+		}
 	}
 
 	end := start + int(pageSize)
@@ -377,6 +388,10 @@ func (s *UserServiceServer) ListUsers(ctx context.Context, req *ListUsersRequest
 
 // validateUser ユーザーのバリデーションを行います
 func (s *UserServiceServer) validateUser(user *User) error {
+	if user == nil {
+		return Error(InvalidArgument, "user is required")
+	}
+
 	var validationErrors []*ValidationError
 
 	if user.ID == "" {
@@ -435,7 +450,10 @@ func isValidEmail(email string) bool {
 // generateRequestID ユニークなリクエストIDを生成します
 func generateRequestID() string {
 	bytes := make([]byte, 8)
-	rand.Read(bytes)
+	if _, err := rand.Read(bytes); err != nil {
+		// 乱数が取れない環境でもリクエストIDは空にしない
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
 	return hex.EncodeToString(bytes)
 }
 
@@ -495,10 +513,13 @@ func (c *UserClient) GetUserWithRetry(ctx context.Context, userID string, config
 			return nil, c.handleError(err)
 		}
 
-		// 最後の試行でなければ待機
+		// 最後の試行でなければ待機（待機中のキャンセルにも応じる）
 		if attempt < config.MaxAttempts-1 {
-			backoff := c.calculateBackoff(attempt, config)
-			time.Sleep(backoff)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(c.calculateBackoff(attempt, config)):
+			}
 		}
 	}
 
@@ -539,31 +560,22 @@ func (c *UserClient) processValidationErrors(st *Status) error {
 			for _, ve := range errorDetails.ValidationErrors {
 				messages = append(messages, fmt.Sprintf("%s: %s", ve.Field, ve.Message))
 			}
-			return fmt.Errorf("validation errors: %s", strings.Join(messages, ", "))
+			return fmt.Errorf("validation errors: %s: %w", strings.Join(messages, ", "), ErrValidationFailed)
 		}
 	}
-	return fmt.Errorf("validation failed: %s", st.Message())
+	return fmt.Errorf("%w: %s", ErrValidationFailed, st.Message())
 }
 
-// isRetryableError エラーがリトライ可能かどうかを判定します
+// isRetryableError エラーがデフォルトのリトライ設定でリトライ可能かどうかを判定します
 func (c *UserClient) isRetryableError(err error) bool {
-	st, ok := FromError(err)
-	if !ok {
-		return false
-	}
-
-	switch st.Code() {
-	case Unavailable, DeadlineExceeded, Internal:
-		return true
-	default:
-		return false
-	}
+	return c.isRetryableErrorWithConfig(err, NewDefaultRetryConfig())
 }
 
 // isRetryableErrorWithConfig configに基づいてリトライ可能かチェック
+// gRPCステータスを持たないエラーはリトライしない
 func (c *UserClient) isRetryableErrorWithConfig(err error, config RetryConfig) bool {
 	st, ok := FromError(err)
-	if !ok {
+	if !ok || st.Code() == OK {
 		return false
 	}
 
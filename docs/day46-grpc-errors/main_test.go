@@ -373,6 +373,157 @@ func TestUserService_ListUsers_Success(t *testing.T) {
 	t.Logf("Successfully retrieved %d users with next page token: %s", len(resp.Users), resp.NextPageToken)
 }
 
+func TestUserService_StatusCodes(t *testing.T) {
+	server := NewUserServiceServer()
+	ctx := context.Background()
+
+	alice := &User{ID: "alice", Name: "Alice", Email: "alice@example.com", Age: 30}
+	if _, err := server.CreateUser(ctx, &CreateUserRequest{User: alice}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		call func() error
+		code Code
+	}{
+		{"get missing user", func() error {
+			_, err := server.GetUser(ctx, &GetUserRequest{UserID: "bob"})
+			return err
+		}, NotFound},
+		{"update missing user", func() error {
+			_, err := server.UpdateUser(ctx, &UpdateUserRequest{User: &User{ID: "bob", Name: "Bob", Email: "bob@example.com", Age: 20}})
+			return err
+		}, NotFound},
+		{"delete missing user", func() error {
+			_, err := server.DeleteUser(ctx, &DeleteUserRequest{UserID: "bob"})
+			return err
+		}, NotFound},
+		{"delete without id", func() error {
+			_, err := server.DeleteUser(ctx, &DeleteUserRequest{})
+			return err
+		}, InvalidArgument},
+		{"create with duplicate email", func() error {
+			_, err := server.CreateUser(ctx, &CreateUserRequest{User: &User{ID: "alice2", Name: "Alice", Email: "ALICE@example.com", Age: 31}})
+			return err
+		}, AlreadyExists},
+		{"create without user", func() error {
+			_, err := server.CreateUser(ctx, &CreateUserRequest{})
+			return err
+		}, InvalidArgument},
+		{"list with invalid page token", func() error {
+			_, err := server.ListUsers(ctx, &ListUsersRequest{PageToken: "not-a-number"})
+			return err
+		}, InvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			st, ok := FromError(err)
+			if !ok {
+				t.Fatalf("Expected gRPC status error, got %v", err)
+			}
+			if st.Code() != tc.code {
+				t.Errorf("Expected %v, got %v", tc.code, st.Code())
+			}
+		})
+	}
+
+	// 重複したメールアドレスのユーザーは作成されていない
+	if _, exists := server.users["alice2"]; exists {
+		t.Error("User with duplicate email should not be stored")
+	}
+}
+
+func TestUserService_ValidationErrorDetails(t *testing.T) {
+	server := NewUserServiceServer()
+
+	// 名前とIDは正しく、メール形式と年齢だけが不正
+	_, err := server.CreateUser(context.Background(), &CreateUserRequest{User: &User{
+		ID:    "user001",
+		Name:  "Test User",
+		Email: "not-an-email",
+		Age:   -1,
+	}})
+
+	st, ok := FromError(err)
+	if !ok || st.Code() != InvalidArgument {
+		t.Fatalf("Expected InvalidArgument status, got %v", err)
+	}
+
+	var details *ErrorDetails
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*ErrorDetails); ok {
+			details = d
+		}
+	}
+	if details == nil {
+		t.Fatal("Expected ErrorDetails in status")
+	}
+
+	got := make(map[string]string)
+	for _, ve := range details.ValidationErrors {
+		got[ve.Field] = ve.Message
+	}
+	want := map[string]string{
+		"email": "invalid email format",
+		"age":   "must be positive",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected only %v to fail validation, got %v", want, got)
+	}
+	for field, message := range want {
+		if got[field] != message {
+			t.Errorf("Expected %s error %q, got %q", field, message, got[field])
+		}
+	}
+
+	if len(details.RequestID) != 16 || strings.Trim(details.RequestID, "0123456789abcdef") != "" {
+		t.Errorf("Expected 16-char hex request ID, got %q", details.RequestID)
+	}
+	if details.Timestamp == 0 {
+		t.Error("Expected error details to carry a timestamp")
+	}
+}
+
+func TestUserService_ListUsers_Pagination(t *testing.T) {
+	server := NewUserServiceServer()
+	for i := 1; i <= 25; i++ {
+		id := fmt.Sprintf("user%03d", i)
+		server.users[id] = &User{ID: id, Name: id, Email: id + "@example.com", Age: 20}
+	}
+
+	// ページを辿ると全ユーザーを重複なくID順で取得できる
+	var ids []string
+	token := ""
+	for page := 0; page < 5; page++ {
+		resp, err := server.ListUsers(context.Background(), &ListUsersRequest{PageSize: 10, PageToken: token})
+		if err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+		for _, user := range resp.Users {
+			ids = append(ids, user.ID)
+		}
+		token = resp.NextPageToken
+		if token == "" {
+			break
+		}
+	}
+
+	if len(ids) != 25 {
+		t.Fatalf("Expected 25 users across pages, got %d", len(ids))
+	}
+	for i, id := range ids {
+		if want := fmt.Sprintf("user%03d", i+1); id != want {
+			t.Errorf("Expected %s at position %d, got %s", want, i, id)
+		}
+	}
+}
+
 func TestUserClient_GetUserWithRetry_Success(t *testing.T) {
 	server := NewUserServiceServer()
 	_ = NewUserClient(server)
@@ -565,6 +716,104 @@ func (s *FailingUserServiceServer) GetUser(ctx context.Context, req *GetUserRequ
 	return s.UserServiceServer.GetUser(ctx, req)
 }
 
+func TestUserClient_GetUserWithRetry_AttemptCount(t *testing.T) {
+	config := RetryConfig{
+		MaxAttempts: 5,
+		BackoffBase: time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+		RetryableCodes: map[Code]bool{
+			Unavailable: true,
+		},
+	}
+
+	newServer := func(failAttempts int) *FailingUserServiceServer {
+		server := NewUserServiceServer()
+		server.users["user001"] = &User{ID: "user001", Name: "Test User", Email: "test@example.com", Age: 25}
+		return &FailingUserServiceServer{UserServiceServer: server, failAttempts: failAttempts}
+	}
+
+	t.Run("succeeds on third attempt", func(t *testing.T) {
+		server := newServer(2) // Unavailable を2回返してから成功
+		user, err := NewUserClient(server).GetUserWithRetry(context.Background(), "user001", config)
+		if err != nil {
+			t.Fatalf("Expected success after retries, got %v", err)
+		}
+		if user.ID != "user001" {
+			t.Errorf("Expected user001, got %s", user.ID)
+		}
+		if server.attempts != 3 {
+			t.Errorf("Expected exactly 3 attempts, got %d", server.attempts)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		server := newServer(10)
+		_, err := NewUserClient(server).GetUserWithRetry(context.Background(), "user001", config)
+		if !errors.Is(err, ErrServiceUnavailable) {
+			t.Errorf("Expected ErrServiceUnavailable, got %v", err)
+		}
+		if server.attempts != config.MaxAttempts {
+			t.Errorf("Expected %d attempts, got %d", config.MaxAttempts, server.attempts)
+		}
+	})
+
+	t.Run("non-retryable error returns immediately", func(t *testing.T) {
+		server := newServer(0)
+		_, err := NewUserClient(server).GetUserWithRetry(context.Background(), "missing", config)
+		if !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+		if server.attempts != 1 {
+			t.Errorf("Expected a single attempt, got %d", server.attempts)
+		}
+	})
+
+	t.Run("stops waiting when context is cancelled", func(t *testing.T) {
+		server := newServer(10)
+		slow := config
+		slow.BackoffBase = time.Hour
+		slow.MaxBackoff = time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := NewUserClient(server).GetUserWithRetry(ctx, "user001", slow)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+		if server.attempts != 1 {
+			t.Errorf("Expected a single attempt before cancellation, got %d", server.attempts)
+		}
+	})
+}
+
+func TestUserClient_HandleError_Sentinels(t *testing.T) {
+	client := &UserClient{}
+
+	testCases := []struct {
+		code Code
+		want error
+	}{
+		{NotFound, ErrUserNotFound},
+		{AlreadyExists, ErrUserAlreadyExists},
+		{Unavailable, ErrServiceUnavailable},
+		{DeadlineExceeded, ErrTimeout},
+		{InvalidArgument, ErrValidationFailed},
+	}
+
+	for _, tc := range testCases {
+		if err := client.handleError(Error(tc.code, "test error")); !errors.Is(err, tc.want) {
+			t.Errorf("handleError(%v) = %v, expected %v", tc.code, err, tc.want)
+		}
+	}
+
+	// gRPCステータスでないエラーはそのまま返す
+	plain := errors.New("plain error")
+	if err := client.handleError(plain); err != plain {
+		t.Errorf("Expected non-status error to pass through, got %v", err)
+	}
+}
+
 func TestRetryableErrorTypes(t *testing.T) {
 	client := &UserClient{}
 
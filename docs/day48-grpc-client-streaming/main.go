@@ -7,8 +7,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -91,53 +94,309 @@ func NewStreamingServer() *StreamingServer {
 	}
 }
 
-// TODO: CollectData メソッドを実装してください
-// クライアントからのデータポイントストリームを受信し、処理してください
+// CollectData クライアントからのデータポイントストリームを受信し、処理
 func (s *StreamingServer) CollectData(stream DataCollectorStreamClient) (*CollectionResult, error) {
-	panic("TODO: implement CollectData")
+	var count int32
+	var dataPoints []*DataPoint
+
+	// MockDataCollectorStreamの場合は、既に蓄積されたデータを処理
+	if mockStream, ok := stream.(*MockDataCollectorStream); ok {
+		dataPoints = mockStream.GetDataPoints()
+	} else {
+		// 実際のストリームからデータを受信
+		for {
+			dataPoint, err := s.receiveDataPoint(stream)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return &CollectionResult{
+					TotalPoints:  count,
+					ProcessedAt:  time.Now().Unix(),
+					Status:       "ERROR",
+					ErrorMessage: err.Error(),
+				}, err
+			}
+			dataPoints = append(dataPoints, dataPoint)
+		}
+	}
+
+	// データポイントの検証と保存
+	for _, dataPoint := range dataPoints {
+		if err := validateDataPoint(dataPoint); err != nil {
+			return &CollectionResult{
+				TotalPoints:  count,
+				ProcessedAt:  time.Now().Unix(),
+				Status:       "ERROR",
+				ErrorMessage: fmt.Sprintf("validation failed: %v", err),
+			}, err
+		}
+
+		s.mu.Lock()
+		s.dataPoints = append(s.dataPoints, dataPoint)
+		s.mu.Unlock()
+		count++
+	}
+
+	return &CollectionResult{
+		TotalPoints: count,
+		ProcessedAt: time.Now().Unix(),
+		Status:      "SUCCESS",
+	}, nil
 }
 
-// TODO: CollectLogs メソッドを実装してください
-// クライアントからのログストリームを受信し、処理してください
+// CollectLogs クライアントからのログストリームを受信し、処理
 func (s *StreamingServer) CollectLogs(stream LogCollectorStreamClient) (*LogCollectionResult, error) {
-	panic("TODO: implement CollectLogs")
+	var count int32
+	var logs []*LogEntry
+
+	// MockLogCollectorStreamの場合は、既に蓄積されたデータを処理
+	if mockStream, ok := stream.(*MockLogCollectorStream); ok {
+		logs = mockStream.GetLogs()
+	} else {
+		// 実際のストリームからログを受信
+		for {
+			log, err := s.receiveLog(stream)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return &LogCollectionResult{
+					TotalLogs:   count,
+					ProcessedAt: time.Now().Unix(),
+					Status:      "ERROR",
+				}, err
+			}
+			logs = append(logs, log)
+		}
+	}
+
+	// ログの保存
+	s.mu.Lock()
+	for _, log := range logs {
+		s.logs = append(s.logs, log)
+		count++
+	}
+	s.mu.Unlock()
+
+	return &LogCollectionResult{
+		TotalLogs:   count,
+		ProcessedAt: time.Now().Unix(),
+		Status:      "SUCCESS",
+	}, nil
 }
 
-// TODO: UploadFile メソッドを実装してください
-// クライアントからのファイルチャンクストリームを受信し、ファイルを再構築してください
+// UploadFile クライアントからのファイルチャンクストリームを受信し、ファイルを再構築
 func (s *StreamingServer) UploadFile(stream FileUploaderStreamClient) (*FileUploadResult, error) {
-	panic("TODO: implement UploadFile")
+	var chunks []*FileChunk
+	var filename string
+	var totalChunks int32
+
+	// MockFileUploaderStreamの場合は、既に蓄積されたチャンクを処理
+	if mockStream, ok := stream.(*MockFileUploaderStream); ok {
+		chunks = mockStream.GetChunks()
+	} else {
+		// 実際のストリームからチャンクを受信
+		for {
+			chunk, err := s.receiveFileChunk(stream)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return &FileUploadResult{
+					Filename:    filename,
+					TotalChunks: totalChunks,
+					ProcessedAt: time.Now().Unix(),
+					Status:      "ERROR",
+				}, err
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return &FileUploadResult{
+			TotalChunks: 0,
+			ProcessedAt: time.Now().Unix(),
+			Status:      "ERROR",
+		}, fmt.Errorf("no chunks received")
+	}
+
+	// ストリーム上の到着順ではなく ChunkID 順に並べ替えてから再構築する
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].ChunkID < chunks[j].ChunkID
+	})
+
+	// ファイル再構築（欠番や重複があればエラー）
+	filename = chunks[0].Filename
+	var fileData []byte
+
+	for i, chunk := range chunks {
+		if chunk.ChunkID != int32(i) {
+			return &FileUploadResult{
+				Filename:    filename,
+				TotalChunks: int32(len(chunks)),
+				ProcessedAt: time.Now().Unix(),
+				Status:      "ERROR",
+			}, fmt.Errorf("chunk sequence error: expected %d, got %d", i, chunk.ChunkID)
+		}
+		fileData = append(fileData, chunk.Data...)
+		totalChunks++
+	}
+
+	// ファイル保存
+	s.mu.Lock()
+	s.uploadedFiles[filename] = fileData
+	s.mu.Unlock()
+
+	return &FileUploadResult{
+		Filename:    filename,
+		TotalSize:   int64(len(fileData)),
+		TotalChunks: totalChunks,
+		ProcessedAt: time.Now().Unix(),
+		Status:      "SUCCESS",
+	}, nil
 }
 
-// TODO: GetDataPoints メソッドを実装してください
-// 収集されたデータポイントを返してください
+// GetDataPoints 収集されたデータポイントを返す
 func (s *StreamingServer) GetDataPoints() []*DataPoint {
-	panic("TODO: implement GetDataPoints")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*DataPoint, len(s.dataPoints))
+	copy(result, s.dataPoints)
+	return result
 }
 
-// TODO: GetLogs メソッドを実装してください
-// 収集されたログを返してください
+// GetLogs 収集されたログを返す
 func (s *StreamingServer) GetLogs() []*LogEntry {
-	panic("TODO: implement GetLogs")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*LogEntry, len(s.logs))
+	copy(result, s.logs)
+	return result
 }
 
-// TODO: GetUploadedFile メソッドを実装してください
-// アップロードされたファイルを返してください
+// GetUploadedFile アップロードされたファイルを返す
 func (s *StreamingServer) GetUploadedFile(filename string) ([]byte, bool) {
-	panic("TODO: implement GetUploadedFile")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, exists := s.uploadedFiles[filename]
+	if !exists {
+		return nil, false
+	}
+
+	// 呼び出し側の変更がサーバーの状態に影響しないようコピーを返す
+	result := make([]byte, len(data))
+	copy(result, data)
+	return result, true
+}
+
+// ヘルパーメソッド（実際のgRPCストリーム用）
+func (s *StreamingServer) receiveDataPoint(stream DataCollectorStreamClient) (*DataPoint, error) {
+	// 実際の実装では stream.Recv() を使用
+	return nil, io.EOF
+}
+
+func (s *StreamingServer) receiveLog(stream LogCollectorStreamClient) (*LogEntry, error) {
+	// 実際の実装では stream.Recv() を使用
+	return nil, io.EOF
+}
+
+func (s *StreamingServer) receiveFileChunk(stream FileUploaderStreamClient) (*FileChunk, error) {
+	// 実際の実装では stream.Recv() を使用
+	return nil, io.EOF
+}
+
+// ErrStreamUnavailable は接続断など、ストリームを張り直せば回復しうる一時的なエラー
+var ErrStreamUnavailable = errors.New("stream unavailable")
+
+// RetryPolicy はデータポイント送信のリトライ方針
+type RetryPolicy struct {
+	MaxAttempts     int              // チェックポイントごとのストリーム確立の最大回数（初回を含む）
+	InitialBackoff  time.Duration    // 1回目のリトライ前の待機時間
+	MaxBackoff      time.Duration    // 待機時間の上限
+	CheckpointEvery int              // この件数ごとにストリームを閉じてサーバーの受領確認を得る（0以下なら全件を1本で送る）
+	IsRetryable     func(error) bool // nil の場合は ErrStreamUnavailable のみリトライする
+}
+
+// NewDefaultRetryPolicy はデフォルトのリトライポリシーを返す
+func NewDefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     3,
+		InitialBackoff:  50 * time.Millisecond,
+		MaxBackoff:      time.Second,
+		CheckpointEvery: 100,
+	}
+}
+
+func (p RetryPolicy) isRetryable(err error) bool {
+	if p.IsRetryable != nil {
+		return p.IsRetryable(err)
+	}
+	return errors.Is(err, ErrStreamUnavailable)
+}
+
+// backoff は attempt 回目の失敗後に待つ時間を返す（指数バックオフ）
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// StreamSendError はリトライしても送信を完了できなかったことを表す。
+// 先頭から Confirmed 件まではサーバーが受領を確認済み
+type StreamSendError struct {
+	Confirmed int
+	Attempts  int
+	Err       error
+}
+
+func (e *StreamSendError) Error() string {
+	return fmt.Sprintf("stream send failed after %d attempts (%d points confirmed): %v", e.Attempts, e.Confirmed, e.Err)
+}
+
+func (e *StreamSendError) Unwrap() error {
+	return e.Err
 }
 
 // クライアント実装
 type StreamingClient struct {
-	server *StreamingServer
+	server        *StreamingServer
+	retryPolicy   RetryPolicy
+	newDataStream func(ctx context.Context) DataCollectorStreamClient
 }
 
 func NewStreamingClient(server *StreamingServer) *StreamingClient {
-	return &StreamingClient{server: server}
+	c := &StreamingClient{
+		server:      server,
+		retryPolicy: NewDefaultRetryPolicy(),
+	}
+	c.newDataStream = func(ctx context.Context) DataCollectorStreamClient {
+		return NewMockDataCollectorStream(ctx, c.server)
+	}
+	return c
+}
+
+// SetRetryPolicy は SendDataPoints のリトライポリシーを変更する
+func (c *StreamingClient) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
 }
 
 // TODO: SendDataPoints メソッドを実装してください
 // データポイントの配列をストリームで送信してください
+// - c.retryPolicy.CheckpointEvery 件ごとにストリームを閉じ、サーバーの応答を受領確認とする
+// - リトライ可能なエラーなら c.newDataStream でストリームを張り直し、
+//   最後のチェックポイントから未確認のデータを再送する
+// - リトライ回数は MaxAttempts まで、間隔は backoff で求める
+// - コンテキストのエラーはそのまま返し、それ以外の失敗は *StreamSendError で返す
 func (c *StreamingClient) SendDataPoints(ctx context.Context, dataPoints []*DataPoint) (*CollectionResult, error) {
 	panic("TODO: implement SendDataPoints")
 }
@@ -148,16 +407,48 @@ func (c *StreamingClient) SendLogs(ctx context.Context, logs []*LogEntry) (*LogC
 	panic("TODO: implement SendLogs")
 }
 
-// TODO: UploadFile メソッドを実装してください
-// ファイルをチャンクに分割してストリームで送信してください
+// UploadFile ファイルをチャンクに分割してストリームで送信
 func (c *StreamingClient) UploadFile(ctx context.Context, filename string, data []byte, chunkSize int) (*FileUploadResult, error) {
-	panic("TODO: implement UploadFile")
+	stream := NewMockFileUploaderStream(ctx, c.server)
+	
+	chunks := createFileChunks(filename, data, chunkSize)
+	
+	for _, chunk := range chunks {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			if err := stream.Send(chunk); err != nil {
+				return nil, fmt.Errorf("failed to send chunk: %w", err)
+			}
+		}
+	}
+	
+	return stream.CloseAndRecv()
 }
 
-// TODO: SendDataPointsWithCallback メソッドを実装してください
-// 送信進捗をコールバックで通知しながらデータを送信してください
+// SendDataPointsWithCallback 送信進捗をコールバックで通知しながらデータを送信
 func (c *StreamingClient) SendDataPointsWithCallback(ctx context.Context, dataPoints []*DataPoint, callback func(int, int)) (*CollectionResult, error) {
-	panic("TODO: implement SendDataPointsWithCallback")
+	stream := NewMockDataCollectorStream(ctx, c.server)
+	
+	total := len(dataPoints)
+	for i, dataPoint := range dataPoints {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			if err := stream.Send(dataPoint); err != nil {
+				return nil, fmt.Errorf("failed to send data point: %w", err)
+			}
+			
+			// 進捗を通知
+			if callback != nil {
+				callback(i+1, total)
+			}
+		}
+	}
+	
+	return stream.CloseAndRecv()
 }
 
 // モックストリーム実装
@@ -217,7 +508,7 @@ func (m *MockDataCollectorStream) GetDataPoints() []*DataPoint {
 	return result
 }
 
-// TODO: MockLogCollectorStream を実装してください
+// MockLogCollectorStream ログ収集ストリームのモック実装
 type MockLogCollectorStream struct {
 	logs   []*LogEntry
 	ctx    context.Context
@@ -226,27 +517,52 @@ type MockLogCollectorStream struct {
 	mu     sync.Mutex
 }
 
-// TODO: NewMockLogCollectorStream を実装してください
 func NewMockLogCollectorStream(ctx context.Context, server *StreamingServer) *MockLogCollectorStream {
-	panic("TODO: implement NewMockLogCollectorStream")
+	return &MockLogCollectorStream{
+		logs:   make([]*LogEntry, 0),
+		ctx:    ctx,
+		server: server,
+	}
 }
 
-// TODO: Send メソッドを実装してください
 func (m *MockLogCollectorStream) Send(log *LogEntry) error {
-	panic("TODO: implement Send")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if m.closed {
+		return fmt.Errorf("stream is closed")
+	}
+	
+	select {
+	case <-m.ctx.Done():
+		return m.ctx.Err()
+	default:
+		m.logs = append(m.logs, log)
+		return nil
+	}
 }
 
-// TODO: CloseAndRecv メソッドを実装してください
 func (m *MockLogCollectorStream) CloseAndRecv() (*LogCollectionResult, error) {
-	panic("TODO: implement CloseAndRecv")
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	
+	return m.server.CollectLogs(m)
 }
 
-// TODO: Context メソッドを実装してください
 func (m *MockLogCollectorStream) Context() context.Context {
-	panic("TODO: implement Context")
+	return m.ctx
+}
+
+func (m *MockLogCollectorStream) GetLogs() []*LogEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]*LogEntry, len(m.logs))
+	copy(result, m.logs)
+	return result
 }
 
-// TODO: MockFileUploaderStream を実装してください
+// MockFileUploaderStream ファイルアップロードストリームのモック実装
 type MockFileUploaderStream struct {
 	chunks []*FileChunk
 	ctx    context.Context
@@ -255,24 +571,49 @@ type MockFileUploaderStream struct {
 	mu     sync.Mutex
 }
 
-// TODO: NewMockFileUploaderStream を実装してください
 func NewMockFileUploaderStream(ctx context.Context, server *StreamingServer) *MockFileUploaderStream {
-	panic("TODO: implement NewMockFileUploaderStream")
+	return &MockFileUploaderStream{
+		chunks: make([]*FileChunk, 0),
+		ctx:    ctx,
+		server: server,
+	}
 }
 
-// TODO: Send メソッドを実装してください
 func (m *MockFileUploaderStream) Send(chunk *FileChunk) error {
-	panic("TODO: implement Send")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if m.closed {
+		return fmt.Errorf("stream is closed")
+	}
+	
+	select {
+	case <-m.ctx.Done():
+		return m.ctx.Err()
+	default:
+		m.chunks = append(m.chunks, chunk)
+		return nil
+	}
 }
 
-// TODO: CloseAndRecv メソッドを実装してください
 func (m *MockFileUploaderStream) CloseAndRecv() (*FileUploadResult, error) {
-	panic("TODO: implement CloseAndRecv")
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+	
+	return m.server.UploadFile(m)
 }
 
-// TODO: Context メソッドを実装してください
 func (m *MockFileUploaderStream) Context() context.Context {
-	panic("TODO: implement Context")
+	return m.ctx
+}
+
+func (m *MockFileUploaderStream) GetChunks() []*FileChunk {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]*FileChunk, len(m.chunks))
+	copy(result, m.chunks)
+	return result
 }
 
 // ユーティリティ関数
@@ -289,16 +630,61 @@ func generateLogs(count int, service string) []*LogEntry {
 	panic("TODO: implement generateLogs")
 }
 
-// TODO: createFileChunks 関数を実装してください
-// ファイルデータをチャンクに分割してください
+// createFileChunks ファイルデータをチャンクに分割
 func createFileChunks(filename string, data []byte, chunkSize int) []*FileChunk {
-	panic("TODO: implement createFileChunks")
+	if chunkSize <= 0 {
+		chunkSize = 1024 // デフォルト 1KB
+	}
+	
+	var chunks []*FileChunk
+	
+	for i := 0; i < len(data); i += chunkSize {
+		end := i + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		
+		chunk := &FileChunk{
+			ChunkID:  int32(len(chunks)),
+			Data:     data[i:end],
+			Filename: filename,
+			IsLast:   end == len(data),
+		}
+		chunks = append(chunks, chunk)
+	}
+	
+	if len(chunks) == 0 {
+		// 空ファイルの場合
+		chunks = append(chunks, &FileChunk{
+			ChunkID:  0,
+			Data:     []byte{},
+			Filename: filename,
+			IsLast:   true,
+		})
+	}
+	
+	return chunks
 }
 
-// TODO: validateDataPoint 関数を実装してください
-// データポイントの妥当性を検証してください
+// validateDataPoint データポイントの妥当性を検証
 func validateDataPoint(dataPoint *DataPoint) error {
-	panic("TODO: implement validateDataPoint")
+	if dataPoint == nil {
+		return fmt.Errorf("data point is nil")
+	}
+	
+	if strings.TrimSpace(dataPoint.ID) == "" {
+		return fmt.Errorf("data point ID cannot be empty")
+	}
+	
+	if strings.TrimSpace(dataPoint.Source) == "" {
+		return fmt.Errorf("data point source cannot be empty")
+	}
+	
+	if dataPoint.Timestamp <= 0 {
+		return fmt.Errorf("data point timestamp must be positive")
+	}
+	
+	return nil
 }
 
 func main() {
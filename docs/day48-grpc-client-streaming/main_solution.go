@@ -5,8 +5,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -218,7 +220,12 @@ func (s *StreamingServer) UploadFile(stream FileUploaderStreamClient) (*FileUplo
 		}, fmt.Errorf("no chunks received")
 	}
 
-	// ファイル再構築
+	// ストリーム上の到着順ではなく ChunkID 順に並べ替えてから再構築する
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].ChunkID < chunks[j].ChunkID
+	})
+
+	// ファイル再構築（欠番や重複があればエラー）
 	filename = chunks[0].Filename
 	var fileData []byte
 
@@ -300,19 +307,149 @@ func (s *StreamingServer) receiveFileChunk(stream FileUploaderStreamClient) (*Fi
 	return nil, io.EOF
 }
 
+// ErrStreamUnavailable は接続断など、ストリームを張り直せば回復しうる一時的なエラー
+var ErrStreamUnavailable = errors.New("stream unavailable")
+
+// RetryPolicy はデータポイント送信のリトライ方針
+type RetryPolicy struct {
+	MaxAttempts     int              // チェックポイントごとのストリーム確立の最大回数（初回を含む）
+	InitialBackoff  time.Duration    // 1回目のリトライ前の待機時間
+	MaxBackoff      time.Duration    // 待機時間の上限
+	CheckpointEvery int              // この件数ごとにストリームを閉じてサーバーの受領確認を得る（0以下なら全件を1本で送る）
+	IsRetryable     func(error) bool // nil の場合は ErrStreamUnavailable のみリトライする
+}
+
+// NewDefaultRetryPolicy はデフォルトのリトライポリシーを返す
+func NewDefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     3,
+		InitialBackoff:  50 * time.Millisecond,
+		MaxBackoff:      time.Second,
+		CheckpointEvery: 100,
+	}
+}
+
+func (p RetryPolicy) isRetryable(err error) bool {
+	if p.IsRetryable != nil {
+		return p.IsRetryable(err)
+	}
+	return errors.Is(err, ErrStreamUnavailable)
+}
+
+// backoff は attempt 回目の失敗後に待つ時間を返す（指数バックオフ）
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// StreamSendError はリトライしても送信を完了できなかったことを表す。
+// 先頭から Confirmed 件まではサーバーが受領を確認済み
+type StreamSendError struct {
+	Confirmed int
+	Attempts  int
+	Err       error
+}
+
+func (e *StreamSendError) Error() string {
+	return fmt.Sprintf("stream send failed after %d attempts (%d points confirmed): %v", e.Attempts, e.Confirmed, e.Err)
+}
+
+func (e *StreamSendError) Unwrap() error {
+	return e.Err
+}
+
 // クライアント実装
 type StreamingClient struct {
-	server *StreamingServer
+	server        *StreamingServer
+	retryPolicy   RetryPolicy
+	newDataStream func(ctx context.Context) DataCollectorStreamClient
 }
 
 func NewStreamingClient(server *StreamingServer) *StreamingClient {
-	return &StreamingClient{server: server}
+	c := &StreamingClient{
+		server:      server,
+		retryPolicy: NewDefaultRetryPolicy(),
+	}
+	c.newDataStream = func(ctx context.Context) DataCollectorStreamClient {
+		return NewMockDataCollectorStream(ctx, c.server)
+	}
+	return c
+}
+
+// SetRetryPolicy は SendDataPoints のリトライポリシーを変更する
+func (c *StreamingClient) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
 }
 
 // SendDataPoints データポイントの配列をストリームで送信
+//
+// CheckpointEvery 件ごとにストリームを閉じ、サーバーの応答をもって受領確認とする。
+// リトライ可能なエラーでストリームが切れた場合は張り直し、未確認のデータを
+// 最後のチェックポイントから再送する。
 func (c *StreamingClient) SendDataPoints(ctx context.Context, dataPoints []*DataPoint) (*CollectionResult, error) {
-	stream := NewMockDataCollectorStream(ctx, c.server)
-	
+	policy := c.retryPolicy
+	batchSize := policy.CheckpointEvery
+	if batchSize <= 0 || batchSize > len(dataPoints) {
+		batchSize = len(dataPoints)
+	}
+
+	confirmed := 0 // チェックポイント: サーバーが受領を確認した件数
+	attempt := 0
+	var total int32
+	var result *CollectionResult
+
+	for {
+		end := confirmed + batchSize
+		if end > len(dataPoints) {
+			end = len(dataPoints)
+		}
+
+		batchResult, err := c.streamDataPoints(ctx, dataPoints[confirmed:end])
+		if err == nil {
+			confirmed = end
+			total += batchResult.TotalPoints
+			result = batchResult
+			attempt = 0
+			if confirmed >= len(dataPoints) {
+				break
+			}
+			continue
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		attempt++
+		if !policy.isRetryable(err) || attempt >= policy.MaxAttempts {
+			return nil, &StreamSendError{Confirmed: confirmed, Attempts: attempt, Err: err}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(policy.backoff(attempt)):
+		}
+	}
+
+	result.TotalPoints = total
+	return result, nil
+}
+
+// streamDataPoints は1本のストリームでデータポイントを送信し、サーバーの応答を待つ
+func (c *StreamingClient) streamDataPoints(ctx context.Context, dataPoints []*DataPoint) (*CollectionResult, error) {
+	// 失敗したストリームは閉じずに破棄するため、試行ごとにコンテキストを分ける
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream := c.newDataStream(streamCtx)
+
 	for _, dataPoint := range dataPoints {
 		select {
 		case <-ctx.Done():
@@ -323,7 +460,7 @@ func (c *StreamingClient) SendDataPoints(ctx context.Context, dataPoints []*Data
 			}
 		}
 	}
-	
+
 	return stream.CloseAndRecv()
 }
 
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -312,6 +314,69 @@ func TestStreamingClient_UploadFile_LargeFile(t *testing.T) {
 		result.TotalSize, result.TotalChunks)
 }
 
+func TestStreamingClient_UploadFile_ChunkingWithProgress(t *testing.T) {
+	const chunkSize = 1024
+	payload := bytes.Repeat([]byte("0123456789"), chunkSize) // 10KB
+
+	t.Run("splits payload into 1KB chunks", func(t *testing.T) {
+		chunks := createFileChunks("payload.bin", payload, chunkSize)
+		if len(chunks) != 10 {
+			t.Fatalf("Expected 10 chunks, got %d", len(chunks))
+		}
+		for i, chunk := range chunks {
+			if chunk.ChunkID != int32(i) {
+				t.Errorf("Chunk %d has ChunkID %d", i, chunk.ChunkID)
+			}
+			if len(chunk.Data) != chunkSize {
+				t.Errorf("Chunk %d has %d bytes, want %d", i, len(chunk.Data), chunkSize)
+			}
+			if chunk.IsLast != (i == len(chunks)-1) {
+				t.Errorf("Chunk %d IsLast = %v", i, chunk.IsLast)
+			}
+		}
+
+		server := NewStreamingServer()
+		client := NewStreamingClient(server)
+		result, err := client.UploadFile(context.Background(), "payload.bin", payload, chunkSize)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.TotalChunks != 10 || result.TotalSize != int64(len(payload)) {
+			t.Errorf("Expected 10 chunks / %d bytes, got %d / %d", len(payload), result.TotalChunks, result.TotalSize)
+		}
+		saved, _ := server.GetUploadedFile("payload.bin")
+		if !bytes.Equal(saved, payload) {
+			t.Error("Uploaded payload was not reassembled correctly")
+		}
+	})
+
+	t.Run("callback reports increasing progress", func(t *testing.T) {
+		server := NewStreamingServer()
+		client := NewStreamingClient(server)
+
+		var sentCounts []int
+		callback := func(sent, total int) {
+			if total != 10 {
+				t.Errorf("Expected total 10, got %d", total)
+			}
+			sentCounts = append(sentCounts, sent)
+		}
+
+		if _, err := client.SendDataPointsWithCallback(context.Background(), generateDataPoints(10, "progress"), callback); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(sentCounts) != 10 {
+			t.Fatalf("Expected callback to fire 10 times, got %d", len(sentCounts))
+		}
+		for i, sent := range sentCounts {
+			if sent != i+1 {
+				t.Errorf("Expected callback %d to report %d sent, got %d", i, i+1, sent)
+			}
+		}
+	})
+}
+
 func TestStreamingServer_CollectData_ValidationError(t *testing.T) {
 	server := NewStreamingServer()
 
@@ -356,6 +421,204 @@ func TestStreamingServer_CollectData_ValidationError(t *testing.T) {
 	t.Logf("Correctly handled validation error: %s", result.ErrorMessage)
 }
 
+func TestStreamingServer_CollectData_ManyPoints(t *testing.T) {
+	server := NewStreamingServer()
+	stream := NewMockDataCollectorStream(context.Background(), server)
+
+	const numPoints = 100
+	for i := 0; i < numPoints; i++ {
+		err := stream.Send(&DataPoint{
+			ID:        fmt.Sprintf("point_%d", i),
+			Value:     float64(i),
+			Timestamp: time.Now().Unix(),
+			Source:    "bulk",
+		})
+		if err != nil {
+			t.Fatalf("Send %d failed: %v", i, err)
+		}
+	}
+
+	result, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Status != "SUCCESS" {
+		t.Errorf("Expected SUCCESS status, got %s", result.Status)
+	}
+	if result.TotalPoints != numPoints {
+		t.Errorf("Expected %d points, got %d", numPoints, result.TotalPoints)
+	}
+
+	saved := server.GetDataPoints()
+	if len(saved) != numPoints {
+		t.Fatalf("Expected %d saved points, got %d", numPoints, len(saved))
+	}
+	for i, point := range saved {
+		if point.Value != float64(i) {
+			t.Errorf("Expected point %d to keep send order, got value %f", i, point.Value)
+			break
+		}
+	}
+
+	// クローズ後の送信は拒否される
+	if err := stream.Send(saved[0]); err == nil {
+		t.Error("Expected Send after CloseAndRecv to fail")
+	}
+}
+
+func TestStreamingServer_UploadFile_ReassemblesByChunkID(t *testing.T) {
+	server := NewStreamingServer()
+
+	data := []byte("client-side streaming reassembles chunks by id")
+	const chunkSize = 8
+
+	var chunks []*FileChunk
+	for i := 0; i*chunkSize < len(data); i++ {
+		end := (i + 1) * chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		chunks = append(chunks, &FileChunk{
+			ChunkID:  int32(i),
+			Data:     data[i*chunkSize : end],
+			Filename: "chunked.txt",
+			IsLast:   end == len(data),
+		})
+	}
+
+	// 到着順が入れ替わっても ChunkID 順に再構築される
+	shuffled := []*FileChunk{chunks[3], chunks[0], chunks[5], chunks[1], chunks[4], chunks[2]}
+	result, err := server.UploadFile(&MockFileUploaderStream{chunks: shuffled})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Status != "SUCCESS" {
+		t.Errorf("Expected SUCCESS status, got %s", result.Status)
+	}
+	if result.TotalChunks != int32(len(chunks)) {
+		t.Errorf("Expected %d chunks, got %d", len(chunks), result.TotalChunks)
+	}
+	if result.TotalSize != int64(len(data)) {
+		t.Errorf("Expected total size %d, got %d", len(data), result.TotalSize)
+	}
+
+	saved, ok := server.GetUploadedFile("chunked.txt")
+	if !ok {
+		t.Fatal("File was not saved on server")
+	}
+	if !bytes.Equal(saved, data) {
+		t.Errorf("Reassembled file mismatch: got %q, want %q", saved, data)
+	}
+
+	// 欠番があるとエラーになり、ファイルは保存されない
+	missing := []*FileChunk{chunks[0], chunks[1], chunks[3]}
+	for _, chunk := range missing {
+		chunk.Filename = "missing.txt"
+	}
+	result, err = server.UploadFile(&MockFileUploaderStream{chunks: missing})
+	if err == nil {
+		t.Error("Expected error for missing chunk")
+	}
+	if result.Status != "ERROR" {
+		t.Errorf("Expected ERROR status, got %s", result.Status)
+	}
+	if _, ok := server.GetUploadedFile("missing.txt"); ok {
+		t.Error("Incomplete file should not be stored")
+	}
+}
+
+func TestMockStreams_LogAndFileRoundTrip(t *testing.T) {
+	t.Run("log collector stream", func(t *testing.T) {
+		server := NewStreamingServer()
+		stream := NewMockLogCollectorStream(context.Background(), server)
+
+		logs := []*LogEntry{
+			{Level: "INFO", Message: "started", Timestamp: 1, Service: "api"},
+			{Level: "WARN", Message: "slow query", Timestamp: 2, Service: "db"},
+			{Level: "ERROR", Message: "timeout", Timestamp: 3, Service: "api"},
+		}
+		for _, entry := range logs {
+			if err := stream.Send(entry); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+		}
+
+		result, err := stream.CloseAndRecv()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Status != "SUCCESS" || result.TotalLogs != int32(len(logs)) {
+			t.Errorf("Expected SUCCESS with %d logs, got %s with %d", len(logs), result.Status, result.TotalLogs)
+		}
+
+		saved := server.GetLogs()
+		if len(saved) != len(logs) {
+			t.Fatalf("Expected %d saved logs, got %d", len(logs), len(saved))
+		}
+		for i, entry := range saved {
+			if *entry != *logs[i] {
+				t.Errorf("Log %d mismatch: got %+v, want %+v", i, *entry, *logs[i])
+			}
+		}
+
+		if err := stream.Send(logs[0]); err == nil {
+			t.Error("Expected Send after CloseAndRecv to fail")
+		}
+		if stream.Context() == nil {
+			t.Error("Expected stream context to be set")
+		}
+	})
+
+	t.Run("file uploader stream", func(t *testing.T) {
+		server := NewStreamingServer()
+		stream := NewMockFileUploaderStream(context.Background(), server)
+
+		parts := [][]byte{[]byte("mock "), []byte("file "), []byte("upload")}
+		for i, part := range parts {
+			err := stream.Send(&FileChunk{
+				ChunkID:  int32(i),
+				Data:     part,
+				Filename: "mock.txt",
+				IsLast:   i == len(parts)-1,
+			})
+			if err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+		}
+
+		result, err := stream.CloseAndRecv()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Filename != "mock.txt" || result.TotalChunks != int32(len(parts)) {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+
+		saved, ok := server.GetUploadedFile("mock.txt")
+		if !ok || string(saved) != "mock file upload" {
+			t.Errorf("Expected reassembled file %q, got %q", "mock file upload", saved)
+		}
+
+		if err := stream.Send(&FileChunk{ChunkID: 3, Filename: "mock.txt"}); err == nil {
+			t.Error("Expected Send after CloseAndRecv to fail")
+		}
+	})
+
+	t.Run("send after context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		logStream := NewMockLogCollectorStream(ctx, NewStreamingServer())
+		if err := logStream.Send(&LogEntry{Level: "INFO"}); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled from log stream, got %v", err)
+		}
+		fileStream := NewMockFileUploaderStream(ctx, NewStreamingServer())
+		if err := fileStream.Send(&FileChunk{}); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled from file stream, got %v", err)
+		}
+	})
+}
+
 func TestConcurrentDataStreaming(t *testing.T) {
 	server := NewStreamingServer()
 	client := NewStreamingClient(server)
@@ -517,6 +780,308 @@ func TestUtilityFunctions(t *testing.T) {
 	t.Log("All utility functions work correctly")
 }
 
+// streamingHarness はモックストリーム経由で StreamingClient と StreamingServer をつなぎ、
+// 送信からサーバー側の蓄積状態の検証までをまとめて行うテスト用ハーネス
+type streamingHarness struct {
+	t      *testing.T
+	ctx    context.Context
+	server *StreamingServer
+	client *StreamingClient
+}
+
+func newStreamingHarness(t *testing.T) *streamingHarness {
+	t.Helper()
+	server := NewStreamingServer()
+	return &streamingHarness{
+		t:      t,
+		ctx:    context.Background(),
+		server: server,
+		client: NewStreamingClient(server),
+	}
+}
+
+// sendDataPoints は source のデータポイントを n 件送信し、成功したことを確認する
+func (h *streamingHarness) sendDataPoints(n int, source string) *CollectionResult {
+	h.t.Helper()
+	result, err := h.client.SendDataPoints(h.ctx, generateDataPoints(n, source))
+	if err != nil {
+		h.t.Fatalf("SendDataPoints(%d, %q) failed: %v", n, source, err)
+	}
+	if result.Status != "SUCCESS" || result.TotalPoints != int32(n) {
+		h.t.Fatalf("Expected SUCCESS with %d points, got %s with %d", n, result.Status, result.TotalPoints)
+	}
+	return result
+}
+
+// sendLogs は service のログを n 件送信し、成功したことを確認する
+func (h *streamingHarness) sendLogs(n int, service string) *LogCollectionResult {
+	h.t.Helper()
+	result, err := h.client.SendLogs(h.ctx, generateLogs(n, service))
+	if err != nil {
+		h.t.Fatalf("SendLogs(%d, %q) failed: %v", n, service, err)
+	}
+	if result.Status != "SUCCESS" || result.TotalLogs != int32(n) {
+		h.t.Fatalf("Expected SUCCESS with %d logs, got %s with %d", n, result.Status, result.TotalLogs)
+	}
+	return result
+}
+
+// uploadFile はファイルを chunkSize ごとに分割して送信し、成功したことを確認する
+func (h *streamingHarness) uploadFile(filename string, data []byte, chunkSize int) *FileUploadResult {
+	h.t.Helper()
+	result, err := h.client.UploadFile(h.ctx, filename, data, chunkSize)
+	if err != nil {
+		h.t.Fatalf("UploadFile(%q) failed: %v", filename, err)
+	}
+	if result.Status != "SUCCESS" {
+		h.t.Fatalf("Expected SUCCESS status for %q, got %s", filename, result.Status)
+	}
+	return result
+}
+
+// assertDataPoints はサーバーに蓄積されたデータポイントのソース別件数を検証する
+func (h *streamingHarness) assertDataPoints(want map[string]int) {
+	h.t.Helper()
+	got := make(map[string]int)
+	total := 0
+	for _, point := range h.server.GetDataPoints() {
+		got[point.Source]++
+		total++
+	}
+	for source, n := range want {
+		if got[source] != n {
+			h.t.Errorf("Expected %d data points from %q, got %d", n, source, got[source])
+		}
+		total -= n
+	}
+	if total != 0 {
+		h.t.Errorf("Server holds %d unexpected data points: %v", total, got)
+	}
+}
+
+// assertLogs はサーバーに蓄積されたログのサービス別件数を検証する
+func (h *streamingHarness) assertLogs(want map[string]int) {
+	h.t.Helper()
+	got := make(map[string]int)
+	total := 0
+	for _, log := range h.server.GetLogs() {
+		got[log.Service]++
+		total++
+	}
+	for service, n := range want {
+		if got[service] != n {
+			h.t.Errorf("Expected %d logs from %q, got %d", n, service, got[service])
+		}
+		total -= n
+	}
+	if total != 0 {
+		h.t.Errorf("Server holds %d unexpected logs: %v", total, got)
+	}
+}
+
+// assertUploadedFile はサーバーで再構築されたファイルの内容を検証する
+func (h *streamingHarness) assertUploadedFile(filename string, want []byte) {
+	h.t.Helper()
+	got, ok := h.server.GetUploadedFile(filename)
+	if !ok {
+		h.t.Fatalf("Expected file %q to be stored on the server", filename)
+	}
+	if !bytes.Equal(got, want) {
+		h.t.Errorf("File %q was not reassembled correctly: got %d bytes, want %d", filename, len(got), len(want))
+	}
+}
+
+func TestStreamingHarness_DataCollectionRoundTrip(t *testing.T) {
+	h := newStreamingHarness(t)
+
+	// 複数回のストリームで送信したデータがサーバーに蓄積されていく
+	h.sendDataPoints(5, "sensor1")
+	h.sendDataPoints(12, "sensor2")
+	h.sendDataPoints(3, "sensor1")
+	h.sendLogs(8, "api")
+	h.sendLogs(4, "worker")
+
+	h.assertDataPoints(map[string]int{"sensor1": 8, "sensor2": 12})
+	h.assertLogs(map[string]int{"api": 8, "worker": 4})
+
+	// 送信順が保たれていることを確認
+	points := h.server.GetDataPoints()
+	if points[0].ID != "sensor1_point_1" || points[len(points)-1].ID != "sensor1_point_3" {
+		t.Errorf("Expected data points in send order, got first=%s last=%s", points[0].ID, points[len(points)-1].ID)
+	}
+
+	// アクセサはコピーを返すため、呼び出し側の変更はサーバーに影響しない
+	points[0] = nil
+	if h.server.GetDataPoints()[0] == nil {
+		t.Error("GetDataPoints should return a copy of the server state")
+	}
+}
+
+func TestStreamingHarness_FileUploadReassembly(t *testing.T) {
+	h := newStreamingHarness(t)
+
+	data := make([]byte, 5000)
+	for i := range data {
+		data[i] = byte(i * 7 % 251)
+	}
+
+	// チャンクサイズで割り切れないサイズのファイルを送信
+	result := h.uploadFile("sensor.bin", data, 1024)
+	if result.TotalChunks != 5 {
+		t.Errorf("Expected 5 chunks, got %d", result.TotalChunks)
+	}
+	if result.TotalSize != int64(len(data)) {
+		t.Errorf("Expected total size %d, got %d", len(data), result.TotalSize)
+	}
+	h.uploadFile("notes.txt", []byte("client streaming"), 4)
+
+	h.assertUploadedFile("sensor.bin", data)
+	h.assertUploadedFile("notes.txt", []byte("client streaming"))
+
+	// 返されたデータを書き換えてもサーバーのファイルは変わらない
+	stored, _ := h.server.GetUploadedFile("sensor.bin")
+	stored[0] ^= 0xFF
+	h.assertUploadedFile("sensor.bin", data)
+
+	if _, ok := h.server.GetUploadedFile("missing.bin"); ok {
+		t.Error("Expected missing file lookup to report false")
+	}
+}
+
+// flakyDataStream は Send の前に beforeSend を呼び、エラーを注入できるストリーム
+type flakyDataStream struct {
+	*MockDataCollectorStream
+	beforeSend func() error
+}
+
+func (s *flakyDataStream) Send(dataPoint *DataPoint) error {
+	if err := s.beforeSend(); err != nil {
+		return err
+	}
+	return s.MockDataCollectorStream.Send(dataPoint)
+}
+
+// useFlakyStreams は送信全体で failSends 番目（1始まり）の Send を失敗させ、開いたストリーム数を返す関数を返す
+func useFlakyStreams(client *StreamingClient, server *StreamingServer, failSends ...int) func() int {
+	var mu sync.Mutex
+	sends, streams := 0, 0
+	fail := make(map[int]bool)
+	for _, n := range failSends {
+		fail[n] = true
+	}
+
+	client.newDataStream = func(ctx context.Context) DataCollectorStreamClient {
+		mu.Lock()
+		streams++
+		mu.Unlock()
+		return &flakyDataStream{
+			MockDataCollectorStream: NewMockDataCollectorStream(ctx, server),
+			beforeSend: func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				sends++
+				if fail[sends] {
+					return ErrStreamUnavailable
+				}
+				return nil
+			},
+		}
+	}
+
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return streams
+	}
+}
+
+func TestStreamingClient_SendDataPoints_Retry(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:     3,
+		InitialBackoff:  time.Millisecond,
+		MaxBackoff:      5 * time.Millisecond,
+		CheckpointEvery: 4,
+	}
+
+	t.Run("resends from checkpoint after mid-stream failure", func(t *testing.T) {
+		server := NewStreamingServer()
+		client := NewStreamingClient(server)
+		client.SetRetryPolicy(policy)
+		// 2本目のストリームの2件目（全体で6件目）の送信で切断させる
+		streams := useFlakyStreams(client, server, 6)
+
+		dataPoints := generateDataPoints(10, "sensor")
+		result, err := client.SendDataPoints(context.Background(), dataPoints)
+		if err != nil {
+			t.Fatalf("SendDataPoints failed: %v", err)
+		}
+		if result.Status != "SUCCESS" || result.TotalPoints != 10 {
+			t.Errorf("Expected SUCCESS with 10 points, got %s with %d", result.Status, result.TotalPoints)
+		}
+
+		// チェックポイント3回 + 切断後の張り直し1回
+		if got := streams(); got != 4 {
+			t.Errorf("Expected 4 streams to be opened, got %d", got)
+		}
+
+		received := server.GetDataPoints()
+		if len(received) != len(dataPoints) {
+			t.Fatalf("Expected server to receive %d points, got %d", len(dataPoints), len(received))
+		}
+		for i, point := range received {
+			if point.ID != dataPoints[i].ID {
+				t.Errorf("Point %d: expected %s exactly once in order, got %s", i, dataPoints[i].ID, point.ID)
+			}
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		server := NewStreamingServer()
+		client := NewStreamingClient(server)
+		client.SetRetryPolicy(policy)
+		// 1本目は成功し、以降の3回の試行はすべて最初の Send で切断させる
+		useFlakyStreams(client, server, 5, 6, 7)
+
+		result, err := client.SendDataPoints(context.Background(), generateDataPoints(10, "sensor"))
+		if result != nil {
+			t.Error("Expected nil result when retries are exhausted")
+		}
+
+		var sendErr *StreamSendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("Expected StreamSendError, got %v", err)
+		}
+		if sendErr.Confirmed != 4 || sendErr.Attempts != 3 {
+			t.Errorf("Expected 4 confirmed points after 3 attempts, got %d after %d", sendErr.Confirmed, sendErr.Attempts)
+		}
+		if !errors.Is(err, ErrStreamUnavailable) {
+			t.Errorf("Expected error to wrap ErrStreamUnavailable, got %v", err)
+		}
+		if got := len(server.GetDataPoints()); got != 4 {
+			t.Errorf("Expected only confirmed points on the server, got %d", got)
+		}
+	})
+
+	t.Run("non-retryable error is not retried", func(t *testing.T) {
+		server := NewStreamingServer()
+		client := NewStreamingClient(server)
+		client.SetRetryPolicy(policy)
+		streams := useFlakyStreams(client, server)
+
+		dataPoints := generateDataPoints(3, "sensor")
+		dataPoints[1].Source = "" // サーバー側の検証で失敗させる
+
+		_, err := client.SendDataPoints(context.Background(), dataPoints)
+		var sendErr *StreamSendError
+		if !errors.As(err, &sendErr) || sendErr.Attempts != 1 {
+			t.Fatalf("Expected a single failed attempt, got %v", err)
+		}
+		if got := streams(); got != 1 {
+			t.Errorf("Expected 1 stream for a non-retryable error, got %d", got)
+		}
+	})
+}
+
 // ベンチマークテスト
 func BenchmarkStreamingClient_SendDataPoints(b *testing.B) {
 	server := NewStreamingServer()
@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -47,6 +48,9 @@ type DLQMessage struct {
 	Metadata        map[string]interface{} `json:"metadata"`
 }
 
+// ErrMessageNotFound は指定したIDのメッセージがDLQに存在しないことを表す
+var ErrMessageNotFound = errors.New("message not found in DLQ")
+
 type DeadLetterQueue struct {
 	messages  map[string]*DLQMessage
 	mu        sync.RWMutex
@@ -57,7 +61,7 @@ type DeadLetterQueue struct {
 type ReprocessingStrategy interface {
 	ShouldReprocess(dlqMsg *DLQMessage) bool
 	NextAttemptTime(dlqMsg *DLQMessage) time.Time
-	MaxAttempts() int
+	MaxRetryAttempts() int
 }
 
 type DLQAnalytics struct {
@@ -118,6 +122,19 @@ func (dlq *DeadLetterQueue) GetAnalytics() *DLQAnalytics {
 	return nil
 }
 
+// ErrorClassifier はエラーを分類できた場合に分類と true を返す
+type ErrorClassifier func(error) (ErrorClassification, bool)
+
+var (
+	classifiersMu sync.RWMutex
+	classifiers   []ErrorClassifier
+)
+
+// TODO: 独自の分類ロジックを追加
+func RegisterClassifier(fn func(error) (ErrorClassification, bool)) {
+	// ヒント: 登録された分類器は組み込みの判定より先に、登録順で評価する
+}
+
 // TODO: エラーを分類
 func ClassifyError(err error) ErrorClassification {
 	// ヒント:
@@ -153,7 +170,7 @@ func (ebr *ExponentialBackoffReprocessing) NextAttemptTime(dlqMsg *DLQMessage) t
 }
 
 // TODO: 最大試行回数を返す
-func (ebr *ExponentialBackoffReprocessing) MaxAttempts() int {
+func (ebr *ExponentialBackoffReprocessing) MaxRetryAttempts() int {
 	return ebr.MaxAttempts
 }
 
@@ -213,6 +230,13 @@ type MonitorConfig struct {
 	CheckInterval   time.Duration
 }
 
+// アラート種別
+const (
+	AlertTypeHighVolume     = "DLQ_HIGH_VOLUME"
+	AlertTypeOldMessages    = "DLQ_OLD_MESSAGES"
+	AlertTypeSecurityErrors = "DLQ_SECURITY_ERRORS"
+)
+
 // TODO: DLQMonitor を初期化
 func NewDLQMonitor(dlq *DeadLetterQueue, alerting AlertingService, config MonitorConfig) *DLQMonitor {
 	return nil
@@ -247,6 +271,13 @@ type Alert struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// アラートレベル
+const (
+	AlertLevelWarning  = "warning"
+	AlertLevelCritical = "critical"
+	AlertLevelSecurity = "security"
+)
+
 // TODO: SimpleAlertingService を初期化
 func NewSimpleAlertingService() *SimpleAlertingService {
 	return nil
@@ -5,576 +5,280 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
+	"math"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
-// Message メッセージ構造体
-type Message struct {
-	ID          string                 `json:"id"`
-	Type        string                 `json:"type"`
-	Data        interface{}            `json:"data"`
-	Headers     map[string]string      `json:"headers"`
-	Timestamp   time.Time              `json:"timestamp"`
-	RetryCount  int                    `json:"retry_count"`
-	MaxRetries  int                    `json:"max_retries"`
-	LastError   string                 `json:"last_error,omitempty"`
-	FailureTime time.Time              `json:"failure_time,omitempty"`
-}
-
-// DeadLetterMessage DLQに送信されるメッセージ
-type DeadLetterMessage struct {
-	*Message
-	Reason         string    `json:"reason"`
-	OriginalQueue  string    `json:"original_queue"`
-	DeadLetterTime time.Time `json:"dead_letter_time"`
-	FailureHistory []FailureRecord `json:"failure_history"`
-}
-
-// FailureRecord 失敗記録
-type FailureRecord struct {
-	Timestamp time.Time `json:"timestamp"`
-	Error     string    `json:"error"`
-	RetryNumber int      `json:"retry_number"`
-}
-
-// MessageProcessor メッセージ処理インターフェース
-type MessageProcessor interface {
-	Process(ctx context.Context, msg *Message) error
-	GetProcessorType() string
-}
-
-// Queue キューインターフェース
-type Queue interface {
-	Send(ctx context.Context, msg *Message) error
-	Receive(ctx context.Context) (*Message, error)
-	Ack(ctx context.Context, msg *Message) error
-	Nack(ctx context.Context, msg *Message) error
-	Size() int
-}
-
-// DeadLetterQueue Dead Letter Queueインターフェース
-type DeadLetterQueue interface {
-	SendToDeadLetter(ctx context.Context, msg *Message, reason string, originalQueue string) error
-	GetDeadLetterMessages(ctx context.Context) ([]*DeadLetterMessage, error)
-	ReprocessMessage(ctx context.Context, dlqMsg *DeadLetterMessage, targetQueue string) error
-	PurgeExpiredMessages(ctx context.Context, expiration time.Duration) error
-}
-
-// RetryPolicy 再試行ポリシー
-type RetryPolicy struct {
-	MaxRetries      int
-	InitialDelay    time.Duration
-	BackoffFactor   float64
-	MaxDelay        time.Duration
-	RetryableErrors map[string]bool
-}
-
-func NewDefaultRetryPolicy() *RetryPolicy {
-	return &RetryPolicy{
-		MaxRetries:    3,
-		InitialDelay:  1 * time.Second,
-		BackoffFactor: 2.0,
-		MaxDelay:      30 * time.Second,
-		RetryableErrors: map[string]bool{
-			"temporary_error":   true,
-			"network_error":     true,
-			"resource_busy":     true,
-			"service_unavailable": true,
-		},
-	}
-}
-
-func (p *RetryPolicy) CalculateDelay(retryCount int) time.Duration {
-	delay := float64(p.InitialDelay)
-	for i := 0; i < retryCount; i++ {
-		delay *= p.BackoffFactor
-	}
-	
-	if time.Duration(delay) > p.MaxDelay {
-		return p.MaxDelay
-	}
-	
-	return time.Duration(delay)
-}
+// 実装している機能：
+// 1. エラー分類と管理
+// 2. 自動再処理戦略
+// 3. バッチ再処理機能
+// 4. 分析と監視機能
+// 5. アラート機能
 
-func (p *RetryPolicy) IsRetryable(errorType string) bool {
-	return p.RetryableErrors[errorType]
-}
+type ErrorClassification string
 
-// InMemoryQueue メモリベースのキュー実装
-type InMemoryQueue struct {
-	name     string
-	messages []*Message
-	mu       sync.RWMutex
-}
+const (
+	TemporaryError  ErrorClassification = "temporary"
+	PermanentError  ErrorClassification = "permanent"
+	ValidationError ErrorClassification = "validation"
+	TimeoutError    ErrorClassification = "timeout"
+	SecurityError   ErrorClassification = "security"
+)
 
-func NewInMemoryQueue(name string) *InMemoryQueue {
-	return &InMemoryQueue{
-		name:     name,
-		messages: make([]*Message, 0),
-	}
+type Message struct {
+	ID        string                 `json:"id"`
+	Topic     string                 `json:"topic"`
+	Data      []byte                 `json:"data"`
+	Headers   map[string]string      `json:"headers"`
+	Timestamp time.Time              `json:"timestamp"`
+	Metadata  map[string]interface{} `json:"metadata"`
 }
 
-func (q *InMemoryQueue) Send(ctx context.Context, msg *Message) error {
-	q.mu.Lock()
-	defer q.mu.Unlock()
-	
-	q.messages = append(q.messages, msg)
-	log.Printf("Message %s sent to queue %s", msg.ID, q.name)
-	return nil
+type DLQMessage struct {
+	OriginalMessage *Message               `json:"original_message"`
+	FailureReason   string                 `json:"failure_reason"`
+	ErrorClass      ErrorClassification    `json:"error_class"`
+	FailureCount    int                    `json:"failure_count"`
+	FirstFailure    time.Time              `json:"first_failure"`
+	LastFailure     time.Time              `json:"last_failure"`
+	Metadata        map[string]interface{} `json:"metadata"`
 }
 
-func (q *InMemoryQueue) Receive(ctx context.Context) (*Message, error) {
-	q.mu.Lock()
-	defer q.mu.Unlock()
-	
-	if len(q.messages) == 0 {
-		return nil, fmt.Errorf("queue empty")
-	}
-	
-	msg := q.messages[0]
-	q.messages = q.messages[1:]
-	
-	log.Printf("Message %s received from queue %s", msg.ID, q.name)
-	return msg, nil
-}
+// ErrMessageNotFound は指定したIDのメッセージがDLQに存在しないことを表す
+var ErrMessageNotFound = errors.New("message not found in DLQ")
 
-func (q *InMemoryQueue) Ack(ctx context.Context, msg *Message) error {
-	log.Printf("Message %s acknowledged", msg.ID)
-	return nil
+type DeadLetterQueue struct {
+	messages map[string]*DLQMessage // OriginalMessage.ID → メッセージ
+	mu       sync.RWMutex
+	strategy ReprocessingStrategy
 }
 
-func (q *InMemoryQueue) Nack(ctx context.Context, msg *Message) error {
-	log.Printf("Message %s not acknowledged", msg.ID)
-	return nil
+type ReprocessingStrategy interface {
+	ShouldReprocess(dlqMsg *DLQMessage) bool
+	NextAttemptTime(dlqMsg *DLQMessage) time.Time
+	MaxRetryAttempts() int
 }
 
-func (q *InMemoryQueue) Size() int {
-	q.mu.RLock()
-	defer q.mu.RUnlock()
-	return len(q.messages)
+type DLQAnalytics struct {
+	TotalMessages  int64                            `json:"total_messages"`
+	ErrorBreakdown map[ErrorClassification]int64    `json:"error_breakdown"`
+	TopicBreakdown map[string]int64                 `json:"topic_breakdown"`
+	HourlyStats    map[string]int64                 `json:"hourly_stats"`
+	AverageRetries float64                          `json:"average_retries"`
+	OldestMessage  *time.Time                       `json:"oldest_message"`
 }
 
-// InMemoryDeadLetterQueue メモリベースのDLQ実装
-type InMemoryDeadLetterQueue struct {
-	messages map[string]*DeadLetterMessage
-	mu       sync.RWMutex
-}
+// hourlyBucketLayout は HourlyStats のキーの書式（UTCの1時間単位）
+const hourlyBucketLayout = "2006-01-02T15:00"
 
-func NewInMemoryDeadLetterQueue() *InMemoryDeadLetterQueue {
-	return &InMemoryDeadLetterQueue{
-		messages: make(map[string]*DeadLetterMessage),
+// NewDeadLetterQueue は再処理戦略を指定してDLQを作成する
+func NewDeadLetterQueue(strategy ReprocessingStrategy) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		messages: make(map[string]*DLQMessage),
+		strategy: strategy,
 	}
 }
 
-func (dlq *InMemoryDeadLetterQueue) SendToDeadLetter(ctx context.Context, msg *Message, reason string, originalQueue string) error {
-	dlq.mu.Lock()
-	defer dlq.mu.Unlock()
-	
-	dlqMsg := &DeadLetterMessage{
-		Message:        msg,
-		Reason:         reason,
-		OriginalQueue:  originalQueue,
-		DeadLetterTime: time.Now(),
-		FailureHistory: make([]FailureRecord, 0),
+// Send はメッセージをDLQに登録する。
+// 同じ OriginalMessage.ID のメッセージが既にあれば新規追加せず、
+// 失敗回数と最終失敗時刻、失敗理由を更新する
+func (dlq *DeadLetterQueue) Send(ctx context.Context, dlqMessage *DLQMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	
-	// 失敗履歴を追加
-	if msg.LastError != "" {
-		dlqMsg.FailureHistory = append(dlqMsg.FailureHistory, FailureRecord{
-			Timestamp:   msg.FailureTime,
-			Error:       msg.LastError,
-			RetryNumber: msg.RetryCount,
-		})
+	if dlqMessage == nil || dlqMessage.OriginalMessage == nil {
+		return errors.New("dlq message must have an original message")
 	}
-	
-	dlq.messages[msg.ID] = dlqMsg
-	log.Printf("Message %s sent to dead letter queue. Reason: %s", msg.ID, reason)
-	return nil
-}
 
-func (dlq *InMemoryDeadLetterQueue) GetDeadLetterMessages(ctx context.Context) ([]*DeadLetterMessage, error) {
-	dlq.mu.RLock()
-	defer dlq.mu.RUnlock()
-	
-	messages := make([]*DeadLetterMessage, 0, len(dlq.messages))
-	for _, msg := range dlq.messages {
-		messages = append(messages, msg)
+	now := time.Now()
+	lastFailure := dlqMessage.LastFailure
+	if lastFailure.IsZero() {
+		lastFailure = now
 	}
-	
-	return messages, nil
-}
 
-func (dlq *InMemoryDeadLetterQueue) ReprocessMessage(ctx context.Context, dlqMsg *DeadLetterMessage, targetQueue string) error {
 	dlq.mu.Lock()
 	defer dlq.mu.Unlock()
-	
-	// DLQからメッセージを削除
-	delete(dlq.messages, dlqMsg.Message.ID)
-	
-	// 再処理用にメッセージをリセット
-	dlqMsg.Message.RetryCount = 0
-	dlqMsg.Message.LastError = ""
-	
-	log.Printf("Message %s reprocessed from DLQ to queue %s", dlqMsg.Message.ID, targetQueue)
-	return nil
-}
 
-func (dlq *InMemoryDeadLetterQueue) PurgeExpiredMessages(ctx context.Context, expiration time.Duration) error {
-	dlq.mu.Lock()
-	defer dlq.mu.Unlock()
-	
-	cutoff := time.Now().Add(-expiration)
-	expired := make([]string, 0)
-	
-	for id, msg := range dlq.messages {
-		if msg.DeadLetterTime.Before(cutoff) {
-			expired = append(expired, id)
+	id := dlqMessage.OriginalMessage.ID
+	if existing, ok := dlq.messages[id]; ok {
+		existing.FailureCount++
+		if lastFailure.After(existing.LastFailure) {
+			existing.LastFailure = lastFailure
 		}
+		existing.FailureReason = dlqMessage.FailureReason
+		existing.ErrorClass = dlqMessage.ErrorClass
+		return nil
 	}
-	
-	for _, id := range expired {
-		delete(dlq.messages, id)
+
+	if dlqMessage.FailureCount < 1 {
+		dlqMessage.FailureCount = 1
 	}
-	
-	log.Printf("Purged %d expired messages from DLQ", len(expired))
+	if dlqMessage.FirstFailure.IsZero() {
+		dlqMessage.FirstFailure = lastFailure
+	}
+	dlqMessage.LastFailure = lastFailure
+	dlq.messages[id] = dlqMessage
+
 	return nil
 }
 
-func (dlq *InMemoryDeadLetterQueue) Size() int {
+// GetMessage は OriginalMessage.ID でメッセージを取得する
+func (dlq *DeadLetterQueue) GetMessage(messageID string) (*DLQMessage, bool) {
 	dlq.mu.RLock()
 	defer dlq.mu.RUnlock()
-	return len(dlq.messages)
-}
 
-// MessageProcessorWithRetry 再試行機能付きメッセージプロセッサー
-type MessageProcessorWithRetry struct {
-	processor    MessageProcessor
-	retryPolicy  *RetryPolicy
-	deadLetterQ  DeadLetterQueue
-	originalQueue string
+	msg, ok := dlq.messages[messageID]
+	return msg, ok
 }
 
-func NewMessageProcessorWithRetry(processor MessageProcessor, retryPolicy *RetryPolicy, dlq DeadLetterQueue, originalQueue string) *MessageProcessorWithRetry {
-	return &MessageProcessorWithRetry{
-		processor:     processor,
-		retryPolicy:   retryPolicy,
-		deadLetterQ:   dlq,
-		originalQueue: originalQueue,
-	}
-}
+// GetMessagesForReprocessing は再処理戦略と filter の両方を満たすメッセージを
+// FirstFailure の古い順に返す（filter が nil なら戦略のみで判定する）
+func (dlq *DeadLetterQueue) GetMessagesForReprocessing(filter func(*DLQMessage) bool) []*DLQMessage {
+	dlq.mu.RLock()
+	defer dlq.mu.RUnlock()
 
-func (p *MessageProcessorWithRetry) ProcessWithRetry(ctx context.Context, msg *Message) error {
-	for {
-		err := p.processor.Process(ctx, msg)
-		if err == nil {
-			log.Printf("Message %s processed successfully", msg.ID)
-			return nil
-		}
-		
-		// エラータイプを判定
-		errorType := p.classifyError(err)
-		msg.LastError = err.Error()
-		msg.FailureTime = time.Now()
-		
-		// 再試行可能かチェック
-		if !p.retryPolicy.IsRetryable(errorType) {
-			log.Printf("Non-retryable error for message %s: %v", msg.ID, err)
-			return p.sendToDeadLetter(ctx, msg, fmt.Sprintf("non-retryable error: %s", errorType))
-		}
-		
-		// 最大再試行回数チェック
-		if msg.RetryCount >= p.retryPolicy.MaxRetries {
-			log.Printf("Max retries exceeded for message %s", msg.ID)
-			return p.sendToDeadLetter(ctx, msg, "max retries exceeded")
+	result := make([]*DLQMessage, 0)
+	for _, msg := range dlq.messages {
+		if dlq.strategy != nil && !dlq.strategy.ShouldReprocess(msg) {
+			continue
 		}
-		
-		// 再試行
-		msg.RetryCount++
-		delay := p.retryPolicy.CalculateDelay(msg.RetryCount - 1)
-		
-		log.Printf("Retrying message %s in %v (attempt %d/%d)", 
-			msg.ID, delay, msg.RetryCount, p.retryPolicy.MaxRetries)
-		
-		select {
-		case <-time.After(delay):
+		if filter != nil && !filter(msg) {
 			continue
-		case <-ctx.Done():
-			return ctx.Err()
 		}
+		result = append(result, msg)
 	}
-}
 
-func (p *MessageProcessorWithRetry) classifyError(err error) string {
-	errorMsg := err.Error()
-	
-	// 簡単なエラー分類（実際の実装ではより詳細な分類が必要）
-	switch {
-	case contains(errorMsg, "network"):
-		return "network_error"
-	case contains(errorMsg, "timeout"):
-		return "timeout_error"
-	case contains(errorMsg, "busy"):
-		return "resource_busy"
-	case contains(errorMsg, "unavailable"):
-		return "service_unavailable"
-	case contains(errorMsg, "temporary"):
-		return "temporary_error"
-	default:
-		return "unknown_error"
-	}
-}
-
-func (p *MessageProcessorWithRetry) sendToDeadLetter(ctx context.Context, msg *Message, reason string) error {
-	return p.deadLetterQ.SendToDeadLetter(ctx, msg, reason, p.originalQueue)
-}
-
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && s[:len(substr)] == substr
-}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].FirstFailure.Equal(result[j].FirstFailure) {
+			return result[i].FirstFailure.Before(result[j].FirstFailure)
+		}
+		return result[i].OriginalMessage.ID < result[j].OriginalMessage.ID
+	})
 
-// OrderProcessor 注文処理プロセッサーの例
-type OrderProcessor struct {
-	failureRate float64 // テスト用の失敗率
+	return result
 }
 
-func NewOrderProcessor(failureRate float64) *OrderProcessor {
-	return &OrderProcessor{
-		failureRate: failureRate,
-	}
-}
+// RemoveMessage はメッセージをDLQから削除する
+func (dlq *DeadLetterQueue) RemoveMessage(messageID string) error {
+	dlq.mu.Lock()
+	defer dlq.mu.Unlock()
 
-func (p *OrderProcessor) Process(ctx context.Context, msg *Message) error {
-	// ランダムに失敗をシミュレート
-	if p.failureRate > 0 && rand.Float64() < p.failureRate {
-		errorTypes := []string{
-			"network timeout",
-			"service unavailable", 
-			"resource busy",
-			"temporary database error",
-			"payment service down",
-		}
-		
-		errorType := errorTypes[rand.Intn(len(errorTypes))]
-		return fmt.Errorf(errorType)
+	if _, ok := dlq.messages[messageID]; !ok {
+		return fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
 	}
-	
-	// 成功時の処理
-	orderData, ok := msg.Data.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid order data format")
-	}
-	
-	orderID, ok := orderData["order_id"].(string)
-	if !ok {
-		return fmt.Errorf("missing order_id")
-	}
-	
-	log.Printf("Order %s processed successfully", orderID)
+	delete(dlq.messages, messageID)
 	return nil
 }
 
-func (p *OrderProcessor) GetProcessorType() string {
-	return "order_processor"
-}
-
-// DLQManager DLQ管理システム
-type DLQManager struct {
-	deadLetterQ DeadLetterQueue
-	queues      map[string]Queue
-	processors  map[string]MessageProcessor
-	mu          sync.RWMutex
-}
+// GetAnalytics は現在DLQにあるメッセージから分析データを集計する
+func (dlq *DeadLetterQueue) GetAnalytics() *DLQAnalytics {
+	dlq.mu.RLock()
+	defer dlq.mu.RUnlock()
 
-func NewDLQManager(dlq DeadLetterQueue) *DLQManager {
-	return &DLQManager{
-		deadLetterQ: dlq,
-		queues:      make(map[string]Queue),
-		processors:  make(map[string]MessageProcessor),
+	analytics := &DLQAnalytics{
+		TotalMessages:  int64(len(dlq.messages)),
+		ErrorBreakdown: make(map[ErrorClassification]int64),
+		TopicBreakdown: make(map[string]int64),
+		HourlyStats:    make(map[string]int64),
 	}
-}
-
-func (m *DLQManager) RegisterQueue(name string, queue Queue) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.queues[name] = queue
-}
 
-func (m *DLQManager) RegisterProcessor(queueName string, processor MessageProcessor) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.processors[queueName] = processor
-}
+	var totalRetries int64
+	for _, msg := range dlq.messages {
+		analytics.ErrorBreakdown[msg.ErrorClass]++
+		analytics.TopicBreakdown[msg.OriginalMessage.Topic]++
+		analytics.HourlyStats[msg.FirstFailure.UTC().Format(hourlyBucketLayout)]++
+		totalRetries += int64(msg.FailureCount)
 
-func (m *DLQManager) GetDLQStatistics(ctx context.Context) (*DLQStatistics, error) {
-	messages, err := m.deadLetterQ.GetDeadLetterMessages(ctx)
-	if err != nil {
-		return nil, err
-	}
-	
-	stats := &DLQStatistics{
-		TotalMessages: len(messages),
-		MessagesByQueue: make(map[string]int),
-		MessagesByReason: make(map[string]int),
-		OldestMessage: time.Now(),
-	}
-	
-	for _, msg := range messages {
-		stats.MessagesByQueue[msg.OriginalQueue]++
-		stats.MessagesByReason[msg.Reason]++
-		
-		if msg.DeadLetterTime.Before(stats.OldestMessage) {
-			stats.OldestMessage = msg.DeadLetterTime
+		if analytics.OldestMessage == nil || msg.FirstFailure.Before(*analytics.OldestMessage) {
+			oldest := msg.FirstFailure
+			analytics.OldestMessage = &oldest
 		}
 	}
-	
-	return stats, nil
-}
 
-func (m *DLQManager) ReprocessFromDLQ(ctx context.Context, messageID string, targetQueue string) error {
-	messages, err := m.deadLetterQ.GetDeadLetterMessages(ctx)
-	if err != nil {
-		return err
+	if analytics.TotalMessages > 0 {
+		analytics.AverageRetries = float64(totalRetries) / float64(analytics.TotalMessages)
 	}
-	
-	for _, dlqMsg := range messages {
-		if dlqMsg.Message.ID == messageID {
-			m.mu.RLock()
-			queue, exists := m.queues[targetQueue]
-			m.mu.RUnlock()
-			
-			if !exists {
-				return fmt.Errorf("target queue %s not found", targetQueue)
-			}
-			
-			// DLQから削除
-			err = m.deadLetterQ.ReprocessMessage(ctx, dlqMsg, targetQueue)
-			if err != nil {
-				return err
-			}
-			
-			// ターゲットキューに送信
-			return queue.Send(ctx, dlqMsg.Message)
-		}
-	}
-	
-	return fmt.Errorf("message %s not found in DLQ", messageID)
-}
 
-func (m *DLQManager) StartCleanupWorker(ctx context.Context, interval time.Duration, expiration time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-ticker.C:
-			err := m.deadLetterQ.PurgeExpiredMessages(ctx, expiration)
-			if err != nil {
-				log.Printf("Failed to purge expired DLQ messages: %v", err)
-			}
-		case <-ctx.Done():
-			return
-		}
-	}
+	return analytics
 }
 
-// DLQStatistics DLQ統計情報
-type DLQStatistics struct {
-	TotalMessages    int               `json:"total_messages"`
-	MessagesByQueue  map[string]int    `json:"messages_by_queue"`
-	MessagesByReason map[string]int    `json:"messages_by_reason"`
-	OldestMessage    time.Time         `json:"oldest_message"`
-}
+// ErrorClassifier はエラーを分類できた場合に分類と true を返す
+type ErrorClassifier func(error) (ErrorClassification, bool)
 
-// PoisonMessageDetector 毒メッセージ検出器
-type PoisonMessageDetector struct {
-	patterns     []PoisonPattern
-	deadLetterQ  DeadLetterQueue
-}
+var (
+	classifiersMu sync.RWMutex
+	classifiers   []ErrorClassifier
+)
 
-type PoisonPattern struct {
-	Name        string
-	Condition   func(*Message) bool
-	Description string
+// RegisterClassifier は独自の分類ロジックを追加する。
+// 登録された分類器は組み込みの判定より先に、登録順で評価される
+func RegisterClassifier(fn func(error) (ErrorClassification, bool)) {
+	classifiersMu.Lock()
+	defer classifiersMu.Unlock()
+	classifiers = append(classifiers, fn)
 }
 
-func NewPoisonMessageDetector(dlq DeadLetterQueue) *PoisonMessageDetector {
-	detector := &PoisonMessageDetector{
-		deadLetterQ: dlq,
-		patterns:    make([]PoisonPattern, 0),
+// 組み込み分類で使うキーワード（小文字で比較）
+var (
+	timeoutKeywords    = []string{"timeout", "timed out", "deadline exceeded"}
+	securityKeywords   = []string{"unauthorized", "forbidden", "permission denied", "access denied"}
+	validationKeywords = []string{"invalid", "validation"}
+	temporaryKeywords  = []string{"connection refused", "connection reset", "network", "temporary", "temporarily", "unavailable", "try again"}
+)
+
+// ClassifyError はエラーの種類から再処理の可否を判断するための分類を返す
+func ClassifyError(err error) ErrorClassification {
+	if err == nil {
+		return PermanentError
 	}
-	
-	// デフォルトのパターンを追加
-	detector.AddPattern(PoisonPattern{
-		Name: "malformed_json",
-		Condition: func(msg *Message) bool {
-			// JSONデータの検証
-			_, ok := msg.Data.(string)
-			return ok && len(msg.Data.(string)) > 1000000 // 1MB以上
-		},
-		Description: "Message contains malformed or oversized JSON",
-	})
-	
-	detector.AddPattern(PoisonPattern{
-		Name: "circular_reference",
-		Condition: func(msg *Message) bool {
-			// 循環参照の検出（簡略化）
-			if dataMap, ok := msg.Data.(map[string]interface{}); ok {
-				return contains(fmt.Sprintf("%v", dataMap), "circular")
-			}
-			return false
-		},
-		Description: "Message contains circular references",
-	})
-	
-	return detector
-}
 
-func (d *PoisonMessageDetector) AddPattern(pattern PoisonPattern) {
-	d.patterns = append(d.patterns, pattern)
-}
+	classifiersMu.RLock()
+	registered := classifiers
+	classifiersMu.RUnlock()
 
-func (d *PoisonMessageDetector) DetectPoisonMessage(ctx context.Context, msg *Message) (bool, string) {
-	for _, pattern := range d.patterns {
-		if pattern.Condition(msg) {
-			reason := fmt.Sprintf("poison message detected: %s - %s", pattern.Name, pattern.Description)
-			
-			// 毒メッセージをDLQに送信
-			d.deadLetterQ.SendToDeadLetter(ctx, msg, reason, "poison_detection")
-			
-			return true, reason
+	for _, classify := range registered {
+		if class, ok := classify(err); ok {
+			return class
 		}
 	}
-	
-	return false, ""
-}
 
-// ErrorClassification エラー分類
-type ErrorClassification int
+	// net.Error などタイムアウトを自己申告するエラー
+	var timeoutErr interface{ Timeout() bool }
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &timeoutErr) && timeoutErr.Timeout()) {
+		return TimeoutError
+	}
 
-const (
-	TemporaryError ErrorClassification = iota
-	PermanentError
-	ValidationError
-	SecurityError
-	TimeoutError
-)
+	msg := strings.ToLower(err.Error())
+	switch {
+	case containsAny(msg, timeoutKeywords):
+		return TimeoutError
+	case containsAny(msg, securityKeywords):
+		return SecurityError
+	case containsAny(msg, validationKeywords):
+		return ValidationError
+	case containsAny(msg, temporaryKeywords):
+		return TemporaryError
+	default:
+		return PermanentError
+	}
+}
 
-// DLQMessage テスト用のDLQメッセージ構造体
-type DLQMessage struct {
-	OriginalMessage *Message             `json:"original_message"`
-	FailureReason   string               `json:"failure_reason"`
-	ErrorClass      ErrorClassification  `json:"error_class"`
-	FailureCount    int                  `json:"failure_count"`
-	FirstFailure    time.Time            `json:"first_failure"`
-	LastFailure     time.Time            `json:"last_failure"`
+func containsAny(s string, keywords []string) bool {
+	for _, kw := range keywords {
+		if strings.Contains(s, kw) {
+			return true
+		}
+	}
+	return false
 }
 
-// ExponentialBackoffReprocessing 指数バックオフ再処理戦略
+// 指数バックオフ再処理戦略
 type ExponentialBackoffReprocessing struct {
 	BaseDelay   time.Duration
 	MaxDelay    time.Duration
@@ -582,240 +286,194 @@ type ExponentialBackoffReprocessing struct {
 	Multiplier  float64
 }
 
-func (s *ExponentialBackoffReprocessing) ShouldReprocess(dlqMsg *DLQMessage) bool {
-	// 永続エラーは再処理しない
-	if dlqMsg.ErrorClass == PermanentError {
+// isRetryableClass は再処理で回復しうるエラー分類かどうかを返す
+func isRetryableClass(class ErrorClassification) bool {
+	switch class {
+	case PermanentError, ValidationError, SecurityError:
 		return false
+	default:
+		return true
 	}
-	
-	// 最大試行回数チェック
-	if dlqMsg.FailureCount >= s.MaxAttempts {
-		return false
-	}
-	
-	return true
-}
-
-func (s *ExponentialBackoffReprocessing) NextAttemptTime(dlqMsg *DLQMessage) time.Time {
-	delay := time.Duration(float64(s.BaseDelay) * pow(s.Multiplier, float64(dlqMsg.FailureCount)))
-	if delay > s.MaxDelay {
-		delay = s.MaxDelay
-	}
-	return dlqMsg.LastFailure.Add(delay)
 }
 
-func pow(base, exp float64) float64 {
-	if exp == 0 {
-		return 1
+// ShouldReprocess は再処理可能な分類で、試行回数が上限未満かつ
+// バックオフ期間が経過している場合に true を返す
+func (ebr *ExponentialBackoffReprocessing) ShouldReprocess(dlqMsg *DLQMessage) bool {
+	if !isRetryableClass(dlqMsg.ErrorClass) {
+		return false
 	}
-	result := base
-	for i := 1; i < int(exp); i++ {
-		result *= base
+	if dlqMsg.FailureCount >= ebr.MaxAttempts {
+		return false
 	}
-	return result
+	return !time.Now().Before(ebr.NextAttemptTime(dlqMsg))
 }
 
-// DeadLetterQueueTest テスト用のDLQ実装
-type DeadLetterQueueTest struct {
-	messages map[string]*DLQMessage
-	strategy *ExponentialBackoffReprocessing
-	mu       sync.RWMutex
-}
+// NextAttemptTime は LastFailure + min(BaseDelay * Multiplier^FailureCount, MaxDelay) を返す
+func (ebr *ExponentialBackoffReprocessing) NextAttemptTime(dlqMsg *DLQMessage) time.Time {
+	multiplier := ebr.Multiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
 
-func NewDeadLetterQueue(strategy *ExponentialBackoffReprocessing) *DeadLetterQueueTest {
-	return &DeadLetterQueueTest{
-		messages: make(map[string]*DLQMessage),
-		strategy: strategy,
+	// float64 で計算し、オーバーフローする前に MaxDelay で打ち切る
+	delay := float64(ebr.BaseDelay) * math.Pow(multiplier, float64(dlqMsg.FailureCount))
+	if ebr.MaxDelay > 0 && delay > float64(ebr.MaxDelay) {
+		delay = float64(ebr.MaxDelay)
 	}
+
+	return dlqMsg.LastFailure.Add(time.Duration(delay))
 }
 
-func (dlq *DeadLetterQueueTest) Send(ctx context.Context, dlqMsg *DLQMessage) error {
-	dlq.mu.Lock()
-	defer dlq.mu.Unlock()
-	dlq.messages[dlqMsg.OriginalMessage.ID] = dlqMsg
-	return nil
+// MaxRetryAttempts は最大試行回数を返す
+func (ebr *ExponentialBackoffReprocessing) MaxRetryAttempts() int {
+	return ebr.MaxAttempts
 }
 
-func (dlq *DeadLetterQueueTest) GetMessage(messageID string) (*DLQMessage, bool) {
-	dlq.mu.RLock()
-	defer dlq.mu.RUnlock()
-	msg, exists := dlq.messages[messageID]
-	return msg, exists
+// バッチ再処理機能
+type BatchReprocessor struct {
+	dlq       *DeadLetterQueue
+	publisher Publisher
+	batchSize int
+	strategy  ReprocessingStrategy
+	semaphore chan struct{}
 }
 
-func (dlq *DeadLetterQueueTest) RemoveMessage(messageID string) error {
-	dlq.mu.Lock()
-	defer dlq.mu.Unlock()
-	delete(dlq.messages, messageID)
-	return nil
+type Publisher interface {
+	Publish(ctx context.Context, topic string, message *Message) error
 }
 
-func (dlq *DeadLetterQueueTest) GetAnalytics() *DLQAnalytics {
-	dlq.mu.RLock()
-	defer dlq.mu.RUnlock()
-	
-	analytics := &DLQAnalytics{
-		TotalMessages:   len(dlq.messages),
-		ErrorBreakdown:  make(map[ErrorClassification]int),
-		TopicBreakdown:  make(map[string]int),
-		AverageRetries:  0,
-		OldestMessage:   nil,
-	}
-	
-	if len(dlq.messages) == 0 {
-		return analytics
+// NewBatchReprocessor は batchSize 件ずつ再処理するリプロセッサを作成する。
+// 同時に発行するメッセージ数も batchSize までに制限する
+func NewBatchReprocessor(dlq *DeadLetterQueue, publisher Publisher, batchSize int, strategy ReprocessingStrategy) *BatchReprocessor {
+	if batchSize < 1 {
+		batchSize = 1
 	}
-	
-	totalRetries := 0
-	var oldestTime time.Time
-	var oldestMsg *DLQMessage
-	first := true
-	
-	for _, msg := range dlq.messages {
-		analytics.ErrorBreakdown[msg.ErrorClass]++
-		analytics.TopicBreakdown[msg.OriginalMessage.Topic]++
-		totalRetries += msg.FailureCount
-		
-		if first || msg.FirstFailure.Before(oldestTime) {
-			oldestTime = msg.FirstFailure
-			oldestMsg = msg
-			first = false
-		}
+	return &BatchReprocessor{
+		dlq:       dlq,
+		publisher: publisher,
+		batchSize: batchSize,
+		strategy:  strategy,
+		semaphore: make(chan struct{}, batchSize),
 	}
-	
-	analytics.AverageRetries = float64(totalRetries) / float64(len(dlq.messages))
-	analytics.OldestMessage = oldestMsg
-	
-	return analytics
 }
 
-// DLQAnalytics DLQ分析データ
-type DLQAnalytics struct {
-	TotalMessages   int                              `json:"total_messages"`
-	ErrorBreakdown  map[ErrorClassification]int      `json:"error_breakdown"`
-	TopicBreakdown  map[string]int                   `json:"topic_breakdown"`
-	AverageRetries  float64                          `json:"average_retries"`
-	OldestMessage   *DLQMessage                      `json:"oldest_message"`
-}
+// ReprocessBatch は再処理対象のメッセージをバッチに分けて再発行する。
+// 発行に成功したメッセージはDLQから削除し、失敗したものは失敗回数を増やして残す。
+// 失敗があった場合はそれらをまとめたエラーを返す
+func (br *BatchReprocessor) ReprocessBatch(ctx context.Context, filter func(*DLQMessage) bool) error {
+	messages := br.dlq.GetMessagesForReprocessing(func(msg *DLQMessage) bool {
+		if br.strategy != nil && !br.strategy.ShouldReprocess(msg) {
+			return false
+		}
+		return filter == nil || filter(msg)
+	})
 
-// Message テスト用のメッセージ構造体（簡略版）
-type Message struct {
-	ID    string `json:"id"`
-	Topic string `json:"topic"`
-	Data  []byte `json:"data"`
-}
+	var errs []error
+	for start := 0; start < len(messages); start += br.batchSize {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
 
-// ClassifyError エラー分類関数
-func ClassifyError(err error) ErrorClassification {
-	errMsg := err.Error()
-	
-	if contains(errMsg, "deadline exceeded") || contains(errMsg, "timeout") {
-		return TimeoutError
-	}
-	if contains(errMsg, "validation") || contains(errMsg, "invalid") {
-		return ValidationError
-	}
-	if contains(errMsg, "unauthorized") || contains(errMsg, "security") {
-		return SecurityError
-	}
-	if contains(errMsg, "connection") || contains(errMsg, "network") {
-		return TemporaryError
+		end := start + br.batchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+		if err := br.processBatch(ctx, messages[start:end]); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	
-	return PermanentError
-}
 
-// SimplePublisher テスト用のシンプルパブリッシャー
-type SimplePublisher struct {
-	published []PublishedMessage
-	mu        sync.RWMutex
+	return errors.Join(errs...)
 }
 
-type PublishedMessage struct {
-	Topic   string   `json:"topic"`
-	Message *Message `json:"message"`
-}
+// processBatch はバッチ内のメッセージをセマフォで並行数を制限しながら再発行する
+func (br *BatchReprocessor) processBatch(ctx context.Context, batch []*DLQMessage) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
 
-func NewSimplePublisher() *SimplePublisher {
-	return &SimplePublisher{
-		published: make([]PublishedMessage, 0),
-	}
-}
+	for _, msg := range batch {
+		select {
+		case br.semaphore <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return errors.Join(append(errs, ctx.Err())...)
+		}
 
-func (p *SimplePublisher) Publish(ctx context.Context, topic string, message *Message) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.published = append(p.published, PublishedMessage{
-		Topic:   topic,
-		Message: message,
-	})
-	return nil
-}
+		wg.Add(1)
+		go func(msg *DLQMessage) {
+			defer wg.Done()
+			defer func() { <-br.semaphore }()
 
-func (p *SimplePublisher) GetPublishedMessages() []PublishedMessage {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	result := make([]PublishedMessage, len(p.published))
-	copy(result, p.published)
-	return result
-}
+			if err := br.reprocessMessage(ctx, msg); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(msg)
+	}
 
-// BatchReprocessor バッチ再処理器
-type BatchReprocessor struct {
-	dlq         *DeadLetterQueueTest
-	publisher   *SimplePublisher
-	batchSize   int
-	strategy    *ExponentialBackoffReprocessing
+	wg.Wait()
+	return errors.Join(errs...)
 }
 
-func NewBatchReprocessor(dlq *DeadLetterQueueTest, publisher *SimplePublisher, batchSize int, strategy *ExponentialBackoffReprocessing) *BatchReprocessor {
-	return &BatchReprocessor{
-		dlq:       dlq,
-		publisher: publisher,
-		batchSize: batchSize,
-		strategy:  strategy,
-	}
-}
+// reprocessMessage は1件を再発行し、結果に応じてDLQを更新する
+func (br *BatchReprocessor) reprocessMessage(ctx context.Context, msg *DLQMessage) error {
+	original := msg.OriginalMessage
 
-func (r *BatchReprocessor) ReprocessBatch(ctx context.Context, filter func(*DLQMessage) bool) error {
-	r.dlq.mu.RLock()
-	var toReprocess []*DLQMessage
-	for _, msg := range r.dlq.messages {
-		if filter(msg) && len(toReprocess) < r.batchSize {
-			toReprocess = append(toReprocess, msg)
+	if err := br.publisher.Publish(ctx, original.Topic, original); err != nil {
+		// 同じIDで Send すると失敗回数と最終失敗時刻が更新される
+		if sendErr := br.dlq.Send(ctx, &DLQMessage{
+			OriginalMessage: original,
+			FailureReason:   err.Error(),
+			ErrorClass:      ClassifyError(err),
+		}); sendErr != nil {
+			return fmt.Errorf("republish %s: %w (dlq update failed: %v)", original.ID, err, sendErr)
 		}
+		return fmt.Errorf("republish %s: %w", original.ID, err)
 	}
-	r.dlq.mu.RUnlock()
-	
-	// 再処理
-	for _, msg := range toReprocess {
-		err := r.publisher.Publish(ctx, msg.OriginalMessage.Topic, msg.OriginalMessage)
-		if err != nil {
-			return err
-		}
-		
-		// DLQから削除
-		r.dlq.RemoveMessage(msg.OriginalMessage.ID)
+
+	// 並行して別の処理が削除済みの場合は無視する
+	if err := br.dlq.RemoveMessage(original.ID); err != nil && !errors.Is(err, ErrMessageNotFound) {
+		return err
 	}
-	
 	return nil
 }
 
-// DLQMonitor DLQ監視器
+// アラート機能
+type AlertingService interface {
+	SendWarningAlert(alertType, message string) error
+	SendCriticalAlert(alertType, message string) error
+	SendSecurityAlert(alertType, message string) error
+}
+
 type DLQMonitor struct {
-	dlq      *DeadLetterQueueTest
-	alerting *SimpleAlertingService
+	dlq      *DeadLetterQueue
+	alerting AlertingService
 	config   MonitorConfig
 }
 
 type MonitorConfig struct {
-	MaxMessages     int
+	MaxMessages     int64
 	MaxMessageAge   time.Duration
-	MaxSecurityErrs int
+	MaxSecurityErrs int64
 	CheckInterval   time.Duration
 }
 
-func NewDLQMonitor(dlq *DeadLetterQueueTest, alerting *SimpleAlertingService, config MonitorConfig) *DLQMonitor {
+// アラート種別
+const (
+	AlertTypeHighVolume     = "DLQ_HIGH_VOLUME"
+	AlertTypeOldMessages    = "DLQ_OLD_MESSAGES"
+	AlertTypeSecurityErrors = "DLQ_SECURITY_ERRORS"
+)
+
+// NewDLQMonitor はDLQの閾値監視を作成する
+func NewDLQMonitor(dlq *DeadLetterQueue, alerting AlertingService, config MonitorConfig) *DLQMonitor {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = time.Minute
+	}
 	return &DLQMonitor{
 		dlq:      dlq,
 		alerting: alerting,
@@ -823,40 +481,49 @@ func NewDLQMonitor(dlq *DeadLetterQueueTest, alerting *SimpleAlertingService, co
 	}
 }
 
-func (m *DLQMonitor) StartMonitoring(ctx context.Context) {
-	ticker := time.NewTicker(m.config.CheckInterval)
+// StartMonitoring は ctx がキャンセルされるまで CheckInterval ごとに
+// 分析データを確認してアラートを送信する
+func (dm *DLQMonitor) StartMonitoring(ctx context.Context) {
+	ticker := time.NewTicker(dm.config.CheckInterval)
 	defer ticker.Stop()
-	
+
 	for {
 		select {
-		case <-ticker.C:
-			analytics := m.dlq.GetAnalytics()
-			
-			// 高ボリュームアラート
-			if analytics.TotalMessages > m.config.MaxMessages {
-				m.alerting.SendCriticalAlert("HIGH_VOLUME", fmt.Sprintf("DLQ has %d messages", analytics.TotalMessages))
-			}
-			
-			// 古いメッセージアラート
-			if analytics.OldestMessage != nil {
-				age := time.Since(analytics.OldestMessage.FirstFailure)
-				if age > m.config.MaxMessageAge {
-					m.alerting.SendWarningAlert("OLD_MESSAGES", fmt.Sprintf("Oldest message is %v old", age))
-				}
-			}
-			
-			// セキュリティエラーアラート
-			if securityCount := analytics.ErrorBreakdown[SecurityError]; securityCount > m.config.MaxSecurityErrs {
-				m.alerting.SendSecurityAlert("SECURITY", fmt.Sprintf("Too many security errors: %d", securityCount))
-			}
-			
 		case <-ctx.Done():
 			return
+		case <-ticker.C:
+			dm.checkAndAlert(dm.dlq.GetAnalytics())
 		}
 	}
 }
 
-// SimpleAlertingService テスト用のシンプルアラートサービス
+// checkAndAlert は閾値を超えた項目ごとにアラートを1件ずつ送信する。
+// 閾値が0以下の項目はチェックしない
+func (dm *DLQMonitor) checkAndAlert(analytics *DLQAnalytics) {
+	if analytics == nil {
+		return
+	}
+
+	if dm.config.MaxMessages > 0 && analytics.TotalMessages > dm.config.MaxMessages {
+		dm.alerting.SendWarningAlert(AlertTypeHighVolume,
+			fmt.Sprintf("DLQ has %d messages (threshold %d)", analytics.TotalMessages, dm.config.MaxMessages))
+	}
+
+	if dm.config.MaxMessageAge > 0 && analytics.OldestMessage != nil {
+		if age := time.Since(*analytics.OldestMessage); age > dm.config.MaxMessageAge {
+			dm.alerting.SendCriticalAlert(AlertTypeOldMessages,
+				fmt.Sprintf("oldest DLQ message is %s old (threshold %s)", age.Round(time.Second), dm.config.MaxMessageAge))
+		}
+	}
+
+	// セキュリティエラーは件数が少なくても見逃さないよう専用のアラートで通知する
+	if securityErrs := analytics.ErrorBreakdown[SecurityError]; securityErrs > dm.config.MaxSecurityErrs {
+		dm.alerting.SendSecurityAlert(AlertTypeSecurityErrors,
+			fmt.Sprintf("DLQ contains %d security errors (threshold %d)", securityErrs, dm.config.MaxSecurityErrs))
+	}
+}
+
+// 簡単なアラートサービス実装
 type SimpleAlertingService struct {
 	alerts []Alert
 	mu     sync.RWMutex
@@ -869,57 +536,213 @@ type Alert struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// アラートレベル
+const (
+	AlertLevelWarning  = "warning"
+	AlertLevelCritical = "critical"
+	AlertLevelSecurity = "security"
+)
+
+// NewSimpleAlertingService はアラートをメモリに記録するサービスを作成する
 func NewSimpleAlertingService() *SimpleAlertingService {
 	return &SimpleAlertingService{
 		alerts: make([]Alert, 0),
 	}
 }
 
-func (s *SimpleAlertingService) SendWarningAlert(alertType, message string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.alerts = append(s.alerts, Alert{
-		Type:      alertType,
-		Level:     "warning",
-		Message:   message,
-		Timestamp: time.Now(),
-	})
-	return nil
+// SendWarningAlert は警告アラートを記録する
+func (sas *SimpleAlertingService) SendWarningAlert(alertType, message string) error {
+	return sas.record(alertType, AlertLevelWarning, message)
+}
+
+// SendCriticalAlert は重要アラートを記録する
+func (sas *SimpleAlertingService) SendCriticalAlert(alertType, message string) error {
+	return sas.record(alertType, AlertLevelCritical, message)
+}
+
+// SendSecurityAlert はセキュリティアラートを記録する
+func (sas *SimpleAlertingService) SendSecurityAlert(alertType, message string) error {
+	return sas.record(alertType, AlertLevelSecurity, message)
 }
 
-func (s *SimpleAlertingService) SendCriticalAlert(alertType, message string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.alerts = append(s.alerts, Alert{
+func (sas *SimpleAlertingService) record(alertType, level, message string) error {
+	sas.mu.Lock()
+	defer sas.mu.Unlock()
+
+	sas.alerts = append(sas.alerts, Alert{
 		Type:      alertType,
-		Level:     "critical",
+		Level:     level,
 		Message:   message,
 		Timestamp: time.Now(),
 	})
 	return nil
 }
 
-func (s *SimpleAlertingService) SendSecurityAlert(alertType, message string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.alerts = append(s.alerts, Alert{
-		Type:      alertType,
-		Level:     "security",
+// GetAlerts はアラートのコピーを返す
+func (sas *SimpleAlertingService) GetAlerts() []Alert {
+	sas.mu.RLock()
+	defer sas.mu.RUnlock()
+
+	result := make([]Alert, len(sas.alerts))
+	copy(result, sas.alerts)
+	return result
+}
+
+// 簡単なPublisher実装
+type SimplePublisher struct {
+	publishedMessages []PublishedMessage
+	mu               sync.RWMutex
+}
+
+type PublishedMessage struct {
+	Topic     string    `json:"topic"`
+	Message   *Message  `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewSimplePublisher は発行したメッセージをメモリに記録するPublisherを作成する
+func NewSimplePublisher() *SimplePublisher {
+	return &SimplePublisher{
+		publishedMessages: make([]PublishedMessage, 0),
+	}
+}
+
+// Publish はメッセージを発行済みとして記録する
+func (sp *SimplePublisher) Publish(ctx context.Context, topic string, message *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	sp.publishedMessages = append(sp.publishedMessages, PublishedMessage{
+		Topic:     topic,
 		Message:   message,
 		Timestamp: time.Now(),
 	})
 	return nil
 }
 
-func (s *SimpleAlertingService) GetAlerts() []Alert {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	result := make([]Alert, len(s.alerts))
-	copy(result, s.alerts)
+// GetPublishedMessages は発行済みメッセージのコピーを返す
+func (sp *SimplePublisher) GetPublishedMessages() []PublishedMessage {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+
+	result := make([]PublishedMessage, len(sp.publishedMessages))
+	copy(result, sp.publishedMessages)
 	return result
 }
 
 func main() {
-	fmt.Println("Day 54: Dead-Letter Queue (DLQ)")
-	fmt.Println("Run 'go test -v' to see the dead letter queue system in action")
+	// 再処理戦略を作成
+	strategy := &ExponentialBackoffReprocessing{
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		MaxAttempts: 3,
+		Multiplier:  2.0,
+	}
+	
+	// DLQを作成
+	dlq := NewDeadLetterQueue(strategy)
+	
+	// アラートサービスを作成
+	alerting := NewSimpleAlertingService()
+	
+	// 監視設定
+	monitorConfig := MonitorConfig{
+		MaxMessages:     100,
+		MaxMessageAge:   24 * time.Hour,
+		MaxSecurityErrs: 5,
+		CheckInterval:   1 * time.Minute,
+	}
+	
+	// DLQ監視を作成
+	monitor := NewDLQMonitor(dlq, alerting, monitorConfig)
+	
+	// Publisherを作成
+	publisher := NewSimplePublisher()
+	
+	// バッチ再処理器を作成
+	reprocessor := NewBatchReprocessor(dlq, publisher, 10, strategy)
+	
+	// テストメッセージをDLQに追加
+	testMessages := []*DLQMessage{
+		{
+			OriginalMessage: &Message{
+				ID:    "msg-1",
+				Topic: "test-topic",
+				Data:  []byte("test data 1"),
+			},
+			FailureReason: "temporary network error",
+			ErrorClass:    TemporaryError,
+			FailureCount:  1,
+			FirstFailure:  time.Now().Add(-1 * time.Hour),
+			LastFailure:   time.Now().Add(-1 * time.Hour),
+		},
+		{
+			OriginalMessage: &Message{
+				ID:    "msg-2",
+				Topic: "test-topic",
+				Data:  []byte("test data 2"),
+			},
+			FailureReason: "validation failed",
+			ErrorClass:    ValidationError,
+			FailureCount:  2,
+			FirstFailure:  time.Now().Add(-2 * time.Hour),
+			LastFailure:   time.Now().Add(-30 * time.Minute),
+		},
+		{
+			OriginalMessage: &Message{
+				ID:    "msg-3",
+				Topic: "secure-topic",
+				Data:  []byte("sensitive data"),
+			},
+			FailureReason: "unauthorized access",
+			ErrorClass:    SecurityError,
+			FailureCount:  1,
+			FirstFailure:  time.Now().Add(-10 * time.Minute),
+			LastFailure:   time.Now().Add(-10 * time.Minute),
+		},
+	}
+	
+	ctx := context.Background()
+	
+	// メッセージをDLQに追加
+	for _, dlqMsg := range testMessages {
+		dlq.Send(ctx, dlqMsg)
+	}
+	
+	// 分析データを表示
+	analytics := dlq.GetAnalytics()
+	fmt.Printf("DLQ Analytics: %+v\n", analytics)
+	
+	// 再処理可能なメッセージを取得
+	reprocessableFilter := func(dlqMsg *DLQMessage) bool {
+		return strategy.ShouldReprocess(dlqMsg)
+	}
+	
+	reprocessableMessages := dlq.GetMessagesForReprocessing(reprocessableFilter)
+	fmt.Printf("Reprocessable messages: %d\n", len(reprocessableMessages))
+	
+	// バッチ再処理を実行
+	err := reprocessor.ReprocessBatch(ctx, reprocessableFilter)
+	if err != nil {
+		fmt.Printf("Reprocessing error: %v\n", err)
+	}
+	
+	// 監視を短時間実行
+	monitorCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	
+	go monitor.StartMonitoring(monitorCtx)
+	
+	// 処理時間を待つ
+	time.Sleep(3 * time.Second)
+	
+	// 結果を表示
+	fmt.Printf("Published messages: %d\n", len(publisher.GetPublishedMessages()))
+	fmt.Printf("Alerts: %d\n", len(alerting.GetAlerts()))
+	
+	fmt.Println("DLQ test completed")
 }
\ No newline at end of file
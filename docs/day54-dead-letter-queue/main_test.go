@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -90,6 +92,67 @@ func TestDeadLetterQueue_ErrorClassification(t *testing.T) {
 	t.Log("Error classification working correctly")
 }
 
+type fakeNetError struct{ timeout bool }
+
+func (e fakeNetError) Error() string   { return "i/o failure" }
+func (e fakeNetError) Timeout() bool   { return e.timeout }
+func (e fakeNetError) Temporary() bool { return false }
+
+func TestClassifyError_BuiltinClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorClassification
+	}{
+		{"wrapped deadline", fmt.Errorf("fetch order: %w", context.DeadlineExceeded), TimeoutError},
+		{"timeout interface", fakeNetError{timeout: true}, TimeoutError},
+		{"timeout message", errors.New("read tcp: i/o Timeout"), TimeoutError},
+		{"network", errors.New("network is unreachable"), TemporaryError},
+		{"service unavailable", errors.New("503 Service Unavailable"), TemporaryError},
+		{"forbidden", errors.New("Forbidden: missing scope"), SecurityError},
+		{"invalid payload", errors.New("invalid JSON payload"), ValidationError},
+		{"non-timeout net error", fakeNetError{timeout: false}, PermanentError},
+		{"nil", nil, PermanentError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v for error: %v", tt.expected, got, tt.err)
+			}
+		})
+	}
+}
+
+func TestClassifyError_RegisteredClassifier(t *testing.T) {
+	classifiersMu.Lock()
+	saved := classifiers
+	classifiersMu.Unlock()
+	t.Cleanup(func() {
+		classifiersMu.Lock()
+		classifiers = saved
+		classifiersMu.Unlock()
+	})
+
+	errQuotaExceeded := errors.New("quota exceeded: invalid plan")
+	RegisterClassifier(func(err error) (ErrorClassification, bool) {
+		if errors.Is(err, errQuotaExceeded) {
+			return TemporaryError, true
+		}
+		return "", false
+	})
+
+	// 組み込み判定では ValidationError になるが、登録した分類器が優先される
+	if got := ClassifyError(fmt.Errorf("charge: %w", errQuotaExceeded)); got != TemporaryError {
+		t.Errorf("Expected registered classifier to win with %v, got %v", TemporaryError, got)
+	}
+
+	// 分類器が判定しないエラーは組み込みの判定にフォールバックする
+	if got := ClassifyError(errors.New("invalid email")); got != ValidationError {
+		t.Errorf("Expected fallback to %v, got %v", ValidationError, got)
+	}
+}
+
 func TestDeadLetterQueue_Reprocessing(t *testing.T) {
 	strategy := &ExponentialBackoffReprocessing{
 		BaseDelay:   1 * time.Millisecond,
@@ -170,6 +233,89 @@ func TestDeadLetterQueue_Reprocessing(t *testing.T) {
 	t.Log("Message reprocessing working correctly")
 }
 
+// flakyPublisher は3回に1回発行に失敗するPublisher
+type flakyPublisher struct {
+	mu        sync.Mutex
+	calls     int
+	failed    map[string]bool
+	published map[string]bool
+}
+
+func newFlakyPublisher() *flakyPublisher {
+	return &flakyPublisher{failed: make(map[string]bool), published: make(map[string]bool)}
+}
+
+func (fp *flakyPublisher) Publish(ctx context.Context, topic string, message *Message) error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	fp.calls++
+	if fp.calls%3 == 0 {
+		fp.failed[message.ID] = true
+		return errors.New("broker connection refused")
+	}
+	fp.published[message.ID] = true
+	return nil
+}
+
+func TestBatchReprocessor_PartialFailure(t *testing.T) {
+	strategy := &ExponentialBackoffReprocessing{
+		BaseDelay:   1 * time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+		MaxAttempts: 5,
+		Multiplier:  2.0,
+	}
+	dlq := NewDeadLetterQueue(strategy)
+	publisher := newFlakyPublisher()
+	reprocessor := NewBatchReprocessor(dlq, publisher, 4, strategy)
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	for i := 0; i < 9; i++ {
+		dlq.Send(ctx, &DLQMessage{
+			OriginalMessage: &Message{ID: fmt.Sprintf("batch-msg-%d", i), Topic: "orders"},
+			FailureReason:   "temporary error",
+			ErrorClass:      TemporaryError,
+			FailureCount:    1,
+			FirstFailure:    past,
+			LastFailure:     past,
+		})
+	}
+
+	err := reprocessor.ReprocessBatch(ctx, nil)
+	if err == nil {
+		t.Fatal("Expected an error describing the failed republishes")
+	}
+
+	if len(publisher.published) != 6 || len(publisher.failed) != 3 {
+		t.Fatalf("Expected 6 successes and 3 failures, got %d and %d", len(publisher.published), len(publisher.failed))
+	}
+
+	for id := range publisher.published {
+		if _, found := dlq.GetMessage(id); found {
+			t.Errorf("Republished message %s should be removed from DLQ", id)
+		}
+	}
+
+	for id := range publisher.failed {
+		msg, found := dlq.GetMessage(id)
+		if !found {
+			t.Errorf("Failed message %s should remain in DLQ", id)
+			continue
+		}
+		if msg.FailureCount != 2 {
+			t.Errorf("Expected failure count 2 for %s, got %d", id, msg.FailureCount)
+		}
+		if !strings.Contains(msg.FailureReason, "connection refused") {
+			t.Errorf("Expected failure reason to be updated for %s, got %q", id, msg.FailureReason)
+		}
+	}
+
+	if total := dlq.GetAnalytics().TotalMessages; total != 3 {
+		t.Errorf("Expected 3 messages left in DLQ, got %d", total)
+	}
+}
+
 func TestDeadLetterQueue_Analytics(t *testing.T) {
 	strategy := &ExponentialBackoffReprocessing{
 		BaseDelay:   10 * time.Millisecond,
@@ -262,6 +408,154 @@ func TestDeadLetterQueue_Analytics(t *testing.T) {
 	t.Log("DLQ analytics working correctly")
 }
 
+// acceptAllStrategy は常に再処理を許可する戦略（DLQ本体のテスト用）
+type acceptAllStrategy struct{}
+
+func (acceptAllStrategy) ShouldReprocess(dlqMsg *DLQMessage) bool      { return true }
+func (acceptAllStrategy) NextAttemptTime(dlqMsg *DLQMessage) time.Time { return dlqMsg.LastFailure }
+func (acceptAllStrategy) MaxRetryAttempts() int                        { return 0 }
+
+func TestDeadLetterQueue_SendUpsert(t *testing.T) {
+	dlq := NewDeadLetterQueue(acceptAllStrategy{})
+	ctx := context.Background()
+
+	first := time.Now().Add(-time.Hour)
+	err := dlq.Send(ctx, &DLQMessage{
+		OriginalMessage: &Message{ID: "order-1", Topic: "orders"},
+		FailureReason:   "connection refused",
+		ErrorClass:      TemporaryError,
+		FailureCount:    1,
+		FirstFailure:    first,
+		LastFailure:     first,
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	// 同じIDで再送すると新規追加ではなく既存メッセージが更新される
+	retryAt := time.Now()
+	err = dlq.Send(ctx, &DLQMessage{
+		OriginalMessage: &Message{ID: "order-1", Topic: "orders"},
+		FailureReason:   "timeout",
+		ErrorClass:      TimeoutError,
+		FailureCount:    1,
+		FirstFailure:    retryAt,
+		LastFailure:     retryAt,
+	})
+	if err != nil {
+		t.Fatalf("Re-send failed: %v", err)
+	}
+
+	msg, found := dlq.GetMessage("order-1")
+	if !found {
+		t.Fatal("Message not found after re-send")
+	}
+	if msg.FailureCount != 2 {
+		t.Errorf("Expected failure count 2, got %d", msg.FailureCount)
+	}
+	if !msg.FirstFailure.Equal(first) {
+		t.Errorf("FirstFailure should be kept: expected %v, got %v", first, msg.FirstFailure)
+	}
+	if !msg.LastFailure.Equal(retryAt) {
+		t.Errorf("LastFailure should be bumped: expected %v, got %v", retryAt, msg.LastFailure)
+	}
+	if msg.FailureReason != "timeout" || msg.ErrorClass != TimeoutError {
+		t.Errorf("Expected latest failure to be recorded, got %q (%s)", msg.FailureReason, msg.ErrorClass)
+	}
+	if total := dlq.GetAnalytics().TotalMessages; total != 1 {
+		t.Errorf("Expected 1 message after upsert, got %d", total)
+	}
+
+	if err := dlq.Send(ctx, &DLQMessage{}); err == nil {
+		t.Error("Expected error for message without OriginalMessage")
+	}
+	if err := dlq.RemoveMessage("missing"); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestDeadLetterQueue_AnalyticsAggregation(t *testing.T) {
+	dlq := NewDeadLetterQueue(acceptAllStrategy{})
+	ctx := context.Background()
+
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	sends := []struct {
+		id    string
+		topic string
+		class ErrorClassification
+		at    time.Time
+	}{
+		{"a", "orders", TemporaryError, base.Add(5 * time.Minute)},
+		{"b", "orders", ValidationError, base.Add(50 * time.Minute)},
+		{"c", "payments", TemporaryError, base.Add(70 * time.Minute)},
+		{"a", "orders", TemporaryError, base.Add(90 * time.Minute)}, // 再送
+		{"d", "payments", SecurityError, base.Add(-30 * time.Minute)},
+	}
+	for _, s := range sends {
+		err := dlq.Send(ctx, &DLQMessage{
+			OriginalMessage: &Message{ID: s.id, Topic: s.topic},
+			ErrorClass:      s.class,
+			FailureCount:    1,
+			FirstFailure:    s.at,
+			LastFailure:     s.at,
+		})
+		if err != nil {
+			t.Fatalf("Send(%s) failed: %v", s.id, err)
+		}
+	}
+
+	analytics := dlq.GetAnalytics()
+
+	if analytics.TotalMessages != 4 {
+		t.Errorf("Expected 4 messages, got %d", analytics.TotalMessages)
+	}
+	wantErrors := map[ErrorClassification]int64{TemporaryError: 2, ValidationError: 1, SecurityError: 1}
+	for class, want := range wantErrors {
+		if got := analytics.ErrorBreakdown[class]; got != want {
+			t.Errorf("ErrorBreakdown[%s] = %d, want %d", class, got, want)
+		}
+	}
+	wantTopics := map[string]int64{"orders": 2, "payments": 2}
+	for topic, want := range wantTopics {
+		if got := analytics.TopicBreakdown[topic]; got != want {
+			t.Errorf("TopicBreakdown[%s] = %d, want %d", topic, got, want)
+		}
+	}
+	wantHourly := map[string]int64{"2024-03-01T09:00": 1, "2024-03-01T10:00": 2, "2024-03-01T11:00": 1}
+	if len(analytics.HourlyStats) != len(wantHourly) {
+		t.Errorf("Expected %d hourly buckets, got %v", len(wantHourly), analytics.HourlyStats)
+	}
+	for bucket, want := range wantHourly {
+		if got := analytics.HourlyStats[bucket]; got != want {
+			t.Errorf("HourlyStats[%s] = %d, want %d", bucket, got, want)
+		}
+	}
+	// a は2回失敗、それ以外は1回
+	if want := float64(2+1+1+1) / 4; analytics.AverageRetries != want {
+		t.Errorf("AverageRetries = %.2f, want %.2f", analytics.AverageRetries, want)
+	}
+	if analytics.OldestMessage == nil || !analytics.OldestMessage.Equal(base.Add(-30*time.Minute)) {
+		t.Errorf("OldestMessage = %v, want %v", analytics.OldestMessage, base.Add(-30*time.Minute))
+	}
+
+	// 再処理候補は filter を満たすものが FirstFailure の古い順に並ぶ
+	candidates := dlq.GetMessagesForReprocessing(func(msg *DLQMessage) bool {
+		return msg.ErrorClass != SecurityError
+	})
+	var ids []string
+	for _, msg := range candidates {
+		ids = append(ids, msg.OriginalMessage.ID)
+	}
+	if strings.Join(ids, ",") != "a,b,c" {
+		t.Errorf("Expected reprocessing order a,b,c, got %v", ids)
+	}
+
+	empty := NewDeadLetterQueue(acceptAllStrategy{}).GetAnalytics()
+	if empty.TotalMessages != 0 || empty.AverageRetries != 0 || empty.OldestMessage != nil {
+		t.Errorf("Expected zero analytics for empty DLQ, got %+v", empty)
+	}
+}
+
 func TestExponentialBackoffReprocessing(t *testing.T) {
 	strategy := &ExponentialBackoffReprocessing{
 		BaseDelay:   10 * time.Millisecond,
@@ -330,6 +624,47 @@ func TestExponentialBackoffReprocessing(t *testing.T) {
 	t.Log("Exponential backoff strategy working correctly")
 }
 
+func TestExponentialBackoffReprocessing_Decisions(t *testing.T) {
+	strategy := &ExponentialBackoffReprocessing{
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+		MaxAttempts: 3,
+		Multiplier:  2.0,
+	}
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		msg  *DLQMessage
+		want bool
+	}{
+		{"temporary error after backoff", &DLQMessage{ErrorClass: TemporaryError, FailureCount: 1, LastFailure: past}, true},
+		{"timeout error after backoff", &DLQMessage{ErrorClass: TimeoutError, FailureCount: 2, LastFailure: past}, true},
+		{"temporary error still backing off", &DLQMessage{ErrorClass: TemporaryError, FailureCount: 1, LastFailure: time.Now()}, false},
+		{"temporary error at max attempts", &DLQMessage{ErrorClass: TemporaryError, FailureCount: 3, LastFailure: past}, false},
+		{"security error is never reprocessed", &DLQMessage{ErrorClass: SecurityError, FailureCount: 0, LastFailure: past}, false},
+		{"validation error is never reprocessed", &DLQMessage{ErrorClass: ValidationError, FailureCount: 1, LastFailure: past}, false},
+		{"permanent error is never reprocessed", &DLQMessage{ErrorClass: PermanentError, FailureCount: 1, LastFailure: past}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strategy.ShouldReprocess(tt.msg); got != tt.want {
+				t.Errorf("ShouldReprocess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 1回目の失敗後は BaseDelay * 2^1、上限を超える回数では MaxDelay
+	last := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := strategy.NextAttemptTime(&DLQMessage{FailureCount: 1, LastFailure: last}); !got.Equal(last.Add(2 * time.Second)) {
+		t.Errorf("NextAttemptTime(1) = %v, want %v", got, last.Add(2*time.Second))
+	}
+	if got := strategy.NextAttemptTime(&DLQMessage{FailureCount: 10, LastFailure: last}); !got.Equal(last.Add(time.Minute)) {
+		t.Errorf("NextAttemptTime(10) = %v, want capped at %v", got, last.Add(time.Minute))
+	}
+}
+
 func TestDLQMonitor_Alerting(t *testing.T) {
 	strategy := &ExponentialBackoffReprocessing{
 		BaseDelay:   10 * time.Millisecond,
@@ -420,6 +755,89 @@ func TestDLQMonitor_Alerting(t *testing.T) {
 	t.Log("DLQ monitoring and alerting working correctly")
 }
 
+func TestDLQMonitor_CheckAndAlertThresholds(t *testing.T) {
+	config := MonitorConfig{
+		MaxMessages:     10,
+		MaxMessageAge:   1 * time.Hour,
+		MaxSecurityErrs: 0,
+		CheckInterval:   time.Second,
+	}
+	recent := time.Now().Add(-1 * time.Minute)
+	old := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name      string
+		analytics *DLQAnalytics
+		wantType  string
+		wantLevel string
+	}{
+		{
+			name:      "high volume",
+			analytics: &DLQAnalytics{TotalMessages: 11, ErrorBreakdown: map[ErrorClassification]int64{}, OldestMessage: &recent},
+			wantType:  AlertTypeHighVolume,
+			wantLevel: AlertLevelWarning,
+		},
+		{
+			name:      "old messages",
+			analytics: &DLQAnalytics{TotalMessages: 1, ErrorBreakdown: map[ErrorClassification]int64{}, OldestMessage: &old},
+			wantType:  AlertTypeOldMessages,
+			wantLevel: AlertLevelCritical,
+		},
+		{
+			name:      "security errors",
+			analytics: &DLQAnalytics{TotalMessages: 1, ErrorBreakdown: map[ErrorClassification]int64{SecurityError: 1}, OldestMessage: &recent},
+			wantType:  AlertTypeSecurityErrors,
+			wantLevel: AlertLevelSecurity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerting := NewSimpleAlertingService()
+			monitor := NewDLQMonitor(NewDeadLetterQueue(nil), alerting, config)
+
+			monitor.checkAndAlert(tt.analytics)
+
+			alerts := alerting.GetAlerts()
+			if len(alerts) != 1 {
+				t.Fatalf("Expected exactly 1 alert, got %d: %+v", len(alerts), alerts)
+			}
+			if alerts[0].Type != tt.wantType || alerts[0].Level != tt.wantLevel {
+				t.Errorf("Expected %s/%s alert, got %s/%s", tt.wantType, tt.wantLevel, alerts[0].Type, alerts[0].Level)
+			}
+		})
+	}
+
+	t.Run("below thresholds", func(t *testing.T) {
+		alerting := NewSimpleAlertingService()
+		monitor := NewDLQMonitor(NewDeadLetterQueue(nil), alerting, config)
+
+		monitor.checkAndAlert(&DLQAnalytics{TotalMessages: 10, ErrorBreakdown: map[ErrorClassification]int64{}, OldestMessage: &recent})
+
+		if alerts := alerting.GetAlerts(); len(alerts) != 0 {
+			t.Errorf("Expected no alerts, got %+v", alerts)
+		}
+	})
+}
+
+func TestDLQMonitor_StopsOnCancel(t *testing.T) {
+	monitor := NewDLQMonitor(NewDeadLetterQueue(nil), NewSimpleAlertingService(), MonitorConfig{CheckInterval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		monitor.StartMonitoring(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("StartMonitoring did not return after context cancellation")
+	}
+}
+
 func TestSimplePublisher(t *testing.T) {
 	publisher := NewSimplePublisher()
 	
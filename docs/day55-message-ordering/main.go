@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -55,6 +56,12 @@ func NewPartitionedQueue(partitionCount int, partitioner Partitioner) *Partition
 	return nil
 }
 
+// TODO: パーティションごとの滞留上限を指定して PartitionedQueue を初期化
+func NewPartitionedQueueWithBackpressure(partitionCount int, partitioner Partitioner, maxQueueSize int) *PartitionedQueue {
+	// ヒント: 各パーティションに BackpressureController を持たせる
+	return nil
+}
+
 // TODO: メッセージを送信
 func (pq *PartitionedQueue) Send(message *OrderedMessage) error {
 	// ヒント:
@@ -65,6 +72,16 @@ func (pq *PartitionedQueue) Send(message *OrderedMessage) error {
 	return nil
 }
 
+// TODO: メッセージを送信（パーティションが滞留上限を超えている間はブロック）
+func (pq *PartitionedQueue) SendContext(ctx context.Context, message *OrderedMessage) error {
+	// ヒント:
+	// 1. パーティションを決定
+	// 2. BackpressureController で枠が空くまで待機（ctx のキャンセルで中断）
+	// 3. パーティションにメッセージを追加
+	
+	return nil
+}
+
 // TODO: コンシューマーを追加
 func (pq *PartitionedQueue) AddConsumer(partitionID int, consumer *OrderedConsumer) error {
 	// ヒント: 指定されたパーティションにコンシューマーを追加
@@ -109,6 +126,8 @@ type OrderedConsumer struct {
 	handler           MessageHandler
 	backpressure      *BackpressureController
 	orderingBuffer    *OrderingBuffer
+	errors            chan error
+	failedCount       int64
 }
 
 // TODO: OrderedConsumer を初期化
@@ -117,6 +136,16 @@ func NewOrderedConsumer(id string, handler MessageHandler) *OrderedConsumer {
 	return nil
 }
 
+// TODO: ハンドラーが返したエラーを受け取るチャネル
+func (oc *OrderedConsumer) Errors() <-chan error {
+	return nil
+}
+
+// TODO: ハンドラーが失敗したメッセージ数
+func (oc *OrderedConsumer) FailedCount() int64 {
+	return 0
+}
+
 // TODO: コンシューマーを開始
 func (oc *OrderedConsumer) Start(ctx context.Context) error {
 	// ヒント:
@@ -155,6 +184,12 @@ func NewBackpressureController(maxQueueSize int) *BackpressureController {
 	return nil
 }
 
+// TODO: 目標処理レート（msg/sec）を指定して BackpressureController を初期化
+func NewBackpressureControllerWithTargetRate(maxQueueSize int, targetRate float64) *BackpressureController {
+	// ヒント: targetRate が 0 の場合はキューサイズのみで制御する
+	return nil
+}
+
 // TODO: スロットルが必要かチェック
 func (bp *BackpressureController) ShouldThrottle() bool {
 	// ヒント:
@@ -207,6 +242,12 @@ func (rc *RateCalculator) RecordProcessing() {
 	// ヒント: カウンターを更新し、レートを再計算
 }
 
+// TODO: 滞留上限に空きができるまで待ち、送信するメッセージの枠を確保
+func (bp *BackpressureController) acquire(ctx context.Context) error {
+	// ヒント: 空きの確認と滞留数の加算はまとめて行い、待機中の送信者は数えない
+	return nil
+}
+
 // 順序保証バッファ
 type OrderingBuffer struct {
 	buffer          map[int64]*OrderedMessage
@@ -216,6 +257,11 @@ type OrderingBuffer struct {
 	mu              sync.RWMutex
 }
 
+var (
+	ErrOrderingBufferFull = errors.New("ordering buffer is full")
+	ErrDuplicateSequence  = errors.New("sequence number already delivered or buffered")
+)
+
 // TODO: OrderingBuffer を初期化
 func NewOrderingBuffer(maxBufferSize int) *OrderingBuffer {
 	return nil
@@ -273,6 +319,16 @@ type DistributedOrderingCoordinator struct {
 	mu           sync.RWMutex
 }
 
+// メッセージのメタデータに付与する時刻情報のキー
+const (
+	MetadataSenderNode   = "sender_node"
+	MetadataVectorClock  = "vector_clock"
+	MetadataLamportClock = "lamport_clock"
+)
+
+// ErrCausalOrderViolation は因果的に先行するメッセージより先に届いたことを表す
+var ErrCausalOrderViolation = errors.New("message delivered before its causal dependencies")
+
 // TODO: DistributedOrderingCoordinator を初期化
 func NewDistributedOrderingCoordinator(nodeID string) *DistributedOrderingCoordinator {
 	return nil
@@ -5,676 +5,755 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"sort"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Message 順序付きメッセージ
-type Message struct {
-	ID           string      `json:"id"`
-	PartitionKey string      `json:"partition_key"`
-	SequenceNum  int64       `json:"sequence_num"`
-	Timestamp    time.Time   `json:"timestamp"`
-	Type         string      `json:"type"`
-	Data         interface{} `json:"data"`
-	Dependencies []string    `json:"dependencies,omitempty"`
+// 実装している機能：
+// 1. パーティション分割による順序保証
+// 2. シーケンス番号ベースの順序制御
+// 3. バックプレッシャー制御
+// 4. 順序違反検出と修復
+// 5. 分散順序保証
+
+type OrderedMessage struct {
+	ID           string                 `json:"id"`
+	PartitionKey string                 `json:"partition_key"`
+	SequenceNo   int64                  `json:"sequence_no"`
+	Data         []byte                 `json:"data"`
+	Timestamp    time.Time              `json:"timestamp"`
+	Metadata     map[string]interface{} `json:"metadata"`
 }
 
-// OrderedMessageProcessor 順序保証メッセージプロセッサー
-type OrderedMessageProcessor interface {
-	Process(ctx context.Context, msg *Message) error
-	GetProcessorType() string
+type PartitionedQueue struct {
+	partitions  map[int]*OrderedPartition
+	partitioner Partitioner
+	mu          sync.RWMutex
 }
 
-// SequentialProcessor 順次処理プロセッサー
-type SequentialProcessor struct {
-	name           string
-	partitionState map[string]*PartitionState
-	mu             sync.RWMutex
+type OrderedPartition struct {
+	id           int
+	messages     []*OrderedMessage
+	consumers    []*OrderedConsumer
+	mu           sync.RWMutex
+	sequenceNo   int64
+	backpressure *BackpressureController
 }
 
-type PartitionState struct {
-	lastProcessedSeq int64
-	pendingMessages  []*Message
-	processing       bool
-	mu               sync.RWMutex
+type Partitioner interface {
+	GetPartition(key string) int
 }
 
-func NewSequentialProcessor(name string) *SequentialProcessor {
-	return &SequentialProcessor{
-		name:           name,
-		partitionState: make(map[string]*PartitionState),
-	}
+type MessageHandler func(ctx context.Context, message *OrderedMessage) error
+
+// デフォルトのパーティションあたりの最大滞留メッセージ数
+const DefaultMaxPartitionQueueSize = 1000
+
+// PartitionedQueue を初期化
+func NewPartitionedQueue(partitionCount int, partitioner Partitioner) *PartitionedQueue {
+	return NewPartitionedQueueWithBackpressure(partitionCount, partitioner, DefaultMaxPartitionQueueSize)
 }
 
-func (p *SequentialProcessor) Process(ctx context.Context, msg *Message) error {
-	partitionKey := msg.PartitionKey
-	if partitionKey == "" {
-		partitionKey = "default"
-	}
-	
-	// パーティション状態を取得または作成
-	state := p.getPartitionState(partitionKey)
-	
-	state.mu.Lock()
-	defer state.mu.Unlock()
-	
-	// 既に処理中の場合は待機
-	if state.processing {
-		state.pendingMessages = append(state.pendingMessages, msg)
-		p.sortPendingMessages(state)
-		return nil
+// パーティションごとの滞留上限を指定して PartitionedQueue を初期化
+func NewPartitionedQueueWithBackpressure(partitionCount int, partitioner Partitioner, maxQueueSize int) *PartitionedQueue {
+	partitions := make(map[int]*OrderedPartition, partitionCount)
+	for i := 0; i < partitionCount; i++ {
+		partitions[i] = &OrderedPartition{
+			id:           i,
+			messages:     make([]*OrderedMessage, 0),
+			consumers:    make([]*OrderedConsumer, 0),
+			backpressure: NewBackpressureController(maxQueueSize),
+		}
 	}
-	
-	// 順序チェック
-	if msg.SequenceNum == state.lastProcessedSeq+1 {
-		// 順序が正しい場合は即座に処理
-		return p.processMessage(ctx, msg, state)
-	} else if msg.SequenceNum > state.lastProcessedSeq+1 {
-		// 未来のメッセージの場合は保留
-		state.pendingMessages = append(state.pendingMessages, msg)
-		p.sortPendingMessages(state)
-		log.Printf("Message %s queued for future processing (seq: %d, expected: %d)", 
-			msg.ID, msg.SequenceNum, state.lastProcessedSeq+1)
-		return nil
-	} else {
-		// 過去のメッセージの場合は重複として処理
-		log.Printf("Duplicate or out-of-order message %s (seq: %d, last processed: %d)", 
-			msg.ID, msg.SequenceNum, state.lastProcessedSeq)
-		return nil
+
+	return &PartitionedQueue{
+		partitions:  partitions,
+		partitioner: partitioner,
 	}
 }
 
-func (p *SequentialProcessor) processMessage(ctx context.Context, msg *Message, state *PartitionState) error {
-	state.processing = true
-	defer func() { state.processing = false }()
-	
-	// 実際のメッセージ処理
-	err := p.doProcess(ctx, msg)
-	if err != nil {
-		return err
-	}
-	
-	state.lastProcessedSeq = msg.SequenceNum
-	log.Printf("Processed message %s (seq: %d)", msg.ID, msg.SequenceNum)
-	
-	// 保留中のメッセージを処理
-	p.processNextPendingMessages(ctx, state)
-	
-	return nil
+// メッセージを送信
+func (pq *PartitionedQueue) Send(message *OrderedMessage) error {
+	return pq.SendContext(context.Background(), message)
 }
 
-func (p *SequentialProcessor) processNextPendingMessages(ctx context.Context, state *PartitionState) {
-	for len(state.pendingMessages) > 0 {
-		nextMsg := state.pendingMessages[0]
-		
-		if nextMsg.SequenceNum == state.lastProcessedSeq+1 {
-			// 次のメッセージを処理
-			state.pendingMessages = state.pendingMessages[1:]
-			
-			err := p.doProcess(ctx, nextMsg)
-			if err != nil {
-				log.Printf("Failed to process pending message %s: %v", nextMsg.ID, err)
-				return
-			}
-			
-			state.lastProcessedSeq = nextMsg.SequenceNum
-			log.Printf("Processed pending message %s (seq: %d)", nextMsg.ID, nextMsg.SequenceNum)
-		} else {
-			// まだ順序が来ていない
-			break
-		}
+// メッセージを送信（パーティションが滞留上限を超えている間はブロック）
+func (pq *PartitionedQueue) SendContext(ctx context.Context, message *OrderedMessage) error {
+	partitionID := pq.partitioner.GetPartition(message.PartitionKey)
+	partition, ok := pq.GetPartition(partitionID)
+	if !ok {
+		return fmt.Errorf("partition %d not found", partitionID)
+	}
+
+	// 滞留上限に空きができるまで待ってから枠を確保する
+	if err := partition.backpressure.acquire(ctx); err != nil {
+		return err
 	}
+
+	return partition.AddMessage(message)
 }
 
-func (p *SequentialProcessor) doProcess(ctx context.Context, msg *Message) error {
-	// 実際の処理ロジック（例：注文処理）
-	time.Sleep(10 * time.Millisecond) // 処理時間をシミュレート
-	
-	if orderData, ok := msg.Data.(map[string]interface{}); ok {
-		orderID := orderData["order_id"]
-		log.Printf("Processing order %v in sequence %d", orderID, msg.SequenceNum)
+// コンシューマーを追加
+func (pq *PartitionedQueue) AddConsumer(partitionID int, consumer *OrderedConsumer) error {
+	partition, ok := pq.GetPartition(partitionID)
+	if !ok {
+		return fmt.Errorf("partition %d not found", partitionID)
 	}
-	
+
+	partition.mu.Lock()
+	consumer.partition = partition
+	consumer.backpressure = partition.backpressure
+	partition.consumers = append(partition.consumers, consumer)
+	partition.mu.Unlock()
+
+	// 追加前に届いていたメッセージを配信
+	partition.notifyConsumers()
 	return nil
 }
 
-func (p *SequentialProcessor) getPartitionState(partitionKey string) *PartitionState {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	
-	if state, exists := p.partitionState[partitionKey]; exists {
-		return state
-	}
-	
-	state := &PartitionState{
-		lastProcessedSeq: 0,
-		pendingMessages:  make([]*Message, 0),
-		processing:       false,
-	}
-	p.partitionState[partitionKey] = state
-	return state
+// パーティション取得
+func (pq *PartitionedQueue) GetPartition(partitionID int) (*OrderedPartition, bool) {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+
+	partition, ok := pq.partitions[partitionID]
+	return partition, ok
 }
 
-func (p *SequentialProcessor) sortPendingMessages(state *PartitionState) {
-	sort.Slice(state.pendingMessages, func(i, j int) bool {
-		return state.pendingMessages[i].SequenceNum < state.pendingMessages[j].SequenceNum
-	})
+// OrderedPartition の実装
+
+// メッセージをパーティションに追加
+func (op *OrderedPartition) AddMessage(message *OrderedMessage) error {
+	op.mu.Lock()
+	message.SequenceNo = op.getNextSequenceNo()
+	op.messages = append(op.messages, message)
+	op.mu.Unlock()
+
+	op.notifyConsumers()
+	return nil
 }
 
-func (p *SequentialProcessor) GetProcessorType() string {
-	return p.name
+// 次のシーケンス番号を取得
+func (op *OrderedPartition) getNextSequenceNo() int64 {
+	return atomic.AddInt64(&op.sequenceNo, 1)
 }
 
-func (p *SequentialProcessor) GetPartitionStats() map[string]PartitionStats {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	
-	stats := make(map[string]PartitionStats)
-	for key, state := range p.partitionState {
-		state.mu.RLock()
-		stats[key] = PartitionStats{
-			LastProcessedSeq: state.lastProcessedSeq,
-			PendingCount:     len(state.pendingMessages),
-			Processing:       state.processing,
+// コンシューマーに通知
+func (op *OrderedPartition) notifyConsumers() {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	// 受け取る余裕のあるコンシューマーへ順番に渡し、残りは次回の通知まで保持する
+	for _, consumer := range op.consumers {
+		for len(op.messages) > 0 && consumer.tryEnqueue(op.messages[0]) {
+			op.messages = op.messages[1:]
 		}
-		state.mu.RUnlock()
 	}
-	
-	return stats
 }
 
-type PartitionStats struct {
-	LastProcessedSeq int64 `json:"last_processed_seq"`
-	PendingCount     int   `json:"pending_count"`
-	Processing       bool  `json:"processing"`
+// 順序付きコンシューマー
+type OrderedConsumer struct {
+	id                string
+	partition         *OrderedPartition
+	lastProcessedSeq  int64
+	processingQueue   chan *OrderedMessage
+	handler           MessageHandler
+	backpressure      *BackpressureController
+	orderingBuffer    *OrderingBuffer
+	errors            chan error
+	failedCount       int64
+}
+
+// エラーチャネルのバッファサイズ（読まれずに溢れたエラーは破棄され、件数のみ記録される）
+const consumerErrorBufferSize = 100
+
+// OrderedConsumer を初期化
+func NewOrderedConsumer(id string, handler MessageHandler) *OrderedConsumer {
+	return &OrderedConsumer{
+		id:              id,
+		processingQueue: make(chan *OrderedMessage, 100),
+		handler:         handler,
+		errors:          make(chan error, consumerErrorBufferSize),
+	}
 }
 
-// DependencyOrderProcessor 依存関係ベースの順序プロセッサー
-type DependencyOrderProcessor struct {
-	name               string
-	processedMessages  map[string]bool
-	dependencyGraph    map[string]*DependencyNode
-	pendingByDependency map[string][]*Message
-	mu                 sync.RWMutex
+// ハンドラーが返したエラーを受け取るチャネル
+func (oc *OrderedConsumer) Errors() <-chan error {
+	return oc.errors
 }
 
-type DependencyNode struct {
-	MessageID    string
-	Dependencies []string
-	Dependents   []string
-	Processed    bool
+// ハンドラーが失敗したメッセージ数
+func (oc *OrderedConsumer) FailedCount() int64 {
+	return atomic.LoadInt64(&oc.failedCount)
 }
 
-func NewDependencyOrderProcessor(name string) *DependencyOrderProcessor {
-	return &DependencyOrderProcessor{
-		name:                name,
-		processedMessages:   make(map[string]bool),
-		dependencyGraph:     make(map[string]*DependencyNode),
-		pendingByDependency: make(map[string][]*Message),
-	}
-}
+// ハンドラーのエラーを記録（読み手がいなくても処理を止めない）
+func (oc *OrderedConsumer) recordError(message *OrderedMessage, err error) {
+	atomic.AddInt64(&oc.failedCount, 1)
 
-func (p *DependencyOrderProcessor) Process(ctx context.Context, msg *Message) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	
-	// 依存関係チェック
-	if !p.areDependenciesSatisfied(msg) {
-		// 依存関係が満たされていない場合は保留
-		return p.addToPendingList(msg)
+	select {
+	case oc.errors <- fmt.Errorf("consumer %s: message %s (seq %d): %w", oc.id, message.ID, message.SequenceNo, err):
+	default:
 	}
-	
-	// 依存関係が満たされている場合は処理
-	return p.processMessageWithDependents(ctx, msg)
 }
 
-func (p *DependencyOrderProcessor) areDependenciesSatisfied(msg *Message) bool {
-	for _, depID := range msg.Dependencies {
-		if !p.processedMessages[depID] {
-			return false
-		}
+// 処理キューに空きがあればメッセージを渡す
+func (oc *OrderedConsumer) tryEnqueue(message *OrderedMessage) bool {
+	select {
+	case oc.processingQueue <- message:
+		return true
+	default:
+		return false
 	}
-	return true
 }
 
-func (p *DependencyOrderProcessor) addToPendingList(msg *Message) error {
-	for _, depID := range msg.Dependencies {
-		if !p.processedMessages[depID] {
-			p.pendingByDependency[depID] = append(p.pendingByDependency[depID], msg)
-		}
+// コンシューマーを開始
+func (oc *OrderedConsumer) Start(ctx context.Context) error {
+	if oc.partition == nil {
+		return fmt.Errorf("consumer %s is not attached to a partition", oc.id)
 	}
-	
-	log.Printf("Message %s added to pending list due to unmet dependencies: %v", 
-		msg.ID, msg.Dependencies)
-	return nil
-}
 
-func (p *DependencyOrderProcessor) processMessageWithDependents(ctx context.Context, msg *Message) error {
-	// メッセージを処理
-	err := p.doProcess(ctx, msg)
-	if err != nil {
-		return err
-	}
-	
-	// 処理済みとしてマーク
-	p.processedMessages[msg.ID] = true
-	log.Printf("Processed message %s with dependencies %v", msg.ID, msg.Dependencies)
-	
-	// このメッセージに依存していた保留中のメッセージを処理
-	p.processDependentMessages(ctx, msg.ID)
-	
+	go oc.processMessages(ctx)
 	return nil
 }
 
-func (p *DependencyOrderProcessor) processDependentMessages(ctx context.Context, processedMsgID string) {
-	pendingMessages := p.pendingByDependency[processedMsgID]
-	delete(p.pendingByDependency, processedMsgID)
-	
-	for _, msg := range pendingMessages {
-		if p.areDependenciesSatisfied(msg) {
-			go func(m *Message) {
-				if err := p.processMessageWithDependents(ctx, m); err != nil {
-					log.Printf("Failed to process dependent message %s: %v", m.ID, err)
-				}
-			}(msg)
-		} else {
-			// まだ他の依存関係が満たされていない
-			p.addToPendingList(msg)
+// メッセージを順序通りに処理
+func (oc *OrderedConsumer) processMessages(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message := <-oc.processingQueue:
+			if err := oc.handler(ctx, message); err != nil {
+				oc.recordError(message, err)
+			}
+			atomic.StoreInt64(&oc.lastProcessedSeq, message.SequenceNo)
+
+			// 処理完了を通知して、待機中の送信者と滞留メッセージを進める
+			oc.backpressure.MessageProcessed()
+			oc.partition.notifyConsumers()
 		}
 	}
 }
 
-func (p *DependencyOrderProcessor) doProcess(ctx context.Context, msg *Message) error {
-	// 実際の処理ロジック
-	time.Sleep(5 * time.Millisecond)
-	
-	if data, ok := msg.Data.(map[string]interface{}); ok {
-		entityID := data["entity_id"]
-		log.Printf("Processing entity %v with dependencies resolved", entityID)
-	}
-	
-	return nil
+// バックプレッシャー制御
+type BackpressureController struct {
+	maxQueueSize     int
+	currentQueueSize int64
+	processingRate   *RateCalculator
+	mu               sync.RWMutex
+	throttle         chan struct{}
 }
 
-func (p *DependencyOrderProcessor) GetProcessorType() string {
-	return p.name
+// スロットル解除を取りこぼした場合に状態を再確認する間隔
+const throttleRecheckInterval = 10 * time.Millisecond
+
+// BackpressureController を初期化
+func NewBackpressureController(maxQueueSize int) *BackpressureController {
+	return NewBackpressureControllerWithTargetRate(maxQueueSize, 0)
 }
 
-func (p *DependencyOrderProcessor) GetPendingStats() map[string]int {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	
-	stats := make(map[string]int)
-	for depID, messages := range p.pendingByDependency {
-		stats[depID] = len(messages)
+// 目標処理レート（msg/sec）を指定して BackpressureController を初期化
+// targetRate が 0 の場合はキューサイズのみで制御する
+func NewBackpressureControllerWithTargetRate(maxQueueSize int, targetRate float64) *BackpressureController {
+	return &BackpressureController{
+		maxQueueSize:   maxQueueSize,
+		processingRate: NewRateCalculator(targetRate),
+		throttle:       make(chan struct{}, 1),
 	}
-	
-	return stats
 }
 
-// TimestampOrderProcessor タイムスタンプベースの順序プロセッサー
-type TimestampOrderProcessor struct {
-	name            string
-	window          time.Duration
-	messageBuffer   []*Message
-	lastProcessed   time.Time
-	mu              sync.RWMutex
-	ticker          *time.Ticker
-	stopCh          chan struct{}
+// スロットルが必要かチェック
+func (bp *BackpressureController) ShouldThrottle() bool {
+	return bp.throttledAt(atomic.LoadInt64(&bp.currentQueueSize))
 }
 
-func NewTimestampOrderProcessor(name string, window time.Duration) *TimestampOrderProcessor {
-	p := &TimestampOrderProcessor{
-		name:          name,
-		window:        window,
-		messageBuffer: make([]*Message, 0),
-		lastProcessed: time.Now(),
-		stopCh:        make(chan struct{}),
+// 滞留数が size のときにスロットルが必要か判定
+func (bp *BackpressureController) throttledAt(size int64) bool {
+	if size > int64(bp.maxQueueSize) {
+		return true
 	}
-	
-	// 定期的な処理を開始
-	p.startPeriodicProcessing()
-	
-	return p
+
+	// 処理が目標レートに追いついていない場合は、キューが半分埋まった時点で流入を絞る
+	target := bp.processingRate.GetTargetRate()
+	if target <= 0 || size == 0 || size < int64(bp.maxQueueSize/2) {
+		return false
+	}
+	rate := bp.processingRate.GetCurrentRate()
+	return rate > 0 && rate < target
 }
 
-func (p *TimestampOrderProcessor) startPeriodicProcessing() {
-	p.ticker = time.NewTicker(p.window / 2)
-	
-	go func() {
-		for {
-			select {
-			case <-p.ticker.C:
-				p.processBufferedMessages()
-			case <-p.stopCh:
-				return
-			}
+// 必要に応じて待機
+func (bp *BackpressureController) WaitIfNeeded(ctx context.Context) error {
+	ticker := time.NewTicker(throttleRecheckInterval)
+	defer ticker.Stop()
+
+	for bp.ShouldThrottle() {
+		select {
+		case <-bp.throttle:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-	}()
+	}
+	return nil
 }
 
-func (p *TimestampOrderProcessor) Process(ctx context.Context, msg *Message) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	
-	// バッファに追加
-	p.messageBuffer = append(p.messageBuffer, msg)
-	
-	// タイムスタンプでソート
-	sort.Slice(p.messageBuffer, func(i, j int) bool {
-		return p.messageBuffer[i].Timestamp.Before(p.messageBuffer[j].Timestamp)
-	})
-	
-	log.Printf("Message %s buffered for timestamp ordering", msg.ID)
-	return nil
+// メッセージ処理完了を記録
+func (bp *BackpressureController) MessageProcessed() {
+	bp.release()
+	bp.processingRate.RecordProcessing()
+	bp.notifyWaiters()
 }
 
-func (p *TimestampOrderProcessor) processBufferedMessages() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	
-	if len(p.messageBuffer) == 0 {
-		return
-	}
-	
-	cutoff := time.Now().Add(-p.window)
-	processableMessages := make([]*Message, 0)
-	remainingMessages := make([]*Message, 0)
-	
-	for _, msg := range p.messageBuffer {
-		if msg.Timestamp.Before(cutoff) || msg.Timestamp.Equal(cutoff) {
-			processableMessages = append(processableMessages, msg)
-		} else {
-			remainingMessages = append(remainingMessages, msg)
-		}
-	}
-	
-	// 処理可能なメッセージを処理
-	for _, msg := range processableMessages {
-		err := p.doProcess(context.Background(), msg)
-		if err != nil {
-			log.Printf("Failed to process buffered message %s: %v", msg.ID, err)
+// 滞留上限に空きができるまで待ち、送信するメッセージの枠を確保する
+// 待機中の送信者は滞留数に数えない
+func (bp *BackpressureController) acquire(ctx context.Context) error {
+	ticker := time.NewTicker(throttleRecheckInterval)
+	defer ticker.Stop()
+
+	for !bp.tryAcquire() {
+		select {
+		case <-bp.throttle:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
-	
-	p.messageBuffer = remainingMessages
-	
-	if len(processableMessages) > 0 {
-		log.Printf("Processed %d buffered messages", len(processableMessages))
-	}
-}
-
-func (p *TimestampOrderProcessor) doProcess(ctx context.Context, msg *Message) error {
-	// 実際の処理ロジック
-	time.Sleep(2 * time.Millisecond)
-	
-	log.Printf("Processed message %s at timestamp %v", msg.ID, msg.Timestamp)
 	return nil
 }
 
-func (p *TimestampOrderProcessor) GetProcessorType() string {
-	return p.name
-}
+// 枠に空きがあれば滞留数を1つ増やす（判定と加算は mu の下でまとめて行う）
+func (bp *BackpressureController) tryAcquire() bool {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
 
-func (p *TimestampOrderProcessor) Stop() {
-	if p.ticker != nil {
-		p.ticker.Stop()
+	size := atomic.LoadInt64(&bp.currentQueueSize)
+	if bp.throttledAt(size + 1) {
+		return false
 	}
-	close(p.stopCh)
-	
-	// 残りのメッセージを処理
-	p.processBufferedMessages()
-}
+	atomic.AddInt64(&bp.currentQueueSize, 1)
 
-func (p *TimestampOrderProcessor) GetBufferStats() BufferStats {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	
-	return BufferStats{
-		BufferedCount: len(p.messageBuffer),
-		OldestMessage: p.getOldestMessageTime(),
-		WindowSize:    p.window,
+	// まだ空きがあれば次の待機者にも再チェックを促す
+	if !bp.throttledAt(size + 2) {
+		bp.notifyWaiters()
 	}
+	return true
 }
 
-func (p *TimestampOrderProcessor) getOldestMessageTime() time.Time {
-	if len(p.messageBuffer) == 0 {
-		return time.Time{}
+// 待機中の送信者に再チェックを促す
+func (bp *BackpressureController) notifyWaiters() {
+	select {
+	case bp.throttle <- struct{}{}:
+	default:
 	}
-	return p.messageBuffer[0].Timestamp
 }
 
-type BufferStats struct {
-	BufferedCount int           `json:"buffered_count"`
-	OldestMessage time.Time     `json:"oldest_message"`
-	WindowSize    time.Duration `json:"window_size"`
+func (bp *BackpressureController) release() {
+	for {
+		size := atomic.LoadInt64(&bp.currentQueueSize)
+		if size <= 0 {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&bp.currentQueueSize, size, size-1) {
+			return
+		}
+	}
 }
 
-// PartitionedOrderManager パーティション分割による順序管理
-type PartitionedOrderManager struct {
-	processors map[string]*SequentialProcessor
-	mu         sync.RWMutex
+// レート計算器
+type RateCalculator struct {
+	processedCount int64
+	startTime      time.Time
+	lastUpdate     time.Time
+	currentRate    float64
+	targetRate     float64
+	mu             sync.RWMutex
 }
 
-func NewPartitionedOrderManager() *PartitionedOrderManager {
-	return &PartitionedOrderManager{
-		processors: make(map[string]*SequentialProcessor),
+// RateCalculator を初期化
+func NewRateCalculator(targetRate float64) *RateCalculator {
+	now := time.Now()
+	return &RateCalculator{
+		startTime:  now,
+		lastUpdate: now,
+		targetRate: targetRate,
 	}
 }
 
-func (m *PartitionedOrderManager) ProcessMessage(ctx context.Context, msg *Message) error {
-	partitionKey := msg.PartitionKey
-	if partitionKey == "" {
-		partitionKey = "default"
+// 現在の処理レートを取得
+func (rc *RateCalculator) GetCurrentRate() float64 {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	if rc.processedCount == 0 {
+		return 0
 	}
-	
-	processor := m.getOrCreateProcessor(partitionKey)
-	return processor.Process(ctx, msg)
+	elapsed := time.Since(rc.startTime).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(rc.processedCount) / elapsed
 }
 
-func (m *PartitionedOrderManager) getOrCreateProcessor(partitionKey string) *SequentialProcessor {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	
-	if processor, exists := m.processors[partitionKey]; exists {
-		return processor
-	}
-	
-	processor := NewSequentialProcessor(fmt.Sprintf("partition_%s", partitionKey))
-	m.processors[partitionKey] = processor
-	return processor
+// ターゲットレートを取得
+func (rc *RateCalculator) GetTargetRate() float64 {
+	return rc.targetRate
 }
 
-func (m *PartitionedOrderManager) GetAllPartitionStats() map[string]map[string]PartitionStats {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	
-	allStats := make(map[string]map[string]PartitionStats)
-	for partitionKey, processor := range m.processors {
-		allStats[partitionKey] = processor.GetPartitionStats()
+// 処理を記録
+func (rc *RateCalculator) RecordProcessing() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.processedCount++
+	rc.lastUpdate = time.Now()
+	if elapsed := rc.lastUpdate.Sub(rc.startTime).Seconds(); elapsed > 0 {
+		rc.currentRate = float64(rc.processedCount) / elapsed
 	}
-	
-	return allStats
 }
 
-// OrderViolationDetector 順序違反検出器
-type OrderViolationDetector struct {
-	expectedSeq map[string]int64
-	violations  []OrderViolation
-	mu          sync.RWMutex
+// 順序保証バッファ
+type OrderingBuffer struct {
+	buffer          map[int64]*OrderedMessage
+	expectedSeq     int64
+	maxBufferSize   int
+	deliveryChannel chan *OrderedMessage
+	mu              sync.RWMutex
 }
 
-type OrderViolation struct {
-	PartitionKey string    `json:"partition_key"`
-	MessageID    string    `json:"message_id"`
-	Expected     int64     `json:"expected_seq"`
-	Actual       int64     `json:"actual_seq"`
-	Timestamp    time.Time `json:"timestamp"`
-}
+// OrderingBuffer のエラー
+var (
+	ErrOrderingBufferFull = errors.New("ordering buffer is full")
+	ErrDuplicateSequence  = errors.New("sequence number already delivered or buffered")
+)
 
-func NewOrderViolationDetector() *OrderViolationDetector {
-	return &OrderViolationDetector{
-		expectedSeq: make(map[string]int64),
-		violations:  make([]OrderViolation, 0),
+// OrderingBuffer を初期化（シーケンス番号は1から始まる）
+func NewOrderingBuffer(maxBufferSize int) *OrderingBuffer {
+	if maxBufferSize < 1 {
+		maxBufferSize = 1
+	}
+	return &OrderingBuffer{
+		buffer:          make(map[int64]*OrderedMessage),
+		expectedSeq:     1,
+		maxBufferSize:   maxBufferSize,
+		// 欠番が埋まった際の一括配信（保持分＋到着分）がブロックしない容量にする
+		deliveryChannel: make(chan *OrderedMessage, maxBufferSize+1),
 	}
 }
 
-func (d *OrderViolationDetector) CheckOrder(msg *Message) bool {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	
-	partitionKey := msg.PartitionKey
-	if partitionKey == "" {
-		partitionKey = "default"
+// メッセージを追加
+// 期待するシーケンス番号なら即座に配信し、先の番号は欠番が埋まるまで保持する
+func (ob *OrderingBuffer) AddMessage(message *OrderedMessage) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if message.SequenceNo < ob.expectedSeq {
+		return fmt.Errorf("%w: %d", ErrDuplicateSequence, message.SequenceNo)
 	}
-	
-	expected := d.expectedSeq[partitionKey] + 1
-	
-	if msg.SequenceNum == expected {
-		d.expectedSeq[partitionKey] = msg.SequenceNum
-		return true
+	if message.SequenceNo == ob.expectedSeq {
+		return ob.deliverInOrder(message)
 	}
-	
-	// 順序違反を記録
-	violation := OrderViolation{
-		PartitionKey: partitionKey,
-		MessageID:    msg.ID,
-		Expected:     expected,
-		Actual:       msg.SequenceNum,
-		Timestamp:    time.Now(),
+
+	if _, exists := ob.buffer[message.SequenceNo]; exists {
+		return fmt.Errorf("%w: %d", ErrDuplicateSequence, message.SequenceNo)
 	}
-	
-	d.violations = append(d.violations, violation)
-	log.Printf("Order violation detected: %+v", violation)
-	
-	// 期待値を更新（ギャップを認識）
-	if msg.SequenceNum > expected {
-		d.expectedSeq[partitionKey] = msg.SequenceNum
+	if len(ob.buffer) >= ob.maxBufferSize {
+		return fmt.Errorf("%w: waiting for sequence %d", ErrOrderingBufferFull, ob.expectedSeq)
 	}
-	
-	return false
+	ob.buffer[message.SequenceNo] = message
+	return nil
 }
 
-func (d *OrderViolationDetector) GetViolations() []OrderViolation {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	
-	violations := make([]OrderViolation, len(d.violations))
-	copy(violations, d.violations)
-	return violations
-}
+// 順序通りに配信（ob.mu を保持した状態で呼び出す）
+// 配信後、バッファ内で連続する後続メッセージもまとめて配信する
+func (ob *OrderingBuffer) deliverInOrder(message *OrderedMessage) error {
+	for message != nil {
+		ob.deliveryChannel <- message
+		ob.expectedSeq++
 
-func (d *OrderViolationDetector) GetViolationCount() int {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return len(d.violations)
+		next, ok := ob.buffer[ob.expectedSeq]
+		if !ok {
+			break
+		}
+		delete(ob.buffer, ob.expectedSeq)
+		message = next
+	}
+	return nil
 }
 
-// OrderedMessage テスト用の順序付きメッセージ
-type OrderedMessage struct {
-	ID           string `json:"id"`
-	PartitionKey string `json:"partition_key"`
-	Data         []byte `json:"data"`
-	Sequence     int64  `json:"sequence"`
+// 配信チャネルを取得
+func (ob *OrderingBuffer) GetDeliveryChannel() <-chan *OrderedMessage {
+	return ob.deliveryChannel
 }
 
-// HashPartitioner ハッシュベースパーティショナー
+// ハッシュパーティショナー
 type HashPartitioner struct {
-	partitions int
+	partitionCount int
 }
 
-func NewHashPartitioner(partitions int) *HashPartitioner {
-	return &HashPartitioner{
-		partitions: partitions,
+// HashPartitioner を初期化
+func NewHashPartitioner(partitionCount int) *HashPartitioner {
+	if partitionCount < 1 {
+		partitionCount = 1
 	}
+	return &HashPartitioner{partitionCount: partitionCount}
 }
 
-func (h *HashPartitioner) GetPartition(key string) int {
-	if h.partitions <= 0 {
-		return 0
-	}
-	hash := 0
-	for _, c := range key {
-		hash = hash*31 + int(c)
-	}
-	if hash < 0 {
-		hash = -hash
+// パーティションを決定（同じキーは常に同じパーティションになる）
+func (hp *HashPartitioner) GetPartition(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(hp.partitionCount))
+}
+
+// 分散順序保証（ベクタークロック）
+type VectorClock map[string]int64
+
+type DistributedOrderingCoordinator struct {
+	nodeID       string
+	vectorClock  VectorClock
+	lamportClock int64
+	nodeClocks   map[string]int64
+	mu           sync.RWMutex
+}
+
+// メッセージのメタデータに付与する時刻情報のキー
+const (
+	MetadataSenderNode   = "sender_node"
+	MetadataVectorClock  = "vector_clock"
+	MetadataLamportClock = "lamport_clock"
+)
+
+// ErrCausalOrderViolation は因果的に先行するメッセージより先に届いたことを表す
+var ErrCausalOrderViolation = errors.New("message delivered before its causal dependencies")
+
+// DistributedOrderingCoordinator を初期化
+func NewDistributedOrderingCoordinator(nodeID string) *DistributedOrderingCoordinator {
+	return &DistributedOrderingCoordinator{
+		nodeID:      nodeID,
+		vectorClock: make(VectorClock),
+		nodeClocks:  make(map[string]int64),
 	}
-	return hash % h.partitions
 }
 
-// PartitionedQueue パーティション分割キュー
-type PartitionedQueue struct {
-	partitions  int
-	partitioner *HashPartitioner
-	queues      []chan *OrderedMessage
-	consumers   map[int]*OrderedConsumer
-	mu          sync.RWMutex
+// メッセージを送信
+// 自ノードのエントリを進め、ベクタークロックのコピーとランポートクロックをメタデータに付与する
+func (doc *DistributedOrderingCoordinator) SendMessage(message *OrderedMessage) error {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	doc.vectorClock[doc.nodeID]++
+	doc.lamportClock++
+
+	if message.Metadata == nil {
+		message.Metadata = make(map[string]interface{})
+	}
+	message.Metadata[MetadataSenderNode] = doc.nodeID
+	message.Metadata[MetadataVectorClock] = doc.copyVectorClock()
+	message.Metadata[MetadataLamportClock] = doc.lamportClock
+	message.Timestamp = time.Now()
+	return nil
 }
 
-func NewPartitionedQueue(partitions int, partitioner *HashPartitioner) *PartitionedQueue {
-	queues := make([]chan *OrderedMessage, partitions)
-	for i := range queues {
-		queues[i] = make(chan *OrderedMessage, 100)
+// メッセージを受信
+// 送信ノードの次のメッセージであり、かつ送信時点で送信ノードが知っていた他ノードの
+// メッセージを全て受信済みの場合のみ配信可能とする。そうでなければクロックを更新せずに
+// ErrCausalOrderViolation を返すので、呼び出し側は依存メッセージの受信後に再度渡す
+func (doc *DistributedOrderingCoordinator) ReceiveMessage(message *OrderedMessage) error {
+	sender, _ := message.Metadata[MetadataSenderNode].(string)
+	received, _ := message.Metadata[MetadataVectorClock].(VectorClock)
+	lamport, _ := message.Metadata[MetadataLamportClock].(int64)
+	if sender == "" || received == nil {
+		return fmt.Errorf("message %s has no vector clock metadata", message.ID)
 	}
-	
-	return &PartitionedQueue{
-		partitions:  partitions,
-		partitioner: partitioner,
-		queues:      queues,
-		consumers:   make(map[int]*OrderedConsumer),
+
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	if want := doc.vectorClock[sender] + 1; received[sender] != want {
+		return fmt.Errorf("%w: message %s from %s has clock %d, expected %d",
+			ErrCausalOrderViolation, message.ID, sender, received[sender], want)
+	}
+	for node, count := range received {
+		if node != sender && count > doc.vectorClock[node] {
+			return fmt.Errorf("%w: message %s depends on %d message(s) from %s, only %d received",
+				ErrCausalOrderViolation, message.ID, count, node, doc.vectorClock[node])
+		}
 	}
+
+	doc.updateVectorClock(received)
+	if lamport > doc.lamportClock {
+		doc.lamportClock = lamport
+	}
+	doc.lamportClock++
+	doc.nodeClocks[sender] = lamport
+	return nil
 }
 
-func (pq *PartitionedQueue) Send(message *OrderedMessage) error {
-	partition := pq.partitioner.GetPartition(message.PartitionKey)
-	select {
-	case pq.queues[partition] <- message:
-		return nil
-	default:
-		return fmt.Errorf("queue full for partition %d", partition)
+// ベクタークロックを更新（doc.mu を保持した状態で呼び出す）
+// 受信時は自ノードのエントリを進めず、送信イベントのみを数える
+func (doc *DistributedOrderingCoordinator) updateVectorClock(receivedClock VectorClock) {
+	for node, count := range receivedClock {
+		if count > doc.vectorClock[node] {
+			doc.vectorClock[node] = count
+		}
 	}
 }
 
-func (pq *PartitionedQueue) AddConsumer(partition int, consumer *OrderedConsumer) {
-	pq.mu.Lock()
-	defer pq.mu.Unlock()
-	pq.consumers[partition] = consumer
-	consumer.queue = pq.queues[partition]
+// ベクタークロックをコピー（doc.mu を保持した状態で呼び出す）
+func (doc *DistributedOrderingCoordinator) copyVectorClock() VectorClock {
+	clock := make(VectorClock, len(doc.vectorClock))
+	for node, count := range doc.vectorClock {
+		clock[node] = count
+	}
+	return clock
 }
 
-// OrderedConsumer 順序保証コンシューマー
-type OrderedConsumer struct {
-	name    string
-	handler func(context.Context, *OrderedMessage) error
-	queue   chan *OrderedMessage
+// 順序違反検出
+type OrderViolationDetector struct {
+	expectedSequences map[string]int64
+	violations        []OrderViolation
+	mu                sync.RWMutex
 }
 
-func NewOrderedConsumer(name string, handler func(context.Context, *OrderedMessage) error) *OrderedConsumer {
-	return &OrderedConsumer{
-		name:    name,
-		handler: handler,
+type OrderViolation struct {
+	PartitionKey     string    `json:"partition_key"`
+	ExpectedSequence int64     `json:"expected_sequence"`
+	ActualSequence   int64     `json:"actual_sequence"`
+	Timestamp        time.Time `json:"timestamp"`
+	Message          *OrderedMessage `json:"message"`
+}
+
+// OrderViolationDetector を初期化
+func NewOrderViolationDetector() *OrderViolationDetector {
+	return &OrderViolationDetector{
+		expectedSequences: make(map[string]int64),
+		violations:        make([]OrderViolation, 0),
 	}
 }
 
-func (oc *OrderedConsumer) Start(ctx context.Context) {
-	for {
-		select {
-		case msg := <-oc.queue:
-			if msg != nil {
-				oc.handler(ctx, msg)
-			}
-		case <-ctx.Done():
-			return
-		}
+// 順序違反をチェック
+// パーティションキーごとに次のシーケンス番号（初期値1）を期待し、一致しなければ違反を記録して返す。
+// 番号が飛んだ場合は以降の違反が連鎖しないよう受信した番号の次に合わせ、
+// 古い番号（重複・遅延）の場合は期待値を据え置く
+func (ovd *OrderViolationDetector) CheckMessage(message *OrderedMessage) *OrderViolation {
+	ovd.mu.Lock()
+	defer ovd.mu.Unlock()
+
+	expected, ok := ovd.expectedSequences[message.PartitionKey]
+	if !ok {
+		expected = 1
+	}
+
+	if message.SequenceNo == expected {
+		ovd.expectedSequences[message.PartitionKey] = expected + 1
+		return nil
+	}
+
+	violation := &OrderViolation{
+		PartitionKey:     message.PartitionKey,
+		ExpectedSequence: expected,
+		ActualSequence:   message.SequenceNo,
+		Timestamp:        time.Now(),
+		Message:          message,
 	}
+	ovd.recordViolation(violation)
+
+	if message.SequenceNo > expected {
+		ovd.expectedSequences[message.PartitionKey] = message.SequenceNo + 1
+	} else {
+		ovd.expectedSequences[message.PartitionKey] = expected
+	}
+	return violation
+}
+
+// 違反を記録（ovd.mu を保持した状態で呼び出す）
+func (ovd *OrderViolationDetector) recordViolation(violation *OrderViolation) {
+	ovd.violations = append(ovd.violations, *violation)
+}
+
+// 違反一覧を取得
+func (ovd *OrderViolationDetector) GetViolations() []OrderViolation {
+	ovd.mu.RLock()
+	defer ovd.mu.RUnlock()
+
+	result := make([]OrderViolation, len(ovd.violations))
+	copy(result, ovd.violations)
+	return result
 }
 
 func main() {
-	fmt.Println("Day 55: メッセージ順序保証")
-	fmt.Println("Run 'go test -v' to see the message ordering system in action")
+	// パーティショナーを作成
+	partitioner := NewHashPartitioner(4)
+	
+	// パーティション付きキューを作成
+	queue := NewPartitionedQueue(4, partitioner)
+	
+	// 順序違反検出器を作成
+	detector := NewOrderViolationDetector()
+	
+	// メッセージハンドラーを定義
+	handler := func(ctx context.Context, message *OrderedMessage) error {
+		// 順序違反をチェック
+		if violation := detector.CheckMessage(message); violation != nil {
+			fmt.Printf("Order violation detected: %+v\n", violation)
+		}
+		
+		fmt.Printf("Processed message: ID=%s, Seq=%d, Partition=%s\n", 
+			message.ID, message.SequenceNo, message.PartitionKey)
+		
+		// 模擬処理時間
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+	
+	// コンシューマーを作成して各パーティションに追加
+	for i := 0; i < 4; i++ {
+		consumer := NewOrderedConsumer(fmt.Sprintf("consumer-%d", i), handler)
+		queue.AddConsumer(i, consumer)
+		
+		// コンシューマーを開始
+		go consumer.Start(context.Background())
+	}
+	
+	// テストメッセージを送信
+	testMessages := []*OrderedMessage{
+		{ID: "msg-1", PartitionKey: "user-1", Data: []byte("data-1"), Timestamp: time.Now()},
+		{ID: "msg-2", PartitionKey: "user-1", Data: []byte("data-2"), Timestamp: time.Now()},
+		{ID: "msg-3", PartitionKey: "user-2", Data: []byte("data-3"), Timestamp: time.Now()},
+		{ID: "msg-4", PartitionKey: "user-1", Data: []byte("data-4"), Timestamp: time.Now()},
+		{ID: "msg-5", PartitionKey: "user-2", Data: []byte("data-5"), Timestamp: time.Now()},
+	}
+	
+	// メッセージを順番に送信
+	for _, message := range testMessages {
+		err := queue.Send(message)
+		if err != nil {
+			fmt.Printf("Failed to send message %s: %v\n", message.ID, err)
+		}
+		
+		// 少し間隔を開ける
+		time.Sleep(50 * time.Millisecond)
+	}
+	
+	// 処理時間を待つ
+	time.Sleep(2 * time.Second)
+	
+	// 違反があれば表示
+	violations := detector.GetViolations()
+	if len(violations) > 0 {
+		fmt.Printf("Total violations detected: %d\n", len(violations))
+		for _, violation := range violations {
+			fmt.Printf("Violation: %+v\n", violation)
+		}
+	} else {
+		fmt.Println("No order violations detected")
+	}
+	
+	fmt.Println("Message ordering test completed")
 }
\ No newline at end of file
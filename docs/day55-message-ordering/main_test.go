@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -180,6 +183,62 @@ func TestOrderingBuffer_SequenceHandling(t *testing.T) {
 	t.Log("Ordering buffer working correctly")
 }
 
+func TestOrderingBuffer_Reorder(t *testing.T) {
+	buffer := NewOrderingBuffer(10)
+
+	for _, seq := range []int64{3, 1, 2, 5, 4} {
+		message := &OrderedMessage{ID: fmt.Sprintf("msg-%d", seq), SequenceNo: seq}
+		if err := buffer.AddMessage(message); err != nil {
+			t.Fatalf("Failed to add message %d: %v", seq, err)
+		}
+	}
+
+	for want := int64(1); want <= 5; want++ {
+		select {
+		case message := <-buffer.GetDeliveryChannel():
+			if message.SequenceNo != want {
+				t.Errorf("Expected sequence %d, got %d", want, message.SequenceNo)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for sequence %d", want)
+		}
+	}
+
+	select {
+	case message := <-buffer.GetDeliveryChannel():
+		t.Errorf("Unexpected extra delivery: %d", message.SequenceNo)
+	default:
+	}
+
+	if err := buffer.AddMessage(&OrderedMessage{ID: "dup", SequenceNo: 2}); !errors.Is(err, ErrDuplicateSequence) {
+		t.Errorf("Expected ErrDuplicateSequence for already delivered sequence, got %v", err)
+	}
+}
+
+func TestOrderingBuffer_RejectsWhenFull(t *testing.T) {
+	buffer := NewOrderingBuffer(2)
+
+	// シーケンス1が届かないまま先の番号を積む
+	for _, seq := range []int64{2, 3} {
+		if err := buffer.AddMessage(&OrderedMessage{SequenceNo: seq}); err != nil {
+			t.Fatalf("Failed to buffer sequence %d: %v", seq, err)
+		}
+	}
+	if err := buffer.AddMessage(&OrderedMessage{SequenceNo: 4}); !errors.Is(err, ErrOrderingBufferFull) {
+		t.Fatalf("Expected ErrOrderingBufferFull, got %v", err)
+	}
+
+	// 欠番が埋まればバッファ分がまとめて配信される
+	if err := buffer.AddMessage(&OrderedMessage{SequenceNo: 1}); err != nil {
+		t.Fatalf("Failed to add sequence 1: %v", err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		if message := <-buffer.GetDeliveryChannel(); message.SequenceNo != want {
+			t.Errorf("Expected sequence %d, got %d", want, message.SequenceNo)
+		}
+	}
+}
+
 func TestBackpressureController_ThrottleControl(t *testing.T) {
 	controller := NewBackpressureController(5)
 	
@@ -233,6 +292,349 @@ func TestBackpressureController_ThrottleControl(t *testing.T) {
 	t.Log("Backpressure control working correctly")
 }
 
+func TestBackpressureController_FloodBlocksUntilDrained(t *testing.T) {
+	controller := NewBackpressureController(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 上限まで埋めておく
+	for i := 0; i < 3; i++ {
+		if err := controller.acquire(ctx); err != nil {
+			t.Fatalf("acquire failed: %v", err)
+		}
+	}
+
+	// 上限を大きく超える10件の送信者が一斉に枠を待つ
+	const producers = 10
+	var admitted int64
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := controller.acquire(ctx); err != nil {
+				t.Errorf("acquire failed: %v", err)
+				return
+			}
+			atomic.AddInt64(&admitted, 1)
+		}()
+	}
+
+	// 待機中の送信者は滞留数に数えられない
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt64(&admitted); n != 0 {
+		t.Fatalf("Expected all producers to block while the queue is full, %d admitted", n)
+	}
+	if size := atomic.LoadInt64(&controller.currentQueueSize); size != 3 {
+		t.Fatalf("Expected queue size 3 while producers wait, got %d", size)
+	}
+
+	// 1件処理されるごとに1件だけ受け入れられる
+	for want := int64(1); want <= producers; want++ {
+		controller.MessageProcessed()
+
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt64(&admitted) < want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d producers admitted, got %d", want, atomic.LoadInt64(&admitted))
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if size := atomic.LoadInt64(&controller.currentQueueSize); size > 3 {
+			t.Fatalf("Queue size %d exceeds the limit", size)
+		}
+	}
+
+	wg.Wait()
+	if size := atomic.LoadInt64(&controller.currentQueueSize); size != 3 {
+		t.Errorf("Expected queue size 3 after draining, got %d", size)
+	}
+}
+
+// singlePartitioner は全てのメッセージをパーティション0へ送る
+type singlePartitioner struct{}
+
+func (singlePartitioner) GetPartition(key string) int { return 0 }
+
+// newBackpressureTestQueue は指定したコントローラーを持つ単一パーティションのキューを作る
+func newBackpressureTestQueue(controller *BackpressureController) (*PartitionedQueue, *OrderedPartition) {
+	partition := &OrderedPartition{id: 0, backpressure: controller}
+	queue := &PartitionedQueue{
+		partitions:  map[int]*OrderedPartition{0: partition},
+		partitioner: singlePartitioner{},
+	}
+	return queue, partition
+}
+
+func TestPartitionedQueue_SendBlocksUnderBackpressure(t *testing.T) {
+	queue, partition := newBackpressureTestQueue(NewBackpressureController(2))
+
+	// 上限まではブロックせずに送信できる
+	for i := 1; i <= 2; i++ {
+		if err := queue.Send(&OrderedMessage{ID: fmt.Sprintf("msg-%d", i), PartitionKey: "user-1"}); err != nil {
+			t.Fatalf("Failed to send message %d: %v", i, err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Send(&OrderedMessage{ID: "msg-3", PartitionKey: "user-1"})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Send should block while the partition is full, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 遅いコンシューマーが1件処理を終えると枠が空き、送信が完了する
+	partition.backpressure.MessageProcessed()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Send failed after capacity freed up: %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Send should unblock once the consumer frees capacity")
+	}
+}
+
+func TestPartitionedQueue_SendMoreWaitersThanLimit(t *testing.T) {
+	queue, partition := newBackpressureTestQueue(NewBackpressureController(1))
+
+	if err := queue.Send(&OrderedMessage{ID: "msg-1", PartitionKey: "user-1"}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	// 上限(1)を超える2件の送信者が同時に待機する
+	done := make(chan error, 2)
+	for i := 2; i <= 3; i++ {
+		go func(i int) {
+			done <- queue.Send(&OrderedMessage{ID: fmt.Sprintf("msg-%d", i), PartitionKey: "user-1"})
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// 1件処理されるごとに待機中の送信者が1件ずつ受け入れられる
+	for i := 0; i < 2; i++ {
+		partition.backpressure.MessageProcessed()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Send failed: %v", err)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatalf("Waiting sender %d was never admitted, queue size %d", i+1, atomic.LoadInt64(&partition.backpressure.currentQueueSize))
+		}
+
+		if size := atomic.LoadInt64(&partition.backpressure.currentQueueSize); size != 1 {
+			t.Errorf("Expected queue size 1, got %d", size)
+		}
+	}
+}
+
+func TestPartitionedQueue_SendContextCancellation(t *testing.T) {
+	queue, partition := newBackpressureTestQueue(NewBackpressureController(2))
+
+	for i := 1; i <= 2; i++ {
+		if err := queue.Send(&OrderedMessage{ID: fmt.Sprintf("msg-%d", i), PartitionKey: "user-1"}); err != nil {
+			t.Fatalf("Failed to send message %d: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.SendContext(ctx, &OrderedMessage{ID: "msg-3", PartitionKey: "user-1"})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Cancelling the context should unblock Send")
+	}
+
+	// キャンセルされた送信の枠は解放されている
+	if size := atomic.LoadInt64(&partition.backpressure.currentQueueSize); size != 2 {
+		t.Errorf("Expected queue size 2 after cancelled send, got %d", size)
+	}
+}
+
+func TestBackpressureController_PacesBelowTargetRate(t *testing.T) {
+	paced := NewBackpressureControllerWithTargetRate(10, 1000)
+	unpaced := NewBackpressureController(10)
+
+	for _, controller := range []*BackpressureController{paced, unpaced} {
+		for i := 0; i < 7; i++ {
+			atomic.AddInt64(&controller.currentQueueSize, 1)
+		}
+	}
+
+	// 計測前はキューサイズのみで判断する
+	if paced.ShouldThrottle() {
+		t.Error("Should not throttle before any processing has been measured")
+	}
+
+	// 20msで1件しか処理できない遅いコンシューマー（約50msg/sec）
+	time.Sleep(20 * time.Millisecond)
+	paced.MessageProcessed()
+	unpaced.MessageProcessed()
+
+	if !paced.ShouldThrottle() {
+		t.Errorf("Should throttle when rate %.0f msg/sec is below target %.0f", paced.processingRate.GetCurrentRate(), paced.processingRate.GetTargetRate())
+	}
+	if unpaced.ShouldThrottle() {
+		t.Error("Without a target rate, throttling should depend only on the queue size")
+	}
+}
+
+func TestPartitionedQueue_SendPacedByTargetRate(t *testing.T) {
+	queue, partition := newBackpressureTestQueue(NewBackpressureControllerWithTargetRate(10, 1000))
+
+	for i := 1; i <= 6; i++ {
+		if err := queue.Send(&OrderedMessage{ID: fmt.Sprintf("msg-%d", i), PartitionKey: "user-1"}); err != nil {
+			t.Fatalf("Failed to send message %d: %v", i, err)
+		}
+	}
+
+	// 遅いコンシューマーが1件処理し、目標レートを下回っていることが計測される
+	time.Sleep(20 * time.Millisecond)
+	partition.backpressure.MessageProcessed()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Send(&OrderedMessage{ID: "msg-7", PartitionKey: "user-1"})
+	}()
+
+	// 上限(10)に達していなくても、キューが半分以上埋まっている間は流入が絞られる
+	select {
+	case err := <-done:
+		t.Fatalf("Send should be paced while processing is below the target rate, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// キューが半分を下回るまで処理されると送信が再開する
+	partition.backpressure.MessageProcessed()
+	partition.backpressure.MessageProcessed()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Send failed after the queue drained: %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Send should resume once the queue drains below half")
+	}
+}
+
+func TestPartitionedQueue_SlowConsumerBackpressure(t *testing.T) {
+	queue := NewPartitionedQueueWithBackpressure(1, NewHashPartitioner(1), 2)
+
+	// 遅いコンシューマー: release されるまで処理を終えない
+	release := make(chan struct{})
+	handler := func(ctx context.Context, message *OrderedMessage) error {
+		<-release
+		return nil
+	}
+
+	consumer := NewOrderedConsumer("slow-consumer", handler)
+	if err := queue.AddConsumer(0, consumer); err != nil {
+		t.Fatalf("Failed to add consumer: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	consumer.Start(ctx)
+
+	// 上限まではブロックせずに送信できる
+	for i := 1; i <= 2; i++ {
+		if err := queue.Send(&OrderedMessage{ID: fmt.Sprintf("msg-%d", i), PartitionKey: "user-1"}); err != nil {
+			t.Fatalf("Failed to send message %d: %v", i, err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Send(&OrderedMessage{ID: "msg-3", PartitionKey: "user-1"})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Send should block while the partition is full, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 1件処理されると枠が空き、送信が完了する
+	release <- struct{}{}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Send failed after capacity freed up: %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Send should unblock once the consumer frees capacity")
+	}
+
+	close(release)
+}
+
+func TestOrderedConsumer_RecordsHandlerErrors(t *testing.T) {
+	queue := NewPartitionedQueue(1, NewHashPartitioner(1))
+
+	handlerErr := errors.New("invalid payload")
+	handled := make(chan struct{}, 3)
+	consumer := NewOrderedConsumer("failing-consumer", func(ctx context.Context, message *OrderedMessage) error {
+		defer func() { handled <- struct{}{} }()
+		if message.SequenceNo == 2 {
+			return handlerErr
+		}
+		return nil
+	})
+	queue.AddConsumer(0, consumer)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	consumer.Start(ctx)
+
+	for i := 1; i <= 3; i++ {
+		if err := queue.Send(&OrderedMessage{ID: fmt.Sprintf("msg-%d", i), PartitionKey: "user-1"}); err != nil {
+			t.Fatalf("Failed to send message %d: %v", i, err)
+		}
+	}
+
+	select {
+	case err := <-consumer.Errors():
+		if !errors.Is(err, handlerErr) {
+			t.Errorf("Expected wrapped handler error, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "msg-2") {
+			t.Errorf("Expected error to identify msg-2, got %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Expected handler error to be reported")
+	}
+
+	// 失敗しても後続のメッセージは処理される
+	for i := 0; i < 3; i++ {
+		select {
+		case <-handled:
+		case <-time.After(1 * time.Second):
+			t.Fatalf("Only %d of 3 messages were handled", i)
+		}
+	}
+
+	if n := consumer.FailedCount(); n != 1 {
+		t.Errorf("Expected 1 failed message, got %d", n)
+	}
+}
+
 func TestHashPartitioner_Distribution(t *testing.T) {
 	partitioner := NewHashPartitioner(4)
 	
@@ -257,6 +659,67 @@ func TestHashPartitioner_Distribution(t *testing.T) {
 	t.Log("Hash partitioner distributing keys correctly")
 }
 
+func TestHashPartitioner_StableRouting(t *testing.T) {
+	const partitionCount = 4
+	partitioner := NewHashPartitioner(partitionCount)
+	queue := NewPartitionedQueue(partitionCount, partitioner)
+
+	// 複数の送信者から同時に100件送信
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			message := &OrderedMessage{
+				ID:           fmt.Sprintf("msg-%d", i),
+				PartitionKey: fmt.Sprintf("user-%d", i%10),
+			}
+			if err := queue.Send(message); err != nil {
+				t.Errorf("Failed to send message: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	keyPartitions := make(map[string]int)
+	for id := 0; id < partitionCount; id++ {
+		partition, ok := queue.GetPartition(id)
+		if !ok {
+			t.Fatalf("Partition %d not found", id)
+		}
+
+		partition.mu.RLock()
+		messages := partition.messages
+		partition.mu.RUnlock()
+
+		var lastSeq int64
+		for _, message := range messages {
+			if message.SequenceNo <= lastSeq {
+				t.Errorf("Partition %d: sequence %d not greater than %d", id, message.SequenceNo, lastSeq)
+			}
+			lastSeq = message.SequenceNo
+
+			if prev, seen := keyPartitions[message.PartitionKey]; seen && prev != id {
+				t.Errorf("Key %s routed to partitions %d and %d", message.PartitionKey, prev, id)
+			}
+			keyPartitions[message.PartitionKey] = id
+
+			if want := partitioner.GetPartition(message.PartitionKey); want != id {
+				t.Errorf("Key %s stored in partition %d, partitioner says %d", message.PartitionKey, id, want)
+			}
+		}
+		if lastSeq != int64(len(messages)) {
+			t.Errorf("Partition %d: expected contiguous sequences up to %d, last was %d", id, len(messages), lastSeq)
+		}
+		total += len(messages)
+	}
+
+	if total != 100 {
+		t.Errorf("Expected 100 messages across partitions, got %d", total)
+	}
+}
+
 func TestOrderViolationDetector_Detection(t *testing.T) {
 	detector := NewOrderViolationDetector()
 	
@@ -303,6 +766,55 @@ func TestOrderViolationDetector_Detection(t *testing.T) {
 	t.Log("Order violation detection working correctly")
 }
 
+func TestOrderViolationDetector_SkippedSequence(t *testing.T) {
+	detector := NewOrderViolationDetector()
+
+	// user-2 は独立して追跡される
+	for _, seq := range []int64{1, 2, 4, 5} {
+		detector.CheckMessage(&OrderedMessage{PartitionKey: "user-1", SequenceNo: seq})
+	}
+	for _, seq := range []int64{1, 2, 3} {
+		if v := detector.CheckMessage(&OrderedMessage{PartitionKey: "user-2", SequenceNo: seq}); v != nil {
+			t.Errorf("Unexpected violation for user-2: %+v", v)
+		}
+	}
+
+	violations := detector.GetViolations()
+	if len(violations) != 1 {
+		t.Fatalf("Expected exactly 1 violation, got %d: %+v", len(violations), violations)
+	}
+	v := violations[0]
+	if v.PartitionKey != "user-1" || v.ExpectedSequence != 3 || v.ActualSequence != 4 {
+		t.Errorf("Expected user-1 expected=3 actual=4, got %s expected=%d actual=%d",
+			v.PartitionKey, v.ExpectedSequence, v.ActualSequence)
+	}
+}
+
+func TestOrderViolationDetector_ConcurrentConsumers(t *testing.T) {
+	detector := NewOrderViolationDetector()
+
+	// 各コンシューマーが自分のパーティションキーを順序通りに処理する
+	var wg sync.WaitGroup
+	for c := 0; c < 8; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			key := fmt.Sprintf("partition-%d", c)
+			for seq := int64(1); seq <= 200; seq++ {
+				if v := detector.CheckMessage(&OrderedMessage{PartitionKey: key, SequenceNo: seq}); v != nil {
+					t.Errorf("Unexpected violation: %+v", v)
+					return
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if violations := detector.GetViolations(); len(violations) != 0 {
+		t.Errorf("Expected no violations, got %d", len(violations))
+	}
+}
+
 func TestRateCalculator_Calculation(t *testing.T) {
 	calculator := NewRateCalculator(100.0) // 100 msg/sec target
 	
@@ -372,6 +884,59 @@ func TestDistributedOrderingCoordinator_VectorClock(t *testing.T) {
 }
 
 // 統合テスト
+func TestDistributedOrderingCoordinator_CausalOrdering(t *testing.T) {
+	nodeA := NewDistributedOrderingCoordinator("node-a")
+	nodeB := NewDistributedOrderingCoordinator("node-b")
+	nodeC := NewDistributedOrderingCoordinator("node-c")
+
+	// A が m1 を送信し、B が受信してから m2 を送信（m2 は m1 に因果的に依存）
+	m1 := &OrderedMessage{ID: "m1"}
+	if err := nodeA.SendMessage(m1); err != nil {
+		t.Fatalf("node-a send failed: %v", err)
+	}
+	if err := nodeB.ReceiveMessage(m1); err != nil {
+		t.Fatalf("node-b receive m1 failed: %v", err)
+	}
+	m2 := &OrderedMessage{ID: "m2"}
+	if err := nodeB.SendMessage(m2); err != nil {
+		t.Fatalf("node-b send failed: %v", err)
+	}
+
+	stamped := m2.Metadata[MetadataVectorClock].(VectorClock)
+	if stamped["node-a"] != 1 || stamped["node-b"] != 1 {
+		t.Errorf("Expected m2 to carry {node-a:1 node-b:1}, got %v", stamped)
+	}
+
+	// C には m2 が先に届く
+	if err := nodeC.ReceiveMessage(m2); !errors.Is(err, ErrCausalOrderViolation) {
+		t.Fatalf("Expected causal violation for m2 before m1, got %v", err)
+	}
+	if len(nodeC.vectorClock) != 0 {
+		t.Errorf("Rejected message must not change the clock, got %v", nodeC.vectorClock)
+	}
+
+	// 依存する m1 を受信した後なら m2 を配信できる
+	if err := nodeC.ReceiveMessage(m1); err != nil {
+		t.Fatalf("node-c receive m1 failed: %v", err)
+	}
+	if err := nodeC.ReceiveMessage(m2); err != nil {
+		t.Fatalf("node-c receive m2 after m1 failed: %v", err)
+	}
+	if nodeC.vectorClock["node-a"] != 1 || nodeC.vectorClock["node-b"] != 1 || nodeC.vectorClock["node-c"] != 0 {
+		t.Errorf("Expected merged clock {node-a:1 node-b:1}, got %v", nodeC.vectorClock)
+	}
+
+	// ランポートクロックは受信した最大値より大きくなる
+	if m2Lamport := m2.Metadata[MetadataLamportClock].(int64); nodeC.lamportClock <= m2Lamport {
+		t.Errorf("Expected node-c lamport clock > %d, got %d", m2Lamport, nodeC.lamportClock)
+	}
+
+	// 同じメッセージの再配信も検出する
+	if err := nodeC.ReceiveMessage(m1); !errors.Is(err, ErrCausalOrderViolation) {
+		t.Errorf("Expected duplicate m1 to be flagged, got %v", err)
+	}
+}
+
 func TestIntegratedOrderingSystem(t *testing.T) {
 	partitioner := NewHashPartitioner(2)
 	queue := NewPartitionedQueue(2, partitioner)
@@ -1,10 +1,15 @@
+//go:build ignore
+
 package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,10 +30,13 @@ type Queue interface {
 	Close() error                                     // キューをクローズ
 }
 
+// ErrQueueClosed はクローズ済みのキューに対する操作で返される
+var ErrQueueClosed = errors.New("queue is closed")
+
 // InMemoryQueue インメモリキュー実装
 type InMemoryQueue struct {
 	messages []Message
-	mutex    sync.RWMutex
+	mutex    sync.Mutex
 	cond     *sync.Cond
 	closed   bool
 }
@@ -48,7 +56,7 @@ func (q *InMemoryQueue) Enqueue(msg Message) error {
 	defer q.mutex.Unlock()
 	
 	if q.closed {
-		return fmt.Errorf("queue is closed")
+		return ErrQueueClosed
 	}
 	
 	q.messages = append(q.messages, msg)
@@ -57,29 +65,30 @@ func (q *InMemoryQueue) Enqueue(msg Message) error {
 }
 
 // Dequeue メッセージをキューから取得
+// メッセージが届く、ctx がキャンセルされる、またはキューがクローズされるまで待機する。
+// クローズ後も残っているメッセージは取り出せ、空になると ErrQueueClosed を返す
 func (q *InMemoryQueue) Dequeue(ctx context.Context) (*Message, error) {
+	// キャンセル時に待機中のゴルーチンを起こす。
+	// ロックを取ってから Broadcast するので、ctx.Err() の確認と Wait の間に取りこぼすことはない
+	stop := context.AfterFunc(ctx, func() {
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		q.cond.Broadcast()
+	})
+	defer stop()
+
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 	
 	for len(q.messages) == 0 && !q.closed {
-		done := make(chan struct{})
-		go func() {
-			q.cond.Wait()
-			close(done)
-		}()
-		
-		q.mutex.Unlock()
-		select {
-		case <-ctx.Done():
-			q.mutex.Lock()
-			return nil, ctx.Err()
-		case <-done:
-			q.mutex.Lock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
+		q.cond.Wait()
 	}
 	
-	if q.closed && len(q.messages) == 0 {
-		return nil, fmt.Errorf("queue is closed")
+	if len(q.messages) == 0 {
+		return nil, ErrQueueClosed
 	}
 	
 	msg := q.messages[0]
@@ -89,12 +98,12 @@ func (q *InMemoryQueue) Dequeue(ctx context.Context) (*Message, error) {
 
 // Size キューのサイズを取得
 func (q *InMemoryQueue) Size() int {
-	q.mutex.RLock()
-	defer q.mutex.RUnlock()
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
 	return len(q.messages)
 }
 
-// Close キューをクローズ
+// Close キューをクローズし、待機中のコンシューマーを全て起こす
 func (q *InMemoryQueue) Close() error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
@@ -103,14 +112,66 @@ func (q *InMemoryQueue) Close() error {
 	return nil
 }
 
-// TODO: Consumer構造体を実装してください
+// priorityRank 優先度文字列の順位（大きいほど先に取り出す）。未知の値や空文字は "normal" 扱い
+var priorityRank = map[string]int{
+	"high":   2,
+	"normal": 1,
+	"low":    0,
+}
+
+// TODO: PriorityQueue構造体を実装してください
+// Message.Priority の高いものから取り出し、同じ優先度では投入順（FIFO）を保つ
+type PriorityQueue struct {
+	// TODO: 優先度付きキューに必要なフィールド
+	// - container/heap で扱うスライス（優先度の順位と投入順の通し番号を持たせる）
+	// - 次の通し番号
+	// - mutex と sync.Cond（InMemoryQueue と同様に Dequeue を待機させる）
+	// - closed: クローズ済みかどうか
+}
+
+// TODO: NewPriorityQueue関数を実装してください
+func NewPriorityQueue() *PriorityQueue {
+	// ここに実装
+	return nil
+}
+
+// TODO: Enqueue メソッドを実装してください
+func (q *PriorityQueue) Enqueue(msg Message) error {
+	// TODO: 優先度の順位と通し番号をつけてヒープに追加し、待機中のコンシューマーを起こす
+	return nil
+}
+
+// TODO: Dequeue メソッドを実装してください
+// 待機・キャンセル・クローズの扱いは InMemoryQueue と同じ
+func (q *PriorityQueue) Dequeue(ctx context.Context) (*Message, error) {
+	// TODO: 順位が高いもの、同順位なら通し番号が小さいものから取り出す
+	return nil, ErrQueueClosed
+}
+
+// TODO: Size メソッドを実装してください
+func (q *PriorityQueue) Size() int {
+	// ここに実装
+	return 0
+}
+
+// TODO: Close メソッドを実装してください
+func (q *PriorityQueue) Close() error {
+	// ここに実装
+	return nil
+}
+
+// Consumer コンシューマー構造体
 type Consumer struct {
-	// TODO: consumerに必要なフィールド
-	// - ID: consumer の識別子
-	// - queue: メッセージキュー
-	// - processor: メッセージ処理ロジック
-	// - stats: 統計情報
-	// - done: 停止シグナル
+	ID        string
+	queue     Queue
+	processor MessageProcessor
+	stats     ConsumerStats
+	done      chan struct{}      // Stop で閉じる停止シグナル
+	stopped   chan struct{}      // 処理ループの終了時に閉じる
+	cancel    context.CancelFunc // 待機中の Dequeue を中断する
+	started   bool
+	stopOnce  sync.Once
+	mutex     sync.RWMutex
 }
 
 // TODO: MessageProcessor型を定義してください
@@ -126,116 +187,332 @@ type ConsumerStats struct {
 	LastProcessedAt     time.Time     // 最後に処理した時刻
 }
 
-// TODO: NewConsumer関数を実装してください
+// NewConsumer 新しいコンシューマーを作成
 func NewConsumer(id string, queue Queue, processor MessageProcessor) *Consumer {
-	// ここに実装
-	return nil
+	return &Consumer{
+		ID:        id,
+		queue:     queue,
+		processor: processor,
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
 }
 
-// TODO: Start メソッドを実装してください
-// consumerを開始し、メッセージの処理を始める
+// Start コンシューマーを開始（処理ループはゴルーチンで動き、すぐに返る）
 func (c *Consumer) Start(ctx context.Context) {
-	// TODO: 以下の処理を実装
-	// 1. ゴルーチンでメッセージ処理ループを開始
-	// 2. キューからメッセージを継続的に取得
-	// 3. メッセージを処理し、統計情報を更新
-	// 4. エラーハンドリング
-	// 5. コンテキストキャンセレーション時の正常終了
+	ctx, cancel := context.WithCancel(ctx)
+
+	c.mutex.Lock()
+	if c.started {
+		c.mutex.Unlock()
+		cancel()
+		return
+	}
+	c.started = true
+	c.cancel = cancel
+	c.mutex.Unlock()
+
+	go func() {
+		defer close(c.stopped)
+		defer cancel()
+
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			// Dequeue が返すエラーはコンテキストの終了か ErrQueueClosed のみなので、どちらでもループを抜ける
+			msg, err := c.queue.Dequeue(ctx)
+			if err != nil {
+				return
+			}
+
+			start := time.Now()
+			err = c.processor(*msg)
+			c.record(time.Since(start), err)
+		}
+	}()
 }
 
-// TODO: Stop メソッドを実装してください
+// record 1件分の処理結果を統計に反映する
+func (c *Consumer) record(duration time.Duration, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stats.ProcessedCount++
+	c.stats.TotalProcessingTime += duration
+	c.stats.LastProcessedAt = time.Now()
+	if err != nil {
+		c.stats.ErrorCount++
+	}
+}
+
+// Stop コンシューマーを停止し、処理中のメッセージが終わるまで待つ（複数回呼んでもよい）
 func (c *Consumer) Stop() {
-	// ここに実装
+	c.mutex.RLock()
+	started, cancel := c.started, c.cancel
+	c.mutex.RUnlock()
+
+	c.stopOnce.Do(func() {
+		close(c.done)
+		if cancel != nil {
+			cancel()
+		}
+	})
+
+	if started {
+		<-c.stopped
+	}
 }
 
-// TODO: GetStats メソッドを実装してください
+// GetStats 統計情報を取得
 func (c *Consumer) GetStats() ConsumerStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.stats
+}
+
+// DefaultLatencyBuckets はレイテンシヒストグラムのデフォルトのバケット上限
+var DefaultLatencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// TODO: Metrics構造体を実装してください
+// メッセージ処理の成功・失敗数とレイテンシを集計する（複数コンシューマーで共有できる）
+type Metrics struct {
+	// TODO: メトリクスに必要なフィールド
+	// - 成功数・失敗数
+	// - 合計・最小・最大レイテンシ
+	// - バケット上限と各バケットの件数（上限超過用に1つ多く持つ）
+	// - mutex
+}
+
+// LatencyBucket ヒストグラムの1バケット（UpperBound 以下で、前のバケットの上限を超えるもの）
+type LatencyBucket struct {
+	UpperBound time.Duration // 最後のバケットは math.MaxInt64
+	Count      int64
+}
+
+// MetricsSnapshot ある時点の集計結果
+type MetricsSnapshot struct {
+	SuccessCount int64
+	FailureCount int64
+	TotalLatency time.Duration
+	MinLatency   time.Duration
+	MaxLatency   time.Duration
+	Histogram    []LatencyBucket
+}
+
+// TODO: NewMetrics関数を実装してください
+// buckets を省略した場合は DefaultLatencyBuckets を使う
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	// ここに実装
+	return nil
+}
+
+// TODO: Observe メソッドを実装してください
+// 1回の処理結果（レイテンシと成否）を記録する
+func (m *Metrics) Observe(latency time.Duration, err error) {
+	// ここに実装
+}
+
+// TODO: Snapshot メソッドを実装してください
+// 現在の集計結果のコピーを返す
+func (m *Metrics) Snapshot() MetricsSnapshot {
 	// ここに実装
-	return ConsumerStats{}
+	return MetricsSnapshot{}
+}
+
+// Count 記録された処理の総数
+func (s MetricsSnapshot) Count() int64 {
+	return s.SuccessCount + s.FailureCount
+}
+
+// AverageLatency 平均レイテンシ（記録がなければ0）
+func (s MetricsSnapshot) AverageLatency() time.Duration {
+	if s.Count() == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Count())
+}
+
+// TODO: InstrumentedProcessor関数を実装してください
+// next の処理時間と成否を metrics に記録するデコレーター
+func InstrumentedProcessor(next MessageProcessor, metrics *Metrics) MessageProcessor {
+	// TODO: 処理時間を計測し、metrics.Observe で記録する
+	return next
 }
 
-// TODO: ConsumerGroup構造体を実装してください
+// ConsumerGroup コンシューマー群
 type ConsumerGroup struct {
-	// TODO: consumer群の管理に必要なフィールド
-	// - consumers: 複数のconsumer
-	// - queue: 共有キュー
-	// - wg: consumer群の終了待機用
-	// - stats: 群全体の統計
+	consumers []*Consumer
+	queue     Queue
+	wg        sync.WaitGroup
 }
 
-// TODO: NewConsumerGroup関数を実装してください
+// NewConsumerGroup 新しいコンシューマー群を作成
 func NewConsumerGroup(queue Queue, consumerCount int, processor MessageProcessor) *ConsumerGroup {
-	// ここに実装
-	return nil
+	cg := &ConsumerGroup{
+		consumers: make([]*Consumer, consumerCount),
+		queue:     queue,
+	}
+	
+	for i := 0; i < consumerCount; i++ {
+		id := fmt.Sprintf("consumer-%d", i)
+		cg.consumers[i] = NewConsumer(id, queue, processor)
+	}
+	
+	return cg
 }
 
-// TODO: Start メソッドを実装してください（ConsumerGroup用）
+// Start 全てのコンシューマーを開始
 func (cg *ConsumerGroup) Start(ctx context.Context) {
-	// TODO: 全てのconsumerを開始
+	for _, consumer := range cg.consumers {
+		consumer.Start(ctx)
+
+		cg.wg.Add(1)
+		go func(c *Consumer) {
+			defer cg.wg.Done()
+			<-c.stopped
+		}(consumer)
+	}
 }
 
-// TODO: Stop メソッドを実装してください（ConsumerGroup用）
+// Stop 全てのコンシューマーを停止
 func (cg *ConsumerGroup) Stop() {
-	// TODO: 全てのconsumerを停止し、終了を待機
+	for _, consumer := range cg.consumers {
+		consumer.Stop()
+	}
+	cg.wg.Wait()
 }
 
-// TODO: GetAggregatedStats メソッドを実装してください
+// GetAggregatedStats 全コンシューマーの統計情報を集約
 func (cg *ConsumerGroup) GetAggregatedStats() map[string]ConsumerStats {
-	// TODO: 全consumerの統計情報を集約
-	return nil
+	stats := make(map[string]ConsumerStats)
+	for _, consumer := range cg.consumers {
+		stats[consumer.ID] = consumer.GetStats()
+	}
+	return stats
 }
 
-// TODO: Producer構造体を実装してください
+// Producer プロデューサー構造体
 type Producer struct {
-	// TODO: producerに必要なフィールド
-	// - queue: メッセージキュー
-	// - messageID: メッセージIDカウンター
+	queue     Queue
+	balancer  *LoadBalancer // 設定されている場合は queue の代わりに使う
+	messageID int64
 }
 
-// TODO: NewProducer関数を実装してください
+// NewProducer 新しいプロデューサーを作成
 func NewProducer(queue Queue) *Producer {
-	// ここに実装
-	return nil
+	return &Producer{
+		queue: queue,
+	}
 }
 
-// TODO: Produce メソッドを実装してください
+// NewBalancedProducer ロードバランサー経由で複数のキューに送信するプロデューサーを作成
+func NewBalancedProducer(balancer *LoadBalancer) *Producer {
+	return &Producer{
+		balancer: balancer,
+	}
+}
+
+// targetQueue 次のメッセージの送信先キューを返す
+func (p *Producer) targetQueue() Queue {
+	if p.balancer != nil {
+		return p.balancer.SelectQueue()
+	}
+	return p.queue
+}
+
+// Produce メッセージを生成してキューに送信
 func (p *Producer) Produce(data string) error {
-	// TODO: メッセージを生成してキューに送信
-	return nil
+	id := atomic.AddInt64(&p.messageID, 1)
+	msg := Message{
+		ID:        int(id),
+		Data:      data,
+		Timestamp: time.Now(),
+		Priority:  "normal",
+	}
+
+	queue := p.targetQueue()
+	if queue == nil {
+		return ErrNoQueueAvailable
+	}
+	return queue.Enqueue(msg)
 }
 
-// TODO: ProduceBatch メソッドを実装してください
+// ProduceBatch 複数のメッセージを一括送信
 func (p *Producer) ProduceBatch(dataList []string) error {
-	// TODO: 複数のメッセージを一括で送信
+	for _, data := range dataList {
+		if err := p.Produce(data); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// TODO: LoadBalancer構造体を実装してください
-type LoadBalancer struct {
-	// TODO: 負荷分散に必要なフィールド
-	// - queues: 複数のキュー
-	// - strategy: 負荷分散戦略
-	// - roundRobinIndex: ラウンドロビン用インデックス
-}
-
-// TODO: LoadBalanceStrategy型を定義してください
+// LoadBalanceStrategy 負荷分散戦略
 type LoadBalanceStrategy int
 
 const (
-	// TODO: 負荷分散戦略の定数を定義
-	// RoundRobin, LeastQueue, Random など
+	RoundRobin LoadBalanceStrategy = iota // 順番に選択
+	LeastQueue                            // Size() が最小のキューを選択
+	Random                                // ランダムに選択
 )
 
-// TODO: NewLoadBalancer関数を実装してください
+// ErrNoQueueAvailable は送信先のキューが1つもないときに返される
+var ErrNoQueueAvailable = errors.New("no queue available")
+
+// LoadBalancer 負荷分散器
+type LoadBalancer struct {
+	queues            []Queue
+	strategy          LoadBalanceStrategy
+	roundRobinIndex   int64
+	mutex             sync.RWMutex
+}
+
+// NewLoadBalancer 新しいロードバランサーを作成
 func NewLoadBalancer(queues []Queue, strategy LoadBalanceStrategy) *LoadBalancer {
-	// ここに実装
-	return nil
+	return &LoadBalancer{
+		queues:   queues,
+		strategy: strategy,
+	}
 }
 
-// TODO: SelectQueue メソッドを実装してください
+// SelectQueue 戦略に基づいてキューを選択
 func (lb *LoadBalancer) SelectQueue() Queue {
-	// TODO: 戦略に基づいてキューを選択
-	return nil
+	if len(lb.queues) == 0 {
+		return nil
+	}
+	
+	switch lb.strategy {
+	case RoundRobin:
+		index := (atomic.AddInt64(&lb.roundRobinIndex, 1) - 1) % int64(len(lb.queues))
+		return lb.queues[index]
+	case LeastQueue:
+		minSize := lb.queues[0].Size()
+		minIndex := 0
+		for i, queue := range lb.queues {
+			if size := queue.Size(); size < minSize {
+				minSize = size
+				minIndex = i
+			}
+		}
+		return lb.queues[minIndex]
+	case Random:
+		return lb.queues[rand.Intn(len(lb.queues))]
+	default:
+		return lb.queues[0]
+	}
 }
 
 // ===============================
@@ -4,8 +4,14 @@
 package main
 
 import (
+	"container/heap"
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,6 +34,9 @@ type Queue interface {
 	Close() error                                     // キューをクローズ
 }
 
+// ErrQueueClosed はクローズ済みのキューに対する操作で返される
+var ErrQueueClosed = errors.New("queue is closed")
+
 // InMemoryQueue インメモリキュー実装
 type InMemoryQueue struct {
 	messages []Message
@@ -51,7 +60,7 @@ func (q *InMemoryQueue) Enqueue(msg Message) error {
 	defer q.mutex.Unlock()
 	
 	if q.closed {
-		return fmt.Errorf("queue is closed")
+		return ErrQueueClosed
 	}
 	
 	q.messages = append(q.messages, msg)
@@ -60,34 +69,30 @@ func (q *InMemoryQueue) Enqueue(msg Message) error {
 }
 
 // Dequeue メッセージをキューから取得
+// メッセージが届く、ctx がキャンセルされる、またはキューがクローズされるまで待機する。
+// クローズ後も残っているメッセージは取り出せ、空になると ErrQueueClosed を返す
 func (q *InMemoryQueue) Dequeue(ctx context.Context) (*Message, error) {
+	// キャンセル時に待機中のゴルーチンを起こす。
+	// ロックを取ってから Broadcast するので、ctx.Err() の確認と Wait の間に取りこぼすことはない
+	stop := context.AfterFunc(ctx, func() {
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		q.cond.Broadcast()
+	})
+	defer stop()
+
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
 	
 	for len(q.messages) == 0 && !q.closed {
-		// check for context cancellation before waiting
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
-		
-		// Use a timeout-based approach instead of cond.Wait()
-		q.mutex.Unlock()
-		
-		// Wait for either a signal or context cancellation
-		select {
-		case <-ctx.Done():
-			q.mutex.Lock()
-			return nil, ctx.Err()
-		case <-time.After(10 * time.Millisecond):
-			// Reacquire lock and check again
-			q.mutex.Lock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
+		q.cond.Wait()
 	}
 	
-	if q.closed && len(q.messages) == 0 {
-		return nil, fmt.Errorf("queue is closed")
+	if len(q.messages) == 0 {
+		return nil, ErrQueueClosed
 	}
 	
 	msg := q.messages[0]
@@ -102,7 +107,7 @@ func (q *InMemoryQueue) Size() int {
 	return len(q.messages)
 }
 
-// Close キューをクローズ
+// Close キューをクローズし、待機中のコンシューマーを全て起こす
 func (q *InMemoryQueue) Close() error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
@@ -111,13 +116,133 @@ func (q *InMemoryQueue) Close() error {
 	return nil
 }
 
+// priorityRank 優先度文字列の順位（大きいほど先に取り出す）。未知の値や空文字は "normal" 扱い
+var priorityRank = map[string]int{
+	"high":   2,
+	"normal": 1,
+	"low":    0,
+}
+
+func rankOf(priority string) int {
+	if rank, ok := priorityRank[priority]; ok {
+		return rank
+	}
+	return priorityRank["normal"]
+}
+
+// priorityItem ヒープの要素。seq は同じ優先度内で投入順を保つための通し番号
+type priorityItem struct {
+	msg  Message
+	rank int
+	seq  uint64
+}
+
+// priorityHeap container/heap 用の実装
+type priorityHeap []priorityItem
+
+func (h priorityHeap) Len() int { return len(h) }
+func (h priorityHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank > h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+func (h priorityHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *priorityHeap) Push(x any)   { *h = append(*h, x.(priorityItem)) }
+func (h *priorityHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// PriorityQueue 優先度付きキュー実装
+// Message.Priority の高いものから取り出し、同じ優先度では投入順（FIFO）を保つ
+type PriorityQueue struct {
+	items   priorityHeap
+	nextSeq uint64
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	closed  bool
+}
+
+// NewPriorityQueue 新しい優先度付きキューを作成
+func NewPriorityQueue() *PriorityQueue {
+	q := &PriorityQueue{}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+// Enqueue メッセージを優先度に応じた位置に追加
+func (q *PriorityQueue) Enqueue(msg Message) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	heap.Push(&q.items, priorityItem{msg: msg, rank: rankOf(msg.Priority), seq: q.nextSeq})
+	q.nextSeq++
+	q.cond.Signal()
+	return nil
+}
+
+// Dequeue 最も優先度の高いメッセージを取得（待機とクローズの扱いは InMemoryQueue と同じ）
+func (q *PriorityQueue) Dequeue(ctx context.Context) (*Message, error) {
+	stop := context.AfterFunc(ctx, func() {
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		q.cond.Broadcast()
+	})
+	defer stop()
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for q.items.Len() == 0 && !q.closed {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		q.cond.Wait()
+	}
+
+	if q.items.Len() == 0 {
+		return nil, ErrQueueClosed
+	}
+
+	item := heap.Pop(&q.items).(priorityItem)
+	return &item.msg, nil
+}
+
+// Size キューのサイズを取得
+func (q *PriorityQueue) Size() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.items.Len()
+}
+
+// Close キューをクローズし、待機中のコンシューマーを全て起こす
+func (q *PriorityQueue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+	return nil
+}
+
 // Consumer コンシューマー構造体
 type Consumer struct {
 	ID        string
 	queue     Queue
 	processor MessageProcessor
 	stats     ConsumerStats
-	done      chan struct{}
+	done      chan struct{}      // Stop で閉じる停止シグナル
+	stopped   chan struct{}      // 処理ループの終了時に閉じる
+	cancel    context.CancelFunc // 待機中の Dequeue を中断する
+	started   bool
+	stopOnce  sync.Once
 	mutex     sync.RWMutex
 }
 
@@ -139,12 +264,28 @@ func NewConsumer(id string, queue Queue, processor MessageProcessor) *Consumer {
 		queue:     queue,
 		processor: processor,
 		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
 	}
 }
 
-// Start コンシューマーを開始
+// Start コンシューマーを開始（処理ループはゴルーチンで動き、すぐに返る）
 func (c *Consumer) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	c.mutex.Lock()
+	if c.started {
+		c.mutex.Unlock()
+		cancel()
+		return
+	}
+	c.started = true
+	c.cancel = cancel
+	c.mutex.Unlock()
+
 	go func() {
+		defer close(c.stopped)
+		defer cancel()
+
 		for {
 			select {
 			case <-c.done:
@@ -152,34 +293,50 @@ func (c *Consumer) Start(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			default:
-				msg, err := c.queue.Dequeue(ctx)
-				if err != nil {
-					if err == context.Canceled || err == context.DeadlineExceeded {
-						return
-					}
-					continue
-				}
-				
-				start := time.Now()
-				err = c.processor(*msg)
-				duration := time.Since(start)
-				
-				c.mutex.Lock()
-				c.stats.ProcessedCount++
-				c.stats.TotalProcessingTime += duration
-				c.stats.LastProcessedAt = time.Now()
-				if err != nil {
-					c.stats.ErrorCount++
-				}
-				c.mutex.Unlock()
 			}
+
+			// Dequeue が返すエラーはコンテキストの終了か ErrQueueClosed のみなので、どちらでもループを抜ける
+			msg, err := c.queue.Dequeue(ctx)
+			if err != nil {
+				return
+			}
+
+			start := time.Now()
+			err = c.processor(*msg)
+			c.record(time.Since(start), err)
 		}
 	}()
 }
 
-// Stop コンシューマーを停止
+// record 1件分の処理結果を統計に反映する
+func (c *Consumer) record(duration time.Duration, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stats.ProcessedCount++
+	c.stats.TotalProcessingTime += duration
+	c.stats.LastProcessedAt = time.Now()
+	if err != nil {
+		c.stats.ErrorCount++
+	}
+}
+
+// Stop コンシューマーを停止し、処理中のメッセージが終わるまで待つ（複数回呼んでもよい）
 func (c *Consumer) Stop() {
-	close(c.done)
+	c.mutex.RLock()
+	started, cancel := c.started, c.cancel
+	c.mutex.RUnlock()
+
+	c.stopOnce.Do(func() {
+		close(c.done)
+		if cancel != nil {
+			cancel()
+		}
+	})
+
+	if started {
+		<-c.stopped
+	}
 }
 
 // GetStats 統計情報を取得
@@ -189,6 +346,128 @@ func (c *Consumer) GetStats() ConsumerStats {
 	return c.stats
 }
 
+// DefaultLatencyBuckets はレイテンシヒストグラムのデフォルトのバケット上限
+var DefaultLatencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// Metrics メッセージ処理の成功・失敗数とレイテンシを集計する（複数コンシューマーで共有できる）
+type Metrics struct {
+	successCount int64
+	failureCount int64
+	totalLatency time.Duration
+	minLatency   time.Duration
+	maxLatency   time.Duration
+	buckets      []time.Duration // 各バケットの上限（昇順）
+	bucketCounts []int64         // 最後の要素は上限を超えたもの
+	mutex        sync.Mutex
+}
+
+// LatencyBucket ヒストグラムの1バケット（UpperBound 以下で、前のバケットの上限を超えるもの）
+type LatencyBucket struct {
+	UpperBound time.Duration // 最後のバケットは math.MaxInt64
+	Count      int64
+}
+
+// MetricsSnapshot ある時点の集計結果
+type MetricsSnapshot struct {
+	SuccessCount int64
+	FailureCount int64
+	TotalLatency time.Duration
+	MinLatency   time.Duration
+	MaxLatency   time.Duration
+	Histogram    []LatencyBucket
+}
+
+// NewMetrics 新しいメトリクスを作成（buckets を省略すると DefaultLatencyBuckets を使う）
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	sorted := append([]time.Duration(nil), buckets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return &Metrics{
+		buckets:      sorted,
+		bucketCounts: make([]int64, len(sorted)+1),
+	}
+}
+
+// Observe 1回の処理結果を記録
+func (m *Metrics) Observe(latency time.Duration, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err != nil {
+		m.failureCount++
+	} else {
+		m.successCount++
+	}
+
+	if m.successCount+m.failureCount == 1 || latency < m.minLatency {
+		m.minLatency = latency
+	}
+	if latency > m.maxLatency {
+		m.maxLatency = latency
+	}
+	m.totalLatency += latency
+
+	i := sort.Search(len(m.buckets), func(i int) bool { return latency <= m.buckets[i] })
+	m.bucketCounts[i]++
+}
+
+// Snapshot 現在の集計結果のコピーを返す
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	histogram := make([]LatencyBucket, len(m.bucketCounts))
+	for i, count := range m.bucketCounts {
+		upper := time.Duration(math.MaxInt64)
+		if i < len(m.buckets) {
+			upper = m.buckets[i]
+		}
+		histogram[i] = LatencyBucket{UpperBound: upper, Count: count}
+	}
+
+	return MetricsSnapshot{
+		SuccessCount: m.successCount,
+		FailureCount: m.failureCount,
+		TotalLatency: m.totalLatency,
+		MinLatency:   m.minLatency,
+		MaxLatency:   m.maxLatency,
+		Histogram:    histogram,
+	}
+}
+
+// Count 記録された処理の総数
+func (s MetricsSnapshot) Count() int64 {
+	return s.SuccessCount + s.FailureCount
+}
+
+// AverageLatency 平均レイテンシ（記録がなければ0）
+func (s MetricsSnapshot) AverageLatency() time.Duration {
+	if s.Count() == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Count())
+}
+
+// InstrumentedProcessor next の処理時間と成否を metrics に記録するデコレーター
+func InstrumentedProcessor(next MessageProcessor, metrics *Metrics) MessageProcessor {
+	return func(msg Message) error {
+		start := time.Now()
+		err := next(msg)
+		metrics.Observe(time.Since(start), err)
+		return err
+	}
+}
+
 // ConsumerGroup コンシューマー群
 type ConsumerGroup struct {
 	consumers []*Consumer
@@ -214,10 +493,12 @@ func NewConsumerGroup(queue Queue, consumerCount int, processor MessageProcessor
 // Start 全てのコンシューマーを開始
 func (cg *ConsumerGroup) Start(ctx context.Context) {
 	for _, consumer := range cg.consumers {
+		consumer.Start(ctx)
+
 		cg.wg.Add(1)
 		go func(c *Consumer) {
 			defer cg.wg.Done()
-			c.Start(ctx)
+			<-c.stopped
 		}(consumer)
 	}
 }
@@ -242,6 +523,7 @@ func (cg *ConsumerGroup) GetAggregatedStats() map[string]ConsumerStats {
 // Producer プロデューサー構造体
 type Producer struct {
 	queue     Queue
+	balancer  *LoadBalancer // 設定されている場合は queue の代わりに使う
 	messageID int64
 }
 
@@ -252,6 +534,21 @@ func NewProducer(queue Queue) *Producer {
 	}
 }
 
+// NewBalancedProducer ロードバランサー経由で複数のキューに送信するプロデューサーを作成
+func NewBalancedProducer(balancer *LoadBalancer) *Producer {
+	return &Producer{
+		balancer: balancer,
+	}
+}
+
+// targetQueue 次のメッセージの送信先キューを返す
+func (p *Producer) targetQueue() Queue {
+	if p.balancer != nil {
+		return p.balancer.SelectQueue()
+	}
+	return p.queue
+}
+
 // Produce メッセージを生成してキューに送信
 func (p *Producer) Produce(data string) error {
 	id := atomic.AddInt64(&p.messageID, 1)
@@ -261,7 +558,12 @@ func (p *Producer) Produce(data string) error {
 		Timestamp: time.Now(),
 		Priority:  "normal",
 	}
-	return p.queue.Enqueue(msg)
+
+	queue := p.targetQueue()
+	if queue == nil {
+		return ErrNoQueueAvailable
+	}
+	return queue.Enqueue(msg)
 }
 
 // ProduceBatch 複数のメッセージを一括送信
@@ -278,11 +580,14 @@ func (p *Producer) ProduceBatch(dataList []string) error {
 type LoadBalanceStrategy int
 
 const (
-	RoundRobin LoadBalanceStrategy = iota
-	LeastQueue
-	Random
+	RoundRobin LoadBalanceStrategy = iota // 順番に選択
+	LeastQueue                            // Size() が最小のキューを選択
+	Random                                // ランダムに選択
 )
 
+// ErrNoQueueAvailable は送信先のキューが1つもないときに返される
+var ErrNoQueueAvailable = errors.New("no queue available")
+
 // LoadBalancer 負荷分散器
 type LoadBalancer struct {
 	queues            []Queue
@@ -307,7 +612,7 @@ func (lb *LoadBalancer) SelectQueue() Queue {
 	
 	switch lb.strategy {
 	case RoundRobin:
-		index := atomic.AddInt64(&lb.roundRobinIndex, 1) % int64(len(lb.queues))
+		index := (atomic.AddInt64(&lb.roundRobinIndex, 1) - 1) % int64(len(lb.queues))
 		return lb.queues[index]
 	case LeastQueue:
 		minSize := lb.queues[0].Size()
@@ -319,11 +624,27 @@ func (lb *LoadBalancer) SelectQueue() Queue {
 			}
 		}
 		return lb.queues[minIndex]
+	case Random:
+		return lb.queues[rand.Intn(len(lb.queues))]
 	default:
 		return lb.queues[0]
 	}
 }
 
+// サンプルのメッセージ処理関数
+func sampleProcessor(msg Message) error {
+	// 処理時間の模擬
+	time.Sleep(time.Duration(50+msg.ID%100) * time.Millisecond)
+	
+	// ランダムなエラー発生（5%の確率）
+	if msg.ID%20 == 0 {
+		return fmt.Errorf("processing failed for message %d", msg.ID)
+	}
+	
+	log.Printf("Consumer processed message ID: %d, Data: %s", msg.ID, msg.Data)
+	return nil
+}
+
 func main() {
 	fmt.Println("Day 56: 競合コンシューマーパターン")
 	fmt.Println("Run 'go test -v' to see the competing consumer system in action")
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -165,6 +167,133 @@ func TestInMemoryQueue_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestInMemoryQueue_BlockedConsumers(t *testing.T) {
+	queue := NewInMemoryQueue()
+	if queue == nil {
+		t.Skip("InMemoryQueue not implemented yet")
+	}
+
+	const consumers = 5
+	type result struct {
+		msg *Message
+		err error
+	}
+	results := make(chan result, consumers)
+
+	var ready sync.WaitGroup
+	for i := 0; i < consumers; i++ {
+		ready.Add(1)
+		go func() {
+			ready.Done()
+			msg, err := queue.Dequeue(context.Background())
+			results <- result{msg, err}
+		}()
+	}
+	ready.Wait()
+	time.Sleep(20 * time.Millisecond)
+
+	select {
+	case r := <-results:
+		t.Fatalf("Dequeue should block on an empty queue, got %+v", r)
+	default:
+	}
+
+	// 3件投入すると3つのコンシューマーだけが起きる
+	for i := 1; i <= 3; i++ {
+		if err := queue.Enqueue(Message{ID: i}); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	seen := make(map[int]bool)
+	for i := 0; i < 3; i++ {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				t.Fatalf("Unexpected error: %v", r.err)
+			}
+			if seen[r.msg.ID] {
+				t.Errorf("Message %d delivered twice", r.msg.ID)
+			}
+			seen[r.msg.ID] = true
+		case <-time.After(time.Second):
+			t.Fatalf("Only %d consumers woke up after enqueue", i)
+		}
+	}
+
+	select {
+	case r := <-results:
+		t.Fatalf("Remaining consumers should still be blocked, got %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Close で残りのコンシューマーが ErrQueueClosed で戻る
+	queue.Close()
+	for i := 0; i < consumers-3; i++ {
+		select {
+		case r := <-results:
+			if !errors.Is(r.err, ErrQueueClosed) {
+				t.Errorf("Expected ErrQueueClosed, got %v", r.err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Blocked consumers did not wake up on Close")
+		}
+	}
+
+	if err := queue.Enqueue(Message{ID: 99}); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Expected ErrQueueClosed when enqueueing into a closed queue, got %v", err)
+	}
+}
+
+func TestPriorityQueue_DequeueOrder(t *testing.T) {
+	queue := NewPriorityQueue()
+	if queue == nil {
+		t.Skip("PriorityQueue not implemented yet")
+	}
+	defer queue.Close()
+
+	var _ Queue = queue
+
+	messages := []Message{
+		{ID: 1, Priority: "low"},
+		{ID: 2, Priority: "normal"},
+		{ID: 3, Priority: "high"},
+		{ID: 4, Priority: "low"},
+		{ID: 5}, // 空文字は normal 扱い
+		{ID: 6, Priority: "high"},
+		{ID: 7, Priority: "normal"},
+	}
+	for _, msg := range messages {
+		if err := queue.Enqueue(msg); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	if size := queue.Size(); size != len(messages) {
+		t.Errorf("Expected size %d, got %d", len(messages), size)
+	}
+
+	expected := []int{3, 6, 2, 5, 7, 1, 4}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for i, id := range expected {
+		msg, err := queue.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue %d failed: %v", i, err)
+		}
+		if msg.ID != id {
+			t.Errorf("Dequeue %d: expected message %d, got %d", i, id, msg.ID)
+		}
+	}
+
+	// 空になったらクローズで待機が解除されること
+	queue.Close()
+	if _, err := queue.Dequeue(ctx); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Expected ErrQueueClosed after close, got %v", err)
+	}
+}
+
 func TestConsumer_BasicProcessing(t *testing.T) {
 	queue := NewInMemoryQueue()
 	if queue == nil {
@@ -344,6 +473,77 @@ func TestConsumerGroup_LoadDistribution(t *testing.T) {
 	}
 }
 
+func TestConsumerGroup_StopWaitsForAllConsumers(t *testing.T) {
+	queue := NewInMemoryQueue()
+	if queue == nil {
+		t.Skip("InMemoryQueue not implemented yet")
+	}
+	defer queue.Close()
+
+	const numConsumers = 3
+	const numMessages = 50
+
+	var handled int64
+	done := make(chan struct{})
+	processor := func(msg Message) error {
+		if atomic.AddInt64(&handled, 1) == numMessages {
+			close(done)
+		}
+		return nil
+	}
+
+	consumerGroup := NewConsumerGroup(queue, numConsumers, processor)
+	if consumerGroup == nil {
+		t.Skip("ConsumerGroup not implemented yet")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 先に開始し、空のキューで待機しているコンシューマーへ投入する
+	consumerGroup.Start(ctx)
+	for i := 1; i <= numMessages; i++ {
+		queue.Enqueue(Message{ID: i, Data: fmt.Sprintf("Message %d", i), Timestamp: time.Now()})
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatalf("Timed out: only %d of %d messages handled", atomic.LoadInt64(&handled), numMessages)
+	}
+
+	// キューが空でブロック中のコンシューマーも Stop で確実に終了すること
+	stopped := make(chan struct{})
+	go func() {
+		consumerGroup.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return while consumers were waiting on an empty queue")
+	}
+
+	stats := consumerGroup.GetAggregatedStats()
+	if len(stats) != numConsumers {
+		t.Errorf("Expected %d consumers in stats, got %d", numConsumers, len(stats))
+	}
+
+	totalProcessed := int64(0)
+	for id, stat := range stats {
+		totalProcessed += stat.ProcessedCount
+		if stat.ProcessedCount > 0 && stat.LastProcessedAt.IsZero() {
+			t.Errorf("Consumer %s processed messages but LastProcessedAt is zero", id)
+		}
+	}
+	if totalProcessed != numMessages {
+		t.Errorf("Expected %d total messages processed, got %d", numMessages, totalProcessed)
+	}
+
+	// 2回目の Stop はすぐに返ること
+	consumerGroup.Stop()
+}
+
 func TestProducer_BasicOperations(t *testing.T) {
 	queue := NewInMemoryQueue()
 	if queue == nil {
@@ -410,6 +610,166 @@ func TestLoadBalancer_RoundRobinStrategy(t *testing.T) {
 	}
 }
 
+func TestLoadBalancer_LeastQueueStrategy(t *testing.T) {
+	queues := make([]Queue, 3)
+	for i := range queues {
+		queues[i] = NewInMemoryQueue()
+		if queues[i] == nil {
+			t.Skip("InMemoryQueue not implemented yet")
+		}
+		defer queues[i].Close()
+	}
+
+	// queue0: 3件, queue1: 1件, queue2: 2件
+	for i, n := range []int{3, 1, 2} {
+		for j := 0; j < n; j++ {
+			queues[i].Enqueue(Message{ID: j, Data: "preload", Timestamp: time.Now()})
+		}
+	}
+
+	loadBalancer := NewLoadBalancer(queues, LeastQueue)
+	if loadBalancer == nil {
+		t.Skip("LoadBalancer not implemented yet")
+	}
+
+	if selected := loadBalancer.SelectQueue(); selected != queues[1] {
+		t.Fatal("LeastQueue should select the queue with the fewest messages")
+	}
+
+	// プロデューサー経由の送信も最も空いているキューに届くこと
+	producer := NewBalancedProducer(loadBalancer)
+	if err := producer.Produce("balanced"); err != nil {
+		t.Fatalf("Produce failed: %v", err)
+	}
+	if size := queues[1].Size(); size != 2 {
+		t.Errorf("Expected emptiest queue to receive the message (size 2), got %d", size)
+	}
+
+	// 残り2件で全てのキューが同数になる
+	if err := producer.ProduceBatch([]string{"a", "b"}); err != nil {
+		t.Fatalf("ProduceBatch failed: %v", err)
+	}
+	for i, q := range queues {
+		if q.Size() != 3 {
+			t.Errorf("Expected queue %d to hold 3 messages after balancing, got %d", i, q.Size())
+		}
+	}
+}
+
+func TestInstrumentedProcessor(t *testing.T) {
+	metrics := NewMetrics()
+	if metrics == nil {
+		t.Skip("Metrics not implemented yet")
+	}
+
+	// Record what sampleProcessor actually did, inside the instrumented wrapper
+	var mu sync.Mutex
+	var observedErrors int64
+	var observedSleep time.Duration
+	recording := func(msg Message) error {
+		err := sampleProcessor(msg)
+		mu.Lock()
+		defer mu.Unlock()
+		observedSleep += time.Duration(50+msg.ID%100) * time.Millisecond
+		if err != nil {
+			observedErrors++
+		}
+		return err
+	}
+
+	// The same decorator is shared by every consumer in the group
+	processor := InstrumentedProcessor(recording, metrics)
+
+	queue := NewInMemoryQueue()
+	defer queue.Close()
+
+	// IDs 15..24 sleep 65..74ms and message 20 fails
+	const firstID, numMessages = 15, 10
+	for id := firstID; id < firstID+numMessages; id++ {
+		queue.Enqueue(Message{ID: id, Data: fmt.Sprintf("Message %d", id), Timestamp: time.Now()})
+	}
+
+	consumerGroup := NewConsumerGroup(queue, 3, processor)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	consumerGroup.Start(ctx)
+	deadline := time.Now().Add(3 * time.Second)
+	for metrics.Snapshot().Count() < numMessages && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	consumerGroup.Stop()
+
+	snapshot := metrics.Snapshot()
+	if snapshot.Count() != numMessages {
+		t.Fatalf("Expected %d recorded calls, got %d", numMessages, snapshot.Count())
+	}
+	if snapshot.FailureCount != observedErrors || observedErrors != 1 {
+		t.Errorf("Expected 1 failure as observed, got %d (observed %d)", snapshot.FailureCount, observedErrors)
+	}
+	if snapshot.SuccessCount != numMessages-observedErrors {
+		t.Errorf("Expected %d successes, got %d", numMessages-observedErrors, snapshot.SuccessCount)
+	}
+
+	// Latency covers at least the simulated processing time
+	if snapshot.TotalLatency < observedSleep {
+		t.Errorf("Total latency %v is below the simulated work %v", snapshot.TotalLatency, observedSleep)
+	}
+	if snapshot.MinLatency < 65*time.Millisecond || snapshot.MaxLatency < 74*time.Millisecond {
+		t.Errorf("Unexpected latency range: min=%v max=%v", snapshot.MinLatency, snapshot.MaxLatency)
+	}
+	if avg := snapshot.AverageLatency(); avg < snapshot.MinLatency || avg > snapshot.MaxLatency {
+		t.Errorf("Average latency %v outside [%v, %v]", avg, snapshot.MinLatency, snapshot.MaxLatency)
+	}
+
+	// Every call lands in exactly one bucket, none at or below 50ms
+	var bucketTotal int64
+	for _, bucket := range snapshot.Histogram {
+		bucketTotal += bucket.Count
+		if bucket.UpperBound <= 50*time.Millisecond && bucket.Count != 0 {
+			t.Errorf("Expected no calls in bucket <=%v, got %d", bucket.UpperBound, bucket.Count)
+		}
+	}
+	if bucketTotal != numMessages {
+		t.Errorf("Histogram holds %d calls, expected %d", bucketTotal, numMessages)
+	}
+	if len(snapshot.Histogram) != len(DefaultLatencyBuckets)+1 {
+		t.Errorf("Expected %d buckets including overflow, got %d", len(DefaultLatencyBuckets)+1, len(snapshot.Histogram))
+	}
+}
+
+func TestMetrics_Histogram(t *testing.T) {
+	metrics := NewMetrics(100*time.Millisecond, 10*time.Millisecond)
+	if metrics == nil {
+		t.Skip("Metrics not implemented yet")
+	}
+
+	metrics.Observe(5*time.Millisecond, nil)
+	metrics.Observe(10*time.Millisecond, nil) // upper bounds are inclusive
+	metrics.Observe(60*time.Millisecond, fmt.Errorf("failed"))
+	metrics.Observe(2*time.Second, nil)
+
+	snapshot := metrics.Snapshot()
+	want := []int64{2, 1, 1}
+	if len(snapshot.Histogram) != len(want) {
+		t.Fatalf("Expected %d buckets, got %d", len(want), len(snapshot.Histogram))
+	}
+	for i, bucket := range snapshot.Histogram {
+		if bucket.Count != want[i] {
+			t.Errorf("Bucket %d (<=%v): expected %d, got %d", i, bucket.UpperBound, want[i], bucket.Count)
+		}
+	}
+	if snapshot.Histogram[0].UpperBound != 10*time.Millisecond {
+		t.Errorf("Expected buckets to be sorted, first bound is %v", snapshot.Histogram[0].UpperBound)
+	}
+	if snapshot.SuccessCount != 3 || snapshot.FailureCount != 1 {
+		t.Errorf("Expected 3 successes and 1 failure, got %d/%d", snapshot.SuccessCount, snapshot.FailureCount)
+	}
+	if snapshot.MinLatency != 5*time.Millisecond || snapshot.MaxLatency != 2*time.Second {
+		t.Errorf("Unexpected latency range: min=%v max=%v", snapshot.MinLatency, snapshot.MaxLatency)
+	}
+}
+
 // ベンチマークテスト
 func BenchmarkInMemoryQueue_EnqueueDequeue(b *testing.B) {
 	queue := NewInMemoryQueue()
@@ -1,30 +1,326 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
 	"time"
 )
 
-// TODO: ServiceMetrics構造体を実装してください
+// metricsNamespace はこのサービスのメトリクス名に付与する名前空間
+const metricsNamespace = "myapp"
+
+// metricName は名前空間付きのメトリクス名を返す（prometheus.BuildFQName に相当）
+func metricName(name string) string {
+	return metricsNamespace + "_" + name
+}
+
+// ServiceMetrics はサービス全体のメトリクスを専用レジストリで管理する
 type ServiceMetrics struct {
-	// TODO: 以下のメトリクスを実装
-	// - httpRequestsTotal: HTTPリクエスト総数（method, path, statusラベル付き）
-	// - httpRequestDuration: HTTPリクエストの処理時間（method, pathラベル付き）
-	// - activeConnections: 現在のアクティブ接続数
-	// - databaseQueriesTotal: データベースクエリ総数（operation, statusラベル付き）
-	// - queueSize: キューサイズ（queueラベル付き）
+	registry *Registry
+
+	httpRequestsTotal    *CounterVec   // HTTPリクエスト総数（method, endpoint, statusラベル付き）
+	httpRequestDuration  *HistogramVec // HTTPリクエストの処理時間（method, endpointラベル付き）
+	activeConnections    *Gauge        // 現在のアクティブ接続数
+	databaseQueriesTotal *CounterVec   // データベースクエリ総数（operation, statusラベル付き）
+	queueSize            *GaugeVec     // キューサイズ（queueラベル付き）
 }
 
-// TODO: NewServiceMetrics関数を実装してください
-// Prometheusメトリクスを初期化し、レジストリに登録する
+// NewServiceMetrics はPrometheusメトリクスを初期化し、レジストリに登録する
 func NewServiceMetrics() *ServiceMetrics {
-	// ここに実装
+	m := &ServiceMetrics{
+		registry: NewRegistry(),
+		httpRequestsTotal: NewCounterVec(
+			metricName("http_requests_total"),
+			"Total number of HTTP requests",
+			[]string{"method", "endpoint", "status"},
+		),
+		httpRequestDuration: NewHistogramVec(
+			metricName("http_request_duration_seconds"),
+			"HTTP request duration in seconds",
+			nil,
+			[]string{"method", "endpoint"},
+		),
+		activeConnections: NewGauge(
+			metricName("active_connections"),
+			"Number of active connections",
+		),
+		databaseQueriesTotal: NewCounterVec(
+			metricName("database_queries_total"),
+			"Total number of database queries",
+			[]string{"operation", "status"},
+		),
+		queueSize: NewGaugeVec(
+			metricName("queue_size"),
+			"Number of items in the queue",
+			[]string{"queue"},
+		),
+	}
+
+	m.registry.MustRegister(
+		m.httpRequestsTotal,
+		m.httpRequestDuration,
+		m.activeConnections,
+		m.databaseQueriesTotal,
+		m.queueSize,
+	)
+	return m
+}
+
+// Registry はメトリクスが登録されているレジストリを返す
+func (m *ServiceMetrics) Registry() *Registry {
+	return m.registry
+}
+
+// RecordRequest はHTTPリクエストを1件記録する
+func (m *ServiceMetrics) RecordRequest(method, endpoint string, status int) {
+	m.httpRequestsTotal.WithLabelValues(method, endpoint, strconv.Itoa(status)).Inc()
+}
+
+// SystemMetrics はGoランタイムから読み取るシステムメトリクス
+type SystemMetrics struct {
+	memoryUsage    *GaugeVec // メモリ使用量（typeラベル付き）
+	goroutineCount *Gauge    // 現在のゴルーチン数
+}
+
+// NewSystemMetrics はシステムメトリクスを作成し、指定したレジストリに登録する
+func NewSystemMetrics(registry *Registry) *SystemMetrics {
+	m := &SystemMetrics{
+		memoryUsage: NewGaugeVec(
+			metricName("memory_usage_bytes"),
+			"Memory usage in bytes",
+			[]string{"type"},
+		),
+		goroutineCount: NewGauge(
+			metricName("goroutines"),
+			"Number of goroutines that currently exist",
+		),
+	}
+
+	registry.MustRegister(m.memoryUsage, m.goroutineCount)
+	return m
+}
+
+// UpdateMemoryUsage は runtime.MemStats からメモリ使用量を更新する
+func (m *SystemMetrics) UpdateMemoryUsage() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	m.memoryUsage.WithLabelValues("heap").Set(float64(stats.HeapInuse))
+	m.memoryUsage.WithLabelValues("stack").Set(float64(stats.StackInuse))
+	m.memoryUsage.WithLabelValues("sys").Set(float64(stats.Sys))
+}
+
+// UpdateGoroutineCount は現在のゴルーチン数を更新する
+func (m *SystemMetrics) UpdateGoroutineCount() {
+	m.goroutineCount.Set(float64(runtime.NumGoroutine()))
+}
+
+// BuildInfo はアプリケーションのビルド情報
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+}
+
+// CustomCollector はビルド情報と稼働時間を収集時に計算して提供するコレクター
+type CustomCollector struct {
+	buildInfo  BuildInfo
+	startTime  time.Time
+	infoDesc   *Desc
+	uptimeDesc *Desc
+}
+
+// NewCustomCollector はビルド情報と稼働時間のコレクターを作成する
+func NewCustomCollector(buildInfo BuildInfo) *CustomCollector {
+	return &CustomCollector{
+		buildInfo: buildInfo,
+		startTime: time.Now(),
+		infoDesc: NewDesc(
+			metricName("app_info"),
+			"Application build information",
+			[]string{"version", "commit", "build_date"},
+		),
+		uptimeDesc: NewDesc(
+			metricName("app_uptime_seconds"),
+			"Time since the application started in seconds",
+			nil,
+		),
+	}
+}
+
+// Describe はこのコレクターが提供するメトリクスの記述子を送信する
+func (c *CustomCollector) Describe(ch chan<- *Desc) {
+	ch <- c.infoDesc
+	ch <- c.uptimeDesc
+}
+
+// Collect は収集時点の値でメトリクスを作成して送信する
+func (c *CustomCollector) Collect(ch chan<- MetricFamily) {
+	ch <- MustNewConstMetric(c.infoDesc, GaugeType, 1,
+		c.buildInfo.Version, c.buildInfo.Commit, c.buildInfo.BuildDate)
+	ch <- MustNewConstMetric(c.uptimeDesc, GaugeType, time.Since(c.startTime).Seconds())
+}
+
+// ErrUnknownComparator はアラートルールの比較演算子が不正であることを表す
+var ErrUnknownComparator = errors.New("unknown comparator")
+
+// AlertRule はメトリクスに対するアラート条件
+type AlertRule struct {
+	Name       string
+	Query      string // 監視するメトリクス名（一致する全系列の合計値で評価する）
+	Comparator string // ">", ">=", "<", "<=", "==", "!="
+	Threshold  float64
+	Severity   string
+}
+
+// Alert は条件を満たしたルールの通知内容
+type Alert struct {
+	RuleName string
+	Severity string
+	Value    float64
+	FiredAt  time.Time
+}
+
+// AlertNotifier はアラートの通知先
+type AlertNotifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// AlertManager は定期的にメトリクスを収集してアラートルールを評価する
+type AlertManager struct {
+	gatherer Gatherer
+	notifier AlertNotifier
+	rules    []AlertRule
+	firing   map[string]bool // 発火中のルール（解消されるまで再通知しない）
+	mu       sync.Mutex
+}
+
+// NewAlertManager はアラートマネージャーを作成する
+func NewAlertManager(gatherer Gatherer, notifier AlertNotifier) *AlertManager {
+	return &AlertManager{
+		gatherer: gatherer,
+		notifier: notifier,
+		firing:   make(map[string]bool),
+	}
+}
+
+// AddRule は評価対象のルールを追加する
+func (am *AlertManager) AddRule(rule AlertRule) error {
+	if _, err := compare(rule.Comparator, 0, 0); err != nil {
+		return fmt.Errorf("rule %s: %w", rule.Name, err)
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.rules = append(am.rules, rule)
 	return nil
 }
 
+// StartMonitoring は interval ごとに全ルールを評価する。ctx がキャンセルされるまでブロックする
+func (am *AlertManager) StartMonitoring(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		am.evaluateRules(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluateRules は全ルールを評価し、新たに条件を満たしたルールを通知する
+func (am *AlertManager) evaluateRules(ctx context.Context) {
+	am.mu.Lock()
+	rules := make([]AlertRule, len(am.rules))
+	copy(rules, am.rules)
+	am.mu.Unlock()
+
+	for _, rule := range rules {
+		value, err := am.evaluateRule(rule)
+		if err != nil {
+			log.Printf("alert rule %s: %v", rule.Name, err)
+			continue
+		}
+
+		firing := am.checkAlert(rule, value)
+
+		am.mu.Lock()
+		alreadyFiring := am.firing[rule.Name]
+		am.firing[rule.Name] = firing
+		am.mu.Unlock()
+
+		if !firing || alreadyFiring {
+			continue
+		}
+
+		alert := Alert{RuleName: rule.Name, Severity: rule.Severity, Value: value, FiredAt: time.Now()}
+		if err := am.notifier.Notify(ctx, alert); err != nil {
+			log.Printf("failed to notify alert %s: %v", rule.Name, err)
+		}
+	}
+}
+
+// evaluateRule はルールの Query に一致するサンプルの合計値を返す
+func (am *AlertManager) evaluateRule(rule AlertRule) (float64, error) {
+	families, err := am.gatherer.Gather()
+	if err != nil {
+		return 0, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	var (
+		total float64
+		found bool
+	)
+	for _, mf := range families {
+		for _, sample := range mf.Samples {
+			if sample.Name == rule.Query {
+				total += sample.Value
+				found = true
+			}
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("metric %s not found", rule.Query)
+	}
+	return total, nil
+}
+
+// checkAlert は値がルールの閾値条件を満たすかどうかを返す
+func (am *AlertManager) checkAlert(rule AlertRule, value float64) bool {
+	breached, err := compare(rule.Comparator, value, rule.Threshold)
+	return err == nil && breached
+}
+
+func compare(comparator string, value, threshold float64) (bool, error) {
+	switch comparator {
+	case ">":
+		return value > threshold, nil
+	case ">=":
+		return value >= threshold, nil
+	case "<":
+		return value < threshold, nil
+	case "<=":
+		return value <= threshold, nil
+	case "==":
+		return value == threshold, nil
+	case "!=":
+		return value != threshold, nil
+	default:
+		return false, fmt.Errorf("%w: %q", ErrUnknownComparator, comparator)
+	}
+}
+
 // TODO: MiddlewareHandler構造体を実装してください
 type MiddlewareHandler struct {
 	metrics *ServiceMetrics
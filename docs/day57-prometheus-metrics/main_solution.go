@@ -5,47 +5,70 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"net/http"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 // 模擬Prometheusメトリクス構造体
 type CounterVec struct {
-	metrics map[string]float64
-	mu      sync.RWMutex
+	desc        metricDesc
+	metrics     map[string]float64
+	labelValues map[string][]string
+	mu          sync.RWMutex
 }
 
 type HistogramVec struct {
-	metrics map[string][]float64
-	buckets []float64
-	mu      sync.RWMutex
+	desc        metricDesc
+	metrics     map[string][]float64
+	labelValues map[string][]string
+	buckets     []float64
+	mu          sync.RWMutex
 }
 
 type Gauge struct {
+	desc  metricDesc
 	value float64
 	mu    sync.RWMutex
 }
 
 type GaugeVec struct {
-	metrics map[string]float64
-	mu      sync.RWMutex
+	desc        metricDesc
+	metrics     map[string]float64
+	labelValues map[string][]string
+	mu          sync.RWMutex
+}
+
+// metricDesc はエクスポジション出力に必要なメトリクスのメタデータ
+type metricDesc struct {
+	name   string
+	help   string
+	labels []string
 }
 
 // CounterVec実装
 func NewCounterVec(name, help string, labels []string) *CounterVec {
 	return &CounterVec{
-		metrics: make(map[string]float64),
+		desc:        metricDesc{name: name, help: help, labels: labels},
+		metrics:     make(map[string]float64),
+		labelValues: make(map[string][]string),
 	}
 }
 
 func (c *CounterVec) WithLabelValues(values ...string) *Counter {
 	key := joinLabels(values)
+	c.mu.Lock()
+	c.labelValues[key] = values
+	c.mu.Unlock()
 	return &Counter{vec: c, key: key}
 }
 
@@ -90,13 +113,18 @@ func NewHistogramVec(name, help string, buckets []float64, labels []string) *His
 		buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
 	}
 	return &HistogramVec{
-		metrics: make(map[string][]float64),
-		buckets: buckets,
+		desc:        metricDesc{name: name, help: help, labels: labels},
+		metrics:     make(map[string][]float64),
+		labelValues: make(map[string][]string),
+		buckets:     buckets,
 	}
 }
 
 func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
 	key := joinLabels(values)
+	h.mu.Lock()
+	h.labelValues[key] = values
+	h.mu.Unlock()
 	return &Histogram{vec: h, key: key}
 }
 
@@ -159,7 +187,7 @@ func (h *Histogram) Observe(value float64) {
 
 // Gauge実装
 func NewGauge(name, help string) *Gauge {
-	return &Gauge{}
+	return &Gauge{desc: metricDesc{name: name, help: help}}
 }
 
 func (g *Gauge) Set(value float64) {
@@ -195,12 +223,17 @@ func (g *Gauge) Get() float64 {
 // GaugeVec実装
 func NewGaugeVec(name, help string, labels []string) *GaugeVec {
 	return &GaugeVec{
-		metrics: make(map[string]float64),
+		desc:        metricDesc{name: name, help: help, labels: labels},
+		metrics:     make(map[string]float64),
+		labelValues: make(map[string][]string),
 	}
 }
 
 func (g *GaugeVec) WithLabelValues(values ...string) *GaugeMetric {
 	key := joinLabels(values)
+	g.mu.Lock()
+	g.labelValues[key] = values
+	g.mu.Unlock()
 	return &GaugeMetric{vec: g, key: key}
 }
 
@@ -364,6 +397,402 @@ func (mc *MetricsCollector) Gather() map[string]interface{} {
 	return result
 }
 
+// Prometheusテキスト形式での公開
+
+// MetricType はメトリクスファミリーの種類
+type MetricType string
+
+const (
+	CounterType   MetricType = "counter"
+	GaugeType     MetricType = "gauge"
+	HistogramType MetricType = "histogram"
+)
+
+// Sample はメトリクスファミリー内の1つの値
+type Sample struct {
+	Name   string
+	Labels []LabelPair
+	Value  float64
+}
+
+// LabelPair はラベル名と値の組
+type LabelPair struct {
+	Name  string
+	Value string
+}
+
+// MetricFamily は同じ名前を持つサンプルの集まり
+type MetricFamily struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+// Collector はメトリクスファミリーを1つ提供する
+type Collector interface {
+	Describe() string
+	Collect() MetricFamily
+}
+
+// Gatherer はメトリクスファミリーを収集する（prometheus.Gatherer に相当）
+type Gatherer interface {
+	Gather() ([]MetricFamily, error)
+}
+
+// Gatherers は複数のGathererの結果を結合する
+type Gatherers []Gatherer
+
+func (gs Gatherers) Gather() ([]MetricFamily, error) {
+	seen := make(map[string]bool)
+	var families []MetricFamily
+	for _, g := range gs {
+		mfs, err := g.Gather()
+		if err != nil {
+			return nil, err
+		}
+		for _, mf := range mfs {
+			if seen[mf.Name] {
+				return nil, fmt.Errorf("metric family %q gathered more than once", mf.Name)
+			}
+			seen[mf.Name] = true
+			families = append(families, mf)
+		}
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families, nil
+}
+
+// Desc はメトリクスファミリーの名前・説明・ラベル名を表す（prometheus.Desc に相当）
+type Desc struct {
+	metricDesc
+}
+
+func NewDesc(name, help string, labels []string) *Desc {
+	return &Desc{metricDesc{name: name, help: help, labels: labels}}
+}
+
+// MustNewConstMetric は収集時点の値からサンプルを1つ持つメトリクスファミリーを作成する。
+// ラベル値の数が Desc と一致しない場合はpanicする（prometheus.MustNewConstMetric に相当）
+func MustNewConstMetric(desc *Desc, valueType MetricType, value float64, labelValues ...string) MetricFamily {
+	if len(labelValues) != len(desc.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", desc.name, len(desc.labels), len(labelValues)))
+	}
+	return MetricFamily{
+		Name:    desc.name,
+		Help:    desc.help,
+		Type:    valueType,
+		Samples: []Sample{{Name: desc.name, Labels: desc.labelPairs(labelValues), Value: value}},
+	}
+}
+
+// MultiCollector は収集のたびに複数のメトリクスファミリーを提供するコレクター（prometheus.Collector に相当）
+type MultiCollector interface {
+	Describe(ch chan<- *Desc)
+	Collect(ch chan<- MetricFamily)
+}
+
+// ErrDuplicateMetric は同名のメトリクスが既に登録されていることを表す
+var ErrDuplicateMetric = errors.New("metric already registered")
+
+// Registry はメトリクスを登録・収集するレジストリ（prometheus.Registry に相当）
+type Registry struct {
+	collectors      map[string]Collector
+	multiCollectors []MultiCollector
+	names           map[string]bool
+	mu              sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]Collector),
+		names:      make(map[string]bool),
+	}
+}
+
+func (r *Registry) Register(c Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := c.Describe()
+	if r.names[name] {
+		return fmt.Errorf("%w: %s", ErrDuplicateMetric, name)
+	}
+	r.collectors[name] = c
+	r.names[name] = true
+	return nil
+}
+
+// RegisterMulti はMultiCollectorが記述する全てのメトリクスをまとめて登録する
+func (r *Registry) RegisterMulti(c MultiCollector) error {
+	descs := make(chan *Desc)
+	go func() {
+		c.Describe(descs)
+		close(descs)
+	}()
+
+	var names []string
+	for desc := range descs {
+		names = append(names, desc.name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		if r.names[name] {
+			return fmt.Errorf("%w: %s", ErrDuplicateMetric, name)
+		}
+	}
+	for _, name := range names {
+		r.names[name] = true
+	}
+	r.multiCollectors = append(r.multiCollectors, c)
+	return nil
+}
+
+// MustRegister は複数のメトリクスを登録し、失敗した場合はpanicする（prometheus.MustRegister に相当）
+func (r *Registry) MustRegister(cs ...Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (r *Registry) Gather() ([]MetricFamily, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	families := make([]MetricFamily, 0, len(r.names))
+	for _, c := range r.collectors {
+		families = append(families, c.Collect())
+	}
+	for _, c := range r.multiCollectors {
+		ch := make(chan MetricFamily)
+		go func(c MultiCollector) {
+			c.Collect(ch)
+			close(ch)
+		}(c)
+		for mf := range ch {
+			families = append(families, mf)
+		}
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families, nil
+}
+
+func (c *CounterVec) Describe() string { return c.desc.name }
+
+func (c *CounterVec) Collect() MetricFamily {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	mf := MetricFamily{Name: c.desc.name, Help: c.desc.help, Type: CounterType}
+	for key, value := range c.metrics {
+		mf.Samples = append(mf.Samples, Sample{
+			Name:   c.desc.name,
+			Labels: c.desc.labelPairs(c.labelValues[key]),
+			Value:  value,
+		})
+	}
+	sortSamples(mf.Samples)
+	return mf
+}
+
+func (g *Gauge) Describe() string { return g.desc.name }
+
+func (g *Gauge) Collect() MetricFamily {
+	return MetricFamily{
+		Name:    g.desc.name,
+		Help:    g.desc.help,
+		Type:    GaugeType,
+		Samples: []Sample{{Name: g.desc.name, Value: g.Get()}},
+	}
+}
+
+func (g *GaugeVec) Describe() string { return g.desc.name }
+
+func (g *GaugeVec) Collect() MetricFamily {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	mf := MetricFamily{Name: g.desc.name, Help: g.desc.help, Type: GaugeType}
+	for key, value := range g.metrics {
+		mf.Samples = append(mf.Samples, Sample{
+			Name:   g.desc.name,
+			Labels: g.desc.labelPairs(g.labelValues[key]),
+			Value:  value,
+		})
+	}
+	sortSamples(mf.Samples)
+	return mf
+}
+
+func (h *HistogramVec) Describe() string { return h.desc.name }
+
+func (h *HistogramVec) Collect() MetricFamily {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	mf := MetricFamily{Name: h.desc.name, Help: h.desc.help, Type: HistogramType}
+	keys := make([]string, 0, len(h.metrics))
+	for key := range h.metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values := h.metrics[key]
+		labels := h.desc.labelPairs(h.labelValues[key])
+
+		// バケットは累積値で出力する
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		for _, bucket := range h.buckets {
+			count := 0
+			for _, v := range values {
+				if v <= bucket {
+					count++
+				}
+			}
+			mf.Samples = append(mf.Samples, Sample{
+				Name:   h.desc.name + "_bucket",
+				Labels: append(labels[:len(labels):len(labels)], LabelPair{Name: "le", Value: formatFloat(bucket)}),
+				Value:  float64(count),
+			})
+		}
+		mf.Samples = append(mf.Samples,
+			Sample{Name: h.desc.name + "_bucket", Labels: append(labels[:len(labels):len(labels)], LabelPair{Name: "le", Value: "+Inf"}), Value: float64(len(values))},
+			Sample{Name: h.desc.name + "_sum", Labels: labels, Value: sum},
+			Sample{Name: h.desc.name + "_count", Labels: labels, Value: float64(len(values))},
+		)
+	}
+	return mf
+}
+
+func (d metricDesc) labelPairs(values []string) []LabelPair {
+	pairs := make([]LabelPair, 0, len(d.labels))
+	for i, name := range d.labels {
+		if i < len(values) {
+			pairs = append(pairs, LabelPair{Name: name, Value: values[i]})
+		}
+	}
+	return pairs
+}
+
+func sortSamples(samples []Sample) {
+	sort.Slice(samples, func(i, j int) bool {
+		return formatLabels(samples[i].Labels) < formatLabels(samples[j].Labels)
+	})
+}
+
+// processStartTime はプロセスメトリクス用の起動時刻
+var processStartTime = time.Now()
+
+// runtimeGatherer はGoランタイムとプロセスのメトリクスを提供する
+type runtimeGatherer struct{}
+
+func (runtimeGatherer) Gather() ([]MetricFamily, error) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	gauge := func(name, help string, value float64) MetricFamily {
+		return MetricFamily{Name: name, Help: help, Type: GaugeType, Samples: []Sample{{Name: name, Value: value}}}
+	}
+	return []MetricFamily{
+		gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine())),
+		gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(m.HeapInuse)),
+		gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(m.Sys)),
+		gauge("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", float64(processStartTime.Unix())),
+	}, nil
+}
+
+// MetricsManagerOptions はMetricsManagerの設定
+type MetricsManagerOptions struct {
+	// IncludeRuntimeMetrics が true ならGoランタイム・プロセスのメトリクスも公開する
+	IncludeRuntimeMetrics bool
+}
+
+// MetricsManager は専用のRegistryでメトリクスを管理し、/metrics 用のハンドラーを提供する。
+// グローバルなレジストリを使わないため、明示的に登録したメトリクスだけが公開される
+type MetricsManager struct {
+	registry *Registry
+	gatherer Gatherer
+}
+
+func NewMetricsManager(opts MetricsManagerOptions) *MetricsManager {
+	registry := NewRegistry()
+	var gatherer Gatherer = registry
+	if opts.IncludeRuntimeMetrics {
+		gatherer = Gatherers{registry, runtimeGatherer{}}
+	}
+	return &MetricsManager{registry: registry, gatherer: gatherer}
+}
+
+// Register はメトリクスを専用レジストリに登録する
+func (mm *MetricsManager) Register(c Collector) error {
+	return mm.registry.Register(c)
+}
+
+// Gather は公開対象のメトリクスファミリーを名前順で返す
+func (mm *MetricsManager) Gather() ([]MetricFamily, error) {
+	return mm.gatherer.Gather()
+}
+
+// GetHandler はマネージャーのメトリクスをPrometheusテキスト形式で返すハンドラーを作成する
+func (mm *MetricsManager) GetHandler() http.Handler {
+	return HandlerFor(mm.gatherer)
+}
+
+// HandlerFor は指定したGathererの内容を公開するハンドラーを作成する（promhttp.HandlerFor に相当）
+func HandlerFor(gatherer Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families, err := gatherer.Gather()
+		if err != nil {
+			http.Error(w, "error gathering metrics: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, mf := range families {
+			writeMetricFamily(w, mf)
+		}
+	})
+}
+
+func writeMetricFamily(w io.Writer, mf MetricFamily) {
+	if mf.Help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", mf.Name, helpEscaper.Replace(mf.Help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", mf.Name, mf.Type)
+	for _, s := range mf.Samples {
+		fmt.Fprintf(w, "%s%s %s\n", s.Name, formatLabels(s.Labels), formatFloat(s.Value))
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatLabels(labels []LabelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = fmt.Sprintf(`%s="%s"`, l.Name, labelEscaper.Replace(l.Value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
 // HTTPミドルウェア
 func PrometheusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -19,6 +21,179 @@ func TestServiceMetrics_Initialization(t *testing.T) {
 	// TODO: 実装後に各メトリクスのnilチェックを追加
 }
 
+// findFamily は収集結果から指定した名前のメトリクスファミリーを探す
+func findFamily(t *testing.T, gatherer Gatherer, name string) MetricFamily {
+	t.Helper()
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	for _, mf := range families {
+		if mf.Name == name {
+			return mf
+		}
+	}
+	t.Fatalf("Metric family %s not found", name)
+	return MetricFamily{}
+}
+
+func TestServiceMetrics_RecordRequest(t *testing.T) {
+	metrics := NewServiceMetrics()
+
+	metrics.RecordRequest("GET", "/api", http.StatusOK)
+	metrics.RecordRequest("GET", "/api", http.StatusOK)
+	metrics.RecordRequest("GET", "/api", http.StatusInternalServerError)
+	metrics.RecordRequest("POST", "/orders", http.StatusCreated)
+
+	mf := findFamily(t, metrics.Registry(), "myapp_http_requests_total")
+	if mf.Type != CounterType {
+		t.Errorf("Expected counter type, got %s", mf.Type)
+	}
+
+	expected := map[string]float64{
+		`{method="GET",endpoint="/api",status="200"}`:     2,
+		`{method="GET",endpoint="/api",status="500"}`:     1,
+		`{method="POST",endpoint="/orders",status="201"}`: 1,
+	}
+	if len(mf.Samples) != len(expected) {
+		t.Errorf("Expected %d label combinations, got %d", len(expected), len(mf.Samples))
+	}
+	for _, sample := range mf.Samples {
+		labels := formatLabels(sample.Labels)
+		want, ok := expected[labels]
+		if !ok {
+			t.Errorf("Unexpected label combination %s", labels)
+			continue
+		}
+		if sample.Value != want {
+			t.Errorf("Expected %s to be %v, got %v", labels, want, sample.Value)
+		}
+	}
+}
+
+func TestSystemMetrics_RuntimeStats(t *testing.T) {
+	metrics := NewServiceMetrics()
+	system := NewSystemMetrics(metrics.Registry())
+
+	system.UpdateMemoryUsage()
+	system.UpdateGoroutineCount()
+
+	goroutines := findFamily(t, metrics.Registry(), "myapp_goroutines")
+	if len(goroutines.Samples) != 1 || goroutines.Samples[0].Value < 1 {
+		t.Errorf("Expected goroutine count >= 1, got %+v", goroutines.Samples)
+	}
+
+	memory := findFamily(t, metrics.Registry(), "myapp_memory_usage_bytes")
+	if len(memory.Samples) != 3 {
+		t.Fatalf("Expected 3 memory samples, got %d", len(memory.Samples))
+	}
+	for _, sample := range memory.Samples {
+		if sample.Value <= 0 {
+			t.Errorf("Expected positive memory usage for %s, got %v", formatLabels(sample.Labels), sample.Value)
+		}
+	}
+
+	// 同じレジストリへの二重登録は失敗する
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected duplicate registration to panic")
+		}
+	}()
+	NewSystemMetrics(metrics.Registry())
+}
+
+func TestCustomCollector_BuildInfoAndUptime(t *testing.T) {
+	registry := NewRegistry()
+	info := BuildInfo{Version: "1.2.3", Commit: "abc1234", BuildDate: "2024-01-01"}
+	if err := registry.RegisterMulti(NewCustomCollector(info)); err != nil {
+		t.Fatalf("Failed to register collector: %v", err)
+	}
+
+	appInfo := findFamily(t, registry, "myapp_app_info")
+	if len(appInfo.Samples) != 1 || appInfo.Samples[0].Value != 1 {
+		t.Fatalf("Expected a single app_info sample with value 1, got %+v", appInfo.Samples)
+	}
+	want := `{version="1.2.3",commit="abc1234",build_date="2024-01-01"}`
+	if got := formatLabels(appInfo.Samples[0].Labels); got != want {
+		t.Errorf("Expected info labels %s, got %s", want, got)
+	}
+
+	first := findFamily(t, registry, "myapp_app_uptime_seconds").Samples[0].Value
+	time.Sleep(10 * time.Millisecond)
+	second := findFamily(t, registry, "myapp_app_uptime_seconds").Samples[0].Value
+	if second <= first {
+		t.Errorf("Expected uptime to increase between gathers, got %v then %v", first, second)
+	}
+
+	if err := registry.RegisterMulti(NewCustomCollector(info)); !errors.Is(err, ErrDuplicateMetric) {
+		t.Errorf("Expected ErrDuplicateMetric, got %v", err)
+	}
+}
+
+// recordingNotifier は受け取ったアラートをチャネルに送るテスト用の通知先
+type recordingNotifier struct {
+	alerts chan Alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert Alert) error {
+	n.alerts <- alert
+	return nil
+}
+
+func TestAlertManager_FiresOnThresholdBreach(t *testing.T) {
+	registry := NewRegistry()
+	queueSize := NewGaugeVec("queue_size", "Queue size", []string{"queue"})
+	registry.MustRegister(queueSize)
+	queueSize.WithLabelValues("emails").Set(80)
+	queueSize.WithLabelValues("reports").Set(40)
+
+	notifier := &recordingNotifier{alerts: make(chan Alert, 10)}
+	manager := NewAlertManager(registry, notifier)
+
+	if err := manager.AddRule(AlertRule{Name: "bad", Query: "queue_size", Comparator: "=>"}); !errors.Is(err, ErrUnknownComparator) {
+		t.Errorf("Expected ErrUnknownComparator, got %v", err)
+	}
+	rules := []AlertRule{
+		{Name: "QueueBacklog", Query: "queue_size", Comparator: ">", Threshold: 100, Severity: "critical"},
+		{Name: "QueueEmpty", Query: "queue_size", Comparator: "<", Threshold: 1, Severity: "warning"},
+	}
+	for _, rule := range rules {
+		if err := manager.AddRule(rule); err != nil {
+			t.Fatalf("AddRule failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.StartMonitoring(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case alert := <-notifier.alerts:
+		if alert.RuleName != "QueueBacklog" || alert.Severity != "critical" || alert.Value != 120 {
+			t.Errorf("Unexpected alert: %+v", alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an alert for the breached rule")
+	}
+
+	// 発火中のルールは解消されるまで再通知されない
+	time.Sleep(50 * time.Millisecond)
+	if len(notifier.alerts) != 0 {
+		t.Errorf("Expected no repeated alerts while firing, got %d", len(notifier.alerts))
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("StartMonitoring did not stop after context cancel")
+	}
+}
+
 func TestPrometheusMiddleware_RequestCounting(t *testing.T) {
 	
 	// テスト用メトリクスを作成
@@ -187,6 +362,120 @@ func TestMetricsEndpoint_Output(t *testing.T) {
 	// - queue_size
 }
 
+// scrapeFamilies は /metrics の出力から "# TYPE" 行のメトリクス名を集める
+func scrapeFamilies(t *testing.T, handler http.Handler) (map[string]bool, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	families := make(map[string]bool)
+	for _, line := range strings.Split(body, "\n") {
+		if fields := strings.Fields(line); len(fields) == 4 && fields[1] == "TYPE" {
+			families[fields[2]] = true
+		}
+	}
+	return families, body
+}
+
+func TestMetricsManager_CustomRegistry(t *testing.T) {
+	manager := NewMetricsManager(MetricsManagerOptions{})
+
+	jobs := NewCounterVec("jobs_processed_total", "Total processed jobs", []string{"queue", "result"})
+	if err := manager.Register(jobs); err != nil {
+		t.Fatalf("Failed to register counter: %v", err)
+	}
+	jobs.WithLabelValues("emails", "success").Add(3)
+	jobs.WithLabelValues("emails", "failure").Inc()
+
+	families, body := scrapeFamilies(t, manager.GetHandler())
+	if len(families) != 1 || !families["jobs_processed_total"] {
+		t.Errorf("Expected only jobs_processed_total, got %v", families)
+	}
+
+	for _, want := range []string{
+		"# HELP jobs_processed_total Total processed jobs",
+		"# TYPE jobs_processed_total counter",
+		`jobs_processed_total{queue="emails",result="success"} 3`,
+		`jobs_processed_total{queue="emails",result="failure"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected scrape output to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	gathered, err := manager.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	if len(gathered) != 1 || len(gathered[0].Samples) != 2 {
+		t.Errorf("Expected 1 family with 2 samples, got %+v", gathered)
+	}
+
+	if err := manager.Register(NewCounterVec("jobs_processed_total", "dup", nil)); !errors.Is(err, ErrDuplicateMetric) {
+		t.Errorf("Expected ErrDuplicateMetric, got %v", err)
+	}
+}
+
+func TestMetricsManager_RuntimeMetricsOptIn(t *testing.T) {
+	manager := NewMetricsManager(MetricsManagerOptions{IncludeRuntimeMetrics: true})
+
+	inflight := NewGauge("worker_inflight", "Jobs currently in flight")
+	if err := manager.Register(inflight); err != nil {
+		t.Fatalf("Failed to register gauge: %v", err)
+	}
+	inflight.Set(2)
+
+	families, body := scrapeFamilies(t, manager.GetHandler())
+	expected := []string{
+		"go_goroutines",
+		"go_memstats_heap_inuse_bytes",
+		"go_memstats_sys_bytes",
+		"process_start_time_seconds",
+		"worker_inflight",
+	}
+	if len(families) != len(expected) {
+		t.Errorf("Expected %d families, got %v", len(expected), families)
+	}
+	for _, name := range expected {
+		if !families[name] {
+			t.Errorf("Expected family %s in scrape output", name)
+		}
+	}
+	if !strings.Contains(body, "worker_inflight 2\n") {
+		t.Errorf("Expected gauge sample in output, got:\n%s", body)
+	}
+}
+
+func TestMetricsManager_HistogramExposition(t *testing.T) {
+	manager := NewMetricsManager(MetricsManagerOptions{})
+
+	latency := NewHistogramVec("job_duration_seconds", "Job duration", []float64{0.1, 1}, []string{"queue"})
+	if err := manager.Register(latency); err != nil {
+		t.Fatalf("Failed to register histogram: %v", err)
+	}
+	latency.WithLabelValues("emails").Observe(0.05)
+	latency.WithLabelValues("emails").Observe(0.5)
+	latency.WithLabelValues("emails").Observe(3)
+
+	_, body := scrapeFamilies(t, manager.GetHandler())
+	for _, want := range []string{
+		`job_duration_seconds_bucket{queue="emails",le="0.1"} 1`,
+		`job_duration_seconds_bucket{queue="emails",le="1"} 2`,
+		`job_duration_seconds_bucket{queue="emails",le="+Inf"} 3`,
+		`job_duration_seconds_sum{queue="emails"} 3.55`,
+		`job_duration_seconds_count{queue="emails"} 3`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected scrape output to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
 func TestResponseWriter_StatusCodeCapture(t *testing.T) {
 	rec := httptest.NewRecorder()
 	
@@ -1,115 +1,260 @@
 package main
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
-// TODO: HistogramMetrics構造体を実装してください
+// HistogramMetrics はサービスで使うヒストグラムメトリクスをまとめたもの
 type HistogramMetrics struct {
-	// TODO: 以下のヒストグラムメトリクスを実装
-	// - httpRequestDuration: HTTPリクエストの処理時間分布
-	// - databaseQueryDuration: データベースクエリの処理時間分布  
-	// - apiResponseSize: APIレスポンスサイズの分布
-	// - queueWaitTime: キュー待機時間の分布
-	// - batchProcessingTime: バッチ処理時間の分布
+	httpRequestDuration   *HistogramVec // HTTPリクエストの処理時間分布
+	databaseQueryDuration *HistogramVec // データベースクエリの処理時間分布
+	apiResponseSize       *HistogramVec // APIレスポンスサイズの分布
+	queueWaitTime         *HistogramVec // キュー待機時間の分布
+	batchProcessingTime   *HistogramVec // バッチ処理時間の分布
 }
 
-// TODO: NewHistogramMetrics関数を実装してください
-// Prometheusヒストグラムメトリクスを初期化し、レジストリに登録する
+// NewHistogramMetrics はヒストグラムメトリクスを初期化する
 func NewHistogramMetrics() *HistogramMetrics {
-	// TODO: 以下のヒストグラムを作成
-	// 1. httpRequestDuration:
-	//    - バケット: 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0, 2.5, 5.0, 10.0 (秒)
-	//    - ラベル: method, endpoint, status
-	//
-	// 2. databaseQueryDuration:
-	//    - バケット: 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0 (秒)
-	//    - ラベル: operation, table
-	//
-	// 3. apiResponseSize:
-	//    - バケット: 100, 1000, 10000, 100000, 1000000, 10000000 (バイト)
-	//    - ラベル: endpoint, content_type
-	//
-	// 4. queueWaitTime:
-	//    - バケット: 0.001, 0.01, 0.1, 1.0, 10.0, 60.0, 300.0 (秒)
-	//    - ラベル: queue_name, priority
-	//
-	// 5. batchProcessingTime:
-	//    - バケット: 1.0, 5.0, 10.0, 30.0, 60.0, 300.0, 600.0 (秒)
-	//    - ラベル: batch_type, size_category
+	return &HistogramMetrics{
+		httpRequestDuration: NewHistogramVec(
+			"http_request_duration_seconds",
+			"HTTP request duration in seconds",
+			[]string{"method", "endpoint", "status"},
+			[]float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0, 2.5, 5.0, 10.0},
+		),
+		databaseQueryDuration: NewHistogramVec(
+			"database_query_duration_seconds",
+			"Database query duration in seconds",
+			[]string{"operation", "table"},
+			[]float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0},
+		),
+		apiResponseSize: NewHistogramVec(
+			"api_response_size_bytes",
+			"API response size in bytes",
+			[]string{"endpoint", "content_type"},
+			[]float64{100, 1000, 10000, 100000, 1000000, 10000000},
+		),
+		queueWaitTime: NewHistogramVec(
+			"queue_wait_time_seconds",
+			"Time messages spend waiting in the queue in seconds",
+			[]string{"queue_name", "priority"},
+			[]float64{0.001, 0.01, 0.1, 1.0, 10.0, 60.0, 300.0},
+		),
+		batchProcessingTime: NewHistogramVec(
+			"batch_processing_time_seconds",
+			"Batch processing time in seconds",
+			[]string{"batch_type", "size_category"},
+			[]float64{1.0, 5.0, 10.0, 30.0, 60.0, 300.0, 600.0},
+		),
+	}
+}
 
-	return nil
+// Handler は全てのヒストグラムをPrometheusテキスト形式で公開するハンドラーを返す
+func (m *HistogramMetrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, hv := range []*HistogramVec{
+			m.httpRequestDuration,
+			m.databaseQueryDuration,
+			m.apiResponseSize,
+			m.queueWaitTime,
+			m.batchProcessingTime,
+		} {
+			hv.WriteText(w)
+		}
+	})
 }
 
-// TODO: HTTPMetricsMiddleware構造体を実装してください
+// HTTPMetricsMiddleware はHTTPリクエストの処理時間とレスポンスサイズを記録する
 type HTTPMetricsMiddleware struct {
 	metrics *HistogramMetrics
 }
 
-// TODO: NewHTTPMetricsMiddleware関数を実装してください
 func NewHTTPMetricsMiddleware(metrics *HistogramMetrics) *HTTPMetricsMiddleware {
-	// ここに実装
-	return nil
+	return &HTTPMetricsMiddleware{metrics: metrics}
 }
 
-// TODO: Middleware関数を実装してください
-// HTTPリクエストの処理時間とレスポンスサイズを測定
+// Middleware はHTTPリクエストの処理時間とレスポンスサイズを測定する
 func (m *HTTPMetricsMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: 以下の処理を実装
-		// 1. リクエスト開始時刻を記録
-		// 2. レスポンスライターをラップしてサイズを測定
-		// 3. 次のハンドラーを実行
-		// 4. 処理時間とレスポンスサイズのヒストグラムを更新
+		start := time.Now()
 
-		next.ServeHTTP(w, r)
+		// ステータスコードを明示しないハンドラーは200として扱う
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		duration := time.Since(start).Seconds()
+		status := strconv.Itoa(rw.statusCode)
+		m.metrics.httpRequestDuration.WithLabelValues(r.Method, r.URL.Path, status).Observe(duration)
+
+		contentType := rw.Header().Get("Content-Type")
+		if contentType == "" {
+			contentType = "unknown"
+		}
+		m.metrics.apiResponseSize.WithLabelValues(r.URL.Path, contentType).Observe(float64(rw.responseSize))
 	})
 }
 
-// TODO: responseWriter構造体を実装してください
-// レスポンスサイズとステータスコードを記録
+// responseWriter はレスポンスサイズとステータスコードを記録する
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode   int
 	responseSize int
 }
 
-// TODO: Write メソッドを実装してください
 func (rw *responseWriter) Write(data []byte) (int, error) {
-	// TODO: データサイズを記録してから書き込み
-	return 0, nil
+	n, err := rw.ResponseWriter.Write(data)
+	rw.responseSize += n
+	return n, err
 }
 
-// TODO: WriteHeader メソッドを実装してください
 func (rw *responseWriter) WriteHeader(statusCode int) {
-	// ここに実装
+	rw.statusCode = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
 // TODO: DatabaseSimulator構造体を実装してください
 type DatabaseSimulator struct {
-	metrics *HistogramMetrics
+	metrics     *HistogramMetrics
+	slowQueries *SlowQuerySampler                    // nil の場合はスロークエリを記録しない
+	latency     func(operation string) time.Duration // 模擬的な処理時間（テストで差し替え可能）
+}
+
+// SlowQuerySample はしきい値を超えたクエリ1件の記録
+type SlowQuerySample struct {
+	Operation string
+	Table     string
+	Duration  time.Duration
+	TraceID   string
+	Timestamp time.Time
 }
 
-// TODO: NewDatabaseSimulator関数を実装してください
+// slowQueryHeap は Duration が最も短いサンプルを先頭に置く最小ヒープ
+type slowQueryHeap []SlowQuerySample
+
+func (h slowQueryHeap) Len() int           { return len(h) }
+func (h slowQueryHeap) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h slowQueryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *slowQueryHeap) Push(x interface{}) { *h = append(*h, x.(SlowQuerySample)) }
+
+func (h *slowQueryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// SlowQuerySampler はしきい値を超えたクエリのうち、最も遅い capacity 件を保持する。
+// ヒストグラムでは個々の外れ値を追えないため、遅いクエリだけを詳細に残す。
+// 最小ヒープの先頭が保持中で最も速いサンプルなので、それより遅いクエリが来たときだけ入れ替える
+type SlowQuerySampler struct {
+	threshold time.Duration
+	capacity  int
+	samples   slowQueryHeap
+	mu        sync.Mutex
+}
+
+// NewSlowQuerySampler は threshold 以上のクエリを遅い順に最大 capacity 件保持するサンプラーを作成する
+func NewSlowQuerySampler(threshold time.Duration, capacity int) *SlowQuerySampler {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &SlowQuerySampler{
+		threshold: threshold,
+		capacity:  capacity,
+		samples:   make(slowQueryHeap, 0, capacity),
+	}
+}
+
+// Record はクエリがしきい値以上で、保持中の上位 capacity 件に入るなら記録し、記録したかどうかを返す
+func (s *SlowQuerySampler) Record(operation, table string, duration time.Duration) bool {
+	if duration < s.threshold {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	full := len(s.samples) >= s.capacity
+	if full && duration <= s.samples[0].Duration {
+		return false
+	}
+
+	sample := SlowQuerySample{
+		Operation: operation,
+		Table:     table,
+		Duration:  duration,
+		TraceID:   fmt.Sprintf("%016x", rand.Uint64()),
+		Timestamp: time.Now(),
+	}
+	if full {
+		s.samples[0] = sample
+		heap.Fix(&s.samples, 0)
+	} else {
+		heap.Push(&s.samples, sample)
+	}
+	return true
+}
+
+// Samples は保持しているサンプルを遅い順に返す
+func (s *SlowQuerySampler) Samples() []SlowQuerySample {
+	s.mu.Lock()
+	result := make([]SlowQuerySample, len(s.samples))
+	copy(result, s.samples)
+	s.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Duration > result[j].Duration })
+	return result
+}
+
+// EnableSlowQuerySampling はヒストグラムへの記録に加えて、threshold 以上のクエリを
+// 遅い順に最大 capacity 件まで保持するようにする
+func (db *DatabaseSimulator) EnableSlowQuerySampling(threshold time.Duration, capacity int) {
+	db.slowQueries = NewSlowQuerySampler(threshold, capacity)
+}
+
+// SlowQueries は記録されたスロークエリを遅い順に返す（無効な場合は nil）
+func (db *DatabaseSimulator) SlowQueries() []SlowQuerySample {
+	if db.slowQueries == nil {
+		return nil
+	}
+	return db.slowQueries.Samples()
+}
+
+// NewDatabaseSimulator はヒストグラムにクエリ時間を記録するシミュレーターを作成する
 func NewDatabaseSimulator(metrics *HistogramMetrics) *DatabaseSimulator {
-	// ここに実装
-	return nil
+	return &DatabaseSimulator{
+		metrics: metrics,
+		latency: getRandomLatency,
+	}
 }
 
-// TODO: ExecuteQuery メソッドを実装してください
-// データベースクエリを模擬し、処理時間を測定
+// ExecuteQuery はデータベースクエリを模擬し、処理時間を databaseQueryDuration と
+// スロークエリサンプラーに記録する。5% の確率でエラーを返す
 func (db *DatabaseSimulator) ExecuteQuery(operation, table string) error {
-	// TODO: 以下の処理を実装
-	// 1. クエリ開始時刻を記録
-	// 2. 模擬的な処理時間（operation とtableによって変動）
-	// 3. databaseQueryDuration ヒストグラムを更新
-	// 4. ランダムなエラー発生（5%の確率）
+	start := time.Now()
+	time.Sleep(db.latency(operation))
+	duration := time.Since(start)
+
+	db.metrics.databaseQueryDuration.WithLabelValues(operation, table).Observe(duration.Seconds())
+	if db.slowQueries != nil {
+		db.slowQueries.Record(operation, table, duration)
+	}
 
+	if rand.Float64() < 0.05 {
+		return fmt.Errorf("query %s on %s failed", operation, table)
+	}
 	return nil
 }
 
@@ -289,9 +434,20 @@ func getSizeCategory(size int) string {
 	return ""
 }
 
+// getRandomLatency はオペレーションごとの典型的な範囲でランダムなレイテンシを返す
 func getRandomLatency(operation string) time.Duration {
-	// TODO: オペレーションに基づいてリアルなレイテンシを返す
-	return 0
+	var lo, hi time.Duration
+	switch operation {
+	case "SELECT":
+		lo, hi = 1*time.Millisecond, 5*time.Millisecond
+	case "INSERT", "DELETE":
+		lo, hi = 2*time.Millisecond, 8*time.Millisecond
+	case "UPDATE":
+		lo, hi = 3*time.Millisecond, 10*time.Millisecond
+	default:
+		lo, hi = 10*time.Millisecond, 50*time.Millisecond
+	}
+	return lo + time.Duration(rand.Int63n(int64(hi-lo)))
 }
 
 func main() {
@@ -7,12 +7,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"math/rand"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -35,6 +37,7 @@ type HistogramVec struct {
 	buckets     []float64
 	labelNames  []string
 	histograms  map[string]*Histogram
+	labelValues map[string][]string
 	mu          sync.RWMutex
 }
 
@@ -71,8 +74,9 @@ func NewHistogramVec(name, help string, labelNames []string, buckets []float64)
 		name:       name,
 		help:       help,
 		buckets:    buckets,
-		labelNames: labelNames,
-		histograms: make(map[string]*Histogram),
+		labelNames:  labelNames,
+		histograms:  make(map[string]*Histogram),
+		labelValues: make(map[string][]string),
 	}
 }
 
@@ -196,6 +200,7 @@ func (hv *HistogramVec) WithLabelValues(values ...string) *HistogramObserver {
 	// 新しいヒストグラムを作成
 	histogram := NewHistogram(hv.name, hv.help, hv.buckets)
 	hv.histograms[key] = histogram
+	hv.labelValues[key] = values
 	
 	return &HistogramObserver{histogram: histogram}
 }
@@ -218,6 +223,45 @@ func (hv *HistogramVec) GetAllStats() map[string]HistogramStats {
 	return stats
 }
 
+// WriteText 全ての系列をPrometheusテキスト形式で書き出す
+func (hv *HistogramVec) WriteText(w io.Writer) {
+	hv.mu.RLock()
+	defer hv.mu.RUnlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", hv.name, hv.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", hv.name)
+
+	keys := make([]string, 0, len(hv.histograms))
+	for key := range hv.histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		stats := hv.histograms[key].GetStats()
+		labels := make([]string, len(hv.labelNames))
+		for i, name := range hv.labelNames {
+			labels[i] = fmt.Sprintf("%s=%q", name, hv.labelValues[key][i])
+		}
+		labelStr := strings.Join(labels, ",")
+		sep := ""
+		if labelStr != "" {
+			sep = ","
+		}
+
+		// バケットは累積値（Observeで上限以下の全バケットを増加させている）
+		for _, bucket := range stats.BucketCounts {
+			le := "+Inf"
+			if !math.IsInf(bucket.UpperBound, 1) {
+				le = strconv.FormatFloat(bucket.UpperBound, 'g', -1, 64)
+			}
+			fmt.Fprintf(w, "%s_bucket{%s%sle=\"%s\"} %d\n", hv.name, labelStr, sep, le, bucket.Count)
+		}
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", hv.name, labelStr, strconv.FormatFloat(stats.Sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", hv.name, labelStr, stats.Count)
+	}
+}
+
 // HistogramStats ヒストグラム統計情報
 type HistogramStats struct {
 	Name         string        `json:"name"`
@@ -295,6 +339,74 @@ func (rw *responseWriterSolution) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// DatabaseSimulatorSolution データベースクエリシミュレーター
+type DatabaseSimulatorSolution struct {
+	histogram   *HistogramVec
+	slowQueries *SlowQuerySampler
+	latency     func(operation string) time.Duration
+}
+
+func NewDatabaseSimulatorSolution() *DatabaseSimulatorSolution {
+	histogram := NewHistogramVec(
+		"database_query_duration_seconds",
+		"Database query duration in seconds",
+		[]string{"operation", "table"},
+		[]float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0},
+	)
+
+	return &DatabaseSimulatorSolution{
+		histogram: histogram,
+		latency:   simulatedQueryLatency,
+	}
+}
+
+// EnableSlowQuerySampling threshold 以上のクエリを遅い順に最大 capacity 件保持する
+func (db *DatabaseSimulatorSolution) EnableSlowQuerySampling(threshold time.Duration, capacity int) {
+	db.slowQueries = NewSlowQuerySampler(threshold, capacity)
+}
+
+// SlowQueries 記録されたスロークエリを遅い順に取得（無効な場合は nil）
+func (db *DatabaseSimulatorSolution) SlowQueries() []SlowQuerySample {
+	if db.slowQueries == nil {
+		return nil
+	}
+	return db.slowQueries.Samples()
+}
+
+// ExecuteQuery クエリを模擬し、処理時間をヒストグラムとスロークエリサンプラーに記録
+func (db *DatabaseSimulatorSolution) ExecuteQuery(operation, table string) error {
+	start := time.Now()
+	time.Sleep(db.latency(operation))
+	duration := time.Since(start)
+
+	db.histogram.WithLabelValues(operation, table).Observe(duration.Seconds())
+	if db.slowQueries != nil {
+		db.slowQueries.Record(operation, table, duration)
+	}
+
+	// 5%の確率でエラー
+	if rand.Float64() < 0.05 {
+		return fmt.Errorf("query %s on %s failed", operation, table)
+	}
+	return nil
+}
+
+// GetStats 統計情報を取得
+func (db *DatabaseSimulatorSolution) GetStats() map[string]HistogramStats {
+	return db.histogram.GetAllStats()
+}
+
+func simulatedQueryLatency(operation string) time.Duration {
+	switch operation {
+	case "SELECT":
+		return time.Duration(1+rand.Intn(5)) * time.Millisecond
+	case "INSERT", "UPDATE", "DELETE":
+		return time.Duration(2+rand.Intn(8)) * time.Millisecond
+	default:
+		return time.Duration(10+rand.Intn(40)) * time.Millisecond
+	}
+}
+
 // PerformanceAnalyzerSolution パフォーマンス分析器
 type PerformanceAnalyzerSolution struct {
 	tracker *RequestLatencyTracker
@@ -358,7 +470,7 @@ func (pa *PerformanceAnalyzerSolution) AnalyzePerformance() PerformanceReport {
 	return report
 }
 
-func (pa *PerformanceAnalyzer) calculatePercentile(endpoint string, percentile float64) float64 {
+func (pa *PerformanceAnalyzerSolution) calculatePercentile(endpoint string, percentile float64) float64 {
 	stats := pa.tracker.GetStats()
 	
 	// エンドポイントに関連するヒストグラムを検索
@@ -372,7 +484,7 @@ func (pa *PerformanceAnalyzer) calculatePercentile(endpoint string, percentile f
 	return 0
 }
 
-func (pa *PerformanceAnalyzer) estimateQuantileFromBuckets(buckets []BucketCount, quantile float64) float64 {
+func (pa *PerformanceAnalyzerSolution) estimateQuantileFromBuckets(buckets []BucketCount, quantile float64) float64 {
 	if len(buckets) == 0 {
 		return 0
 	}
@@ -423,7 +535,7 @@ type EndpointPerformance struct {
 // MetricsServer メトリクスサーバー
 type MetricsServer struct {
 	tracker  *RequestLatencyTracker
-	analyzer *PerformanceAnalyzer
+	analyzer *PerformanceAnalyzerSolution
 	mux      *http.ServeMux
 }
 
@@ -541,7 +653,7 @@ func (as *AlertingSystem) StartMonitoring(ctx context.Context) {
 }
 
 func (as *AlertingSystem) checkThresholds() {
-	analyzer := NewPerformanceAnalyzer(as.tracker)
+	analyzer := NewPerformanceAnalyzerSolution(as.tracker)
 	report := analyzer.AnalyzePerformance()
 	
 	for _, ep := range report.Endpoints {
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
@@ -106,6 +108,60 @@ func TestHTTPMetricsMiddleware_ResponseSizeMeasurement(t *testing.T) {
 	}
 }
 
+func TestHTTPMetricsMiddleware_HistogramBuckets(t *testing.T) {
+	metrics := NewHistogramMetrics()
+	middleware := NewHTTPMetricsMiddleware(metrics)
+
+	handler := middleware.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+		w.Write([]byte(strings.Repeat("x", 5000)))
+	}))
+
+	for i := 0; i < 3; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/data", nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	durations := metrics.httpRequestDuration.GetAllStats()
+	ok := durations[joinLabels([]string{"GET", "/data", "200"})]
+	if ok.Count != 3 {
+		t.Errorf("Expected 3 observations for 200 responses, got %d", ok.Count)
+	}
+	notFound := durations[joinLabels([]string{"GET", "/missing", "404"})]
+	if notFound.Count != 1 {
+		t.Errorf("Expected 1 observation for 404 responses, got %d", notFound.Count)
+	}
+
+	// 5000バイトは 10000 以下のバケットにのみ入る
+	sizes := metrics.apiResponseSize.GetAllStats()
+	data := sizes[joinLabels([]string{"/data", "text/plain"})]
+	expected := map[float64]int64{100: 0, 1000: 0, 10000: 3, 100000: 3, math.Inf(1): 3}
+	for _, bucket := range data.BucketCounts {
+		if want, ok := expected[bucket.UpperBound]; ok && bucket.Count != want {
+			t.Errorf("Expected bucket le=%v to be %d, got %d", bucket.UpperBound, want, bucket.Count)
+		}
+	}
+	if data.Sum != 15000 {
+		t.Errorf("Expected response size sum 15000, got %v", data.Sum)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`api_response_size_bytes_bucket{endpoint="/data",content_type="text/plain",le="10000"} 3`,
+		`http_request_duration_seconds_count{method="GET",endpoint="/missing",status="404"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("Expected metrics output to contain %q", want)
+		}
+	}
+}
+
 func TestDatabaseSimulator_QueryExecution(t *testing.T) {
 	metrics := NewHistogramMetrics()
 	if metrics == nil {
@@ -149,6 +205,102 @@ func TestDatabaseSimulator_QueryExecution(t *testing.T) {
 	}
 }
 
+// slowQuerySimulator は DatabaseSimulator と DatabaseSimulatorSolution の共通インターフェース
+type slowQuerySimulator interface {
+	ExecuteQuery(operation, table string) error
+	EnableSlowQuerySampling(threshold time.Duration, capacity int)
+	SlowQueries() []SlowQuerySample
+}
+
+func TestSlowQuerySampler_CapturesOutliers(t *testing.T) {
+	// 処理時間はオペレーション名で決める（"SLOW_<ms>" は指定ミリ秒、それ以外は1ms）
+	latency := func(operation string) time.Duration {
+		var ms int
+		if _, err := fmt.Sscanf(operation, "SLOW_%d", &ms); err == nil {
+			return time.Duration(ms) * time.Millisecond
+		}
+		return time.Millisecond
+	}
+
+	db := NewDatabaseSimulator(NewHistogramMetrics())
+	db.latency = latency
+	solution := NewDatabaseSimulatorSolution()
+	solution.latency = latency
+
+	for name, sim := range map[string]slowQuerySimulator{"DatabaseSimulator": db, "DatabaseSimulatorSolution": solution} {
+		t.Run(name, func(t *testing.T) {
+			if sim.SlowQueries() != nil {
+				t.Fatal("Slow query sampling should be disabled by default")
+			}
+			sim.EnableSlowQuerySampling(20*time.Millisecond, 3)
+
+			// 高速クエリに遅いクエリを4件混ぜる。容量3なので最も速い30msのものだけが落ちる
+			slow := map[int]int{5: 60, 17: 30, 30: 90, 35: 40}
+			for i := 0; i < 40; i++ {
+				operation := "SELECT"
+				if ms, ok := slow[i]; ok {
+					operation = fmt.Sprintf("SLOW_%d", ms)
+				}
+				sim.ExecuteQuery(operation, fmt.Sprintf("table_%d", i))
+			}
+
+			samples := sim.SlowQueries()
+			if len(samples) != 3 {
+				t.Fatalf("Expected 3 slow queries, got %d: %+v", len(samples), samples)
+			}
+
+			expected := []struct {
+				table     string
+				operation string
+				minimum   time.Duration
+			}{
+				{"table_30", "SLOW_90", 90 * time.Millisecond},
+				{"table_5", "SLOW_60", 60 * time.Millisecond},
+				{"table_35", "SLOW_40", 40 * time.Millisecond},
+			}
+			for i, want := range expected {
+				got := samples[i]
+				if got.Table != want.table || got.Operation != want.operation || got.Duration < want.minimum {
+					t.Errorf("Sample %d: expected %s/%s (>= %v), got %s/%s/%v",
+						i, want.table, want.operation, want.minimum, got.Table, got.Operation, got.Duration)
+				}
+				if len(got.TraceID) != 16 {
+					t.Errorf("Sample %d: expected 16 char trace ID, got %q", i, got.TraceID)
+				}
+			}
+		})
+	}
+}
+
+func TestSlowQuerySampler_RespectsCapacity(t *testing.T) {
+	sampler := NewSlowQuerySampler(10*time.Millisecond, 4)
+
+	if !sampler.Record("UPDATE", "table_big", 500*time.Millisecond) {
+		t.Error("Query above threshold should be recorded")
+	}
+	for i := 1; i <= 10; i++ {
+		sampler.Record("UPDATE", fmt.Sprintf("table_%d", i), time.Duration(i)*10*time.Millisecond)
+	}
+	if sampler.Record("UPDATE", "fast", 5*time.Millisecond) {
+		t.Error("Query below threshold should not be recorded")
+	}
+	if sampler.Record("UPDATE", "not_slow_enough", 50*time.Millisecond) {
+		t.Error("Query faster than every retained sample should not be recorded when full")
+	}
+
+	samples := sampler.Samples()
+	if len(samples) != 4 {
+		t.Fatalf("Expected samples bounded to 4, got %d", len(samples))
+	}
+
+	// 記録順に関係なく、最も遅い4件が残る
+	for i, want := range []string{"table_big", "table_10", "table_9", "table_8"} {
+		if samples[i].Table != want {
+			t.Errorf("Sample %d: expected %s, got %s", i, want, samples[i].Table)
+		}
+	}
+}
+
 func TestQueueManager_MessageProcessing(t *testing.T) {
 	metrics := NewHistogramMetrics()
 	if metrics == nil {
@@ -344,7 +496,7 @@ func TestMetricsEndpoint_HistogramOutput(t *testing.T) {
 	})
 	
 	mux.Handle("/test", middleware.Middleware(testHandler))
-	// mux.Handle("/metrics", promhttp.Handler()) // TODO: 実装後に有効化
+	mux.Handle("/metrics", metrics.Handler())
 
 	server := httptest.NewServer(mux)
 	defer server.Close()
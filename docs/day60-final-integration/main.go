@@ -8,6 +8,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
@@ -80,7 +81,7 @@ func (m *Metrics) Export() map[string]interface{} {
 
 type TraceContext struct {
 	// TODO: トレースコンテキスト構造体
-	// TraceID, SpanID, ParentID
+	// TraceID, SpanID, ParentID, Sampled
 }
 
 type Span struct {
@@ -88,6 +89,8 @@ type Span struct {
 	// - TraceID, SpanID, ParentID
 	// - Operation, StartTime, EndTime, Duration
 	// - Tags, Logs, Error
+	// - Sampled（サンプリング対象かどうか）
+	// - tracer, localRoot（完了時にトレースを確定させるため）
 }
 
 type SpanLog struct {
@@ -97,24 +100,47 @@ type SpanLog struct {
 
 type Tracer struct {
 	// TODO: トレーサー構造体
-	// serviceName, spans, mutex
+	// serviceName, sampleRate, maxTraces, spans, mutex
+	// traceSpans（トレースID → スパンID）, finished（完了順のトレースID）
+}
+
+// DefaultMaxTraces は保持する完了済みトレース数のデフォルト値
+const DefaultMaxTraces = 1000
+
+// SetMaxTraces は保持する完了済みトレース数の上限を変更する
+func (t *Tracer) SetMaxTraces(n int) {
+	// TODO: 上限を設定し、超過分を削除する
 }
 
 func NewTracer(serviceName string) *Tracer {
-	// TODO: トレーサーの初期化
+	// TODO: トレーサーの初期化（サンプリング率1.0）
+	return nil
+}
+
+// NewSampledTracer は sampleRate (0.0〜1.0) の割合のトレースだけを記録するトレーサーを作成する
+func NewSampledTracer(serviceName string, sampleRate float64) *Tracer {
+	// TODO: サンプリング率を0〜1に丸めてトレーサーを初期化
 	return nil
 }
 
 func (t *Tracer) StartSpan(ctx context.Context, operation string) (context.Context, *Span) {
 	// TODO: スパンの開始
 	// - 新しいスパンの作成
-	// - 親スパンからの情報継承
+	// - 親スパンからの情報継承（Sampledも継承）
+	// - 親がなければRemoteTraceContextFromContextの値を継承
+	// - どちらもなければルートスパンとしてサンプリングを判定
 	// - コンテキストへの設定
+	// - 非サンプリングのスパンは記録・ログ出力しない
 	return ctx, nil
 }
 
+func (t *Tracer) shouldSample() bool {
+	// TODO: sampleRateに従ってサンプリングするかを判定
+	return false
+}
+
 func (s *Span) SetTag(key string, value interface{}) {
-	// TODO: スパンにタグを設定
+	// TODO: スパンにタグを設定（非サンプリング時は何もしない）
 }
 
 func (s *Span) LogEvent(message string, fields map[string]interface{}) {
@@ -130,6 +156,21 @@ func (s *Span) Finish() {
 	// - 終了時間の設定
 	// - 継続時間の計算
 	// - ログ出力
+	// - 非サンプリング時は何もしない
+	// - ルートスパンならfinishTraceでトレースを完了扱いにする
+}
+
+// finishTrace は完了したトレースを記録し、上限を超えた古いトレースを丸ごと削除する
+func (t *Tracer) finishTrace(traceID string) {
+	// TODO: 完了順にトレースIDを記録
+	// - 同じトレースIDが既にあれば最新の位置に移す
+	// - evictLockedで上限を超えた分を削除
+}
+
+// evictLocked は t.mu を保持した状態で呼び出す。
+// 実行中のトレースは削除対象にならないため、スパン単位で分断されることはない
+func (t *Tracer) evictLocked() {
+	// TODO: 最も古い完了済みトレースのスパンをすべて削除
 }
 
 func (t *Tracer) GetSpans() map[string]*Span {
@@ -137,9 +178,29 @@ func (t *Tracer) GetSpans() map[string]*Span {
 	return nil
 }
 
+// W3C Trace Context の traceparent ヘッダー
+const traceParentHeader = "traceparent"
+
+// TraceParent はスパンを traceparent ヘッダー値 (version-traceid-spanid-flags) に変換する
+func (s *Span) TraceParent() string {
+	// TODO: "00-<trace-id>-<span-id>-<flags>" 形式で返す（サンプリング時はflags=01）
+	return ""
+}
+
+// ParseTraceParent は traceparent ヘッダー値を解析する
+func ParseTraceParent(header string) (TraceContext, error) {
+	// TODO: traceparentを解析
+	// - 4つのフィールドと各長さ (2-32-16-2) を検証
+	// - 16進数以外やすべて0のIDはエラー
+	// - flagsの最下位ビットをSampledに設定
+	return TraceContext{}, fmt.Errorf("not implemented")
+}
+
 // TODO: コンテキスト関連の実装
 type spanContextKey struct{}
 
+type remoteTraceContextKey struct{}
+
 func ContextWithSpan(ctx context.Context, span *Span) context.Context {
 	// TODO: コンテキストにスパンを設定
 	return ctx
@@ -150,13 +211,24 @@ func SpanFromContext(ctx context.Context) *Span {
 	return nil
 }
 
+// ContextWithRemoteTraceContext は上流サービスから受け取ったトレースコンテキストを設定する
+func ContextWithRemoteTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	// TODO: コンテキストにリモートのトレースコンテキストを設定
+	return ctx
+}
+
+func RemoteTraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	// TODO: コンテキストからリモートのトレースコンテキストを取得
+	return TraceContext{}, false
+}
+
 func generateTraceID() string {
-	// TODO: ユニークなトレースIDを生成
+	// TODO: ユニークなトレースIDを生成（32桁の16進数）
 	return ""
 }
 
 func generateSpanID() string {
-	// TODO: ユニークなスパンIDを生成
+	// TODO: ユニークなスパンIDを生成（16桁の16進数）
 	return ""
 }
 
@@ -171,9 +243,31 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ErrUserNotFound は指定したIDのユーザーが存在しないことを表す
+var ErrUserNotFound = errors.New("user not found")
+
+// UserValidationError は入力値の検証エラー
+type UserValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *UserValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// DuplicateEmailError は既に登録済みのメールアドレスで作成しようとしたことを表す
+type DuplicateEmailError struct {
+	Email string
+}
+
+func (e *DuplicateEmailError) Error() string {
+	return fmt.Sprintf("email already registered: %s", e.Email)
+}
+
 type UserService struct {
 	// TODO: ユーザーサービス構造体
-	// users, tracer, mutex
+	// users, emails（重複チェック用）, tracer, mutex
 }
 
 func NewUserService(tracer *Tracer) *UserService {
@@ -183,10 +277,12 @@ func NewUserService(tracer *Tracer) *UserService {
 
 func (s *UserService) CreateUser(ctx context.Context, name, email string) (*User, error) {
 	// TODO: ユーザー作成の実装
-	// - スパンの開始
-	// - バリデーション
+	// - 子スパンの開始
+	// - バリデーション（失敗時は *UserValidationError）
+	// - メール重複チェック（重複時は *DuplicateEmailError）
 	// - データベースシミュレーション
-	// - ログ記録
+	// - トレースID付きのログ記録
+	// - 結果を user.create.outcome タグに記録（created / invalid / duplicate）
 	return nil, nil
 }
 
@@ -194,7 +290,7 @@ func (s *UserService) GetUser(ctx context.Context, userID string) (*User, error)
 	// TODO: ユーザー取得の実装
 	// - スパンの開始
 	// - データベースアクセスシミュレーション
-	// - エラーハンドリング
+	// - 存在しない場合は ErrUserNotFound をラップして返す
 	return nil, nil
 }
 
@@ -262,7 +358,8 @@ func (s *APIServer) MetricsMiddleware(next http.Handler) http.Handler {
 		// TODO: メトリクスミドルウェアの実装
 		// - 開始時間の記録
 		// - アクティブリクエスト数の増加
-		// - トレーシングコンテキストの設定
+		// - traceparentヘッダーの解析とトレーシングコンテキストの設定
+		// - レスポンスへのtraceparentヘッダー設定
 		// - レスポンスライターのラップ
 		// - メトリクスの記録
 		// - 構造化ログの出力
@@ -281,7 +378,7 @@ func (rw *responseWriter) WriteHeader(code int) {
 func (s *APIServer) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	// TODO: ユーザー作成ハンドラーの実装
 	// - JSONリクエストの解析
-	// - ユーザーサービスの呼び出し
+	// - ユーザーサービスの呼び出し（メール重複は 409 Conflict）
 	// - ビジネスメトリクスの更新
 	// - JSONレスポンスの出力
 }
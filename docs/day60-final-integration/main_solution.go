@@ -6,6 +6,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
@@ -13,6 +14,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -124,6 +126,7 @@ type TraceContext struct {
 	TraceID  string `json:"trace_id"`
 	SpanID   string `json:"span_id"`
 	ParentID string `json:"parent_id,omitempty"`
+	Sampled  bool   `json:"sampled"`
 }
 
 type Span struct {
@@ -137,6 +140,10 @@ type Span struct {
 	Tags      map[string]interface{} `json:"tags"`
 	Logs      []SpanLog              `json:"logs"`
 	Error     *string                `json:"error,omitempty"`
+	Sampled   bool                   `json:"sampled"`
+
+	tracer    *Tracer // 記録先のトレーサー（サンプリング時のみ）
+	localRoot bool    // このプロセス内でのトレースの起点かどうか
 }
 
 type SpanLog struct {
@@ -145,43 +152,90 @@ type SpanLog struct {
 	Fields    map[string]interface{} `json:"fields,omitempty"`
 }
 
+// DefaultMaxTraces は保持する完了済みトレース数のデフォルト値
+const DefaultMaxTraces = 1000
+
 type Tracer struct {
 	serviceName string
+	sampleRate  float64
+	maxTraces   int
 	spans       map[string]*Span
+	traceSpans  map[string][]string // トレースID → スパンID
+	finished    []string            // 完了したトレースIDを完了順に保持する
 	mu          sync.RWMutex
 }
 
 func NewTracer(serviceName string) *Tracer {
+	return NewSampledTracer(serviceName, 1.0)
+}
+
+// NewSampledTracer は sampleRate (0.0〜1.0) の割合のトレースだけを記録するトレーサーを作成する
+func NewSampledTracer(serviceName string, sampleRate float64) *Tracer {
+	if sampleRate < 0 {
+		sampleRate = 0
+	}
+	if sampleRate > 1 {
+		sampleRate = 1
+	}
 	return &Tracer{
 		serviceName: serviceName,
+		sampleRate:  sampleRate,
+		maxTraces:   DefaultMaxTraces,
 		spans:       make(map[string]*Span),
+		traceSpans:  make(map[string][]string),
 	}
 }
 
+// SetMaxTraces は保持する完了済みトレース数の上限を変更する
+func (t *Tracer) SetMaxTraces(n int) {
+	if n < 1 {
+		n = 1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxTraces = n
+	t.evictLocked()
+}
+
 func (t *Tracer) StartSpan(ctx context.Context, operation string) (context.Context, *Span) {
 	span := &Span{
-		TraceID:   generateTraceID(),
 		SpanID:    generateSpanID(),
 		Operation: operation,
 		StartTime: time.Now(),
-		Tags:      make(map[string]interface{}),
-		Logs:      make([]SpanLog, 0),
 	}
 	
-	// 親スパンから情報を継承
-	if parentSpan := SpanFromContext(ctx); parentSpan != nil {
+	// サンプリング判定はルートスパンでのみ行い、子スパンは親の判定を継承する
+	parentSpan := SpanFromContext(ctx)
+	if parentSpan != nil {
 		span.TraceID = parentSpan.TraceID
 		span.ParentID = parentSpan.SpanID
+		span.Sampled = parentSpan.Sampled
+	} else if remote, ok := RemoteTraceContextFromContext(ctx); ok {
+		span.TraceID = remote.TraceID
+		span.ParentID = remote.SpanID
+		span.Sampled = remote.Sampled
+	} else {
+		span.TraceID = generateTraceID()
+		span.Sampled = t.shouldSample()
 	}
 	
-	span.Tags["service.name"] = t.serviceName
+	ctx = ContextWithSpan(ctx, span)
+	
+	// 非サンプリングのスパンはIDの伝播だけを行い、記録もログ出力もしない
+	if !span.Sampled {
+		return ctx, span
+	}
+	
+	span.Tags = map[string]interface{}{"service.name": t.serviceName}
+	span.Logs = make([]SpanLog, 0)
+	span.tracer = t
+	span.localRoot = parentSpan == nil
 	
 	t.mu.Lock()
 	t.spans[span.SpanID] = span
+	t.traceSpans[span.TraceID] = append(t.traceSpans[span.TraceID], span.SpanID)
 	t.mu.Unlock()
 	
-	ctx = ContextWithSpan(ctx, span)
-	
 	logger.Info("Span started",
 		slog.String("trace_id", span.TraceID),
 		slog.String("span_id", span.SpanID),
@@ -191,11 +245,28 @@ func (t *Tracer) StartSpan(ctx context.Context, operation string) (context.Conte
 	return ctx, span
 }
 
+func (t *Tracer) shouldSample() bool {
+	switch {
+	case t.sampleRate >= 1:
+		return true
+	case t.sampleRate <= 0:
+		return false
+	default:
+		return rand.Float64() < t.sampleRate
+	}
+}
+
 func (s *Span) SetTag(key string, value interface{}) {
+	if !s.Sampled {
+		return
+	}
 	s.Tags[key] = value
 }
 
 func (s *Span) LogEvent(message string, fields map[string]interface{}) {
+	if !s.Sampled {
+		return
+	}
 	s.Logs = append(s.Logs, SpanLog{
 		Timestamp: time.Now(),
 		Message:   message,
@@ -204,6 +275,9 @@ func (s *Span) LogEvent(message string, fields map[string]interface{}) {
 }
 
 func (s *Span) SetError(err error) {
+	if !s.Sampled {
+		return
+	}
 	errorMsg := err.Error()
 	s.Error = &errorMsg
 	s.LogEvent("error", map[string]interface{}{
@@ -213,6 +287,9 @@ func (s *Span) SetError(err error) {
 }
 
 func (s *Span) Finish() {
+	if !s.Sampled {
+		return
+	}
 	now := time.Now()
 	s.EndTime = &now
 	duration := now.Sub(s.StartTime)
@@ -225,6 +302,40 @@ func (s *Span) Finish() {
 		slog.Duration("duration", duration),
 		slog.Bool("error", s.Error != nil),
 	)
+	
+	// ルートスパンの完了をもってトレース全体を完了とみなす
+	if s.localRoot && s.tracer != nil {
+		s.tracer.finishTrace(s.TraceID)
+	}
+}
+
+// finishTrace は完了したトレースを記録し、上限を超えた古いトレースを丸ごと削除する
+func (t *Tracer) finishTrace(traceID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	// 同じトレースIDを持つ複数のリクエスト（上流が同一）は最新の完了位置に移す
+	for i, id := range t.finished {
+		if id == traceID {
+			t.finished = append(t.finished[:i], t.finished[i+1:]...)
+			break
+		}
+	}
+	t.finished = append(t.finished, traceID)
+	t.evictLocked()
+}
+
+// evictLocked は t.mu を保持した状態で呼び出す。
+// 実行中のトレースは削除対象にならないため、スパン単位で分断されることはない
+func (t *Tracer) evictLocked() {
+	for len(t.finished) > t.maxTraces {
+		oldest := t.finished[0]
+		t.finished = t.finished[1:]
+		for _, spanID := range t.traceSpans[oldest] {
+			delete(t.spans, spanID)
+		}
+		delete(t.traceSpans, oldest)
+	}
 }
 
 func (t *Tracer) GetSpans() map[string]*Span {
@@ -238,9 +349,59 @@ func (t *Tracer) GetSpans() map[string]*Span {
 	return result
 }
 
+// W3C Trace Context の traceparent ヘッダー
+const traceParentHeader = "traceparent"
+
+// TraceParent はスパンを traceparent ヘッダー値 (version-traceid-spanid-flags) に変換する
+func (s *Span) TraceParent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", s.TraceID, s.SpanID, flags)
+}
+
+// ParseTraceParent は traceparent ヘッダー値を解析する
+func ParseTraceParent(header string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 ||
+		!isHexID(parts[0], 2) || parts[0] == "ff" ||
+		!isHexID(parts[1], 32) || !isHexID(parts[2], 16) || !isHexID(parts[3], 2) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent: %q", header)
+	}
+	
+	flags, _ := strconv.ParseUint(parts[3], 16, 8)
+	return TraceContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Sampled: flags&0x01 == 0x01,
+	}, nil
+}
+
+// isHexID は s が長さ n の小文字16進数で、すべて0ではないかを判定する
+func isHexID(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	nonZero := false
+	for _, c := range s {
+		switch {
+		case c >= '1' && c <= '9', c >= 'a' && c <= 'f':
+			nonZero = true
+		case c == '0':
+		default:
+			return false
+		}
+	}
+	// version と flags は 00 が有効な値
+	return nonZero || n == 2
+}
+
 // コンテキスト関連
 type spanContextKey struct{}
 
+type remoteTraceContextKey struct{}
+
 func ContextWithSpan(ctx context.Context, span *Span) context.Context {
 	return context.WithValue(ctx, spanContextKey{}, span)
 }
@@ -252,12 +413,22 @@ func SpanFromContext(ctx context.Context) *Span {
 	return nil
 }
 
+// ContextWithRemoteTraceContext は上流サービスから受け取ったトレースコンテキストを設定する
+func ContextWithRemoteTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, remoteTraceContextKey{}, tc)
+}
+
+func RemoteTraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(remoteTraceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
 func generateTraceID() string {
-	return fmt.Sprintf("trace_%d_%04d", time.Now().UnixNano(), rand.Intn(10000))
+	return fmt.Sprintf("%016x%016x", rand.Uint64(), rand.Uint64()|1)
 }
 
 func generateSpanID() string {
-	return fmt.Sprintf("span_%d_%04d", time.Now().UnixNano(), rand.Intn(10000))
+	return fmt.Sprintf("%016x", rand.Uint64()|1)
 }
 
 // ===== ビジネスロジック =====
@@ -271,8 +442,31 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ErrUserNotFound は指定したIDのユーザーが存在しないことを表す
+var ErrUserNotFound = errors.New("user not found")
+
+// UserValidationError は入力値の検証エラー
+type UserValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *UserValidationError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// DuplicateEmailError は既に登録済みのメールアドレスで作成しようとしたことを表す
+type DuplicateEmailError struct {
+	Email string
+}
+
+func (e *DuplicateEmailError) Error() string {
+	return fmt.Sprintf("email already registered: %s", e.Email)
+}
+
 type UserService struct {
 	users  map[string]*User
+	emails map[string]string // 正規化したメールアドレス → ユーザーID
 	tracer *Tracer
 	mu     sync.RWMutex
 }
@@ -280,6 +474,7 @@ type UserService struct {
 func NewUserService(tracer *Tracer) *UserService {
 	return &UserService{
 		users:  make(map[string]*User),
+		emails: make(map[string]string),
 		tracer: tracer,
 	}
 }
@@ -292,15 +487,13 @@ func (s *UserService) CreateUser(ctx context.Context, name, email string) (*User
 	span.SetTag("user.email", email)
 	
 	// バリデーション
-	if name == "" {
-		err := fmt.Errorf("name is required")
-		span.SetError(err)
-		return nil, err
-	}
-	
-	if email == "" {
-		err := fmt.Errorf("email is required")
+	if err := validateUserInput(name, email); err != nil {
+		span.SetTag("user.create.outcome", "invalid")
 		span.SetError(err)
+		logger.Warn("User validation failed",
+			slog.String("trace_id", span.TraceID),
+			slog.String("error", err.Error()),
+		)
 		return nil, err
 	}
 	
@@ -320,17 +513,52 @@ func (s *UserService) CreateUser(ctx context.Context, name, email string) (*User
 	
 	time.Sleep(time.Duration(rand.Intn(50)) * time.Millisecond)
 	
+	// 重複チェックと登録は同じロック内で行い、同時登録でも一意性を保つ
+	key := strings.ToLower(email)
 	s.mu.Lock()
+	if _, exists := s.emails[key]; exists {
+		s.mu.Unlock()
+		err := &DuplicateEmailError{Email: email}
+		span.SetTag("user.create.outcome", "duplicate")
+		span.SetError(err)
+		logger.Warn("Duplicate email rejected",
+			slog.String("trace_id", span.TraceID),
+			slog.String("email", email),
+		)
+		return nil, err
+	}
 	s.users[user.ID] = user
+	s.emails[key] = user.ID
 	s.mu.Unlock()
 	
+	span.SetTag("user.id", user.ID)
+	span.SetTag("user.create.outcome", "created")
 	span.LogEvent("user.created", map[string]interface{}{
 		"user.id": user.ID,
 	})
+	logger.Info("User created",
+		slog.String("trace_id", span.TraceID),
+		slog.String("user_id", user.ID),
+	)
 	
 	return user, nil
 }
 
+// validateUserInput は名前とメールアドレスの形式を検証する
+func validateUserInput(name, email string) error {
+	if strings.TrimSpace(name) == "" {
+		return &UserValidationError{Field: "name", Message: "is required"}
+	}
+	if email == "" {
+		return &UserValidationError{Field: "email", Message: "is required"}
+	}
+	at := strings.Index(email, "@")
+	if at <= 0 || at != strings.LastIndex(email, "@") || !strings.Contains(email[at+1:], ".") {
+		return &UserValidationError{Field: "email", Message: "is invalid"}
+	}
+	return nil
+}
+
 func (s *UserService) GetUser(ctx context.Context, userID string) (*User, error) {
 	ctx, span := s.tracer.StartSpan(ctx, "UserService.GetUser")
 	defer span.Finish()
@@ -349,7 +577,7 @@ func (s *UserService) GetUser(ctx context.Context, userID string) (*User, error)
 	s.mu.RUnlock()
 	
 	if !exists {
-		err := fmt.Errorf("user not found: %s", userID)
+		err := fmt.Errorf("%w: %s", ErrUserNotFound, userID)
 		span.SetError(err)
 		return nil, err
 	}
@@ -518,8 +746,13 @@ func (s *APIServer) MetricsMiddleware(next http.Handler) http.Handler {
 		// レスポンスライターをラップ
 		ww := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		
-		// トレーシングコンテキストを設定
-		ctx, span := s.tracer.StartSpan(r.Context(), fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+		// トレーシングコンテキストを設定（上流のtraceparentがあればサンプリング判定を引き継ぐ）
+		ctx := r.Context()
+		if tc, err := ParseTraceParent(r.Header.Get(traceParentHeader)); err == nil {
+			ctx = ContextWithRemoteTraceContext(ctx, tc)
+		}
+		ctx, span := s.tracer.StartSpan(ctx, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+		ww.Header().Set(traceParentHeader, span.TraceParent())
 		span.SetTag("http.method", r.Method)
 		span.SetTag("http.url", r.URL.String())
 		span.SetTag("http.user_agent", r.UserAgent())
@@ -588,6 +821,11 @@ func (s *APIServer) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	
 	user, err := s.userService.CreateUser(r.Context(), req.Name, req.Email)
 	if err != nil {
+		var dupErr *DuplicateEmailError
+		if errors.As(err, &dupErr) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -127,6 +128,259 @@ func TestSpanHierarchy(t *testing.T) {
 	parentSpan.Finish()
 }
 
+func TestTracerSampling(t *testing.T) {
+	initLogger()
+	
+	startTrace := func(tracer *Tracer) (*Span, *Span) {
+		ctx, root := tracer.StartSpan(context.Background(), "root")
+		_, child := tracer.StartSpan(ctx, "child")
+		child.SetTag("key", "value")
+		child.Finish()
+		root.Finish()
+		return root, child
+	}
+	
+	t.Run("rate 0 retains nothing", func(t *testing.T) {
+		tracer := NewSampledTracer("test-service", 0)
+		for i := 0; i < 10; i++ {
+			root, child := startTrace(tracer)
+			if root.Sampled || child.Sampled {
+				t.Fatal("Expected spans to be unsampled")
+			}
+			// 非サンプリングでもIDは伝播する
+			if child.TraceID != root.TraceID || child.ParentID != root.SpanID {
+				t.Error("Expected unsampled child to keep trace relationship")
+			}
+		}
+		if spans := tracer.GetSpans(); len(spans) != 0 {
+			t.Errorf("Expected no spans, got %d", len(spans))
+		}
+	})
+	
+	t.Run("rate 1 retains everything", func(t *testing.T) {
+		tracer := NewSampledTracer("test-service", 1)
+		for i := 0; i < 10; i++ {
+			startTrace(tracer)
+		}
+		spans := tracer.GetSpans()
+		if len(spans) != 20 {
+			t.Errorf("Expected 20 spans, got %d", len(spans))
+		}
+		for _, span := range spans {
+			if span.EndTime == nil {
+				t.Errorf("Expected span %s to be finished", span.Operation)
+			}
+		}
+	})
+	
+	t.Run("children inherit root decision", func(t *testing.T) {
+		tracer := NewSampledTracer("test-service", 0.5)
+		sampledRoots := 0
+		for i := 0; i < 200; i++ {
+			root, child := startTrace(tracer)
+			if child.Sampled != root.Sampled {
+				t.Fatalf("Child sampled=%v, root sampled=%v", child.Sampled, root.Sampled)
+			}
+			if root.Sampled {
+				sampledRoots++
+			}
+		}
+		if sampledRoots == 0 || sampledRoots == 200 {
+			t.Errorf("Expected a mix of sampled traces at rate 0.5, got %d/200", sampledRoots)
+		}
+		if spans := tracer.GetSpans(); len(spans) != sampledRoots*2 {
+			t.Errorf("Expected %d spans, got %d", sampledRoots*2, len(spans))
+		}
+	})
+}
+
+func TestTracerBoundedStore(t *testing.T) {
+	initLogger()
+	
+	// ルート1つと子2つからなるトレースを作成する
+	runTrace := func(tracer *Tracer) string {
+		ctx, root := tracer.StartSpan(context.Background(), "root")
+		for i := 0; i < 2; i++ {
+			_, child := tracer.StartSpan(ctx, fmt.Sprintf("child-%d", i))
+			child.Finish()
+		}
+		root.Finish()
+		return root.TraceID
+	}
+	
+	spansByTrace := func(tracer *Tracer) map[string]int {
+		counts := make(map[string]int)
+		for _, span := range tracer.GetSpans() {
+			counts[span.TraceID]++
+		}
+		return counts
+	}
+	
+	t.Run("retains newest traces", func(t *testing.T) {
+		tracer := NewTracer("test-service")
+		tracer.SetMaxTraces(3)
+		
+		var traceIDs []string
+		for i := 0; i < 10; i++ {
+			traceIDs = append(traceIDs, runTrace(tracer))
+		}
+		
+		counts := spansByTrace(tracer)
+		if len(counts) != 3 {
+			t.Fatalf("Expected 3 traces, got %d", len(counts))
+		}
+		for _, id := range traceIDs[len(traceIDs)-3:] {
+			// トレースは丸ごと保持されるか丸ごと削除されるかのどちらか
+			if counts[id] != 3 {
+				t.Errorf("Expected trace %s to keep all 3 spans, got %d", id, counts[id])
+			}
+		}
+	})
+	
+	t.Run("in-flight trace is never split", func(t *testing.T) {
+		tracer := NewTracer("test-service")
+		tracer.SetMaxTraces(2)
+		
+		ctx, longRoot := tracer.StartSpan(context.Background(), "long-running")
+		_, earlyChild := tracer.StartSpan(ctx, "early-child")
+		earlyChild.Finish()
+		
+		for i := 0; i < 5; i++ {
+			runTrace(tracer)
+		}
+		
+		// 実行中のトレースは上限に数えられず、削除もされない
+		counts := spansByTrace(tracer)
+		if counts[longRoot.TraceID] != 2 {
+			t.Fatalf("Expected in-flight trace to keep 2 spans, got %d", counts[longRoot.TraceID])
+		}
+		if len(counts) != 3 {
+			t.Errorf("Expected 2 finished traces plus the in-flight one, got %d", len(counts))
+		}
+		
+		_, lateChild := tracer.StartSpan(ctx, "late-child")
+		lateChild.Finish()
+		longRoot.Finish()
+		
+		counts = spansByTrace(tracer)
+		if counts[longRoot.TraceID] != 3 {
+			t.Errorf("Expected finished long trace to keep 3 spans, got %d", counts[longRoot.TraceID])
+		}
+		if len(counts) != 2 {
+			t.Errorf("Expected 2 traces after eviction, got %d", len(counts))
+		}
+		for id, n := range counts {
+			if n != 3 {
+				t.Errorf("Trace %s was split: %d spans retained", id, n)
+			}
+		}
+	})
+	
+	t.Run("shrinking the limit evicts oldest", func(t *testing.T) {
+		tracer := NewTracer("test-service")
+		var traceIDs []string
+		for i := 0; i < 5; i++ {
+			traceIDs = append(traceIDs, runTrace(tracer))
+		}
+		
+		tracer.SetMaxTraces(1)
+		counts := spansByTrace(tracer)
+		if len(counts) != 1 || counts[traceIDs[4]] != 3 {
+			t.Errorf("Expected only newest trace to remain, got %v", counts)
+		}
+	})
+}
+
+func TestTraceParentPropagation(t *testing.T) {
+	initLogger()
+	
+	t.Run("round trip", func(t *testing.T) {
+		tracer := NewSampledTracer("test-service", 1)
+		_, span := tracer.StartSpan(context.Background(), "op")
+		
+		tc, err := ParseTraceParent(span.TraceParent())
+		if err != nil {
+			t.Fatalf("Failed to parse own traceparent %q: %v", span.TraceParent(), err)
+		}
+		if tc.TraceID != span.TraceID || tc.SpanID != span.SpanID || !tc.Sampled {
+			t.Errorf("Unexpected trace context: %+v", tc)
+		}
+	})
+	
+	t.Run("invalid headers", func(t *testing.T) {
+		invalid := []string{
+			"",
+			"00-abc-def-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		}
+		for _, header := range invalid {
+			if _, err := ParseTraceParent(header); err == nil {
+				t.Errorf("Expected error for %q", header)
+			}
+		}
+	})
+	
+	tests := []struct {
+		name        string
+		sampleRate  float64
+		flags       string
+		wantSampled bool
+	}{
+		{"upstream sampled overrides rate 0", 0, "01", true},
+		{"upstream unsampled overrides rate 1", 1, "00", false},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := NewSampledTracer("test-service", tt.sampleRate)
+			apiServer := NewAPIServer(NewUserService(tracer), nil, NewMetrics(), tracer)
+			
+			var handlerSpan *Span
+			handler := apiServer.MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, handlerSpan = tracer.StartSpan(r.Context(), "handler")
+				handlerSpan.Finish()
+			}))
+			
+			const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+			const parentID = "00f067aa0ba902b7"
+			req := httptest.NewRequest("GET", "/health", nil)
+			req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-"+tt.flags)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			
+			if handlerSpan.TraceID != traceID || handlerSpan.Sampled != tt.wantSampled {
+				t.Errorf("Expected trace %s sampled=%v, got %s sampled=%v",
+					traceID, tt.wantSampled, handlerSpan.TraceID, handlerSpan.Sampled)
+			}
+			
+			tc, err := ParseTraceParent(w.Header().Get("traceparent"))
+			if err != nil {
+				t.Fatalf("Expected traceparent response header: %v", err)
+			}
+			if tc.TraceID != traceID || tc.Sampled != tt.wantSampled {
+				t.Errorf("Unexpected response traceparent: %+v", tc)
+			}
+			
+			spans := tracer.GetSpans()
+			if !tt.wantSampled {
+				if len(spans) != 0 {
+					t.Errorf("Expected no spans, got %d", len(spans))
+				}
+				return
+			}
+			if len(spans) != 2 {
+				t.Fatalf("Expected 2 spans, got %d", len(spans))
+			}
+			if root := spans[tc.SpanID]; root == nil || root.ParentID != parentID {
+				t.Errorf("Expected request span to have upstream parent %s", parentID)
+			}
+		})
+	}
+}
+
 func TestUserService(t *testing.T) {
 	tracer := NewTracer("test-service")
 	userService := NewUserService(tracer)
@@ -191,6 +445,97 @@ func TestUserServiceValidation(t *testing.T) {
 	}
 }
 
+func TestUserServiceCreateUserTracing(t *testing.T) {
+	initLogger()
+	tracer := NewTracer("test-service")
+	userService := NewUserService(tracer)
+	
+	// 親スパン配下で CreateUser を呼び、生成された子スパンを返す
+	createInTrace := func(name, email string) (*User, *Span, error) {
+		ctx, parent := tracer.StartSpan(context.Background(), "test.request")
+		defer parent.Finish()
+		
+		user, err := userService.CreateUser(ctx, name, email)
+		
+		for _, span := range tracer.GetSpans() {
+			if span.TraceID == parent.TraceID && span.Operation == "UserService.CreateUser" {
+				if span.ParentID != parent.SpanID {
+					t.Errorf("Expected CreateUser span to be a child of %s, got parent %s", parent.SpanID, span.ParentID)
+				}
+				return user, span, err
+			}
+		}
+		t.Fatal("Expected CreateUser span to be recorded")
+		return nil, nil, nil
+	}
+	
+	ctx := context.Background()
+	
+	t.Run("successful creation", func(t *testing.T) {
+		user, span, err := createInTrace("Alice", "alice@example.com")
+		if err != nil {
+			t.Fatalf("Expected user creation to succeed, got error: %v", err)
+		}
+		if span.Tags["user.create.outcome"] != "created" {
+			t.Errorf("Expected outcome tag 'created', got %v", span.Tags["user.create.outcome"])
+		}
+		if span.Tags["user.id"] != user.ID {
+			t.Errorf("Expected user.id tag %s, got %v", user.ID, span.Tags["user.id"])
+		}
+		if span.Error != nil {
+			t.Errorf("Expected no span error, got %s", *span.Error)
+		}
+		
+		retrieved, err := userService.GetUser(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Expected to retrieve created user, got error: %v", err)
+		}
+		if retrieved.Email != "alice@example.com" {
+			t.Errorf("Expected email alice@example.com, got %s", retrieved.Email)
+		}
+	})
+	
+	t.Run("duplicate email is rejected", func(t *testing.T) {
+		_, span, err := createInTrace("Alice Again", "ALICE@example.com")
+		
+		var dupErr *DuplicateEmailError
+		if !errors.As(err, &dupErr) {
+			t.Fatalf("Expected DuplicateEmailError, got %v", err)
+		}
+		if span.Tags["user.create.outcome"] != "duplicate" {
+			t.Errorf("Expected outcome tag 'duplicate', got %v", span.Tags["user.create.outcome"])
+		}
+		if span.Error == nil || *span.Error != err.Error() {
+			t.Errorf("Expected span error %q, got %v", err.Error(), span.Error)
+		}
+	})
+	
+	t.Run("validation failure", func(t *testing.T) {
+		_, span, err := createInTrace("Bob", "not-an-email")
+		
+		var validationErr *UserValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected UserValidationError, got %v", err)
+		}
+		if validationErr.Field != "email" {
+			t.Errorf("Expected email field error, got %s", validationErr.Field)
+		}
+		if span.Tags["user.create.outcome"] != "invalid" {
+			t.Errorf("Expected outcome tag 'invalid', got %v", span.Tags["user.create.outcome"])
+		}
+		if span.Error == nil {
+			t.Error("Expected span to record the validation error")
+		}
+	})
+	
+	t.Run("missing user returns not found", func(t *testing.T) {
+		_, err := userService.GetUser(ctx, "nonexistent")
+		if !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
+
 func TestOrderService(t *testing.T) {
 	tracer := NewTracer("test-service")
 	userService := NewUserService(tracer)